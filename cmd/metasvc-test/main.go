@@ -24,4 +24,15 @@ func main() {
 	log.Infof("Meta: \n%s", meta.PrettyString())
 	log.Infof("LocalIPv4: %s", meta.LocalIPv4.To4().String())
 	log.Infof("PublicIPv4: %s", meta.PublicIPv4.To4().String())
+
+	data, err := provider.FetchUserdata(context.Background())
+	if err != nil {
+		log.Fatalf("Datasource %s FetchUserdata error: %v", provider.GetType(), err)
+	}
+	for name := range data.Scripts() {
+		log.Infof("Userdata script: %s", name)
+	}
+	for name := range data.CloudConfigs() {
+		log.Infof("Userdata cloud-config: %s", name)
+	}
 }
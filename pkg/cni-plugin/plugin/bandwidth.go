@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/vishvananda/netlink"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	// tbf queues packets up to this latency before dropping
+	TBF_LATENCY_MS = 25
+	// burst is the amount of data can be sent at full speed in this duration
+	BANDWIDTH_BURST_MS = 100
+	// burst must hold at least a couple of full sized frames
+	BANDWIDTH_MIN_BURST_BYTES = 3000
+)
+
+// bandwidthBurstBytes returns burst size of rate in bytes per second
+func bandwidthBurstBytes(rateBytes uint64) uint32 {
+	burst := rateBytes * BANDWIDTH_BURST_MS / 1000
+	if burst < BANDWIDTH_MIN_BURST_BYTES {
+		burst = BANDWIDTH_MIN_BURST_BYTES
+	}
+	return uint32(burst)
+}
+
+// bandwidthRateBytes converts bandwidth of nic desc in Mbps to bytes per second
+func bandwidthRateBytes(bwMbps int) uint64 {
+	return uint64(bwMbps) * 1000 * 1000 / 8
+}
+
+// ovsIngressPolicing returns ovs ingress_policing_rate in kbps and ingress_policing_burst in kb
+func ovsIngressPolicing(bwMbps int) (int, int) {
+	if bwMbps <= 0 {
+		return 0, 0
+	}
+	rate := bwMbps * 1000
+	return rate, rate * BANDWIDTH_BURST_MS / 1000
+}
+
+func newTbf(linkIndex int, bwMbps int) *netlink.Tbf {
+	rate := bandwidthRateBytes(bwMbps)
+	burst := bandwidthBurstBytes(rate)
+	latency := uint64(TBF_LATENCY_MS * netlink.TIME_UNITS_PER_SEC / 1000)
+	return &netlink.Tbf{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: linkIndex,
+			Handle:    netlink.MakeHandle(1, 0),
+			Parent:    netlink.HANDLE_ROOT,
+		},
+		Rate:   rate,
+		Buffer: uint32(netlink.Xmittime(rate, burst)),
+		Limit:  uint32(rate*latency/netlink.TIME_UNITS_PER_SEC) + burst,
+	}
+}
+
+func getRootTbf(link netlink.Link) (*netlink.Tbf, error) {
+	qdiscs, err := netlink.QdiscList(link)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list qdiscs of %s", link.Attrs().Name)
+	}
+	for _, q := range qdiscs {
+		if tbf, ok := q.(*netlink.Tbf); ok && tbf.Parent == netlink.HANDLE_ROOT {
+			return tbf, nil
+		}
+	}
+	return nil, nil
+}
+
+// setupBandwidth enforces bandwidth of nic on host side veth, traffic to the pod is
+// shaped by tbf on veth egress and traffic from the pod is policed by ovs
+func setupBandwidth(cli OVSClient, hostIfname string, bwMbps int) error {
+	link, err := netlink.LinkByName(hostIfname)
+	if err != nil {
+		return errors.Wrapf(err, "netlink.LinkByName %s", hostIfname)
+	}
+	if bwMbps > 0 {
+		if err := netlink.QdiscReplace(newTbf(link.Attrs().Index, bwMbps)); err != nil {
+			return errors.Wrapf(err, "replace tbf qdisc of %s", hostIfname)
+		}
+	} else {
+		tbf, err := getRootTbf(link)
+		if err != nil {
+			return err
+		}
+		if tbf != nil {
+			if err := netlink.QdiscDel(tbf); err != nil {
+				return errors.Wrapf(err, "delete tbf qdisc of %s", hostIfname)
+			}
+		}
+	}
+	rate, burst := ovsIngressPolicing(bwMbps)
+	if err := cli.SetIngressPolicing(hostIfname, rate, burst); err != nil {
+		return errors.Wrapf(err, "set ingress policing of %s", hostIfname)
+	}
+	return nil
+}
+
+// checkBandwidth returns drifts of bandwidth enforced on host side veth
+func checkBandwidth(cli OVSClient, link netlink.Link, bwMbps int) ([]string, error) {
+	drifts := make([]string, 0)
+	hostIfname := link.Attrs().Name
+	tbf, err := getRootTbf(link)
+	if err != nil {
+		return nil, err
+	}
+	if bwMbps > 0 {
+		if tbf == nil {
+			drifts = append(drifts, fmt.Sprintf("tbf qdisc of %s not found", hostIfname))
+		} else if expected := bandwidthRateBytes(bwMbps); tbf.Rate != expected {
+			drifts = append(drifts, fmt.Sprintf("tbf rate of %s is %d bytes/s, expected %d", hostIfname, tbf.Rate, expected))
+		}
+	} else if tbf != nil {
+		drifts = append(drifts, fmt.Sprintf("unexpected tbf qdisc of %s with rate %d bytes/s", hostIfname, tbf.Rate))
+	}
+	rate, _, err := cli.GetIngressPolicing(hostIfname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get ingress policing of %s", hostIfname)
+	}
+	if expected, _ := ovsIngressPolicing(bwMbps); rate != expected {
+		drifts = append(drifts, fmt.Sprintf("ingress policing rate of %s is %d kbps, expected %d", hostIfname, rate, expected))
+	}
+	return drifts, nil
+}
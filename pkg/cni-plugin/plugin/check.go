@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/types/current"
+	"github.com/containernetworking/plugins/pkg/ip"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// CNI_ERR_NIC_DRIFT is plugin specific error code returned by CHECK
+// when pod network differs from pod desc
+const CNI_ERR_NIC_DRIFT uint = 100
+
+// checkNic returns drifts of nic between pod desc and the actual network,
+// error is returned only when the check itself can't be performed
+func checkNic(cli OVSClient, index int, nic PodNic, netns ns.NetNS, result *current.Result) ([]string, error) {
+	drifts := make([]string, 0)
+	hostIfname := nic.Ifname
+	ctrIfname := nic.GetInterface(index)
+
+	hostVeth, err := netlink.LinkByName(hostIfname)
+	if err != nil {
+		return append(drifts, fmt.Sprintf("host veth %s not found: %v", hostIfname, err)), nil
+	}
+	if _, ok := hostVeth.(*netlink.Veth); !ok {
+		drifts = append(drifts, fmt.Sprintf("host interface %s is %s, not veth", hostIfname, hostVeth.Type()))
+	}
+	if hostVeth.Attrs().Flags&net.FlagUp == 0 {
+		drifts = append(drifts, fmt.Sprintf("host veth %s is down", hostIfname))
+	}
+
+	if err := netns.Do(func(_ ns.NetNS) error {
+		drifts = append(drifts, checkContainerIface(ctrIfname, nic, hostVeth.Attrs().Index, result)...)
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "check %s in netns %s", ctrIfname, netns.Path())
+	}
+
+	ovsDrifts, err := checkOVSPort(cli, hostIfname, nic)
+	if err != nil {
+		return nil, err
+	}
+	drifts = append(drifts, ovsDrifts...)
+
+	bwDrifts, err := checkBandwidth(cli, hostVeth, nic.Bandwidth)
+	if err != nil {
+		return nil, err
+	}
+	return append(drifts, bwDrifts...), nil
+}
+
+// checkContainerIface runs in pod netns, verifies veth peer, hardware address, ips and routes
+func checkContainerIface(ctrIfname string, nic PodNic, hostVethIndex int, result *current.Result) []string {
+	drifts := make([]string, 0)
+	link, peerIndex, err := ip.GetVethPeerIfindex(ctrIfname)
+	if err != nil {
+		return append(drifts, fmt.Sprintf("container veth %s: %v", ctrIfname, err))
+	}
+	if peerIndex != hostVethIndex {
+		drifts = append(drifts, fmt.Sprintf("container veth %s peer index is %d, expected host veth %s index %d", ctrIfname, peerIndex, nic.Ifname, hostVethIndex))
+	}
+	if mac := link.Attrs().HardwareAddr.String(); mac != nic.Mac {
+		drifts = append(drifts, fmt.Sprintf("container veth %s mac is %s, expected %s", ctrIfname, mac, nic.Mac))
+	}
+	if nic.Mtu > 0 && link.Attrs().MTU != nic.Mtu {
+		drifts = append(drifts, fmt.Sprintf("container veth %s mtu is %d, expected %d", ctrIfname, link.Attrs().MTU, nic.Mtu))
+	}
+	if link.Attrs().Flags&net.FlagUp == 0 {
+		drifts = append(drifts, fmt.Sprintf("container veth %s is down", ctrIfname))
+	}
+	if err := ip.ValidateExpectedInterfaceIPs(ctrIfname, result.IPs); err != nil {
+		drifts = append(drifts, err.Error())
+	}
+	routes, err := netlink.RouteList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return append(drifts, fmt.Sprintf("list routes of %s: %v", ctrIfname, err))
+	}
+	for _, r := range result.Routes {
+		if !hasRoute(routes, r) {
+			drifts = append(drifts, fmt.Sprintf("route %s via %s dev %s not found", r.Dst.String(), r.GW, ctrIfname))
+		}
+	}
+	return drifts
+}
+
+// hasRoute reports whether expected route is in routes, unspecified gateway
+// like 0.0.0.0 is added as on-link route without gateway
+func hasRoute(routes []netlink.Route, expected *types.Route) bool {
+	ones, bits := expected.Dst.Mask.Size()
+	isDefault := ones == 0
+	var gw net.IP
+	if expected.GW != nil && !expected.GW.IsUnspecified() {
+		gw = expected.GW
+	}
+	for _, r := range routes {
+		if r.Dst == nil {
+			// default route, family is told by gateway
+			if !isDefault {
+				continue
+			}
+		} else {
+			rOnes, rBits := r.Dst.Mask.Size()
+			if !r.Dst.IP.Equal(expected.Dst.IP) || rOnes != ones || rBits != bits {
+				continue
+			}
+		}
+		if gw.Equal(r.Gw) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOVSPort verifies host veth is attached to nic bridge with expected vlan and iface-id
+func checkOVSPort(cli OVSClient, hostIfname string, nic PodNic) ([]string, error) {
+	drifts := make([]string, 0)
+	br, err := cli.GetPortBridge(hostIfname)
+	if err != nil {
+		return append(drifts, fmt.Sprintf("ovs port %s not found: %v", hostIfname, err)), nil
+	}
+	if br != nic.Bridge {
+		drifts = append(drifts, fmt.Sprintf("ovs port %s is on bridge %s, expected %s", hostIfname, br, nic.Bridge))
+	}
+	tag, err := cli.GetPortTag(hostIfname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get tag of ovs port %s", hostIfname)
+	}
+	expectedTag := 0
+	if nic.Vlan > 1 {
+		expectedTag = nic.Vlan
+	}
+	if tag != expectedTag {
+		drifts = append(drifts, fmt.Sprintf("ovs port %s tag is %d, expected %d", hostIfname, tag, expectedTag))
+	}
+	if nic.Vpc != nil && nic.Vpc.Provider == POD_NIC_PROVIDER_OVN {
+		ifaceId, err := cli.GetIfaceId(hostIfname)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get iface-id of %s", hostIfname)
+		}
+		if expected := getOVNIfaceId(nic.NetId, hostIfname); ifaceId != expected {
+			drifts = append(drifts, fmt.Sprintf("ovs interface %s iface-id is %q, expected %q", hostIfname, ifaceId, expected))
+		}
+	}
+	return drifts, nil
+}
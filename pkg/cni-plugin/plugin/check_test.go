@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/vishvananda/netlink"
+)
+
+func Test_hasRoute(t *testing.T) {
+	mustCIDR := func(s string) *net.IPNet {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatalf("ParseCIDR(%q): %v", s, err)
+		}
+		return n
+	}
+	routes := []netlink.Route{
+		{Dst: nil, Gw: net.ParseIP("192.168.1.1")},
+		{Dst: mustCIDR("192.168.1.0/24")},
+		{Dst: mustCIDR("169.254.169.254/32")},
+		{Dst: mustCIDR("fd00:ec2::254/128")},
+	}
+	tests := []struct {
+		name  string
+		route *types.Route
+		want  bool
+	}{
+		{"default route", &types.Route{Dst: *mustCIDR("0.0.0.0/0"), GW: net.ParseIP("192.168.1.1")}, true},
+		{"default route with other gateway", &types.Route{Dst: *mustCIDR("0.0.0.0/0"), GW: net.ParseIP("192.168.1.254")}, false},
+		{"metadata route", &types.Route{Dst: *mustCIDR("169.254.169.254/32"), GW: net.ParseIP("0.0.0.0")}, true},
+		{"ipv6 metadata route", &types.Route{Dst: *mustCIDR("fd00:ec2::254/128"), GW: net.ParseIP("::")}, true},
+		{"missing ipv6 default route", &types.Route{Dst: *mustCIDR("::/0"), GW: net.ParseIP("2001:db8::1")}, false},
+		{"missing route", &types.Route{Dst: *mustCIDR("10.0.0.0/8"), GW: net.ParseIP("192.168.1.1")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasRoute(routes, tt.route); got != tt.want {
+				t.Errorf("hasRoute() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseOVSMap(t *testing.T) {
+	tests := []struct {
+		out  string
+		want map[string]string
+	}{
+		{
+			out: `{attached-mac="00:22:33:44:55:66", iface-id="iface-net1-vethabc"}`,
+			want: map[string]string{
+				"attached-mac": "00:22:33:44:55:66",
+				"iface-id":     "iface-net1-vethabc",
+			},
+		},
+		{
+			out:  "{}",
+			want: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.out, func(t *testing.T) {
+			if got := parseOVSMap(tt.out); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOVSMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_bandwidth(t *testing.T) {
+	if got := bandwidthRateBytes(100); got != 12500000 {
+		t.Errorf("bandwidthRateBytes(100) = %d", got)
+	}
+	if got := bandwidthBurstBytes(12500000); got != 1250000 {
+		t.Errorf("bandwidthBurstBytes(12500000) = %d", got)
+	}
+	if got := bandwidthBurstBytes(1000); got != BANDWIDTH_MIN_BURST_BYTES {
+		t.Errorf("bandwidthBurstBytes(1000) = %d", got)
+	}
+	if rate, burst := ovsIngressPolicing(100); rate != 100000 || burst != 10000 {
+		t.Errorf("ovsIngressPolicing(100) = %d, %d", rate, burst)
+	}
+	if rate, burst := ovsIngressPolicing(0); rate != 0 || burst != 0 {
+		t.Errorf("ovsIngressPolicing(0) = %d, %d", rate, burst)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"strconv"
+	"strings"
 	"time"
 
 	"yunion.io/x/pkg/errors"
@@ -15,6 +17,12 @@ type OVSClient interface {
 	AddPort(bridge, port string, vlanId int) error
 	DeletePort(bridge, port string) error
 	SetIfaceId(id string, name string) error
+	SetIngressPolicing(ifName string, rateKbps, burstKb int) error
+
+	GetPortBridge(port string) (string, error)
+	GetPortTag(port string) (int, error)
+	GetIfaceId(ifName string) (string, error)
+	GetIngressPolicing(ifName string) (int, int, error)
 }
 
 func NewOVSClient() (OVSClient, error) {
@@ -53,8 +61,12 @@ func (sw *ovsClient) AddPort(bridge, port string, vlanId int) error {
 	return nil
 }
 
+func getOVNIfaceId(netId string, ifName string) string {
+	return fmt.Sprintf("iface-%s-%s", netId, ifName)
+}
+
 func (sw *ovsClient) SetIfaceId(netId string, ifName string) error {
-	ovsCmd := exec.Command("ovs-vsctl", "set", "Interface", ifName, fmt.Sprintf("external-ids:iface-id=iface-%s-%s", netId, ifName))
+	ovsCmd := exec.Command("ovs-vsctl", "set", "Interface", ifName, fmt.Sprintf("external-ids:iface-id=%s", getOVNIfaceId(netId, ifName)))
 	if out, err := ovsCmd.CombinedOutput(); err != nil {
 		return errors.Wrapf(err, "set external ids: %s", out)
 	}
@@ -67,3 +79,88 @@ func (sw *ovsClient) DeletePort(bridge, port string) error {
 		Port:   port,
 	}))
 }
+
+// SetIngressPolicing limits traffic received by OVS from the interface, which is
+// traffic sent by the pod, zero rate disables policing
+func (sw *ovsClient) SetIngressPolicing(ifName string, rateKbps, burstKb int) error {
+	args := []string{
+		"set", "Interface", ifName,
+		fmt.Sprintf("ingress_policing_rate=%d", rateKbps),
+		fmt.Sprintf("ingress_policing_burst=%d", burstKb),
+	}
+	if out, err := exec.Command("ovs-vsctl", args...).CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "set ingress policing %v: %s", args, out)
+	}
+	return nil
+}
+
+func vsctlOutput(args ...string) (string, error) {
+	out, err := exec.Command("ovs-vsctl", args...).CombinedOutput()
+	if err != nil {
+		return "", errors.Wrapf(err, "ovs-vsctl %v: %s", args, out)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (sw *ovsClient) GetPortBridge(port string) (string, error) {
+	return vsctlOutput("port-to-br", port)
+}
+
+// GetPortTag returns vlan tag of port, zero means untagged
+func (sw *ovsClient) GetPortTag(port string) (int, error) {
+	out, err := vsctlOutput("get", "Port", port, "tag")
+	if err != nil {
+		return 0, err
+	}
+	if out == "[]" {
+		return 0, nil
+	}
+	tag, err := strconv.Atoi(out)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid tag %q of port %s", out, port)
+	}
+	return tag, nil
+}
+
+func (sw *ovsClient) GetIfaceId(ifName string) (string, error) {
+	out, err := vsctlOutput("get", "Interface", ifName, "external-ids")
+	if err != nil {
+		return "", err
+	}
+	return parseOVSMap(out)["iface-id"], nil
+}
+
+func (sw *ovsClient) GetIngressPolicing(ifName string) (int, int, error) {
+	out, err := vsctlOutput("get", "Interface", ifName, "ingress_policing_rate", "ingress_policing_burst")
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 0, 0, errors.Errorf("invalid ingress policing output %q of %s", out, ifName)
+	}
+	rate, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "invalid ingress policing rate %q", fields[0])
+	}
+	burst, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "invalid ingress policing burst %q", fields[1])
+	}
+	return rate, burst, nil
+}
+
+// parseOVSMap parses map column printed by ovs-vsctl, e.g. {attached-mac="00:22:...", iface-id="iface-xxx"}
+func parseOVSMap(out string) map[string]string {
+	ret := make(map[string]string)
+	out = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(out), "{"), "}")
+	for _, pair := range strings.Split(out, ", ") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.Trim(strings.TrimSpace(kv[0]), `"`)
+		ret[key] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+	}
+	return ret
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"runtime"
+	"strings"
 
 	"github.com/containernetworking/cni/pkg/skel"
 	"github.com/containernetworking/cni/pkg/types"
@@ -214,7 +215,49 @@ func cmdDel(args *skel.CmdArgs) error {
 	return nil
 }
 
+// cmdCheck verifies pod network matches its desc, drifts of all nics are reported
+// in one CNI error so the runtime can decide to recreate the sandbox
 func cmdCheck(args *skel.CmdArgs) error {
+	_, _, err := loadNetConf(args.StdinData, args.Args)
+	if err != nil {
+		return errors.Wrap(err, "loadNetConf")
+	}
+	pod, err := NewCloudPodFromCNIArgs(args.Args)
+	if err != nil {
+		return errors.Wrap(err, "NewCloudPodFromCNIArgs")
+	}
+
+	netns, err := ns.GetNS(args.Netns)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open netns %q", args.Netns)
+	}
+	defer netns.Close()
+
+	ovsCli, err := NewOVSClient()
+	if err != nil {
+		return errors.Wrap(err, "NewOVSClient")
+	}
+
+	nics := pod.GetDesc().Nics
+	if len(nics) == 0 {
+		return fmt.Errorf("Pod %s doesn't have nics", pod.Name)
+	}
+	drifts := make([]string, 0)
+	for idx, nic := range nics {
+		nicResult, err := GenerateNetworkResultByNic(idx, nic, idx == 0)
+		if err != nil {
+			return errors.Wrapf(err, "GenerateNetworkResultByNic: %#v", nic)
+		}
+		nicDrifts, err := checkNic(ovsCli, idx, nic, netns, nicResult)
+		if err != nil {
+			return errors.Wrapf(err, "checkNic %s", nic.GetInterface(idx))
+		}
+		drifts = append(drifts, nicDrifts...)
+	}
+	if len(drifts) != 0 {
+		log.Warningf("pod %s/%s network drifts: %v", pod.Namespace, pod.Name, drifts)
+		return types.NewError(CNI_ERR_NIC_DRIFT, fmt.Sprintf("pod %s network differs from desc", pod.Name), strings.Join(drifts, "; "))
+	}
 	return nil
 }
 
@@ -227,10 +227,13 @@ func setupNic(index int, nic PodNic, netns ns.NetNS, result *current.Result) err
 
 	ctrIfname := nic.GetInterface(index)
 	//hostInterface, ctrInterface, err := setupVeth(ovsCli, index, netns, nic.Bridge)
-	_, _, err = setupVeth(ovsCli, index, nic, netns)
+	hostInterface, _, err := setupVeth(ovsCli, index, nic, netns)
 	if err != nil {
 		return errors.Wrap(err, "setupVeth")
 	}
+	if err := setupBandwidth(ovsCli, hostInterface.Name, nic.Bandwidth); err != nil {
+		return errors.Wrap(err, "setupBandwidth")
+	}
 
 	// Configure the container hardware address and IP address(es)
 	if err := netns.Do(func(_ ns.NetNS) error {
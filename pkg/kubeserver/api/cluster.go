@@ -104,6 +104,23 @@ const (
 	ClusterStatusError             = "error"
 	ClusterStatusDeleting          = "deleting"
 	ClusterStatusDeleteFail        = "delete_fail"
+	ClusterStatusUpgrading         = "upgrading"
+	ClusterStatusUpgradeFail       = "upgrade_fail"
+	ClusterStatusRestoring         = "restoring"
+	ClusterStatusRestoreFail       = "restore_fail"
+	ClusterStatusRotatingCerts     = "rotating_certs"
+	ClusterStatusRotateCertsFail   = "rotate_certs_fail"
+)
+
+const (
+	// ClusterMetadataUpgradeState records progress of the latest upgrade, used to resume a failed upgrade
+	ClusterMetadataUpgradeState = "upgrade_state"
+	// ClusterMetadataBackupPolicy records etcd backup policy of cluster
+	ClusterMetadataBackupPolicy = "backup_policy"
+	// ClusterMetadataBackupSecretKey stores object store secret key of backup policy, only visible to system admin
+	ClusterMetadataBackupSecretKey = "__sys_backup_secret_key"
+	// ClusterMetadataCertificateExpiry records result of the latest certificates expiry check
+	ClusterMetadataCertificateExpiry = "certificate_expiry"
 )
 
 type ClusterDeployAction string
@@ -256,6 +273,26 @@ type ClusterDeployInput struct {
 	SkipDownloads bool                `json:"skip_downloads"`
 }
 
+type ClusterUpgradeInput struct {
+	// Version is the target kubernetes version, must be one of the supported versions
+	// and at most one minor version newer than current
+	Version       string `json:"version"`
+	SkipDownloads bool   `json:"skip_downloads"`
+	// DrainOptions used when draining worker nodes before upgrading them
+	DrainOptions *NodeDrainInput `json:"drain_options"`
+}
+
+type ClusterUpgradeState struct {
+	FromVersion string `json:"from_version"`
+	ToVersion   string `json:"to_version"`
+	// ControlplaneUpgraded is true once control plane and etcd nodes are upgraded
+	ControlplaneUpgraded bool `json:"controlplane_upgraded"`
+	// UpgradedMachines are worker machines already upgraded, skipped when resuming
+	UpgradedMachines []string `json:"upgraded_machines"`
+	FailedMachine    string   `json:"failed_machine"`
+	Reason           string   `json:"reason"`
+}
+
 type IClusterRemoteResource interface {
 	GetKey() string
 }
@@ -447,6 +484,15 @@ type ClusterPurgeInput struct {
 	Force bool `json:"force"`
 }
 
+type ClusterDeleteMachinesInput struct {
+	// Machine id or name list
+	Machines []string `json:"machines"`
+	// Drain machines' nodes before removing them from cluster
+	Drain bool `json:"drain"`
+	// Options used when drain is enabled
+	DrainOptions *NodeDrainInput `json:"drain_options"`
+}
+
 type ClusterGetAddonsInput struct {
 	EnableNativeIPAlloc bool `json:"enable_native_ip_alloc"`
 }
@@ -459,10 +505,16 @@ type ClusterAddonsManifestConfig struct {
 	Network ClusterAddonNetworkConfig `json:"network"`
 }
 
+// ClusterKubesprayPrivateKeyDir is directory relative to inventory where private keys of hosts are saved
+const ClusterKubesprayPrivateKeyDir = "ssh_keys"
+
 type ClusterKubesprayConfig struct {
-	InventoryContent string               `json:"inventory_content"`
-	PrivateKey       string               `json:"private_key"`
-	Vars             jsonutils.JSONObject `json:"vars"`
+	InventoryContent string `json:"inventory_content"`
+	// PrivateKey is private key of first host, use PrivateKeys when hosts have different keys
+	PrivateKey string `json:"private_key"`
+	// PrivateKeys maps ansible_ssh_private_key_file of hosts in inventory to key content
+	PrivateKeys map[string]string    `json:"private_keys"`
+	Vars        jsonutils.JSONObject `json:"vars"`
 }
 
 type ClusterExtraConfig struct {
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	ClusterBackupStatusCreating    = "creating"
+	ClusterBackupStatusCreateFail  = "create_fail"
+	ClusterBackupStatusReady       = "ready"
+	ClusterBackupStatusRestoring   = "restoring"
+	ClusterBackupStatusRestoreFail = "restore_fail"
+	ClusterBackupStatusDeleting    = "deleting"
+	ClusterBackupStatusDeleteFail  = "delete_fail"
+)
+
+const (
+	// ClusterBackupTypeManual is created by user and never purged by retention policy
+	ClusterBackupTypeManual = "manual"
+	// ClusterBackupTypeScheduled is created by backup policy
+	ClusterBackupTypeScheduled = "scheduled"
+)
+
+const (
+	DefaultClusterBackupIntervalHours = 24
+	DefaultClusterBackupKeepLast      = 7
+	DefaultClusterBackupPrefix        = "kube-backups"
+)
+
+type ClusterBackupPolicy struct {
+	// Enable scheduled backup
+	Enabled bool `json:"enabled"`
+	// Scheduled backup interval in hours, default 24
+	IntervalHours int `json:"interval_hours"`
+	// Keep latest count of scheduled backups, default 7
+	KeepLast int `json:"keep_last"`
+	// Keep scheduled backups created in days, backups matched by keep_last or keep_days are kept
+	KeepDays int `json:"keep_days"`
+	// Object key prefix of snapshot, default `kube-backups`
+	Prefix string `json:"prefix"`
+	// S3 compatible storage of snapshot
+	ObjectStore *ObjectStoreConfig `json:"object_store"`
+}
+
+type ClusterBackupListInput struct {
+	apis.StatusDomainLevelResourceListInput
+
+	// Filter by cluster name or id
+	Cluster string `json:"cluster"`
+	// Filter by backup type
+	// enum: manual,scheduled
+	BackupType string `json:"backup_type"`
+}
+
+type ClusterBackupCreateInput struct {
+	apis.StatusDomainLevelResourceCreateInput
+
+	// Cluster name or id
+	// required: true
+	Cluster   string `json:"cluster"`
+	ClusterId string `json:"cluster_id"`
+	// enum: manual,scheduled
+	BackupType string `json:"backup_type"`
+}
+
+type ClusterBackupRestoreMember struct {
+	Name    string `json:"name"`
+	DataDir string `json:"data_dir"`
+	PeerURL string `json:"peer_url"`
+}
+
+// ClusterBackupRestorePlan describes what restore action will do, it should be
+// reviewed before performing restore
+type ClusterBackupRestorePlan struct {
+	Backup         string                       `json:"backup"`
+	BackupAt       time.Time                    `json:"backup_at"`
+	Cluster        string                       `json:"cluster"`
+	ClusterStatus  string                       `json:"cluster_status"`
+	Members        []ClusterBackupRestoreMember `json:"members"`
+	InitialCluster string                       `json:"initial_cluster"`
+	Steps          []string                     `json:"steps"`
+	Warnings       []string                     `json:"warnings"`
+}
+
+type ClusterBackupRestoreInput struct {
+	// Name of cluster to confirm restore, all data written after backup will be lost
+	// required: true
+	Confirm string `json:"confirm"`
+}
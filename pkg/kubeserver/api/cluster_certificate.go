@@ -0,0 +1,62 @@
+package api
+
+import (
+	"time"
+)
+
+const (
+	ClusterCertificateStatusOk       = "ok"
+	ClusterCertificateStatusWarning  = "warning"
+	ClusterCertificateStatusCritical = "critical"
+	ClusterCertificateStatusExpired  = "expired"
+)
+
+const (
+	// ClusterCertificateSourceMachine is certificate file read from machine
+	ClusterCertificateSourceMachine = "machine"
+	// ClusterCertificateSourceCA is CA stored in cluster
+	ClusterCertificateSourceCA = "ca"
+	// ClusterCertificateSourceKubeconfig is client certificate of kubeconfig stored in cluster
+	ClusterCertificateSourceKubeconfig = "kubeconfig"
+	// ClusterCertificateSourceApiServer is serving certificate of api server endpoint
+	ClusterCertificateSourceApiServer = "apiserver"
+)
+
+type ClusterCertificate struct {
+	Source string `json:"source"`
+	// Machine and Path are set when certificate is read from machine
+	Machine   string    `json:"machine"`
+	Path      string    `json:"path"`
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	IsCA      bool      `json:"is_ca"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	DaysLeft  int       `json:"days_left"`
+	Status    string    `json:"status"`
+}
+
+type ClusterMachineCertificates struct {
+	Machine      string               `json:"machine"`
+	Certificates []ClusterCertificate `json:"certificates"`
+	// Error is set when certificates of machine can't be read
+	Error string `json:"error"`
+}
+
+type ClusterCertificatesDetail struct {
+	// Status is the worst status of all certificates
+	Status         string               `json:"status"`
+	EarliestExpiry time.Time            `json:"earliest_expiry"`
+	CheckedAt      time.Time            `json:"checked_at"`
+	WarningDays    int                  `json:"warning_days"`
+	CriticalDays   int                  `json:"critical_days"`
+	Certificates   []ClusterCertificate `json:"certificates"`
+	Errors         []string             `json:"errors"`
+}
+
+// ClusterCertificateExpiryState is saved by periodic certificates expiry check
+type ClusterCertificateExpiryState struct {
+	Status         string    `json:"status"`
+	EarliestExpiry time.Time `json:"earliest_expiry"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	ContainerImageMirrorStatusReady    = "ready"
+	ContainerImageMirrorStatusSyncing  = "syncing"
+	ContainerImageMirrorStatusSyncFail = "sync_fail"
+)
+
+const (
+	ContainerImageMirrorImageStatusPending = "pending"
+	ContainerImageMirrorImageStatusCopying = "copying"
+	ContainerImageMirrorImageStatusSynced  = "synced"
+	// ContainerImageMirrorImageStatusSkipped means target already has same manifest
+	ContainerImageMirrorImageStatusSkipped = "skipped"
+	ContainerImageMirrorImageStatusFailed  = "failed"
+)
+
+type ContainerImageMirrorListInput struct {
+	apis.StatusDomainLevelResourceListInput
+
+	// Filter by source or target container registry name or id
+	ContainerRegistry string `json:"container_registry"`
+}
+
+type ContainerImageMirrorCreateInput struct {
+	apis.StatusDomainLevelResourceCreateInput
+
+	// Source container registry name or id, repositories are mirrored from source_url when it's empty
+	SourceRegistry   string `json:"source_registry"`
+	SourceRegistryId string `json:"source_registry_id"`
+	// Public registry url accessed anonymously, default is image repo kubernetes depends on
+	// example: https://registry.cn-beijing.aliyuncs.com/yunionio
+	SourceUrl string `json:"source_url"`
+
+	// Target container registry name or id
+	// required: true
+	TargetRegistry   string `json:"target_registry"`
+	TargetRegistryId string `json:"target_registry_id"`
+	// Namespace prepended to repositories in target registry, e.g. harbor project
+	TargetNamespace string `json:"target_namespace"`
+
+	// Repositories mirrored, required when source registry can't list repositories
+	// example: kubernetes-dashboard
+	Repositories []string `json:"repositories"`
+	// Regex of repositories listed from source registry
+	RepositoryPattern string `json:"repository_pattern"`
+	// Regex of tags mirrored, empty means all tags
+	TagPattern string `json:"tag_pattern"`
+	// Platforms of manifest list copied, empty means all platforms
+	// example: linux/amd64
+	Platforms []string `json:"platforms"`
+
+	// Scheduled sync interval in hours, 0 means only sync on demand
+	IntervalHours int `json:"interval_hours"`
+}
+
+type ContainerImageMirrorUpdateInput struct {
+	apis.StatusDomainLevelResourceBaseUpdateInput
+
+	RepositoryPattern *string `json:"repository_pattern"`
+	TagPattern        *string `json:"tag_pattern"`
+	IntervalHours     *int    `json:"interval_hours"`
+}
+
+type ContainerImageMirrorImageProgress struct {
+	Source    string    `json:"source"`
+	Target    string    `json:"target"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ContainerImageMirrorProgress struct {
+	StartedAt  time.Time                           `json:"started_at"`
+	FinishedAt time.Time                           `json:"finished_at"`
+	Total      int                                 `json:"total"`
+	Synced     int                                 `json:"synced"`
+	Skipped    int                                 `json:"skipped"`
+	Failed     int                                 `json:"failed"`
+	Errors     []string                            `json:"errors"`
+	Images     []ContainerImageMirrorImageProgress `json:"images"`
+}
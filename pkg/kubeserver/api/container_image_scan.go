@@ -0,0 +1,128 @@
+package api
+
+import (
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	// ContainerRegistryMetadataScanner keeps scanner config including token, only visible to system admin
+	ContainerRegistryMetadataScanner = "__sys_scanner"
+
+	// ContainerImageScannerTypeAdapter is scanner implementing harbor pluggable scanner api,
+	// e.g. https://github.com/aquasecurity/harbor-scanner-trivy
+	ContainerImageScannerTypeAdapter = "adapter"
+	// ContainerImageScannerTypeHarbor is scanner configured inside harbor
+	ContainerImageScannerTypeHarbor = "harbor"
+)
+
+const (
+	ContainerImageScanStatusScanning = "scanning"
+	ContainerImageScanStatusReady    = "ready"
+	ContainerImageScanStatusScanFail = "scan_fail"
+)
+
+const (
+	VulnerabilitySeverityCritical = "Critical"
+	VulnerabilitySeverityHigh     = "High"
+	VulnerabilitySeverityMedium   = "Medium"
+	VulnerabilitySeverityLow      = "Low"
+	VulnerabilitySeverityUnknown  = "Unknown"
+	VulnerabilitySeverityNone     = "None"
+)
+
+// VulnerabilitySeverities are ordered from most to least severe
+var VulnerabilitySeverities = []string{
+	VulnerabilitySeverityCritical,
+	VulnerabilitySeverityHigh,
+	VulnerabilitySeverityMedium,
+	VulnerabilitySeverityLow,
+	VulnerabilitySeverityUnknown,
+	VulnerabilitySeverityNone,
+}
+
+type ContainerRegistryScannerConfig struct {
+	// Scanner type, harbor registry uses its own scanner when not set
+	// enum: adapter
+	Type string `json:"type"`
+	// Scanner adapter url
+	// example: http://harbor-scanner-trivy:8080
+	Url string `json:"url"`
+	// Bearer token sent to scanner adapter
+	Token string `json:"token"`
+	// Skip verifying tls certificate of scanner adapter
+	Insecure bool `json:"insecure"`
+}
+
+type ContainerRegistryScanImageInput struct {
+	// required: true
+	Repository string `json:"repository"`
+	// required: true
+	Tag string `json:"tag"`
+}
+
+type ContainerImageVulnerability struct {
+	Id          string   `json:"id"`
+	Package     string   `json:"package"`
+	Version     string   `json:"version"`
+	FixVersion  string   `json:"fix_version"`
+	Severity    string   `json:"severity"`
+	Description string   `json:"description"`
+	Links       []string `json:"links"`
+}
+
+type ContainerImageVulnerabilitySummary struct {
+	Scanner string `json:"scanner"`
+	// Highest severity of vulnerabilities
+	Severity string `json:"severity"`
+	Total    int    `json:"total"`
+	Fixable  int    `json:"fixable"`
+	// Count of vulnerabilities by severity
+	Summary   map[string]int `json:"summary"`
+	ScannedAt time.Time      `json:"scanned_at"`
+}
+
+type ContainerImageVulnerabilityReport struct {
+	ContainerImageVulnerabilitySummary
+
+	Digest          string                        `json:"digest"`
+	Vulnerabilities []ContainerImageVulnerability `json:"vulnerabilities"`
+}
+
+type ContainerImageScanListInput struct {
+	apis.StatusStandaloneResourceListInput
+
+	// Container registry name or id
+	ContainerRegistry string `json:"container_registry"`
+	Repository        string `json:"repository"`
+	// Filter by highest severity
+	Severity []string `json:"severity"`
+}
+
+type ClusterImageVulnerabilityReportInput struct {
+	// Only report images with vulnerabilities of severity
+	Severity []string `json:"severity"`
+}
+
+type ClusterImagePodRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Container string `json:"container"`
+}
+
+type ClusterImageVulnerability struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+	// Scanned is false when image is not found in scanned images of container registries
+	Scanned bool                                `json:"scanned"`
+	Summary *ContainerImageVulnerabilitySummary `json:"summary,omitempty"`
+	Pods    []ClusterImagePodRef                `json:"pods"`
+}
+
+type ClusterImageVulnerabilityReport struct {
+	Images []ClusterImageVulnerability `json:"images"`
+	// Count of vulnerabilities by severity of all scanned images
+	Summary   map[string]int `json:"summary"`
+	Unscanned int            `json:"unscanned"`
+}
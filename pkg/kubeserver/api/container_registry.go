@@ -1,6 +1,8 @@
 package api
 
 import (
+	"time"
+
 	"yunion.io/x/onecloud/pkg/apis"
 )
 
@@ -53,6 +55,10 @@ type ContainerRegistryCreateInput struct {
 
 	// Configuration info
 	Config ContainerRegistryConfig `json:"config"`
+
+	// Skip verifying certificate of registry when mirroring images
+	// required: false
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
 }
 
 type ContainerRegistryUploadImageInput struct {
@@ -82,3 +88,62 @@ type ContainerRegistryListImagesInput struct {
 	Details        bool   `json:"details"`
 	RepositoryName string `json:"repository_name"`
 }
+
+type ContainerRegistryDeleteImageTagInput struct {
+	// Repository is the path on server, e.g. 'yunion/influxdb'
+	Repository string `json:"repository"`
+	// Tag is removed alone, manifest referenced by other tags is kept
+	Tag string `json:"tag"`
+	// Digest removes manifest along with all tags referencing it, e.g. 'sha256:...'
+	Digest string `json:"digest"`
+}
+
+const (
+	ContainerRegistryMetadataRetentionPolicy     = "retention_policy"
+	ContainerRegistryMetadataRetentionLastReport = "retention_last_report"
+
+	DefaultContainerRegistryRetentionIntervalHours = 24
+)
+
+type ContainerRegistryRetentionPolicy struct {
+	// Enable scheduled retention
+	Enabled bool `json:"enabled"`
+	// Scheduled retention interval in hours, default 24
+	IntervalHours int `json:"interval_hours"`
+	// Regex of repositories the policy applies to, empty means all repositories
+	RepositoryPattern string `json:"repository_pattern"`
+	// Keep latest count of tags by creation time in each repository
+	KeepLast int `json:"keep_last"`
+	// Regex of tags always kept, e.g. '^(latest|v\d+\.\d+\.\d+)$'
+	KeepTagPattern string `json:"keep_tag_pattern"`
+	// Tags created in days are kept, tags kept by none of keep_last, keep_tag_pattern and max_age_days are removed,
+	// nothing is removed when both keep_last and max_age_days are 0
+	MaxAgeDays int `json:"max_age_days"`
+	// Trigger registry garbage collection after tags are removed
+	GarbageCollect bool `json:"garbage_collect"`
+}
+
+type ContainerRegistryApplyRetentionInput struct {
+	// Only report what would be removed
+	DryRun bool `json:"dry_run"`
+}
+
+type ContainerRegistryRetentionItem struct {
+	Repository string    `json:"repository"`
+	Tag        string    `json:"tag"`
+	Digest     string    `json:"digest"`
+	Created    time.Time `json:"created"`
+	// Manifest is removed when all tags referencing it are removed
+	ManifestRemoved bool `json:"manifest_removed"`
+}
+
+type ContainerRegistryRetentionReport struct {
+	DryRun    bool      `json:"dry_run"`
+	StartedAt time.Time `json:"started_at"`
+	// Count of tags kept by policy
+	Kept    int                              `json:"kept"`
+	Removed []ContainerRegistryRetentionItem `json:"removed"`
+	Errors  []string                         `json:"errors"`
+	// Garbage collection triggered after cleanup
+	GarbageCollected bool `json:"garbage_collected"`
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"yunion.io/x/jsonutils"
+)
+
+type ClusterCustomResourceDefinitionInput struct {
+	// Name of crd, e.g. virtualmachines.kubevirt.io
+	Name string `json:"name"`
+}
+
+type CustomResourceDefinitionVersion struct {
+	Name       string `json:"name"`
+	Served     bool   `json:"served"`
+	Storage    bool   `json:"storage"`
+	Deprecated bool   `json:"deprecated"`
+	// Columns printed when listing resources of this version
+	AdditionalPrinterColumns []apiextensionsv1.CustomResourceColumnDefinition `json:"additionalPrinterColumns"`
+	// OpenAPI v3 schema of this version, only returned when getting single crd
+	Schema *apiextensionsv1.JSONSchemaProps `json:"schema,omitempty"`
+}
+
+type CustomResourceDefinition struct {
+	Name       string   `json:"name"`
+	Group      string   `json:"group"`
+	Kind       string   `json:"kind"`
+	Plural     string   `json:"plural"`
+	Singular   string   `json:"singular"`
+	ShortNames []string `json:"shortNames"`
+	Categories []string `json:"categories"`
+	// Namespaced or Cluster
+	Scope       string                            `json:"scope"`
+	Established bool                              `json:"established"`
+	Versions    []CustomResourceDefinitionVersion `json:"versions"`
+}
+
+type ClusterCustomResourceInput struct {
+	// Name of crd
+	Crd string `json:"crd"`
+	// Served version of crd, default is storage version
+	Version string `json:"version"`
+	// Namespace of resource, it's ignored when crd is cluster scoped
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type ClusterCustomResourceListInput struct {
+	ClusterCustomResourceInput
+
+	LabelSelector string `json:"label_selector"`
+}
+
+type ClusterCustomResourceCreateInput struct {
+	ClusterCustomResourceInput
+
+	// Custom resource object, apiVersion, kind, name and namespace are filled by input
+	Object jsonutils.JSONObject `json:"object"`
+}
+
+type ClusterCustomResourceUpdateInput struct {
+	ClusterCustomResourceCreateInput
+}
+
+type ClusterCustomResourceDeleteInput struct {
+	ClusterCustomResourceInput
+}
+
+type CustomResource struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	// Values of columns, missing value is null
+	Cells []interface{} `json:"cells"`
+}
+
+type CustomResourceList struct {
+	Kind       string                                           `json:"kind"`
+	APIVersion string                                           `json:"apiVersion"`
+	Columns    []apiextensionsv1.CustomResourceColumnDefinition `json:"columns"`
+	Items      []CustomResource                                 `json:"items"`
+}
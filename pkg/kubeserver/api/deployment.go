@@ -178,4 +178,10 @@ type DeploymentDetailV2 struct {
 	MinReadySeconds int32 `json:"minReadySeconds"`
 	// Optional field that specifies the number of old Replica Sets to retain to allow rollback.
 	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit"`
+	// Horizontal pod autoscalers targeting this deployment
+	HorizontalPodAutoscalers []HorizontalPodAutoscaler `json:"horizontalPodAutoscalers"`
+	// Pod disruption budgets selecting pods of this deployment
+	PodDisruptionBudgets []string `json:"podDisruptionBudgets"`
+	// Current usage summed by pods of this deployment
+	Usage *ResourceUsage `json:"usage,omitempty"`
 }
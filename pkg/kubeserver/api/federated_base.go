@@ -15,6 +15,9 @@ const (
 
 type FederatedResourceCreateInput struct {
 	K8sResourceCreateInput
+	// DriftPolicy is how to handle member cluster objects changed outside, choices: report|enforce
+	// default: report
+	DriftPolicy string `json:"drift_policy"`
 }
 
 type FederatedResourceJointClusterInput struct {
@@ -86,6 +89,7 @@ type FedNamespaceJointClusterResourceDetails struct {
 
 type FedResourceUpdateInput struct {
 	apis.StatusDomainLevelResourceBaseUpdateInput
+	DriftPolicy string `json:"drift_policy"`
 }
 
 type FedNamespaceResourceUpdateInput struct {
@@ -0,0 +1,59 @@
+package api
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/jsonutils"
+)
+
+type FederatedConfigMapSpec struct {
+	Template ConfigMapTemplate `json:"template"`
+	FederatedPolicySpec
+}
+
+type ConfigMapTemplate struct {
+	Metadata FederatedObjectMeta `json:"metadata"`
+	Data     map[string]string   `json:"data"`
+}
+
+func (spec *FederatedConfigMapSpec) String() string {
+	return jsonutils.Marshal(spec).String()
+}
+
+func (spec *FederatedConfigMapSpec) IsZero() bool {
+	if spec == nil {
+		return true
+	}
+	return false
+}
+
+func (spec *FederatedConfigMapSpec) ToK8sObject(objMeta metav1.ObjectMeta) (runtime.Object, error) {
+	return spec.ToConfigMap(objMeta), nil
+}
+
+func (spec *FederatedConfigMapSpec) ToConfigMap(objMeta metav1.ObjectMeta) *corev1.ConfigMap {
+	data := make(map[string]string, len(spec.Template.Data))
+	for k, v := range spec.Template.Data {
+		data[k] = v
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: spec.Template.Metadata.ToObjectMeta(objMeta),
+		Data:       data,
+	}
+}
+
+type FederatedConfigMapCreateInput struct {
+	FederatedNamespaceResourceCreateInput
+	Spec *FederatedConfigMapSpec `json:"spec"`
+}
+
+func (input FederatedConfigMapCreateInput) ToConfigMap(namespace string) *corev1.ConfigMap {
+	return input.Spec.ToConfigMap(input.ToObjectMeta(namespace))
+}
+
+type FederatedConfigMapUpdateInput struct {
+	FedNamespaceResourceUpdateInput
+	Spec *FederatedConfigMapSpec `json:"spec"`
+}
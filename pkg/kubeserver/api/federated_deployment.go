@@ -0,0 +1,55 @@
+package api
+
+import (
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/jsonutils"
+)
+
+type FederatedDeploymentSpec struct {
+	Template DeploymentTemplate `json:"template"`
+	FederatedPolicySpec
+}
+
+type DeploymentTemplate struct {
+	Metadata FederatedObjectMeta `json:"metadata"`
+	Spec     apps.DeploymentSpec `json:"spec"`
+}
+
+func (spec *FederatedDeploymentSpec) String() string {
+	return jsonutils.Marshal(spec).String()
+}
+
+func (spec *FederatedDeploymentSpec) IsZero() bool {
+	if spec == nil {
+		return true
+	}
+	return false
+}
+
+func (spec *FederatedDeploymentSpec) ToK8sObject(objMeta metav1.ObjectMeta) (runtime.Object, error) {
+	return spec.ToDeployment(objMeta), nil
+}
+
+func (spec *FederatedDeploymentSpec) ToDeployment(objMeta metav1.ObjectMeta) *apps.Deployment {
+	return &apps.Deployment{
+		ObjectMeta: spec.Template.Metadata.ToObjectMeta(objMeta),
+		Spec:       *spec.Template.Spec.DeepCopy(),
+	}
+}
+
+type FederatedDeploymentCreateInput struct {
+	FederatedNamespaceResourceCreateInput
+	Spec *FederatedDeploymentSpec `json:"spec"`
+}
+
+func (input FederatedDeploymentCreateInput) ToDeployment(namespace string) *apps.Deployment {
+	return input.Spec.ToDeployment(input.ToObjectMeta(namespace))
+}
+
+type FederatedDeploymentUpdateInput struct {
+	FedNamespaceResourceUpdateInput
+	Spec *FederatedDeploymentSpec `json:"spec"`
+}
@@ -0,0 +1,18 @@
+package api
+
+const (
+	// FederatedDriftPolicyReport only records drift of member cluster objects
+	FederatedDriftPolicyReport = "report"
+	// FederatedDriftPolicyEnforce re-applies federated template when member cluster object drifted
+	FederatedDriftPolicyEnforce = "enforce"
+
+	FederatedDriftStatusUnknown = "unknown"
+	FederatedDriftStatusInSync  = "in_sync"
+	FederatedDriftStatusDrifted = "drifted"
+	FederatedDriftStatusMissing = "missing"
+)
+
+var FederatedDriftPolicies = []string{
+	FederatedDriftPolicyReport,
+	FederatedDriftPolicyEnforce,
+}
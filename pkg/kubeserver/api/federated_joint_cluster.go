@@ -15,6 +15,8 @@ type FedJointClusterListInput struct {
 	NamespaceId         string `json:"namespace_id"`
 	ResourceId          string `json:"resource_id"`
 	ResourceName        string `json:"resource_name"`
+	// DriftStatus filter by drift status, choices: unknown|in_sync|drifted|missing
+	DriftStatus []string `json:"drift_status"`
 }
 
 type FedNamespaceJointClusterListInput struct {
@@ -0,0 +1,121 @@
+package api
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/jsonutils"
+)
+
+// FederatedObjectMeta is metadata of template, name and namespace are set by federated resource
+type FederatedObjectMeta struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+func (meta FederatedObjectMeta) ToObjectMeta(objMeta metav1.ObjectMeta) metav1.ObjectMeta {
+	if len(meta.Labels) != 0 {
+		if objMeta.Labels == nil {
+			objMeta.Labels = make(map[string]string)
+		}
+		for k, v := range meta.Labels {
+			objMeta.Labels[k] = v
+		}
+	}
+	if len(meta.Annotations) != 0 {
+		if objMeta.Annotations == nil {
+			objMeta.Annotations = make(map[string]string)
+		}
+		for k, v := range meta.Annotations {
+			objMeta.Annotations[k] = v
+		}
+	}
+	return objMeta
+}
+
+// FederatedPlacementPolicy selects clusters the federated resource propagated to,
+// clusters of the explicit list and clusters whose tags matching selector are both selected
+type FederatedPlacementPolicy struct {
+	// Clusters is id or name of clusters, stored as id after validated
+	Clusters []string `json:"clusters"`
+	// ClusterSelector matches user tags of clusters
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector"`
+}
+
+func (p *FederatedPlacementPolicy) IsEmpty() bool {
+	return p == nil || (len(p.Clusters) == 0 && p.ClusterSelector == nil)
+}
+
+// FederatedOverridePatch is an operation of RFC 6902 json patch, path is relative to the rendered object,
+// e.g. /spec/replicas or /spec/template/spec/containers/0/image
+type FederatedOverridePatch struct {
+	Op    string               `json:"op"`
+	Path  string               `json:"path"`
+	Value jsonutils.JSONObject `json:"value"`
+}
+
+// FederatedClusterOverride is patches applied to object propagated to one cluster
+type FederatedClusterOverride struct {
+	// Cluster is id or name of cluster, stored as id after validated
+	Cluster string                   `json:"cluster"`
+	Patches []FederatedOverridePatch `json:"patches"`
+}
+
+// FederatedPolicySpec is placement and overrides of federated resource which is applied as kubernetes object.
+// Clusters are attached and detached automatically by placement when it's set,
+// otherwise clusters are managed by attach-cluster and detach-cluster actions.
+type FederatedPolicySpec struct {
+	Placement *FederatedPlacementPolicy  `json:"placement"`
+	Overrides []FederatedClusterOverride `json:"overrides"`
+}
+
+func (spec *FederatedPolicySpec) GetPolicy() *FederatedPolicySpec {
+	return spec
+}
+
+// GetClusterOverride returns patches of cluster
+func (spec FederatedPolicySpec) GetClusterOverride(clusterId string) []FederatedOverridePatch {
+	for _, o := range spec.Overrides {
+		if o.Cluster == clusterId {
+			return o.Patches
+		}
+	}
+	return nil
+}
+
+// IFederatedObjectSpec is spec of federated resource rendered to kubernetes object
+type IFederatedObjectSpec interface {
+	IsZero() bool
+	GetPolicy() *FederatedPolicySpec
+	ToK8sObject(objMeta metav1.ObjectMeta) (runtime.Object, error)
+}
+
+// FederatedClusterStatus is status of resource propagated to member cluster
+type FederatedClusterStatus struct {
+	ClusterId  string `json:"cluster_id"`
+	Cluster    string `json:"cluster"`
+	ResourceId string `json:"resource_id"`
+	Status     string `json:"status"`
+	// DriftStatus is result of comparing member object with federated template
+	DriftStatus string `json:"drift_status"`
+
+	// replicas are only set for workloads
+	DesiredReplicas *int32 `json:"desired_replicas,omitempty"`
+	ReadyReplicas   *int32 `json:"ready_replicas,omitempty"`
+}
+
+// FederatedObjectDetails rolls up status of every member cluster
+type FederatedObjectDetails struct {
+	FederatedNamespaceResourceDetails
+
+	ClusterStatuses []FederatedClusterStatus `json:"cluster_statuses"`
+	// NotBindClusterCount is count of attached clusters the resource not propagated to yet
+	NotBindClusterCount int `json:"not_bind_cluster_count"`
+	// DesiredReplicas and ReadyReplicas are sum of member clusters, only set for workloads in details
+	DesiredReplicas *int32 `json:"desired_replicas,omitempty"`
+	ReadyReplicas   *int32 `json:"ready_replicas,omitempty"`
+}
+
+type FedObjectClusterDetails struct {
+	FedJointClusterResourceDetails
+}
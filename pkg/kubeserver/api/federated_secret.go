@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/base64"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+)
+
+type FederatedSecretSpec struct {
+	Template SecretTemplate `json:"template"`
+	FederatedPolicySpec
+}
+
+type SecretTemplate struct {
+	Metadata FederatedObjectMeta `json:"metadata"`
+	Type     corev1.SecretType   `json:"type"`
+	// Data values are base64 encoded like kubernetes secret
+	Data map[string]string `json:"data"`
+	// StringData values are plain text and merged into data
+	StringData map[string]string `json:"stringData"`
+}
+
+func (spec *FederatedSecretSpec) String() string {
+	return jsonutils.Marshal(spec).String()
+}
+
+func (spec *FederatedSecretSpec) IsZero() bool {
+	if spec == nil {
+		return true
+	}
+	return false
+}
+
+func (spec *FederatedSecretSpec) ToK8sObject(objMeta metav1.ObjectMeta) (runtime.Object, error) {
+	return spec.ToSecret(objMeta)
+}
+
+func (spec *FederatedSecretSpec) ToSecret(objMeta metav1.ObjectMeta) (*corev1.Secret, error) {
+	data := make(map[string][]byte, len(spec.Template.Data)+len(spec.Template.StringData))
+	for k, v := range spec.Template.Data {
+		val, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode data %q", k)
+		}
+		data[k] = val
+	}
+	for k, v := range spec.Template.StringData {
+		data[k] = []byte(v)
+	}
+	secretType := spec.Template.Type
+	if secretType == "" {
+		secretType = corev1.SecretTypeOpaque
+	}
+	return &corev1.Secret{
+		ObjectMeta: spec.Template.Metadata.ToObjectMeta(objMeta),
+		Type:       secretType,
+		Data:       data,
+	}, nil
+}
+
+type FederatedSecretCreateInput struct {
+	FederatedNamespaceResourceCreateInput
+	Spec *FederatedSecretSpec `json:"spec"`
+}
+
+func (input FederatedSecretCreateInput) ToSecret(namespace string) (*corev1.Secret, error) {
+	return input.Spec.ToSecret(input.ToObjectMeta(namespace))
+}
+
+type FederatedSecretUpdateInput struct {
+	FedNamespaceResourceUpdateInput
+	Spec *FederatedSecretSpec `json:"spec"`
+}
+
+type FederatedSecretDetails struct {
+	FederatedObjectDetails
+	// Spec is stored encrypted, data values are masked in details
+	Spec *FederatedSecretSpec `json:"spec"`
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/jsonutils"
+)
+
+type FederatedServiceSpec struct {
+	Template ServiceTemplate `json:"template"`
+	FederatedPolicySpec
+}
+
+type ServiceTemplate struct {
+	Metadata FederatedObjectMeta `json:"metadata"`
+	// Spec clusterIP is allocated by each cluster and should not be set
+	Spec corev1.ServiceSpec `json:"spec"`
+}
+
+func (spec *FederatedServiceSpec) String() string {
+	return jsonutils.Marshal(spec).String()
+}
+
+func (spec *FederatedServiceSpec) IsZero() bool {
+	if spec == nil {
+		return true
+	}
+	return false
+}
+
+func (spec *FederatedServiceSpec) ToK8sObject(objMeta metav1.ObjectMeta) (runtime.Object, error) {
+	return spec.ToService(objMeta), nil
+}
+
+func (spec *FederatedServiceSpec) ToService(objMeta metav1.ObjectMeta) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: spec.Template.Metadata.ToObjectMeta(objMeta),
+		Spec:       *spec.Template.Spec.DeepCopy(),
+	}
+}
+
+type FederatedServiceCreateInput struct {
+	FederatedNamespaceResourceCreateInput
+	Spec *FederatedServiceSpec `json:"spec"`
+}
+
+func (input FederatedServiceCreateInput) ToService(namespace string) *corev1.Service {
+	return input.Spec.ToService(input.ToObjectMeta(namespace))
+}
+
+type FederatedServiceUpdateInput struct {
+	FedNamespaceResourceUpdateInput
+	Spec *FederatedServiceSpec `json:"spec"`
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/jsonutils"
+)
+
+type FederatedStatefulSetSpec struct {
+	Template StatefulSetTemplate `json:"template"`
+	FederatedPolicySpec
+}
+
+type StatefulSetTemplate struct {
+	Metadata FederatedObjectMeta  `json:"metadata"`
+	Spec     apps.StatefulSetSpec `json:"spec"`
+}
+
+func (spec *FederatedStatefulSetSpec) String() string {
+	return jsonutils.Marshal(spec).String()
+}
+
+func (spec *FederatedStatefulSetSpec) IsZero() bool {
+	if spec == nil {
+		return true
+	}
+	return false
+}
+
+func (spec *FederatedStatefulSetSpec) ToK8sObject(objMeta metav1.ObjectMeta) (runtime.Object, error) {
+	return spec.ToStatefulSet(objMeta), nil
+}
+
+func (spec *FederatedStatefulSetSpec) ToStatefulSet(objMeta metav1.ObjectMeta) *apps.StatefulSet {
+	return &apps.StatefulSet{
+		ObjectMeta: spec.Template.Metadata.ToObjectMeta(objMeta),
+		Spec:       *spec.Template.Spec.DeepCopy(),
+	}
+}
+
+type FederatedStatefulSetCreateInput struct {
+	FederatedNamespaceResourceCreateInput
+	Spec *FederatedStatefulSetSpec `json:"spec"`
+}
+
+func (input FederatedStatefulSetCreateInput) ToStatefulSet(namespace string) *apps.StatefulSet {
+	return input.Spec.ToStatefulSet(input.ToObjectMeta(namespace))
+}
+
+type FederatedStatefulSetUpdateInput struct {
+	FedNamespaceResourceUpdateInput
+	Spec *FederatedStatefulSetSpec `json:"spec"`
+}
@@ -18,10 +18,55 @@ const (
 	ReleaseStatusDeleteFail = "delete_fail"
 )
 
+const (
+	ReleaseSourceRepo = "repo"
+	ReleaseSourceGit  = "git"
+
+	// ReleaseMetadataGitCredential stores credential of git source, only visible to system admin
+	ReleaseMetadataGitCredential = "__sys_git_credential"
+)
+
+type ReleaseGitSource struct {
+	// Git repository url, only https, ssh and git schemes are allowed,
+	// file scheme is allowed when server enables release_git_allow_local_repository,
+	// credentials embedded in url are moved to git_credential
+	// example: https://github.com/yunionio/charts.git
+	Url string `json:"url"`
+	// Branch to track
+	// default: master
+	Branch string `json:"branch"`
+	// Path is directory inside repository holding chart and/or values files,
+	// chart is loaded from it when Chart.yaml exists, otherwise chart comes from repo
+	Path string `json:"path"`
+	// ValuesFiles are relative to path, latter overrides former
+	// default: values.yaml
+	ValuesFiles []string `json:"values_files"`
+}
+
+// ReleaseGitCredential is http basic auth of private git repository
+type ReleaseGitCredential struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type ReleaseGitSyncInput struct {
+	// upgrade release even if commit is not changed
+	Force bool `json:"force"`
+}
+
 type ReleaseCreateInput struct {
 	NamespaceResourceCreateInput
+	// Source of chart and values
+	// enum: repo, git
+	// default: repo
+	Source    string            `json:"source"`
+	GitSource *ReleaseGitSource `json:"git_source"`
+	// GitCredential is saved apart from git source and never returned
+	GitCredential *ReleaseGitCredential `json:"git_credential"`
+
 	Repo string `json:"repo"`
 	// Deprecated, use Chart and Repo
+	// oci reference like oci://registry/path/chart:version is resolved to oci repo
 	ChartName string `json:"chart_name"`
 	Chart     string `json:"chart"`
 	// Deprecated, use name
@@ -44,6 +89,40 @@ type ReleaseUpdateInput struct {
 	ResetValues bool `json:"reset_values"`
 	// when upgrading, reuse the last release's values and merge in any overrides, if reset_values is specified, this is ignored
 	ReUseValues bool `json:"reuse_values"`
+	// only preview the upgrade, return rendered changes without applying them
+	DryRun bool `json:"dry_run"`
+}
+
+const (
+	ReleaseResourceActionAdded     = "added"
+	ReleaseResourceActionRemoved   = "removed"
+	ReleaseResourceActionChanged   = "changed"
+	ReleaseResourceActionUnchanged = "unchanged"
+)
+
+type ReleaseResourceDiff struct {
+	ApiVersion string `json:"api_version"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	// Action is one of added, removed, changed and unchanged
+	Action string `json:"action"`
+	// Diff is unified diff between deployed and rendered manifest
+	Diff string `json:"diff,omitempty"`
+	// ImmutableChanges are fields can't be updated in place, such as selector of workloads
+	ImmutableChanges []string `json:"immutable_changes,omitempty"`
+}
+
+type ReleaseUpgradePreview struct {
+	// CurrentRevision is the deployed release revision compared with
+	CurrentRevision int    `json:"current_revision"`
+	CurrentChart    string `json:"current_chart"`
+	Chart           string `json:"chart"`
+	// SchemaErrors are failures of validating merged values against chart values.schema.json
+	SchemaErrors []string              `json:"schema_errors"`
+	Resources    []ReleaseResourceDiff `json:"resources"`
+	// Warnings summarize removed resources and immutable field changes need review
+	Warnings []string `json:"warnings"`
 }
 
 type ReleaseListQuery struct {
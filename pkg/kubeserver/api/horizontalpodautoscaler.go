@@ -0,0 +1,59 @@
+package api
+
+import (
+	autoscaling "k8s.io/api/autoscaling/v2beta2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HorizontalPodAutoscalerDefaultCPUUtilization is used when hpa is created without metrics,
+	// same as kube-controller-manager
+	HorizontalPodAutoscalerDefaultCPUUtilization int32 = 80
+)
+
+// HorizontalPodAutoscalerCreateInput creates autoscaling/v2 hpa,
+// the v2beta2 types share same schema with autoscaling/v2
+type HorizontalPodAutoscalerCreateInput struct {
+	NamespaceResourceCreateInput
+	autoscaling.HorizontalPodAutoscalerSpec
+}
+
+type HorizontalPodAutoscalerUpdateInput struct {
+	// Lower limit for the number of replicas, default is 1
+	MinReplicas *int32 `json:"minReplicas"`
+	// Upper limit for the number of replicas
+	MaxReplicas *int32 `json:"maxReplicas"`
+	// Metrics used to calculate the desired replica count, cpu and memory resources,
+	// pods, object and external custom metrics are supported
+	Metrics []autoscaling.MetricSpec `json:"metrics"`
+	// Scaling behavior in both up and down directions
+	Behavior *autoscaling.HorizontalPodAutoscalerBehavior `json:"behavior"`
+}
+
+type HorizontalPodAutoscalerDetail struct {
+	NamespaceResourceDetail
+
+	ScaleTargetRef autoscaling.CrossVersionObjectReference      `json:"scaleTargetRef"`
+	MinReplicas    *int32                                       `json:"minReplicas"`
+	MaxReplicas    int32                                        `json:"maxReplicas"`
+	Metrics        []autoscaling.MetricSpec                     `json:"metrics"`
+	Behavior       *autoscaling.HorizontalPodAutoscalerBehavior `json:"behavior,omitempty"`
+
+	// Current number of replicas of pods managed by this autoscaler
+	CurrentReplicas int32 `json:"currentReplicas"`
+	// Desired number of replicas of pods managed by this autoscaler
+	DesiredReplicas int32 `json:"desiredReplicas"`
+	// Last time the autoscaler scaled the number of pods
+	LastScaleTime  *metav1.Time                                   `json:"lastScaleTime,omitempty"`
+	CurrentMetrics []autoscaling.MetricStatus                     `json:"currentMetrics"`
+	Conditions     []autoscaling.HorizontalPodAutoscalerCondition `json:"conditions"`
+}
+
+// HorizontalPodAutoscaler is the brief of hpa shown in details of the scale target
+type HorizontalPodAutoscaler struct {
+	Name            string `json:"name"`
+	MinReplicas     *int32 `json:"minReplicas"`
+	MaxReplicas     int32  `json:"maxReplicas"`
+	CurrentReplicas int32  `json:"currentReplicas"`
+	DesiredReplicas int32  `json:"desiredReplicas"`
+}
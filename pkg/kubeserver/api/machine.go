@@ -41,6 +41,19 @@ type MachineCreateConfig struct {
 	ImageRepository *ImageRepository       `json:"image_repository"`
 	DockerConfig    *DockerConfig          `json:"docker_config"`
 	Vm              *MachineCreateVMConfig `json:"vm,omitempty"`
+	// Host is config of existing linux host registered by address
+	Host *MachineCreateHostConfig `json:"host,omitempty"`
+}
+
+// MachineCreateHostConfig is ssh login of existing host,
+// the user should be root or able to sudo without password
+type MachineCreateHostConfig struct {
+	Username   string `json:"username"`
+	Password   string `json:"password,omitempty"`
+	PrivateKey string `json:"private_key,omitempty"`
+	// SSH port of host
+	// default: 22
+	Port int `json:"port,omitempty"`
 }
 
 type MachineCreateVMConfig struct {
@@ -84,12 +97,32 @@ const (
 	DefaultVMMemSize      = 2048       // 2G
 	DefaultVMCPUCount     = 2          // 2 core
 	DefaultVMRootDiskSize = 100 * 1024 // 100G
+
+	DefaultMachineHostSSHPort = 22
 )
 
 const (
 	MachineMetadataCreateParams = "create_params"
+	// MachineMetadataSSHLogin stores host ssh login, only visible to system admin
+	MachineMetadataSSHLogin = "__sys_ssh_login"
+	// MachineMetadataPreflightChecks stores results of host pre-flight checks
+	MachineMetadataPreflightChecks = "preflight_checks"
+)
+
+const (
+	MachinePreflightCheckPass = "pass"
+	MachinePreflightCheckWarn = "warn"
+	MachinePreflightCheckFail = "fail"
 )
 
+type MachinePreflightCheck struct {
+	// Name of check item, e.g. os, swap, port
+	Name string `json:"name"`
+	// Status is one of pass, warn and fail
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
 type MachineListInput struct {
 	apis.VirtualResourceListInput
 
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"time"
+)
+
+const (
+	UsageTimeSeriesDefaultRange = time.Hour
+	UsageTimeSeriesDefaultStep  = time.Minute
+	// Prometheus rejects range query whose points exceed 11000
+	UsageTimeSeriesMaxPoints = 11000
+)
+
+// ResourceUsage is current cpu and memory usage reported by metrics.k8s.io
+type ResourceUsage struct {
+	// CPUUsage is number of used millicores
+	CPUUsage int64 `json:"cpuUsage"`
+	// CPUUsageFraction is a fraction of node CPU capacity, only set for node
+	CPUUsageFraction float64 `json:"cpuUsageFraction,omitempty"`
+	// MemoryUsage is working set memory in bytes
+	MemoryUsage int64 `json:"memoryUsage"`
+	// MemoryUsageFraction is a fraction of node memory capacity, only set for node
+	MemoryUsageFraction float64 `json:"memoryUsageFraction,omitempty"`
+	// Count of pods whose metrics are summed, only set for workload and namespace
+	Pods int `json:"pods,omitempty"`
+	// Time of the latest sample
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type ContainerResourceUsage struct {
+	Name        string `json:"name"`
+	CPUUsage    int64  `json:"cpuUsage"`
+	MemoryUsage int64  `json:"memoryUsage"`
+}
+
+type ClusterUsageTimeSeriesInput struct {
+	// Kind of resource, one of Node, Pod, Namespace, Deployment and StatefulSet
+	Kind string `json:"kind"`
+	// Namespace of resource, it's ignored for Node and Namespace
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Start time in RFC3339 format, default is one hour before end
+	Start string `json:"start"`
+	// End time in RFC3339 format, default is now
+	End string `json:"end"`
+	// Query resolution step like 30s or 5m, default is 1m
+	Step string `json:"step"`
+}
+
+type UsagePoint struct {
+	// Unix timestamp in seconds
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+type UsageTimeSeries struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Step      string `json:"step"`
+	// CPU usage in millicores
+	CPU []UsagePoint `json:"cpu"`
+	// Working set memory in bytes
+	Memory []UsagePoint `json:"memory"`
+}
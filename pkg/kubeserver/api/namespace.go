@@ -54,4 +54,6 @@ type NamespaceListInput struct {
 
 type NamespaceDetailV2 struct {
 	ClusterResourceDetail
+	// Current usage summed by pods in namespace
+	Usage *ResourceUsage `json:"usage,omitempty"`
 }
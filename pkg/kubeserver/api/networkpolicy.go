@@ -0,0 +1,88 @@
+package api
+
+import (
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	NetworkPolicyVerdictAllow = "allow"
+	NetworkPolicyVerdictDeny  = "deny"
+)
+
+type NetworkPolicyCreateInput struct {
+	NamespaceResourceCreateInput
+	networking.NetworkPolicySpec
+}
+
+type NetworkPolicyUpdateInput struct {
+	networking.NetworkPolicySpec
+}
+
+type NetworkPolicyDetail struct {
+	NamespaceResourceDetail
+	// Pods selected by this policy, empty selector selects all pods in namespace
+	PodSelector metav1.LabelSelector `json:"podSelector"`
+	// Effective policy types, Egress is included when egress rules exist and policyTypes isn't set
+	PolicyTypes []networking.PolicyType               `json:"policyTypes"`
+	Ingress     []networking.NetworkPolicyIngressRule `json:"ingress"`
+	Egress      []networking.NetworkPolicyEgressRule  `json:"egress"`
+}
+
+type ClusterNetworkConnectivityInput struct {
+	// Namespace of source
+	SourceNamespace string `json:"source_namespace"`
+	// Source pod, the source is treated as a pod without labels and ip in source namespace if it's empty
+	SourcePod string `json:"source_pod"`
+	// Namespace of destination pod, default is same as source namespace
+	DestinationNamespace string `json:"destination_namespace"`
+	DestinationPod       string `json:"destination_pod"`
+	// Destination port number or name of container port
+	Port string `json:"port"`
+	// Protocol of port, default is TCP
+	Protocol string `json:"protocol"`
+}
+
+type NetworkPolicyRuleRef struct {
+	Policy string `json:"policy"`
+	// Index of ingress or egress rules in policy
+	Rule int `json:"rule"`
+}
+
+type NetworkPolicyDirectionResult struct {
+	// Pod is isolated in this direction when any policy selects it
+	Isolated bool `json:"isolated"`
+	Allowed  bool `json:"allowed"`
+	// Policies select the pod in this direction
+	Policies []string `json:"policies"`
+	// Rules allow the connection
+	MatchedRules []NetworkPolicyRuleRef `json:"matched_rules"`
+}
+
+type ClusterNetworkConnectivity struct {
+	// allow or deny
+	Verdict  string `json:"verdict"`
+	Port     int32  `json:"port"`
+	Protocol string `json:"protocol"`
+	// Egress policies of source namespace
+	Egress NetworkPolicyDirectionResult `json:"egress"`
+	// Ingress policies of destination namespace
+	Ingress NetworkPolicyDirectionResult `json:"ingress"`
+}
+
+type PodNetworkIsolation struct {
+	Name            string   `json:"name"`
+	IngressIsolated bool     `json:"ingress_isolated"`
+	EgressIsolated  bool     `json:"egress_isolated"`
+	IngressPolicies []string `json:"ingress_policies"`
+	EgressPolicies  []string `json:"egress_policies"`
+}
+
+type NamespaceNetworkIsolation struct {
+	Policies []string              `json:"policies"`
+	Pods     []PodNetworkIsolation `json:"pods"`
+	// Count of pods isolated for ingress
+	IngressIsolated int `json:"ingress_isolated"`
+	// Count of pods isolated for egress
+	EgressIsolated int `json:"egress_isolated"`
+}
@@ -7,8 +7,36 @@ import (
 const (
 	NodeStatusReady    = "Ready"
 	NodeStatusNotReady = "NotReady"
+
+	NodeStatusDraining  = "draining"
+	NodeStatusDrainFail = "drain_fail"
+)
+
+const (
+	// DefaultNodeDrainTimeoutSeconds is used when drain input timeout isn't set
+	DefaultNodeDrainTimeoutSeconds = 600
 )
 
+type NodeDrainInput struct {
+	// Continue even if there are pods using emptyDir, local data will be deleted when the node is drained
+	DeleteEmptyDirData bool `json:"delete_empty_dir_data"`
+	// Period of time in seconds given to each pod to terminate gracefully, use pod's default when not set
+	GracePeriodSeconds *int64 `json:"grace_period_seconds"`
+	// The length of time to wait before giving up, zero means DefaultNodeDrainTimeoutSeconds
+	TimeoutSeconds int `json:"timeout_seconds"`
+	// Continue even if there are pods not managed by a controller
+	Force bool `json:"force"`
+}
+
+// NodeDrainResult records what drain did with every pod on the node
+type NodeDrainResult struct {
+	Node    string   `json:"node"`
+	Evicted []string `json:"evicted"`
+	Deleted []string `json:"deleted"`
+	// Skipped pods are daemonset and mirror pods
+	Skipped []string `json:"skipped"`
+}
+
 // NodeAllocatedResources describes node allocated resources.
 type NodeAllocatedResources struct {
 	// CPURequests is number of allocated milicores.
@@ -117,6 +145,8 @@ type NodeDetailV2 struct {
 	ClusterResourceDetail
 	Ready              bool                   `json:"ready"`
 	AllocatedResources NodeAllocatedResources `json:"allocatedResources"`
+	// Current usage reported by metrics api
+	Usage *ResourceUsage `json:"usage,omitempty"`
 	// Addresses is a list of addresses reachable to the node. Queried from cloud provider, if available.
 	Address []v1.NodeAddress `json:"addresses,omitempty"`
 	// Set of ids/uuids to uniquely identify the node.
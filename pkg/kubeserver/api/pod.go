@@ -87,6 +87,9 @@ type PodDetailV2 struct {
 	// Init Container images of deployment
 	InitContainerImages []ContainerImage `json:"initContainerImages"`
 	Conditions          []*Condition     `json:"conditions"`
+	// Current usage reported by metrics api
+	Usage           *ResourceUsage           `json:"usage,omitempty"`
+	ContainersUsage []ContainerResourceUsage `json:"containersUsage,omitempty"`
 	/* Events                 []*Event                 `json:"events"`
 	 * Persistentvolumeclaims []*PersistentVolumeClaim `json:"persistentVolumeClaims"`
 	 * ConfigMaps             []*ConfigMap             `json:"configMaps"`
@@ -174,3 +177,31 @@ type PodListInput struct {
 	NamespaceResourceListInput
 	ListInputOwner
 }
+
+type PodLogInput struct {
+	// Container name, the first container of pod is used when empty
+	Container string `json:"container"`
+	// Only return the last n lines of logs
+	TailLines *int64 `json:"tail_lines"`
+	// Only return logs newer than this number of seconds
+	SinceSeconds *int64 `json:"since_seconds"`
+	// Limit the number of bytes returned
+	LimitBytes *int64 `json:"limit_bytes"`
+	// Add an RFC3339 timestamp at the beginning of every line
+	Timestamps bool `json:"timestamps"`
+	// Return logs of the previous terminated container
+	Previous bool `json:"previous"`
+	// Keep streaming logs through chunked response or websocket
+	Follow bool `json:"follow"`
+	// Download logs as file
+	Download bool `json:"download"`
+}
+
+type PodLogDetail struct {
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	// Containers of the pod can be selected
+	Containers []string `json:"containers"`
+	Previous   bool     `json:"previous"`
+	Logs       string   `json:"logs"`
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+type PodDisruptionBudgetCreateInput struct {
+	NamespaceResourceCreateInput
+
+	// Label query over pods whose evictions are managed by the budget
+	Selector *metav1.LabelSelector `json:"selector"`
+	// Number or percentage of pods must still be available after eviction
+	MinAvailable *intstr.IntOrString `json:"minAvailable"`
+	// Number or percentage of pods can be unavailable after eviction,
+	// it's mutually exclusive with minAvailable
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable"`
+}
+
+type PodDisruptionBudgetUpdateInput struct {
+	MinAvailable   *intstr.IntOrString `json:"minAvailable"`
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable"`
+}
+
+// PodDisruptionBudgetWorkload is workload whose pod template is selected by budget
+type PodDisruptionBudgetWorkload struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+type PodDisruptionBudgetDetail struct {
+	NamespaceResourceDetail
+
+	Selector       *metav1.LabelSelector `json:"selector"`
+	MinAvailable   *intstr.IntOrString   `json:"minAvailable,omitempty"`
+	MaxUnavailable *intstr.IntOrString   `json:"maxUnavailable,omitempty"`
+
+	// Number of pod disruptions that are currently allowed
+	DisruptionsAllowed int32 `json:"disruptionsAllowed"`
+	// Current number of healthy pods
+	CurrentHealthy int32 `json:"currentHealthy"`
+	// Minimum desired number of healthy pods
+	DesiredHealthy int32 `json:"desiredHealthy"`
+	// Total number of pods counted by this budget
+	ExpectedPods int32 `json:"expectedPods"`
+
+	Workloads []PodDisruptionBudgetWorkload `json:"workloads"`
+}
+
+// PodDisruptionBudgetWarning means healthy pods of budget on removed nodes exceed allowed disruptions
+type PodDisruptionBudgetWarning struct {
+	Namespace          string                        `json:"namespace"`
+	Name               string                        `json:"name"`
+	Workloads          []PodDisruptionBudgetWorkload `json:"workloads"`
+	DisruptionsAllowed int32                         `json:"disruptions_allowed"`
+	// Healthy pods of budget running on the nodes
+	Pods    []string `json:"pods"`
+	Message string   `json:"message"`
+}
+
+type NodeDisruptionCheckResult struct {
+	Nodes    []string                     `json:"nodes"`
+	Warnings []PodDisruptionBudgetWarning `json:"pod_disruption_budget_warnings"`
+}
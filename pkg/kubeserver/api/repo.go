@@ -14,6 +14,8 @@ type RepoBackend string
 const (
 	RepoBackendCommon = "common"
 	RepoBackendNexus  = "nexus"
+	// RepoBackendOCI stores charts as OCI artifacts of container registry
+	RepoBackendOCI = "oci"
 )
 
 type RepoCreateInput struct {
@@ -36,8 +38,20 @@ type RepoCreateInput struct {
 	Type string `json:"type"`
 
 	// Repo backend
-	// enum: common, nexus
+	// enum: common, nexus, oci
 	Backend RepoBackend `json:"backend"`
+
+	// Container registry id or name, oci backend uses its url and credential
+	// required: false
+	ContainerRegistryId string `json:"container_registry_id"`
+
+	// Access registry by plain http, only used by oci backend
+	// required: false
+	PlainHttp bool `json:"plain_http"`
+
+	// Skip verifying certificate of registry, only used by oci backend
+	// required: false
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
 }
 
 type RepoListInput struct {
@@ -50,9 +64,10 @@ type RepoListInput struct {
 type RepoDetail struct {
 	apis.StatusInfrasResourceBaseDetails
 
-	Url          string `json:"url"`
-	Type         string `json:"type"`
-	ReleaseCount int    `json:"release_count"`
+	Url               string `json:"url"`
+	Type              string `json:"type"`
+	ReleaseCount      int    `json:"release_count"`
+	ContainerRegistry string `json:"container_registry"`
 }
 
 type RepoDownloadChartInput struct {
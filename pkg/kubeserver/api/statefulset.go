@@ -58,6 +58,12 @@ type StatefulSetDetailV2 struct {
 	InitContainerImages []ContainerImage  `json:"initContainerImages"`
 	Selector            map[string]string `json:"selector"`
 	StatefulSetStatus
+	// Horizontal pod autoscalers targeting this statefulset
+	HorizontalPodAutoscalers []HorizontalPodAutoscaler `json:"horizontalPodAutoscalers"`
+	// Pod disruption budgets selecting pods of this statefulset
+	PodDisruptionBudgets []string `json:"podDisruptionBudgets"`
+	// Current usage summed by pods of this statefulset
+	Usage *ResourceUsage `json:"usage,omitempty"`
 	/*
 	 * PodList  []*Pod     `json:"pods"`
 	 * Events   []*Event   `json:"events"`
@@ -46,6 +46,21 @@ type StorageClassCreateInput struct {
 	// CephCSIRBD is ceph-csi rbd create params
 	// More info: https://github.com/ceph/ceph-csi/blob/master/examples/rbd/storageclass.yaml
 	CephCSIRBD *CephCSIRBDStorageClassCreateInput `json:"cephCSIRBD"`
+
+	// CephCSICephFS is ceph-csi cephfs create params
+	// More info: https://github.com/ceph/ceph-csi/blob/master/examples/cephfs/storageclass.yaml
+	CephCSICephFS *CephCSICephFSStorageClassCreateInput `json:"cephCSICephFS"`
+
+	// NFSCSI is csi-driver-nfs create params
+	// More info: https://github.com/kubernetes-csi/csi-driver-nfs/blob/master/docs/driver-parameters.md
+	NFSCSI *NFSCSIStorageClassCreateInput `json:"nfsCSI"`
+
+	// LocalPath is rancher local-path-provisioner create params
+	// More info: https://github.com/rancher/local-path-provisioner#storage-classes
+	LocalPath *LocalPathStorageClassCreateInput `json:"localPath"`
+
+	// CSI is generic params passed to any provisioner as is
+	CSI *CSIStorageClassCreateInput `json:"csi"`
 }
 
 type CephRBDStorageClassCreateInput struct {
@@ -74,7 +89,13 @@ type CephRBDStorageClassCreateInput struct {
 }
 
 const (
-	StorageClassProvisionerCephCSIRBD = "rbd.csi.ceph.com"
+	StorageClassProvisionerCephCSIRBD    = "rbd.csi.ceph.com"
+	StorageClassProvisionerCephCSICephFS = "cephfs.csi.ceph.com"
+	StorageClassProvisionerNFSCSI        = "nfs.csi.k8s.io"
+	StorageClassProvisionerLocalPath     = "rancher.io/local-path"
+
+	// StorageClassDriverCSI is driver of any provisioner when csi params are provided
+	StorageClassDriverCSI = "csi"
 )
 
 type CephCSIRBDStorageClassCreateInput struct {
@@ -110,6 +131,57 @@ type CephCSIRBDStorageClassCreateInput struct {
 	// EncryptionKMSId string `json:"encryptionKMSId"
 }
 
+type CephCSICephFSStorageClassCreateInput struct {
+	// String representing a Ceph cluster to provision storage from.
+	ClusterId string `json:"clusterId"`
+	// CephFS filesystem name into which the volume shall be created
+	FsName string `json:"fsName"`
+	// Ceph pool into which volume data shall be stored, default is data pool of filesystem
+	Pool string `json:"pool"`
+	// The driver can use either ceph-fuse (fuse) or ceph kernelclient (kernel)
+	// enum: kernel,fuse
+	Mounter string `json:"mounter"`
+
+	SecretName      string `json:"secretName"`
+	SecretNamespace string `json:"secretNamespace"`
+}
+
+type NFSCSIStorageClassCreateInput struct {
+	// NFS server address
+	// example: 192.168.1.10
+	Server string `json:"server"`
+	// NFS share path exported by server
+	// example: /data/k8s
+	Share string `json:"share"`
+	// Sub directory under share created for each volume, support ${pvc.metadata.name} like templates
+	SubDir string `json:"subDir"`
+	// Mounted folder permissions, e.g. 0777
+	MountPermissions string `json:"mountPermissions"`
+	// Action of sub directory when volume is deleted
+	// enum: delete,retain,archive
+	OnDelete string `json:"onDelete"`
+}
+
+type LocalPathStorageClassCreateInput struct {
+	// Node path used instead of nodePathMap of provisioner config, e.g. /data/local-path
+	NodePath string `json:"nodePath"`
+	// Template of directory name under node path, e.g. {{ .PVC.Namespace }}/{{ .PVC.Name }}
+	PathPattern string `json:"pathPattern"`
+}
+
+type CSIStorageClassSecretRef struct {
+	// Secret usage of csi external sidecars
+	// enum: provisioner,controller-expand,controller-publish,node-stage,node-publish
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type CSIStorageClassCreateInput struct {
+	Parameters map[string]string          `json:"parameters"`
+	Secrets    []CSIStorageClassSecretRef `json:"secrets"`
+}
+
 // StorageClass is a representation of a kubernetes StorageClass object.
 type StorageClass struct {
 	ObjectMeta
@@ -161,9 +233,43 @@ type StorageClassDetailV2 struct {
 }
 
 type StorageClassTestResult struct {
-	CephCSIRBD *StorageClassTestResultCephCSIRBD `json:"cephCSIRBD"`
+	CephCSIRBD    *StorageClassTestResultCephCSIRBD    `json:"cephCSIRBD"`
+	CephCSICephFS *StorageClassTestResultCephCSICephFS `json:"cephCSICephFS"`
+	NFSCSI        *StorageClassTestResultNFSCSI        `json:"nfsCSI"`
+	LocalPath     *StorageClassTestResultLocalPath     `json:"localPath"`
+	CSI           *StorageClassTestResultCSI           `json:"csi"`
 }
 
 type StorageClassTestResultCephCSIRBD struct {
 	Pools []string `json:"pools"`
 }
+
+type StorageClassTestResultCephCSICephFS struct {
+	FsNames []string `json:"fsNames"`
+	// Data pools of the filesystem
+	Pools []string `json:"pools"`
+}
+
+type NFSExport struct {
+	Dir    string   `json:"dir"`
+	Groups []string `json:"groups"`
+}
+
+type StorageClassTestResultNFSCSI struct {
+	Exports []NFSExport `json:"exports"`
+}
+
+type StorageClassTestResultLocalPath struct {
+	// Namespace of local-path-provisioner deployment
+	Namespace     string `json:"namespace"`
+	ReadyReplicas int32  `json:"readyReplicas"`
+	// Paths of nodes configured by provisioner, key DEFAULT_PATH_FOR_NON_LISTED_NODES is for all other nodes
+	NodePathMap map[string][]string `json:"nodePathMap"`
+}
+
+type StorageClassTestResultCSI struct {
+	// CSIDriver object of provisioner exists
+	DriverRegistered bool `json:"driverRegistered"`
+	// Nodes on which node plugin of provisioner is registered
+	Nodes []string `json:"nodes"`
+}
@@ -34,6 +34,8 @@ const (
 	KindNameServiceAccount          KindName = "ServiceAccount"
 	KindNameLimitRange              KindName = "LimitRange"
 	KindNameResourceQuota           KindName = "ResourceQuota"
+	KindNameNetworkPolicy           KindName = "NetworkPolicy"
+	KindNamePodDisruptionBudget     KindName = "PodDisruptionBudget"
 
 	// onecloud service operator native kind
 	KindNameVirtualMachine          KindName = "VirtualMachine"
@@ -69,6 +71,8 @@ const (
 	ResourceNameServiceAccount          string = "serviceaccounts"
 	ResourceNameLimitRange              string = "limitranges"
 	ResourceNameResourceQuota           string = "resourcequotas"
+	ResourceNameNetworkPolicy           string = "networkpolicies"
+	ResourceNamePodDisruptionBudget     string = "poddisruptionbudgets"
 
 	// onecloud service operator resource
 	ResourceNameVirtualMachine          string = "virtualmachines"
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"time"
+)
+
+const (
+	// AnnotationRestartedAt is set on pod template to trigger a rolling restart, same as `kubectl rollout restart`
+	AnnotationRestartedAt = "kubectl.kubernetes.io/restartedAt"
+	// AnnotationChangeCause records the reason of a rollout revision
+	AnnotationChangeCause = "kubernetes.io/change-cause"
+	// AnnotationDeploymentRevision is the revision annotation of deployment and its replicasets
+	AnnotationDeploymentRevision = "deployment.kubernetes.io/revision"
+)
+
+type WorkloadScaleInput struct {
+	Replicas *int32 `json:"replicas"`
+}
+
+type WorkloadRollbackInput struct {
+	// Revision to rollback to, 0 means the previous revision
+	Revision int64 `json:"revision"`
+}
+
+type WorkloadRevision struct {
+	Revision int64 `json:"revision"`
+	// Name of the ReplicaSet or ControllerRevision holding this revision
+	Name              string           `json:"name"`
+	CreationTimestamp time.Time        `json:"creationTimestamp"`
+	ChangeCause       string           `json:"changeCause"`
+	ContainerImages   []ContainerImage `json:"containerImages"`
+	Current           bool             `json:"current"`
+	// Replicas of the revision's ReplicaSet, only set for deployment
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Diff is the unified diff of pod template against the previous revision
+	Diff string `json:"diff"`
+}
+
+type WorkloadRevisionsDetail struct {
+	Kind            string             `json:"kind"`
+	Name            string             `json:"name"`
+	CurrentRevision int64              `json:"currentRevision"`
+	Revisions       []WorkloadRevision `json:"revisions"`
+}
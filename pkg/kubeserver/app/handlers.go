@@ -46,7 +46,10 @@ func InitHandlers(app *appsrv.Application) {
 		models.ClusterManager,
 		models.ComponentManager,
 		models.MachineManager,
+		models.ClusterBackupManager,
 		models.GetContainerRegistryManager(),
+		models.ContainerImageMirrorManager,
+		models.ContainerImageScanManager,
 
 		// k8s cluster resource manager
 		models.GetNodeManager(),
@@ -60,6 +63,7 @@ func InitHandlers(app *appsrv.Application) {
 		models.GetPVCManager(),
 		models.GetLimitRangeManager(),
 		models.GetResourceQuotaManager(),
+		models.GetNetworkPolicyManager(),
 		models.GetRoleManager(),
 		models.GetRoleBindingManager(),
 		models.GetServiceManager(),
@@ -70,6 +74,8 @@ func InitHandlers(app *appsrv.Application) {
 		models.GetReplicaSetManager(),
 		models.GetJobManager(),
 		models.GetCronJobManager(),
+		models.GetHorizontalPodAutoscalerManager(),
+		models.GetPodDisruptionBudgetManager(),
 		models.GetPodManager(),
 		models.ServiceAccountManager,
 		models.GetSecretManager(),
@@ -82,6 +88,11 @@ func InitHandlers(app *appsrv.Application) {
 		models.GetFedClusterRoleBindingManager(),
 		models.GetFedRoleManager(),
 		models.GetFedRoleBindingManager(),
+		models.GetFedDeploymentManager(),
+		models.GetFedStatefulSetManager(),
+		models.GetFedServiceManager(),
+		models.GetFedConfigMapManager(),
+		models.GetFedSecretManager(),
 	} {
 		db.RegisterModelManager(man)
 		handler := db.NewModelHandler(man)
@@ -97,6 +108,11 @@ func InitHandlers(app *appsrv.Application) {
 		models.FedClusterRoleBindingClusterManager,
 		models.FedRoleClusterManager,
 		models.FedRoleBindingClusterManager,
+		models.FedDeploymentClusterManager,
+		models.FedStatefulSetClusterManager,
+		models.FedServiceClusterManager,
+		models.FedConfigMapClusterManager,
+		models.FedSecretClusterManager,
 	} {
 		db.RegisterModelManager(man)
 		handler := db.NewJointModelHandler(man)
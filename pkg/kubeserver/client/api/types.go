@@ -6,6 +6,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	// extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -316,10 +317,29 @@ var KindToResourceMap = map[string]ResourceMap{
 		},
 		Namespaced: true,
 	},
+	api.ResourceNameNetworkPolicy: {
+		GroupVersionResourceKind: GroupVersionResourceKind{
+			GroupVersionResource: schema.GroupVersionResource{
+				Group:    networkingv1.GroupName,
+				Version:  networkingv1.SchemeGroupVersion.Version,
+				Resource: api.ResourceNameNetworkPolicy,
+			},
+			Kind: api.KindNameNetworkPolicy,
+		},
+		Namespaced: true,
+	},
 }
 
 var KindHandledByDynamic = []string{
-	api.KindNameIngress, api.KindNameCronJob, // api.KindNameHorizontalPodAutoscaler,
+	api.KindNameIngress, api.KindNameCronJob, api.KindNameHorizontalPodAutoscaler,
+	api.KindNamePodDisruptionBudget,
+}
+
+// KindPreferredVersions overrides the preferred version of group served by cluster,
+// the first served version is used by kinds handled by dynamic client
+var KindPreferredVersions = map[string][]string{
+	// autoscaling/v1 only supports cpu utilization
+	api.KindNameHorizontalPodAutoscaler: {"v2", "v2beta2"},
 }
 
 func GetResourceKinds() sets.String {
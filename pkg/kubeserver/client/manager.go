@@ -188,9 +188,43 @@ func (m *ClustersManager) parseAPIResources(clusterName string, dc discovery.Dis
 			resources = append(resources, res)
 		}
 	}
+	m.overridePreferredVersions(clusterName, dc, resources)
 	return resources, nil
 }
 
+// overridePreferredVersions uses versions defined in KindPreferredVersions if cluster serves them
+func (m *ClustersManager) overridePreferredVersions(clusterName string, dc discovery.DiscoveryInterface, resources []capi.ResourceMap) {
+	for i := range resources {
+		gvrk := &resources[i].GroupVersionResourceKind
+		versions, ok := capi.KindPreferredVersions[gvrk.Kind]
+		if !ok {
+			continue
+		}
+		for _, version := range versions {
+			if version == gvrk.Version {
+				break
+			}
+			gv := schema.GroupVersion{Group: gvrk.Group, Version: version}
+			list, err := dc.ServerResourcesForGroupVersion(gv.String())
+			if err != nil {
+				continue
+			}
+			served := false
+			for _, res := range list.APIResources {
+				if res.Name == gvrk.Resource {
+					served = true
+					break
+				}
+			}
+			if served {
+				log.Infof("Cluster %q use %s for %s instead of %s", clusterName, gv.String(), gvrk.Kind, gvrk.Version)
+				gvrk.Version = version
+				break
+			}
+		}
+	}
+}
+
 func (m *ClustersManager) buildManager(dbCluster manager.ICluster) (*ClusterManager, error) {
 	clusterName := dbCluster.GetName()
 	apiServer, err := dbCluster.GetAPIServer()
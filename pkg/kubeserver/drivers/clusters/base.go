@@ -14,6 +14,7 @@ import (
 	"yunion.io/x/kubecomps/pkg/kubeserver/api"
 	"yunion.io/x/kubecomps/pkg/kubeserver/models"
 	"yunion.io/x/kubecomps/pkg/kubeserver/models/manager"
+	"yunion.io/x/kubecomps/pkg/utils/etcdsnapshot"
 )
 
 type SBaseDriver struct {
@@ -124,6 +125,26 @@ func (d *SBaseDriver) RequestDeployMachines(ctx context.Context, userCred mcclie
 	return nil
 }
 
+func (d *SBaseDriver) ValidateUpgrade(ctx context.Context, userCred mcclient.TokenCredential, cluster *models.SCluster, version string) error {
+	return httperrors.NewNotSupportedError("Cluster %s can not be upgraded", cluster.GetName())
+}
+
+func (d *SBaseDriver) RequestUpgradeMachines(ctx context.Context, userCred mcclient.TokenCredential, cluster *models.SCluster, version string, machines []manager.IMachine, skipDownloads bool) error {
+	return httperrors.NewNotSupportedError("Cluster %s can not be upgraded", cluster.GetName())
+}
+
+func (d *SBaseDriver) GetEtcdMembers(ctx context.Context, userCred mcclient.TokenCredential, cluster *models.SCluster) ([]*etcdsnapshot.Member, error) {
+	return nil, httperrors.NewNotSupportedError("Cluster %s etcd can not be backed up", cluster.GetName())
+}
+
+func (d *SBaseDriver) GetMachinesCertificates(ctx context.Context, userCred mcclient.TokenCredential, cluster *models.SCluster) ([]api.ClusterMachineCertificates, error) {
+	return nil, httperrors.NewNotSupportedError("Cluster %s machines certificates can not be read", cluster.GetName())
+}
+
+func (d *SBaseDriver) RequestRotateCertificates(ctx context.Context, userCred mcclient.TokenCredential, cluster *models.SCluster) error {
+	return httperrors.NewNotSupportedError("Cluster %s certificates can not be rotated", cluster.GetName())
+}
+
 func (d *SBaseDriver) GetKubesprayConfig(ctx context.Context, cluster *models.SCluster) (*api.ClusterKubesprayConfig, error) {
 	return nil, httperrors.NewNotAcceptableError("Cluster can not get kubespray config")
 }
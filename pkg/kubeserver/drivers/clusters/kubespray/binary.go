@@ -194,3 +194,14 @@ func GetKubectlCheckSum(arch, version string) (string, error) {
 func GetCNICheckSum(arch, version string) (string, error) {
 	return getBinaryCheckSum(kubecni, arch, version)
 }
+
+// CheckUpgradeBinaries makes sure checksums of kubernetes binaries for version are known,
+// upgrade playbook will refuse to download binaries without checksum
+func CheckUpgradeBinaries(arch, version string) error {
+	for _, name := range []string{kubeadm, kubelet, kubectl} {
+		if _, err := getBinaryCheckSum(name, arch, version); err != nil {
+			return errors.Wrapf(err, "checksum of %s", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package kubespray
+
+import (
+	"strings"
+)
+
+const certFileMarker = "### "
+
+// ReadCertificatesScript prints PEM certificates of files and kubeconfigs deployed by kubespray,
+// each file content follows a `### <path>` line, files not exist are skipped.
+// Only CERTIFICATE blocks and kubeconfig client-certificate-data are printed,
+// private keys in combined pem files and kubeconfigs never leave the machine
+const ReadCertificatesScript = `
+for f in /etc/kubernetes/pki/*.crt \
+	/etc/kubernetes/admin.conf \
+	/etc/kubernetes/controller-manager.conf \
+	/etc/kubernetes/scheduler.conf \
+	/etc/ssl/etcd/ssl/*.pem \
+	/var/lib/kubelet/pki/kubelet-client-current.pem \
+	/var/lib/kubelet/pki/kubelet.crt; do
+	case "$f" in
+		*-key.pem) continue ;;
+	esac
+	if [ -f "$f" ]; then
+		echo "` + certFileMarker + `$f"
+		case "$f" in
+			*.conf)
+				sed -n 's/^[[:space:]]*client-certificate-data:[[:space:]]*//p' "$f" | base64 -d
+				;;
+			*)
+				sed -n '/-----BEGIN CERTIFICATE-----/,/-----END CERTIFICATE-----/p' "$f"
+				;;
+		esac
+		echo
+	fi
+done
+`
+
+// RenewCertificatesScript renews control plane leaf certificates and restarts control plane
+// components, k8s-certs-renew.sh is installed by kubespray when auto_renew_certificates is enabled
+const RenewCertificatesScript = `
+set -e
+if [ -x /usr/local/bin/k8s-certs-renew.sh ]; then
+	/usr/local/bin/k8s-certs-renew.sh
+	exit 0
+fi
+KUBEADM=/usr/local/bin/kubeadm
+if $KUBEADM certs --help >/dev/null 2>&1; then
+	$KUBEADM certs renew all
+else
+	$KUBEADM alpha certs renew all
+fi
+for c in kube-apiserver kube-controller-manager kube-scheduler; do
+	if command -v crictl >/dev/null 2>&1 && crictl ps >/dev/null 2>&1; then
+		ids=$(crictl ps -q --name "$c")
+		if [ -n "$ids" ]; then
+			crictl stop $ids
+		fi
+	else
+		ids=$(docker ps -q --filter "name=k8s_$c")
+		if [ -n "$ids" ]; then
+			docker stop $ids
+		fi
+	fi
+done
+`
+
+// ParseCertificatesOutput splits output of ReadCertificatesScript to file contents keyed by path
+func ParseCertificatesOutput(out string) map[string][]byte {
+	ret := make(map[string][]byte)
+	var (
+		path    string
+		content []string
+	)
+	flush := func() {
+		if path != "" {
+			ret[path] = []byte(strings.Join(content, "\n"))
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, certFileMarker) {
+			flush()
+			path = strings.TrimSpace(strings.TrimPrefix(line, certFileMarker))
+			content = nil
+			continue
+		}
+		content = append(content, line)
+	}
+	flush()
+	return ret
+}
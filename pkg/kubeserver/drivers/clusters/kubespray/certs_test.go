@@ -0,0 +1,29 @@
+package kubespray
+
+import (
+	"testing"
+)
+
+func TestParseCertificatesOutput(t *testing.T) {
+	out := `### /etc/kubernetes/pki/ca.crt
+-----BEGIN CERTIFICATE-----
+MIIB
+-----END CERTIFICATE-----
+
+### /etc/kubernetes/admin.conf
+apiVersion: v1
+`
+	files := ParseCertificatesOutput(out)
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	if got := string(files["/etc/kubernetes/pki/ca.crt"]); got != "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n" {
+		t.Errorf("unexpected ca.crt content %q", got)
+	}
+	if got := string(files["/etc/kubernetes/admin.conf"]); got != "apiVersion: v1\n" {
+		t.Errorf("unexpected admin.conf content %q", got)
+	}
+	if len(ParseCertificatesOutput("")) != 0 {
+		t.Errorf("empty output should have no files")
+	}
+}
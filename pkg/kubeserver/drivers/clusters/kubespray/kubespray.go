@@ -48,6 +48,8 @@ type KubesprayVars struct {
 	// first `kube-master`
 	DownloadRunOnce bool  `json:"download_run_once"`
 	SkipDownloads   *bool `json:"skip_downloads,omitempty"`
+	// DrainNodes controls whether upgrade-cluster.yml drains node before upgrading
+	DrainNodes *bool `json:"drain_nodes,omitempty"`
 	// YumRepo for rpm: http://mirrors.aliyun.com
 	YumRepo string `json:"yum_repo"`
 	// GCRImageRepo: gcr.azk8s.cn
@@ -189,6 +191,7 @@ type KubesprayInventoryHost struct {
 	Ip             string
 	AccessIp       string
 	Password       string
+	Port           int
 	Roles          sets.String
 	privateKey     string
 	privateKeyFile string
@@ -228,6 +231,19 @@ func (h *KubesprayInventoryHost) SetAliasName(name string) {
 	h.AliasName = name
 }
 
+// SetPort sets ssh port of host, default port 22 is used when it's 0
+func (h *KubesprayInventoryHost) SetPort(port int) {
+	h.Port = port
+}
+
+// GetPort returns ssh port of host
+func (h KubesprayInventoryHost) GetPort() int {
+	if h.Port == 0 {
+		return 22
+	}
+	return h.Port
+}
+
 func (h KubesprayInventoryHost) IsEtcdMember() bool {
 	return h.HasRole(KubesprayNodeRoleEtcd)
 }
@@ -270,8 +286,16 @@ func (h *KubesprayInventoryHost) SetPrivateKey(content []byte) error {
 	return nil
 }
 
+// SetPrivateKeyFile refers to private key file kept by caller, which isn't removed by Clear
+func (h *KubesprayInventoryHost) SetPrivateKeyFile(fPath string) {
+	h.privateKey = ""
+	h.privateKeyFile = fPath
+	h.Password = ""
+}
+
 func (h *KubesprayInventoryHost) Clear() error {
-	if h.privateKeyFile != "" {
+	// privateKey is empty when private key file isn't created by SetPrivateKey
+	if h.privateKey != "" && h.privateKeyFile != "" {
 		if err := os.Remove(h.privateKeyFile); err != nil {
 			if !os.IsNotExist(err) {
 				return errors.Wrapf(err, "remove privateKeyFile %s", h.privateKeyFile)
@@ -303,6 +327,9 @@ func (h KubesprayInventoryHost) ToString() (string, error) {
 	out := fmt.Sprintf("%s", h.Hostname)
 	out = fmt.Sprintf("%s\tansible_host=%s", out, h.AnsibleHost)
 	out = fmt.Sprintf("%s\tansible_ssh_user=%s", out, h.User)
+	if h.Port != 0 {
+		out = fmt.Sprintf("%s\tansible_port=%d", out, h.Port)
+	}
 	if h.Password != "" {
 		out = fmt.Sprintf("%s\tansible_ssh_pass=%s", out, h.Password)
 	}
@@ -25,6 +25,16 @@ func TestKubesprayInventoryHost(t *testing.T) {
 			So(err, ShouldBeNil)
 			So(str, ShouldEqual, "node1\tansible_host=192.168.1.1\tansible_ssh_user=root\tansible_ssh_pass=123\tip=10.168.0.2\tetcd_member_name=node1")
 		})
+
+		Convey("Check ssh port", func() {
+			r, _ := NewKubesprayInventoryHost("node1", "192.168.1.1", "root", "123", KubesprayNodeRoleNode)
+			So(r.GetPort(), ShouldEqual, 22)
+			r.SetPort(2222)
+			So(r.GetPort(), ShouldEqual, 2222)
+			str, err := r.ToString()
+			So(err, ShouldBeNil)
+			So(str, ShouldEqual, "node1\tansible_host=192.168.1.1\tansible_ssh_user=root\tansible_port=2222\tansible_ssh_pass=123")
+		})
 	})
 }
 
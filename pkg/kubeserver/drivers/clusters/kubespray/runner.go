@@ -19,6 +19,7 @@ type DefaultKubesprayExecutor interface {
 	Scale(vars *KubesprayRunVars, allHosts []*KubesprayInventoryHost, addedHosts ...*KubesprayInventoryHost) KubesprayRunner
 	RemoveNode(vars *KubesprayRunVars, allHosts []*KubesprayInventoryHost, removeHosts ...*KubesprayInventoryHost) KubesprayRunner
 	UpgradeMasterConfig(vars *KubesprayRunVars, hosts ...*KubesprayInventoryHost) KubesprayRunner
+	UpgradeCluster(vars *KubesprayRunVars, allHosts []*KubesprayInventoryHost, upgradeHosts ...*KubesprayInventoryHost) KubesprayRunner
 }
 
 type defaultKubesprayExecutor struct {
@@ -64,6 +65,20 @@ func (f *defaultKubesprayExecutor) UpgradeMasterConfig(vars *KubesprayRunVars, h
 	)
 }
 
+func (f *defaultKubesprayExecutor) UpgradeCluster(
+	vars *KubesprayRunVars,
+	allHosts []*KubesprayInventoryHost,
+	upgradeHosts ...*KubesprayInventoryHost,
+) KubesprayRunner {
+	return f.setRunner(
+		func(vars *KubesprayRunVars, allHosts ...*KubesprayInventoryHost) (KubesprayRunner, error) {
+			return NewDefaultKubesprayUpgradeClusterRunner(vars, allHosts, upgradeHosts...)
+		},
+		vars,
+		allHosts...,
+	)
+}
+
 func (f *defaultKubesprayExecutor) RemoveNode(
 	vars *KubesprayRunVars,
 	allHosts []*KubesprayInventoryHost,
@@ -188,6 +203,40 @@ func NewDefaultKubesprayScaleRunner(
 	return runner, nil
 }
 
+// NewDefaultKubesprayUpgradeClusterRunner runs upgrade-cluster.yml node based:
+// control plane hosts are upgraded together with etcd, worker hosts are limited by hostname
+// and aren't drained by kubespray because caller has drained them with user's drain options
+func NewDefaultKubesprayUpgradeClusterRunner(
+	vars *KubesprayRunVars,
+	allHosts []*KubesprayInventoryHost,
+	upgradeHosts ...*KubesprayInventoryHost,
+) (KubesprayRunner, error) {
+	isMaster, err := checkTargetHosts(upgradeHosts)
+	if err != nil {
+		return nil, errors.Wrap(err, "check target hosts")
+	}
+	if !isMaster {
+		drainNodes := false
+		vars.DrainNodes = &drainNodes
+	}
+	runner, err := newDefaultKubesprayRunner(DefaultKubesprayUpgradeClusterYML, vars, allHosts...)
+	if err != nil {
+		return nil, err
+	}
+	if isMaster {
+		if err := runner.AddLimitHosts(false, KubesprayNodeRoleEtcd, KubesprayNodeRoleMaster); err != nil {
+			return nil, errors.Errorf("add limit group %s,%s", KubesprayNodeRoleEtcd, KubesprayNodeRoleMaster)
+		}
+		return runner, nil
+	}
+	for _, h := range upgradeHosts {
+		if err := runner.AddLimitHosts(true, h.Hostname); err != nil {
+			return nil, errors.Wrap(err, "add limit host")
+		}
+	}
+	return runner, nil
+}
+
 func NewDefaultKubesprayRemoveNodeRunner(
 	vars *KubesprayRunVars,
 	allHosts []*KubesprayInventoryHost,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -27,6 +28,8 @@ import (
 	"yunion.io/x/kubecomps/pkg/kubeserver/models"
 	"yunion.io/x/kubecomps/pkg/kubeserver/models/manager"
 	"yunion.io/x/kubecomps/pkg/kubeserver/options"
+	"yunion.io/x/kubecomps/pkg/utils/certificates"
+	"yunion.io/x/kubecomps/pkg/utils/etcdsnapshot"
 	onecloudcli "yunion.io/x/kubecomps/pkg/utils/onecloud/client"
 	"yunion.io/x/kubecomps/pkg/utils/rand"
 
@@ -323,6 +326,16 @@ func (d *selfBuildDriver) ValidateCreateMachines(
 			return httperrors.NewInputParameterError("Apply node vm config: %v", err)
 		}
 	}
+	hostAddrs := make(map[string]bool)
+	for _, m := range data {
+		if m.ResourceType != api.MachineResourceTypeBaremetal || m.Address == "" {
+			continue
+		}
+		if hostAddrs[m.Address] {
+			return httperrors.NewDuplicateResourceError("host address %s", m.Address)
+		}
+		hostAddrs[m.Address] = true
+	}
 	privateKey, err := onecloudcli.GetCloudSSHPrivateKey(session)
 	if err != nil {
 		return errors.Wrapf(err, "failed to get cloud ssh privateKey")
@@ -347,6 +360,10 @@ func (d *selfBuildDriver) applyMachineCreateConfig(s *mcclient.ClientSession, m
 	m.CloudregionId = cloudregionId
 	m.VpcId = vpcId
 
+	// existing host doesn't need vm config
+	if m.ResourceType == api.MachineResourceTypeBaremetal {
+		return nil
+	}
 	if m.Config == nil {
 		m.Config = new(api.MachineCreateConfig)
 	}
@@ -384,7 +401,7 @@ func (d *selfBuildDriver) validateCreateMachine(s *mcclient.ClientSession, priva
 	if err := models.ValidateRole(m.Role); err != nil {
 		return err
 	}
-	if m.ResourceType != api.MachineResourceTypeVm {
+	if !utils.IsInStringArray(m.ResourceType, []string{api.MachineResourceTypeVm, api.MachineResourceTypeBaremetal}) {
 		return httperrors.NewInputParameterError("Invalid resource type: %q", m.ResourceType)
 	}
 	if len(m.ResourceId) != 0 {
@@ -437,6 +454,178 @@ func (d *selfBuildDriver) requestDeployMachines(ctx context.Context, userCred mc
 	return d.deployCluster(ctx, cli, cluster, action, ms, skipDownloads)
 }
 
+func (d *selfBuildDriver) ValidateUpgrade(ctx context.Context, userCred mcclient.TokenCredential, cluster *models.SCluster, version string) error {
+	archs, err := cluster.GetNodesArchitectures(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get nodes architectures")
+	}
+	for _, arch := range archs {
+		if err := kubespray.CheckUpgradeBinaries(arch, version); err != nil {
+			return httperrors.NewNotSupportedError("Binaries of %s/%s not supported: %v", version, arch, err)
+		}
+	}
+	return nil
+}
+
+func (d *selfBuildDriver) RequestUpgradeMachines(ctx context.Context, userCred mcclient.TokenCredential, cluster *models.SCluster, version string, ms []manager.IMachine, skipDownloads bool) error {
+	s, err := models.GetClusterManager().GetSession()
+	if err != nil {
+		return errors.Wrap(err, "get onecloud client session")
+	}
+	cli := onecloudcli.NewClientSets(s)
+	extraConf, err := cluster.GetExtraConfig()
+	if err != nil {
+		return errors.Wrap(err, "get extra config")
+	}
+	vars := &kubespray.KubesprayRunVars{
+		KubesprayVars: d.withKubespray(version, extraConf),
+	}
+	if skipDownloads {
+		vars.SkipDownloads = &skipDownloads
+	}
+	return d.deployClusterByAction(ctx, cli, cluster, vars, ms,
+		func(hosts, upgradeHosts []*kubespray.KubesprayInventoryHost, debug bool) error {
+			return kubespray.NewDefaultKubesprayExecutor().UpgradeCluster(vars, hosts, upgradeHosts...).Run(debug, nil)
+		},
+	)
+}
+
+func (d *selfBuildDriver) GetEtcdMembers(ctx context.Context, userCred mcclient.TokenCredential, cluster *models.SCluster) ([]*etcdsnapshot.Member, error) {
+	hosts, err := d.getInventoryHosts(cluster, true)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]*etcdsnapshot.Member, 0)
+	for _, h := range hosts {
+		members = append(members, &etcdsnapshot.Member{
+			Name: h.GetEtcdMemberName(),
+			// kubespray advertises access ip by default, the real peer url is
+			// fetched from etcd member list when restoring
+			PeerURL:  fmt.Sprintf("https://%s:2380", h.AnsibleHost),
+			Config:   etcdsnapshot.NewKubesprayConfig(h.Hostname, h.User != "root"),
+			Executor: etcdsnapshot.NewSSHExecutor(h.AnsibleHost, 22, h.User, h.Password, h.GetPrivateKey()),
+		})
+		if err := h.Clear(); err != nil {
+			log.Warningf("clear inventory host %s: %v", h.Hostname, err)
+		}
+	}
+	return members, nil
+}
+
+func (d *selfBuildDriver) GetMachinesCertificates(ctx context.Context, userCred mcclient.TokenCredential, cluster *models.SCluster) ([]api.ClusterMachineCertificates, error) {
+	hosts, err := d.getInventoryHosts(cluster, false)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]api.ClusterMachineCertificates, len(hosts))
+	var errgrp errgroup.Group
+	for idx := range hosts {
+		idx := idx
+		h := hosts[idx]
+		ret[idx].Machine = h.Hostname
+		errgrp.Go(func() error {
+			defer func() {
+				if err := h.Clear(); err != nil {
+					log.Warningf("clear inventory host %s: %v", h.Hostname, err)
+				}
+			}()
+			certs, err := readMachineCertificates(h)
+			if err != nil {
+				// one unreachable machine should not hide certificates of others
+				ret[idx].Error = err.Error()
+				return nil
+			}
+			ret[idx].Certificates = certs
+			return nil
+		})
+	}
+	errgrp.Wait()
+	return ret, nil
+}
+
+func readMachineCertificates(h *kubespray.KubesprayInventoryHost) ([]api.ClusterCertificate, error) {
+	out, err := ssh.RemoteSSHBashScript(h.AnsibleHost, h.GetPort(), h.User, h.Password, h.GetPrivateKey(), kubespray.ReadCertificatesScript)
+	if err != nil {
+		return nil, errors.Wrap(err, "read certificate files")
+	}
+	files := kubespray.ParseCertificatesOutput(out)
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	ret := make([]api.ClusterCertificate, 0)
+	for _, path := range paths {
+		certs, err := certificates.ParseCertificates(files[path])
+		if err != nil {
+			log.Warningf("parse certificate %s of %s: %v", path, h.Hostname, err)
+			continue
+		}
+		for _, cert := range certs {
+			c := models.NewClusterCertificate(api.ClusterCertificateSourceMachine, cert)
+			c.Machine = h.Hostname
+			c.Path = path
+			ret = append(ret, c)
+		}
+	}
+	return ret, nil
+}
+
+func (d *selfBuildDriver) RequestRotateCertificates(ctx context.Context, userCred mcclient.TokenCredential, cluster *models.SCluster) error {
+	hosts, err := d.getInventoryHosts(cluster, true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, h := range hosts {
+			if err := h.Clear(); err != nil {
+				log.Warningf("clear inventory host %s: %v", h.Hostname, err)
+			}
+		}
+	}()
+	// renew one control plane by one to keep api server available
+	for _, h := range hosts {
+		out, err := ssh.RemoteSSHBashScript(h.AnsibleHost, h.GetPort(), h.User, h.Password, h.GetPrivateKey(), kubespray.RenewCertificatesScript)
+		if err != nil {
+			return errors.Wrapf(err, "renew certificates of %s: %s", h.Hostname, out)
+		}
+		log.Infof("renew certificates of %s: %s", h.Hostname, out)
+	}
+	return nil
+}
+
+// getInventoryHosts returns kubespray inventory hosts of cluster deployed machines,
+// caller should clear the hosts after used
+func (d *selfBuildDriver) getInventoryHosts(cluster *models.SCluster, controlplaneOnly bool) ([]*kubespray.KubesprayInventoryHost, error) {
+	s, err := models.GetClusterManager().GetSession()
+	if err != nil {
+		return nil, errors.Wrap(err, "get onecloud client session")
+	}
+	cli := onecloudcli.NewClientSets(s)
+	vars, err := d.GetKubesprayVars(cluster)
+	if err != nil {
+		return nil, errors.Wrap(err, "get kubespray vars")
+	}
+	ms, err := cluster.GetDeployMachines(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "get cluster deploy machines")
+	}
+	if controlplaneOnly {
+		cps := make([]manager.IMachine, 0)
+		for _, m := range ms {
+			if m.IsControlplane() {
+				cps = append(cps, m)
+			}
+		}
+		ms = cps
+	}
+	hosts, err := d.GetKubesprayInventory(vars, cli, cluster, ms)
+	if err != nil {
+		return nil, errors.Wrap(err, "new kubespray inventory hosts")
+	}
+	return hosts, nil
+}
+
 func (d *selfBuildDriver) GetKubesprayVars(cluster *models.SCluster) (*kubespray.KubesprayRunVars, error) {
 	extraConf, err := cluster.GetExtraConfig()
 	if err != nil {
@@ -460,9 +649,9 @@ func (d *selfBuildDriver) GetKubesprayInventory(
 		idx := idx
 		m := ms[idx]
 		errgrp.Go(func() error {
-			loginInfo, err := cli.Servers().GetSSHLoginInfo(m.GetResourceId())
+			loginInfo, err := d.getMachineSSHLoginInfo(cli, m)
 			if err != nil {
-				return errors.Wrapf(err, "get server %s loginInfo", m.GetName())
+				return err
 			}
 
 			accessIP := loginInfo.GetAccessIP()
@@ -477,15 +666,19 @@ func (d *selfBuildDriver) GetKubesprayInventory(
 			}
 			roles = append(roles, kubespray.KubesprayNodeRoleNode)
 
-			hostname, err := d.driver.getProviderDriver().GetKubesprayHostname(loginInfo)
-			if err != nil {
-				return errors.Wrapf(err, "get kubespray hostname")
+			hostname := loginInfo.Hostname
+			if !isHostMachine(m) {
+				hostname, err = d.driver.getProviderDriver().GetKubesprayHostname(loginInfo)
+				if err != nil {
+					return errors.Wrapf(err, "get kubespray hostname")
+				}
 			}
 			host, err := kubespray.NewKubesprayInventoryHost(hostname, accessIP, loginInfo.Username, loginInfo.Password, roles...)
 			if err != nil {
 				return errors.Wrapf(err, "new kubespray inventory host for machine %s", m.GetName())
 			}
 			host.SetAliasName(loginInfo.Hostname)
+			host.SetPort(loginInfo.Port)
 			if loginInfo.PrivateKey != "" {
 				if err := host.SetPrivateKey([]byte(loginInfo.PrivateKey)); err != nil {
 					return errors.Wrapf(err, "set private key for host %s", m.GetName())
@@ -503,6 +696,35 @@ func (d *selfBuildDriver) GetKubesprayInventory(
 	return hosts, nil
 }
 
+func isHostMachine(m manager.IMachine) bool {
+	return m.(*models.SMachine).IsHost()
+}
+
+// getMachineSSHLoginInfo returns login of cloud server or existing host
+func (d *selfBuildDriver) getMachineSSHLoginInfo(cli onecloudcli.IClient, m manager.IMachine) (*onecloudcli.ServerSSHLoginInfo, error) {
+	if !isHostMachine(m) {
+		loginInfo, err := cli.Servers().GetSSHLoginInfo(m.GetResourceId())
+		if err != nil {
+			return nil, errors.Wrapf(err, "get server %s loginInfo", m.GetName())
+		}
+		return loginInfo, nil
+	}
+	login, err := m.(*models.SMachine).GetSSHLoginInfo(context.Background())
+	if err != nil {
+		return nil, errors.Wrapf(err, "get host %s loginInfo", m.GetName())
+	}
+	return &onecloudcli.ServerSSHLoginInfo{
+		ServerLoginInfo: &onecloudcli.ServerLoginInfo{
+			Username: login.Username,
+			Password: login.Password,
+		},
+		Hostname:   m.GetName(),
+		PrivateIP:  m.GetResourceId(),
+		PrivateKey: login.PrivateKey,
+		Port:       login.Port,
+	}, nil
+}
+
 func (d *selfBuildDriver) GetKubesprayConfig(ctx context.Context, cluster *models.SCluster) (*api.ClusterKubesprayConfig, error) {
 	s, err := models.GetClusterManager().GetSession()
 	if err != nil {
@@ -528,15 +750,24 @@ func (d *selfBuildDriver) GetKubesprayConfig(ctx context.Context, cluster *model
 		return nil, errors.Error("inventory hosts is empty")
 	}
 
+	// hosts may be logged in with different keys, e.g. cloud vms along with ssh hosts,
+	// so every host refers to its own key file relative to inventory
 	privateKey := hosts[0].GetPrivateKey()
-	consInventoryContent := func(tmpHosts []*kubespray.KubesprayInventoryHost) (string, error) {
-		for _, host := range tmpHosts {
-			host.Clear()
+	privateKeys := make(map[string]string)
+	for _, host := range hosts {
+		key := host.GetPrivateKey()
+		if err := host.Clear(); err != nil {
+			return nil, errors.Wrapf(err, "clear host %s", host.Hostname)
+		}
+		if key == "" {
+			continue
 		}
-		return kubespray.NewKubesprayInventory(cluster.GetVersion(), tmpHosts...).ToString()
+		keyFile := fmt.Sprintf("%s/%s.pem", api.ClusterKubesprayPrivateKeyDir, host.Hostname)
+		host.SetPrivateKeyFile(keyFile)
+		privateKeys[keyFile] = key
 	}
 
-	content, err := consInventoryContent(hosts)
+	content, err := kubespray.NewKubesprayInventory(cluster.GetVersion(), hosts...).ToString()
 	if err != nil {
 		return nil, errors.Wrap(err, "construct inventory content")
 	}
@@ -544,6 +775,7 @@ func (d *selfBuildDriver) GetKubesprayConfig(ctx context.Context, cluster *model
 	conf := &api.ClusterKubesprayConfig{
 		InventoryContent: content,
 		PrivateKey:       privateKey,
+		PrivateKeys:      privateKeys,
 		Vars:             jsonutils.Marshal(vars),
 	}
 
@@ -700,9 +932,10 @@ func (d *selfBuildDriver) getCAKeyPair(cli onecloudcli.IClient, primaryMaster *k
 	user := primaryMaster.User
 	passwd := primaryMaster.Password
 	key := primaryMaster.GetPrivateKey()
+	port := primaryMaster.GetPort()
 
 	remoteCat := func(fp string) ([]byte, error) {
-		out, err := ssh.RemoteSSHCommand(host, 22, user, passwd, key, fmt.Sprintf("sudo cat %s", fp))
+		out, err := ssh.RemoteSSHCommand(host, port, user, passwd, key, fmt.Sprintf("sudo cat %s", fp))
 		if err != nil {
 			return nil, errors.Wrapf(err, "get %s content", fp)
 		}
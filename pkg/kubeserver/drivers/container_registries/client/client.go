@@ -10,9 +10,11 @@ import (
 	"path"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/regclient/regclient"
 	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/types/manifest"
 	"github.com/regclient/regclient/types/ref"
 	"golang.org/x/sync/errgroup"
 	"yunion.io/x/pkg/errors"
@@ -36,6 +38,15 @@ type Client interface {
 	AnalysisImageTarMetadata(tarPath string) (*ImageMetadata, error)
 
 	PushImage(ctx context.Context, input *ImageMetadata, tarPath string) error
+
+	// GetImageTag returns manifest digest and creation time of image tag
+	GetImageTag(ctx context.Context, image string, tag string) (*ImageTagInfo, error)
+
+	// DeleteImageTag removes tag only, manifest referenced by other tags is kept
+	DeleteImageTag(ctx context.Context, image string, tag string) error
+
+	// DeleteImageManifest removes manifest by digest along with all tags referencing it
+	DeleteImageManifest(ctx context.Context, image string, digest string) error
 }
 
 // DockerAuthConfig contains authorization information for connecting to a registry.
@@ -171,8 +182,9 @@ func (c client) ListImages(ctx context.Context, input *api.ContainerRegistryList
 			break
 		}
 	}
-	newRepos := []string{}
+	newRepos := result.Repositories
 	if c.pathPrefix != "" {
+		newRepos = []string{}
 		// filter unmatched images
 		pathPrefix := strings.TrimPrefix(c.pathPrefix, "/")
 		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
@@ -232,9 +244,8 @@ type ImageTagResult struct {
 func (c client) ListImageTags(ctx context.Context, image string) (jsonutils.JSONObject, error) {
 	tagsUrl := "/v2/%s/tags/list"
 	if c.pathPrefix != "" {
-		parts := strings.Split(image, "/")
 		prefix := strings.TrimPrefix(c.pathPrefix, "/")
-		image = fmt.Sprintf("%s/%s", prefix, parts[len(parts)-1])
+		image = fmt.Sprintf("%s/%s", prefix, c.trimPathPrefix(image))
 	}
 	image = url.QueryEscape(image)
 	tagsUrl = fmt.Sprintf(tagsUrl, image)
@@ -252,6 +263,112 @@ func (c client) ListImageTags(ctx context.Context, image string) (jsonutils.JSON
 	return resp, nil
 }
 
+// trimPathPrefix returns repository relative to path prefix, which is appended by regclient
+func (c client) trimPathPrefix(image string) string {
+	if c.pathPrefix == "" {
+		return image
+	}
+	parts := strings.Split(image, "/")
+	return parts[len(parts)-1]
+}
+
+func (c client) imageRef(image string, tagOrDigest string) (ref.Ref, error) {
+	refStr := fmt.Sprintf("%s/%s", c.regHost, c.trimPathPrefix(image))
+	if strings.Contains(tagOrDigest, ":") {
+		refStr = fmt.Sprintf("%s@%s", refStr, tagOrDigest)
+	} else {
+		refStr = fmt.Sprintf("%s:%s", refStr, tagOrDigest)
+	}
+	r, err := ref.New(refStr)
+	if err != nil {
+		return r, errors.Wrapf(err, "parse reference %s", refStr)
+	}
+	return r, nil
+}
+
+type ImageTagInfo struct {
+	Tag    string `json:"tag"`
+	Digest string `json:"digest"`
+	// MediaType is media type of manifest or manifest list
+	MediaType string `json:"media_type"`
+	// Created is creation time recorded in image config, zero if unknown
+	Created time.Time `json:"created"`
+}
+
+func (c client) GetImageTag(ctx context.Context, image string, tag string) (*ImageTagInfo, error) {
+	r, err := c.imageRef(image, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer c.rc.Close(ctx, r)
+	m, err := c.rc.ManifestGet(ctx, r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get manifest %s", r.CommonName())
+	}
+	info := &ImageTagInfo{
+		Tag:       tag,
+		Digest:    m.GetDescriptor().Digest.String(),
+		MediaType: m.GetDescriptor().MediaType,
+	}
+	// creation time of manifest list is taken from its first image
+	if indexer, ok := m.(manifest.Indexer); ok {
+		descs, err := indexer.GetManifestList()
+		if err != nil {
+			return nil, errors.Wrapf(err, "get manifest list %s", r.CommonName())
+		}
+		if len(descs) == 0 {
+			return info, nil
+		}
+		childRef := r
+		childRef.Tag = ""
+		childRef.Digest = descs[0].Digest.String()
+		m, err = c.rc.ManifestGet(ctx, childRef)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get manifest %s", childRef.CommonName())
+		}
+	}
+	imager, ok := m.(manifest.Imager)
+	if !ok {
+		return info, nil
+	}
+	confDesc, err := imager.GetConfig()
+	if err != nil {
+		return nil, errors.Wrapf(err, "get config descriptor %s", r.CommonName())
+	}
+	conf, err := c.rc.BlobGetOCIConfig(ctx, r, confDesc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get config %s", r.CommonName())
+	}
+	if created := conf.GetConfig().Created; created != nil {
+		info.Created = *created
+	}
+	return info, nil
+}
+
+func (c client) DeleteImageTag(ctx context.Context, image string, tag string) error {
+	r, err := c.imageRef(image, tag)
+	if err != nil {
+		return err
+	}
+	defer c.rc.Close(ctx, r)
+	if err := c.rc.TagDelete(ctx, r); err != nil {
+		return errors.Wrapf(err, "delete tag %s", r.CommonName())
+	}
+	return nil
+}
+
+func (c client) DeleteImageManifest(ctx context.Context, image string, digest string) error {
+	r, err := c.imageRef(image, digest)
+	if err != nil {
+		return err
+	}
+	defer c.rc.Close(ctx, r)
+	if err := c.rc.ManifestDelete(ctx, r); err != nil {
+		return errors.Wrapf(err, "delete manifest %s", r.CommonName())
+	}
+	return nil
+}
+
 func (c client) AnalysisTar(tarPath string) (*registry.TarReadData, error) {
 	return registry.AnalysisTar(tarPath)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
 
 	"yunion.io/x/kubecomps/pkg/kubeserver/api"
 )
@@ -33,3 +34,15 @@ func (c *customClient) AnalysisImageTarMetadata(tarPath string) (*ImageMetadata,
 func (c *customClient) PushImage(ctx context.Context, input *ImageMetadata, tarPath string) error {
 	return nil
 }
+
+func (c *customClient) GetImageTag(ctx context.Context, image string, tag string) (*ImageTagInfo, error) {
+	return nil, errors.ErrNotSupported
+}
+
+func (c *customClient) DeleteImageTag(ctx context.Context, image string, tag string) error {
+	return errors.ErrNotSupported
+}
+
+func (c *customClient) DeleteImageManifest(ctx context.Context, image string, digest string) error {
+	return errors.ErrNotSupported
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/types/ref"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// Mirror copies images from source registry to target registry,
+// manifest lists are copied along with images of all platforms
+type Mirror struct {
+	rc     *regclient.RegClient
+	source string
+	target string
+}
+
+// MirrorRegistry is source or target registry of mirror
+type MirrorRegistry struct {
+	Url  string
+	Auth DockerAuthConfig
+	// InsecureSkipVerify skips verifying certificate of https registry
+	InsecureSkipVerify bool
+}
+
+// mirrorConfigHost returns regclient host config and repository prefix of registry url,
+// public registries like docker hub are supported
+func mirrorConfigHost(reg MirrorRegistry) (config.Host, string, error) {
+	regUrl := reg.Url
+	if !strings.Contains(regUrl, "://") {
+		regUrl = "https://" + regUrl
+	}
+	u, err := url.Parse(regUrl)
+	if err != nil {
+		return config.Host{}, "", errors.Wrapf(err, "parse registry url %s", regUrl)
+	}
+	if u.Host == "" {
+		return config.Host{}, "", errors.Errorf("registry host of %q is empty", regUrl)
+	}
+	host := config.HostNewName(u.Host)
+	host.User = reg.Auth.Username
+	host.Pass = reg.Auth.Password
+	host.RepoAuth = true
+	if u.Scheme == "http" {
+		host.TLS = config.TLSDisabled
+	} else if reg.InsecureSkipVerify {
+		host.TLS = config.TLSInsecure
+	}
+	return *host, strings.Trim(u.Path, "/"), nil
+}
+
+func joinRepository(prefix, repo string) string {
+	if prefix == "" {
+		return repo
+	}
+	return fmt.Sprintf("%s/%s", prefix, repo)
+}
+
+// NewMirror creates mirror between registries, repositories are relative to path of registry url
+func NewMirror(src MirrorRegistry, target MirrorRegistry) (*Mirror, error) {
+	srcHost, srcPrefix, err := mirrorConfigHost(src)
+	if err != nil {
+		return nil, errors.Wrap(err, "source registry")
+	}
+	targetHost, targetPrefix, err := mirrorConfigHost(target)
+	if err != nil {
+		return nil, errors.Wrap(err, "target registry")
+	}
+	hosts := []config.Host{srcHost}
+	if targetHost.Name != srcHost.Name {
+		hosts = append(hosts, targetHost)
+	}
+	return &Mirror{
+		rc:     regclient.New(regclient.WithConfigHost(hosts...)),
+		source: joinRepository(srcHost.Name, srcPrefix),
+		target: joinRepository(targetHost.Name, targetPrefix),
+	}, nil
+}
+
+func (m *Mirror) SourceRef(repo, tag string) (ref.Ref, error) {
+	return ref.New(fmt.Sprintf("%s:%s", joinRepository(m.source, repo), tag))
+}
+
+func (m *Mirror) TargetRef(repo, tag string) (ref.Ref, error) {
+	return ref.New(fmt.Sprintf("%s:%s", joinRepository(m.target, repo), tag))
+}
+
+// ListTags lists tags of source repository by registry api, which works for public registries without catalog api
+func (m *Mirror) ListTags(ctx context.Context, repo string) ([]string, error) {
+	r, err := m.SourceRef(repo, "latest")
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse repository %s", repo)
+	}
+	tl, err := m.rc.TagList(ctx, r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list tags of %s", r.CommonName())
+	}
+	return tl.GetTags()
+}
+
+// IsSynced returns true when target tag has same manifest digest with source
+func (m *Mirror) IsSynced(ctx context.Context, src, target ref.Ref) (bool, error) {
+	srcM, err := m.rc.ManifestHead(ctx, src)
+	if err != nil {
+		return false, errors.Wrapf(err, "head manifest %s", src.CommonName())
+	}
+	targetM, err := m.rc.ManifestHead(ctx, target)
+	if err != nil {
+		// target tag not exists
+		return false, nil
+	}
+	srcDigest := srcM.GetDescriptor().Digest
+	return srcDigest != "" && srcDigest == targetM.GetDescriptor().Digest, nil
+}
+
+// Copy copies image of tag, only given platforms of manifest list are copied when platforms provided
+func (m *Mirror) Copy(ctx context.Context, src, target ref.Ref, platforms []string) error {
+	opts := []regclient.ImageOpts{}
+	if len(platforms) > 0 {
+		opts = append(opts, regclient.ImageWithPlatforms(platforms))
+	}
+	if err := m.rc.ImageCopy(ctx, src, target, opts...); err != nil {
+		return errors.Wrapf(err, "copy %s to %s", src.CommonName(), target.CommonName())
+	}
+	return nil
+}
+
+func (m *Mirror) Close(ctx context.Context, r ref.Ref) {
+	m.rc.Close(ctx, r)
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/regclient/regclient/config"
+)
+
+func TestNewMirror(t *testing.T) {
+	m, err := NewMirror(
+		MirrorRegistry{Url: "docker.io/library"},
+		MirrorRegistry{Url: "http://192.168.0.10:5000/mirror", Auth: DockerAuthConfig{Username: "admin", Password: "pass"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := m.SourceRef("nginx", "1.21")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.Registry != "docker.io" || src.Repository != "library/nginx" || src.Tag != "1.21" {
+		t.Errorf("source ref = %#v", src)
+	}
+	target, err := m.TargetRef("nginx", "1.21")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Registry != "192.168.0.10:5000" || target.Repository != "mirror/nginx" {
+		t.Errorf("target ref = %#v", target)
+	}
+}
+
+func TestMirrorConfigHost(t *testing.T) {
+	cases := []struct {
+		reg      MirrorRegistry
+		hostname string
+		prefix   string
+		tls      config.TLSConf
+	}{
+		{reg: MirrorRegistry{Url: "docker.io"}, hostname: config.DockerRegistryDNS, tls: config.TLSEnabled},
+		{reg: MirrorRegistry{Url: "https://registry.cn-beijing.aliyuncs.com/yunionio"}, hostname: "registry.cn-beijing.aliyuncs.com", prefix: "yunionio", tls: config.TLSEnabled},
+		// authenticated registry still verifies certificate unless skipped explicitly
+		{reg: MirrorRegistry{Url: "https://harbor.local", Auth: DockerAuthConfig{Username: "admin"}}, hostname: "harbor.local", tls: config.TLSEnabled},
+		{reg: MirrorRegistry{Url: "https://harbor.local", Auth: DockerAuthConfig{Username: "admin"}, InsecureSkipVerify: true}, hostname: "harbor.local", tls: config.TLSInsecure},
+		{reg: MirrorRegistry{Url: "http://10.0.0.1:5000/", InsecureSkipVerify: true}, hostname: "10.0.0.1:5000", tls: config.TLSDisabled},
+	}
+	for _, c := range cases {
+		host, prefix, err := mirrorConfigHost(c.reg)
+		if err != nil {
+			t.Errorf("%s: %v", c.reg.Url, err)
+			continue
+		}
+		if host.Hostname != c.hostname || prefix != c.prefix || host.TLS != c.tls {
+			t.Errorf("%s: got (%s, %s, %v)", c.reg.Url, host.Hostname, prefix, host.TLS)
+		}
+	}
+}
@@ -19,6 +19,7 @@ import (
 
 	"yunion.io/x/kubecomps/pkg/kubeserver/api"
 	"yunion.io/x/kubecomps/pkg/kubeserver/drivers/container_registries/client"
+	"yunion.io/x/kubecomps/pkg/kubeserver/drivers/container_registries/scanner"
 	"yunion.io/x/kubecomps/pkg/kubeserver/models"
 	"yunion.io/x/kubecomps/pkg/utils/skopeo"
 )
@@ -156,3 +157,12 @@ func (c commonImpl) DownloadImage(ctx context.Context, regUrl string, conf *api.
 
 	return gzFilePath, nil
 }
+
+func (c commonImpl) GarbageCollect(ctx context.Context, url string, conf *api.ContainerRegistryConfig) error {
+	// distribution only collects garbage offline by 'registry garbage-collect' command
+	return httperrors.NewNotSupportedError("common registry not support garbage collection by api, run 'registry garbage-collect' on registry host")
+}
+
+func (c commonImpl) GetImageScanner(url string, conf *api.ContainerRegistryConfig) (scanner.Scanner, error) {
+	return nil, httperrors.NewNotSupportedError("common registry has no builtin scanner, set scanner adapter instead")
+}
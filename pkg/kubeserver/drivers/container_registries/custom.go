@@ -9,6 +9,7 @@ import (
 
 	"yunion.io/x/kubecomps/pkg/kubeserver/api"
 	"yunion.io/x/kubecomps/pkg/kubeserver/drivers/container_registries/client"
+	"yunion.io/x/kubecomps/pkg/kubeserver/drivers/container_registries/scanner"
 	"yunion.io/x/kubecomps/pkg/kubeserver/models"
 )
 
@@ -49,3 +50,11 @@ func (c customImpl) DownloadImage(ctx context.Context, url string, conf *api.Con
 func (c customImpl) GetDockerRegistryClient(url string, config *api.ContainerRegistryConfig) (client.Client, error) {
 	return client.NewCustomClient(), nil
 }
+
+func (c customImpl) GarbageCollect(ctx context.Context, url string, conf *api.ContainerRegistryConfig) error {
+	return httperrors.NewNotSupportedError("custom not support garbage collection")
+}
+
+func (c customImpl) GetImageScanner(url string, conf *api.ContainerRegistryConfig) (scanner.Scanner, error) {
+	return nil, httperrors.NewNotSupportedError("custom has no builtin scanner, set scanner adapter instead")
+}
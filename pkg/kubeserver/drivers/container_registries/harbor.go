@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"github.com/goharbor/go-client/pkg/harbor"
+	"github.com/goharbor/go-client/pkg/sdk/v2.0/client/artifact"
+	"github.com/goharbor/go-client/pkg/sdk/v2.0/client/gc"
 	"github.com/goharbor/go-client/pkg/sdk/v2.0/client/project"
 	"github.com/goharbor/go-client/pkg/sdk/v2.0/client/repository"
 	hbmodels "github.com/goharbor/go-client/pkg/sdk/v2.0/models"
@@ -42,10 +44,18 @@ func (h harborImpl) GetDockerRegistryClient(url string, config *api.ContainerReg
 	if config.Harbor == nil {
 		return nil, errors.Errorf("harbor config is nil")
 	}
-	return client.NewClient(url, client.DockerAuthConfig{
+	cli, err := client.NewClient(url, client.DockerAuthConfig{
 		Username: config.Harbor.Username,
 		Password: config.Harbor.Password,
 	})
+	if err != nil {
+		return nil, err
+	}
+	cs, err := h.getClient(url, config.Harbor)
+	if err != nil {
+		return nil, errors.Wrap(err, "get harbor client")
+	}
+	return &harborClient{Client: cli, cs: cs}, nil
 }
 
 func (h harborImpl) getClient(url string, config *api.ContainerRegistryConfigHarbor) (*harbor.ClientSet, error) {
@@ -112,3 +122,73 @@ func (h harborImpl) PreparePushImage(ctx context.Context, url string, conf *api.
 	log.Infof("create project %q, ret: %#v, error: %v", projectName, ret, err)
 	return nil
 }
+
+func (h harborImpl) GarbageCollect(ctx context.Context, url string, conf *api.ContainerRegistryConfig) error {
+	if conf.Harbor == nil {
+		return errors.Errorf("harbor config is nil")
+	}
+	cs, err := h.getClient(url, conf.Harbor)
+	if err != nil {
+		return errors.Wrap(err, "get harbor client")
+	}
+	params := gc.NewCreateGCScheduleParams()
+	params.Schedule = &hbmodels.Schedule{
+		Schedule: &hbmodels.ScheduleObj{
+			Type: hbmodels.ScheduleObjTypeManual,
+		},
+		Parameters: map[string]interface{}{
+			"delete_untagged": true,
+		},
+	}
+	if _, err := cs.V2().GC.CreateGCSchedule(ctx, params); err != nil {
+		return errors.Wrap(err, "trigger harbor gc")
+	}
+	return nil
+}
+
+// harborClient deletes tags and artifacts by harbor api, tag can't be deleted through registry v2 api of harbor
+type harborClient struct {
+	client.Client
+	cs *harbor.ClientSet
+}
+
+// splitHarborRepository splits 'library/nginx' to project 'library' and repository 'nginx',
+// slash inside repository is double escaped as harbor required
+func splitHarborRepository(image string) (string, string, error) {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.Errorf("harbor repository %q must be like 'project/repository'", image)
+	}
+	return parts[0], strings.ReplaceAll(parts[1], "/", "%2F"), nil
+}
+
+func (c *harborClient) DeleteImageTag(ctx context.Context, image string, tag string) error {
+	project, repo, err := splitHarborRepository(image)
+	if err != nil {
+		return err
+	}
+	params := artifact.NewDeleteTagParams()
+	params.ProjectName = project
+	params.RepositoryName = repo
+	params.Reference = tag
+	params.TagName = tag
+	if _, err := c.cs.V2().Artifact.DeleteTag(ctx, params); err != nil {
+		return errors.Wrapf(err, "delete harbor tag %s:%s", image, tag)
+	}
+	return nil
+}
+
+func (c *harborClient) DeleteImageManifest(ctx context.Context, image string, digest string) error {
+	project, repo, err := splitHarborRepository(image)
+	if err != nil {
+		return err
+	}
+	params := artifact.NewDeleteArtifactParams()
+	params.ProjectName = project
+	params.RepositoryName = repo
+	params.Reference = digest
+	if _, err := c.cs.V2().Artifact.DeleteArtifact(ctx, params); err != nil {
+		return errors.Wrapf(err, "delete harbor artifact %s@%s", image, digest)
+	}
+	return nil
+}
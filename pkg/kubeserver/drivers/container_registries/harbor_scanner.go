@@ -0,0 +1,121 @@
+package container_registries
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goharbor/go-client/pkg/harbor"
+	"github.com/goharbor/go-client/pkg/sdk/v2.0/client/artifact"
+	"github.com/goharbor/go-client/pkg/sdk/v2.0/client/scan"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/drivers/container_registries/scanner"
+)
+
+const harborVulnerabilityReportMimeType = "application/vnd.scanner.adapter.vuln.report.harbor+json; version=1.0"
+
+func (h harborImpl) GetImageScanner(url string, conf *api.ContainerRegistryConfig) (scanner.Scanner, error) {
+	if conf.Harbor == nil {
+		return nil, errors.Errorf("harbor config is nil")
+	}
+	cs, err := h.getClient(url, conf.Harbor)
+	if err != nil {
+		return nil, errors.Wrap(err, "get harbor client")
+	}
+	return &harborScanner{cs: cs}, nil
+}
+
+// harborScanner scans artifacts by scanner configured as default of harbor
+type harborScanner struct {
+	cs *harbor.ClientSet
+}
+
+func (s *harborScanner) GetType() string {
+	return api.ContainerImageScannerTypeHarbor
+}
+
+func (s *harborScanner) Scan(ctx context.Context, art *scanner.Artifact) (string, error) {
+	project, repo, err := splitHarborRepository(art.Repository)
+	if err != nil {
+		return "", err
+	}
+	params := scan.NewScanArtifactParams()
+	params.ProjectName = project
+	params.RepositoryName = repo
+	params.Reference = art.Digest
+	if _, err := s.cs.V2().Scan.ScanArtifact(ctx, params); err != nil {
+		return "", errors.Wrapf(err, "scan harbor artifact %s@%s", art.Repository, art.Digest)
+	}
+	// report is fetched by artifact digest
+	return art.Digest, nil
+}
+
+type harborVulnerabilityReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Scanner     struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"scanner"`
+	Vulnerabilities []api.ContainerImageVulnerability `json:"vulnerabilities"`
+}
+
+func (s *harborScanner) GetReport(ctx context.Context, art *scanner.Artifact, reportId string) (*api.ContainerImageVulnerabilityReport, error) {
+	project, repo, err := splitHarborRepository(art.Repository)
+	if err != nil {
+		return nil, err
+	}
+	withScanOverview := true
+	params := artifact.NewGetArtifactParams()
+	params.ProjectName = project
+	params.RepositoryName = repo
+	params.Reference = reportId
+	params.WithScanOverview = &withScanOverview
+	ret, err := s.cs.V2().Artifact.GetArtifact(ctx, params)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get harbor artifact %s@%s", art.Repository, reportId)
+	}
+	overview, ok := ret.Payload.ScanOverview[harborVulnerabilityReportMimeType]
+	if !ok {
+		return nil, scanner.ErrReportNotReady
+	}
+	switch overview.ScanStatus {
+	case "Success":
+	case "Error", "Stopped":
+		return nil, errors.Errorf("harbor scan %s@%s status %s", art.Repository, reportId, overview.ScanStatus)
+	default:
+		return nil, scanner.ErrReportNotReady
+	}
+
+	vulnParams := artifact.NewGetVulnerabilitiesAdditionParams()
+	vulnParams.ProjectName = project
+	vulnParams.RepositoryName = repo
+	vulnParams.Reference = reportId
+	vulnRet, err := s.cs.V2().Artifact.GetVulnerabilitiesAddition(ctx, vulnParams)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get harbor vulnerabilities of %s@%s", art.Repository, reportId)
+	}
+	reports := make(map[string]harborVulnerabilityReport)
+	if err := json.Unmarshal([]byte(vulnRet.Payload), &reports); err != nil {
+		return nil, errors.Wrap(err, "unmarshal harbor vulnerabilities")
+	}
+	hReport := reports[harborVulnerabilityReportMimeType]
+	report := &api.ContainerImageVulnerabilityReport{
+		Digest:          ret.Payload.Digest,
+		Vulnerabilities: hReport.Vulnerabilities,
+	}
+	if report.Vulnerabilities == nil {
+		report.Vulnerabilities = make([]api.ContainerImageVulnerability, 0)
+	}
+	report.Scanner = strings.TrimSpace(fmt.Sprintf("%s %s", hReport.Scanner.Name, hReport.Scanner.Version))
+	report.ScannedAt = hReport.GeneratedAt
+	if report.ScannedAt.IsZero() {
+		report.ScannedAt = time.Now()
+	}
+	scanner.Summarize(report)
+	return report, nil
+}
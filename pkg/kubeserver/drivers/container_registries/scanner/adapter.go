@@ -0,0 +1,175 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+const (
+	adapterScanRequestMimeType = "application/vnd.scanner.adapter.scan.request+json; version=1.0"
+	adapterScanReportMimeType  = "application/vnd.scanner.adapter.vuln.report.harbor+json; version=1.0"
+
+	defaultArtifactMimeType = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// adapterScanner talks to scanner implementing harbor pluggable scanner api v1.0,
+// ref: https://github.com/goharbor/pluggable-scanner-spec
+type adapterScanner struct {
+	url   string
+	token string
+	cli   *http.Client
+}
+
+func NewAdapterScanner(conf *api.ContainerRegistryScannerConfig) (Scanner, error) {
+	if conf == nil || conf.Url == "" {
+		return nil, errors.Errorf("scanner adapter url is empty")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if conf.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &adapterScanner{
+		url:   strings.TrimSuffix(conf.Url, "/"),
+		token: conf.Token,
+		cli: &http.Client{
+			Transport: transport,
+			Timeout:   time.Minute,
+			// adapter redirects to report url while scanning is in progress
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+func (s *adapterScanner) GetType() string {
+	return api.ContainerImageScannerTypeAdapter
+}
+
+type adapterScanRequest struct {
+	Registry struct {
+		Url           string `json:"url"`
+		Authorization string `json:"authorization,omitempty"`
+	} `json:"registry"`
+	Artifact struct {
+		Repository string `json:"repository"`
+		Digest     string `json:"digest"`
+		Tag        string `json:"tag,omitempty"`
+		MimeType   string `json:"mime_type"`
+	} `json:"artifact"`
+}
+
+type adapterScanReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Scanner     struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"scanner"`
+	Vulnerabilities []api.ContainerImageVulnerability `json:"vulnerabilities"`
+}
+
+func (s *adapterScanner) do(ctx context.Context, method, path string, header http.Header, body []byte) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.url+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "new request")
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.cli.Do(req)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "%s %s", method, req.URL)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "read response")
+	}
+	return resp, respBody, nil
+}
+
+func (s *adapterScanner) Scan(ctx context.Context, art *Artifact) (string, error) {
+	req := new(adapterScanRequest)
+	req.Registry.Url = art.RegistryUrl
+	if art.Username != "" {
+		req.Registry.Authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(art.Username+":"+art.Password))
+	}
+	req.Artifact.Repository = art.Repository
+	req.Artifact.Digest = art.Digest
+	req.Artifact.Tag = art.Tag
+	req.Artifact.MimeType = art.MimeType
+	if req.Artifact.MimeType == "" {
+		req.Artifact.MimeType = defaultArtifactMimeType
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", adapterScanRequestMimeType)
+	resp, respBody, err := s.do(ctx, http.MethodPost, "/api/v1/scan", header, body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return "", errors.Errorf("request scan of %s@%s: %s: %s", art.Repository, art.Digest, resp.Status, respBody)
+	}
+	ret := struct {
+		Id string `json:"id"`
+	}{}
+	if err := json.Unmarshal(respBody, &ret); err != nil {
+		return "", errors.Wrap(err, "unmarshal scan response")
+	}
+	return ret.Id, nil
+}
+
+func (s *adapterScanner) GetReport(ctx context.Context, art *Artifact, reportId string) (*api.ContainerImageVulnerabilityReport, error) {
+	header := http.Header{}
+	header.Set("Accept", adapterScanReportMimeType)
+	resp, respBody, err := s.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/scan/%s/report", reportId), header, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusFound:
+		return nil, ErrReportNotReady
+	default:
+		return nil, errors.Errorf("get scan report %s: %s: %s", reportId, resp.Status, respBody)
+	}
+	ret := new(adapterScanReport)
+	if err := json.Unmarshal(respBody, ret); err != nil {
+		return nil, errors.Wrap(err, "unmarshal scan report")
+	}
+	report := &api.ContainerImageVulnerabilityReport{
+		Digest:          art.Digest,
+		Vulnerabilities: ret.Vulnerabilities,
+	}
+	if report.Vulnerabilities == nil {
+		report.Vulnerabilities = make([]api.ContainerImageVulnerability, 0)
+	}
+	report.Scanner = strings.TrimSpace(fmt.Sprintf("%s %s", ret.Scanner.Name, ret.Scanner.Version))
+	report.ScannedAt = ret.GeneratedAt
+	if report.ScannedAt.IsZero() {
+		report.ScannedAt = time.Now()
+	}
+	Summarize(report)
+	return report, nil
+}
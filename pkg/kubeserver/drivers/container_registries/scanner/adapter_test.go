@@ -0,0 +1,92 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+func TestAdapterScanner(t *testing.T) {
+	scanned := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/scan":
+			req := new(adapterScanRequest)
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+				t.Fatalf("decode scan request: %v", err)
+			}
+			if req.Artifact.Repository != "library/nginx" || req.Artifact.Digest != "sha256:abc" {
+				t.Errorf("unexpected artifact %#v", req.Artifact)
+			}
+			if req.Registry.Authorization != "Basic dXNlcjpwYXNz" {
+				t.Errorf("unexpected registry authorization %q", req.Registry.Authorization)
+			}
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":"report-1"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/scan/report-1/report":
+			if !scanned {
+				scanned = true
+				w.Header().Set("Location", r.URL.Path)
+				w.WriteHeader(http.StatusFound)
+				return
+			}
+			w.Write([]byte(`{
+				"generated_at": "2021-06-10T00:00:00Z",
+				"scanner": {"name": "Trivy", "version": "0.18.0"},
+				"vulnerabilities": [
+					{"id": "CVE-1", "package": "openssl", "version": "1.1.1", "fix_version": "1.1.1k", "severity": "High"},
+					{"id": "CVE-2", "package": "zlib", "version": "1.2.11", "severity": "Low"},
+					{"id": "CVE-3", "package": "curl", "version": "7.64", "severity": "Negligible"}
+				]
+			}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	s, err := NewAdapterScanner(&api.ContainerRegistryScannerConfig{Url: srv.URL + "/", Token: "token"})
+	if err != nil {
+		t.Fatalf("NewAdapterScanner: %v", err)
+	}
+	ctx := context.Background()
+	art := &Artifact{
+		RegistryUrl: "https://registry.example.com",
+		Username:    "user",
+		Password:    "pass",
+		Repository:  "library/nginx",
+		Tag:         "1.19",
+		Digest:      "sha256:abc",
+	}
+	id, err := s.Scan(ctx, art)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if id != "report-1" {
+		t.Fatalf("report id = %q", id)
+	}
+	if _, err := s.GetReport(ctx, art, id); err != ErrReportNotReady {
+		t.Fatalf("GetReport in progress error = %v, want %v", err, ErrReportNotReady)
+	}
+	report, err := s.GetReport(ctx, art, id)
+	if err != nil {
+		t.Fatalf("GetReport: %v", err)
+	}
+	if report.Scanner != "Trivy 0.18.0" || report.Digest != "sha256:abc" {
+		t.Errorf("unexpected report %#v", report.ContainerImageVulnerabilitySummary)
+	}
+	if report.Total != 3 || report.Fixable != 1 || report.Severity != api.VulnerabilitySeverityHigh {
+		t.Errorf("unexpected summary %#v", report.ContainerImageVulnerabilitySummary)
+	}
+	if report.Summary[api.VulnerabilitySeverityLow] != 2 {
+		t.Errorf("low count = %d, want 2", report.Summary[api.VulnerabilitySeverityLow])
+	}
+}
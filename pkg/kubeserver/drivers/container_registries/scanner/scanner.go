@@ -0,0 +1,80 @@
+package scanner
+
+import (
+	"context"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+// ErrReportNotReady is returned by GetReport while the artifact is still being scanned
+const ErrReportNotReady = errors.Error("scan report not ready")
+
+// Artifact is image scanned in registry
+type Artifact struct {
+	// RegistryUrl is scheme and host of registry, e.g. https://registry.example.com
+	RegistryUrl string
+	Username    string
+	Password    string
+	// Repository is full repository path inside registry
+	Repository string
+	Tag        string
+	Digest     string
+	MimeType   string
+}
+
+// Scanner scans vulnerabilities of registry images asynchronously
+type Scanner interface {
+	GetType() string
+	// Scan requests scanning of artifact, returned id is used to fetch report
+	Scan(ctx context.Context, art *Artifact) (string, error)
+	// GetReport returns ErrReportNotReady while scanning is in progress
+	GetReport(ctx context.Context, art *Artifact, reportId string) (*api.ContainerImageVulnerabilityReport, error)
+}
+
+// NormalizeSeverity maps severities of different scanners to api.VulnerabilitySeverities
+func NormalizeSeverity(severity string) string {
+	switch severity {
+	case "Critical", "CRITICAL":
+		return api.VulnerabilitySeverityCritical
+	case "High", "HIGH":
+		return api.VulnerabilitySeverityHigh
+	case "Medium", "MEDIUM":
+		return api.VulnerabilitySeverityMedium
+	case "Low", "LOW", "Negligible", "NEGLIGIBLE":
+		return api.VulnerabilitySeverityLow
+	case "None", "NONE", "":
+		return api.VulnerabilitySeverityNone
+	}
+	return api.VulnerabilitySeverityUnknown
+}
+
+// SeverityRank returns smaller rank for more severe severity
+func SeverityRank(severity string) int {
+	for i, s := range api.VulnerabilitySeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return len(api.VulnerabilitySeverities)
+}
+
+// Summarize counts vulnerabilities of report by severity and sets highest severity
+func Summarize(report *api.ContainerImageVulnerabilityReport) {
+	report.Summary = make(map[string]int)
+	report.Severity = api.VulnerabilitySeverityNone
+	report.Total = len(report.Vulnerabilities)
+	report.Fixable = 0
+	for i := range report.Vulnerabilities {
+		v := &report.Vulnerabilities[i]
+		v.Severity = NormalizeSeverity(v.Severity)
+		report.Summary[v.Severity]++
+		if v.FixVersion != "" {
+			report.Fixable++
+		}
+		if SeverityRank(v.Severity) < SeverityRank(report.Severity) {
+			report.Severity = v.Severity
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package helm_repos
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/helm"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+)
+
+func init() {
+	models.RegisterRepoDriver(newOCIImpl())
+}
+
+func newOCIImpl() models.IRepoDriver {
+	return new(ociImpl)
+}
+
+type ociImpl struct{}
+
+func (o ociImpl) GetBackend() api.RepoBackend {
+	return api.RepoBackendOCI
+}
+
+func (o ociImpl) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data *api.RepoCreateInput) (*api.RepoCreateInput, error) {
+	if data.ContainerRegistryId != "" {
+		regObj, err := models.GetContainerRegistryManager().FetchByIdOrName(ctx, userCred, data.ContainerRegistryId)
+		if err != nil {
+			return nil, models.NewCheckIdOrNameError("container_registry_id", data.ContainerRegistryId, err)
+		}
+		reg := regObj.(*models.SContainerRegistry)
+		data.ContainerRegistryId = reg.GetId()
+		if data.Url == "" {
+			regUrl, err := url.Parse(reg.Url)
+			if err != nil {
+				return nil, httperrors.NewNotAcceptableError("Invalid container registry url %q: %v", reg.Url, err)
+			}
+			data.Url = fmt.Sprintf("%s://%s%s", helm.OCIScheme, regUrl.Host, strings.TrimSuffix(regUrl.Path, "/"))
+		}
+	}
+	if !helm.IsOCIURL(data.Url) {
+		return nil, httperrors.NewInputParameterError("oci repo url %q must start with %s://", data.Url, helm.OCIScheme)
+	}
+	return data, nil
+}
+
+func (o ociImpl) UploadChart(ctx context.Context, userCred mcclient.TokenCredential, repo *models.SRepo, chartName, chartPath string) (jsonutils.JSONObject, error) {
+	cli, err := helm.NewOCIClient(repo.ToEntry())
+	if err != nil {
+		return nil, errors.Wrap(err, "new oci client")
+	}
+	ref, err := cli.PushChart(ctx, chartPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "push chart %s", chartName)
+	}
+	log.Infof("chart %s pushed to %s", chartName, ref)
+	ret := jsonutils.NewDict()
+	ret.Add(jsonutils.NewString(ref), "ref")
+	return ret, nil
+}
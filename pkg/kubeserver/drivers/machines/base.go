@@ -56,7 +56,7 @@ func (d *sBaseDriver) RequestPrepareMachine(ctx context.Context, userCred mcclie
 	return machine.StartPrepareTask(ctx, task.GetUserCred(), jsonutils.Marshal(input).(*jsonutils.JSONDict), task.GetTaskId())
 }
 
-func (d *sBaseDriver) PrepareResource(session *mcclient.ClientSession, machine *models.SMachine, data *api.MachinePrepareInput) (jsonutils.JSONObject, error) {
+func (d *sBaseDriver) PrepareResource(ctx context.Context, session *mcclient.ClientSession, machine *models.SMachine, data *api.MachinePrepareInput) (jsonutils.JSONObject, error) {
 	return nil, nil
 }
 
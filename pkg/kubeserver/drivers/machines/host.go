@@ -0,0 +1,155 @@
+package machines
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/netutils"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models/manager"
+	"yunion.io/x/kubecomps/pkg/utils/ssh"
+)
+
+func init() {
+	// existing hosts can be added to any self build cluster deployed by kubespray
+	for _, provider := range []api.ProviderType{
+		api.ProviderTypeOnecloud,
+		api.ProviderTypeOnecloudKvm,
+		api.ProviderTypeAws,
+		api.ProviderTypeAliyun,
+	} {
+		models.RegisterMachineDriver(newHostDriver(provider))
+	}
+}
+
+// sHostDriver registers existing linux host by address and ssh login,
+// the address is used as machine resource id
+type sHostDriver struct {
+	*sBaseDriver
+	provider api.ProviderType
+}
+
+func newHostDriver(provider api.ProviderType) models.IMachineDriver {
+	return &sHostDriver{
+		sBaseDriver: newBaseDriver(),
+		provider:    provider,
+	}
+}
+
+func (d *sHostDriver) GetProvider() api.ProviderType {
+	return d.provider
+}
+
+func (d *sHostDriver) GetResourceType() api.MachineResourceType {
+	return api.MachineResourceTypeBaremetal
+}
+
+func (d *sHostDriver) ValidateCreateData(s *mcclient.ClientSession, input *api.CreateMachineData) error {
+	if input.ResourceType != api.MachineResourceTypeBaremetal {
+		return httperrors.NewInputParameterError("Invalid resource type: %q", input.ResourceType)
+	}
+	if _, err := netutils.NewIPV4Addr(input.Address); err != nil {
+		return httperrors.NewInputParameterError("Invalid host address %q", input.Address)
+	}
+	if input.Config == nil || input.Config.Host == nil {
+		return httperrors.NewNotEmptyError("Host config must provide")
+	}
+	config := input.Config.Host
+	if config.Username == "" {
+		config.Username = "root"
+	}
+	if config.Password == "" && config.PrivateKey == "" {
+		return httperrors.NewNotEmptyError("Password or private_key must provide")
+	}
+	if config.Port == 0 {
+		config.Port = api.DefaultMachineHostSSHPort
+	}
+	if config.Port < 0 || config.Port > 65535 {
+		return httperrors.NewInputParameterError("Invalid ssh port %d", config.Port)
+	}
+	m, err := models.MachineManager.GetMachineByResourceId(input.Address)
+	if err != nil {
+		return errors.Wrapf(err, "get machine by address %s", input.Address)
+	}
+	if m != nil {
+		return httperrors.NewConflictError("Host %s is used by machine %s", input.Address, m.GetName())
+	}
+	input.ResourceId = input.Address
+
+	// commands are executed by sudo when deploying, so check it doesn't need password
+	if out, err := ssh.RemoteSSHCommand(input.Address, config.Port, config.Username, config.Password, config.PrivateKey, "sudo -n true"); err != nil {
+		return httperrors.NewNotAcceptableError("Login host %s as %s and sudo: %v %s", input.Address, config.Username, err, out)
+	}
+	return nil
+}
+
+// PostCreate moves ssh login from create params to system metadata
+func (d *sHostDriver) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, cluster *models.SCluster, m *models.SMachine, data *jsonutils.JSONDict) error {
+	input, err := m.GetCreateInput(ctx, userCred)
+	if err != nil {
+		return errors.Wrap(err, "get create input")
+	}
+	if input.Config == nil || input.Config.Host == nil {
+		return errors.Errorf("host config of machine %s is empty", m.GetName())
+	}
+	if err := m.SetMetadata(ctx, api.MachineMetadataSSHLogin, input.Config.Host, userCred); err != nil {
+		return errors.Wrap(err, "set ssh login metadata")
+	}
+	input.Config.Host = &api.MachineCreateHostConfig{
+		Username: input.Config.Host.Username,
+		Port:     input.Config.Host.Port,
+	}
+	if err := m.SetMetadata(ctx, api.MachineMetadataCreateParams, jsonutils.Marshal(input).String(), userCred); err != nil {
+		return errors.Wrap(err, "strip ssh login from create params")
+	}
+	return m.SetPrivateIP(input.Address)
+}
+
+func (d *sHostDriver) PrepareResource(ctx context.Context, s *mcclient.ClientSession, m *models.SMachine, data *api.MachinePrepareInput) (jsonutils.JSONObject, error) {
+	login, err := m.GetSSHLoginInfo(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get ssh login")
+	}
+	out, err := ssh.RemoteSSHBashScript(m.Address, login.Port, login.Username, login.Password, login.PrivateKey, getHostPreflightScript())
+	if err != nil {
+		return nil, errors.Wrapf(err, "run pre-flight script on %s: %s", m.Address, out)
+	}
+	checks := evaluateHostPreflight(parseHostPreflightOutput(out), m.IsControlplane(), time.Now())
+	if err := m.SetMetadata(ctx, api.MachineMetadataPreflightChecks, checks, models.GetAdminCred()); err != nil {
+		log.Errorf("save machine %s pre-flight checks: %v", m.GetName(), err)
+	}
+	if err := getPreflightError(checks); err != nil {
+		return nil, errors.Wrapf(err, "host %s", m.Address)
+	}
+	return nil, nil
+}
+
+func (d *sHostDriver) PostPrepareResource(ctx context.Context, userCred mcclient.TokenCredential, m *models.SMachine, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+func (d *sHostDriver) GetPrivateIP(s *mcclient.ClientSession, resourceId string) (string, error) {
+	return resourceId, nil
+}
+
+func (d *sHostDriver) GetEIP(s *mcclient.ClientSession, resourceId string) (string, error) {
+	return "", nil
+}
+
+func (d *sHostDriver) GetInfoFromCloud(ctx context.Context, s *mcclient.ClientSession, m *models.SMachine) (*api.CloudMachineInfo, error) {
+	return &api.CloudMachineInfo{
+		Id:   m.GetResourceId(),
+		Name: m.GetName(),
+	}, nil
+}
+
+func (d *sHostDriver) ValidateDeleteCondition(ctx context.Context, userCred mcclient.TokenCredential, cluster *models.SCluster, machine *models.SMachine) error {
+	return cluster.GetDriver().ValidateDeleteMachines(ctx, userCred, cluster, []manager.IMachine{machine})
+}
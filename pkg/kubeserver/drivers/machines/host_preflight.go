@@ -0,0 +1,252 @@
+package machines
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+// hostPreflightScript collects host facts as 'key=value' lines, it's executed by root through sudo
+const hostPreflightScript = `
+. /etc/os-release 2>/dev/null
+echo "os_id=${ID}"
+echo "os_version=${VERSION_ID}"
+echo "arch=$(uname -m)"
+echo "kernel=$(uname -r)"
+echo "cpu=$(nproc)"
+echo "memory_mb=$(awk '/MemTotal/ {print int($2/1024)}' /proc/meminfo)"
+echo "swap_kb=$(awk 'NR>1 {s+=$3} END {print s+0}' /proc/swaps)"
+for mod in %s; do
+	if [ -d /sys/module/$mod ]; then
+		echo "module.$mod=loaded"
+	elif modinfo $mod >/dev/null 2>&1; then
+		echo "module.$mod=available"
+	else
+		echo "module.$mod=missing"
+	fi
+done
+echo "listen_ports=$(ss -ltn 2>/dev/null | awk 'NR>1 {n=split($4,a,":"); print a[n]}' | sort -un | tr '\n' ',')"
+ntp=$(timedatectl show -p NTPSynchronized --value 2>/dev/null)
+if [ -z "$ntp" ]; then
+	if timedatectl status 2>/dev/null | grep -Eqi '(NTP|System clock) synchronized: yes'; then
+		ntp=yes
+	else
+		ntp=no
+	fi
+fi
+echo "ntp_synced=$ntp"
+echo "timestamp=$(date +%%s)"
+`
+
+const (
+	// same as kubespray minimal_master_memory_mb and minimal_node_memory_mb
+	hostMinControlplaneMemoryMb = 1500
+	hostMinNodeMemoryMb         = 1024
+	// certificates and etcd leases are sensitive to clock skew
+	hostMaxTimeSkew = 30 * time.Second
+)
+
+var (
+	// hostSupportedOS maps os-release ID to minimal major version
+	hostSupportedOS = map[string]int{
+		"centos":    7,
+		"rhel":      7,
+		"rocky":     8,
+		"almalinux": 8,
+		"ol":        7,
+		"fedora":    35,
+		"ubuntu":    18,
+		"debian":    10,
+		"openEuler": 20,
+		"kylin":     10,
+	}
+
+	hostSupportedArchs = []string{"x86_64", "aarch64"}
+
+	// kernel modules required by container runtime and kube-proxy ipvs mode
+	hostRequiredModules = []string{"br_netfilter", "overlay", "ip_vs", "ip_vs_rr", "ip_vs_wrr", "ip_vs_sh"}
+
+	hostNodePorts         = []int{10250, 10256}
+	hostControlplanePorts = []int{6443, 2379, 2380, 10257, 10259}
+)
+
+func getHostPreflightScript() string {
+	return fmt.Sprintf(hostPreflightScript, strings.Join(hostRequiredModules, " "))
+}
+
+func parseHostPreflightOutput(out string) map[string]string {
+	ret := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			continue
+		}
+		ret[parts[0]] = parts[1]
+	}
+	return ret
+}
+
+func parseVersionNumbers(ver string, count int) []int {
+	ret := make([]int, 0, count)
+	for _, part := range strings.FieldsFunc(ver, func(r rune) bool { return r == '.' || r == '-' }) {
+		if len(ret) == count {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		ret = append(ret, n)
+	}
+	return ret
+}
+
+func newPreflightCheck(name string, pass bool, failStatus string, format string, args ...interface{}) api.MachinePreflightCheck {
+	status := api.MachinePreflightCheckPass
+	if !pass {
+		status = failStatus
+	}
+	return api.MachinePreflightCheck{
+		Name:    name,
+		Status:  status,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func checkHostOS(facts map[string]string) api.MachinePreflightCheck {
+	id, ver := facts["os_id"], facts["os_version"]
+	minVer, ok := hostSupportedOS[id]
+	if !ok {
+		return newPreflightCheck("os", false, api.MachinePreflightCheckFail, "unsupported os %q", id)
+	}
+	nums := parseVersionNumbers(ver, 1)
+	if len(nums) == 0 || nums[0] < minVer {
+		return newPreflightCheck("os", false, api.MachinePreflightCheckFail, "%s %s is older than %d", id, ver, minVer)
+	}
+	return newPreflightCheck("os", true, "", "%s %s", id, ver)
+}
+
+func checkHostArch(facts map[string]string) api.MachinePreflightCheck {
+	arch := facts["arch"]
+	return newPreflightCheck("arch", utils.IsInStringArray(arch, hostSupportedArchs), api.MachinePreflightCheckFail, "%s", arch)
+}
+
+func checkHostKernel(facts map[string]string) api.MachinePreflightCheck {
+	kernel := facts["kernel"]
+	nums := parseVersionNumbers(kernel, 2)
+	pass := len(nums) == 2 && (nums[0] > 3 || (nums[0] == 3 && nums[1] >= 10))
+	return newPreflightCheck("kernel", pass, api.MachinePreflightCheckFail, "%s, at least 3.10 is required", kernel)
+}
+
+func checkHostResources(facts map[string]string, isControlplane bool) []api.MachinePreflightCheck {
+	minCPU, minMem := 1, hostMinNodeMemoryMb
+	if isControlplane {
+		minCPU, minMem = 2, hostMinControlplaneMemoryMb
+	}
+	cpu, _ := strconv.Atoi(facts["cpu"])
+	mem, _ := strconv.Atoi(facts["memory_mb"])
+	return []api.MachinePreflightCheck{
+		newPreflightCheck("cpu", cpu >= minCPU, api.MachinePreflightCheckFail, "%d cores, at least %d is required", cpu, minCPU),
+		newPreflightCheck("memory", mem >= minMem, api.MachinePreflightCheckFail, "%dMB, at least %dMB is required", mem, minMem),
+	}
+}
+
+func checkHostSwap(facts map[string]string) api.MachinePreflightCheck {
+	swap, _ := strconv.Atoi(facts["swap_kb"])
+	if swap > 0 {
+		return newPreflightCheck("swap", false, api.MachinePreflightCheckWarn, "%dKB swap is used, it will be turned off when deploying", swap)
+	}
+	return newPreflightCheck("swap", true, "", "swap is off")
+}
+
+func checkHostModules(facts map[string]string) api.MachinePreflightCheck {
+	missing := make([]string, 0)
+	for _, mod := range hostRequiredModules {
+		if facts["module."+mod] == "missing" || facts["module."+mod] == "" {
+			missing = append(missing, mod)
+		}
+	}
+	if len(missing) != 0 {
+		return newPreflightCheck("kernel_module", false, api.MachinePreflightCheckFail, "missing modules: %s", strings.Join(missing, ","))
+	}
+	return newPreflightCheck("kernel_module", true, "", "%s are available", strings.Join(hostRequiredModules, ","))
+}
+
+func checkHostPorts(facts map[string]string, isControlplane bool) api.MachinePreflightCheck {
+	ports := append([]int{}, hostNodePorts...)
+	if isControlplane {
+		ports = append(ports, hostControlplanePorts...)
+	}
+	listening := make(map[int]bool)
+	for _, p := range strings.Split(facts["listen_ports"], ",") {
+		if n, err := strconv.Atoi(p); err == nil {
+			listening[n] = true
+		}
+	}
+	used := make([]int, 0)
+	for _, p := range ports {
+		if listening[p] {
+			used = append(used, p)
+		}
+	}
+	if len(used) != 0 {
+		sort.Ints(used)
+		return newPreflightCheck("port", false, api.MachinePreflightCheckFail, "ports %v are in use", used)
+	}
+	return newPreflightCheck("port", true, "", "ports %v are free", ports)
+}
+
+func checkHostTime(facts map[string]string, now time.Time) api.MachinePreflightCheck {
+	ts, err := strconv.ParseInt(facts["timestamp"], 10, 64)
+	if err != nil {
+		return newPreflightCheck("time_sync", false, api.MachinePreflightCheckFail, "invalid host timestamp %q", facts["timestamp"])
+	}
+	skew := now.Sub(time.Unix(ts, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > hostMaxTimeSkew {
+		return newPreflightCheck("time_sync", false, api.MachinePreflightCheckFail, "clock skew %s exceeds %s", skew, hostMaxTimeSkew)
+	}
+	if facts["ntp_synced"] != "yes" {
+		return newPreflightCheck("time_sync", false, api.MachinePreflightCheckWarn, "clock isn't synchronized by ntp")
+	}
+	return newPreflightCheck("time_sync", true, "", "clock is synchronized")
+}
+
+// evaluateHostPreflight checks host facts collected by hostPreflightScript
+func evaluateHostPreflight(facts map[string]string, isControlplane bool, now time.Time) []api.MachinePreflightCheck {
+	ret := []api.MachinePreflightCheck{
+		checkHostOS(facts),
+		checkHostArch(facts),
+		checkHostKernel(facts),
+	}
+	ret = append(ret, checkHostResources(facts, isControlplane)...)
+	ret = append(ret,
+		checkHostSwap(facts),
+		checkHostModules(facts),
+		checkHostPorts(facts, isControlplane),
+		checkHostTime(facts, now),
+	)
+	return ret
+}
+
+func getPreflightError(checks []api.MachinePreflightCheck) error {
+	msgs := make([]string, 0)
+	for _, c := range checks {
+		if c.Status == api.MachinePreflightCheckFail {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", c.Name, c.Message))
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return errors.Errorf("pre-flight checks failed: %s", strings.Join(msgs, "; "))
+}
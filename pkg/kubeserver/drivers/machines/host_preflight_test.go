@@ -0,0 +1,126 @@
+package machines
+
+import (
+	"testing"
+	"time"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+func TestEvaluateHostPreflight(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	output := `os_id=ubuntu
+os_version=22.04
+arch=x86_64
+kernel=5.15.0-91-generic
+cpu=4
+memory_mb=7940
+swap_kb=0
+module.br_netfilter=loaded
+module.overlay=loaded
+module.ip_vs=available
+module.ip_vs_rr=available
+module.ip_vs_wrr=available
+module.ip_vs_sh=available
+listen_ports=22,53,
+ntp_synced=yes
+timestamp=1700000003
+`
+	statusOf := func(checks []api.MachinePreflightCheck) map[string]string {
+		ret := make(map[string]string)
+		for _, c := range checks {
+			ret[c.Name] = c.Status
+		}
+		return ret
+	}
+
+	tests := []struct {
+		name           string
+		override       map[string]string
+		isControlplane bool
+		want           map[string]string
+		wantErr        bool
+	}{
+		{
+			name:           "all pass",
+			isControlplane: true,
+			want: map[string]string{
+				"os":            api.MachinePreflightCheckPass,
+				"arch":          api.MachinePreflightCheckPass,
+				"kernel":        api.MachinePreflightCheckPass,
+				"cpu":           api.MachinePreflightCheckPass,
+				"memory":        api.MachinePreflightCheckPass,
+				"swap":          api.MachinePreflightCheckPass,
+				"kernel_module": api.MachinePreflightCheckPass,
+				"port":          api.MachinePreflightCheckPass,
+				"time_sync":     api.MachinePreflightCheckPass,
+			},
+		},
+		{
+			name:     "swap and ntp only warn",
+			override: map[string]string{"swap_kb": "2097148", "ntp_synced": "no"},
+			want: map[string]string{
+				"swap":      api.MachinePreflightCheckWarn,
+				"time_sync": api.MachinePreflightCheckWarn,
+			},
+		},
+		{
+			name:     "unsupported os and old kernel",
+			override: map[string]string{"os_id": "centos", "os_version": "6", "kernel": "2.6.32-754.el6.x86_64"},
+			want: map[string]string{
+				"os":     api.MachinePreflightCheckFail,
+				"kernel": api.MachinePreflightCheckFail,
+			},
+			wantErr: true,
+		},
+		{
+			name:           "controlplane ports in use",
+			override:       map[string]string{"listen_ports": "22,2379,6443,"},
+			isControlplane: true,
+			want:           map[string]string{"port": api.MachinePreflightCheckFail},
+			wantErr:        true,
+		},
+		{
+			name:     "etcd port is free for node",
+			override: map[string]string{"listen_ports": "22,2379,"},
+			want:     map[string]string{"port": api.MachinePreflightCheckPass},
+		},
+		{
+			name:     "missing module and clock skew",
+			override: map[string]string{"module.br_netfilter": "missing", "timestamp": "1700000120"},
+			want: map[string]string{
+				"kernel_module": api.MachinePreflightCheckFail,
+				"time_sync":     api.MachinePreflightCheckFail,
+			},
+			wantErr: true,
+		},
+		{
+			name:           "controlplane needs 2 cpu",
+			override:       map[string]string{"cpu": "1", "memory_mb": "1200"},
+			isControlplane: true,
+			want: map[string]string{
+				"cpu":    api.MachinePreflightCheckFail,
+				"memory": api.MachinePreflightCheckFail,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			facts := parseHostPreflightOutput(output)
+			for k, v := range tt.override {
+				facts[k] = v
+			}
+			checks := evaluateHostPreflight(facts, tt.isControlplane, now)
+			got := statusOf(checks)
+			for name, status := range tt.want {
+				if got[name] != status {
+					t.Errorf("check %s status = %s, want %s", name, got[name], status)
+				}
+			}
+			if err := getPreflightError(checks); (err != nil) != tt.wantErr {
+				t.Errorf("getPreflightError() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
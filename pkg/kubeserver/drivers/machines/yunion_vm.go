@@ -300,6 +300,7 @@ func (d *sYunionVMDriver) GetPrivateIP(s *mcclient.ClientSession, resourceId str
 }
 
 func (d *sYunionVMDriver) PrepareResource(
+	ctx context.Context,
 	s *mcclient.ClientSession,
 	m *models.SMachine,
 	data *api.MachinePrepareInput) (jsonutils.JSONObject, error) {
@@ -355,7 +356,7 @@ func (d *sYunionVMDriver) PrepareResource(
 		return nil, errors.Wrapf(err, "prepare other resources for machine %s", m.GetName())
 	}
 
-	if err := drv.PostPrepareServerResource(context.Background(), s, m, srvDetail); err != nil {
+	if err := drv.PostPrepareServerResource(ctx, s, m, srvDetail); err != nil {
 		return nil, errors.Wrapf(err, "PostPrepareServerResource %s", srvDetail.Name)
 	}
 
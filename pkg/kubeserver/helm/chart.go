@@ -1,6 +1,7 @@
 package helm
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -212,6 +213,10 @@ func (c ChartClient) LocateChartPath(repoName, chartName, version string, repo *
 		log.Infof("download chart %s:%s of repo %v", chartName, version, repo)
 	}
 
+	if IsOCIURL(repo.URL) {
+		return c.pullOCIChart(chartName, chartVersion.Version, chPath, repo)
+	}
+
 	pathOpt := c.NewChartPathOptions(version, false, repo)
 	pathOpt.InsecureSkipTLSverify = true
 
@@ -224,6 +229,17 @@ func (c ChartClient) LocateChartPath(repoName, chartName, version string, repo *
 	return cp, nil
 }
 
+func (c ChartClient) pullOCIChart(chartName, version, chPath string, repo *repo.Entry) (string, error) {
+	cli, err := NewOCIClient(repo)
+	if err != nil {
+		return "", err
+	}
+	if err := cli.PullChart(context.Background(), chartName, version, chPath); err != nil {
+		return "", errors.Wrapf(err, "pull oci chart %s:%s", chartName, version)
+	}
+	return chPath, nil
+}
+
 func (c ChartClient) LocateChart(repoName, chartName, version string, repo *repo.Entry) (*chart.Chart, error) {
 	chPath, err := c.LocateChartPath(repoName, chartName, version, repo)
 	if err != nil {
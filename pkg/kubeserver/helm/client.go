@@ -5,9 +5,11 @@ import (
 	"io/ioutil"
 	"os"
 	"regexp"
+	"strings"
 
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/cli/values"
 	"helm.sh/helm/v3/pkg/getter"
@@ -43,6 +45,7 @@ type IRelease interface {
 	List() *action.List
 	Create(*api.ReleaseCreateInput) (*release.Release, error)
 	Update(*api.ReleaseUpdateInput) (*release.Release, error)
+	UpgradePreview(*api.ReleaseUpdateInput) (*api.ReleaseUpgradePreview, error)
 	Install() *action.Install
 	UnInstall() *action.Uninstall
 	Upgrade() *action.Upgrade
@@ -258,37 +261,113 @@ func isChartInstallable(ch *chart.Chart) (bool, error) {
 	return false, errors.Errorf("%s charts are not installable", ch.Metadata.Type)
 }
 
-func (r releaseClient) Update(input *api.ReleaseUpdateInput) (*release.Release, error) {
+// prepareUpgrade returns chart and values used to upgrade release
+func (r releaseClient) prepareUpgrade(input *api.ReleaseUpdateInput) (*chart.Chart, map[string]interface{}, error) {
 	if input.Version == "" {
 		input.Version = ">0.0.0-0"
 	}
 
 	vals, err := r.getValueOptions(input.Values, input.Sets)
 	if err != nil {
-		return nil, errors.Wrapf(err, "getValueOptions by values: %s, sets: %#v", input.Values, input.Sets)
+		return nil, nil, errors.Wrapf(err, "getValueOptions by values: %s, sets: %#v", input.Values, input.Sets)
 	}
 	valObj := jsonutils.Marshal(vals).(*jsonutils.JSONDict)
 	if input.ValuesJson != nil {
 		valObj.Update(input.ValuesJson)
 		vals, err = r.getValueOptions(valObj.YAMLString(), nil)
 		if err != nil {
-			return nil, errors.Wrapf(err, "getValueOptions again by %s", valObj.PrettyString())
+			return nil, nil, errors.Wrapf(err, "getValueOptions again by %s", valObj.PrettyString())
 		}
 	}
 	cp, err := r.chartClient.Show(input.Repo, input.ChartName, input.Version)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Check chart dependencies to make sure all are present in /charts
 	if req := cp.Metadata.Dependencies; req != nil {
 		if err := action.CheckDependencies(cp, req); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
+	return cp, vals, nil
+}
 
+func (r releaseClient) newUpgrade(input *api.ReleaseUpdateInput) *action.Upgrade {
 	cli := r.Upgrade()
 	cli.Version = input.Version
 	cli.Namespace = input.Namespace
+	return cli
+}
+
+func (r releaseClient) Update(input *api.ReleaseUpdateInput) (*release.Release, error) {
+	cp, vals, err := r.prepareUpgrade(input)
+	if err != nil {
+		return nil, err
+	}
+	cli := r.newUpgrade(input)
 	return cli.Run(input.ReleaseName, cp, vals)
 }
+
+// validateValuesSchema validates values going to be rendered against chart values.schema.json,
+// values of deployed release are copied when no value provided like the upgrade does
+func validateValuesSchema(cp *chart.Chart, current *release.Release, vals map[string]interface{}) error {
+	if len(vals) == 0 && len(current.Config) > 0 {
+		vals = current.Config
+	}
+	merged, err := chartutil.CoalesceValues(cp, vals)
+	if err != nil {
+		return errors.Wrap(err, "coalesce values")
+	}
+	return chartutil.ValidateAgainstSchema(cp, merged)
+}
+
+// UpgradePreview renders release by new chart and values, returns changes compared with deployed release
+func (r releaseClient) UpgradePreview(input *api.ReleaseUpdateInput) (*api.ReleaseUpgradePreview, error) {
+	current, err := r.client.config.Releases.Deployed(input.ReleaseName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get deployed release %s", input.ReleaseName)
+	}
+	cp, vals, err := r.prepareUpgrade(input)
+	if err != nil {
+		return nil, err
+	}
+	ret := &api.ReleaseUpgradePreview{
+		CurrentRevision: current.Version,
+		CurrentChart:    formatChart(current.Chart),
+		Chart:           formatChart(cp),
+		SchemaErrors:    make([]string, 0),
+		Resources:       make([]api.ReleaseResourceDiff, 0),
+		Warnings:        make([]string, 0),
+	}
+	if err := validateValuesSchema(cp, current, vals); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				ret.SchemaErrors = append(ret.SchemaErrors, line)
+			}
+		}
+		// rendering fails by same schema errors
+		return ret, nil
+	}
+
+	// same upgrade options as Update, so that preview shows what will be applied
+	cli := r.newUpgrade(input)
+	cli.DryRun = true
+	rendered, err := cli.Run(input.ReleaseName, cp, vals)
+	if err != nil {
+		return nil, errors.Wrap(err, "dry run upgrade")
+	}
+	ret.Resources, err = DiffManifests(current.Manifest, rendered.Manifest, current.Namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "diff manifests")
+	}
+	ret.Warnings = DiffWarnings(ret.Resources)
+	return ret, nil
+}
+
+func formatChart(c *chart.Chart) string {
+	if c == nil || c.Metadata == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s-%s", c.Metadata.Name, c.Metadata.Version)
+}
@@ -0,0 +1,262 @@
+package helm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"sigs.k8s.io/yaml"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+const (
+	secretKind = "Secret"
+	// maskedSecretValue replaces values of secret data in diff
+	maskedSecretValue = "******"
+)
+
+type immutableField struct {
+	path []string
+	// ignoreUnset skips when field is not rendered, which means it's allocated by cluster
+	ignoreUnset bool
+}
+
+// immutableFields are fields kubernetes rejects to update in place
+var immutableFields = map[string][]immutableField{
+	"Deployment": {{path: []string{"spec", "selector"}}},
+	"ReplicaSet": {{path: []string{"spec", "selector"}}},
+	"DaemonSet":  {{path: []string{"spec", "selector"}}},
+	"StatefulSet": {
+		{path: []string{"spec", "selector"}},
+		{path: []string{"spec", "serviceName"}},
+		{path: []string{"spec", "volumeClaimTemplates"}},
+		{path: []string{"spec", "podManagementPolicy"}},
+	},
+	"Job": {
+		{path: []string{"spec", "selector"}, ignoreUnset: true},
+		{path: []string{"spec", "template"}},
+		{path: []string{"spec", "completions"}},
+	},
+	"Service": {{path: []string{"spec", "clusterIP"}, ignoreUnset: true}},
+	"PersistentVolumeClaim": {
+		{path: []string{"spec", "storageClassName"}, ignoreUnset: true},
+		{path: []string{"spec", "volumeName"}, ignoreUnset: true},
+		{path: []string{"spec", "accessModes"}},
+	},
+}
+
+type manifestResource struct {
+	apiVersion string
+	kind       string
+	namespace  string
+	name       string
+	content    string
+	obj        map[string]interface{}
+}
+
+func (r manifestResource) key() string {
+	return fmt.Sprintf("%s/%s/%s", r.kind, r.namespace, r.name)
+}
+
+// parseManifest splits release manifest to resources, namespace is defaulted by release namespace
+func parseManifest(manifest, namespace string) (map[string]*manifestResource, error) {
+	ret := make(map[string]*manifestResource)
+	for _, content := range releaseutil.SplitManifests(manifest) {
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(content), &obj); err != nil {
+			return nil, errors.Wrapf(err, "unmarshal manifest %s", content)
+		}
+		kind, _ := obj["kind"].(string)
+		if kind == "" {
+			continue
+		}
+		res := &manifestResource{
+			kind:      kind,
+			namespace: namespace,
+			content:   strings.TrimSpace(content) + "\n",
+			obj:       obj,
+		}
+		res.apiVersion, _ = obj["apiVersion"].(string)
+		if meta, ok := obj["metadata"].(map[string]interface{}); ok {
+			res.name, _ = meta["name"].(string)
+			if ns, _ := meta["namespace"].(string); ns != "" {
+				res.namespace = ns
+			}
+		}
+		ret[res.key()] = res
+	}
+	return ret, nil
+}
+
+func getField(obj map[string]interface{}, path []string) interface{} {
+	var cur interface{} = obj
+	for _, p := range path {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[p]
+	}
+	return cur
+}
+
+// immutableChanges returns immutable fields changed between deployed and rendered object
+func immutableChanges(oldRes, newRes *manifestResource) []string {
+	ret := make([]string, 0)
+	for _, f := range immutableFields[oldRes.kind] {
+		oldVal, newVal := getField(oldRes.obj, f.path), getField(newRes.obj, f.path)
+		if newVal == nil && f.ignoreUnset {
+			continue
+		}
+		if !reflect.DeepEqual(oldVal, newVal) {
+			ret = append(ret, strings.Join(f.path, "."))
+		}
+	}
+	// configmaps and secrets marked immutable can't change content
+	if immutable, _ := oldRes.obj["immutable"].(bool); immutable {
+		for _, field := range []string{"data", "binaryData", "stringData"} {
+			if !reflect.DeepEqual(oldRes.obj[field], newRes.obj[field]) {
+				ret = append(ret, field)
+			}
+		}
+	}
+	return ret
+}
+
+// maskSecretContent hides values of secret data and stringData,
+// values differ from the other side are marked so changes are still shown in diff
+func maskSecretContent(res, other *manifestResource, side string) (string, error) {
+	masked := make(map[string]interface{}, len(res.obj))
+	for k, v := range res.obj {
+		masked[k] = v
+	}
+	for _, field := range []string{"data", "stringData"} {
+		vals, ok := res.obj[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var otherVals map[string]interface{}
+		if other != nil {
+			otherVals, _ = other.obj[field].(map[string]interface{})
+		}
+		maskedVals := make(map[string]interface{}, len(vals))
+		for k, v := range vals {
+			if ov, ok := otherVals[k]; ok && reflect.DeepEqual(ov, v) {
+				maskedVals[k] = maskedSecretValue
+			} else {
+				maskedVals[k] = fmt.Sprintf("%s (%s)", maskedSecretValue, side)
+			}
+		}
+		masked[field] = maskedVals
+	}
+	out, err := yaml.Marshal(masked)
+	if err != nil {
+		return "", errors.Wrapf(err, "marshal masked secret %s", res.key())
+	}
+	return string(out), nil
+}
+
+func unifiedDiff(oldContent, newContent, name string) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(oldContent),
+		B:        difflib.SplitLines(newContent),
+		FromFile: "deployed/" + name,
+		ToFile:   "rendered/" + name,
+		Context:  3,
+	})
+}
+
+// DiffManifests compares deployed release manifest with rendered one per resource
+func DiffManifests(oldManifest, newManifest, namespace string) ([]api.ReleaseResourceDiff, error) {
+	oldRes, err := parseManifest(oldManifest, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse deployed manifest")
+	}
+	newRes, err := parseManifest(newManifest, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse rendered manifest")
+	}
+	keys := make([]string, 0, len(oldRes)+len(newRes))
+	for k := range oldRes {
+		keys = append(keys, k)
+	}
+	for k := range newRes {
+		if _, ok := oldRes[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	ret := make([]api.ReleaseResourceDiff, 0, len(keys))
+	for _, k := range keys {
+		o, n := oldRes[k], newRes[k]
+		ref := n
+		if ref == nil {
+			ref = o
+		}
+		diff := api.ReleaseResourceDiff{
+			ApiVersion: ref.apiVersion,
+			Kind:       ref.kind,
+			Namespace:  ref.namespace,
+			Name:       ref.name,
+		}
+		oldContent, newContent := "", ""
+		switch {
+		case o == nil:
+			diff.Action = api.ReleaseResourceActionAdded
+			newContent = n.content
+		case n == nil:
+			diff.Action = api.ReleaseResourceActionRemoved
+			oldContent = o.content
+		case reflect.DeepEqual(o.obj, n.obj):
+			diff.Action = api.ReleaseResourceActionUnchanged
+		default:
+			diff.Action = api.ReleaseResourceActionChanged
+			oldContent, newContent = o.content, n.content
+			if changes := immutableChanges(o, n); len(changes) > 0 {
+				diff.ImmutableChanges = changes
+			}
+		}
+		if diff.Action != api.ReleaseResourceActionUnchanged {
+			if ref.kind == secretKind {
+				if o != nil && oldContent != "" {
+					if oldContent, err = maskSecretContent(o, n, "deployed"); err != nil {
+						return nil, err
+					}
+				}
+				if n != nil && newContent != "" {
+					if newContent, err = maskSecretContent(n, o, "rendered"); err != nil {
+						return nil, err
+					}
+				}
+			}
+			diff.Diff, err = unifiedDiff(oldContent, newContent, k)
+			if err != nil {
+				return nil, errors.Wrapf(err, "diff %s", k)
+			}
+		}
+		ret = append(ret, diff)
+	}
+	return ret, nil
+}
+
+// DiffWarnings summarizes changes need to be reviewed before upgrade
+func DiffWarnings(diffs []api.ReleaseResourceDiff) []string {
+	ret := make([]string, 0)
+	for _, d := range diffs {
+		name := fmt.Sprintf("%s %s/%s", d.Kind, d.Namespace, d.Name)
+		if d.Action == api.ReleaseResourceActionRemoved {
+			ret = append(ret, fmt.Sprintf("%s will be deleted", name))
+		}
+		if len(d.ImmutableChanges) > 0 {
+			ret = append(ret, fmt.Sprintf("%s immutable fields changed: %s, upgrade will fail unless it is recreated", name, strings.Join(d.ImmutableChanges, ", ")))
+		}
+	}
+	return ret
+}
@@ -0,0 +1,178 @@
+package helm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+const deployedManifest = `---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  clusterIP: 10.0.0.10
+  ports:
+  - port: 80
+---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+---
+# Source: web/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-config
+data:
+  key: value
+`
+
+const renderedManifest = `---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: web-v2
+---
+# Source: web/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web-secret
+  namespace: other
+`
+
+func TestDiffManifests(t *testing.T) {
+	diffs, err := DiffManifests(deployedManifest, renderedManifest, "default")
+	if err != nil {
+		t.Fatalf("diff manifests: %v", err)
+	}
+	got := make(map[string]api.ReleaseResourceDiff)
+	for _, d := range diffs {
+		got[d.Kind+"/"+d.Namespace+"/"+d.Name] = d
+	}
+	wantActions := map[string]string{
+		"ConfigMap/default/web-config": api.ReleaseResourceActionRemoved,
+		"Deployment/default/web":       api.ReleaseResourceActionChanged,
+		"Secret/other/web-secret":      api.ReleaseResourceActionAdded,
+		"Service/default/web":          api.ReleaseResourceActionChanged,
+	}
+	if len(got) != len(wantActions) {
+		t.Fatalf("got %d resources, want %d", len(got), len(wantActions))
+	}
+	for k, action := range wantActions {
+		if got[k].Action != action {
+			t.Errorf("%s action = %s, want %s", k, got[k].Action, action)
+		}
+	}
+
+	deploy := got["Deployment/default/web"]
+	if !reflect.DeepEqual(deploy.ImmutableChanges, []string{"spec.selector"}) {
+		t.Errorf("deployment immutable changes = %v", deploy.ImmutableChanges)
+	}
+	if !strings.Contains(deploy.Diff, "-  replicas: 1") || !strings.Contains(deploy.Diff, "+  replicas: 2") {
+		t.Errorf("deployment diff missing replicas change:\n%s", deploy.Diff)
+	}
+	// cluster ip not rendered is allocated by cluster
+	if len(got["Service/default/web"].ImmutableChanges) != 0 {
+		t.Errorf("service immutable changes = %v", got["Service/default/web"].ImmutableChanges)
+	}
+
+	warnings := DiffWarnings(diffs)
+	if len(warnings) != 2 {
+		t.Errorf("warnings = %v", warnings)
+	}
+}
+
+func TestDiffManifestsUnchanged(t *testing.T) {
+	// only source comments differ
+	rendered := strings.ReplaceAll(deployedManifest, "# Source: web/", "# Source: web-v2/")
+	diffs, err := DiffManifests(deployedManifest, rendered, "default")
+	if err != nil {
+		t.Fatalf("diff manifests: %v", err)
+	}
+	for _, d := range diffs {
+		if d.Action != api.ReleaseResourceActionUnchanged || d.Diff != "" {
+			t.Errorf("%s/%s should be unchanged, got %s", d.Kind, d.Name, d.Action)
+		}
+	}
+}
+
+func TestImmutableConfigMap(t *testing.T) {
+	oldRes := &manifestResource{kind: "ConfigMap", obj: map[string]interface{}{
+		"immutable": true,
+		"data":      map[string]interface{}{"a": "1"},
+	}}
+	newRes := &manifestResource{kind: "ConfigMap", obj: map[string]interface{}{
+		"immutable": true,
+		"data":      map[string]interface{}{"a": "2"},
+	}}
+	if changes := immutableChanges(oldRes, newRes); !reflect.DeepEqual(changes, []string{"data"}) {
+		t.Errorf("changes = %v", changes)
+	}
+}
+
+func TestDiffManifestsMaskSecret(t *testing.T) {
+	deployed := `apiVersion: v1
+kind: Secret
+metadata:
+  name: web-auth
+data:
+  user: YWRtaW4=
+  password: b2xkLXNlY3JldA==
+`
+	rendered := `apiVersion: v1
+kind: Secret
+metadata:
+  name: web-auth
+data:
+  user: YWRtaW4=
+  password: bmV3LXNlY3JldA==
+stringData:
+  token: plain-token
+`
+	diffs, err := DiffManifests(deployed, rendered, "default")
+	if err != nil {
+		t.Fatalf("diff manifests: %v", err)
+	}
+	if len(diffs) != 1 || diffs[0].Action != api.ReleaseResourceActionChanged {
+		t.Fatalf("diffs = %#v", diffs)
+	}
+	diff := diffs[0].Diff
+	for _, secret := range []string{"YWRtaW4=", "b2xkLXNlY3JldA==", "bmV3LXNlY3JldA==", "plain-token"} {
+		if strings.Contains(diff, secret) {
+			t.Errorf("diff contains secret value %q:\n%s", secret, diff)
+		}
+	}
+	for _, line := range []string{"-  password: '****** (deployed)'", "+  password: '****** (rendered)'", "+  token: '****** (rendered)'"} {
+		if !strings.Contains(diff, line) {
+			t.Errorf("diff should contain %q:\n%s", line, diff)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/utils/gitrepo"
+)
+
+const (
+	GitSourceDefaultBranch     = "master"
+	GitSourceDefaultValuesFile = "values.yaml"
+)
+
+// GitSource is chart and values loaded from one commit of git repository
+type GitSource struct {
+	Commit string
+	// Chart is nil when source path only holds values files
+	Chart  *chart.Chart
+	Values map[string]interface{}
+}
+
+// SetGitSourceDefaults fills default branch and values files of input
+func SetGitSourceDefaults(src *api.ReleaseGitSource) {
+	if src.Branch == "" {
+		src.Branch = GitSourceDefaultBranch
+	}
+	if len(src.ValuesFiles) == 0 {
+		src.ValuesFiles = []string{GitSourceDefaultValuesFile}
+	}
+}
+
+// LoadGitSource syncs working copy to latest commit of branch, then loads chart and values under source path
+func LoadGitSource(ctx context.Context, co *gitrepo.Checkout, src *api.ReleaseGitSource) (*GitSource, error) {
+	commit, err := co.Sync(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "sync git repository %s", src.Url)
+	}
+	dir, err := co.Path(src.Path)
+	if err != nil {
+		return nil, err
+	}
+	ret := &GitSource{
+		Commit: commit,
+		Values: map[string]interface{}{},
+	}
+	if _, err := os.Stat(filepath.Join(dir, chartutil.ChartfileName)); err == nil {
+		ret.Chart, err = loader.LoadDir(dir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "load chart from %s", src.Path)
+		}
+	}
+	for _, f := range src.ValuesFiles {
+		fp, err := co.Path(filepath.Join(src.Path, f))
+		if err != nil {
+			return nil, err
+		}
+		vals, err := chartutil.ReadValuesFile(fp)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read values file %s", f)
+		}
+		// latter values file overrides former
+		ret.Values = chartutil.CoalesceTables(vals.AsMap(), ret.Values)
+	}
+	return ret, nil
+}
+
+// GitSyncDescription is recorded as release revision description
+func GitSyncDescription(commit string) string {
+	return fmt.Sprintf("Git sync commit %s", commit)
+}
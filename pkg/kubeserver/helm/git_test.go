@@ -0,0 +1,86 @@
+package helm
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/utils/gitrepo"
+)
+
+func TestLoadGitSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	tmpDir, err := ioutil.TempDir("", "helm-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	git := func(dir string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	bareDir := filepath.Join(tmpDir, "deploy.git")
+	workDir := filepath.Join(tmpDir, "work")
+	git(tmpDir, "init", "--bare", bareDir)
+	git(tmpDir, "clone", bareDir, workDir)
+	files := map[string]string{
+		"web/Chart.yaml":            "apiVersion: v2\nname: web\nversion: 0.1.0\n",
+		"web/values.yaml":           "replicas: 1\nimage:\n  tag: v1\n",
+		"web/templates/config.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web\n",
+		"web/prod.yaml":             "image:\n  tag: v2\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(workDir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git(workDir, "add", ".")
+	git(workDir, "commit", "-m", "add web chart")
+	git(workDir, "push", "origin", "HEAD:master")
+
+	src := &api.ReleaseGitSource{
+		Url:         "file://" + bareDir,
+		Path:        "web",
+		ValuesFiles: []string{"values.yaml", "prod.yaml"},
+	}
+	SetGitSourceDefaults(src)
+	co := gitrepo.NewCheckout(src.Url, src.Branch, filepath.Join(tmpDir, "checkout"))
+	gs, err := LoadGitSource(context.Background(), co, src)
+	if err != nil {
+		t.Fatalf("load git source: %v", err)
+	}
+	if gs.Commit == "" {
+		t.Error("empty commit")
+	}
+	if gs.Chart == nil || gs.Chart.Metadata.Name != "web" || gs.Chart.Metadata.Version != "0.1.0" {
+		t.Fatalf("chart = %#v", gs.Chart)
+	}
+	if gs.Values["replicas"] != float64(1) {
+		t.Errorf("replicas = %v", gs.Values["replicas"])
+	}
+	if tag := gs.Values["image"].(map[string]interface{})["tag"]; tag != "v2" {
+		t.Errorf("image tag = %v", tag)
+	}
+
+	src.ValuesFiles = []string{"missing.yaml"}
+	if _, err := LoadGitSource(context.Background(), co, src); err == nil {
+		t.Error("want error of missing values file")
+	}
+}
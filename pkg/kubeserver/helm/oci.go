@@ -0,0 +1,373 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/types"
+	"github.com/regclient/regclient/types/manifest"
+	v1 "github.com/regclient/regclient/types/oci/v1"
+	"github.com/regclient/regclient/types/ref"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/repo"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	OCIScheme = "oci"
+
+	// HelmChartConfigMediaType is the media type of chart metadata inside OCI manifest
+	HelmChartConfigMediaType = "application/vnd.cncf.helm.config.v1+json"
+	// HelmChartContentLayerMediaType is the media type of chart archive inside OCI manifest
+	HelmChartContentLayerMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	// legacyChartLayerMediaType is pushed by helm before v3.7
+	legacyChartLayerMediaType = "application/tar+gzip"
+)
+
+// IsOCIURL returns true when url is an oci:// registry reference
+func IsOCIURL(url string) bool {
+	return strings.HasPrefix(url, OCIScheme+"://")
+}
+
+// ParseOCIRef splits reference like oci://registry/path/chart:version to repository url, chart name and version
+func ParseOCIRef(ociRef string) (string, string, string, error) {
+	if !IsOCIURL(ociRef) {
+		return "", "", "", errors.Errorf("%q is not an oci reference", ociRef)
+	}
+	refPath := strings.TrimPrefix(ociRef, OCIScheme+"://")
+	version := ""
+	slashIdx := strings.LastIndex(refPath, "/")
+	if colonIdx := strings.LastIndex(refPath, ":"); colonIdx > slashIdx {
+		version = refPath[colonIdx+1:]
+		refPath = refPath[:colonIdx]
+	}
+	if slashIdx <= 0 || slashIdx == len(refPath)-1 {
+		return "", "", "", errors.Errorf("oci reference %q must contain registry and chart name", ociRef)
+	}
+	return fmt.Sprintf("%s://%s", OCIScheme, refPath[:slashIdx]), refPath[slashIdx+1:], chartVersionFromTag(version), nil
+}
+
+// chartVersionToTag converts chart version to OCI tag, '+' is not allowed inside tag
+func chartVersionToTag(version string) string {
+	return strings.ReplaceAll(version, "+", "_")
+}
+
+func chartVersionFromTag(tag string) string {
+	return strings.ReplaceAll(tag, "_", "+")
+}
+
+// OCIClient manages helm charts stored as OCI artifacts of a registry
+type OCIClient struct {
+	registry   string
+	pathPrefix string
+	rc         *regclient.RegClient
+}
+
+// OCIPlainHTTPQuery is query of oci repo url to access registry by plain http,
+// it's kept in url because helm repo entry has no field of it
+const OCIPlainHTTPQuery = "plain_http"
+
+// OCIURLWithPlainHTTP marks oci repo url to access registry by plain http
+func OCIURLWithPlainHTTP(repoUrl string) string {
+	return fmt.Sprintf("%s?%s=true", strings.TrimSuffix(repoUrl, "/"), OCIPlainHTTPQuery)
+}
+
+// NewOCIClient creates client by repo entry whose url is like oci://registry/path,
+// registry is accessed by https unless plain http is set in url, and certificate
+// is only not verified when InsecureSkipTLSverify of entry is set
+func NewOCIClient(entry *repo.Entry) (*OCIClient, error) {
+	if !IsOCIURL(entry.URL) {
+		return nil, errors.Errorf("repo %s url %q is not oci", entry.Name, entry.URL)
+	}
+	u, err := url.Parse(entry.URL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse oci url %q", entry.URL)
+	}
+	if u.Host == "" {
+		return nil, errors.Errorf("registry of oci url %q is empty", entry.URL)
+	}
+	cli := &OCIClient{
+		registry:   u.Host,
+		pathPrefix: strings.Trim(u.Path, "/"),
+	}
+	confHost := config.Host{
+		Name:     cli.registry,
+		Hostname: cli.registry,
+		User:     entry.Username,
+		Pass:     entry.Password,
+		RepoAuth: true,
+		TLS:      config.TLSEnabled,
+	}
+	if u.Query().Get(OCIPlainHTTPQuery) == "true" {
+		confHost.TLS = config.TLSDisabled
+	} else if entry.InsecureSkipTLSverify {
+		confHost.TLS = config.TLSInsecure
+	}
+	cli.rc = regclient.New(regclient.WithConfigHost(confHost))
+	return cli, nil
+}
+
+func (c *OCIClient) chartRepository(chartName string) string {
+	if c.pathPrefix == "" {
+		return chartName
+	}
+	return fmt.Sprintf("%s/%s", c.pathPrefix, chartName)
+}
+
+func (c *OCIClient) chartRef(chartName, version string) (ref.Ref, error) {
+	refStr := fmt.Sprintf("%s/%s", c.registry, c.chartRepository(chartName))
+	if version != "" {
+		refStr = fmt.Sprintf("%s:%s", refStr, chartVersionToTag(version))
+	}
+	r, err := ref.New(refStr)
+	if err != nil {
+		return ref.Ref{}, errors.Wrapf(err, "parse reference %q", refStr)
+	}
+	return r, nil
+}
+
+// ChartURL returns oci reference of chart version
+func (c *OCIClient) ChartURL(chartName, version string) string {
+	return fmt.Sprintf("%s://%s/%s:%s", OCIScheme, c.registry, c.chartRepository(chartName), chartVersionToTag(version))
+}
+
+// ListCharts returns chart names directly under registry path prefix
+func (c *OCIClient) ListCharts(ctx context.Context) ([]string, error) {
+	rl, err := c.rc.RepoList(ctx, c.registry)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list repositories of %s", c.registry)
+	}
+	repos, err := rl.GetRepos()
+	if err != nil {
+		return nil, errors.Wrap(err, "get repositories")
+	}
+	prefix := ""
+	if c.pathPrefix != "" {
+		prefix = c.pathPrefix + "/"
+	}
+	ret := make([]string, 0)
+	for _, r := range repos {
+		if !strings.HasPrefix(r, prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(r, prefix)
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// ListChartVersions returns versions of chart converted from repository tags
+func (c *OCIClient) ListChartVersions(ctx context.Context, chartName string) ([]string, error) {
+	r, err := c.chartRef(chartName, "")
+	if err != nil {
+		return nil, err
+	}
+	tl, err := c.rc.TagList(ctx, r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list tags of %s", r.CommonName())
+	}
+	tags, err := tl.GetTags()
+	if err != nil {
+		return nil, errors.Wrap(err, "get tags")
+	}
+	ret := make([]string, len(tags))
+	for i := range tags {
+		ret[i] = chartVersionFromTag(tags[i])
+	}
+	return ret, nil
+}
+
+// getChartDescriptors returns config and chart content layer descriptors of chart manifest
+func (c *OCIClient) getChartDescriptors(ctx context.Context, r ref.Ref) (types.Descriptor, types.Descriptor, error) {
+	m, err := c.rc.ManifestGet(ctx, r)
+	if err != nil {
+		return types.Descriptor{}, types.Descriptor{}, errors.Wrapf(err, "get manifest %s", r.CommonName())
+	}
+	mi, ok := m.(manifest.Imager)
+	if !ok {
+		return types.Descriptor{}, types.Descriptor{}, errors.Errorf("manifest %s is not an image", r.CommonName())
+	}
+	conf, err := mi.GetConfig()
+	if err != nil {
+		return types.Descriptor{}, types.Descriptor{}, errors.Wrap(err, "get manifest config")
+	}
+	if conf.MediaType != HelmChartConfigMediaType {
+		return types.Descriptor{}, types.Descriptor{}, errors.Errorf("%s config media type %q is not helm chart", r.CommonName(), conf.MediaType)
+	}
+	layers, err := mi.GetLayers()
+	if err != nil {
+		return types.Descriptor{}, types.Descriptor{}, errors.Wrap(err, "get manifest layers")
+	}
+	for _, l := range layers {
+		if l.MediaType == HelmChartContentLayerMediaType || l.MediaType == legacyChartLayerMediaType {
+			return conf, l, nil
+		}
+	}
+	return types.Descriptor{}, types.Descriptor{}, errors.Errorf("chart content layer not found in %s", r.CommonName())
+}
+
+func (c *OCIClient) readBlob(ctx context.Context, r ref.Ref, d types.Descriptor, w io.Writer) error {
+	rdr, err := c.rc.BlobGet(ctx, r, d)
+	if err != nil {
+		return errors.Wrapf(err, "get blob %s", d.Digest)
+	}
+	defer rdr.Close()
+	if _, err := io.Copy(w, rdr); err != nil {
+		return errors.Wrapf(err, "read blob %s", d.Digest)
+	}
+	return nil
+}
+
+// GetChartVersion returns index entry of chart version stored inside registry
+func (c *OCIClient) GetChartVersion(ctx context.Context, chartName, version string) (*repo.ChartVersion, error) {
+	r, err := c.chartRef(chartName, version)
+	if err != nil {
+		return nil, err
+	}
+	conf, layer, err := c.getChartDescriptors(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := c.readBlob(ctx, r, conf, buf); err != nil {
+		return nil, err
+	}
+	md := new(chart.Metadata)
+	if err := json.Unmarshal(buf.Bytes(), md); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal chart metadata of %s", r.CommonName())
+	}
+	return &repo.ChartVersion{
+		Metadata: md,
+		URLs:     []string{c.ChartURL(chartName, version)},
+		Digest:   layer.Digest.Encoded(),
+	}, nil
+}
+
+// BuildIndex generates classic repository index of charts inside registry,
+// so search and locate works same as http repository
+func (c *OCIClient) BuildIndex(ctx context.Context) (*repo.IndexFile, error) {
+	charts, err := c.ListCharts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	idx := repo.NewIndexFile()
+	for _, name := range charts {
+		versions, err := c.ListChartVersions(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, version := range versions {
+			cv, err := c.GetChartVersion(ctx, name, version)
+			if err != nil {
+				// registry may contain images or other artifacts
+				log.Warningf("skip %s:%s of oci registry %s: %v", name, version, c.registry, err)
+				continue
+			}
+			cv.Created = time.Now()
+			idx.Entries[name] = append(idx.Entries[name], cv)
+		}
+	}
+	idx.SortEntries()
+	return idx, nil
+}
+
+// PullChart downloads chart archive to dstPath
+func (c *OCIClient) PullChart(ctx context.Context, chartName, version, dstPath string) error {
+	r, err := c.chartRef(chartName, version)
+	if err != nil {
+		return err
+	}
+	_, layer, err := c.getChartDescriptors(ctx, r)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return errors.Wrapf(err, "mkdir %s", filepath.Dir(dstPath))
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(dstPath), filepath.Base(dstPath))
+	if err != nil {
+		return errors.Wrap(err, "create temp file")
+	}
+	defer os.Remove(tmpFile.Name())
+	if err := c.readBlob(ctx, r, layer, tmpFile); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return errors.Wrapf(err, "close %s", tmpFile.Name())
+	}
+	return os.Rename(tmpFile.Name(), dstPath)
+}
+
+func (c *OCIClient) putBlob(ctx context.Context, r ref.Ref, mediaType string, data []byte) (types.Descriptor, error) {
+	d := types.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+	}
+	if _, err := c.rc.BlobPut(ctx, r, d, bytes.NewReader(data)); err != nil {
+		return d, errors.Wrapf(err, "put blob %s", d.Digest)
+	}
+	return d, nil
+}
+
+// PushChart uploads chart archive as OCI artifact, returns oci reference of pushed chart
+func (c *OCIClient) PushChart(ctx context.Context, chartPath string) (string, error) {
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "load chart %s", chartPath)
+	}
+	content, err := ioutil.ReadFile(chartPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "read chart %s", chartPath)
+	}
+	confData, err := json.Marshal(ch.Metadata)
+	if err != nil {
+		return "", errors.Wrap(err, "marshal chart metadata")
+	}
+	r, err := c.chartRef(ch.Name(), ch.Metadata.Version)
+	if err != nil {
+		return "", err
+	}
+	confDesc, err := c.putBlob(ctx, r, HelmChartConfigMediaType, confData)
+	if err != nil {
+		return "", errors.Wrap(err, "push chart config")
+	}
+	layerDesc, err := c.putBlob(ctx, r, HelmChartContentLayerMediaType, content)
+	if err != nil {
+		return "", errors.Wrap(err, "push chart content")
+	}
+	m, err := manifest.New(manifest.WithOrig(v1.Manifest{
+		Versioned: v1.ManifestSchemaVersion,
+		MediaType: types.MediaTypeOCI1Manifest,
+		Config:    confDesc,
+		Layers:    []types.Descriptor{layerDesc},
+	}))
+	if err != nil {
+		return "", errors.Wrap(err, "new manifest")
+	}
+	if err := c.rc.ManifestPut(ctx, r, m); err != nil {
+		return "", errors.Wrapf(err, "put manifest %s", r.CommonName())
+	}
+	return c.ChartURL(ch.Name(), ch.Metadata.Version), nil
+}
@@ -0,0 +1,149 @@
+package helm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestParseOCIRef(t *testing.T) {
+	cases := []struct {
+		ref     string
+		repoUrl string
+		chart   string
+		version string
+		wantErr bool
+	}{
+		{ref: "oci://localhost:5000/charts/nginx:1.2.0", repoUrl: "oci://localhost:5000/charts", chart: "nginx", version: "1.2.0"},
+		{ref: "oci://localhost:5000/nginx", repoUrl: "oci://localhost:5000", chart: "nginx"},
+		{ref: "oci://harbor.io/library/helm/redis:6.0.1_build.1", repoUrl: "oci://harbor.io/library/helm", chart: "redis", version: "6.0.1+build.1"},
+		{ref: "oci://localhost:5000", wantErr: true},
+		{ref: "oci://localhost:5000/", wantErr: true},
+		{ref: "https://charts.io/nginx", wantErr: true},
+	}
+	for _, c := range cases {
+		repoUrl, chart, version, err := ParseOCIRef(c.ref)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error", c.ref)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.ref, err)
+			continue
+		}
+		if repoUrl != c.repoUrl || chart != c.chart || version != c.version {
+			t.Errorf("%s: got (%s, %s, %s), want (%s, %s, %s)", c.ref, repoUrl, chart, version, c.repoUrl, c.chart, c.version)
+		}
+	}
+}
+
+func TestChartVersionTag(t *testing.T) {
+	if tag := chartVersionToTag("1.0.0+build.1"); tag != "1.0.0_build.1" {
+		t.Errorf("tag = %s", tag)
+	}
+	if v := chartVersionFromTag("1.0.0_build.1"); v != "1.0.0+build.1" {
+		t.Errorf("version = %s", v)
+	}
+}
+
+func TestNewOCIClient(t *testing.T) {
+	cli, err := NewOCIClient(&repo.Entry{Name: "local", URL: OCIURLWithPlainHTTP("oci://localhost:5000/charts/")})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if cli.registry != "localhost:5000" || cli.pathPrefix != "charts" {
+		t.Errorf("got registry %q, path prefix %q", cli.registry, cli.pathPrefix)
+	}
+	if ref := cli.ChartURL("nginx", "1.0.0+build.1"); ref != "oci://localhost:5000/charts/nginx:1.0.0_build.1" {
+		t.Errorf("chart url = %s", ref)
+	}
+	if _, err := NewOCIClient(&repo.Entry{Name: "bad", URL: "oci:///charts"}); err == nil {
+		t.Errorf("empty registry should fail")
+	}
+}
+
+// TestOCIRegistry runs against a local distribution registry serving plain http, e.g.
+//
+//	docker run -d -p 5000:5000 registry:2
+//	HELM_OCI_TEST_REGISTRY=localhost:5000 go test ./pkg/kubeserver/helm/
+func TestOCIRegistry(t *testing.T) {
+	registry := os.Getenv("HELM_OCI_TEST_REGISTRY")
+	if registry == "" {
+		t.Skip("HELM_OCI_TEST_REGISTRY not set")
+	}
+	ctx := context.Background()
+	cli, err := NewOCIClient(&repo.Entry{
+		Name:     "test",
+		URL:      OCIURLWithPlainHTTP("oci://" + registry + "/kubecomps-test"),
+		Username: os.Getenv("HELM_OCI_TEST_USERNAME"),
+		Password: os.Getenv("HELM_OCI_TEST_PASSWORD"),
+	})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	dir := t.TempDir()
+	chDir, err := chartutil.Create("ocitest", dir)
+	if err != nil {
+		t.Fatalf("create chart: %v", err)
+	}
+	ch, err := loader.Load(chDir)
+	if err != nil {
+		t.Fatalf("load chart: %v", err)
+	}
+	ch.Metadata.Version = "0.1.0+build.1"
+	chPath, err := chartutil.Save(ch, dir)
+	if err != nil {
+		t.Fatalf("save chart: %v", err)
+	}
+
+	ref, err := cli.PushChart(ctx, chPath)
+	if err != nil {
+		t.Fatalf("push chart: %v", err)
+	}
+	if want := cli.ChartURL("ocitest", "0.1.0+build.1"); ref != want {
+		t.Errorf("pushed ref = %s, want %s", ref, want)
+	}
+
+	versions, err := cli.ListChartVersions(ctx, "ocitest")
+	if err != nil {
+		t.Fatalf("list versions: %v", err)
+	}
+	found := false
+	for _, v := range versions {
+		if v == "0.1.0+build.1" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("pushed version not in %v", versions)
+	}
+
+	idx, err := cli.BuildIndex(ctx)
+	if err != nil {
+		t.Fatalf("build index: %v", err)
+	}
+	cv, err := idx.Get("ocitest", "0.1.0+build.1")
+	if err != nil {
+		t.Fatalf("get version from index: %v", err)
+	}
+
+	dst := filepath.Join(dir, "pulled", "ocitest.tgz")
+	if err := cli.PullChart(ctx, "ocitest", cv.Version, dst); err != nil {
+		t.Fatalf("pull chart: %v", err)
+	}
+	pulled, err := loader.Load(dst)
+	if err != nil {
+		t.Fatalf("load pulled chart: %v", err)
+	}
+	if pulled.Name() != "ocitest" || pulled.Metadata.Version != "0.1.0+build.1" {
+		t.Errorf("pulled chart %s:%s", pulled.Name(), pulled.Metadata.Version)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -111,12 +112,7 @@ func (c RepoClient) Add(entry *repo.Entry) error {
 		return errors.Wrapf(ErrRepoAlreadyExists, "repository name (%s) already exists, please specify a different name", entry.Name)
 	}
 
-	r, err := repo.NewChartRepository(entry, getter.All(c.GetSetting()))
-	if err != nil {
-		return errors.Wrapf(err, "NewChartRepository")
-	}
-
-	if _, err := r.DownloadIndexFile(); err != nil {
+	if err := c.downloadIndexFile(entry); err != nil {
 		return errors.Wrapf(err, "looks like %q is not a valid chart repository or cannot be readched", entry.URL)
 	}
 
@@ -129,6 +125,40 @@ func (c RepoClient) Add(entry *repo.Entry) error {
 	return nil
 }
 
+func (c RepoClient) downloadIndexFile(entry *repo.Entry) error {
+	if IsOCIURL(entry.URL) {
+		return c.buildOCIIndexFile(entry)
+	}
+	r, err := repo.NewChartRepository(entry, getter.All(c.GetSetting()))
+	if err != nil {
+		return errors.Wrapf(err, "NewChartRepository")
+	}
+	if _, err := r.DownloadIndexFile(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// buildOCIIndexFile saves index of charts inside oci registry to repository cache
+func (c RepoClient) buildOCIIndexFile(entry *repo.Entry) error {
+	cli, err := NewOCIClient(entry)
+	if err != nil {
+		return err
+	}
+	idx, err := cli.BuildIndex(context.Background())
+	if err != nil {
+		return errors.Wrapf(err, "build index of oci repo %s", entry.Name)
+	}
+	if err := os.MkdirAll(c.RepositoryCache, 0755); err != nil {
+		return errors.Wrapf(err, "mkdir %s", c.RepositoryCache)
+	}
+	idxPath := filepath.Join(c.RepositoryCache, helmpath.CacheIndexFile(entry.Name))
+	if err := idx.WriteFile(idxPath, 0644); err != nil {
+		return errors.Wrapf(err, "write index file %s", idxPath)
+	}
+	return nil
+}
+
 func isNotExist(err error) bool {
 	return os.IsNotExist(errors.Cause(err))
 }
@@ -141,13 +171,20 @@ func (c RepoClient) Update(name string) error {
 	}
 	var repos []*repo.ChartRepository
 	for _, cfg := range f.Repositories {
+		if cfg.Name != name {
+			continue
+		}
+		if IsOCIURL(cfg.URL) {
+			if err := c.buildOCIIndexFile(cfg); err != nil {
+				log.Errorf("...Unable to get an update from %q oci repository (%s): %s", cfg.Name, cfg.URL, err)
+			}
+			continue
+		}
 		r, err := repo.NewChartRepository(cfg, getter.All(c.GetSetting()))
 		if err != nil {
 			return err
 		}
-		if r.Config.Name == name {
-			repos = append(repos, r)
-		}
+		repos = append(repos, r)
 	}
 
 	return c.update(repos)
@@ -184,6 +221,27 @@ func (c RepoClient) GetEntry(name string) (*repo.Entry, error) {
 	return nil, errors.Errorf("Not found repo %s", name)
 }
 
+// UpdateEntry replaces config of existing repo, e.g. credential of oci repo changed in container registry
+func (c RepoClient) UpdateEntry(entry *repo.Entry) error {
+	repoFile := c.RepositoryConfig
+	r, err := repo.LoadFile(repoFile)
+	if err != nil {
+		return errors.Wrapf(err, "load file %s", repoFile)
+	}
+	oldEntry := r.Get(entry.Name)
+	if oldEntry == nil {
+		return errors.Errorf("Not found repo %s", entry.Name)
+	}
+	if reflect.DeepEqual(oldEntry, entry) {
+		return nil
+	}
+	r.Update(entry)
+	if err := r.WriteFile(repoFile, 0644); err != nil {
+		return errors.Wrapf(err, "Write file %s", repoFile)
+	}
+	return nil
+}
+
 func (c RepoClient) Remove(name string) error {
 	repoFile := c.RepositoryConfig
 	r, err := repo.LoadFile(repoFile)
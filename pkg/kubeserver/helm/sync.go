@@ -36,6 +36,9 @@ func newRepoCacheBackend() (*RepoCacheBackend, error) {
 func (r *RepoCacheBackend) Update(repos ...*repo.Entry) error {
 	for _, repo := range repos {
 		if oldRepo, _ := r.client.GetEntry(repo.Name); oldRepo != nil {
+			if err := r.client.UpdateEntry(repo); err != nil {
+				log.Errorf("Update repo %s entry error: %v", repo.Name, err)
+			}
 			continue
 		}
 		if err := r.client.Add(repo); err != nil {
@@ -26,6 +26,20 @@ func InitClient(cron *cronman.SCronJobManager) {
 
 	cron.AddJobAtIntervalsWithStartRun("StartKubeClusterHealthCheck", 5*time.Minute, models.ClusterManager.ClusterHealthCheckTask, true)
 	cron.AddJobAtIntervalsWithStartRun("StartKubeClusterAutoSyncTask", 30*time.Minute, models.ClusterManager.StartAutoSyncTask, true)
+	cron.AddJobAtIntervalsWithStartRun("StartKubeClusterScheduledBackups", 10*time.Minute, models.ClusterBackupManager.StartScheduledBackups, false)
+	cron.AddJobAtIntervalsWithStartRun("StartContainerRegistryScheduledRetention", 30*time.Minute, models.GetContainerRegistryManager().StartScheduledRetention, false)
+	cron.AddJobAtIntervalsWithStartRun("StartContainerImageMirrorScheduledSync", 10*time.Minute, models.ContainerImageMirrorManager.StartScheduledSync, false)
+	cron.AddJobAtIntervalsWithStartRun("CheckKubeClusterCertificatesExpiry", 6*time.Hour, models.ClusterManager.CheckCertificatesExpiry, false)
+	driftInterval := options.Options.FederatedDriftCheckIntervalMinutes
+	if driftInterval < models.FedDriftMinCheckIntervalMinutes {
+		driftInterval = models.FedDriftMinCheckIntervalMinutes
+	}
+	cron.AddJobAtIntervalsWithStartRun("ReconcileFederatedResourcesDrift", time.Duration(driftInterval)*time.Minute, models.ReconcileFederatedResourcesDrift, false)
+	gitSyncInterval := options.Options.ReleaseGitSyncIntervalMinutes
+	if gitSyncInterval < models.ReleaseGitSyncMinIntervalMinutes {
+		gitSyncInterval = models.ReleaseGitSyncMinIntervalMinutes
+	}
+	cron.AddJobAtIntervalsWithStartRun("SyncGitSourceReleases", time.Duration(gitSyncInterval)*time.Minute, models.GetReleaseManager().SyncGitReleases, false)
 	if options.Options.RunningMode == options.RUNNING_MODE_K8S {
 		cron.AddJobAtIntervalsWithStartRun("StartSyncSystemGrafanaDashboard", 1*time.Minute, models.MonitorComponentManager.SyncSystemGrafanaDashboard, true)
 	}
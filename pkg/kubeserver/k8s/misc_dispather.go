@@ -4,18 +4,19 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	//"net/url"
 
-	//"k8s.io/api/core/v1"
-	//"k8s.io/client-go/kubernetes/scheme"
-	//"k8s.io/client-go/tools/remotecommand"
-	//"yunion.io/x/jsonutils"
+	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
-	//"yunion.io/x/onecloud/pkg/appctx"
 	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/policy"
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
-	//"yunion.io/x/kubecomps/pkg/kubeserver/clusterrouter/proxy"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+	"yunion.io/x/kubecomps/pkg/kubeserver/resources/common"
+	"yunion.io/x/kubecomps/pkg/utils/websocket"
 )
 
 func AddMiscDispatcher(prefix string, app *appsrv.Application) {
@@ -28,50 +29,60 @@ func AddMiscDispatcher(prefix string, app *appsrv.Application) {
 		auth.Authenticate(handleExecShell))
 }
 
+// checkPodExecPrivilege requires the same privilege as updating the pod
+func checkPodExecPrivilege(ctx context.Context, req *common.Request, namespace, podName string) error {
+	cluster := req.GetCluster()
+	nsObj, err := models.GetNamespaceManager().GetByName(req.UserCred, cluster.GetId(), namespace)
+	if err != nil {
+		return errors.Wrapf(err, "get namespace %s", namespace)
+	}
+	pod, err := models.GetPodManager().GetByName(req.UserCred, cluster.GetId(), nsObj.GetId(), podName)
+	if err != nil {
+		return errors.Wrapf(err, "get pod %s/%s", namespace, podName)
+	}
+	if err := db.IsObjectRbacAllowed(ctx, pod.(*models.SPod), req.UserCred, policy.PolicyActionUpdate); err != nil {
+		return httperrors.NewForbiddenError("not allow to exec pod %s/%s: %v", namespace, podName, err)
+	}
+	return nil
+}
+
 func handleExecShell(ctx context.Context, w http.ResponseWriter, r *http.Request) {
-	httperrors.GeneralServerError(ctx, w, fmt.Errorf("Not impl"))
-	return
-	/*params, query, data := _fetchEnv(ctx, w, r)
-	request, err := NewCloudK8sRequest(ctx, query.(*jsonutils.JSONDict), nil)
+	params, query, _ := _fetchEnv(ctx, w, r)
+	queryDict := jsonutils.NewDict()
+	if query != nil {
+		queryDict = query.(*jsonutils.JSONDict)
+	}
+	request, err := NewCloudK8sRequest(ctx, queryDict, nil)
 	if err != nil {
-		httperrors.GeneralServerError(w, err)
+		httperrors.GeneralServerError(ctx, w, err)
 		return
 	}
-	cluster, _ := getCluster(query.(*jsonutils.JSONDict), data.(*jsonutils.JSONDict), request.UserCred)
 	podName := params["<pod>"]
 	container := params["<container>"]
+	namespace := request.GetDefaultNamespace()
+	if err := checkPodExecPrivilege(ctx, request, namespace, podName); err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	shell, _ := queryDict.GetString("shell")
+	shells, err := getExecShells(shell)
+	if err != nil {
+		httperrors.InputParameterError(ctx, w, "%v", err)
+		return
+	}
 
-	req := request.GetK8sClient().CoreV1().RESTClient().Post().
-		Resource("pods").
-		Name(podName).
-		Namespace(request.GetNamespaceQuery().ToRequestParam()).
-		SubResource("exec")
-
-	vars := url.Values{}
-	vars.Add("container", container)
-	vars.Add("stdout", "1")
-	vars.Add("stdin", "1")
-	vars.Add("stderr", "1")
-	vars.Add("tty", "1")
-	vars.Add("command", "bash")
-	//vars.Add("command", token)
-	//vars.Add("command", context.ClusterName)
-
-	//req.VersionedParams(&v1.PodExecOptions{
-	//Container: container,
-	//Command:   []string{"bash"},
-	//Stdin:     true,
-	//Stdout:    true,
-	//Stderr:    true,
-	//TTY:       true,
-	//}, scheme.ParameterCodec)
-
-	service, err := proxy.New(cluster)
+	conn, err := websocket.Upgrade(w, r, "")
 	if err != nil {
-		httperrors.GeneralServerError(w, err)
+		httperrors.BadRequestError(ctx, w, "upgrade to websocket: %v", err)
+		return
+	}
+	session := NewTerminalSession(conn)
+	log.Infof("User %s exec shell into pod %s/%s container %s of cluster %s",
+		request.UserCred.GetUserName(), namespace, podName, container, request.GetCluster().GetName())
+	if err := startTerminal(request.GetK8sAdminClient(), request.GetK8sAdminRestConfig(), namespace, podName, container, shells, session); err != nil {
+		log.Errorf("Exec shell into pod %s/%s container %s: %v", namespace, podName, container, err)
+		session.Close(err.Error())
 		return
 	}
-	r.URL.Path = req.URL().Path
-	r.URL.RawQuery = vars.Encode()
-	service.ServeHTTP(w, r)*/
+	session.Close("")
 }
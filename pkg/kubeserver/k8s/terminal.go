@@ -0,0 +1,232 @@
+package k8s
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/utils/websocket"
+)
+
+const (
+	TerminalOpStdin  = "stdin"
+	TerminalOpStdout = "stdout"
+	TerminalOpResize = "resize"
+	TerminalOpToast  = "toast"
+)
+
+var (
+	// validShells are tried in order when client doesn't choose one
+	validShells = []string{"bash", "sh", "powershell", "cmd"}
+)
+
+// TerminalMessage is the json message exchanged with web terminal
+//
+// stdin and resize are sent by client, stdout and toast are sent by server
+type TerminalMessage struct {
+	Op   string `json:"op"`
+	Data string `json:"data,omitempty"`
+	Rows uint16 `json:"rows,omitempty"`
+	Cols uint16 `json:"cols,omitempty"`
+}
+
+// TerminalSession bridges websocket connection and remotecommand streams
+type TerminalSession struct {
+	conn     *websocket.Conn
+	sizeChan chan remotecommand.TerminalSize
+	doneChan chan struct{}
+	doneOnce sync.Once
+	// stdin not consumed by last Read
+	pending []byte
+}
+
+var (
+	_ io.Reader                       = new(TerminalSession)
+	_ io.Writer                       = new(TerminalSession)
+	_ remotecommand.TerminalSizeQueue = new(TerminalSession)
+)
+
+func NewTerminalSession(conn *websocket.Conn) *TerminalSession {
+	return &TerminalSession{
+		conn:     conn,
+		sizeChan: make(chan remotecommand.TerminalSize, 1),
+		doneChan: make(chan struct{}),
+	}
+}
+
+// Next implements remotecommand.TerminalSizeQueue, nil means session is done
+func (t *TerminalSession) Next() *remotecommand.TerminalSize {
+	select {
+	case size := <-t.sizeChan:
+		return &size
+	case <-t.doneChan:
+		return nil
+	}
+}
+
+// Read reads stdin from websocket and dispatches resize messages
+func (t *TerminalSession) Read(p []byte) (int, error) {
+	if len(t.pending) != 0 {
+		n := copy(p, t.pending)
+		t.pending = t.pending[n:]
+		return n, nil
+	}
+	for {
+		_, data, err := t.conn.ReadMessage()
+		if err != nil {
+			t.done()
+			return 0, err
+		}
+		msg := new(TerminalMessage)
+		if err := json.Unmarshal(data, msg); err != nil {
+			return 0, errors.Wrapf(err, "unmarshal terminal message %q", data)
+		}
+		switch msg.Op {
+		case TerminalOpStdin:
+			if len(msg.Data) == 0 {
+				continue
+			}
+			n := copy(p, msg.Data)
+			t.pending = []byte(msg.Data[n:])
+			return n, nil
+		case TerminalOpResize:
+			// drop stale size when remote hasn't consumed it
+			select {
+			case <-t.sizeChan:
+			default:
+			}
+			t.sizeChan <- remotecommand.TerminalSize{Width: msg.Cols, Height: msg.Rows}
+		default:
+			return 0, errors.Errorf("unknown terminal message op %q", msg.Op)
+		}
+	}
+}
+
+// Write sends stdout and stderr content to websocket
+func (t *TerminalSession) Write(p []byte) (int, error) {
+	if err := t.send(TerminalOpStdout, string(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Toast sends out of band message to web terminal
+func (t *TerminalSession) Toast(msg string) error {
+	return t.send(TerminalOpToast, msg)
+}
+
+func (t *TerminalSession) send(op, data string) error {
+	content, err := json.Marshal(TerminalMessage{Op: op, Data: data})
+	if err != nil {
+		return err
+	}
+	return t.conn.WriteMessage(websocket.OpText, content)
+}
+
+func (t *TerminalSession) done() {
+	t.doneOnce.Do(func() {
+		close(t.doneChan)
+	})
+}
+
+func (t *TerminalSession) isDone() bool {
+	select {
+	case <-t.doneChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close ends session, reason is shown to client when it's not empty
+func (t *TerminalSession) Close(reason string) error {
+	t.done()
+	code := websocket.CloseNormal
+	if reason != "" {
+		t.Toast(reason)
+		code = websocket.CloseInternalError
+	}
+	return t.conn.CloseWithReason(code, reason)
+}
+
+// getExecShells returns shells will be tried by terminal
+func getExecShells(shell string) ([]string, error) {
+	if shell == "" {
+		return validShells, nil
+	}
+	for _, s := range validShells {
+		if s == shell {
+			return []string{shell}, nil
+		}
+	}
+	return nil, errors.Errorf("invalid shell %q, supported: %v", shell, validShells)
+}
+
+func startProcess(cli kubernetes.Interface, config *rest.Config, namespace, podName, container string, cmd []string, session *TerminalSession) error {
+	req := cli.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(namespace).
+		SubResource("exec")
+
+	req.VersionedParams(&v1.PodExecOptions{
+		Container: container,
+		Command:   cmd,
+		Stdin:     true,
+		Stdout:    true,
+		Stderr:    true,
+		TTY:       true,
+	}, scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+	if err != nil {
+		return errors.Wrap(err, "new spdy executor")
+	}
+
+	return exec.Stream(remotecommand.StreamOptions{
+		Stdin:             session,
+		Stdout:            session,
+		Stderr:            session,
+		TerminalSizeQueue: session,
+		Tty:               true,
+	})
+}
+
+// isShellNotFound reports whether exec failed because shell can't be started,
+// a shell exiting with its own status is not a reason to try another one
+func isShellNotFound(err error) bool {
+	// connection or apiserver errors aren't caused by missing shell
+	exitErr, ok := errors.Cause(err).(utilexec.ExitError)
+	if !ok {
+		return false
+	}
+	// 126: not executable, 127: command not found
+	return exitErr.ExitStatus() == 126 || exitErr.ExitStatus() == 127
+}
+
+// startTerminal tries shells one by one until one of them starts
+func startTerminal(cli kubernetes.Interface, config *rest.Config, namespace, podName, container string, shells []string, session *TerminalSession) error {
+	var err error
+	for _, shell := range shells {
+		cmd := []string{shell}
+		err = startProcess(cli, config, namespace, podName, container, cmd, session)
+		if err == nil {
+			return nil
+		}
+		if session.isDone() || !isShellNotFound(err) {
+			return err
+		}
+		log.Warningf("Exec %s in pod %s/%s container %s: %v", shell, namespace, podName, container, err)
+	}
+	return err
+}
@@ -0,0 +1,690 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/wait"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/utils/etcdsnapshot"
+	"yunion.io/x/kubecomps/pkg/utils/objstore/s3"
+)
+
+var ClusterBackupManager *SClusterBackupManager
+
+func init() {
+	ClusterBackupManager = &SClusterBackupManager{
+		SStatusDomainLevelResourceBaseManager: db.NewStatusDomainLevelResourceBaseManager(
+			SClusterBackup{},
+			"cluster_backups_tbl",
+			"kubeclusterbackup",
+			"kubeclusterbackups",
+		),
+	}
+	ClusterBackupManager.SetVirtualObject(ClusterBackupManager)
+}
+
+// +onecloud:swagger-gen-model-singular=kubeclusterbackup
+type SClusterBackupManager struct {
+	db.SStatusDomainLevelResourceBaseManager
+}
+
+// SClusterBackup is an etcd snapshot of cluster stored in S3 compatible object storage,
+// backups are kept after cluster deleted
+type SClusterBackup struct {
+	db.SStatusDomainLevelResourceBase
+
+	ClusterId string `width:"36" charset:"ascii" nullable:"false" create:"required" list:"user" index:"true"`
+	// BackupType is manual or scheduled, only scheduled backups are purged by retention policy
+	BackupType string `width:"16" charset:"ascii" nullable:"false" default:"manual" create:"optional" list:"user"`
+
+	Endpoint  string `width:"256" charset:"ascii" nullable:"true" list:"user"`
+	Bucket    string `width:"128" charset:"ascii" nullable:"true" list:"user"`
+	ObjectKey string `width:"512" charset:"utf8" nullable:"true" list:"user"`
+	Size      int64  `nullable:"true" list:"user"`
+	// Checksum is sha256 of snapshot content
+	Checksum string `width:"64" charset:"ascii" nullable:"true" list:"user"`
+	// EtcdMember records which etcd member the snapshot is taken from
+	EtcdMember string `width:"128" charset:"ascii" nullable:"true" list:"user"`
+}
+
+func (m *SClusterBackupManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, input *api.ClusterBackupListInput) (*sqlchemy.SQuery, error) {
+	q, err := m.SStatusDomainLevelResourceBaseManager.ListItemFilter(ctx, q, userCred, input.StatusDomainLevelResourceListInput)
+	if err != nil {
+		return nil, err
+	}
+	if input.Cluster != "" {
+		clusters := GetClusterManager().Query().SubQuery()
+		sq := clusters.Query(clusters.Field("id")).
+			Filter(sqlchemy.OR(
+				sqlchemy.Equals(clusters.Field("name"), input.Cluster),
+				sqlchemy.Equals(clusters.Field("id"), input.Cluster))).SubQuery()
+		q = q.In("cluster_id", sq)
+	}
+	if input.BackupType != "" {
+		q = q.Equals("backup_type", input.BackupType)
+	}
+	return q, nil
+}
+
+func (m *SClusterBackupManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []*jsonutils.JSONDict {
+	rows := make([]*jsonutils.JSONDict, len(objs))
+	baseRows := m.SStatusDomainLevelResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range objs {
+		rows[i] = jsonutils.Marshal(baseRows[i]).(*jsonutils.JSONDict)
+		if cluster, err := objs[i].(*SClusterBackup).GetCluster(); err == nil {
+			rows[i].Add(jsonutils.NewString(cluster.GetName()), "cluster")
+		}
+	}
+	return rows
+}
+
+func (m *SClusterBackupManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.ClusterBackupCreateInput) (*api.ClusterBackupCreateInput, error) {
+	if input.Cluster == "" {
+		input.Cluster = input.ClusterId
+	}
+	if input.Cluster == "" {
+		return nil, httperrors.NewNotEmptyError("cluster")
+	}
+	cluster, err := GetClusterManager().GetClusterByIdOrName(ctx, userCred, input.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.GetStatus() != api.ClusterStatusRunning {
+		return nil, httperrors.NewNotAcceptableError("Can not backup cluster when status is %s", cluster.GetStatus())
+	}
+	policy, err := cluster.GetBackupPolicy(ctx, userCred)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, httperrors.NewNotAcceptableError("Cluster %s backup policy is not set", cluster.GetName())
+	}
+	input.ClusterId = cluster.GetId()
+	if input.BackupType == "" {
+		input.BackupType = api.ClusterBackupTypeManual
+	}
+	if !utils.IsInStringArray(input.BackupType, []string{api.ClusterBackupTypeManual, api.ClusterBackupTypeScheduled}) {
+		return nil, httperrors.NewInputParameterError("Invalid backup type %q", input.BackupType)
+	}
+	if input.Name == "" {
+		input.Name = fmt.Sprintf("%s-%s", cluster.GetName(), time.Now().Format("20060102150405"))
+	}
+	sInput, err := m.SStatusDomainLevelResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.StatusDomainLevelResourceCreateInput)
+	if err != nil {
+		return nil, err
+	}
+	input.StatusDomainLevelResourceCreateInput = sInput
+	return input, nil
+}
+
+// CreateBackup creates backup of cluster owned by cluster domain and starts backup task
+func (m *SClusterBackupManager) CreateBackup(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster, backupType string) (*SClusterBackup, error) {
+	input := &api.ClusterBackupCreateInput{
+		Cluster:    cluster.GetId(),
+		BackupType: backupType,
+	}
+	data := jsonutils.Marshal(input)
+	obj, err := db.DoCreate(m, ctx, userCred, nil, data, cluster.GetOwnerId())
+	if err != nil {
+		return nil, err
+	}
+	b := obj.(*SClusterBackup)
+	func() {
+		lockman.LockObject(ctx, b)
+		defer lockman.ReleaseObject(ctx, b)
+		b.PostCreate(ctx, userCred, cluster.GetOwnerId(), nil, data)
+	}()
+	return b, nil
+}
+
+func (b *SClusterBackup) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	b.SStatusDomainLevelResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	if err := b.StartCreateTask(ctx, userCred, ""); err != nil {
+		log.Errorf("StartCreateTask of cluster backup %s: %v", b.GetName(), err)
+	}
+}
+
+func (b *SClusterBackup) StartCreateTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	b.SetStatus(ctx, userCred, api.ClusterBackupStatusCreating, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "ClusterBackupCreateTask", b, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (b *SClusterBackup) GetCluster() (*SCluster, error) {
+	return GetClusterManager().GetCluster(b.ClusterId)
+}
+
+func newBackupStorageClient(conf *api.ObjectStoreConfig) (*s3.Client, error) {
+	return s3.NewClient(&s3.Config{
+		Endpoint:  conf.Endpoint,
+		Secure:    !conf.Insecure,
+		AccessKey: conf.AccessKey,
+		SecretKey: conf.SecretKey,
+	})
+}
+
+// getBackupStorage returns client of object storage the backup is saved in, bucket and key
+// recorded in backup are used, only credential is taken from cluster backup policy,
+// so the policy must still point to the same endpoint
+func (b *SClusterBackup) getBackupStorage(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster) (*s3.Client, error) {
+	policy, err := cluster.GetBackupPolicy(ctx, userCred)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, httperrors.NewNotAcceptableError("Cluster %s backup policy is not set", cluster.GetName())
+	}
+	conf := policy.ObjectStore
+	if b.Endpoint != "" && conf.Endpoint != b.Endpoint {
+		return nil, httperrors.NewNotAcceptableError("Backup is stored in %s, but cluster backup storage is %s", b.Endpoint, conf.Endpoint)
+	}
+	return newBackupStorageClient(conf)
+}
+
+func backupObjectKey(prefix string, cluster *SCluster, createdAt time.Time) string {
+	return path.Join(prefix, cluster.GetName()+"-"+cluster.GetId(), fmt.Sprintf("etcd-snapshot-%s.db", createdAt.UTC().Format("20060102-150405")))
+}
+
+// DoBackup takes etcd snapshot from the first available member and uploads it to object storage
+func (b *SClusterBackup) DoBackup(ctx context.Context, userCred mcclient.TokenCredential) error {
+	cluster, err := b.GetCluster()
+	if err != nil {
+		return errors.Wrap(err, "get cluster")
+	}
+	policy, err := cluster.GetBackupPolicy(ctx, userCred)
+	if err != nil {
+		return errors.Wrap(err, "get backup policy")
+	}
+	if policy == nil {
+		return errors.Errorf("cluster %s backup policy is not set", cluster.GetName())
+	}
+	cli, err := newBackupStorageClient(policy.ObjectStore)
+	if err != nil {
+		return errors.Wrap(err, "new object storage client")
+	}
+	members, err := cluster.GetDriver().GetEtcdMembers(ctx, userCred, cluster)
+	if err != nil {
+		return errors.Wrap(err, "get etcd members")
+	}
+	if len(members) == 0 {
+		return errors.Errorf("cluster %s has no etcd member", cluster.GetName())
+	}
+
+	bucket := policy.ObjectStore.Bucket
+	key := backupObjectKey(policy.Prefix, cluster, b.CreatedAt)
+	errs := make([]error, 0)
+	for _, m := range members {
+		m := m
+		size, checksum, err := cli.PutStream(ctx, bucket, key, func(w io.Writer) error {
+			return etcdsnapshot.Save(m, w)
+		})
+		if err != nil {
+			log.Warningf("backup cluster %s etcd from member %s: %v", cluster.GetName(), m.Name, err)
+			errs = append(errs, err)
+			continue
+		}
+		_, err = db.Update(b, func() error {
+			b.Endpoint = policy.ObjectStore.Endpoint
+			b.Bucket = bucket
+			b.ObjectKey = key
+			b.Size = size
+			b.Checksum = checksum
+			b.EtcdMember = m.Name
+			return nil
+		})
+		return err
+	}
+	return errors.NewAggregate(errs)
+}
+
+func (b *SClusterBackup) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	if utils.IsInStringArray(b.GetStatus(), []string{api.ClusterBackupStatusCreating, api.ClusterBackupStatusRestoring}) {
+		return httperrors.NewInvalidStatusError("Can not delete backup when status is %s", b.GetStatus())
+	}
+	return b.SStatusDomainLevelResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (b *SClusterBackup) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	log.Infof("Cluster backup delete do nothing")
+	return nil
+}
+
+func (b *SClusterBackup) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return b.SStatusDomainLevelResourceBase.Delete(ctx, userCred)
+}
+
+func (b *SClusterBackup) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	return b.StartDeleteTask(ctx, userCred, "")
+}
+
+func (b *SClusterBackup) StartDeleteTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	b.SetStatus(ctx, userCred, api.ClusterBackupStatusDeleting, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "ClusterBackupDeleteTask", b, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+// RemoveObject removes snapshot from object storage, snapshot which can't be reached any more,
+// e.g. cluster is deleted or its backup storage is changed, is left in storage
+func (b *SClusterBackup) RemoveObject(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if b.ObjectKey == "" {
+		return nil
+	}
+	cluster, err := b.GetCluster()
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			log.Warningf("cluster %s of backup %s is deleted, leave %s/%s in storage", b.ClusterId, b.GetName(), b.Bucket, b.ObjectKey)
+			return nil
+		}
+		return errors.Wrap(err, "get cluster")
+	}
+	cli, err := b.getBackupStorage(ctx, userCred, cluster)
+	if err != nil {
+		log.Warningf("storage of backup %s can't be reached: %v, leave %s/%s/%s in storage", b.GetName(), err, b.Endpoint, b.Bucket, b.ObjectKey)
+		return nil
+	}
+	if err := cli.RemoveObject(ctx, b.Bucket, b.ObjectKey, minio.RemoveObjectOptions{}); err != nil {
+		return errors.Wrapf(err, "remove object %s/%s", b.Bucket, b.ObjectKey)
+	}
+	return nil
+}
+
+// selectExpiredBackups returns backups kept by neither keepLast nor keepDays,
+// backups must be sorted by creation time in descending order
+func selectExpiredBackups(backups []SClusterBackup, keepLast, keepDays int, now time.Time) []*SClusterBackup {
+	ret := make([]*SClusterBackup, 0)
+	if keepLast <= 0 && keepDays <= 0 {
+		return ret
+	}
+	for i := range backups {
+		b := &backups[i]
+		if i < keepLast {
+			continue
+		}
+		if keepDays > 0 && now.Sub(b.CreatedAt) < time.Duration(keepDays)*24*time.Hour {
+			continue
+		}
+		ret = append(ret, b)
+	}
+	return ret
+}
+
+// PurgeExpiredBackups deletes ready scheduled backups by retention policy
+func (m *SClusterBackupManager) PurgeExpiredBackups(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster) error {
+	policy, err := cluster.GetBackupPolicy(ctx, userCred)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return nil
+	}
+	q := m.Query().Equals("cluster_id", cluster.GetId()).
+		Equals("backup_type", api.ClusterBackupTypeScheduled).
+		Equals("status", api.ClusterBackupStatusReady).
+		Desc("created_at")
+	backups := make([]SClusterBackup, 0)
+	if err := db.FetchModelObjects(m, q, &backups); err != nil {
+		return errors.Wrap(err, "fetch scheduled backups")
+	}
+	for _, b := range selectExpiredBackups(backups, policy.KeepLast, policy.KeepDays, time.Now()) {
+		if err := b.StartDeleteTask(ctx, userCred, ""); err != nil {
+			log.Errorf("delete expired backup %s: %v", b.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// StartScheduledBackups creates backups of running clusters whose backup interval is reached
+func (m *SClusterBackupManager) StartScheduledBackups(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	clusters, err := GetClusterManager().getClustersByStatus(api.ClusterStatusRunning)
+	if err != nil {
+		log.Errorf("StartScheduledBackups get clusters: %v", err)
+		return
+	}
+	for idx := range clusters {
+		c := &clusters[idx]
+		policy, err := c.GetBackupPolicy(ctx, userCred)
+		if err != nil {
+			log.Errorf("get cluster %s backup policy: %v", c.GetName(), err)
+			continue
+		}
+		if policy == nil || !policy.Enabled {
+			continue
+		}
+		last := new(SClusterBackup)
+		q := m.Query().Equals("cluster_id", c.GetId()).Equals("backup_type", api.ClusterBackupTypeScheduled).Desc("created_at")
+		if err := q.First(last); err == nil {
+			if time.Since(last.CreatedAt) < time.Duration(policy.IntervalHours)*time.Hour {
+				continue
+			}
+		} else if errors.Cause(err) != sql.ErrNoRows {
+			log.Errorf("get cluster %s latest backup: %v", c.GetName(), err)
+			continue
+		}
+		if _, err := m.CreateBackup(ctx, userCred, c, api.ClusterBackupTypeScheduled); err != nil {
+			log.Errorf("create cluster %s scheduled backup: %v", c.GetName(), err)
+		}
+	}
+}
+
+func (b *SClusterBackup) getRestoreMembers(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster) ([]*etcdsnapshot.Member, string, error) {
+	members, err := cluster.GetDriver().GetEtcdMembers(ctx, userCred, cluster)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "get etcd members")
+	}
+	if len(members) == 0 {
+		return nil, "", httperrors.NewNotAcceptableError("Cluster %s has no etcd member", cluster.GetName())
+	}
+	token := etcdsnapshot.KubesprayClusterToken
+	for _, m := range members {
+		env, err := etcdsnapshot.LoadEnvironment(m)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "load etcd environment of %s", m.Name)
+		}
+		if val := env["ETCD_INITIAL_CLUSTER_TOKEN"]; val != "" {
+			token = val
+		}
+	}
+	return members, token, nil
+}
+
+func (b *SClusterBackup) validateRestore(ctx context.Context, userCred mcclient.TokenCredential) (*SCluster, error) {
+	if !utils.IsInStringArray(b.GetStatus(), []string{api.ClusterBackupStatusReady, api.ClusterBackupStatusRestoreFail}) {
+		return nil, httperrors.NewInvalidStatusError("Can not restore backup when status is %s", b.GetStatus())
+	}
+	cluster, err := b.GetCluster()
+	if err != nil {
+		return nil, httperrors.NewNotFoundError("Cluster %s of backup not found: %v", b.ClusterId, err)
+	}
+	if !utils.IsInStringArray(cluster.GetStatus(), []string{
+		api.ClusterStatusRunning,
+		api.ClusterStatusLost,
+		api.ClusterStatusUnknown,
+		api.ClusterStatusError,
+		api.ClusterStatusRestoreFail,
+	}) {
+		return nil, httperrors.NewNotAcceptableError("Can not restore cluster when status is %s", cluster.GetStatus())
+	}
+	return cluster, nil
+}
+
+func (b *SClusterBackup) GetDetailsRestorePlan(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ClusterBackupRestorePlan, error) {
+	cluster, err := b.validateRestore(ctx, userCred)
+	if err != nil {
+		return nil, err
+	}
+	members, token, err := b.getRestoreMembers(ctx, userCred, cluster)
+	if err != nil {
+		return nil, err
+	}
+	initialCluster := etcdsnapshot.InitialCluster(members)
+	plan := &api.ClusterBackupRestorePlan{
+		Backup:         b.GetName(),
+		BackupAt:       b.CreatedAt,
+		Cluster:        cluster.GetName(),
+		ClusterStatus:  cluster.GetStatus(),
+		Members:        make([]api.ClusterBackupRestoreMember, len(members)),
+		InitialCluster: initialCluster,
+		Steps: []string{
+			fmt.Sprintf("Download %s/%s (%d bytes) to each member and verify sha256 checksum", b.Bucket, b.ObjectKey, b.Size),
+			fmt.Sprintf("Restore snapshot to a new data directory on each member with initial cluster %s and token %s", initialCluster, token),
+			"Stop etcd service on all members",
+			"Move current data directory aside and replace it with the restored one on all members",
+			"Start etcd service on all members",
+			"Wait kubernetes api server healthy and resync cluster resources",
+		},
+		Warnings: []string{
+			fmt.Sprintf("All changes made to cluster %s after %s will be lost", cluster.GetName(), b.CreatedAt.Format(time.RFC3339)),
+		},
+	}
+	for i, m := range members {
+		plan.Members[i] = api.ClusterBackupRestoreMember{
+			Name:    m.Name,
+			DataDir: m.Config.DataDir,
+			PeerURL: m.PeerURL,
+		}
+	}
+	if cluster.GetStatus() == api.ClusterStatusRunning {
+		plan.Warnings = append(plan.Warnings, "Cluster is running, kubernetes api server is unavailable while etcd is stopped")
+	}
+	return plan, nil
+}
+
+func (b *SClusterBackup) AllowPerformRestore(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return db.IsDomainAllowPerform(ctx, userCred, b, "restore")
+}
+
+// PerformRestore restores cluster etcd from backup, restore plan should be reviewed first
+// and cluster name must be given as confirmation
+func (b *SClusterBackup) PerformRestore(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ClusterBackupRestoreInput) (jsonutils.JSONObject, error) {
+	cluster, err := b.validateRestore(ctx, userCred)
+	if err != nil {
+		return nil, err
+	}
+	if input.Confirm != cluster.GetName() {
+		return nil, httperrors.NewInputParameterError("Confirm with cluster name %q to restore, review restore-plan before restoring", cluster.GetName())
+	}
+	if b.Checksum == "" {
+		return nil, httperrors.NewNotAcceptableError("Backup %s has no checksum", b.GetName())
+	}
+	if _, err := b.getBackupStorage(ctx, userCred, cluster); err != nil {
+		return nil, err
+	}
+	return nil, b.StartRestoreTask(ctx, userCred, cluster, "")
+}
+
+func (b *SClusterBackup) StartRestoreTask(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster, parentTaskId string) error {
+	b.SetStatus(ctx, userCred, api.ClusterBackupStatusRestoring, "")
+	cluster.SetStatus(ctx, userCred, api.ClusterStatusRestoring, fmt.Sprintf("restore from backup %s", b.GetName()))
+	task, err := taskman.TaskManager.NewTask(ctx, "ClusterBackupRestoreTask", b, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+// DoRestore replaces etcd data of all members with the backup snapshot, running etcd
+// is only stopped after the snapshot is restored and verified on every member
+func (b *SClusterBackup) DoRestore(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster) error {
+	cli, err := b.getBackupStorage(ctx, userCred, cluster)
+	if err != nil {
+		return err
+	}
+	members, token, err := b.getRestoreMembers(ctx, userCred, cluster)
+	if err != nil {
+		return err
+	}
+	initialCluster := etcdsnapshot.InitialCluster(members)
+
+	for _, m := range members {
+		if err := b.prepareRestore(ctx, cli, m, initialCluster, token); err != nil {
+			return err
+		}
+	}
+	suffix := "bak-" + time.Now().Format("20060102150405")
+	if err := b.replaceDataDir(members, suffix); err != nil {
+		return err
+	}
+	if err := wait.Poll(10*time.Second, 10*time.Minute, func() (bool, error) {
+		if err := cluster.IsHealthy(); err != nil {
+			log.Infof("wait cluster %s healthy after restore: %v", cluster.GetName(), err)
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		err = errors.Wrap(err, "wait cluster healthy")
+		for _, m := range members {
+			if sErr := etcdsnapshot.Stop(m); sErr != nil {
+				log.Errorf("stop etcd of unhealthy restored member %s: %v", m.Name, sErr)
+			}
+		}
+		return rollbackRestore(err, members, suffix)
+	}
+	return nil
+}
+
+// replaceDataDir stops etcd and swaps in the restored data dir on all members,
+// original data dir is brought back and etcd is started again on failure
+func (b *SClusterBackup) replaceDataDir(members []*etcdsnapshot.Member, suffix string) error {
+	for _, m := range members {
+		if err := etcdsnapshot.Stop(m); err != nil {
+			return rollbackRestore(err, members, suffix)
+		}
+	}
+	for _, m := range members {
+		if err := etcdsnapshot.SwapDataDir(m, suffix); err != nil {
+			return rollbackRestore(err, members, suffix)
+		}
+	}
+	for _, m := range members {
+		if err := etcdsnapshot.Start(m); err != nil {
+			return rollbackRestore(err, members, suffix)
+		}
+	}
+	return nil
+}
+
+// rollbackRestore reverts data dir of members swapped by restore and starts etcd,
+// restore error is returned together with rollback errors
+func rollbackRestore(restoreErr error, members []*etcdsnapshot.Member, suffix string) error {
+	errs := []error{restoreErr}
+	for _, m := range members {
+		if err := etcdsnapshot.RevertDataDir(m, suffix); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, m := range members {
+		if err := etcdsnapshot.Start(m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+func (b *SClusterBackup) prepareRestore(ctx context.Context, cli *s3.Client, m *etcdsnapshot.Member, initialCluster, token string) error {
+	obj, err := cli.GetObject(ctx, b.Bucket, b.ObjectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "get object %s/%s", b.Bucket, b.ObjectKey)
+	}
+	defer obj.Close()
+	hasher := sha256.New()
+	if err := etcdsnapshot.PrepareRestore(m, initialCluster, token, io.TeeReader(obj, hasher)); err != nil {
+		return err
+	}
+	if checksum := hex.EncodeToString(hasher.Sum(nil)); checksum != b.Checksum {
+		return errors.Errorf("snapshot checksum %s of member %s mismatch, expected %s", checksum, m.Name, b.Checksum)
+	}
+	return nil
+}
+
+// GetBackupPolicy returns nil when backup policy is not set
+func (c *SCluster) GetBackupPolicy(ctx context.Context, userCred mcclient.TokenCredential) (*api.ClusterBackupPolicy, error) {
+	obj := c.GetMetadataJson(ctx, api.ClusterMetadataBackupPolicy, userCred)
+	if obj == nil {
+		return nil, nil
+	}
+	policy := new(api.ClusterBackupPolicy)
+	if err := obj.Unmarshal(policy); err != nil {
+		return nil, errors.Wrap(err, "unmarshal backup policy")
+	}
+	if policy.ObjectStore == nil {
+		return nil, nil
+	}
+	policy.ObjectStore.SecretKey = c.GetMetadata(ctx, api.ClusterMetadataBackupSecretKey, GetAdminCred())
+	return policy, nil
+}
+
+func (c *SCluster) GetDetailsBackupPolicy(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ClusterBackupPolicy, error) {
+	policy, err := c.GetBackupPolicy(ctx, userCred)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, httperrors.NewNotFoundError("cluster %s backup policy is not set", c.GetName())
+	}
+	policy.ObjectStore.SecretKey = ""
+	return policy, nil
+}
+
+func (c *SCluster) AllowPerformSetBackupPolicy(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return c.allowPerformAction(ctx, userCred, "set-backup-policy")
+}
+
+// PerformSetBackupPolicy sets etcd backup policy, secret key is kept when it's
+// not provided and storage endpoint and access key are unchanged
+func (c *SCluster) PerformSetBackupPolicy(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ClusterBackupPolicy) (jsonutils.JSONObject, error) {
+	if input.ObjectStore == nil {
+		return nil, httperrors.NewNotEmptyError("object_store")
+	}
+	old, err := c.GetBackupPolicy(ctx, userCred)
+	if err != nil {
+		return nil, err
+	}
+	if input.ObjectStore.SecretKey == "" && old != nil &&
+		old.ObjectStore.Endpoint == input.ObjectStore.Endpoint &&
+		old.ObjectStore.AccessKey == input.ObjectStore.AccessKey {
+		input.ObjectStore.SecretKey = old.ObjectStore.SecretKey
+	}
+	if input.IntervalHours < 0 || input.KeepLast < 0 || input.KeepDays < 0 {
+		return nil, httperrors.NewInputParameterError("interval_hours, keep_last and keep_days must not be negative")
+	}
+	if input.IntervalHours == 0 {
+		input.IntervalHours = api.DefaultClusterBackupIntervalHours
+	}
+	if input.KeepLast == 0 && input.KeepDays == 0 {
+		input.KeepLast = api.DefaultClusterBackupKeepLast
+	}
+	if input.Prefix == "" {
+		input.Prefix = api.DefaultClusterBackupPrefix
+	}
+	if _, err := c.GetDriver().GetEtcdMembers(ctx, userCred, c); err != nil {
+		return nil, err
+	}
+	if err := validateObjectStore(ctx, input.ObjectStore); err != nil {
+		return nil, err
+	}
+	if err := c.SetMetadata(ctx, api.ClusterMetadataBackupSecretKey, input.ObjectStore.SecretKey, userCred); err != nil {
+		return nil, errors.Wrap(err, "save backup secret key")
+	}
+	policy := *input
+	store := *input.ObjectStore
+	store.SecretKey = ""
+	policy.ObjectStore = &store
+	return nil, c.SetMetadata(ctx, api.ClusterMetadataBackupPolicy, jsonutils.Marshal(policy).String(), userCred)
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSelectExpiredBackups(t *testing.T) {
+	now := time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC)
+	newBackups := func() []SClusterBackup {
+		// one backup each day, newest first
+		ret := make([]SClusterBackup, 5)
+		for i := range ret {
+			ret[i].Name = fmt.Sprintf("b%d", i)
+			ret[i].CreatedAt = now.Add(-time.Duration(i)*24*time.Hour - time.Hour)
+		}
+		return ret
+	}
+	cases := []struct {
+		keepLast int
+		keepDays int
+		want     []string
+	}{
+		{keepLast: 3, want: []string{"b3", "b4"}},
+		{keepDays: 2, want: []string{"b2", "b3", "b4"}},
+		{keepLast: 1, keepDays: 3, want: []string{"b3", "b4"}},
+		{keepLast: 4, keepDays: 1, want: []string{"b4"}},
+		{keepLast: 10, want: []string{}},
+		{want: []string{}},
+	}
+	for _, c := range cases {
+		got := selectExpiredBackups(newBackups(), c.keepLast, c.keepDays, now)
+		names := make([]string, len(got))
+		for i := range got {
+			names[i] = got[i].Name
+		}
+		if fmt.Sprint(names) != fmt.Sprint(c.want) {
+			t.Errorf("keep last %d days %d: got %v, want %v", c.keepLast, c.keepDays, names, c.want)
+		}
+	}
+}
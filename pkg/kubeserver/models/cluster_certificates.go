@@ -0,0 +1,267 @@
+package models
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"sort"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/client"
+	"yunion.io/x/kubecomps/pkg/kubeserver/options"
+	"yunion.io/x/kubecomps/pkg/utils/certificates"
+	"yunion.io/x/kubecomps/pkg/utils/logclient"
+)
+
+const apiServerDialTimeout = 10 * time.Second
+
+var certificateStatusLevel = map[string]int{
+	api.ClusterCertificateStatusOk:       0,
+	api.ClusterCertificateStatusWarning:  1,
+	api.ClusterCertificateStatusCritical: 2,
+	api.ClusterCertificateStatusExpired:  3,
+}
+
+// NewClusterCertificate converts x509 certificate to api certificate, status is filled when checking
+func NewClusterCertificate(source string, cert *x509.Certificate) api.ClusterCertificate {
+	return api.ClusterCertificate{
+		Source:    source,
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		IsCA:      cert.IsCA,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+	}
+}
+
+// certificateStatus returns days left before notAfter and its status by thresholds
+func certificateStatus(notAfter, now time.Time, warningDays, criticalDays int) (int, string) {
+	left := notAfter.Sub(now)
+	if left <= 0 {
+		return int(math.Floor(left.Hours() / 24)), api.ClusterCertificateStatusExpired
+	}
+	days := int(left.Hours() / 24)
+	switch {
+	case left < time.Duration(criticalDays)*24*time.Hour:
+		return days, api.ClusterCertificateStatusCritical
+	case left < time.Duration(warningDays)*24*time.Hour:
+		return days, api.ClusterCertificateStatusWarning
+	}
+	return days, api.ClusterCertificateStatusOk
+}
+
+func parseClusterCertificates(source string, data []byte) ([]api.ClusterCertificate, error) {
+	certs, err := certificates.ParseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]api.ClusterCertificate, 0, len(certs))
+	for _, cert := range certs {
+		ret = append(ret, NewClusterCertificate(source, cert))
+	}
+	return ret, nil
+}
+
+// getApiServerCertificates returns serving certificates presented by cluster api server
+func (c *SCluster) getApiServerCertificates() ([]api.ClusterCertificate, error) {
+	u, err := url.Parse(c.ApiServer)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse api server %q", c.ApiServer)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+	dialer := &net.Dialer{Timeout: apiServerDialTimeout}
+	// only certificates are inspected, chain is not verified
+	conn, err := tls.DialWithDialer(dialer, "tcp", host, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "dial api server %s", host)
+	}
+	defer conn.Close()
+	ret := make([]api.ClusterCertificate, 0)
+	for _, cert := range conn.ConnectionState().PeerCertificates {
+		ret = append(ret, NewClusterCertificate(api.ClusterCertificateSourceApiServer, cert))
+	}
+	return ret, nil
+}
+
+// GetCertificates collects certificates of cluster and checks their expiry,
+// certificates can't be read are reported in errors instead of failing the whole check
+func (c *SCluster) GetCertificates(ctx context.Context, userCred mcclient.TokenCredential) (*api.ClusterCertificatesDetail, error) {
+	certs := make([]api.ClusterCertificate, 0)
+	errs := make([]string, 0)
+
+	if c.Ca != "" {
+		caCerts, err := parseClusterCertificates(api.ClusterCertificateSourceCA, []byte(c.Ca))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("parse ca: %v", err))
+		}
+		certs = append(certs, caCerts...)
+	}
+	if c.Kubeconfig != "" {
+		kubeCerts, err := parseClusterCertificates(api.ClusterCertificateSourceKubeconfig, []byte(c.Kubeconfig))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("parse kubeconfig: %v", err))
+		}
+		certs = append(certs, kubeCerts...)
+	}
+	if c.ApiServer != "" {
+		apiCerts, err := c.getApiServerCertificates()
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		certs = append(certs, apiCerts...)
+	}
+	machines, err := c.GetDriver().GetMachinesCertificates(ctx, userCred, c)
+	if err != nil {
+		if errors.Cause(err) != httperrors.ErrNotSupported {
+			errs = append(errs, fmt.Sprintf("get machines certificates: %v", err))
+		}
+	}
+	for _, m := range machines {
+		if m.Error != "" {
+			errs = append(errs, fmt.Sprintf("machine %s: %s", m.Machine, m.Error))
+		}
+		certs = append(certs, m.Certificates...)
+	}
+
+	now := time.Now()
+	ret := &api.ClusterCertificatesDetail{
+		Status:       api.ClusterCertificateStatusOk,
+		CheckedAt:    now,
+		WarningDays:  options.Options.CertificateExpiryWarningDays,
+		CriticalDays: options.Options.CertificateExpiryCriticalDays,
+		Certificates: certs,
+		Errors:       errs,
+	}
+	for i := range certs {
+		cert := &certs[i]
+		cert.DaysLeft, cert.Status = certificateStatus(cert.NotAfter, now, ret.WarningDays, ret.CriticalDays)
+		if certificateStatusLevel[cert.Status] > certificateStatusLevel[ret.Status] {
+			ret.Status = cert.Status
+		}
+		if ret.EarliestExpiry.IsZero() || cert.NotAfter.Before(ret.EarliestExpiry) {
+			ret.EarliestExpiry = cert.NotAfter
+		}
+	}
+	sort.SliceStable(certs, func(i, j int) bool {
+		return certs[i].NotAfter.Before(certs[j].NotAfter)
+	})
+	return ret, nil
+}
+
+func (c *SCluster) GetDetailsCertificates(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ClusterCertificatesDetail, error) {
+	return c.GetCertificates(ctx, userCred)
+}
+
+// GetCertificateExpiryState returns result of latest periodic check, nil if never checked
+func (c *SCluster) GetCertificateExpiryState(ctx context.Context, userCred mcclient.TokenCredential) (*api.ClusterCertificateExpiryState, error) {
+	obj := c.GetMetadataJson(ctx, api.ClusterMetadataCertificateExpiry, userCred)
+	if obj == nil {
+		return nil, nil
+	}
+	state := new(api.ClusterCertificateExpiryState)
+	if err := obj.Unmarshal(state); err != nil {
+		return nil, errors.Wrap(err, "unmarshal certificate expiry state")
+	}
+	return state, nil
+}
+
+func (c *SCluster) checkCertificatesExpiry(ctx context.Context, userCred mcclient.TokenCredential) error {
+	detail, err := c.GetCertificates(ctx, userCred)
+	if err != nil {
+		return err
+	}
+	prev, err := c.GetCertificateExpiryState(ctx, userCred)
+	if err != nil {
+		log.Warningf("get cluster %s certificate expiry state: %v", c.GetName(), err)
+	}
+	state := &api.ClusterCertificateExpiryState{
+		Status:         detail.Status,
+		EarliestExpiry: detail.EarliestExpiry,
+		CheckedAt:      detail.CheckedAt,
+	}
+	if err := c.SetMetadata(ctx, api.ClusterMetadataCertificateExpiry, jsonutils.Marshal(state).String(), userCred); err != nil {
+		return errors.Wrap(err, "save certificate expiry state")
+	}
+	if detail.Status == api.ClusterCertificateStatusOk {
+		return nil
+	}
+	// raise event once each time status gets worse
+	if prev != nil && certificateStatusLevel[prev.Status] >= certificateStatusLevel[detail.Status] {
+		return nil
+	}
+	notes := fmt.Sprintf("certificates %s, earliest expiry at %s", detail.Status, detail.EarliestExpiry.Format(time.RFC3339))
+	logclient.LogWithContext(ctx, c, logclient.ActionClusterCertsExpiring, notes, userCred, false)
+	return nil
+}
+
+// CheckCertificatesExpiry is cron job checking certificates expiry of running clusters
+func (m *SClusterManager) CheckCertificatesExpiry(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	clusters, err := m.getClustersByStatus(api.ClusterStatusRunning)
+	if err != nil {
+		log.Errorf("CheckCertificatesExpiry get clusters: %v", err)
+		return
+	}
+	for idx := range clusters {
+		c := &clusters[idx]
+		if err := c.checkCertificatesExpiry(ctx, userCred); err != nil {
+			log.Errorf("check cluster %s certificates expiry: %v", c.GetName(), err)
+		}
+	}
+}
+
+func (c *SCluster) AllowPerformRotateCertificates(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return c.allowPerformAction(ctx, userCred, "rotate-certificates")
+}
+
+// PerformRotateCertificates renews leaf certificates of control plane and regenerates stored admin kubeconfig,
+// CA is kept so existing kubeconfigs signed by it remain valid
+func (c *SCluster) PerformRotateCertificates(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(c.GetStatus(), []string{api.ClusterStatusRunning, api.ClusterStatusRotateCertsFail}) {
+		return nil, httperrors.NewNotAcceptableError("Can not rotate certificates when cluster status is %s", c.GetStatus())
+	}
+	if _, err := c.GetCAKeyPair(); err != nil {
+		return nil, httperrors.NewNotAcceptableError("Cluster %s CA key pair not found: %v", c.GetName(), err)
+	}
+	return nil, c.StartRotateCertificatesTask(ctx, userCred, "")
+}
+
+func (c *SCluster) StartRotateCertificatesTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	c.SetStatus(ctx, userCred, api.ClusterStatusRotatingCerts, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "ClusterRotateCertificatesTask", c, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+// RegenerateKubeconfig signs a new admin kubeconfig by cluster CA and refreshes cluster client
+func (c *SCluster) RegenerateKubeconfig() error {
+	kubeconfig, err := c.GetKubeconfigByCerts()
+	if err != nil {
+		return errors.Wrap(err, "generate kubeconfig")
+	}
+	if err := c.SetKubeconfig(kubeconfig); err != nil {
+		return errors.Wrap(err, "save kubeconfig")
+	}
+	if err := client.GetClustersManager().UpdateClient(c, false); err != nil {
+		return errors.Wrap(err, "update cluster client")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+func TestCertificateStatus(t *testing.T) {
+	now := time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	cases := []struct {
+		notAfter time.Time
+		days     int
+		status   string
+	}{
+		{now.Add(365 * day), 365, api.ClusterCertificateStatusOk},
+		{now.Add(30 * day), 30, api.ClusterCertificateStatusOk},
+		{now.Add(29*day + time.Hour), 29, api.ClusterCertificateStatusWarning},
+		{now.Add(6 * day), 6, api.ClusterCertificateStatusCritical},
+		{now.Add(time.Hour), 0, api.ClusterCertificateStatusCritical},
+		{now, 0, api.ClusterCertificateStatusExpired},
+		{now.Add(-time.Hour), -1, api.ClusterCertificateStatusExpired},
+	}
+	for _, c := range cases {
+		days, status := certificateStatus(c.notAfter, now, 30, 7)
+		if days != c.days || status != c.status {
+			t.Errorf("not after %s: got %d %s, want %d %s", c.notAfter, days, status, c.days, c.status)
+		}
+	}
+}
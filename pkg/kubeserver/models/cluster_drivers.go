@@ -16,6 +16,7 @@ import (
 	"yunion.io/x/kubecomps/pkg/kubeserver/api"
 	"yunion.io/x/kubecomps/pkg/kubeserver/drivers"
 	"yunion.io/x/kubecomps/pkg/kubeserver/models/manager"
+	"yunion.io/x/kubecomps/pkg/utils/etcdsnapshot"
 )
 
 type IClusterDriver interface {
@@ -59,6 +60,17 @@ type IClusterDriverMethods interface {
 	// RequestDeployMachines run ansible deploy machines as kubernetes nodes
 	RequestDeployMachines(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster, action api.ClusterDeployAction, machines []manager.IMachine, skipDownloads bool, task taskman.ITask) error
 	GetKubesprayConfig(ctx context.Context, cluster *SCluster) (*api.ClusterKubesprayConfig, error)
+	// ValidateUpgrade checks the binaries of target version can be deployed to the cluster
+	ValidateUpgrade(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster, version string) error
+	// RequestUpgradeMachines runs upgrade playbook on machines synchronously, control plane machines
+	// must be upgraded together before any worker machine
+	RequestUpgradeMachines(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster, version string, machines []manager.IMachine, skipDownloads bool) error
+	// GetEtcdMembers returns etcd members of cluster reached through ssh, first control plane member comes first
+	GetEtcdMembers(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster) ([]*etcdsnapshot.Member, error)
+	// GetMachinesCertificates reads certificate files deployed on cluster machines
+	GetMachinesCertificates(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster) ([]api.ClusterMachineCertificates, error)
+	// RequestRotateCertificates renews control plane leaf certificates synchronously
+	RequestRotateCertificates(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster) error
 
 	ValidateDeleteCondition() error
 	ValidateDeleteMachines(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster, machines []manager.IMachine) error
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/rbacscope"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/drivers/container_registries/scanner"
+)
+
+// normalizeImageReference completes image reference like docker does,
+// e.g. 'nginx' is normalized to 'docker.io/library/nginx:latest'
+func normalizeImageReference(image string) string {
+	name, digest := image, ""
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		name, digest = image[:idx], image[idx:]
+	}
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 1 {
+		name = "docker.io/library/" + name
+	} else if !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		name = "docker.io/" + name
+	}
+	if digest == "" && !strings.Contains(name[strings.LastIndex(name, "/")+1:], ":") {
+		name += ":latest"
+	}
+	return name + digest
+}
+
+// imageIDDigest extracts digest from container status image id,
+// e.g. 'docker-pullable://nginx@sha256:xxx' or 'sha256:xxx'
+func imageIDDigest(imageID string) string {
+	if idx := strings.LastIndex(imageID, "@"); idx >= 0 {
+		return imageID[idx+1:]
+	}
+	return ""
+}
+
+// fetchReadyScans returns scans of container registries visible to userCred
+func (m *SContainerImageScanManager) fetchReadyScans(ctx context.Context, userCred mcclient.TokenCredential) ([]SContainerImageScan, error) {
+	q := m.Query().Equals("status", api.ContainerImageScanStatusReady)
+	scope := rbacscope.ScopeProject
+	if db.IsAdminAllowList(userCred, m).Result.IsAllow() {
+		scope = rbacscope.ScopeSystem
+	}
+	q = m.FilterByOwner(ctx, q, m, userCred, userCred, scope)
+	scans := make([]SContainerImageScan, 0)
+	if err := db.FetchModelObjects(m, q, &scans); err != nil {
+		return nil, err
+	}
+	return scans, nil
+}
+
+// GetDetailsImageVulnerabilities maps images of running pods to vulnerabilities scanned in container registries
+func (c *SCluster) GetDetailsImageVulnerabilities(ctx context.Context, userCred mcclient.TokenCredential, query *api.ClusterImageVulnerabilityReportInput) (*api.ClusterImageVulnerabilityReport, error) {
+	pods, err := GetPodManager().GetPodsByClusters([]string{c.GetId()})
+	if err != nil {
+		return nil, errors.Wrap(err, "get cluster pods")
+	}
+	scans, err := ContainerImageScanManager.fetchReadyScans(ctx, userCred)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch image scans")
+	}
+	scansByDigest := make(map[string]*SContainerImageScan)
+	scansByImage := make(map[string]*SContainerImageScan)
+	for i := range scans {
+		if scans[i].Digest != "" {
+			scansByDigest[scans[i].Digest] = &scans[i]
+		}
+		scansByImage[normalizeImageReference(scans[i].Image)] = &scans[i]
+	}
+
+	images := make(map[string]*api.ClusterImageVulnerability)
+	for i := range pods {
+		obj, err := pods[i].GetK8sObject()
+		if err != nil {
+			log.Warningf("get pod %s object: %v", pods[i].GetName(), err)
+			continue
+		}
+		pod := obj.(*v1.Pod)
+		digests := make(map[string]string)
+		// pod is shared with informer cache, don't append to its slices
+		statuses := make([]v1.ContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses))
+		statuses = append(append(statuses, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, s := range statuses {
+			digests[s.Name] = imageIDDigest(s.ImageID)
+		}
+		containers := make([]v1.Container, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+		containers = append(append(containers, pod.Spec.InitContainers...), pod.Spec.Containers...)
+		for _, container := range containers {
+			image := normalizeImageReference(container.Image)
+			item, ok := images[image]
+			if !ok {
+				item = &api.ClusterImageVulnerability{
+					Image:  image,
+					Digest: digests[container.Name],
+					Pods:   make([]api.ClusterImagePodRef, 0),
+				}
+				images[image] = item
+			}
+			item.Pods = append(item.Pods, api.ClusterImagePodRef{
+				Namespace: pod.GetNamespace(),
+				Name:      pod.GetName(),
+				Container: container.Name,
+			})
+		}
+	}
+
+	report := &api.ClusterImageVulnerabilityReport{
+		Images:  make([]api.ClusterImageVulnerability, 0),
+		Summary: make(map[string]int),
+	}
+	for _, item := range images {
+		scan, ok := scansByDigest[item.Digest]
+		if !ok || item.Digest == "" {
+			scan, ok = scansByImage[item.Image]
+		}
+		if ok {
+			item.Scanned = true
+			item.Summary = scan.GetSummary()
+		}
+		if len(query.Severity) != 0 && (item.Summary == nil || !utils.IsInStringArray(item.Summary.Severity, query.Severity)) {
+			continue
+		}
+		if item.Summary == nil {
+			report.Unscanned++
+		} else {
+			for severity, count := range item.Summary.Summary {
+				report.Summary[severity] += count
+			}
+		}
+		report.Images = append(report.Images, *item)
+	}
+	// most severe images first
+	sort.Slice(report.Images, func(i, j int) bool {
+		si, sj := api.VulnerabilitySeverityNone, api.VulnerabilitySeverityNone
+		if s := report.Images[i].Summary; s != nil {
+			si = s.Severity
+		}
+		if s := report.Images[j].Summary; s != nil {
+			sj = s.Severity
+		}
+		if si != sj {
+			return scanner.SeverityRank(si) < scanner.SeverityRank(sj)
+		}
+		return report.Images[i].Image < report.Images[j].Image
+	})
+	return report, nil
+}
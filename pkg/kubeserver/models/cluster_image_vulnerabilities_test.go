@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestNormalizeImageReference(t *testing.T) {
+	cases := map[string]string{
+		"nginx":           "docker.io/library/nginx:latest",
+		"nginx:1.19":      "docker.io/library/nginx:1.19",
+		"grafana/grafana": "docker.io/grafana/grafana:latest",
+		"registry.cn-beijing.aliyuncs.com/yunionio/kubeserver:v3.8": "registry.cn-beijing.aliyuncs.com/yunionio/kubeserver:v3.8",
+		"192.168.0.1:5000/library/busybox":                          "192.168.0.1:5000/library/busybox:latest",
+		"localhost/pause":                                           "localhost/pause:latest",
+		"nginx@sha256:abc":                                          "docker.io/library/nginx@sha256:abc",
+	}
+	for image, want := range cases {
+		if got := normalizeImageReference(image); got != want {
+			t.Errorf("normalizeImageReference(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestImageIDDigest(t *testing.T) {
+	cases := map[string]string{
+		"docker-pullable://nginx@sha256:abc":            "sha256:abc",
+		"docker.io/library/nginx@sha256:abc":            "sha256:abc",
+		"sha256:e1ab0b71b1b7f0a5b6e1d6c0b4c1d0c9f8e7d6": "",
+		"": "",
+	}
+	for id, want := range cases {
+		if got := imageIDDigest(id); got != want {
+			t.Errorf("imageIDDigest(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/sets"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models/manager"
+)
+
+// validateClusterUpgradeVersion checks version skew, kubeadm can only upgrade one minor version at a time
+func validateClusterUpgradeVersion(current, target string, supported []string) error {
+	if !utils.IsInStringArray(target, supported) {
+		return httperrors.NewInputParameterError("Invalid version: %q, choose one from %v", target, supported)
+	}
+	cur, err := version.ParseGeneric(current)
+	if err != nil {
+		return httperrors.NewNotAcceptableError("Parse current version %q: %v", current, err)
+	}
+	tgt, err := version.ParseGeneric(target)
+	if err != nil {
+		return httperrors.NewInputParameterError("Parse version %q: %v", target, err)
+	}
+	if !cur.LessThan(tgt) {
+		return httperrors.NewInputParameterError("Version %s is not newer than current version %s", target, current)
+	}
+	if tgt.Major() != cur.Major() || tgt.Minor() > cur.Minor()+1 {
+		return httperrors.NewInputParameterError("Can only upgrade one minor version at a time, %s => %s is not allowed", current, target)
+	}
+	return nil
+}
+
+func (c *SCluster) AllowPerformUpgrade(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return c.allowPerformAction(ctx, userCred, "upgrade")
+}
+
+// PerformUpgrade upgrades kubernetes of cluster in place, a failed upgrade
+// is resumed from the failed machine when performed again with the same version
+func (c *SCluster) PerformUpgrade(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ClusterUpgradeInput) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(c.GetStatus(), []string{api.ClusterStatusRunning, api.ClusterStatusUpgradeFail}) {
+		return nil, httperrors.NewNotAcceptableError("Can not upgrade cluster when status is %s", c.GetStatus())
+	}
+	state, err := c.GetUpgradeState(ctx, userCred)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil && c.GetStatus() == api.ClusterStatusUpgradeFail {
+		if input.Version == "" {
+			input.Version = state.ToVersion
+		}
+		if input.Version != state.ToVersion {
+			return nil, httperrors.NewInputParameterError("Previous upgrade to %s is not finished, resume it first", state.ToVersion)
+		}
+	} else {
+		if input.Version == "" {
+			return nil, httperrors.NewNotEmptyError("version")
+		}
+		if err := validateClusterUpgradeVersion(c.GetVersion(), input.Version, c.GetDriver().GetK8sVersions()); err != nil {
+			return nil, err
+		}
+		state = &api.ClusterUpgradeState{
+			FromVersion:      c.GetVersion(),
+			ToVersion:        input.Version,
+			UpgradedMachines: []string{},
+		}
+	}
+	if err := c.GetDriver().ValidateUpgrade(ctx, userCred, c, input.Version); err != nil {
+		return nil, err
+	}
+	state.FailedMachine = ""
+	state.Reason = ""
+	if err := c.SetUpgradeState(ctx, userCred, state); err != nil {
+		return nil, errors.Wrap(err, "save upgrade state")
+	}
+	return nil, c.StartUpgradeTask(ctx, userCred, input, "")
+}
+
+func (c *SCluster) StartUpgradeTask(ctx context.Context, userCred mcclient.TokenCredential, input *api.ClusterUpgradeInput, parentTaskId string) error {
+	c.SetStatus(ctx, userCred, api.ClusterStatusUpgrading, "")
+	data := jsonutils.Marshal(input).(*jsonutils.JSONDict)
+	task, err := taskman.TaskManager.NewTask(ctx, "ClusterUpgradeTask", c, userCred, data, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+// GetUpgradeState returns nil when there is no unfinished upgrade
+func (c *SCluster) GetUpgradeState(ctx context.Context, userCred mcclient.TokenCredential) (*api.ClusterUpgradeState, error) {
+	obj := c.GetMetadataJson(ctx, api.ClusterMetadataUpgradeState, userCred)
+	if obj == nil {
+		return nil, nil
+	}
+	state := new(api.ClusterUpgradeState)
+	if err := obj.Unmarshal(state); err != nil {
+		return nil, errors.Wrap(err, "unmarshal upgrade state")
+	}
+	if state.ToVersion == "" {
+		return nil, nil
+	}
+	return state, nil
+}
+
+func (c *SCluster) SetUpgradeState(ctx context.Context, userCred mcclient.TokenCredential, state *api.ClusterUpgradeState) error {
+	return c.SetMetadata(ctx, api.ClusterMetadataUpgradeState, jsonutils.Marshal(state).String(), userCred)
+}
+
+func (c *SCluster) ClearUpgradeState(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return c.RemoveMetadata(ctx, api.ClusterMetadataUpgradeState, userCred)
+}
+
+func (c *SCluster) GetDetailsUpgradeState(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ClusterUpgradeState, error) {
+	state, err := c.GetUpgradeState(ctx, userCred)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, httperrors.NewNotFoundError("cluster %s has no unfinished upgrade", c.GetName())
+	}
+	return state, nil
+}
+
+// GetNodesArchitectures returns cpu architectures of all kubernetes nodes
+func (c *SCluster) GetNodesArchitectures(ctx context.Context) ([]string, error) {
+	cli, err := c.GetK8sClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get k8s client")
+	}
+	nodes, err := cli.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "list nodes")
+	}
+	archs := sets.NewString()
+	for _, node := range nodes.Items {
+		archs.Insert(node.Status.NodeInfo.Architecture)
+	}
+	return archs.List(), nil
+}
+
+// SetMachineNodeSchedulable uncordons node of machine, node is matched by name or ip like draining
+func (c *SCluster) SetMachineNodeSchedulable(userCred mcclient.TokenCredential, m manager.IMachine) error {
+	nodes, err := c.listRemoteNodes()
+	if err != nil {
+		return errors.Wrap(err, "list remote nodes")
+	}
+	node := matchMachineK8sNode(m, nodes)
+	if node == nil {
+		return errors.Wrapf(errors.ErrNotFound, "node of machine %s", m.GetName())
+	}
+	obj, err := GetNodeManager().GetByName(userCred, c.GetId(), node.GetName())
+	if err != nil {
+		return errors.Wrapf(err, "get machine %s node %s", m.GetName(), node.GetName())
+	}
+	return obj.(*SNode).SetNodeScheduleToggle(false)
+}
+
+// GetUpgradeMachines splits machines to be upgraded into control plane and worker groups
+func (c *SCluster) GetUpgradeMachines(state *api.ClusterUpgradeState) ([]manager.IMachine, []manager.IMachine, error) {
+	ms, err := c.GetDeployMachines(nil)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "get deploy machines")
+	}
+	upgraded := sets.NewString(state.UpgradedMachines...)
+	cps := make([]manager.IMachine, 0)
+	workers := make([]manager.IMachine, 0)
+	for _, m := range ms {
+		if m.IsControlplane() {
+			if !state.ControlplaneUpgraded {
+				cps = append(cps, m)
+			}
+			continue
+		}
+		if !upgraded.Has(m.GetName()) {
+			workers = append(workers, m)
+		}
+	}
+	return cps, workers, nil
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestValidateClusterUpgradeVersion(t *testing.T) {
+	supported := []string{"v1.17.0", "v1.18.8", "v1.20.0", "v1.20.4"}
+	cases := []struct {
+		current string
+		target  string
+		wantErr bool
+	}{
+		{current: "v1.17.0", target: "v1.18.8"},
+		{current: "v1.20.0", target: "v1.20.4"},
+		{current: "v1.18.8", target: "v1.20.0", wantErr: true},
+		{current: "v1.18.8", target: "v1.17.0", wantErr: true},
+		{current: "v1.20.4", target: "v1.20.4", wantErr: true},
+		{current: "v1.17.0", target: "v1.18.0", wantErr: true},
+	}
+	for _, c := range cases {
+		err := validateClusterUpgradeVersion(c.current, c.target, supported)
+		if c.wantErr && err == nil {
+			t.Errorf("%s => %s: expect error", c.current, c.target)
+		}
+		if !c.wantErr && err != nil {
+			t.Errorf("%s => %s: %v", c.current, c.target, err)
+		}
+	}
+}
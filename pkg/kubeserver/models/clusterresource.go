@@ -1167,6 +1167,7 @@ func (m *SClusterResourceBaseManager) OnRemoteObjectUpdate(ctx context.Context,
 	if err := processCreateOrUpdateByRemoteObject(ctx, userCred, cluster, resMan.(IClusterModelManager), newObj); err != nil {
 		log.Errorf("OnRemoteObjectUpdate %s error: %v", m.kObjLogPrefix(cluster, newObj), err)
 	}
+	OnFedMemberObjectChanged(cluster, resMan.(IClusterModelManager), newObj)
 }
 
 // OnRemoteObjectDelete invoked when remote resource deleted
@@ -1175,6 +1176,7 @@ func (m *SClusterResourceBaseManager) OnRemoteObjectDelete(ctx context.Context,
 	if err := processRemoteObjectDelete(ctx, userCred, cluster, resMan.(IClusterModelManager), obj); err != nil {
 		log.Errorf("processRemoteObjectDelete %s error: %v", m.kObjLogPrefix(cluster, obj), err)
 	}
+	OnFedMemberObjectChanged(cluster, resMan.(IClusterModelManager), obj)
 }
 
 func processCreateOrUpdateByRemoteObject(ctx context.Context, userCred mcclient.TokenCredential, cluster manager.ICluster, resMan IClusterModelManager, obj runtime.Object) error {
@@ -1922,7 +1922,44 @@ func (c *SCluster) PerformDeleteMachines(ctx context.Context, userCred mcclient.
 	if err := driver.ValidateDeleteMachines(ctx, userCred, c, machines); err != nil {
 		return nil, err
 	}
-	return nil, c.StartDeleteMachinesTask(ctx, userCred, machines, data.(*jsonutils.JSONDict), "")
+	ret := c.checkMachinesDisruption(machines)
+	if err := c.StartDeleteMachinesTask(ctx, userCred, machines, data.(*jsonutils.JSONDict), ""); err != nil {
+		return nil, err
+	}
+	if ret == nil {
+		return nil, nil
+	}
+	return jsonutils.Marshal(ret), nil
+}
+
+// checkMachinesDisruption warns pod disruption budgets broken by deleting machines,
+// it doesn't block deleting and nil is returned when cluster isn't reachable
+func (c *SCluster) checkMachinesDisruption(machines []manager.IMachine) *api.NodeDisruptionCheckResult {
+	cli, err := c.GetRemoteClient()
+	if err != nil {
+		log.Warningf("Get cluster %s remote client error: %v", c.GetName(), err)
+		return nil
+	}
+	remoteNodes, err := c.listRemoteNodes()
+	if err != nil {
+		log.Warningf("List cluster %s nodes error: %v", c.GetName(), err)
+		return nil
+	}
+	nodes := make([]string, 0)
+	for _, m := range machines {
+		if node := matchMachineK8sNode(m, remoteNodes); node != nil {
+			nodes = append(nodes, node.GetName())
+		}
+	}
+	ret, err := CheckNodesDisruption(cli, nodes)
+	if err != nil {
+		log.Warningf("Check cluster %s pod disruption budgets error: %v", c.GetName(), err)
+		return nil
+	}
+	for _, w := range ret.Warnings {
+		log.Warningf("Delete cluster %s machines: poddisruptionbudget %s/%s %s", c.GetName(), w.Namespace, w.Name, w.Message)
+	}
+	return ret
 }
 
 func (c *SCluster) StartDeleteMachinesTask(ctx context.Context, userCred mcclient.TokenCredential, ms []manager.IMachine, data *jsonutils.JSONDict, parentTaskId string) error {
@@ -6,6 +6,9 @@ import (
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kubernetes/pkg/apis/core"
+	"k8s.io/kubernetes/pkg/apis/core/validation"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/onecloud/pkg/httperrors"
@@ -61,6 +64,12 @@ func (m SConfigMapManager) GetRawConfigMaps(cluster *client.ClusterManager, ns s
 	return indexer.ConfigMapLister().ConfigMaps(ns).List(labels.Everything())
 }
 
+func (m *SConfigMapManager) ValidateConfigMapObject(cfg *v1.ConfigMap) error {
+	return ValidateCreateK8sObject(cfg, new(core.ConfigMap), func(out interface{}) field.ErrorList {
+		return validation.ValidateConfigMap(out.(*core.ConfigMap))
+	})
+}
+
 func (m *SConfigMapManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.ConfigMapCreateInput) (*api.ConfigMapCreateInput, error) {
 	if _, err := m.SNamespaceResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, &input.NamespaceResourceCreateInput); err != nil {
 		return input, err
@@ -0,0 +1,513 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/regclient/regclient/types/ref"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/drivers/container_registries/client"
+	"yunion.io/x/kubecomps/pkg/kubeserver/options"
+)
+
+var ContainerImageMirrorManager *SContainerImageMirrorManager
+
+// ContainerImageMirrorSyncStaleTimeout is how long a syncing mirror without progress is considered stale
+const ContainerImageMirrorSyncStaleTimeout = 6 * time.Hour
+
+func init() {
+	ContainerImageMirrorManager = &SContainerImageMirrorManager{
+		SStatusDomainLevelResourceBaseManager: db.NewStatusDomainLevelResourceBaseManager(
+			SContainerImageMirror{},
+			"container_image_mirrors_tbl",
+			"container_image_mirror",
+			"container_image_mirrors",
+		),
+	}
+	ContainerImageMirrorManager.SetVirtualObject(ContainerImageMirrorManager)
+}
+
+type SContainerImageMirrorManager struct {
+	db.SStatusDomainLevelResourceBaseManager
+}
+
+// SContainerImageMirror is a replication job mirroring repositories from source registry to target registry
+type SContainerImageMirror struct {
+	db.SStatusDomainLevelResourceBase
+
+	// SourceRegistryId is empty when mirroring from public registry of source url
+	SourceRegistryId string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional" index:"true"`
+	SourceUrl        string `width:"256" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	TargetRegistryId string `width:"36" charset:"ascii" nullable:"false" list:"user" create:"required" index:"true"`
+	TargetNamespace  string `width:"128" charset:"ascii" nullable:"true" list:"user" create:"optional" update:"user"`
+
+	Repositories      jsonutils.JSONObject `nullable:"true" list:"user" create:"optional" update:"user"`
+	RepositoryPattern string               `width:"256" charset:"utf8" nullable:"true" list:"user" create:"optional" update:"user"`
+	TagPattern        string               `width:"256" charset:"utf8" nullable:"true" list:"user" create:"optional" update:"user"`
+	Platforms         jsonutils.JSONObject `nullable:"true" list:"user" create:"optional" update:"user"`
+
+	IntervalHours int       `nullable:"false" default:"0" list:"user" create:"optional" update:"user"`
+	LastSyncAt    time.Time `nullable:"true" list:"user"`
+	// Progress is per image status of current or last sync
+	Progress jsonutils.JSONObject `nullable:"true" list:"user"`
+}
+
+func (m *SContainerImageMirrorManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, input *api.ContainerImageMirrorListInput) (*sqlchemy.SQuery, error) {
+	q, err := m.SStatusDomainLevelResourceBaseManager.ListItemFilter(ctx, q, userCred, input.StatusDomainLevelResourceListInput)
+	if err != nil {
+		return nil, err
+	}
+	if input.ContainerRegistry != "" {
+		regs := GetContainerRegistryManager().Query().SubQuery()
+		sq := regs.Query(regs.Field("id")).
+			Filter(sqlchemy.OR(
+				sqlchemy.Equals(regs.Field("name"), input.ContainerRegistry),
+				sqlchemy.Equals(regs.Field("id"), input.ContainerRegistry))).SubQuery()
+		q = q.Filter(sqlchemy.OR(
+			sqlchemy.In(q.Field("source_registry_id"), sq),
+			sqlchemy.In(q.Field("target_registry_id"), sq)))
+	}
+	return q, nil
+}
+
+func (m *SContainerImageMirrorManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []*jsonutils.JSONDict {
+	rows := make([]*jsonutils.JSONDict, len(objs))
+	baseRows := m.SStatusDomainLevelResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range objs {
+		rows[i] = jsonutils.Marshal(baseRows[i]).(*jsonutils.JSONDict)
+		mirror := objs[i].(*SContainerImageMirror)
+		if reg, err := mirror.GetSourceRegistry(); err == nil && reg != nil {
+			rows[i].Add(jsonutils.NewString(reg.GetName()), "source_registry")
+		}
+		if reg, err := mirror.GetTargetRegistry(); err == nil {
+			rows[i].Add(jsonutils.NewString(reg.GetName()), "target_registry")
+		}
+	}
+	return rows
+}
+
+func fetchContainerRegistry(ctx context.Context, userCred mcclient.TokenCredential, idOrName string) (*SContainerRegistry, error) {
+	obj, err := GetContainerRegistryManager().FetchByIdOrName(ctx, userCred, idOrName)
+	if err != nil {
+		return nil, NewCheckIdOrNameError("container_registry", idOrName, err)
+	}
+	return obj.(*SContainerRegistry), nil
+}
+
+func validateMirrorPatterns(patterns ...string) error {
+	for _, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return httperrors.NewInputParameterError("invalid pattern %q: %v", p, err)
+		}
+	}
+	return nil
+}
+
+func (m *SContainerImageMirrorManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.ContainerImageMirrorCreateInput) (*api.ContainerImageMirrorCreateInput, error) {
+	if input.TargetRegistry == "" {
+		input.TargetRegistry = input.TargetRegistryId
+	}
+	if input.TargetRegistry == "" {
+		return nil, httperrors.NewNotEmptyError("target_registry")
+	}
+	target, err := fetchContainerRegistry(ctx, userCred, input.TargetRegistry)
+	if err != nil {
+		return nil, err
+	}
+	input.TargetRegistryId = target.GetId()
+
+	if input.SourceRegistry == "" {
+		input.SourceRegistry = input.SourceRegistryId
+	}
+	if input.SourceRegistry != "" {
+		src, err := fetchContainerRegistry(ctx, userCred, input.SourceRegistry)
+		if err != nil {
+			return nil, err
+		}
+		if src.GetId() == target.GetId() {
+			return nil, httperrors.NewInputParameterError("source and target registry are same")
+		}
+		input.SourceRegistryId = src.GetId()
+		input.SourceUrl = ""
+	} else {
+		if input.SourceUrl == "" {
+			input.SourceUrl = options.Options.ImageRepo
+		}
+		if input.RepositoryPattern != "" {
+			return nil, httperrors.NewInputParameterError("repository_pattern is only supported by source registry")
+		}
+	}
+	if len(input.Repositories) == 0 && input.RepositoryPattern == "" {
+		return nil, httperrors.NewNotEmptyError("repositories or repository_pattern")
+	}
+	if err := validateMirrorPatterns(input.RepositoryPattern, input.TagPattern); err != nil {
+		return nil, err
+	}
+	if input.IntervalHours < 0 {
+		return nil, httperrors.NewInputParameterError("interval_hours must not be negative")
+	}
+	sInput, err := m.SStatusDomainLevelResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.StatusDomainLevelResourceCreateInput)
+	if err != nil {
+		return nil, err
+	}
+	input.StatusDomainLevelResourceCreateInput = sInput
+	return input, nil
+}
+
+func (mr *SContainerImageMirror) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	if err := mr.SStatusDomainLevelResourceBase.CustomizeCreate(ctx, userCred, ownerId, query, data); err != nil {
+		return err
+	}
+	mr.Status = api.ContainerImageMirrorStatusReady
+	return nil
+}
+
+func (mr *SContainerImageMirror) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ContainerImageMirrorUpdateInput) (*api.ContainerImageMirrorUpdateInput, error) {
+	if input.RepositoryPattern != nil {
+		if *input.RepositoryPattern != "" && mr.SourceRegistryId == "" {
+			return nil, httperrors.NewInputParameterError("repository_pattern is only supported by source registry")
+		}
+		if err := validateMirrorPatterns(*input.RepositoryPattern); err != nil {
+			return nil, err
+		}
+	}
+	if input.TagPattern != nil {
+		if err := validateMirrorPatterns(*input.TagPattern); err != nil {
+			return nil, err
+		}
+	}
+	if input.IntervalHours != nil && *input.IntervalHours < 0 {
+		return nil, httperrors.NewInputParameterError("interval_hours must not be negative")
+	}
+	sInput, err := mr.SStatusDomainLevelResourceBase.ValidateUpdateData(ctx, userCred, query, input.StatusDomainLevelResourceBaseUpdateInput)
+	if err != nil {
+		return nil, err
+	}
+	input.StatusDomainLevelResourceBaseUpdateInput = sInput
+	return input, nil
+}
+
+func (mr *SContainerImageMirror) GetSourceRegistry() (*SContainerRegistry, error) {
+	if mr.SourceRegistryId == "" {
+		return nil, nil
+	}
+	obj, err := GetContainerRegistryManager().FetchById(mr.SourceRegistryId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch source registry %s", mr.SourceRegistryId)
+	}
+	return obj.(*SContainerRegistry), nil
+}
+
+func (mr *SContainerImageMirror) GetTargetRegistry() (*SContainerRegistry, error) {
+	obj, err := GetContainerRegistryManager().FetchById(mr.TargetRegistryId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch target registry %s", mr.TargetRegistryId)
+	}
+	return obj.(*SContainerRegistry), nil
+}
+
+func (mr *SContainerImageMirror) getStringList(obj jsonutils.JSONObject) []string {
+	ret := make([]string, 0)
+	if obj != nil {
+		obj.Unmarshal(&ret)
+	}
+	return ret
+}
+
+func (mr *SContainerImageMirror) GetDetailsProgress(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ContainerImageMirrorProgress, error) {
+	progress := new(api.ContainerImageMirrorProgress)
+	if mr.Progress == nil {
+		return progress, nil
+	}
+	if err := mr.Progress.Unmarshal(progress); err != nil {
+		return nil, errors.Wrap(err, "unmarshal progress")
+	}
+	return progress, nil
+}
+
+// PerformSync starts mirroring on demand
+func (mr *SContainerImageMirror) PerformSync(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if mr.GetStatus() == api.ContainerImageMirrorStatusSyncing {
+		return nil, httperrors.NewNotAcceptableError("container image mirror %s is syncing", mr.GetName())
+	}
+	return nil, mr.StartSyncTask(ctx, userCred, "")
+}
+
+func (mr *SContainerImageMirror) StartSyncTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	mr.SetStatus(ctx, userCred, api.ContainerImageMirrorStatusSyncing, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "ContainerImageMirrorSyncTask", mr, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (mr *SContainerImageMirror) newMirror() (*client.Mirror, error) {
+	target, err := mr.GetTargetRegistry()
+	if err != nil {
+		return nil, err
+	}
+	targetReg, err := target.GetMirrorRegistry()
+	if err != nil {
+		return nil, errors.Wrap(err, "get target registry")
+	}
+	srcReg := &client.MirrorRegistry{Url: mr.SourceUrl}
+	src, err := mr.GetSourceRegistry()
+	if err != nil {
+		return nil, err
+	}
+	if src != nil {
+		srcReg, err = src.GetMirrorRegistry()
+		if err != nil {
+			return nil, errors.Wrap(err, "get source registry")
+		}
+	}
+	return client.NewMirror(*srcReg, *targetReg)
+}
+
+// listSourceRepositories returns explicit repositories along with repositories matched in source registry catalog
+func (mr *SContainerImageMirror) listSourceRepositories(ctx context.Context) ([]string, error) {
+	repos := mr.getStringList(mr.Repositories)
+	if mr.RepositoryPattern == "" {
+		return repos, nil
+	}
+	pattern, err := regexp.Compile(mr.RepositoryPattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "compile repository pattern")
+	}
+	src, err := mr.GetSourceRegistry()
+	if err != nil {
+		return nil, errors.Wrap(err, "get source registry")
+	}
+	if src == nil {
+		return nil, errors.Wrap(errors.ErrNotFound, "repository pattern requires source registry")
+	}
+	cli, err := src.GetDockerRegistryClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get source registry client")
+	}
+	imagesObj, err := cli.ListImages(ctx, &api.ContainerRegistryListImagesInput{})
+	if err != nil {
+		return nil, errors.Wrap(err, "list source images")
+	}
+	if imagesObj == nil {
+		return nil, errors.Wrapf(errors.ErrNotSupported, "list images of source registry %s", src.GetName())
+	}
+	catalog := new(client.CatalogResult)
+	if err := imagesObj.Unmarshal(catalog); err != nil {
+		return nil, errors.Wrap(err, "unmarshal source images")
+	}
+	// catalog contains path prefix of source registry url
+	prefix := strings.Trim(registryUrlPath(src.Url), "/")
+	for _, repo := range catalog.Repositories {
+		if prefix != "" {
+			repo = strings.TrimPrefix(repo, prefix+"/")
+		}
+		if pattern.MatchString(repo) && !utils.IsInStringArray(repo, repos) {
+			repos = append(repos, repo)
+		}
+	}
+	return repos, nil
+}
+
+func registryUrlPath(regUrl string) string {
+	if idx := strings.Index(regUrl, "://"); idx >= 0 {
+		regUrl = regUrl[idx+3:]
+	}
+	if idx := strings.Index(regUrl, "/"); idx >= 0 {
+		return regUrl[idx:]
+	}
+	return ""
+}
+
+// targetRepository keeps full path of source repository under target namespace,
+// so that repositories having same base name don't overwrite each other
+func (mr *SContainerImageMirror) targetRepository(repo string) string {
+	ns := strings.Trim(mr.TargetNamespace, "/")
+	if ns == "" {
+		return repo
+	}
+	return fmt.Sprintf("%s/%s", ns, strings.TrimPrefix(repo, "/"))
+}
+
+func (mr *SContainerImageMirror) saveProgress(progress *api.ContainerImageMirrorProgress) {
+	if _, err := db.Update(mr, func() error {
+		mr.Progress = jsonutils.Marshal(progress)
+		return nil
+	}); err != nil {
+		log.Errorf("save container image mirror %s progress: %v", mr.GetName(), err)
+	}
+}
+
+// DoSync copies tags matched of all repositories, failure of one image doesn't stop others
+// but fails the sync at last
+func (mr *SContainerImageMirror) DoSync(ctx context.Context, userCred mcclient.TokenCredential) (*api.ContainerImageMirrorProgress, error) {
+	// record sync time before anything may fail, otherwise scheduled sync retries failed mirror every round
+	startedAt := time.Now()
+	if _, err := db.Update(mr, func() error {
+		mr.LastSyncAt = startedAt
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "update last sync time")
+	}
+	var tagPattern *regexp.Regexp
+	if mr.TagPattern != "" {
+		var err error
+		if tagPattern, err = regexp.Compile(mr.TagPattern); err != nil {
+			return nil, errors.Wrap(err, "compile tag pattern")
+		}
+	}
+	mirror, err := mr.newMirror()
+	if err != nil {
+		return nil, errors.Wrap(err, "new mirror")
+	}
+	repos, err := mr.listSourceRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &api.ContainerImageMirrorProgress{
+		StartedAt: startedAt,
+		Errors:    make([]string, 0),
+		Images:    make([]api.ContainerImageMirrorImageProgress, 0),
+	}
+	type image struct {
+		repo string
+		tag  string
+	}
+	images := make([]image, 0)
+	for _, repo := range repos {
+		tags, err := mirror.ListTags(ctx, repo)
+		if err != nil {
+			progress.Errors = append(progress.Errors, err.Error())
+			continue
+		}
+		for _, tag := range tags {
+			if tagPattern != nil && !tagPattern.MatchString(tag) {
+				continue
+			}
+			images = append(images, image{repo: repo, tag: tag})
+			progress.Images = append(progress.Images, api.ContainerImageMirrorImageProgress{
+				Source:    fmt.Sprintf("%s:%s", repo, tag),
+				Target:    fmt.Sprintf("%s:%s", mr.targetRepository(repo), tag),
+				Status:    api.ContainerImageMirrorImageStatusPending,
+				UpdatedAt: time.Now(),
+			})
+		}
+	}
+	progress.Total = len(images)
+	mr.saveProgress(progress)
+
+	platforms := mr.getStringList(mr.Platforms)
+	for i, img := range images {
+		p := &progress.Images[i]
+		src, err := mirror.SourceRef(img.repo, img.tag)
+		if err == nil {
+			p.Source = src.CommonName()
+			var target ref.Ref
+			target, err = mirror.TargetRef(mr.targetRepository(img.repo), img.tag)
+			if err == nil {
+				p.Target = target.CommonName()
+				err = mr.syncImage(ctx, mirror, src, target, platforms, progress, p)
+				mirror.Close(ctx, src)
+				mirror.Close(ctx, target)
+			}
+		}
+		if err != nil {
+			p.Status = api.ContainerImageMirrorImageStatusFailed
+			p.Error = err.Error()
+			progress.Failed++
+		}
+		p.UpdatedAt = time.Now()
+		mr.saveProgress(progress)
+	}
+	progress.FinishedAt = time.Now()
+	mr.saveProgress(progress)
+	if progress.Failed > 0 || len(progress.Errors) > 0 {
+		return progress, errors.Errorf("%d of %d images failed, %d repositories errors", progress.Failed, progress.Total, len(progress.Errors))
+	}
+	return progress, nil
+}
+
+func (mr *SContainerImageMirror) syncImage(ctx context.Context, mirror *client.Mirror, src, target ref.Ref, platforms []string, progress *api.ContainerImageMirrorProgress, p *api.ContainerImageMirrorImageProgress) error {
+	synced, err := mirror.IsSynced(ctx, src, target)
+	if err != nil {
+		return err
+	}
+	if synced {
+		p.Status = api.ContainerImageMirrorImageStatusSkipped
+		progress.Skipped++
+		return nil
+	}
+	p.Status = api.ContainerImageMirrorImageStatusCopying
+	p.UpdatedAt = time.Now()
+	mr.saveProgress(progress)
+	if err := mirror.Copy(ctx, src, target, platforms); err != nil {
+		return err
+	}
+	p.Status = api.ContainerImageMirrorImageStatusSynced
+	progress.Synced++
+	return nil
+}
+
+// resetStaleSyncing marks mirrors left in syncing as failed, sync task is running in process and
+// never finishes after service restarted, or if its progress hasn't been updated for a long time
+func (m *SContainerImageMirrorManager) resetStaleSyncing(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	q := m.Query().Equals("status", api.ContainerImageMirrorStatusSyncing)
+	if !isStart {
+		q = q.LT("updated_at", time.Now().Add(-ContainerImageMirrorSyncStaleTimeout))
+	}
+	mirrors := make([]SContainerImageMirror, 0)
+	if err := db.FetchModelObjects(m, q, &mirrors); err != nil {
+		log.Errorf("fetch stale syncing container image mirrors: %v", err)
+		return
+	}
+	for i := range mirrors {
+		mr := &mirrors[i]
+		log.Warningf("reset stale syncing container image mirror %s", mr.GetName())
+		mr.SetStatus(ctx, userCred, api.ContainerImageMirrorStatusSyncFail, "stale syncing")
+	}
+}
+
+// StartScheduledSync syncs mirrors whose interval is reached
+func (m *SContainerImageMirrorManager) StartScheduledSync(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	m.resetStaleSyncing(ctx, userCred, isStart)
+	q := m.Query().GT("interval_hours", 0).NotEquals("status", api.ContainerImageMirrorStatusSyncing)
+	mirrors := make([]SContainerImageMirror, 0)
+	if err := db.FetchModelObjects(m, q, &mirrors); err != nil {
+		log.Errorf("StartScheduledSync fetch container image mirrors: %v", err)
+		return
+	}
+	for i := range mirrors {
+		mr := &mirrors[i]
+		if time.Since(mr.LastSyncAt) < time.Duration(mr.IntervalHours)*time.Hour {
+			continue
+		}
+		if err := mr.StartSyncTask(ctx, userCred, ""); err != nil {
+			log.Errorf("start container image mirror %s sync task: %v", mr.GetName(), err)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import "testing"
+
+func TestContainerImageMirrorTargetRepository(t *testing.T) {
+	cases := []struct {
+		namespace string
+		repo      string
+		want      string
+	}{
+		{repo: "kubernetes-dashboard", want: "kubernetes-dashboard"},
+		{repo: "library/nginx", want: "library/nginx"},
+		{namespace: "mirror", repo: "library/nginx", want: "mirror/library/nginx"},
+		{namespace: "mirror", repo: "team-a/nginx", want: "mirror/team-a/nginx"},
+		{namespace: "/mirror/", repo: "coredns", want: "mirror/coredns"},
+	}
+	for _, c := range cases {
+		mr := &SContainerImageMirror{TargetNamespace: c.namespace}
+		if got := mr.targetRepository(c.repo); got != c.want {
+			t.Errorf("namespace %q repo %q: got %q, want %q", c.namespace, c.repo, got, c.want)
+		}
+	}
+}
+
+func TestRegistryUrlPath(t *testing.T) {
+	cases := map[string]string{
+		"https://registry.cn-beijing.aliyuncs.com/yunionio": "/yunionio",
+		"http://192.168.0.1:5000":                           "",
+		"192.168.0.1:5000/library":                          "/library",
+	}
+	for url, want := range cases {
+		if got := registryUrlPath(url); got != want {
+			t.Errorf("%s: got %q, want %q", url, got, want)
+		}
+	}
+}
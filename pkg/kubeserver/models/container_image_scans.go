@@ -0,0 +1,461 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/rbacscope"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/drivers/container_registries/client"
+	"yunion.io/x/kubecomps/pkg/kubeserver/drivers/container_registries/scanner"
+)
+
+const (
+	ContainerImageScanPollInterval = 5 * time.Second
+	ContainerImageScanTimeout      = 30 * time.Minute
+)
+
+var ContainerImageScanManager *SContainerImageScanManager
+
+func init() {
+	ContainerImageScanManager = &SContainerImageScanManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			SContainerImageScan{},
+			"container_image_scans_tbl",
+			"container_image_scan",
+			"container_image_scans",
+		),
+	}
+	ContainerImageScanManager.SetVirtualObject(ContainerImageScanManager)
+}
+
+type SContainerImageScanManager struct {
+	db.SStatusStandaloneResourceBaseManager
+}
+
+// ResourceScope of scans follows container registries, they are checked against registry owner
+func (m *SContainerImageScanManager) ResourceScope() rbacscope.TRbacScope {
+	return rbacscope.ScopeProject
+}
+
+func (m *SContainerImageScanManager) NamespaceScope() rbacscope.TRbacScope {
+	return rbacscope.ScopeProject
+}
+
+// FilterByOwner limits scans to container registries visible to owner
+func (m *SContainerImageScanManager) FilterByOwner(ctx context.Context, q *sqlchemy.SQuery, man db.FilterByOwnerProvider, userCred mcclient.TokenCredential, owner mcclient.IIdentityProvider, scope rbacscope.TRbacScope) *sqlchemy.SQuery {
+	if owner == nil || scope == rbacscope.ScopeSystem {
+		return q
+	}
+	regMan := GetContainerRegistryManager()
+	sq := regMan.Query("id")
+	sq = regMan.FilterByOwner(ctx, sq, regMan, userCred, owner, scope)
+	return q.In("container_registry_id", sq.SubQuery())
+}
+
+// SContainerImageScan is the latest vulnerability scan result of an image tag in container registry
+type SContainerImageScan struct {
+	db.SStatusStandaloneResourceBase
+
+	ContainerRegistryId string `width:"36" charset:"ascii" nullable:"false" list:"user" index:"true"`
+	Repository          string `width:"256" charset:"utf8" nullable:"false" list:"user" index:"true"`
+	Tag                 string `width:"128" charset:"utf8" nullable:"false" list:"user"`
+	// Image is full reference like 'registry.example.com/library/nginx:1.19' used to match running pods
+	Image  string `width:"512" charset:"utf8" nullable:"false" list:"user"`
+	Digest string `width:"128" charset:"ascii" nullable:"true" list:"user" index:"true"`
+
+	ScannerType string `width:"32" charset:"ascii" nullable:"true" list:"user"`
+	ReportId    string `width:"128" charset:"ascii" nullable:"true"`
+	// Severity is highest severity of vulnerabilities
+	Severity        string               `width:"16" charset:"ascii" nullable:"true" list:"user" index:"true"`
+	Summary         jsonutils.JSONObject `nullable:"true" list:"user"`
+	Vulnerabilities jsonutils.JSONObject `nullable:"true"`
+	ScannedAt       time.Time            `nullable:"true" list:"user"`
+}
+
+func (m *SContainerImageScanManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, input *api.ContainerImageScanListInput) (*sqlchemy.SQuery, error) {
+	q, err := m.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, input.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, err
+	}
+	if input.ContainerRegistry != "" {
+		reg, err := fetchContainerRegistry(ctx, userCred, input.ContainerRegistry)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Equals("container_registry_id", reg.GetId())
+	}
+	if input.Repository != "" {
+		q = q.Equals("repository", input.Repository)
+	}
+	if len(input.Severity) != 0 {
+		q = q.In("severity", input.Severity)
+	}
+	return q, nil
+}
+
+func (m *SContainerImageScanManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []*jsonutils.JSONDict {
+	rows := make([]*jsonutils.JSONDict, len(objs))
+	baseRows := m.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range objs {
+		rows[i] = jsonutils.Marshal(baseRows[i]).(*jsonutils.JSONDict)
+		scan := objs[i].(*SContainerImageScan)
+		if reg, err := scan.GetContainerRegistry(); err == nil {
+			rows[i].Add(jsonutils.NewString(reg.GetName()), "container_registry")
+		}
+	}
+	return rows
+}
+
+func (m *SContainerImageScanManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	return nil, httperrors.NewUnsupportOperationError("use container registry scan-image action instead")
+}
+
+func (m *SContainerImageScanManager) fetchByTag(registryId, repository, tag string) (*SContainerImageScan, error) {
+	q := m.Query().Equals("container_registry_id", registryId).Equals("repository", repository).Equals("tag", tag)
+	scans := make([]SContainerImageScan, 0)
+	if err := db.FetchModelObjects(m, q, &scans); err != nil {
+		return nil, err
+	}
+	if len(scans) == 0 {
+		return nil, nil
+	}
+	return &scans[0], nil
+}
+
+// GetRegistryTagSummaries returns vulnerability summaries of scanned tags in repository
+func (m *SContainerImageScanManager) GetRegistryTagSummaries(registryId, repository string) (map[string]*api.ContainerImageVulnerabilitySummary, error) {
+	q := m.Query().Equals("container_registry_id", registryId).Equals("repository", repository).Equals("status", api.ContainerImageScanStatusReady)
+	scans := make([]SContainerImageScan, 0)
+	if err := db.FetchModelObjects(m, q, &scans); err != nil {
+		return nil, err
+	}
+	ret := make(map[string]*api.ContainerImageVulnerabilitySummary)
+	for i := range scans {
+		ret[scans[i].Tag] = scans[i].GetSummary()
+	}
+	return ret, nil
+}
+
+func (s *SContainerImageScan) GetContainerRegistry() (*SContainerRegistry, error) {
+	obj, err := GetContainerRegistryManager().FetchById(s.ContainerRegistryId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch container registry %s", s.ContainerRegistryId)
+	}
+	return obj.(*SContainerRegistry), nil
+}
+
+func (s *SContainerImageScan) GetOwnerId() mcclient.IIdentityProvider {
+	reg, err := s.GetContainerRegistry()
+	if err != nil {
+		return nil
+	}
+	return reg.GetOwnerId()
+}
+
+func (s *SContainerImageScan) IsSharable(reqUsrId mcclient.IIdentityProvider) bool {
+	reg, err := s.GetContainerRegistry()
+	if err != nil {
+		return false
+	}
+	return reg.IsSharable(reqUsrId)
+}
+
+func (s *SContainerImageScan) GetSummary() *api.ContainerImageVulnerabilitySummary {
+	summary := new(api.ContainerImageVulnerabilitySummary)
+	if s.Summary != nil {
+		s.Summary.Unmarshal(summary)
+	}
+	return summary
+}
+
+// GetDetailsReport returns summary along with all vulnerabilities of last scan
+func (s *SContainerImageScan) GetDetailsReport(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ContainerImageVulnerabilityReport, error) {
+	report := &api.ContainerImageVulnerabilityReport{
+		ContainerImageVulnerabilitySummary: *s.GetSummary(),
+		Digest:                             s.Digest,
+		Vulnerabilities:                    make([]api.ContainerImageVulnerability, 0),
+	}
+	if s.Vulnerabilities != nil {
+		if err := s.Vulnerabilities.Unmarshal(&report.Vulnerabilities); err != nil {
+			return nil, errors.Wrap(err, "unmarshal vulnerabilities")
+		}
+	}
+	return report, nil
+}
+
+// PerformRescan scans the image tag again
+func (s *SContainerImageScan) PerformRescan(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if s.GetStatus() == api.ContainerImageScanStatusScanning {
+		return nil, httperrors.NewNotAcceptableError("image %s is scanning", s.Image)
+	}
+	return nil, s.StartScanTask(ctx, userCred, "")
+}
+
+func (s *SContainerImageScan) StartScanTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	s.SetStatus(ctx, userCred, api.ContainerImageScanStatusScanning, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "ContainerImageScanTask", s, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (s *SContainerImageScan) newScanArtifact(reg *SContainerRegistry, digest, mediaType string) (*scanner.Artifact, error) {
+	auth, err := reg.GetAuthConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "get registry auth")
+	}
+	regUrl, err := url.Parse(reg.Url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse registry url %s", reg.Url)
+	}
+	return &scanner.Artifact{
+		RegistryUrl: fmt.Sprintf("%s://%s", regUrl.Scheme, regUrl.Host),
+		Username:    auth.Username,
+		Password:    auth.Password,
+		Repository:  registryRepository(reg.Url, s.Repository),
+		Tag:         s.Tag,
+		Digest:      digest,
+		MimeType:    mediaType,
+	}, nil
+}
+
+// DoScan requests scanning of image, report is fetched by FetchReport afterwards
+func (s *SContainerImageScan) DoScan(ctx context.Context, userCred mcclient.TokenCredential) error {
+	reg, err := s.GetContainerRegistry()
+	if err != nil {
+		return err
+	}
+	scn, err := reg.GetImageScanner(ctx, userCred)
+	if err != nil {
+		return errors.Wrap(err, "get image scanner")
+	}
+	cli, err := reg.GetDockerRegistryClient()
+	if err != nil {
+		return errors.Wrap(err, "GetDockerRegistryClient")
+	}
+	info, err := cli.GetImageTag(ctx, s.Repository, s.Tag)
+	if err != nil {
+		return errors.Wrapf(err, "get image %s", s.Image)
+	}
+	art, err := s.newScanArtifact(reg, info.Digest, info.MediaType)
+	if err != nil {
+		return err
+	}
+	reportId, err := scn.Scan(ctx, art)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Update(s, func() error {
+		s.Digest = info.Digest
+		s.ScannerType = scn.GetType()
+		s.ReportId = reportId
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "update scan report id")
+	}
+	return nil
+}
+
+// FetchReport saves report of requested scan, false is returned when scanner hasn't finished
+func (s *SContainerImageScan) FetchReport(ctx context.Context, userCred mcclient.TokenCredential) (bool, error) {
+	reg, err := s.GetContainerRegistry()
+	if err != nil {
+		return false, err
+	}
+	scn, err := reg.GetImageScanner(ctx, userCred)
+	if err != nil {
+		return false, errors.Wrap(err, "get image scanner")
+	}
+	art, err := s.newScanArtifact(reg, s.Digest, "")
+	if err != nil {
+		return false, err
+	}
+	report, err := scn.GetReport(ctx, art, s.ReportId)
+	if err != nil {
+		if errors.Cause(err) == scanner.ErrReportNotReady {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, s.saveReport(report)
+}
+
+func (s *SContainerImageScan) saveReport(report *api.ContainerImageVulnerabilityReport) error {
+	_, err := db.Update(s, func() error {
+		if report.Digest != "" {
+			s.Digest = report.Digest
+		}
+		s.Severity = report.Severity
+		s.Summary = jsonutils.Marshal(report.ContainerImageVulnerabilitySummary)
+		s.Vulnerabilities = jsonutils.Marshal(report.Vulnerabilities)
+		s.ScannedAt = report.ScannedAt
+		return nil
+	})
+	return err
+}
+
+// registryRepository returns full repository path inside registry, path of registry url is prepended
+func registryRepository(regUrl, repo string) string {
+	prefix := strings.Trim(registryUrlPath(regUrl), "/")
+	if prefix == "" {
+		return repo
+	}
+	parts := strings.Split(repo, "/")
+	return fmt.Sprintf("%s/%s", prefix, parts[len(parts)-1])
+}
+
+func (r *SContainerRegistry) GetScannerConfig(ctx context.Context, userCred mcclient.TokenCredential) (*api.ContainerRegistryScannerConfig, error) {
+	// token of scanner is only visible to system admin
+	obj := r.GetMetadataJson(ctx, api.ContainerRegistryMetadataScanner, GetAdminCred())
+	if obj == nil {
+		return nil, nil
+	}
+	conf := new(api.ContainerRegistryScannerConfig)
+	if err := obj.Unmarshal(conf); err != nil {
+		return nil, errors.Wrap(err, "unmarshal scanner config")
+	}
+	return conf, nil
+}
+
+func (r *SContainerRegistry) GetDetailsScanner(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ContainerRegistryScannerConfig, error) {
+	conf, err := r.GetScannerConfig(ctx, userCred)
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		return new(api.ContainerRegistryScannerConfig), nil
+	}
+	conf.Token = ""
+	return conf, nil
+}
+
+// PerformSetScanner sets scanner adapter of registry, empty type falls back to builtin scanner of registry
+func (r *SContainerRegistry) PerformSetScanner(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ContainerRegistryScannerConfig) (jsonutils.JSONObject, error) {
+	switch input.Type {
+	case "":
+		return nil, r.SetMetadata(ctx, api.ContainerRegistryMetadataScanner, "", userCred)
+	case api.ContainerImageScannerTypeAdapter:
+		if input.Url == "" {
+			return nil, httperrors.NewNotEmptyError("url")
+		}
+		if _, err := url.Parse(input.Url); err != nil {
+			return nil, httperrors.NewInputParameterError("invalid url %q: %v", input.Url, err)
+		}
+	default:
+		return nil, httperrors.NewInputParameterError("unsupported scanner type %q", input.Type)
+	}
+	return nil, r.SetMetadata(ctx, api.ContainerRegistryMetadataScanner, jsonutils.Marshal(input).String(), userCred)
+}
+
+// GetImageScanner returns configured scanner adapter, or builtin scanner of registry driver
+func (r *SContainerRegistry) GetImageScanner(ctx context.Context, userCred mcclient.TokenCredential) (scanner.Scanner, error) {
+	conf, err := r.GetScannerConfig(ctx, userCred)
+	if err != nil {
+		return nil, err
+	}
+	if conf != nil && conf.Type == api.ContainerImageScannerTypeAdapter {
+		return scanner.NewAdapterScanner(conf)
+	}
+	regConf, err := r.GetConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "get config")
+	}
+	return r.GetDriver().GetImageScanner(r.Url, regConf)
+}
+
+// PerformScanImage scans vulnerabilities of image tag, result is kept as container_image_scan
+func (r *SContainerRegistry) PerformScanImage(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ContainerRegistryScanImageInput) (jsonutils.JSONObject, error) {
+	if input.Repository == "" {
+		return nil, httperrors.NewNotEmptyError("repository is empty")
+	}
+	if input.Tag == "" {
+		return nil, httperrors.NewNotEmptyError("tag is empty")
+	}
+	if _, err := r.GetImageScanner(ctx, userCred); err != nil {
+		return nil, err
+	}
+	scan, err := ContainerImageScanManager.fetchByTag(r.GetId(), input.Repository, input.Tag)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch image scan")
+	}
+	if scan == nil {
+		scan, err = r.newImageScan(ctx, userCred, input.Repository, input.Tag)
+		if err != nil {
+			return nil, err
+		}
+	} else if scan.GetStatus() == api.ContainerImageScanStatusScanning {
+		return nil, httperrors.NewNotAcceptableError("image %s is scanning", scan.Image)
+	}
+	if err := scan.StartScanTask(ctx, userCred, ""); err != nil {
+		return nil, err
+	}
+	return jsonutils.Marshal(scan), nil
+}
+
+func (r *SContainerRegistry) newImageScan(ctx context.Context, userCred mcclient.TokenCredential, repository, tag string) (*SContainerImageScan, error) {
+	regUrl, err := url.Parse(r.Url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse registry url %s", r.Url)
+	}
+	scan := &SContainerImageScan{
+		ContainerRegistryId: r.GetId(),
+		Repository:          repository,
+		Tag:                 tag,
+		Image:               fmt.Sprintf("%s/%s:%s", regUrl.Host, registryRepository(r.Url, repository), tag),
+	}
+	scan.SetModelManager(ContainerImageScanManager, scan)
+	scan.Name = fmt.Sprintf("%s:%s", repository, tag)
+	scan.Status = api.ContainerImageScanStatusScanning
+	if err := ContainerImageScanManager.TableSpec().Insert(ctx, scan); err != nil {
+		return nil, errors.Wrap(err, "insert image scan")
+	}
+	return scan, nil
+}
+
+// attachTagVulnerabilities adds vulnerability summaries of scanned tags to image tags details
+func (r *SContainerRegistry) attachTagVulnerabilities(repository string, tagsObj jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if tagsObj == nil {
+		// registry doesn't support listing tags
+		return nil, nil
+	}
+	result := new(client.ImageTagResult)
+	if err := tagsObj.Unmarshal(result); err != nil {
+		return nil, errors.Wrap(err, "unmarshal image tags")
+	}
+	summaries, err := ContainerImageScanManager.GetRegistryTagSummaries(r.GetId(), repository)
+	if err != nil {
+		return nil, errors.Wrap(err, "get vulnerability summaries")
+	}
+	ret := jsonutils.Marshal(result).(*jsonutils.JSONDict)
+	ret.Add(jsonutils.Marshal(summaries), "vulnerabilities")
+	return ret, nil
+}
+
+func (s *SContainerImageScan) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	if s.GetStatus() == api.ContainerImageScanStatusScanning {
+		return httperrors.NewNotAcceptableError("image %s is scanning", s.Image)
+	}
+	return s.SStatusStandaloneResourceBase.ValidateDeleteCondition(ctx, nil)
+}
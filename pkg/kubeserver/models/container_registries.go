@@ -63,6 +63,8 @@ type SContainerRegistry struct {
 	Type         string               `charset:"ascii" width:"128" create:"required" nullable:"true" list:"user"`
 	CredentialId string               `width:"256" charset:"ascii" nullable:"true" create:"optional" list:"user"`
 	Config       jsonutils.JSONObject `nullable:"true" create:"optional"`
+	// InsecureSkipVerify skips verifying certificate of registry when mirroring images
+	InsecureSkipVerify bool `nullable:"false" default:"false" create:"optional" update:"user" list:"user"`
 }
 
 func (man *SContainerRegistryManager) AddDispatcher(prefix string, app *appsrv.Application, manager dispatcher.IModelDispatchHandler) {
@@ -227,6 +229,42 @@ func (r *SContainerRegistry) getConfigByCredential() (*api.ContainerRegistryConf
 	return conf, nil
 }
 
+// GetAuthConfig returns username and password used to access registry
+func (r *SContainerRegistry) GetAuthConfig() (*client.DockerAuthConfig, error) {
+	conf, err := r.GetConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "get config")
+	}
+	var common *api.ContainerRegistryConfigCommon
+	switch {
+	case conf.Common != nil:
+		common = conf.Common
+	case conf.Harbor != nil:
+		common = &conf.Harbor.ContainerRegistryConfigCommon
+	case conf.Custom != nil:
+		common = &conf.Custom.ContainerRegistryConfigCommon
+	}
+	ret := new(client.DockerAuthConfig)
+	if common != nil {
+		ret.Username = common.Username
+		ret.Password = common.Password
+	}
+	return ret, nil
+}
+
+// GetMirrorRegistry returns url, credential and tls option of registry used by image mirror
+func (r *SContainerRegistry) GetMirrorRegistry() (*client.MirrorRegistry, error) {
+	auth, err := r.GetAuthConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "get auth config")
+	}
+	return &client.MirrorRegistry{
+		Url:                r.Url,
+		Auth:               *auth,
+		InsecureSkipVerify: r.InsecureSkipVerify,
+	}, nil
+}
+
 func (r *SContainerRegistry) GetType() api.ContainerRegistryType {
 	return api.ContainerRegistryType(r.Type)
 }
@@ -281,7 +319,11 @@ func (r *SContainerRegistry) GetDetailsImageTags(ctx context.Context, userCred m
 	if err != nil {
 		return nil, errors.Wrap(err, "GetDockerRegistryClient")
 	}
-	return rgCli.ListImageTags(ctx, query.Repository)
+	tags, err := rgCli.ListImageTags(ctx, query.Repository)
+	if err != nil {
+		return nil, err
+	}
+	return r.attachTagVulnerabilities(query.Repository, tags)
 }
 
 func (r *SContainerRegistry) GetDetailsConfig(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ContainerRegistryConfig, error) {
@@ -11,6 +11,7 @@ import (
 	"yunion.io/x/kubecomps/pkg/kubeserver/api"
 	"yunion.io/x/kubecomps/pkg/kubeserver/drivers"
 	"yunion.io/x/kubecomps/pkg/kubeserver/drivers/container_registries/client"
+	"yunion.io/x/kubecomps/pkg/kubeserver/drivers/container_registries/scanner"
 )
 
 var containerRegistryDrivers *drivers.DriverManager
@@ -29,6 +30,10 @@ type IContainerRegistryDriver interface {
 
 	PreparePushImage(ctx context.Context, url string, conf *api.ContainerRegistryConfig, meta *client.ImageMetadata) error
 	DownloadImage(ctx context.Context, url string, conf *api.ContainerRegistryConfig, input api.ContainerRegistryDownloadImageInput) (string, error)
+	// GarbageCollect reclaims blobs no longer referenced by manifests
+	GarbageCollect(ctx context.Context, url string, conf *api.ContainerRegistryConfig) error
+	// GetImageScanner returns builtin vulnerability scanner of registry, used when scanner isn't configured
+	GetImageScanner(url string, conf *api.ContainerRegistryConfig) (scanner.Scanner, error)
 }
 
 func RegisterContainerRegistryDriver(driver IContainerRegistryDriver) {
@@ -0,0 +1,305 @@
+package models
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/drivers/container_registries/client"
+)
+
+// PerformDeleteImageTag removes a tag, or a manifest with all its tags when digest is provided
+func (r *SContainerRegistry) PerformDeleteImageTag(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ContainerRegistryDeleteImageTagInput) (jsonutils.JSONObject, error) {
+	if input.Repository == "" {
+		return nil, httperrors.NewNotEmptyError("repository is empty")
+	}
+	if input.Tag == "" && input.Digest == "" {
+		return nil, httperrors.NewNotEmptyError("tag or digest is required")
+	}
+	cli, err := r.GetDockerRegistryClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetDockerRegistryClient")
+	}
+	if input.Digest != "" {
+		err = cli.DeleteImageManifest(ctx, input.Repository, input.Digest)
+	} else {
+		err = cli.DeleteImageTag(ctx, input.Repository, input.Tag)
+	}
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return nil, nil
+}
+
+// PerformGarbageCollect triggers registry garbage collection
+func (r *SContainerRegistry) PerformGarbageCollect(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	conf, err := r.GetConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "get config")
+	}
+	return nil, r.GetDriver().GarbageCollect(ctx, r.Url, conf)
+}
+
+// GetRetentionPolicy returns nil when retention policy is not set
+func (r *SContainerRegistry) GetRetentionPolicy(ctx context.Context, userCred mcclient.TokenCredential) (*api.ContainerRegistryRetentionPolicy, error) {
+	obj := r.GetMetadataJson(ctx, api.ContainerRegistryMetadataRetentionPolicy, userCred)
+	if obj == nil {
+		return nil, nil
+	}
+	policy := new(api.ContainerRegistryRetentionPolicy)
+	if err := obj.Unmarshal(policy); err != nil {
+		return nil, errors.Wrap(err, "unmarshal retention policy")
+	}
+	return policy, nil
+}
+
+func (r *SContainerRegistry) GetDetailsRetentionPolicy(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ContainerRegistryRetentionPolicy, error) {
+	policy, err := r.GetRetentionPolicy(ctx, userCred)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, httperrors.NewNotFoundError("container registry %s retention policy is not set", r.GetName())
+	}
+	return policy, nil
+}
+
+func (r *SContainerRegistry) PerformSetRetentionPolicy(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ContainerRegistryRetentionPolicy) (jsonutils.JSONObject, error) {
+	if input.IntervalHours < 0 || input.KeepLast < 0 || input.MaxAgeDays < 0 {
+		return nil, httperrors.NewInputParameterError("interval_hours, keep_last and max_age_days must not be negative")
+	}
+	if input.IntervalHours == 0 {
+		input.IntervalHours = api.DefaultContainerRegistryRetentionIntervalHours
+	}
+	for _, pattern := range []string{input.RepositoryPattern, input.KeepTagPattern} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, httperrors.NewInputParameterError("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	return nil, r.SetMetadata(ctx, api.ContainerRegistryMetadataRetentionPolicy, jsonutils.Marshal(input).String(), userCred)
+}
+
+// GetDetailsRetentionReport returns report of last applied retention
+func (r *SContainerRegistry) GetDetailsRetentionReport(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ContainerRegistryRetentionReport, error) {
+	obj := r.GetMetadataJson(ctx, api.ContainerRegistryMetadataRetentionLastReport, userCred)
+	if obj == nil {
+		return nil, httperrors.NewNotFoundError("container registry %s retention is never applied", r.GetName())
+	}
+	report := new(api.ContainerRegistryRetentionReport)
+	if err := obj.Unmarshal(report); err != nil {
+		return nil, errors.Wrap(err, "unmarshal retention report")
+	}
+	return report, nil
+}
+
+// PerformApplyRetention removes tags by retention policy, dry run only reports what would be removed
+func (r *SContainerRegistry) PerformApplyRetention(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ContainerRegistryApplyRetentionInput) (*api.ContainerRegistryRetentionReport, error) {
+	policy, err := r.GetRetentionPolicy(ctx, userCred)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, httperrors.NewNotFoundError("container registry %s retention policy is not set", r.GetName())
+	}
+	return r.ApplyRetention(ctx, userCred, policy, input.DryRun)
+}
+
+// selectRetentionTags returns tags kept by none of keep_last, keep_tag_pattern and max_age_days
+func selectRetentionTags(tags []client.ImageTagInfo, keepLast int, keepTag *regexp.Regexp, maxAgeDays int, now time.Time) []client.ImageTagInfo {
+	ret := make([]client.ImageTagInfo, 0)
+	if keepLast <= 0 && maxAgeDays <= 0 {
+		return ret
+	}
+	sorted := make([]client.ImageTagInfo, len(tags))
+	copy(sorted, tags)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Created.After(sorted[j].Created)
+	})
+	for i, t := range sorted {
+		if i < keepLast {
+			continue
+		}
+		if keepTag != nil && keepTag.MatchString(t.Tag) {
+			continue
+		}
+		if maxAgeDays > 0 {
+			// tag without creation time is never expired
+			if t.Created.IsZero() || now.Sub(t.Created) < time.Duration(maxAgeDays)*24*time.Hour {
+				continue
+			}
+		}
+		ret = append(ret, t)
+	}
+	return ret
+}
+
+func compileRetentionPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+// applyRepositoryRetention removes expired tags of repository, manifest is removed as a whole
+// when all its tags are expired, otherwise only expired tags are removed
+func applyRepositoryRetention(ctx context.Context, cli client.Client, repo string, policy *api.ContainerRegistryRetentionPolicy, keepTag *regexp.Regexp, report *api.ContainerRegistryRetentionReport) error {
+	tagsObj, err := cli.ListImageTags(ctx, repo)
+	if err != nil {
+		return errors.Wrap(err, "list tags")
+	}
+	if tagsObj == nil {
+		return errors.Wrap(errors.ErrNotSupported, "list tags")
+	}
+	tagsResult := new(client.ImageTagResult)
+	if err := tagsObj.Unmarshal(tagsResult); err != nil {
+		return errors.Wrap(err, "unmarshal tags")
+	}
+	tags := make([]client.ImageTagInfo, 0, len(tagsResult.Tags))
+	tagsOfDigest := make(map[string]int)
+	for _, tag := range tagsResult.Tags {
+		info, err := cli.GetImageTag(ctx, repo, tag)
+		if err != nil {
+			// policy can't be evaluated without creation time of all tags
+			return errors.Wrapf(err, "get tag %s", tag)
+		}
+		tags = append(tags, *info)
+		tagsOfDigest[info.Digest]++
+	}
+	removed := selectRetentionTags(tags, policy.KeepLast, keepTag, policy.MaxAgeDays, time.Now())
+	report.Kept += len(tags) - len(removed)
+
+	removedOfDigest := make(map[string]int)
+	for _, t := range removed {
+		removedOfDigest[t.Digest]++
+	}
+	// result of manifest deletion is shared by all tags of the digest
+	deletedDigests := make(map[string]error)
+	for _, t := range removed {
+		item := api.ContainerRegistryRetentionItem{
+			Repository:      repo,
+			Tag:             t.Tag,
+			Digest:          t.Digest,
+			Created:         t.Created,
+			ManifestRemoved: removedOfDigest[t.Digest] == tagsOfDigest[t.Digest],
+		}
+		if !report.DryRun {
+			var err error
+			if item.ManifestRemoved {
+				var attempted bool
+				err, attempted = deletedDigests[t.Digest]
+				if !attempted {
+					err = cli.DeleteImageManifest(ctx, repo, t.Digest)
+					deletedDigests[t.Digest] = err
+				}
+			} else {
+				err = cli.DeleteImageTag(ctx, repo, t.Tag)
+			}
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+		}
+		report.Removed = append(report.Removed, item)
+	}
+	return nil
+}
+
+// ApplyRetention evaluates retention policy against all matched repositories
+func (r *SContainerRegistry) ApplyRetention(ctx context.Context, userCred mcclient.TokenCredential, policy *api.ContainerRegistryRetentionPolicy, dryRun bool) (*api.ContainerRegistryRetentionReport, error) {
+	repoPattern, err := compileRetentionPattern(policy.RepositoryPattern)
+	if err != nil {
+		return nil, httperrors.NewInputParameterError("invalid repository_pattern: %v", err)
+	}
+	keepTag, err := compileRetentionPattern(policy.KeepTagPattern)
+	if err != nil {
+		return nil, httperrors.NewInputParameterError("invalid keep_tag_pattern: %v", err)
+	}
+	cli, err := r.GetDockerRegistryClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetDockerRegistryClient")
+	}
+	imagesObj, err := cli.ListImages(ctx, &api.ContainerRegistryListImagesInput{})
+	if err != nil {
+		return nil, errors.Wrap(err, "list images")
+	}
+	if imagesObj == nil {
+		return nil, httperrors.NewNotSupportedError("registry type %s does not support listing images", r.Type)
+	}
+	catalog := new(client.CatalogResult)
+	if err := imagesObj.Unmarshal(catalog); err != nil {
+		return nil, errors.Wrap(err, "unmarshal images")
+	}
+
+	report := &api.ContainerRegistryRetentionReport{
+		DryRun:    dryRun,
+		StartedAt: time.Now(),
+		Removed:   make([]api.ContainerRegistryRetentionItem, 0),
+		Errors:    make([]string, 0),
+	}
+	for _, repo := range catalog.Repositories {
+		if repoPattern != nil && !repoPattern.MatchString(repo) {
+			continue
+		}
+		if err := applyRepositoryRetention(ctx, cli, repo, policy, keepTag, report); err != nil {
+			report.Errors = append(report.Errors, errors.Wrapf(err, "repository %s", repo).Error())
+		}
+	}
+	if dryRun {
+		return report, nil
+	}
+	if policy.GarbageCollect && len(report.Removed) > 0 {
+		conf, err := r.GetConfig()
+		if err == nil {
+			err = r.GetDriver().GarbageCollect(ctx, r.Url, conf)
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, errors.Wrap(err, "garbage collect").Error())
+		} else {
+			report.GarbageCollected = true
+		}
+	}
+	if err := r.SetMetadata(ctx, api.ContainerRegistryMetadataRetentionLastReport, jsonutils.Marshal(report).String(), userCred); err != nil {
+		return nil, errors.Wrap(err, "save retention report")
+	}
+	return report, nil
+}
+
+// StartScheduledRetention applies retention policies of registries whose interval is reached
+func (m *SContainerRegistryManager) StartScheduledRetention(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	regs := make([]SContainerRegistry, 0)
+	if err := db.FetchModelObjects(m, m.Query(), &regs); err != nil {
+		log.Errorf("StartScheduledRetention fetch container registries: %v", err)
+		return
+	}
+	for idx := range regs {
+		r := &regs[idx]
+		policy, err := r.GetRetentionPolicy(ctx, userCred)
+		if err != nil {
+			log.Errorf("get container registry %s retention policy: %v", r.GetName(), err)
+			continue
+		}
+		if policy == nil || !policy.Enabled {
+			continue
+		}
+		if last, err := r.GetDetailsRetentionReport(ctx, userCred, nil); err == nil {
+			if time.Since(last.StartedAt) < time.Duration(policy.IntervalHours)*time.Hour {
+				continue
+			}
+		}
+		report, err := r.ApplyRetention(ctx, userCred, policy, false)
+		if err != nil {
+			log.Errorf("apply container registry %s retention: %v", r.GetName(), err)
+			continue
+		}
+		log.Infof("container registry %s retention removed %d tags, errors: %v", r.GetName(), len(report.Removed), report.Errors)
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/drivers/container_registries/client"
+)
+
+func TestSelectRetentionTags(t *testing.T) {
+	now := time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC)
+	// one tag each day, out of order, v4 is newest
+	tags := []client.ImageTagInfo{
+		{Tag: "v2", Created: now.Add(-2*24*time.Hour - time.Hour)},
+		{Tag: "v4", Created: now.Add(-time.Hour)},
+		{Tag: "latest", Created: now.Add(-4*24*time.Hour - time.Hour)},
+		{Tag: "v3", Created: now.Add(-1*24*time.Hour - time.Hour)},
+		{Tag: "v1", Created: now.Add(-3*24*time.Hour - time.Hour)},
+		{Tag: "unknown"},
+	}
+	keepLatest := regexp.MustCompile(`^latest$`)
+	cases := []struct {
+		keepLast   int
+		keepTag    *regexp.Regexp
+		maxAgeDays int
+		want       []string
+	}{
+		{keepLast: 3, want: []string{"v1", "latest", "unknown"}},
+		{keepLast: 3, keepTag: keepLatest, want: []string{"v1", "unknown"}},
+		{maxAgeDays: 2, want: []string{"v2", "v1", "latest"}},
+		{keepLast: 3, maxAgeDays: 2, keepTag: keepLatest, want: []string{"v1"}},
+		{keepLast: 10, want: []string{}},
+		{keepTag: keepLatest, want: []string{}},
+	}
+	for _, c := range cases {
+		got := selectRetentionTags(tags, c.keepLast, c.keepTag, c.maxAgeDays, now)
+		names := make([]string, len(got))
+		for i := range got {
+			names[i] = got[i].Tag
+		}
+		if fmt.Sprint(names) != fmt.Sprint(c.want) {
+			t.Errorf("keep last %d, tag %v, max age %d: got %v, want %v", c.keepLast, c.keepTag, c.maxAgeDays, names, c.want)
+		}
+	}
+}
@@ -0,0 +1,493 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiservervalidation "k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/jsonpath"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/client"
+)
+
+var crdScheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(apiextensions.AddToScheme(crdScheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(crdScheme))
+	utilruntime.Must(apiextensionsv1beta1.AddToScheme(crdScheme))
+}
+
+// customResourceDefaultColumns are printed like kube-apiserver when crd doesn't define printer columns
+var customResourceDefaultColumns = []apiextensionsv1.CustomResourceColumnDefinition{
+	{
+		Name:        "Age",
+		Type:        "date",
+		Description: "CreationTimestamp is a timestamp representing the server time when this object was created",
+		JSONPath:    ".metadata.creationTimestamp",
+	},
+}
+
+func getDynamicClient(cli *client.ClusterManager) (dynamic.Interface, error) {
+	dCli, err := cli.ClientV2.K8S().DynamicClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get dynamic client")
+	}
+	return dCli, nil
+}
+
+// getCRDResource returns apiextensions.k8s.io/v1 crd resource, v1beta1 is used by clusters older than 1.16
+func getCRDResource(cli *client.ClusterManager) schema.GroupVersionResource {
+	gvr := apiextensionsv1.SchemeGroupVersion.WithResource("customresourcedefinitions")
+	dc := cli.GetHandler().GetClientset().Discovery()
+	if _, err := dc.ServerResourcesForGroupVersion(gvr.GroupVersion().String()); err != nil {
+		return apiextensionsv1beta1.SchemeGroupVersion.WithResource("customresourcedefinitions")
+	}
+	return gvr
+}
+
+// toCustomResourceDefinition converts v1 or v1beta1 unstructured crd to v1 object
+func toCustomResourceDefinition(obj *unstructured.Unstructured) (*apiextensionsv1.CustomResourceDefinition, error) {
+	ret := new(apiextensionsv1.CustomResourceDefinition)
+	if obj.GetAPIVersion() != apiextensionsv1beta1.SchemeGroupVersion.String() {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, ret); err != nil {
+			return nil, errors.Wrap(err, "convert from unstructured")
+		}
+		return ret, nil
+	}
+	betaObj := new(apiextensionsv1beta1.CustomResourceDefinition)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, betaObj); err != nil {
+		return nil, errors.Wrap(err, "convert v1beta1 from unstructured")
+	}
+	internalObj := new(apiextensions.CustomResourceDefinition)
+	if err := crdScheme.Convert(betaObj, internalObj, nil); err != nil {
+		return nil, errors.Wrap(err, "convert v1beta1 to internal")
+	}
+	if err := crdScheme.Convert(internalObj, ret, nil); err != nil {
+		return nil, errors.Wrap(err, "convert internal to v1")
+	}
+	return ret, nil
+}
+
+func listCustomResourceDefinitions(ctx context.Context, cli *client.ClusterManager) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	dCli, err := getDynamicClient(cli)
+	if err != nil {
+		return nil, err
+	}
+	list, err := dCli.Resource(getCRDResource(cli)).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "list customresourcedefinitions")
+	}
+	ret := make([]*apiextensionsv1.CustomResourceDefinition, 0, len(list.Items))
+	for i := range list.Items {
+		crd, err := toCustomResourceDefinition(&list.Items[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "convert crd %s", list.Items[i].GetName())
+		}
+		ret = append(ret, crd)
+	}
+	return ret, nil
+}
+
+func getCustomResourceDefinition(ctx context.Context, cli *client.ClusterManager, name string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	if name == "" {
+		return nil, httperrors.NewNotEmptyError("crd name is empty")
+	}
+	dCli, err := getDynamicClient(cli)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := dCli.Resource(getCRDResource(cli)).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, httperrors.NewNotFoundError("customresourcedefinition %s not found", name)
+		}
+		return nil, errors.Wrapf(err, "get customresourcedefinition %s", name)
+	}
+	return toCustomResourceDefinition(obj)
+}
+
+func toAPICustomResourceDefinition(crd *apiextensionsv1.CustomResourceDefinition, withSchema bool) api.CustomResourceDefinition {
+	ret := api.CustomResourceDefinition{
+		Name:       crd.GetName(),
+		Group:      crd.Spec.Group,
+		Kind:       crd.Spec.Names.Kind,
+		Plural:     crd.Spec.Names.Plural,
+		Singular:   crd.Spec.Names.Singular,
+		ShortNames: crd.Spec.Names.ShortNames,
+		Categories: crd.Spec.Names.Categories,
+		Scope:      string(crd.Spec.Scope),
+		Versions:   make([]api.CustomResourceDefinitionVersion, 0, len(crd.Spec.Versions)),
+	}
+	for _, cond := range crd.Status.Conditions {
+		if cond.Type == apiextensionsv1.Established {
+			ret.Established = cond.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+	for _, v := range crd.Spec.Versions {
+		ver := api.CustomResourceDefinitionVersion{
+			Name:                     v.Name,
+			Served:                   v.Served,
+			Storage:                  v.Storage,
+			Deprecated:               v.Deprecated,
+			AdditionalPrinterColumns: getCustomResourceColumns(crd, v.Name),
+		}
+		if withSchema && v.Schema != nil {
+			ver.Schema = v.Schema.OpenAPIV3Schema
+		}
+		ret.Versions = append(ret.Versions, ver)
+	}
+	return ret
+}
+
+func getCustomResourceDefinitionVersion(crd *apiextensionsv1.CustomResourceDefinition, version string) (*apiextensionsv1.CustomResourceDefinitionVersion, error) {
+	for i := range crd.Spec.Versions {
+		v := &crd.Spec.Versions[i]
+		if (version == "" && v.Storage) || (version != "" && v.Name == version) {
+			if !v.Served {
+				return nil, httperrors.NewNotSupportedError("version %s of crd %s isn't served", v.Name, crd.GetName())
+			}
+			return v, nil
+		}
+	}
+	return nil, httperrors.NewNotFoundError("version %q of crd %s not found", version, crd.GetName())
+}
+
+func getCustomResourceColumns(crd *apiextensionsv1.CustomResourceDefinition, version string) []apiextensionsv1.CustomResourceColumnDefinition {
+	for _, v := range crd.Spec.Versions {
+		if v.Name == version && len(v.AdditionalPrinterColumns) != 0 {
+			return v.AdditionalPrinterColumns
+		}
+	}
+	return customResourceDefaultColumns
+}
+
+// getCustomResourceCells evaluates jsonPath of columns, value of missing path is nil
+func getCustomResourceCells(obj *unstructured.Unstructured, columns []apiextensionsv1.CustomResourceColumnDefinition) []interface{} {
+	cells := make([]interface{}, len(columns))
+	for i, col := range columns {
+		jp := jsonpath.New(col.Name).AllowMissingKeys(true)
+		if err := jp.Parse(fmt.Sprintf("{%s}", col.JSONPath)); err != nil {
+			log.Warningf("Parse column %s jsonPath %q error: %v", col.Name, col.JSONPath, err)
+			continue
+		}
+		results, err := jp.FindResults(obj.Object)
+		if err != nil || len(results) == 0 || len(results[0]) == 0 {
+			continue
+		}
+		cells[i] = results[0][0].Interface()
+	}
+	return cells
+}
+
+// validateCustomResource validates object against openAPIV3Schema of crd version like kube-apiserver does,
+// kubernetes extensions like nullable and x-kubernetes-int-or-string are respected
+func validateCustomResource(ver *apiextensionsv1.CustomResourceDefinitionVersion, obj *unstructured.Unstructured) error {
+	if ver.Schema == nil || ver.Schema.OpenAPIV3Schema == nil {
+		return nil
+	}
+	internal := new(apiextensions.CustomResourceValidation)
+	if err := apiextensionsv1.Convert_v1_CustomResourceValidation_To_apiextensions_CustomResourceValidation(ver.Schema, internal, nil); err != nil {
+		return errors.Wrap(err, "convert openAPIV3Schema")
+	}
+	validator, _, err := apiservervalidation.NewSchemaValidator(internal)
+	if err != nil {
+		return errors.Wrap(err, "new schema validator")
+	}
+	if errs := apiservervalidation.ValidateCustomResource(nil, obj.UnstructuredContent(), validator); len(errs) != 0 {
+		return httperrors.NewInputParameterError("invalid %s: %v", obj.GetKind(), errs.ToAggregate())
+	}
+	return nil
+}
+
+type customResourceClient struct {
+	crd     *apiextensionsv1.CustomResourceDefinition
+	version *apiextensionsv1.CustomResourceDefinitionVersion
+	res     dynamic.NamespaceableResourceInterface
+}
+
+func newCustomResourceClient(ctx context.Context, cli *client.ClusterManager, input *api.ClusterCustomResourceInput) (*customResourceClient, error) {
+	crd, err := getCustomResourceDefinition(ctx, cli, input.Crd)
+	if err != nil {
+		return nil, err
+	}
+	ver, err := getCustomResourceDefinitionVersion(crd, input.Version)
+	if err != nil {
+		return nil, err
+	}
+	input.Version = ver.Name
+	if crd.Spec.Scope == apiextensionsv1.ClusterScoped {
+		input.Namespace = ""
+	}
+	dCli, err := getDynamicClient(cli)
+	if err != nil {
+		return nil, err
+	}
+	return &customResourceClient{
+		crd:     crd,
+		version: ver,
+		res: dCli.Resource(schema.GroupVersionResource{
+			Group:    crd.Spec.Group,
+			Version:  ver.Name,
+			Resource: crd.Spec.Names.Plural,
+		}),
+	}, nil
+}
+
+func (c *customResourceClient) apiVersion() string {
+	return schema.GroupVersion{Group: c.crd.Spec.Group, Version: c.version.Name}.String()
+}
+
+func (c *customResourceClient) isNamespaced() bool {
+	return c.crd.Spec.Scope == apiextensionsv1.NamespaceScoped
+}
+
+func (c *customResourceClient) resource(namespace string) dynamic.ResourceInterface {
+	if c.isNamespaced() {
+		return c.res.Namespace(namespace)
+	}
+	return c.res
+}
+
+// newObject builds unstructured object from input and validates it against crd schema
+func (c *customResourceClient) newObject(input *api.ClusterCustomResourceCreateInput) (*unstructured.Unstructured, error) {
+	if input.Object == nil {
+		return nil, httperrors.NewNotEmptyError("object is empty")
+	}
+	if c.isNamespaced() && input.Namespace == "" {
+		return nil, httperrors.NewNotEmptyError("namespace is empty")
+	}
+	obj := new(unstructured.Unstructured)
+	if err := json.Unmarshal([]byte(input.Object.String()), &obj.Object); err != nil {
+		return nil, httperrors.NewInputParameterError("invalid object: %v", err)
+	}
+	obj.SetAPIVersion(c.apiVersion())
+	obj.SetKind(c.crd.Spec.Names.Kind)
+	if input.Name != "" {
+		obj.SetName(input.Name)
+	}
+	if obj.GetName() == "" && obj.GetGenerateName() == "" {
+		return nil, httperrors.NewNotEmptyError("name is empty")
+	}
+	obj.SetNamespace(input.Namespace)
+	if err := validateCustomResource(c.version, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (c *customResourceClient) getObject(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	if name == "" {
+		return nil, httperrors.NewNotEmptyError("name is empty")
+	}
+	obj, err := c.resource(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, httperrors.NewNotFoundError("%s %s not found", c.crd.Spec.Names.Kind, name)
+		}
+		return nil, errors.Wrapf(err, "get %s %s", c.crd.Spec.Names.Kind, name)
+	}
+	return obj, nil
+}
+
+// GetDetailsCustomResourceDefinitions lists crds with versions and printer columns
+func (c *SCluster) GetDetailsCustomResourceDefinitions(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) ([]api.CustomResourceDefinition, error) {
+	cli, err := c.GetRemoteClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get remote kubernetes client")
+	}
+	crds, err := listCustomResourceDefinitions(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]api.CustomResourceDefinition, 0, len(crds))
+	for _, crd := range crds {
+		ret = append(ret, toAPICustomResourceDefinition(crd, false))
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret, nil
+}
+
+// GetDetailsCustomResourceDefinition gets crd with openAPIV3Schema of versions
+func (c *SCluster) GetDetailsCustomResourceDefinition(ctx context.Context, userCred mcclient.TokenCredential, query *api.ClusterCustomResourceDefinitionInput) (*api.CustomResourceDefinition, error) {
+	cli, err := c.GetRemoteClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get remote kubernetes client")
+	}
+	crd, err := getCustomResourceDefinition(ctx, cli, query.Name)
+	if err != nil {
+		return nil, err
+	}
+	ret := toAPICustomResourceDefinition(crd, true)
+	return &ret, nil
+}
+
+// GetDetailsCustomResources lists custom resources with printer columns of crd,
+// resources of all namespaces are listed when namespace is empty
+func (c *SCluster) GetDetailsCustomResources(ctx context.Context, userCred mcclient.TokenCredential, query *api.ClusterCustomResourceListInput) (*api.CustomResourceList, error) {
+	cli, err := c.GetRemoteClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get remote kubernetes client")
+	}
+	crCli, err := newCustomResourceClient(ctx, cli, &query.ClusterCustomResourceInput)
+	if err != nil {
+		return nil, err
+	}
+	list, err := crCli.resource(query.Namespace).List(ctx, metav1.ListOptions{LabelSelector: query.LabelSelector})
+	if err != nil {
+		if kerrors.IsBadRequest(err) {
+			return nil, httperrors.NewInputParameterError("%v", err)
+		}
+		return nil, errors.Wrapf(err, "list %s", crCli.crd.Spec.Names.Plural)
+	}
+	columns := getCustomResourceColumns(crCli.crd, crCli.version.Name)
+	ret := &api.CustomResourceList{
+		Kind:       crCli.crd.Spec.Names.Kind,
+		APIVersion: crCli.apiVersion(),
+		Columns:    columns,
+		Items:      make([]api.CustomResource, 0, len(list.Items)),
+	}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		ret.Items = append(ret.Items, api.CustomResource{
+			Name:      obj.GetName(),
+			Namespace: obj.GetNamespace(),
+			Cells:     getCustomResourceCells(obj, columns),
+		})
+	}
+	sort.Slice(ret.Items, func(i, j int) bool {
+		if ret.Items[i].Namespace != ret.Items[j].Namespace {
+			return ret.Items[i].Namespace < ret.Items[j].Namespace
+		}
+		return ret.Items[i].Name < ret.Items[j].Name
+	})
+	return ret, nil
+}
+
+func (c *SCluster) GetDetailsCustomResource(ctx context.Context, userCred mcclient.TokenCredential, query *api.ClusterCustomResourceInput) (jsonutils.JSONObject, error) {
+	cli, err := c.GetRemoteClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get remote kubernetes client")
+	}
+	crCli, err := newCustomResourceClient(ctx, cli, query)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := crCli.getObject(ctx, query.Namespace, query.Name)
+	if err != nil {
+		return nil, err
+	}
+	return jsonutils.Marshal(obj.Object), nil
+}
+
+func (c *SCluster) AllowPerformCreateCustomResource(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return c.allowPerformAction(ctx, userCred, "create-custom-resource")
+}
+
+func (c *SCluster) PerformCreateCustomResource(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ClusterCustomResourceCreateInput) (jsonutils.JSONObject, error) {
+	cli, err := c.GetRemoteClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get remote kubernetes client")
+	}
+	crCli, err := newCustomResourceClient(ctx, cli, &input.ClusterCustomResourceInput)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := crCli.newObject(input)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := crCli.resource(input.Namespace).Create(ctx, obj, metav1.CreateOptions{})
+	if err != nil {
+		if kerrors.IsAlreadyExists(err) {
+			return nil, httperrors.NewDuplicateResourceError("%s %s already exists", obj.GetKind(), obj.GetName())
+		}
+		if kerrors.IsInvalid(err) || kerrors.IsBadRequest(err) {
+			return nil, httperrors.NewInputParameterError("%v", err)
+		}
+		return nil, errors.Wrapf(err, "create %s %s", obj.GetKind(), obj.GetName())
+	}
+	return jsonutils.Marshal(ret.Object), nil
+}
+
+func (c *SCluster) AllowPerformUpdateCustomResource(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return c.allowPerformAction(ctx, userCred, "update-custom-resource")
+}
+
+// PerformUpdateCustomResource replaces custom resource by object, resourceVersion of remote object is used if it isn't set
+func (c *SCluster) PerformUpdateCustomResource(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ClusterCustomResourceUpdateInput) (jsonutils.JSONObject, error) {
+	cli, err := c.GetRemoteClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get remote kubernetes client")
+	}
+	crCli, err := newCustomResourceClient(ctx, cli, &input.ClusterCustomResourceInput)
+	if err != nil {
+		return nil, err
+	}
+	remoteObj, err := crCli.getObject(ctx, input.Namespace, input.Name)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := crCli.newObject(&input.ClusterCustomResourceCreateInput)
+	if err != nil {
+		return nil, err
+	}
+	if obj.GetResourceVersion() == "" {
+		obj.SetResourceVersion(remoteObj.GetResourceVersion())
+	}
+	ret, err := crCli.resource(input.Namespace).Update(ctx, obj, metav1.UpdateOptions{})
+	if err != nil {
+		if kerrors.IsConflict(err) {
+			return nil, httperrors.NewConflictError("%v", err)
+		}
+		if kerrors.IsInvalid(err) || kerrors.IsBadRequest(err) {
+			return nil, httperrors.NewInputParameterError("%v", err)
+		}
+		return nil, errors.Wrapf(err, "update %s %s", obj.GetKind(), obj.GetName())
+	}
+	return jsonutils.Marshal(ret.Object), nil
+}
+
+func (c *SCluster) AllowPerformDeleteCustomResource(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return c.allowPerformAction(ctx, userCred, "delete-custom-resource")
+}
+
+func (c *SCluster) PerformDeleteCustomResource(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ClusterCustomResourceDeleteInput) (jsonutils.JSONObject, error) {
+	cli, err := c.GetRemoteClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get remote kubernetes client")
+	}
+	crCli, err := newCustomResourceClient(ctx, cli, &input.ClusterCustomResourceInput)
+	if err != nil {
+		return nil, err
+	}
+	if input.Name == "" {
+		return nil, httperrors.NewNotEmptyError("name is empty")
+	}
+	if err := crCli.resource(input.Namespace).Delete(ctx, input.Name, metav1.DeleteOptions{}); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, httperrors.NewNotFoundError("%s %s not found", crCli.crd.Spec.Names.Kind, input.Name)
+		}
+		return nil, errors.Wrapf(err, "delete %s %s", crCli.crd.Spec.Names.Kind, input.Name)
+	}
+	return nil, nil
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newCRTestObject(spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Backup",
+		"metadata": map[string]interface{}{
+			"name":              "daily",
+			"namespace":         "default",
+			"creationTimestamp": "2021-01-01T00:00:00Z",
+		},
+		"spec": spec,
+	}}
+}
+
+func TestValidateCustomResource(t *testing.T) {
+	minimum := float64(1)
+	preserve := true
+	ver := &apiextensionsv1.CustomResourceDefinitionVersion{
+		Name: "v1",
+		Schema: &apiextensionsv1.CustomResourceValidation{
+			OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"spec": {
+						Type:     "object",
+						Required: []string{"schedule"},
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"schedule": {Type: "string"},
+							"keep":     {Type: "integer", Minimum: &minimum},
+							"mode": {
+								Type: "string",
+								Enum: []apiextensionsv1.JSON{{Raw: []byte(`"full"`)}, {Raw: []byte(`"incremental"`)}},
+							},
+							"comment":  {Type: "string", Nullable: true},
+							"port":     {XIntOrString: true},
+							"template": {Type: "object", XPreserveUnknownFields: &preserve},
+						},
+					},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name    string
+		spec    map[string]interface{}
+		wantErr bool
+	}{
+		{"valid", map[string]interface{}{"schedule": "@daily", "keep": int64(7), "mode": "full"}, false},
+		{"missing required", map[string]interface{}{"keep": int64(7)}, true},
+		{"wrong type", map[string]interface{}{"schedule": "@daily", "keep": "7"}, true},
+		{"below minimum", map[string]interface{}{"schedule": "@daily", "keep": int64(0)}, true},
+		{"not in enum", map[string]interface{}{"schedule": "@daily", "mode": "diff"}, true},
+		{"nullable", map[string]interface{}{"schedule": "@daily", "comment": nil}, false},
+		{"int or string", map[string]interface{}{"schedule": "@daily", "port": "http"}, false},
+		{"int or string integer", map[string]interface{}{"schedule": "@daily", "port": int64(80)}, false},
+		{"int or string wrong type", map[string]interface{}{"schedule": "@daily", "port": true}, true},
+		{"preserve unknown fields", map[string]interface{}{"schedule": "@daily", "template": map[string]interface{}{"foo": "bar"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCustomResource(ver, newCRTestObject(tt.spec))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCustomResource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetCustomResourceCells(t *testing.T) {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name: "v1",
+					AdditionalPrinterColumns: []apiextensionsv1.CustomResourceColumnDefinition{
+						{Name: "Schedule", Type: "string", JSONPath: ".spec.schedule"},
+						{Name: "Keep", Type: "integer", JSONPath: ".spec.keep"},
+						{Name: "Phase", Type: "string", JSONPath: ".status.phase"},
+					},
+				},
+				{Name: "v1beta1"},
+			},
+		},
+	}
+	obj := newCRTestObject(map[string]interface{}{"schedule": "@daily", "keep": int64(7)})
+
+	got := getCustomResourceCells(obj, getCustomResourceColumns(crd, "v1"))
+	want := []interface{}{"@daily", int64(7), nil}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("v1 cells = %#v, want %#v", got, want)
+	}
+
+	got = getCustomResourceCells(obj, getCustomResourceColumns(crd, "v1beta1"))
+	want = []interface{}{"2021-01-01T00:00:00Z"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("default cells = %#v, want %#v", got, want)
+	}
+}
+
+func TestToCustomResourceDefinitionV1beta1(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1beta1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": "backups.example.com"},
+		"spec": map[string]interface{}{
+			"group":   "example.com",
+			"version": "v1",
+			"scope":   "Namespaced",
+			"names":   map[string]interface{}{"kind": "Backup", "plural": "backups"},
+			"validation": map[string]interface{}{
+				"openAPIV3Schema": map[string]interface{}{"type": "object"},
+			},
+			"additionalPrinterColumns": []interface{}{
+				map[string]interface{}{"name": "Schedule", "type": "string", "JSONPath": ".spec.schedule"},
+			},
+		},
+	}}
+	crd, err := toCustomResourceDefinition(obj)
+	if err != nil {
+		t.Fatalf("toCustomResourceDefinition: %v", err)
+	}
+	if len(crd.Spec.Versions) != 1 {
+		t.Fatalf("versions = %#v, want one version", crd.Spec.Versions)
+	}
+	ver := crd.Spec.Versions[0]
+	if ver.Name != "v1" || !ver.Served || !ver.Storage {
+		t.Errorf("unexpected version %#v", ver)
+	}
+	if ver.Schema == nil || ver.Schema.OpenAPIV3Schema == nil || ver.Schema.OpenAPIV3Schema.Type != "object" {
+		t.Errorf("schema isn't converted: %#v", ver.Schema)
+	}
+	if len(ver.AdditionalPrinterColumns) != 1 || ver.AdditionalPrinterColumns[0].JSONPath != ".spec.schedule" {
+		t.Errorf("printer columns aren't converted: %#v", ver.AdditionalPrinterColumns)
+	}
+}
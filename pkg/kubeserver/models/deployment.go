@@ -263,5 +263,25 @@ func (obj *SDeployment) GetDetails(
 	}
 	detail.RollingUpdateStrategy = rollingUpdateStrategy
 	detail.RevisionHistoryLimit = deploy.Spec.RevisionHistoryLimit
+	if !isList {
+		hpas, err := GetHorizontalPodAutoscalersByTarget(cli, deploy.GetNamespace(), api.KindNameDeployment, deploy.GetName())
+		if err != nil {
+			log.Errorf("Get horizontalpodautoscalers by deployment %s error: %v", obj.GetName(), err)
+		} else {
+			detail.HorizontalPodAutoscalers = hpas
+		}
+		pdbs, err := GetPodDisruptionBudgetsByTemplate(cli, deploy.GetNamespace(), &deploy.Spec.Template)
+		if err != nil {
+			log.Errorf("Get poddisruptionbudgets by deployment %s error: %v", obj.GetName(), err)
+		} else {
+			detail.PodDisruptionBudgets = pdbs
+		}
+		usage, err := GetWorkloadUsage(ctx, cli, deploy.GetNamespace(), deploy.Spec.Selector)
+		if err != nil {
+			logUsageError(api.KindNameDeployment, obj.GetName(), err)
+		} else {
+			detail.Usage = usage
+		}
+	}
 	return detail
 }
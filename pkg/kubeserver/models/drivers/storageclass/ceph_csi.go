@@ -0,0 +1,111 @@
+package storageclass
+
+import (
+	"context"
+	"database/sql"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/client"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+)
+
+// cephConfig is ceph cluster of cephCSI component along with credential from secret
+type cephConfig struct {
+	api.ComponentCephCSIConfigCluster
+	User string
+	Key  string
+
+	SecretName      string
+	SecretNamespace string
+}
+
+func getCephUserKeyFromSecret(cliMan *client.ClusterManager, name, namespace string) (string, string, error) {
+	cli := cliMan.GetClientset()
+	secret, err := cli.CoreV1().Secrets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return "", "", err
+	} else if secret.Type != api.SecretTypeCephCSI {
+		return "", "", httperrors.NewInputParameterError("%s/%s secret type is not %s", namespace, name, api.SecretTypeCephCSI)
+	}
+	uId := string(secret.Data["userID"])
+	key := string(secret.Data["userKey"])
+	return uId, key, nil
+}
+
+func validateCephClusterId(cId string, conf *api.ComponentSettingCephCSI) (api.ComponentCephCSIConfigCluster, error) {
+	for _, c := range conf.Config {
+		if c.ClsuterId == cId {
+			return c, nil
+		}
+	}
+	return api.ComponentCephCSIConfigCluster{}, httperrors.NewNotFoundError("Not found clusterId %s in component config", cId)
+}
+
+// getCephCSIConfig validates secret and ceph cluster id configured in cephCSI component
+func getCephCSIConfig(userCred mcclient.TokenCredential, cli *client.ClusterManager, clusterId, secretName, secretNamespace string) (*cephConfig, error) {
+	cluster := cli.GetClusterObject().(*models.SCluster)
+	if secretName == "" {
+		return nil, httperrors.NewNotEmptyError("secretName is empty")
+	}
+	if secretNamespace == "" {
+		return nil, httperrors.NewNotEmptyError("secretNamespace is empty")
+	}
+	nsObj, err := models.FetchClusterResourceByIdOrName(models.GetNamespaceManager(), userCred, cluster.GetId(), "", secretNamespace)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get cluster %s namespace %s", cluster.GetId(), secretNamespace)
+	}
+	secObj, err := models.FetchClusterResourceByIdOrName(models.GetSecretManager(), userCred, cluster.GetId(), nsObj.GetId(), secretName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get cluster %s secret %s/%s object", cluster.GetId(), nsObj.GetName(), secretName)
+	}
+
+	user, key, err := getCephUserKeyFromSecret(cli, secObj.GetName(), nsObj.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	// check clusterId
+	component, err := cluster.GetComponentByType(api.ClusterComponentCephCSI)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewNotFoundError("not found cluster %s component %s", cluster.GetName(), api.ClusterComponentCephCSI)
+		}
+		return nil, err
+	}
+	settings, err := component.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	if clusterId == "" {
+		return nil, httperrors.NewInputParameterError("clusterId is empty")
+	}
+	cephConf, err := validateCephClusterId(clusterId, settings.CephCSI)
+	if err != nil {
+		return nil, err
+	}
+	return &cephConfig{
+		ComponentCephCSIConfigCluster: cephConf,
+		User:                          user,
+		Key:                           key,
+		SecretName:                    secObj.GetName(),
+		SecretNamespace:               nsObj.GetName(),
+	}, nil
+}
+
+// cephCSISecretParams returns secret params of provisioner, resizer and node plugin
+func cephCSISecretParams(secretName, secretNamespace string) map[string]string {
+	return map[string]string{
+		GetCSIParamsKey("provisioner-secret-name"):            secretName,
+		GetCSIParamsKey("provisioner-secret-namespace"):       secretNamespace,
+		GetCSIParamsKey("controller-expand-secret-name"):      secretName,
+		GetCSIParamsKey("controller-expand-secret-namespace"): secretNamespace,
+		GetCSIParamsKey("node-stage-secret-name"):             secretName,
+		GetCSIParamsKey("node-stage-secret-namespace"):        secretNamespace,
+	}
+}
@@ -0,0 +1,112 @@
+package storageclass
+
+import (
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/client"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+	"yunion.io/x/kubecomps/pkg/utils/ceph"
+)
+
+func init() {
+	models.GetStorageClassManager().RegisterDriver(
+		api.StorageClassProvisionerCephCSICephFS,
+		newCephCSICephFS(),
+	)
+}
+
+type CephCSICephFS struct{}
+
+func newCephCSICephFS() models.IStorageClassDriver {
+	return new(CephCSICephFS)
+}
+
+func (drv *CephCSICephFS) getCephConfig(userCred mcclient.TokenCredential, cli *client.ClusterManager, data *api.StorageClassCreateInput) (*cephConfig, error) {
+	input := data.CephCSICephFS
+	if input == nil {
+		return nil, httperrors.NewInputParameterError("cephCSICephFS config is empty")
+	}
+	conf, err := getCephCSIConfig(userCred, cli, input.ClusterId, input.SecretName, input.SecretNamespace)
+	if err != nil {
+		return nil, err
+	}
+	input.SecretName = conf.SecretName
+	input.SecretNamespace = conf.SecretNamespace
+	return conf, nil
+}
+
+func (drv *CephCSICephFS) listFilesystems(conf *cephConfig) ([]ceph.CephFS, error) {
+	cephCli, err := ceph.NewClient(conf.User, conf.Key, conf.Monitors...)
+	if err != nil {
+		return nil, errors.Wrap(err, "new ceph client")
+	}
+	return cephCli.ListFilesystems()
+}
+
+func (drv *CephCSICephFS) ValidateCreateData(userCred mcclient.TokenCredential, cli *client.ClusterManager, data *api.StorageClassCreateInput) (*api.StorageClassCreateInput, error) {
+	cephConf, err := drv.getCephConfig(userCred, cli, data)
+	if err != nil {
+		return nil, err
+	}
+	input := data.CephCSICephFS
+	if input.FsName == "" {
+		return nil, httperrors.NewInputParameterError("fsName is empty")
+	}
+	if input.Mounter != "" && !utils.IsInStringArray(input.Mounter, []string{"kernel", "fuse"}) {
+		return nil, httperrors.NewInputParameterError("unsupport mounter %s", input.Mounter)
+	}
+	fss, err := drv.listFilesystems(cephConf)
+	if err != nil {
+		return nil, err
+	}
+	for _, fs := range fss {
+		if fs.Name != input.FsName {
+			continue
+		}
+		if input.Pool != "" && !utils.IsInStringArray(input.Pool, fs.DataPools) {
+			return nil, httperrors.NewNotFoundError("pool %s isn't data pool of filesystem %s: %v", input.Pool, fs.Name, fs.DataPools)
+		}
+		return data, nil
+	}
+	return nil, httperrors.NewNotFoundError("not found filesystem %s in %v", input.FsName, cephConf.Monitors)
+}
+
+func (drv *CephCSICephFS) ConnectionTest(userCred mcclient.TokenCredential, cli *client.ClusterManager, data *api.StorageClassCreateInput) (*api.StorageClassTestResult, error) {
+	cephConf, err := drv.getCephConfig(userCred, cli, data)
+	if err != nil {
+		return nil, err
+	}
+	fss, err := drv.listFilesystems(cephConf)
+	if err != nil {
+		return nil, err
+	}
+	result := &api.StorageClassTestResultCephCSICephFS{
+		FsNames: make([]string, 0),
+		Pools:   make([]string, 0),
+	}
+	for _, fs := range fss {
+		result.FsNames = append(result.FsNames, fs.Name)
+		if fs.Name == data.CephCSICephFS.FsName {
+			result.Pools = fs.DataPools
+		}
+	}
+	return &api.StorageClassTestResult{CephCSICephFS: result}, nil
+}
+
+func (drv *CephCSICephFS) ToStorageClassParams(input *api.StorageClassCreateInput) (map[string]string, error) {
+	config := input.CephCSICephFS
+	params := cephCSISecretParams(config.SecretName, config.SecretNamespace)
+	params["clusterID"] = config.ClusterId
+	params["fsName"] = config.FsName
+	if config.Pool != "" {
+		params["pool"] = config.Pool
+	}
+	if config.Mounter != "" {
+		params["mounter"] = config.Mounter
+	}
+	return params, nil
+}
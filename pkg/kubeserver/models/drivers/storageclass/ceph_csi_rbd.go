@@ -1,11 +1,6 @@
 package storageclass
 
 import (
-	"context"
-	"database/sql"
-
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/utils"
@@ -39,83 +34,18 @@ func newCephCSIRBD() models.IStorageClassDriver {
 	return new(CephCSIRBD)
 }
 
-func (drv *CephCSIRBD) getUserKeyFromSecret(cliMan *client.ClusterManager, name, namespace string) (string, string, error) {
-	cli := cliMan.GetClientset()
-	secret, err := cli.CoreV1().Secrets(namespace).Get(context.Background(), name, metav1.GetOptions{})
-	if err != nil {
-		return "", "", err
-	} else if secret.Type != api.SecretTypeCephCSI {
-		return "", "", httperrors.NewInputParameterError("%s/%s secret type is not %s", namespace, name, api.SecretTypeCephCSI)
-	}
-	uId := string(secret.Data["userID"])
-	key := string(secret.Data["userKey"])
-	if err != nil {
-		return "", "", httperrors.NewNotAcceptableError("%s/%s user key decode error: %v", namespace, name, err)
-	}
-	return uId, key, nil
-}
-
-type cephConfig struct {
-	api.ComponentCephCSIConfigCluster
-	User string
-	Key  string
-}
-
 func (drv *CephCSIRBD) getCephConfig(userCred mcclient.TokenCredential, cli *client.ClusterManager, data *api.StorageClassCreateInput) (*cephConfig, error) {
 	input := data.CephCSIRBD
 	if input == nil {
 		return nil, httperrors.NewInputParameterError("cephCSIRBD config is empty")
 	}
-	cluster := cli.GetClusterObject().(*models.SCluster)
-	secretName := input.SecretName
-	if secretName == "" {
-		return nil, httperrors.NewNotEmptyError("secretName is empty")
-	}
-	secretNamespace := input.SecretNamespace
-	if secretNamespace == "" {
-		return nil, httperrors.NewNotEmptyError("secretNamespace is empty")
-	}
-	nsObj, err := models.FetchClusterResourceByIdOrName(models.GetNamespaceManager(), userCred, cluster.GetId(), "", secretNamespace)
-	if err != nil {
-		return nil, errors.Wrapf(err, "get cluster %s namespace %s", cluster.GetId(), secretNamespace)
-	}
-	secObj, err := models.FetchClusterResourceByIdOrName(models.GetSecretManager(), userCred, cluster.GetId(), nsObj.GetId(), secretName)
-	if err != nil {
-		return nil, errors.Wrapf(err, "get cluster %s secret %s/%s object", cluster.GetId(), nsObj.GetName(), secretName)
-	}
-
-	user, key, err := drv.getUserKeyFromSecret(cli, secObj.GetName(), nsObj.GetName())
-	if err != nil {
-		return nil, err
-	}
-
-	input.SecretName = secObj.GetName()
-	input.SecretNamespace = nsObj.GetName()
-
-	// check clusterId
-	component, err := cluster.GetComponentByType(api.ClusterComponentCephCSI)
-	if err != nil {
-		if errors.Cause(err) == sql.ErrNoRows {
-			return nil, httperrors.NewNotFoundError("not found cluster %s component %s", cluster.GetName(), api.ClusterComponentCephCSI)
-		}
-		return nil, err
-	}
-	settings, err := component.GetSettings()
+	conf, err := getCephCSIConfig(userCred, cli, input.ClusterId, input.SecretName, input.SecretNamespace)
 	if err != nil {
 		return nil, err
 	}
-	if input.ClusterId == "" {
-		return nil, httperrors.NewInputParameterError("clusterId is empty")
-	}
-	cephConf, err := drv.validateClusterId(input.ClusterId, settings.CephCSI)
-	if err != nil {
-		return nil, err
-	}
-	return &cephConfig{
-		cephConf,
-		user,
-		key,
-	}, nil
+	input.SecretName = conf.SecretName
+	input.SecretNamespace = conf.SecretNamespace
+	return conf, nil
 }
 
 func (drv *CephCSIRBD) ValidateCreateData(userCred mcclient.TokenCredential, cli *client.ClusterManager, data *api.StorageClassCreateInput) (*api.StorageClassCreateInput, error) {
@@ -155,15 +85,6 @@ func (drv *CephCSIRBD) listPools(monitors []string, user string, key string) ([]
 	return cephCli.ListPoolsNoDefault()
 }
 
-func (drv *CephCSIRBD) validateClusterId(cId string, conf *api.ComponentSettingCephCSI) (api.ComponentCephCSIConfigCluster, error) {
-	for _, c := range conf.Config {
-		if c.ClsuterId == cId {
-			return c, nil
-		}
-	}
-	return api.ComponentCephCSIConfigCluster{}, httperrors.NewNotFoundError("Not found clusterId %s in component config", cId)
-}
-
 func (drv *CephCSIRBD) validatePool(monitors []string, user string, key string, pool string) error {
 	pools, err := drv.listPools(monitors, user, key)
 	if err != nil {
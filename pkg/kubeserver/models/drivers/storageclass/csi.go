@@ -0,0 +1,129 @@
+package storageclass
+
+import (
+	"context"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/client"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+)
+
+// csiSecretTypes are secrets supported by csi external sidecars,
+// ref: https://kubernetes-csi.github.io/docs/secrets-and-credentials-storage-class.html
+var csiSecretTypes = []string{
+	"provisioner",
+	"controller-expand",
+	"controller-publish",
+	"node-stage",
+	"node-publish",
+}
+
+func init() {
+	models.GetStorageClassManager().RegisterDriver(
+		api.StorageClassDriverCSI,
+		newCSI(),
+	)
+}
+
+// CSI manages storageclass of any provisioner by parameters and secret references given as is
+type CSI struct{}
+
+func newCSI() models.IStorageClassDriver {
+	return new(CSI)
+}
+
+// isCSITemplate returns true when value contains template like ${pvc.namespace} resolved by csi sidecars
+func isCSITemplate(val string) bool {
+	return strings.Contains(val, "${")
+}
+
+func (drv *CSI) ValidateCreateData(userCred mcclient.TokenCredential, cli *client.ClusterManager, data *api.StorageClassCreateInput) (*api.StorageClassCreateInput, error) {
+	input := data.CSI
+	if input == nil {
+		return nil, httperrors.NewInputParameterError("csi config is empty")
+	}
+	for key := range input.Parameters {
+		if key == "" {
+			return nil, httperrors.NewInputParameterError("parameter key is empty")
+		}
+	}
+	cluster := cli.GetClusterObject().(*models.SCluster)
+	for i := range input.Secrets {
+		ref := &input.Secrets[i]
+		if !utils.IsInStringArray(ref.Type, csiSecretTypes) {
+			return nil, httperrors.NewInputParameterError("unsupport secret type %q, must be one of %v", ref.Type, csiSecretTypes)
+		}
+		if ref.Name == "" {
+			return nil, httperrors.NewNotEmptyError("%s secret name is empty", ref.Type)
+		}
+		if ref.Namespace == "" {
+			return nil, httperrors.NewNotEmptyError("%s secret namespace is empty", ref.Type)
+		}
+		if isCSITemplate(ref.Name) || isCSITemplate(ref.Namespace) {
+			continue
+		}
+		nsObj, err := models.FetchClusterResourceByIdOrName(models.GetNamespaceManager(), userCred, cluster.GetId(), "", ref.Namespace)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get cluster %s namespace %s", cluster.GetId(), ref.Namespace)
+		}
+		secObj, err := models.FetchClusterResourceByIdOrName(models.GetSecretManager(), userCred, cluster.GetId(), nsObj.GetId(), ref.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get cluster %s secret %s/%s object", cluster.GetId(), nsObj.GetName(), ref.Name)
+		}
+		ref.Name = secObj.GetName()
+		ref.Namespace = nsObj.GetName()
+	}
+	return data, nil
+}
+
+// ConnectionTest checks csi driver of provisioner is registered in cluster and on which nodes
+func (drv *CSI) ConnectionTest(userCred mcclient.TokenCredential, cli *client.ClusterManager, data *api.StorageClassCreateInput) (*api.StorageClassTestResult, error) {
+	ctx := context.Background()
+	k8sCli := cli.GetClientset()
+	result := &api.StorageClassTestResultCSI{
+		Nodes: make([]string, 0),
+	}
+	if _, err := k8sCli.StorageV1().CSIDrivers().Get(ctx, data.Provisioner, metav1.GetOptions{}); err == nil {
+		result.DriverRegistered = true
+	} else if !k8serrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "get csidriver %s", data.Provisioner)
+	}
+	csiNodes, err := k8sCli.StorageV1().CSINodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "list csinodes")
+	}
+	for _, node := range csiNodes.Items {
+		for _, d := range node.Spec.Drivers {
+			if d.Name == data.Provisioner {
+				result.Nodes = append(result.Nodes, node.GetName())
+				break
+			}
+		}
+	}
+	if !result.DriverRegistered && len(result.Nodes) == 0 {
+		return nil, httperrors.NewNotFoundError("csi driver %s isn't registered in cluster", data.Provisioner)
+	}
+	return &api.StorageClassTestResult{CSI: result}, nil
+}
+
+func (drv *CSI) ToStorageClassParams(input *api.StorageClassCreateInput) (map[string]string, error) {
+	config := input.CSI
+	params := make(map[string]string)
+	for k, v := range config.Parameters {
+		params[k] = v
+	}
+	for _, ref := range config.Secrets {
+		params[GetCSIParamsKey(ref.Type+"-secret-name")] = ref.Name
+		params[GetCSIParamsKey(ref.Type+"-secret-namespace")] = ref.Namespace
+	}
+	return params, nil
+}
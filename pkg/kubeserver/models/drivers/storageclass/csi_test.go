@@ -0,0 +1,24 @@
+package storageclass
+
+import (
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/pkg/util/httputils"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+func TestCSIValidateCreateDataEmptyConfig(t *testing.T) {
+	drv := newCSI()
+	data := &api.StorageClassCreateInput{
+		Provisioner: "csi.example.com",
+	}
+	_, err := drv.ValidateCreateData(nil, nil, data)
+	if err == nil {
+		t.Fatal("create without csi config should fail")
+	}
+	if jsonErr, ok := err.(*httputils.JSONClientError); !ok || jsonErr.Class != string(httperrors.ErrInputParameter) {
+		t.Errorf("err = %v, want input parameter error", err)
+	}
+}
@@ -0,0 +1,107 @@
+package storageclass
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/client"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+)
+
+const (
+	// names are same as RancherLocalPathCSI addon
+	localPathProvisionerSelector = "app=local-path-provisioner"
+	localPathConfigMap           = "local-path-config"
+	localPathConfigKey           = "config.json"
+)
+
+func init() {
+	models.GetStorageClassManager().RegisterDriver(
+		api.StorageClassProvisionerLocalPath,
+		newLocalPath(),
+	)
+}
+
+type LocalPath struct{}
+
+func newLocalPath() models.IStorageClassDriver {
+	return new(LocalPath)
+}
+
+func (drv *LocalPath) ValidateCreateData(userCred mcclient.TokenCredential, cli *client.ClusterManager, data *api.StorageClassCreateInput) (*api.StorageClassCreateInput, error) {
+	// local volume is bound after pod scheduled to node
+	waitMode := storagev1.VolumeBindingWaitForFirstConsumer
+	if data.VolumeBindingMode == nil {
+		data.VolumeBindingMode = &waitMode
+	} else if *data.VolumeBindingMode != waitMode {
+		return nil, httperrors.NewInputParameterError("volumeBindingMode of local path must be %s", waitMode)
+	}
+	if input := data.LocalPath; input != nil {
+		if input.NodePath != "" && !strings.HasPrefix(input.NodePath, "/") {
+			return nil, httperrors.NewInputParameterError("nodePath must be absolute path")
+		}
+	}
+	return data, nil
+}
+
+type localPathConfig struct {
+	NodePathMap []struct {
+		Node  string   `json:"node"`
+		Paths []string `json:"paths"`
+	} `json:"nodePathMap"`
+}
+
+func (drv *LocalPath) ConnectionTest(userCred mcclient.TokenCredential, cli *client.ClusterManager, data *api.StorageClassCreateInput) (*api.StorageClassTestResult, error) {
+	ctx := context.Background()
+	k8sCli := cli.GetClientset()
+	deploys, err := k8sCli.AppsV1().Deployments("").List(ctx, metav1.ListOptions{LabelSelector: localPathProvisionerSelector})
+	if err != nil {
+		return nil, errors.Wrap(err, "list local-path-provisioner deployments")
+	}
+	if len(deploys.Items) == 0 {
+		return nil, httperrors.NewNotFoundError("local-path-provisioner isn't deployed, enable RancherLocalPathCSI addon first")
+	}
+	deploy := deploys.Items[0]
+	if deploy.Status.ReadyReplicas == 0 {
+		return nil, httperrors.NewNotAcceptableError("local-path-provisioner %s/%s has no ready replica", deploy.Namespace, deploy.Name)
+	}
+	result := &api.StorageClassTestResultLocalPath{
+		Namespace:     deploy.Namespace,
+		ReadyReplicas: deploy.Status.ReadyReplicas,
+		NodePathMap:   make(map[string][]string),
+	}
+	cm, err := k8sCli.CoreV1().ConfigMaps(deploy.Namespace).Get(ctx, localPathConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "get configmap %s/%s", deploy.Namespace, localPathConfigMap)
+	}
+	conf := new(localPathConfig)
+	if err := json.Unmarshal([]byte(cm.Data[localPathConfigKey]), conf); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal %s of configmap %s/%s", localPathConfigKey, deploy.Namespace, localPathConfigMap)
+	}
+	for _, np := range conf.NodePathMap {
+		result.NodePathMap[np.Node] = np.Paths
+	}
+	return &api.StorageClassTestResult{LocalPath: result}, nil
+}
+
+func (drv *LocalPath) ToStorageClassParams(input *api.StorageClassCreateInput) (map[string]string, error) {
+	params := make(map[string]string)
+	if config := input.LocalPath; config != nil {
+		if config.NodePath != "" {
+			params["nodePath"] = config.NodePath
+		}
+		if config.PathPattern != "" {
+			params["pathPattern"] = config.PathPattern
+		}
+	}
+	return params, nil
+}
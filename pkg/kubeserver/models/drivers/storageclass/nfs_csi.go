@@ -0,0 +1,120 @@
+package storageclass
+
+import (
+	"context"
+	"net"
+	"path"
+	"strings"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/client"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+	"yunion.io/x/kubecomps/pkg/utils/nfs"
+)
+
+const (
+	nfsServicePort = "2049"
+)
+
+func init() {
+	models.GetStorageClassManager().RegisterDriver(
+		api.StorageClassProvisionerNFSCSI,
+		newNFSCSI(),
+	)
+}
+
+type NFSCSI struct{}
+
+func newNFSCSI() models.IStorageClassDriver {
+	return new(NFSCSI)
+}
+
+func (drv *NFSCSI) validateInput(data *api.StorageClassCreateInput) (*api.NFSCSIStorageClassCreateInput, error) {
+	input := data.NFSCSI
+	if input == nil {
+		return nil, httperrors.NewInputParameterError("nfsCSI config is empty")
+	}
+	if input.Server == "" {
+		return nil, httperrors.NewNotEmptyError("server is empty")
+	}
+	if !strings.HasPrefix(input.Share, "/") {
+		return nil, httperrors.NewInputParameterError("share must be absolute path")
+	}
+	if input.OnDelete != "" && !utils.IsInStringArray(input.OnDelete, []string{"delete", "retain", "archive"}) {
+		return nil, httperrors.NewInputParameterError("unsupport onDelete %s", input.OnDelete)
+	}
+	return input, nil
+}
+
+func (drv *NFSCSI) ValidateCreateData(userCred mcclient.TokenCredential, cli *client.ClusterManager, data *api.StorageClassCreateInput) (*api.StorageClassCreateInput, error) {
+	input, err := drv.validateInput(data)
+	if err != nil {
+		return nil, err
+	}
+	// servers only serving nfsv4 have no mountd to list exports, so only nfs service is checked
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(input.Server, nfsServicePort), 5*time.Second)
+	if err != nil {
+		return nil, httperrors.NewNotAcceptableError("connect nfs server %s: %v", input.Server, err)
+	}
+	conn.Close()
+	return data, nil
+}
+
+// isShareExported checks share is an export or sub directory of an export
+func isShareExported(share string, exports []nfs.Export) bool {
+	share = path.Clean(share)
+	for _, exp := range exports {
+		dir := path.Clean(exp.Dir)
+		if share == dir || strings.HasPrefix(share, strings.TrimSuffix(dir, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (drv *NFSCSI) ConnectionTest(userCred mcclient.TokenCredential, cli *client.ClusterManager, data *api.StorageClassCreateInput) (*api.StorageClassTestResult, error) {
+	input, err := drv.validateInput(data)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	exports, err := nfs.ListExports(ctx, input.Server)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list exports of nfs server %s", input.Server)
+	}
+	if !isShareExported(input.Share, exports) {
+		return nil, httperrors.NewNotFoundError("share %s isn't exported by %s", input.Share, input.Server)
+	}
+	result := &api.StorageClassTestResultNFSCSI{
+		Exports: make([]api.NFSExport, len(exports)),
+	}
+	for i, exp := range exports {
+		result.Exports[i] = api.NFSExport{Dir: exp.Dir, Groups: exp.Groups}
+	}
+	return &api.StorageClassTestResult{NFSCSI: result}, nil
+}
+
+func (drv *NFSCSI) ToStorageClassParams(input *api.StorageClassCreateInput) (map[string]string, error) {
+	config := input.NFSCSI
+	params := map[string]string{
+		"server": config.Server,
+		"share":  config.Share,
+	}
+	if config.SubDir != "" {
+		params["subDir"] = config.SubDir
+	}
+	if config.MountPermissions != "" {
+		params["mountPermissions"] = config.MountPermissions
+	}
+	if config.OnDelete != "" {
+		params["onDelete"] = config.OnDelete
+	}
+	return params, nil
+}
@@ -4,6 +4,11 @@ import (
 	"context"
 	"database/sql"
 
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/onecloud/pkg/apis"
@@ -259,6 +264,8 @@ func newFedClusterResAPI() IFedClusterResAPI {
 type IFedNamespaceResAPI interface {
 	// StartAttachClusterTask start background task to attach fedreated namespace resource to cluster
 	StartAttachClusterTask(obj IFedNamespaceModel, ctx context.Context, userCred mcclient.TokenCredential, data *jsonutils.JSONDict, parentTaskId string) error
+	// ReconcilePlacement attach clusters selected by placement and detach clusters not selected any more
+	ReconcilePlacement(obj IFedObjectModel, ctx context.Context, userCred mcclient.TokenCredential) error
 }
 
 type sFedNamespaceResAPI struct{}
@@ -276,6 +283,55 @@ func (a sFedNamespaceResAPI) StartAttachClusterTask(obj IFedNamespaceModel, ctx
 	return nil
 }
 
+func (a sFedNamespaceResAPI) ReconcilePlacement(obj IFedObjectModel, ctx context.Context, userCred mcclient.TokenCredential) error {
+	clusters, isSet, err := GetFedObjectPlacementClusters(obj)
+	if err != nil {
+		return errors.Wrap(err, "get placement clusters")
+	}
+	if !isSet {
+		// clusters are attached manually
+		return nil
+	}
+	attached, err := GetFedResAPI().GetAttachedClusters(obj)
+	if err != nil {
+		return errors.Wrap(err, "get attached clusters")
+	}
+	attachedIds := make(map[string]bool)
+	for _, cluster := range attached {
+		attachedIds[cluster.GetId()] = true
+	}
+	selectedIds := make(map[string]bool)
+	errs := make([]error, 0)
+	for _, cluster := range clusters {
+		selectedIds[cluster.GetId()] = true
+		if attachedIds[cluster.GetId()] {
+			continue
+		}
+		if cluster.GetStatus() != api.ClusterStatusRunning {
+			log.Warningf("%s skip attaching to cluster %s with status %s", obj.LogPrefix(), cluster.GetName(), cluster.GetStatus())
+			continue
+		}
+		input := api.FederatedResourceJointClusterInput{
+			ClusterId: cluster.GetId(),
+		}
+		if err := a.StartAttachClusterTask(obj, ctx, userCred, input.JSON(input), ""); err != nil {
+			errs = append(errs, errors.Wrapf(err, "attach to cluster %s", cluster.GetName()))
+		}
+	}
+	for _, cluster := range attached {
+		if selectedIds[cluster.GetId()] {
+			continue
+		}
+		input := api.FederatedResourceJointClusterInput{
+			ClusterId: cluster.GetId(),
+		}
+		if err := GetFedResAPI().PerformDetachCluster(obj, ctx, userCred, input.JSON(input)); err != nil {
+			errs = append(errs, errors.Wrapf(err, "detach from cluster %s", cluster.GetName()))
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
 type IFedJointResAPI interface {
 	ClusterScope() IFedJointClusterResAPI
 	NamespaceScope() IFedNamespaceJointClusterResAPI
@@ -432,6 +488,12 @@ func (a sFedJointResAPI) ReconcileResource(jObj IFedJointClusterModel, ctx conte
 	}
 	ownerId := cluster.GetOwnerId()
 	fedObj := db.JointMaster(jObj).(IFedModel)
+	if objModel, ok := fedObj.(IFedObjectModel); ok {
+		if err := a.applyObject(ctx, userCred, jObj, objModel, cluster, resObj, exist); err != nil {
+			return errors.Wrap(err, "ApplyClusterObject")
+		}
+		return nil
+	}
 	if exist {
 		if err := a.updateResource(ctx, userCred, jObj, resObj); err != nil {
 			return errors.Wrap(err, "UpdateClusterResource")
@@ -511,6 +573,109 @@ func (a sFedJointResAPI) updateResource(
 	return nil
 }
 
+// applyObject creates or updates object rendered from federated template and cluster overrides
+func (a sFedJointResAPI) applyObject(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	jObj IFedJointClusterModel,
+	fedObj IFedObjectModel,
+	cluster *SCluster,
+	resObj IClusterModel,
+	exist bool,
+) error {
+	clsNs, err := a.namespaceScope.FetchClusterNamespace(userCred, jObj, cluster)
+	if err != nil {
+		return errors.Wrapf(err, "get %s cluster %s namespace", jObj.Keyword(), cluster.GetName())
+	}
+	objMeta := fedObj.GetK8sObjectMeta()
+	objMeta.Namespace = clsNs.GetName()
+	obj, err := RenderFedObjectForCluster(fedObj, cluster.GetId(), objMeta)
+	if err != nil {
+		return errors.Wrapf(err, "render %s for cluster %s", fedObj.LogPrefix(), cluster.GetName())
+	}
+	if exist {
+		remoteObj, err := resObj.GetRemoteObject()
+		if err != nil && !kerrors.IsNotFound(errors.Cause(err)) {
+			return errors.Wrapf(err, "get %s remote object", resObj.LogPrefix())
+		}
+		if err == nil {
+			if err := mergeFedObjectRemoteFields(obj, remoteObj.(runtime.Object)); err != nil {
+				return errors.Wrap(err, "merge remote object fields")
+			}
+			updatedObj, err := resObj.UpdateRemoteObject(obj)
+			if err != nil {
+				return errors.Wrapf(err, "update %s remote object", resObj.LogPrefix())
+			}
+			if err := jObj.SetResource(resObj); err != nil {
+				return errors.Wrapf(err, "set %s resource object", jObj.Keyword())
+			}
+			return GetClusterResAPI().UpdateFromRemoteObject(resObj, ctx, userCred, updatedObj)
+		}
+		// local object exists but remote object has been deleted, create it again
+	}
+	cli, err := GetClusterClient(cluster.GetId())
+	if err != nil {
+		return errors.Wrapf(err, "get cluster %s client", cluster.GetName())
+	}
+	man := jObj.GetResourceManager()
+	createdObj, err := cli.GetHandler().CreateV2(man.GetK8sResourceInfo().ResourceName, objMeta.Namespace, obj)
+	if err != nil {
+		return errors.Wrapf(err, "create %s to cluster %s", fedObj.LogPrefix(), cluster.GetName())
+	}
+	// local object may be created by informer at the same time
+	localObj, err := a.FetchResourceModelByName(jObj, userCred)
+	if err != nil {
+		return errors.Wrap(err, "fetch local resource by name")
+	}
+	if localObj == nil {
+		dbObj, err := NewFromRemoteObject(ctx, userCred, man, cluster, createdObj)
+		if err != nil {
+			if localObj, _ = a.FetchResourceModelByName(jObj, userCred); localObj == nil {
+				return errors.Wrapf(err, "new %s local object", man.Keyword())
+			}
+		} else {
+			localObj = dbObj.(IClusterModel)
+		}
+	}
+	if err := jObj.SetResource(localObj); err != nil {
+		return errors.Wrapf(err, "set %s resource object", jObj.Keyword())
+	}
+	return nil
+}
+
+// mergeFedObjectRemoteFields keeps fields allocated by member cluster when updating remote object
+func mergeFedObjectRemoteFields(obj runtime.Object, remoteObj runtime.Object) error {
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	remoteMeta, err := meta.Accessor(remoteObj)
+	if err != nil {
+		return err
+	}
+	objMeta.SetResourceVersion(remoteMeta.GetResourceVersion())
+	svc, ok := obj.(*corev1.Service)
+	if !ok {
+		return nil
+	}
+	remoteSvc := remoteObj.(*corev1.Service)
+	if svc.Spec.ClusterIP == "" {
+		svc.Spec.ClusterIP = remoteSvc.Spec.ClusterIP
+	}
+	for i := range svc.Spec.Ports {
+		port := &svc.Spec.Ports[i]
+		if port.NodePort != 0 {
+			continue
+		}
+		for _, rPort := range remoteSvc.Spec.Ports {
+			if rPort.Name == port.Name && rPort.Port == port.Port {
+				port.NodePort = rPort.NodePort
+			}
+		}
+	}
+	return nil
+}
+
 func (_ sFedJointResAPI) FetchFedResourceModel(jObj IFedJointClusterModel) (IFedModel, error) {
 	fedMan := jObj.GetManager().GetFedManager()
 	fObj, err := fedMan.FetchById(jObj.GetFedResourceId())
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+)
+
+var (
+	FedConfigMapClusterManager *SFedConfigMapClusterManager
+	_                          IFedJointClusterModel = new(SFedConfigMapCluster)
+)
+
+func init() {
+	db.InitManager(func() {
+		FedConfigMapClusterManager = NewFedJointManager(func() db.IJointModelManager {
+			return &SFedConfigMapClusterManager{
+				SFedNamespaceJointClusterManager: NewFedNamespaceJointClusterManager(
+					SFedConfigMapCluster{},
+					"federatedconfigmapclusters_tbl",
+					"federatedconfigmapcluster",
+					"federatedconfigmapclusters",
+					GetFedConfigMapManager(),
+					GetConfigMapManager(),
+				),
+			}
+		}).(*SFedConfigMapClusterManager)
+		GetFedConfigMapManager().SetJointModelManager(FedConfigMapClusterManager)
+		RegisterFedJointClusterManager(GetFedConfigMapManager(), FedConfigMapClusterManager)
+	})
+}
+
+// +onecloud:swagger-gen-model-singular=federatedconfigmapcluster
+// +onecloud:swagger-gen-model-plural=federatedconfigmapclusters
+type SFedConfigMapClusterManager struct {
+	SFedNamespaceJointClusterManager
+}
+
+type SFedConfigMapCluster struct {
+	SFedNamespaceJointObjectCluster
+}
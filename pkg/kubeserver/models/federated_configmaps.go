@@ -0,0 +1,73 @@
+package models
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/mcclient"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+var (
+	fedConfigMapManager *SFedConfigMapManager
+	_                   IFedModelManager = new(SFedConfigMapManager)
+	_                   IFedObjectModel  = new(SFedConfigMap)
+)
+
+func init() {
+	GetFedConfigMapManager()
+}
+
+func GetFedConfigMapManager() *SFedConfigMapManager {
+	if fedConfigMapManager == nil {
+		fedConfigMapManager = newModelManager(func() db.IModelManager {
+			return &SFedConfigMapManager{
+				SFedNamespaceResourceManager: NewFedNamespaceResourceManager(
+					SFedConfigMap{},
+					"federatedconfigmaps_tbl",
+					"federatedconfigmap",
+					"federatedconfigmaps",
+				),
+			}
+		}).(*SFedConfigMapManager)
+	}
+	return fedConfigMapManager
+}
+
+// +onecloud:swagger-gen-model-singular=federatedconfigmap
+// +onecloud:swagger-gen-model-plural=federatedconfigmaps
+type SFedConfigMapManager struct {
+	SFedNamespaceResourceManager
+}
+
+type SFedConfigMap struct {
+	SFedObjectResource
+	Spec *api.FederatedConfigMapSpec `list:"user" update:"user" create:"required"`
+}
+
+func validateFedConfigMapObject(obj runtime.Object) error {
+	return GetConfigMapManager().ValidateConfigMapObject(obj.(*v1.ConfigMap))
+}
+
+func (m *SFedConfigMapManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerCred mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.FederatedConfigMapCreateInput) (*api.FederatedConfigMapCreateInput, error) {
+	if err := m.ValidateFedObjectCreateData(ctx, userCred, ownerCred, query, &input.FederatedNamespaceResourceCreateInput, input.Spec, validateFedConfigMapObject); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (obj *SFedConfigMap) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.FederatedConfigMapUpdateInput) (*api.FederatedConfigMapUpdateInput, error) {
+	if err := obj.ValidateFedObjectUpdateData(ctx, userCred, query, &input.FedNamespaceResourceUpdateInput, input.Spec, validateFedConfigMapObject); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (obj *SFedConfigMap) GetSpec() api.IFederatedObjectSpec {
+	return obj.Spec
+}
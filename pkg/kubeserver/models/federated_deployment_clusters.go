@@ -0,0 +1,39 @@
+package models
+
+import (
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+)
+
+var (
+	FedDeploymentClusterManager *SFedDeploymentClusterManager
+	_                           IFedJointClusterModel = new(SFedDeploymentCluster)
+)
+
+func init() {
+	db.InitManager(func() {
+		FedDeploymentClusterManager = NewFedJointManager(func() db.IJointModelManager {
+			return &SFedDeploymentClusterManager{
+				SFedNamespaceJointClusterManager: NewFedNamespaceJointClusterManager(
+					SFedDeploymentCluster{},
+					"federateddeploymentclusters_tbl",
+					"federateddeploymentcluster",
+					"federateddeploymentclusters",
+					GetFedDeploymentManager(),
+					GetDeploymentManager(),
+				),
+			}
+		}).(*SFedDeploymentClusterManager)
+		GetFedDeploymentManager().SetJointModelManager(FedDeploymentClusterManager)
+		RegisterFedJointClusterManager(GetFedDeploymentManager(), FedDeploymentClusterManager)
+	})
+}
+
+// +onecloud:swagger-gen-model-singular=federateddeploymentcluster
+// +onecloud:swagger-gen-model-plural=federateddeploymentclusters
+type SFedDeploymentClusterManager struct {
+	SFedNamespaceJointClusterManager
+}
+
+type SFedDeploymentCluster struct {
+	SFedNamespaceJointObjectCluster
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"context"
+
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/mcclient"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+var (
+	fedDeploymentManager *SFedDeploymentManager
+	_                    IFedModelManager = new(SFedDeploymentManager)
+	_                    IFedObjectModel  = new(SFedDeployment)
+)
+
+func init() {
+	GetFedDeploymentManager()
+}
+
+func GetFedDeploymentManager() *SFedDeploymentManager {
+	if fedDeploymentManager == nil {
+		fedDeploymentManager = newModelManager(func() db.IModelManager {
+			return &SFedDeploymentManager{
+				SFedNamespaceResourceManager: NewFedNamespaceResourceManager(
+					SFedDeployment{},
+					"federateddeployments_tbl",
+					"federateddeployment",
+					"federateddeployments",
+				),
+			}
+		}).(*SFedDeploymentManager)
+	}
+	return fedDeploymentManager
+}
+
+// +onecloud:swagger-gen-model-singular=federateddeployment
+// +onecloud:swagger-gen-model-plural=federateddeployments
+type SFedDeploymentManager struct {
+	SFedNamespaceResourceManager
+}
+
+type SFedDeployment struct {
+	SFedObjectResource
+	Spec *api.FederatedDeploymentSpec `list:"user" update:"user" create:"required"`
+}
+
+func validateFedDeploymentObject(obj runtime.Object) error {
+	return GetDeploymentManager().ValidateDeploymentObject(obj.(*apps.Deployment))
+}
+
+func (m *SFedDeploymentManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerCred mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.FederatedDeploymentCreateInput) (*api.FederatedDeploymentCreateInput, error) {
+	if err := m.ValidateFedObjectCreateData(ctx, userCred, ownerCred, query, &input.FederatedNamespaceResourceCreateInput, input.Spec, validateFedDeploymentObject); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (obj *SFedDeployment) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.FederatedDeploymentUpdateInput) (*api.FederatedDeploymentUpdateInput, error) {
+	if err := obj.ValidateFedObjectUpdateData(ctx, userCred, query, &input.FedNamespaceResourceUpdateInput, input.Spec, validateFedDeploymentObject); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (obj *SFedDeployment) GetSpec() api.IFederatedObjectSpec {
+	return obj.Spec
+}
@@ -0,0 +1,458 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/api/legacyscheme"
+
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models/manager"
+	"yunion.io/x/kubecomps/pkg/utils/logclient"
+)
+
+const (
+	// fedDriftMaxDiffs limits fields recorded in drift_diff of joint model
+	fedDriftMaxDiffs = 20
+	fedDriftHidden   = "<hidden>"
+
+	// FedDriftMinCheckIntervalMinutes is lower bound of periodic drift check interval
+	FedDriftMinCheckIntervalMinutes = 1
+
+	fedDriftEnforceMinBackoff = time.Minute
+	fedDriftEnforceMaxBackoff = time.Hour
+)
+
+var (
+	fedDriftWorker  *appsrv.SWorkerManager
+	fedDriftBackoff = newFedDriftEnforceBackoff(fedDriftEnforceMinBackoff, fedDriftEnforceMaxBackoff)
+)
+
+func init() {
+	fedDriftWorker = appsrv.NewWorkerManager("FedDriftWorkerManager", 2, 1024, true)
+	// member object status updates are frequent, only check the latest one
+	fedDriftWorker.EnableCancelPreviousIdenticalTask()
+}
+
+// IFedRenderableModel renders the object expected inside member cluster
+type IFedRenderableModel interface {
+	NewK8sObject(objMeta metav1.ObjectMeta) (runtime.Object, error)
+}
+
+// renderFedClusterObject returns desired object of joint cluster, nil is returned when
+// federated resource can't be rendered and only existence of member object is checked
+func renderFedClusterObject(userCred mcclient.TokenCredential, fedObj IFedModel, jObj IFedJointClusterModel, cluster *SCluster) (runtime.Object, error) {
+	metaGetter, ok := fedObj.(interface {
+		GetK8sObjectMeta() metav1.ObjectMeta
+	})
+	if !ok {
+		return nil, nil
+	}
+	objMeta := metaGetter.GetK8sObjectMeta()
+	if GetFedResAPI().JointResAPI().IsNamespaceScope(jObj) {
+		clsNs, err := GetFedResAPI().JointResAPI().NamespaceScope().FetchClusterNamespace(userCred, jObj, cluster)
+		if err != nil {
+			return nil, errors.Wrap(err, "get cluster namespace")
+		}
+		objMeta.Namespace = clsNs.GetName()
+	}
+	if objModel, ok := fedObj.(IFedObjectModel); ok {
+		return RenderFedObjectForCluster(objModel, cluster.GetId(), objMeta)
+	}
+	if rModel, ok := fedObj.(IFedRenderableModel); ok {
+		return rModel.NewK8sObject(objMeta)
+	}
+	return nil, nil
+}
+
+func escapeJSONPointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+func fedDriftValueString(val interface{}, hidden bool) string {
+	if val == nil {
+		return "<none>"
+	}
+	if hidden {
+		return fedDriftHidden
+	}
+	out, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprintf("%v", val)
+	}
+	return string(out)
+}
+
+// diffFedObjectFields compares fields set in desired with actual,
+// fields only set by member cluster like defaults and status are ignored
+func diffFedObjectFields(path string, desired, actual interface{}, hidden bool, diffs *[]string) {
+	addDiff := func() {
+		*diffs = append(*diffs, fmt.Sprintf("%s: desired %s, actual %s", path, fedDriftValueString(desired, hidden), fedDriftValueString(actual, hidden)))
+	}
+	switch d := desired.(type) {
+	case nil:
+		return
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			if len(d) == 0 && actual == nil {
+				return
+			}
+			addDiff()
+			return
+		}
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			diffFedObjectFields(path+"/"+escapeJSONPointer(k), d[k], a[k], hidden, diffs)
+		}
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(d) {
+			if len(d) == 0 && actual == nil {
+				return
+			}
+			addDiff()
+			return
+		}
+		for i := range d {
+			diffFedObjectFields(fmt.Sprintf("%s/%d", path, i), d[i], a[i], hidden, diffs)
+		}
+	default:
+		if !reflect.DeepEqual(desired, actual) {
+			addDiff()
+		}
+	}
+}
+
+// DiffFedObject returns fields of actual object differ from desired object
+func DiffFedObject(desired, actual runtime.Object) ([]string, error) {
+	toMap := func(obj runtime.Object) (map[string]interface{}, error) {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		ret := make(map[string]interface{})
+		if err := json.Unmarshal(data, &ret); err != nil {
+			return nil, err
+		}
+		return ret, nil
+	}
+	// member cluster fills defaults like targetPort of service port, apply them to desired object as well
+	desired = desired.DeepCopyObject()
+	legacyscheme.Scheme.Default(desired)
+	dMap, err := toMap(desired)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert desired object")
+	}
+	aMap, err := toMap(actual)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert actual object")
+	}
+	delete(dMap, "status")
+	// only name, namespace, labels and annotations of metadata are rendered
+	if dMeta, ok := dMap["metadata"].(map[string]interface{}); ok {
+		for k := range dMeta {
+			if k != "labels" && k != "annotations" {
+				delete(dMeta, k)
+			}
+		}
+	}
+	_, hidden := desired.(*corev1.Secret)
+	diffs := make([]string, 0)
+	diffFedObjectFields("", dMap, aMap, hidden, &diffs)
+	return diffs, nil
+}
+
+// CheckFedJointDrift compares member cluster object of joint model with federated template
+func CheckFedJointDrift(userCred mcclient.TokenCredential, fedObj IFedModel, jObj IFedJointClusterModel) (string, []string, error) {
+	if jObj.GetResourceId() == "" {
+		return api.FederatedDriftStatusMissing, nil, nil
+	}
+	resObj, err := GetFedResAPI().JointResAPI().FetchResourceModel(jObj)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return api.FederatedDriftStatusMissing, nil, nil
+		}
+		return api.FederatedDriftStatusUnknown, nil, errors.Wrap(err, "fetch member resource")
+	}
+	remoteObj, err := resObj.GetRemoteObject()
+	if err != nil {
+		if kerrors.IsNotFound(errors.Cause(err)) {
+			return api.FederatedDriftStatusMissing, nil, nil
+		}
+		return api.FederatedDriftStatusUnknown, nil, errors.Wrapf(err, "get %s remote object", resObj.LogPrefix())
+	}
+	cluster, err := jObj.GetCluster()
+	if err != nil {
+		return api.FederatedDriftStatusUnknown, nil, errors.Wrap(err, "get cluster")
+	}
+	desired, err := renderFedClusterObject(userCred, fedObj, jObj, cluster)
+	if err != nil {
+		return api.FederatedDriftStatusUnknown, nil, errors.Wrap(err, "render desired object")
+	}
+	if desired == nil {
+		return api.FederatedDriftStatusInSync, nil, nil
+	}
+	diffs, err := DiffFedObject(desired, remoteObj.(runtime.Object))
+	if err != nil {
+		return api.FederatedDriftStatusUnknown, nil, err
+	}
+	if len(diffs) != 0 {
+		return api.FederatedDriftStatusDrifted, diffs, nil
+	}
+	return api.FederatedDriftStatusInSync, nil, nil
+}
+
+// fedDriftEnforceBackoff delays re-applying template to member object drifted repeatedly,
+// e.g. fields defaulted by member cluster are reported as drift again after every apply
+type fedDriftEnforceBackoff struct {
+	lock  sync.Mutex
+	min   time.Duration
+	max   time.Duration
+	items map[string]*fedDriftBackoffItem
+}
+
+type fedDriftBackoffItem struct {
+	attempts int
+	next     time.Time
+}
+
+func newFedDriftEnforceBackoff(min, max time.Duration) *fedDriftEnforceBackoff {
+	return &fedDriftEnforceBackoff{
+		min:   min,
+		max:   max,
+		items: make(map[string]*fedDriftBackoffItem),
+	}
+}
+
+// Allow reports whether re-apply of key is allowed at now, and doubles the delay of next one when allowed
+func (b *fedDriftEnforceBackoff) Allow(key string, now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	item, ok := b.items[key]
+	if !ok {
+		item = new(fedDriftBackoffItem)
+		b.items[key] = item
+	}
+	if now.Before(item.next) {
+		return false
+	}
+	delay := b.min << uint(item.attempts)
+	if delay > b.max || delay <= 0 {
+		delay = b.max
+	} else {
+		item.attempts++
+	}
+	item.next = now.Add(delay)
+	return true
+}
+
+// Reset clears delay of key after member object is in sync
+func (b *fedDriftEnforceBackoff) Reset(key string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.items, key)
+}
+
+func fedDriftBackoffKey(fedObj IFedModel, jObj IFedJointClusterModel) string {
+	return fmt.Sprintf("%s/%s/%s", fedObj.Keyword(), fedObj.GetId(), jObj.GetClusterId())
+}
+
+// isFedJointDetached checks joint model is still attached, member object is deleted by detaching
+// before the joint record removed, which should not be re-applied
+func isFedJointDetached(fedObj IFedModel, jObj IFedJointClusterModel) (bool, error) {
+	if fedObj.GetDeleted() {
+		return true, nil
+	}
+	if _, err := GetFederatedJointClusterModel(jObj.GetManager(), fedObj.GetId(), jObj.GetClusterId()); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// ReconcileFedJointDrift records drift status of joint model and re-applies template by drift policy
+func ReconcileFedJointDrift(ctx context.Context, userCred mcclient.TokenCredential, fedObj IFedModel, jObj IFedJointClusterModel) error {
+	status, diffs, err := CheckFedJointDrift(userCred, fedObj, jObj)
+	diff := ""
+	if err != nil {
+		diff = err.Error()
+	} else {
+		if len(diffs) > fedDriftMaxDiffs {
+			diffs = append(diffs[:fedDriftMaxDiffs], fmt.Sprintf("... %d more", len(diffs)-fedDriftMaxDiffs))
+		}
+		diff = strings.Join(diffs, "\n")
+	}
+	prevStatus := jObj.GetDriftStatus()
+	if err := jObj.SetDrift(status, diff); err != nil {
+		return err
+	}
+	backoffKey := fedDriftBackoffKey(fedObj, jObj)
+	if status == api.FederatedDriftStatusInSync {
+		fedDriftBackoff.Reset(backoffKey)
+	}
+	if status != api.FederatedDriftStatusDrifted && status != api.FederatedDriftStatusMissing {
+		return nil
+	}
+	if prevStatus != status {
+		logclient.LogWithContext(ctx, fedObj, logclient.ActionResourceDrift, fmt.Sprintf("cluster %s %s: %s", jObj.GetClusterId(), status, diff), userCred, false)
+	}
+	if fedObj.GetDriftPolicy() != api.FederatedDriftPolicyEnforce {
+		return nil
+	}
+	if detached, err := isFedJointDetached(fedObj, jObj); err != nil {
+		return errors.Wrap(err, "check joint cluster attached")
+	} else if detached {
+		return nil
+	}
+	if !fedDriftBackoff.Allow(backoffKey, time.Now()) {
+		log.Warningf("%s drift %s in cluster %s, re-apply is backed off", fedObj.LogPrefix(), status, jObj.GetClusterId())
+		return nil
+	}
+	log.Infof("%s drift %s in cluster %s, re-apply template", fedObj.LogPrefix(), status, jObj.GetClusterId())
+	input := api.FederatedResourceJointClusterInput{
+		ClusterId: jObj.GetClusterId(),
+	}
+	if err := GetFedResAPI().PerformSyncCluster(fedObj, ctx, userCred, input.JSON(input)); err != nil {
+		return errors.Wrapf(err, "re-apply to cluster %s", jObj.GetClusterId())
+	}
+	return nil
+}
+
+func reconcileFedJointsDrift(ctx context.Context, userCred mcclient.TokenCredential, jMan IFedJointClusterManager, q func() ([]IFedJointClusterModel, error)) {
+	jObjs, err := q()
+	if err != nil {
+		log.Errorf("fetch %s for drift reconcile error: %v", jMan.KeywordPlural(), err)
+		return
+	}
+	fedAPI := GetFedResAPI().JointResAPI()
+	for _, jObj := range jObjs {
+		cluster, err := jObj.GetCluster()
+		if err != nil {
+			log.Errorf("get %s cluster %s error: %v", jObj.Keyword(), jObj.GetClusterId(), err)
+			continue
+		}
+		// deleting clusters are skipped by status
+		if cluster.GetDeleted() || cluster.GetStatus() != api.ClusterStatusRunning {
+			continue
+		}
+		fedObj, err := fedAPI.FetchFedResourceModel(jObj)
+		if err != nil {
+			log.Errorf("get %s federated resource error: %v", jObj.Keyword(), err)
+			continue
+		}
+		if err := ReconcileFedJointDrift(ctx, userCred, fedObj, jObj); err != nil {
+			log.Errorf("reconcile %s drift in cluster %s error: %v", fedObj.LogPrefix(), cluster.GetName(), err)
+		}
+	}
+}
+
+func fetchFedJointModelsByQuery(jMan IFedJointClusterManager, q func(*sqlchemy.SQuery) *sqlchemy.SQuery) ([]IFedJointClusterModel, error) {
+	objs := make([]interface{}, 0)
+	if err := db.FetchModelObjects(jMan, q(jMan.Query()), &objs); err != nil {
+		return nil, err
+	}
+	ret := make([]IFedJointClusterModel, len(objs))
+	for i := range objs {
+		ret[i] = GetObjectPtr(objs[i]).(IFedJointClusterModel)
+	}
+	return ret, nil
+}
+
+// ReconcileFederatedResourcesDrift checks every member object of federated resources periodically
+func ReconcileFederatedResourcesDrift(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	for _, fedMan := range GetFedManagers() {
+		jMan := fedMan.GetJointModelManager()
+		if jMan == nil {
+			continue
+		}
+		reconcileFedJointsDrift(ctx, userCred, jMan, func() ([]IFedJointClusterModel, error) {
+			return fetchFedJointModelsByQuery(jMan, func(q *sqlchemy.SQuery) *sqlchemy.SQuery {
+				return q
+			})
+		})
+	}
+}
+
+// OnFedMemberObjectChanged triggers drift reconcile of joint models whose member object
+// changed or deleted inside cluster
+func OnFedMemberObjectChanged(cluster manager.ICluster, resMan IClusterModelManager, obj runtime.Object) {
+	metaObj, ok := obj.(metav1.Object)
+	if !ok {
+		return
+	}
+	for _, jMan := range getFedJointManagersByResource(resMan) {
+		fedDriftWorker.Run(newFedDriftTask(cluster.GetId(), jMan, metaObj.GetNamespace(), metaObj.GetName()), nil, nil)
+	}
+}
+
+func getFedJointManagersByResource(resMan IClusterModelManager) []IFedJointClusterManager {
+	ret := make([]IFedJointClusterManager, 0)
+	for _, jMan := range globalFedJointClusterManagers {
+		if jMan.GetResourceManager().Keyword() == resMan.Keyword() {
+			ret = append(ret, jMan)
+		}
+	}
+	return ret
+}
+
+type fedDriftTask struct {
+	clusterId string
+	jMan      IFedJointClusterManager
+	namespace string
+	name      string
+}
+
+func newFedDriftTask(clusterId string, jMan IFedJointClusterManager, namespace, name string) *fedDriftTask {
+	return &fedDriftTask{
+		clusterId: clusterId,
+		jMan:      jMan,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+func (t *fedDriftTask) Run() {
+	ctx := context.Background()
+	userCred := auth.AdminCredential()
+	reconcileFedJointsDrift(ctx, userCred, t.jMan, func() ([]IFedJointClusterModel, error) {
+		return fetchFedJointModelsByQuery(t.jMan, func(q *sqlchemy.SQuery) *sqlchemy.SQuery {
+			// member object has same name with federated resource
+			fedQ := t.jMan.GetFedManager().Query("id").Equals("name", t.name)
+			if t.namespace != "" {
+				nsSq := GetFedNamespaceManager().Query("id").Equals("name", t.namespace).SubQuery()
+				fedQ = fedQ.In("federatednamespace_id", nsSq)
+			}
+			return q.Equals("cluster_id", t.clusterId).In("federatedresource_id", fedQ.SubQuery())
+		})
+	})
+}
+
+func (t *fedDriftTask) Dump() string {
+	return fmt.Sprintf("fedDriftTask %s cluster %s %s/%s", t.jMan.Keyword(), t.clusterId, t.namespace, t.name)
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	_ "k8s.io/kubernetes/pkg/apis/core/install"
+)
+
+func TestDiffFedObject(t *testing.T) {
+	replicas := int32(2)
+	desired := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "web"},
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "web", Image: "nginx:1.19"}},
+				},
+			},
+		},
+	}
+	newActual := func() *apps.Deployment {
+		actual := desired.DeepCopy()
+		// fields set by cluster should be ignored
+		actual.ResourceVersion = "100"
+		actual.UID = "uid"
+		actual.Labels["pod-template-hash"] = "abc"
+		actual.Spec.Strategy.Type = apps.RollingUpdateDeploymentStrategyType
+		actual.Spec.Template.Spec.Containers[0].TerminationMessagePath = "/dev/termination-log"
+		actual.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyAlways
+		actual.Status.ReadyReplicas = 2
+		return actual
+	}
+
+	diffs, err := DiffFedObject(desired, newActual())
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("defaults should not drift, got %v", diffs)
+	}
+
+	actual := newActual()
+	scaled := int32(5)
+	actual.Spec.Replicas = &scaled
+	actual.Spec.Template.Spec.Containers[0].Image = "nginx:latest"
+	delete(actual.Labels, "app")
+	diffs, err = DiffFedObject(desired, actual)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	want := []string{
+		`/metadata/labels/app: desired "web", actual <none>`,
+		`/spec/replicas: desired 2, actual 5`,
+		`/spec/template/spec/containers/0/image: desired "nginx:1.19", actual "nginx:latest"`,
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("diffs = %#v, want %#v", diffs, want)
+	}
+
+	actual = newActual()
+	actual.Spec.Template.Spec.Containers = append(actual.Spec.Template.Spec.Containers, corev1.Container{Name: "sidecar"})
+	diffs, _ = DiffFedObject(desired, actual)
+	if len(diffs) != 1 {
+		t.Errorf("added container should drift, got %v", diffs)
+	}
+}
+
+func TestDiffFedObjectServiceDefaults(t *testing.T) {
+	desired := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "web"},
+			Ports:    []corev1.ServicePort{{Name: "http", Port: 80}},
+		},
+	}
+	actual := desired.DeepCopy()
+	actual.Spec.Type = corev1.ServiceTypeClusterIP
+	actual.Spec.ClusterIP = "10.96.0.10"
+	actual.Spec.SessionAffinity = corev1.ServiceAffinityNone
+	actual.Spec.Ports[0].Protocol = corev1.ProtocolTCP
+	actual.Spec.Ports[0].TargetPort = intstr.FromInt(80)
+
+	diffs, err := DiffFedObject(desired, actual)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("service defaults should not drift, got %v", diffs)
+	}
+	if desired.Spec.Ports[0].TargetPort.IntValue() != 0 {
+		t.Errorf("desired object should not be modified")
+	}
+
+	actual.Spec.Ports[0].TargetPort = intstr.FromInt(8080)
+	diffs, _ = DiffFedObject(desired, actual)
+	want := []string{"/spec/ports/0/targetPort: desired 80, actual 8080"}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("diffs = %#v, want %#v", diffs, want)
+	}
+}
+
+func TestDiffFedObjectHideSecret(t *testing.T) {
+	desired := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "auth"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	actual := desired.DeepCopy()
+	actual.Type = corev1.SecretTypeOpaque
+	actual.Data["password"] = []byte("changed")
+	diffs, err := DiffFedObject(desired, actual)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	want := []string{"/data/password: desired <hidden>, actual <hidden>"}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("diffs = %#v, want %#v", diffs, want)
+	}
+}
+
+func TestEscapeJSONPointer(t *testing.T) {
+	if got := escapeJSONPointer("app.kubernetes.io/name"); got != "app.kubernetes.io~1name" {
+		t.Errorf("escape = %s", got)
+	}
+	if got := escapeJSONPointer("a~b"); got != "a~0b" {
+		t.Errorf("escape = %s", got)
+	}
+}
+
+func TestFedDriftEnforceBackoff(t *testing.T) {
+	b := newFedDriftEnforceBackoff(time.Minute, 4*time.Minute)
+	now := time.Now()
+	if !b.Allow("obj", now) {
+		t.Fatalf("first re-apply should be allowed")
+	}
+	if b.Allow("obj", now.Add(30*time.Second)) {
+		t.Errorf("re-apply within backoff should be skipped")
+	}
+	// delays are 1m, 2m, 4m and capped at 4m
+	now = now.Add(time.Minute)
+	for _, delay := range []time.Duration{2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		if !b.Allow("obj", now) {
+			t.Fatalf("re-apply at %s should be allowed", now)
+		}
+		if b.Allow("obj", now.Add(delay-time.Second)) {
+			t.Errorf("re-apply before %s should be skipped", delay)
+		}
+		now = now.Add(delay)
+	}
+	b.Reset("obj")
+	if !b.Allow("obj", now) {
+		t.Errorf("re-apply after reset should be allowed")
+	}
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/onecloud/pkg/apis"
@@ -79,6 +80,8 @@ type IFedJointClusterModel interface {
 	GetCluster() (*SCluster, error)
 	GetResourceManager() IClusterModelManager
 	SetResource(resObj IClusterModel) error
+	GetDriftStatus() string
+	SetDrift(status string, diff string) error
 	GetResourceCreateData(ctx context.Context, userCred mcclient.TokenCredential, fedObj IFedModel, baseInput api.NamespaceResourceCreateInput) (jsonutils.JSONObject, error)
 	GetResourceUpdateData(ctx context.Context, userCred mcclient.TokenCredential, fedObj IFedModel, resObj IClusterModel, baseInput api.NamespaceResourceUpdateInput) (jsonutils.JSONObject, error)
 	GetDetails(base api.FedJointClusterResourceDetails, isList bool) interface{}
@@ -131,6 +134,12 @@ type SFedJointCluster struct {
 	ClusterId           string `width:"36" charset:"ascii" nullable:"false" list:"user" create:"required" index:"true"`
 	// NamespaceId should be calculated by cluster and federated resource
 	ResourceId string `width:"36" charset:"ascii" list:"user" index:"true"`
+
+	// DriftStatus is result of comparing member cluster object with federated template
+	DriftStatus string `width:"16" charset:"ascii" nullable:"false" default:"unknown" list:"user"`
+	// DriftDiff records fields of member cluster object differ from federated template
+	DriftDiff      string    `length:"0" charset:"utf8" list:"user"`
+	DriftCheckedAt time.Time `list:"user"`
 }
 
 func (m SFedJointClusterManager) GetFedManager() IFedModelManager {
@@ -200,6 +209,23 @@ func (obj *SFedJointCluster) SetResource(resObj IClusterModel) error {
 	return nil
 }
 
+func (obj *SFedJointCluster) GetDriftStatus() string {
+	return obj.DriftStatus
+}
+
+func (obj *SFedJointCluster) SetDrift(status string, diff string) error {
+	_, err := db.Update(obj, func() error {
+		obj.DriftStatus = status
+		obj.DriftDiff = diff
+		obj.DriftCheckedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "set drift status")
+	}
+	return nil
+}
+
 func (m *SFedJointClusterManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, input *api.FedJointClusterListInput) (*sqlchemy.SQuery, error) {
 	q, err := m.SFedJointResourceBaseManager.ListItemFilter(ctx, q, userCred, input.JointResourceBaseListInput)
 	if err != nil {
@@ -235,6 +261,9 @@ func (m *SFedJointClusterManager) ListItemFilter(ctx context.Context, q *sqlchem
 		rsq := m.GetResourceManager().Query("id").Contains("name", input.ResourceName).SubQuery()
 		q = q.In("resource_id", rsq)
 	}
+	if len(input.DriftStatus) > 0 {
+		q = q.In("drift_status", input.DriftStatus)
+	}
 	/*
 	 * if len(input.NamespaceId) > 0 {
 	 *     nsObj, err := GetNamespaceManager().FetchByIdOrName(userCred, input.NamespaceId)
@@ -4,6 +4,8 @@ import (
 	"context"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/kubernetes/pkg/api/legacyscheme"
 	"k8s.io/kubernetes/pkg/apis/core"
 	"k8s.io/kubernetes/pkg/apis/core/validation"
@@ -153,3 +155,11 @@ func (obj *SFedNamespace) ValidateUpdateData(ctx context.Context, userCred mccli
 	}
 	return input, nil
 }
+
+// NewK8sObject renders template to kubernetes object expected inside member cluster
+func (obj *SFedNamespace) NewK8sObject(objMeta metav1.ObjectMeta) (runtime.Object, error) {
+	if obj.Spec == nil {
+		return nil, errors.Errorf("%s spec is empty", obj.LogPrefix())
+	}
+	return obj.Spec.ToNamespace(objMeta), nil
+}
@@ -0,0 +1,410 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+// IFedObjectModel is federated resource applied to member clusters as rendered kubernetes object,
+// instead of creating cluster resource by create input like federated roles
+type IFedObjectModel interface {
+	IFedNamespaceModel
+
+	GetK8sObjectMeta() metav1.ObjectMeta
+	// GetSpec returns template and policy of the object
+	GetSpec() api.IFederatedObjectSpec
+	// GetPolicy get placement and per cluster overrides
+	GetPolicy() api.FederatedPolicySpec
+	// NewK8sObject render template to kubernetes object without overrides
+	NewK8sObject(objMeta metav1.ObjectMeta) (runtime.Object, error)
+}
+
+type SFedObjectResource struct {
+	SFedNamespaceResource
+}
+
+// ValidateFedObjectCreateData validates create input of IFedObjectModel with the template rendered from spec
+func (m *SFedNamespaceResourceManager) ValidateFedObjectCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerCred mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.FederatedNamespaceResourceCreateInput, spec api.IFederatedObjectSpec, validateFunc func(runtime.Object) error) error {
+	nInput, err := m.ValidateCreateData(ctx, userCred, ownerCred, query, input)
+	if err != nil {
+		return err
+	}
+	*input = *nInput
+	if spec.IsZero() {
+		return httperrors.NewNotEmptyError("spec is empty")
+	}
+	tmpl, err := spec.ToK8sObject(input.ToObjectMeta(input.Federatednamespace))
+	if err != nil {
+		return httperrors.NewInputParameterError("%v", err)
+	}
+	return ValidateFedObjectPolicy(ctx, userCred, spec.GetPolicy(), tmpl, validateFunc)
+}
+
+// ValidateFedObjectUpdateData validates update input of IFedObjectModel, spec is optional when updating
+func (obj *SFedObjectResource) ValidateFedObjectUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.FedNamespaceResourceUpdateInput, spec api.IFederatedObjectSpec, validateFunc func(runtime.Object) error) error {
+	bInput, err := obj.SFedNamespaceResource.ValidateUpdateData(ctx, userCred, query, input)
+	if err != nil {
+		return err
+	}
+	*input = *bInput
+	if spec.IsZero() {
+		return nil
+	}
+	tmpl, err := spec.ToK8sObject(obj.GetK8sObjectMeta())
+	if err != nil {
+		return httperrors.NewInputParameterError("%v", err)
+	}
+	return ValidateFedObjectPolicy(ctx, userCred, spec.GetPolicy(), tmpl, validateFunc)
+}
+
+func (obj *SFedObjectResource) getSpec() api.IFederatedObjectSpec {
+	return obj.GetVirtualObject().(IFedObjectModel).GetSpec()
+}
+
+func (obj *SFedObjectResource) GetPolicy() api.FederatedPolicySpec {
+	spec := obj.getSpec()
+	if spec.IsZero() {
+		return api.FederatedPolicySpec{}
+	}
+	return *spec.GetPolicy()
+}
+
+func (obj *SFedObjectResource) NewK8sObject(objMeta metav1.ObjectMeta) (runtime.Object, error) {
+	spec := obj.getSpec()
+	if spec.IsZero() {
+		return nil, errors.Errorf("%s spec is empty", obj.LogPrefix())
+	}
+	return spec.ToK8sObject(objMeta)
+}
+
+func (obj *SFedObjectResource) GetDetails(base interface{}, isList bool) interface{} {
+	return GetFedObjectDetails(obj.GetVirtualObject().(IFedObjectModel), obj.SFedNamespaceResource.GetDetails(base, isList), isList)
+}
+
+var fedOverridePatchOps = []string{"add", "remove", "replace", "move", "copy", "test"}
+
+// RenderFedObject applies json patches to object and returns a new object with same type
+func RenderFedObject(obj runtime.Object, patches []api.FederatedOverridePatch) (runtime.Object, error) {
+	if len(patches) == 0 {
+		return obj.DeepCopyObject(), nil
+	}
+	doc, err := json.Marshal(obj)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal object")
+	}
+	ops := jsonutils.NewArray()
+	for _, p := range patches {
+		op := jsonutils.NewDict()
+		op.Add(jsonutils.NewString(p.Op), "op")
+		op.Add(jsonutils.NewString(p.Path), "path")
+		if p.Value != nil {
+			op.Add(p.Value, "value")
+		}
+		ops.Add(op)
+	}
+	patch, err := jsonpatch.DecodePatch([]byte(ops.String()))
+	if err != nil {
+		return nil, errors.Wrap(err, "decode json patch")
+	}
+	patched, err := patch.Apply(doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "apply json patch")
+	}
+	out := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(runtime.Object)
+	if err := json.Unmarshal(patched, out); err != nil {
+		return nil, errors.Wrap(err, "unmarshal patched object")
+	}
+	oldMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, errors.Wrap(err, "get object meta")
+	}
+	newMeta, err := meta.Accessor(out)
+	if err != nil {
+		return nil, errors.Wrap(err, "get patched object meta")
+	}
+	if oldMeta.GetName() != newMeta.GetName() || oldMeta.GetNamespace() != newMeta.GetNamespace() {
+		return nil, errors.Errorf("overrides can not change name or namespace")
+	}
+	return out, nil
+}
+
+// RenderFedObjectForCluster renders federated object template with the overrides of cluster
+func RenderFedObjectForCluster(obj IFedObjectModel, clusterId string, objMeta metav1.ObjectMeta) (runtime.Object, error) {
+	tmpl, err := obj.NewK8sObject(objMeta)
+	if err != nil {
+		return nil, errors.Wrap(err, "render template")
+	}
+	return RenderFedObject(tmpl, obj.GetPolicy().GetClusterOverride(clusterId))
+}
+
+// ValidateFedObjectPolicy validates template, normalizes cluster id of placement and overrides,
+// and checks the object rendered for every override cluster
+func ValidateFedObjectPolicy(ctx context.Context, userCred mcclient.TokenCredential, policy *api.FederatedPolicySpec, tmpl runtime.Object, validateFunc func(runtime.Object) error) error {
+	if err := validateFunc(tmpl); err != nil {
+		return err
+	}
+	if policy.Placement != nil {
+		clusterIds := make([]string, 0)
+		for _, id := range policy.Placement.Clusters {
+			cluster, err := GetClusterManager().GetClusterByIdOrName(ctx, userCred, id)
+			if err != nil {
+				return err
+			}
+			if !utils.IsInStringArray(cluster.GetId(), clusterIds) {
+				clusterIds = append(clusterIds, cluster.GetId())
+			}
+		}
+		policy.Placement.Clusters = clusterIds
+		if policy.Placement.ClusterSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(policy.Placement.ClusterSelector); err != nil {
+				return httperrors.NewInputParameterError("invalid clusterSelector: %v", err)
+			}
+		}
+	}
+	overrideClusters := make([]string, 0)
+	for i := range policy.Overrides {
+		override := &policy.Overrides[i]
+		cluster, err := GetClusterManager().GetClusterByIdOrName(ctx, userCred, override.Cluster)
+		if err != nil {
+			return err
+		}
+		if utils.IsInStringArray(cluster.GetId(), overrideClusters) {
+			return httperrors.NewInputParameterError("duplicate overrides of cluster %s", cluster.GetName())
+		}
+		overrideClusters = append(overrideClusters, cluster.GetId())
+		override.Cluster = cluster.GetId()
+		for _, p := range override.Patches {
+			if !utils.IsInStringArray(p.Op, fedOverridePatchOps) {
+				return httperrors.NewInputParameterError("cluster %s override op %q not in %v", cluster.GetName(), p.Op, fedOverridePatchOps)
+			}
+			if !strings.HasPrefix(p.Path, "/") {
+				return httperrors.NewInputParameterError("cluster %s override path %q should start with /", cluster.GetName(), p.Path)
+			}
+		}
+		obj, err := RenderFedObject(tmpl, override.Patches)
+		if err != nil {
+			return httperrors.NewInputParameterError("render cluster %s overrides: %v", cluster.GetName(), err)
+		}
+		if err := validateFunc(obj); err != nil {
+			return errors.Wrapf(err, "validate cluster %s overrides", cluster.GetName())
+		}
+	}
+	return nil
+}
+
+func matchFedClusterSelector(selector labels.Selector, tags map[string]string) bool {
+	if selector.Empty() {
+		return true
+	}
+	return selector.Matches(labels.Set(tags))
+}
+
+// GetFedObjectPlacementClusters returns clusters selected by placement,
+// isSet is false when placement is empty and clusters are attached manually
+func GetFedObjectPlacementClusters(obj IFedObjectModel) ([]SCluster, bool, error) {
+	placement := obj.GetPolicy().Placement
+	if placement.IsEmpty() {
+		return nil, false, nil
+	}
+	ret := make([]SCluster, 0)
+	clusterIds := make([]string, 0)
+	for _, id := range placement.Clusters {
+		cluster, err := GetClusterManager().GetCluster(id)
+		if err != nil {
+			if errors.Cause(err) == sql.ErrNoRows {
+				log.Warningf("%s placement cluster %s not found", obj.LogPrefix(), id)
+				continue
+			}
+			return nil, true, errors.Wrapf(err, "get cluster %s", id)
+		}
+		ret = append(ret, *cluster)
+		clusterIds = append(clusterIds, cluster.GetId())
+	}
+	if placement.ClusterSelector == nil {
+		return ret, true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(placement.ClusterSelector)
+	if err != nil {
+		return nil, true, errors.Wrap(err, "invalid cluster selector")
+	}
+	clusters := make([]SCluster, 0)
+	q := GetClusterManager().Query().Equals("domain_id", obj.GetOwnerId().GetProjectDomainId())
+	if err := db.FetchModelObjects(GetClusterManager(), q, &clusters); err != nil {
+		return nil, true, errors.Wrap(err, "fetch clusters")
+	}
+	for i := range clusters {
+		cluster := clusters[i]
+		if utils.IsInStringArray(cluster.GetId(), clusterIds) {
+			continue
+		}
+		tags, err := cluster.GetAllUserMetadata()
+		if err != nil {
+			return nil, true, errors.Wrapf(err, "get cluster %s tags", cluster.GetName())
+		}
+		if matchFedClusterSelector(selector, tags) {
+			ret = append(ret, cluster)
+		}
+	}
+	return ret, true, nil
+}
+
+func fetchFedJointClusterModels(obj IFedModel) ([]IFedJointClusterModel, error) {
+	jMan := obj.GetJointModelManager()
+	objs := make([]interface{}, 0)
+	q := jMan.Query().Equals(jMan.GetMasterFieldName(), obj.GetId())
+	if err := db.FetchModelObjects(jMan, q, &objs); err != nil {
+		return nil, err
+	}
+	ret := make([]IFedJointClusterModel, len(objs))
+	for i := range objs {
+		ret[i] = GetObjectPtr(objs[i]).(IFedJointClusterModel)
+	}
+	return ret, nil
+}
+
+func getWorkloadReplicas(obj runtime.Object) (*int32, *int32) {
+	switch o := obj.(type) {
+	case *apps.Deployment:
+		return o.Spec.Replicas, &o.Status.ReadyReplicas
+	case *apps.StatefulSet:
+		return o.Spec.Replicas, &o.Status.ReadyReplicas
+	}
+	return nil, nil
+}
+
+func addReplicas(sum **int32, val *int32) {
+	if val == nil {
+		return
+	}
+	if *sum == nil {
+		*sum = new(int32)
+	}
+	**sum += *val
+}
+
+// GetFedObjectDetails rolls up status of resources propagated to member clusters
+func GetFedObjectDetails(obj IFedObjectModel, base interface{}, isList bool) api.FederatedObjectDetails {
+	out := api.FederatedObjectDetails{
+		FederatedNamespaceResourceDetails: base.(api.FederatedNamespaceResourceDetails),
+	}
+	jObjs, err := fetchFedJointClusterModels(obj)
+	if err != nil {
+		log.Errorf("fetch %s joint clusters error: %v", obj.LogPrefix(), err)
+		return out
+	}
+	jointAPI := GetFedResAPI().JointResAPI()
+	out.ClusterStatuses = make([]api.FederatedClusterStatus, 0, len(jObjs))
+	for _, jObj := range jObjs {
+		status := api.FederatedClusterStatus{
+			ClusterId:   jObj.GetClusterId(),
+			ResourceId:  jObj.GetResourceId(),
+			Status:      api.FederatedResourceStatusNotBind,
+			DriftStatus: jObj.GetDriftStatus(),
+		}
+		if cluster, err := jObj.GetCluster(); err != nil {
+			log.Errorf("get cluster %s error: %v", jObj.GetClusterId(), err)
+		} else {
+			status.Cluster = cluster.GetName()
+		}
+		if jObj.GetResourceId() == "" {
+			out.NotBindClusterCount++
+			out.ClusterStatuses = append(out.ClusterStatuses, status)
+			continue
+		}
+		resObj, err := jointAPI.FetchResourceModel(jObj)
+		if err != nil {
+			if errors.Cause(err) != sql.ErrNoRows {
+				log.Errorf("fetch %s resource of cluster %s error: %v", obj.LogPrefix(), jObj.GetClusterId(), err)
+			}
+			out.NotBindClusterCount++
+			out.ClusterStatuses = append(out.ClusterStatuses, status)
+			continue
+		}
+		status.Status = resObj.GetStatus()
+		if !isList {
+			// replicas are read from informer cache, only fetched when getting details
+			if remoteObj, err := resObj.GetRemoteObject(); err != nil {
+				log.Warningf("get %s remote object error: %v", resObj.LogPrefix(), err)
+			} else {
+				status.DesiredReplicas, status.ReadyReplicas = getWorkloadReplicas(remoteObj.(runtime.Object))
+				addReplicas(&out.DesiredReplicas, status.DesiredReplicas)
+				addReplicas(&out.ReadyReplicas, status.ReadyReplicas)
+			}
+		}
+		out.ClusterStatuses = append(out.ClusterStatuses, status)
+	}
+	return out
+}
+
+func (obj *SFedObjectResource) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	obj.SFedNamespaceResource.PostCreate(ctx, userCred, ownerId, query, data)
+	elemObj, err := obj.GetElemModel()
+	if err != nil {
+		log.Errorf("get %s elem model error: %v", obj.LogPrefix(), err)
+		return
+	}
+	// propagate to clusters selected by placement
+	if err := GetFedResAPI().StartSyncTask(elemObj, ctx, userCred, jsonutils.NewDict(), ""); err != nil {
+		log.Errorf("StartSyncTask %s error: %v", obj.LogPrefix(), err)
+	}
+}
+
+// SFedNamespaceJointObjectCluster is joint model of IFedObjectModel,
+// member resource is applied by IFedJointResAPI.ReconcileResource directly
+type SFedNamespaceJointObjectCluster struct {
+	SFederatedNamespaceJointCluster
+}
+
+func (obj *SFedNamespaceJointObjectCluster) GetResourceCreateData(ctx context.Context, userCred mcclient.TokenCredential, fObj IFedModel, base api.NamespaceResourceCreateInput) (jsonutils.JSONObject, error) {
+	return nil, httperrors.NewNotSupportedError("%s is applied as kubernetes object", fObj.Keyword())
+}
+
+func (obj *SFedNamespaceJointObjectCluster) GetResourceUpdateData(ctx context.Context, userCred mcclient.TokenCredential, fObj IFedModel, resObj IClusterModel, base api.NamespaceResourceUpdateInput) (jsonutils.JSONObject, error) {
+	return nil, httperrors.NewNotSupportedError("%s is applied as kubernetes object", fObj.Keyword())
+}
+
+// Detach deletes resource propagated to member cluster before removing joint record
+func (obj *SFedNamespaceJointObjectCluster) Detach(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if err := obj.DeleteClusterObject(); err != nil {
+		return errors.Wrap(err, "delete cluster object")
+	}
+	return db.DetachJoint(ctx, userCred, obj.GetIJointModel())
+}
+
+// DeleteClusterObject deletes resource propagated to member cluster
+func (obj *SFedNamespaceJointObjectCluster) DeleteClusterObject() error {
+	if obj.ResourceId == "" {
+		return nil
+	}
+	resObj, err := FetchClusterResourceById(obj.GetResourceManager(), obj.ClusterId, "", obj.ResourceId)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil
+		}
+		return errors.Wrapf(err, "fetch cluster %s resource %s", obj.ClusterId, obj.ResourceId)
+	}
+	if err := resObj.DeleteRemoteObject(); err != nil {
+		return errors.Wrapf(err, "delete %s remote object", resObj.LogPrefix())
+	}
+	return nil
+}
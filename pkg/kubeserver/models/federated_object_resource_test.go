@@ -0,0 +1,157 @@
+package models
+
+import (
+	"testing"
+
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+func TestRenderFedObject(t *testing.T) {
+	replicas := int32(1)
+	tmpl := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "web", Image: "nginx:1.19"}},
+				},
+			},
+		},
+	}
+	out, err := RenderFedObject(tmpl, []api.FederatedOverridePatch{
+		{Op: "replace", Path: "/spec/replicas", Value: jsonutils.NewInt(3)},
+		{Op: "replace", Path: "/spec/template/spec/containers/0/image", Value: jsonutils.NewString("nginx:1.20")},
+		{Op: "add", Path: "/metadata/labels", Value: jsonutils.Marshal(map[string]string{"region": "east"})},
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	deploy := out.(*apps.Deployment)
+	if *deploy.Spec.Replicas != 3 {
+		t.Errorf("replicas = %d, want 3", *deploy.Spec.Replicas)
+	}
+	if img := deploy.Spec.Template.Spec.Containers[0].Image; img != "nginx:1.20" {
+		t.Errorf("image = %s, want nginx:1.20", img)
+	}
+	if deploy.Labels["region"] != "east" {
+		t.Errorf("labels = %v", deploy.Labels)
+	}
+	if *tmpl.Spec.Replicas != 1 || tmpl.Spec.Template.Spec.Containers[0].Image != "nginx:1.19" {
+		t.Errorf("template should not be changed")
+	}
+
+	if _, err := RenderFedObject(tmpl, []api.FederatedOverridePatch{
+		{Op: "replace", Path: "/metadata/name", Value: jsonutils.NewString("other")},
+	}); err == nil {
+		t.Errorf("rename by overrides should fail")
+	}
+	if _, err := RenderFedObject(tmpl, []api.FederatedOverridePatch{
+		{Op: "remove", Path: "/spec/notexists"},
+	}); err == nil {
+		t.Errorf("remove not exists path should fail")
+	}
+}
+
+func TestMatchFedClusterSelector(t *testing.T) {
+	cases := []struct {
+		selector *metav1.LabelSelector
+		tags     map[string]string
+		want     bool
+	}{
+		{
+			selector: &metav1.LabelSelector{},
+			tags:     nil,
+			want:     true,
+		},
+		{
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			tags:     map[string]string{"env": "prod", "region": "east"},
+			want:     true,
+		},
+		{
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			tags:     map[string]string{"env": "dev"},
+			want:     false,
+		},
+		{
+			selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "region", Operator: metav1.LabelSelectorOpIn, Values: []string{"east", "west"}},
+				},
+			},
+			tags: map[string]string{"region": "west"},
+			want: true,
+		},
+	}
+	for _, c := range cases {
+		sel, err := metav1.LabelSelectorAsSelector(c.selector)
+		if err != nil {
+			t.Fatalf("selector %v: %v", c.selector, err)
+		}
+		if got := matchFedClusterSelector(sel, c.tags); got != c.want {
+			t.Errorf("selector %v match %v = %v, want %v", c.selector, c.tags, got, c.want)
+		}
+	}
+	if !matchFedClusterSelector(labels.Everything(), map[string]string{"a": "b"}) {
+		t.Errorf("everything selector should match")
+	}
+}
+
+func TestFederatedSecretSpecToSecret(t *testing.T) {
+	spec := &api.FederatedSecretSpec{
+		Template: api.SecretTemplate{
+			Data:       map[string]string{"user": "YWRtaW4="},
+			StringData: map[string]string{"password": "secret"},
+		},
+	}
+	secret, err := spec.ToSecret(metav1.ObjectMeta{Name: "auth"})
+	if err != nil {
+		t.Fatalf("to secret: %v", err)
+	}
+	if secret.Type != corev1.SecretTypeOpaque {
+		t.Errorf("type = %s", secret.Type)
+	}
+	if string(secret.Data["user"]) != "admin" || string(secret.Data["password"]) != "secret" {
+		t.Errorf("data = %v", secret.Data)
+	}
+	spec.Template.Data["bad"] = "!!"
+	if _, err := spec.ToSecret(metav1.ObjectMeta{Name: "auth"}); err == nil {
+		t.Errorf("invalid base64 data should fail")
+	}
+}
+
+func TestEncryptFedSecretSpec(t *testing.T) {
+	spec := &api.FederatedSecretSpec{
+		Template: api.SecretTemplate{
+			Data:       map[string]string{"user": "YWRtaW4="},
+			StringData: map[string]string{"password": "secret"},
+		},
+	}
+	if err := encryptFedSecretSpec("fed-secret-id", spec); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if len(spec.Template.StringData) != 0 || len(spec.Template.Data) != 2 {
+		t.Fatalf("string data should be merged into data: %#v", spec.Template)
+	}
+	if spec.Template.Data["user"] == "YWRtaW4=" {
+		t.Errorf("data should be stored encrypted")
+	}
+	obj := &SFedSecret{Spec: spec}
+	obj.Id = "fed-secret-id"
+	out, err := obj.GetSpec().ToK8sObject(metav1.ObjectMeta{Name: "auth"})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	secret := out.(*corev1.Secret)
+	if string(secret.Data["user"]) != "admin" || string(secret.Data["password"]) != "secret" {
+		t.Errorf("data = %v", secret.Data)
+	}
+}
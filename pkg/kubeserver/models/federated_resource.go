@@ -15,6 +15,7 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
 	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
 	"yunion.io/x/sqlchemy"
 
 	"yunion.io/x/kubecomps/pkg/kubeserver/api"
@@ -39,6 +40,7 @@ type IFedModel interface {
 	ValidateDetachCluster(ctx context.Context, userCred mcclient.TokenCredential, data jsonutils.JSONObject) (jsonutils.JSONObject, error)
 	SetStatus(ctx context.Context, userCred mcclient.TokenCredential, status string, reason string) error
 	LogPrefix() string
+	GetDriftPolicy() string
 }
 
 // +onecloud:swagger-gen-ignore
@@ -49,6 +51,9 @@ type SFedResourceBaseManager struct {
 
 type SFedResourceBase struct {
 	db.SStatusDomainLevelResourceBase
+
+	// DriftPolicy decides whether re-apply template when member cluster object drifted
+	DriftPolicy string `width:"16" charset:"ascii" nullable:"false" default:"report" list:"user" create:"optional" update:"user"`
 }
 
 func NewFedResourceBaseManager(
@@ -75,6 +80,13 @@ func (m *SFedResourceBase) GetJointModelManager() IFedJointClusterManager {
 	return m.GetManager().GetJointModelManager()
 }
 
+func (obj *SFedResourceBase) GetDriftPolicy() string {
+	if obj.DriftPolicy == "" {
+		return api.FederatedDriftPolicyReport
+	}
+	return obj.DriftPolicy
+}
+
 func (obj *SFedResourceBase) LogPrefix() string {
 	return fmt.Sprintf("Federated %s %s(%s)", obj.Keyword(), obj.GetName(), obj.GetId())
 }
@@ -89,6 +101,12 @@ func (m *SFedResourceBaseManager) ValidateCreateData(ctx context.Context, userCr
 		return nil, err
 	}
 	input.StatusDomainLevelResourceCreateInput = dInput
+	if input.DriftPolicy == "" {
+		input.DriftPolicy = api.FederatedDriftPolicyReport
+	}
+	if !utils.IsInStringArray(input.DriftPolicy, api.FederatedDriftPolicies) {
+		return nil, httperrors.NewInputParameterError("drift_policy %q not in %v", input.DriftPolicy, api.FederatedDriftPolicies)
+	}
 	return input, nil
 }
 
@@ -243,6 +261,25 @@ func (obj *SFedResourceBase) PerformSync(ctx context.Context, userCred mcclient.
 	return nil, GetFedResAPI().StartSyncTask(elemObj, ctx, userCred, data.(*jsonutils.JSONDict), "")
 }
 
+// PerformCheckDrift compares member cluster objects with template and re-applies by drift policy
+func (obj *SFedResourceBase) PerformCheckDrift(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	elemObj, err := obj.GetElemModel()
+	if err != nil {
+		return nil, err
+	}
+	jObjs, err := fetchFedJointClusterModels(elemObj)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch joint clusters")
+	}
+	errs := make([]error, 0)
+	for _, jObj := range jObjs {
+		if err := ReconcileFedJointDrift(ctx, userCred, elemObj, jObj); err != nil {
+			errs = append(errs, errors.Wrapf(err, "cluster %s", jObj.GetClusterId()))
+		}
+	}
+	return nil, errors.NewAggregate(errs)
+}
+
 func (obj *SFedResourceBase) PerformSyncCluster(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data *api.FederatedResourceJointClusterInput) (*api.FederatedResourceJointClusterInput, error) {
 	elemObj, err := obj.GetElemModel()
 	if err != nil {
@@ -279,6 +316,9 @@ func (m *SFedResourceBase) ValidateUpdateData(ctx context.Context, userCred mccl
 	if input.Name != "" {
 		return nil, httperrors.NewInputParameterError("Can not update name")
 	}
+	if input.DriftPolicy != "" && !utils.IsInStringArray(input.DriftPolicy, api.FederatedDriftPolicies) {
+		return nil, httperrors.NewInputParameterError("drift_policy %q not in %v", input.DriftPolicy, api.FederatedDriftPolicies)
+	}
 	return input, nil
 }
 
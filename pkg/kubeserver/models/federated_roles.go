@@ -4,6 +4,8 @@ import (
 	"context"
 
 	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/kubernetes/pkg/apis/rbac"
 	"k8s.io/kubernetes/pkg/apis/rbac/validation"
@@ -121,3 +123,11 @@ func (obj *SFedRole) ValidateUpdateData(ctx context.Context, userCred mcclient.T
 	}
 	return input, nil
 }
+
+// NewK8sObject renders template to kubernetes object expected inside member cluster
+func (obj *SFedRole) NewK8sObject(objMeta metav1.ObjectMeta) (runtime.Object, error) {
+	if obj.Spec == nil {
+		return nil, errors.Errorf("%s spec is empty", obj.LogPrefix())
+	}
+	return obj.Spec.ToRole(objMeta), nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+)
+
+var (
+	FedSecretClusterManager *SFedSecretClusterManager
+	_                       IFedJointClusterModel = new(SFedSecretCluster)
+)
+
+func init() {
+	db.InitManager(func() {
+		FedSecretClusterManager = NewFedJointManager(func() db.IJointModelManager {
+			return &SFedSecretClusterManager{
+				SFedNamespaceJointClusterManager: NewFedNamespaceJointClusterManager(
+					SFedSecretCluster{},
+					"federatedsecretclusters_tbl",
+					"federatedsecretcluster",
+					"federatedsecretclusters",
+					GetFedSecretManager(),
+					GetSecretManager(),
+				),
+			}
+		}).(*SFedSecretClusterManager)
+		GetFedSecretManager().SetJointModelManager(FedSecretClusterManager)
+		RegisterFedJointClusterManager(GetFedSecretManager(), FedSecretClusterManager)
+	})
+}
+
+// +onecloud:swagger-gen-model-singular=federatedsecretcluster
+// +onecloud:swagger-gen-model-plural=federatedsecretclusters
+type SFedSecretClusterManager struct {
+	SFedNamespaceJointClusterManager
+}
+
+type SFedSecretCluster struct {
+	SFedNamespaceJointObjectCluster
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/stringutils"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+var (
+	fedSecretManager *SFedSecretManager
+	_                IFedModelManager = new(SFedSecretManager)
+	_                IFedObjectModel  = new(SFedSecret)
+)
+
+func init() {
+	GetFedSecretManager()
+}
+
+func GetFedSecretManager() *SFedSecretManager {
+	if fedSecretManager == nil {
+		fedSecretManager = newModelManager(func() db.IModelManager {
+			return &SFedSecretManager{
+				SFedNamespaceResourceManager: NewFedNamespaceResourceManager(
+					SFedSecret{},
+					"federatedsecrets_tbl",
+					"federatedsecret",
+					"federatedsecrets",
+				),
+			}
+		}).(*SFedSecretManager)
+	}
+	return fedSecretManager
+}
+
+// +onecloud:swagger-gen-model-singular=federatedsecret
+// +onecloud:swagger-gen-model-plural=federatedsecrets
+type SFedSecretManager struct {
+	SFedNamespaceResourceManager
+}
+
+type SFedSecret struct {
+	SFedObjectResource
+	// Spec data values are encrypted with id of the secret, string data is merged into data
+	Spec *api.FederatedSecretSpec `update:"user" create:"required"`
+}
+
+func validateFedSecretObject(obj runtime.Object) error {
+	return GetSecretManager().ValidateSecretObject(obj.(*v1.Secret))
+}
+
+func (m *SFedSecretManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerCred mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.FederatedSecretCreateInput) (*api.FederatedSecretCreateInput, error) {
+	if err := m.ValidateFedObjectCreateData(ctx, userCred, ownerCred, query, &input.FederatedNamespaceResourceCreateInput, input.Spec, validateFedSecretObject); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (obj *SFedSecret) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	if err := obj.SFedObjectResource.CustomizeCreate(ctx, userCred, ownerId, query, data); err != nil {
+		return err
+	}
+	// id is the key of encryption, so it's set before inserted
+	if obj.Id == "" {
+		obj.Id = stringutils.UUID4()
+	}
+	return encryptFedSecretSpec(obj.Id, obj.Spec)
+}
+
+func (obj *SFedSecret) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.FederatedSecretUpdateInput) (*api.FederatedSecretUpdateInput, error) {
+	if err := obj.ValidateFedObjectUpdateData(ctx, userCred, query, &input.FedNamespaceResourceUpdateInput, input.Spec, validateFedSecretObject); err != nil {
+		return nil, err
+	}
+	if input.Spec != nil {
+		if err := encryptFedSecretSpec(obj.Id, input.Spec); err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+	}
+	return input, nil
+}
+
+// encryptFedSecretSpec merges string data into data and encrypts data values in place
+func encryptFedSecretSpec(key string, spec *api.FederatedSecretSpec) error {
+	data := make(map[string]string, len(spec.Template.Data)+len(spec.Template.StringData))
+	for k, v := range spec.Template.Data {
+		val, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return errors.Wrapf(err, "decode data %q", k)
+		}
+		data[k] = string(val)
+	}
+	for k, v := range spec.Template.StringData {
+		data[k] = v
+	}
+	for k, v := range data {
+		secret, err := utils.EncryptAESBase64(key, v)
+		if err != nil {
+			return errors.Wrapf(err, "encrypt data %q", k)
+		}
+		data[k] = secret
+	}
+	spec.Template.Data = data
+	spec.Template.StringData = nil
+	return nil
+}
+
+// fedSecretSpec decrypts data values of stored spec when rendering template
+type fedSecretSpec struct {
+	*api.FederatedSecretSpec
+	key string
+}
+
+func (spec fedSecretSpec) ToK8sObject(objMeta metav1.ObjectMeta) (runtime.Object, error) {
+	out := *spec.FederatedSecretSpec
+	out.Template.Data = make(map[string]string, len(spec.Template.Data))
+	for k, v := range spec.Template.Data {
+		val, err := utils.DescryptAESBase64(spec.key, v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decrypt data %q", k)
+		}
+		out.Template.Data[k] = base64.StdEncoding.EncodeToString([]byte(val))
+	}
+	return out.ToSecret(objMeta)
+}
+
+func (obj *SFedSecret) GetSpec() api.IFederatedObjectSpec {
+	return fedSecretSpec{
+		FederatedSecretSpec: obj.Spec,
+		key:                 obj.Id,
+	}
+}
+
+func (obj *SFedSecret) GetDetails(base interface{}, isList bool) interface{} {
+	out := api.FederatedSecretDetails{
+		FederatedObjectDetails: obj.SFedObjectResource.GetDetails(base, isList).(api.FederatedObjectDetails),
+	}
+	if obj.Spec != nil {
+		spec := *obj.Spec
+		spec.Template.Data = make(map[string]string, len(obj.Spec.Template.Data))
+		for k := range obj.Spec.Template.Data {
+			spec.Template.Data[k] = "******"
+		}
+		out.Spec = &spec
+	}
+	return out
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+)
+
+var (
+	FedServiceClusterManager *SFedServiceClusterManager
+	_                        IFedJointClusterModel = new(SFedServiceCluster)
+)
+
+func init() {
+	db.InitManager(func() {
+		FedServiceClusterManager = NewFedJointManager(func() db.IJointModelManager {
+			return &SFedServiceClusterManager{
+				SFedNamespaceJointClusterManager: NewFedNamespaceJointClusterManager(
+					SFedServiceCluster{},
+					"federatedserviceclusters_tbl",
+					"federatedservicecluster",
+					"federatedserviceclusters",
+					GetFedServiceManager(),
+					GetServiceManager(),
+				),
+			}
+		}).(*SFedServiceClusterManager)
+		GetFedServiceManager().SetJointModelManager(FedServiceClusterManager)
+		RegisterFedJointClusterManager(GetFedServiceManager(), FedServiceClusterManager)
+	})
+}
+
+// +onecloud:swagger-gen-model-singular=federatedservicecluster
+// +onecloud:swagger-gen-model-plural=federatedserviceclusters
+type SFedServiceClusterManager struct {
+	SFedNamespaceJointClusterManager
+}
+
+type SFedServiceCluster struct {
+	SFedNamespaceJointObjectCluster
+}
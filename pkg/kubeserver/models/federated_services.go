@@ -0,0 +1,73 @@
+package models
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/mcclient"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+var (
+	fedServiceManager *SFedServiceManager
+	_                 IFedModelManager = new(SFedServiceManager)
+	_                 IFedObjectModel  = new(SFedService)
+)
+
+func init() {
+	GetFedServiceManager()
+}
+
+func GetFedServiceManager() *SFedServiceManager {
+	if fedServiceManager == nil {
+		fedServiceManager = newModelManager(func() db.IModelManager {
+			return &SFedServiceManager{
+				SFedNamespaceResourceManager: NewFedNamespaceResourceManager(
+					SFedService{},
+					"federatedservices_tbl",
+					"federatedservice",
+					"federatedservices",
+				),
+			}
+		}).(*SFedServiceManager)
+	}
+	return fedServiceManager
+}
+
+// +onecloud:swagger-gen-model-singular=federatedservice
+// +onecloud:swagger-gen-model-plural=federatedservices
+type SFedServiceManager struct {
+	SFedNamespaceResourceManager
+}
+
+type SFedService struct {
+	SFedObjectResource
+	Spec *api.FederatedServiceSpec `list:"user" update:"user" create:"required"`
+}
+
+func validateFedServiceObject(obj runtime.Object) error {
+	return GetServiceManager().ValidateService(obj.(*v1.Service))
+}
+
+func (m *SFedServiceManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerCred mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.FederatedServiceCreateInput) (*api.FederatedServiceCreateInput, error) {
+	if err := m.ValidateFedObjectCreateData(ctx, userCred, ownerCred, query, &input.FederatedNamespaceResourceCreateInput, input.Spec, validateFedServiceObject); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (obj *SFedService) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.FederatedServiceUpdateInput) (*api.FederatedServiceUpdateInput, error) {
+	if err := obj.ValidateFedObjectUpdateData(ctx, userCred, query, &input.FedNamespaceResourceUpdateInput, input.Spec, validateFedServiceObject); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (obj *SFedService) GetSpec() api.IFederatedObjectSpec {
+	return obj.Spec
+}
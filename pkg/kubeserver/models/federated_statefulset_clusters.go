@@ -0,0 +1,39 @@
+package models
+
+import (
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+)
+
+var (
+	FedStatefulSetClusterManager *SFedStatefulSetClusterManager
+	_                            IFedJointClusterModel = new(SFedStatefulSetCluster)
+)
+
+func init() {
+	db.InitManager(func() {
+		FedStatefulSetClusterManager = NewFedJointManager(func() db.IJointModelManager {
+			return &SFedStatefulSetClusterManager{
+				SFedNamespaceJointClusterManager: NewFedNamespaceJointClusterManager(
+					SFedStatefulSetCluster{},
+					"federatedstatefulsetclusters_tbl",
+					"federatedstatefulsetcluster",
+					"federatedstatefulsetclusters",
+					GetFedStatefulSetManager(),
+					GetStatefulSetManager(),
+				),
+			}
+		}).(*SFedStatefulSetClusterManager)
+		GetFedStatefulSetManager().SetJointModelManager(FedStatefulSetClusterManager)
+		RegisterFedJointClusterManager(GetFedStatefulSetManager(), FedStatefulSetClusterManager)
+	})
+}
+
+// +onecloud:swagger-gen-model-singular=federatedstatefulsetcluster
+// +onecloud:swagger-gen-model-plural=federatedstatefulsetclusters
+type SFedStatefulSetClusterManager struct {
+	SFedNamespaceJointClusterManager
+}
+
+type SFedStatefulSetCluster struct {
+	SFedNamespaceJointObjectCluster
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"context"
+
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/mcclient"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+var (
+	fedStatefulSetManager *SFedStatefulSetManager
+	_                     IFedModelManager = new(SFedStatefulSetManager)
+	_                     IFedObjectModel  = new(SFedStatefulSet)
+)
+
+func init() {
+	GetFedStatefulSetManager()
+}
+
+func GetFedStatefulSetManager() *SFedStatefulSetManager {
+	if fedStatefulSetManager == nil {
+		fedStatefulSetManager = newModelManager(func() db.IModelManager {
+			return &SFedStatefulSetManager{
+				SFedNamespaceResourceManager: NewFedNamespaceResourceManager(
+					SFedStatefulSet{},
+					"federatedstatefulsets_tbl",
+					"federatedstatefulset",
+					"federatedstatefulsets",
+				),
+			}
+		}).(*SFedStatefulSetManager)
+	}
+	return fedStatefulSetManager
+}
+
+// +onecloud:swagger-gen-model-singular=federatedstatefulset
+// +onecloud:swagger-gen-model-plural=federatedstatefulsets
+type SFedStatefulSetManager struct {
+	SFedNamespaceResourceManager
+}
+
+type SFedStatefulSet struct {
+	SFedObjectResource
+	Spec *api.FederatedStatefulSetSpec `list:"user" update:"user" create:"required"`
+}
+
+func validateFedStatefulSetObject(obj runtime.Object) error {
+	return GetStatefulSetManager().ValidateStatefulSetObject(obj.(*apps.StatefulSet))
+}
+
+func (m *SFedStatefulSetManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerCred mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.FederatedStatefulSetCreateInput) (*api.FederatedStatefulSetCreateInput, error) {
+	if err := m.ValidateFedObjectCreateData(ctx, userCred, ownerCred, query, &input.FederatedNamespaceResourceCreateInput, input.Spec, validateFedStatefulSetObject); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (obj *SFedStatefulSet) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.FederatedStatefulSetUpdateInput) (*api.FederatedStatefulSetUpdateInput, error) {
+	if err := obj.ValidateFedObjectUpdateData(ctx, userCred, query, &input.FedNamespaceResourceUpdateInput, input.Spec, validateFedStatefulSetObject); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (obj *SFedStatefulSet) GetSpec() api.IFederatedObjectSpec {
+	return obj.Spec
+}
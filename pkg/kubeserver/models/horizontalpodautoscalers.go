@@ -0,0 +1,374 @@
+package models
+
+import (
+	"context"
+
+	apps "k8s.io/api/apps/v1"
+	autoscaling "k8s.io/api/autoscaling/v2beta2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/client"
+)
+
+var (
+	hpaManager *SHorizontalPodAutoscalerManager
+	_          IClusterModel = new(SHorizontalPodAutoscaler)
+)
+
+func init() {
+	GetHorizontalPodAutoscalerManager()
+}
+
+// GetHorizontalPodAutoscalerManager manages hpa through dynamic client, autoscaling/v2 or v2beta2
+// is used if cluster serves it, the remote object is converted by v2beta2 types which share same schema
+func GetHorizontalPodAutoscalerManager() *SHorizontalPodAutoscalerManager {
+	if hpaManager == nil {
+		hpaManager = NewK8sNamespaceModelManager(func() ISyncableManager {
+			return &SHorizontalPodAutoscalerManager{
+				SNamespaceResourceBaseManager: NewNamespaceResourceBaseManager(
+					SHorizontalPodAutoscaler{},
+					"horizontalpodautoscalers_tbl",
+					"horizontalpodautoscaler",
+					"horizontalpodautoscalers",
+					api.ResourceNameHorizontalPodAutoscaler,
+					"",
+					"",
+					api.KindNameHorizontalPodAutoscaler,
+					new(unstructured.Unstructured),
+				),
+			}
+		}).(*SHorizontalPodAutoscalerManager)
+	}
+	return hpaManager
+}
+
+// +onecloud:swagger-gen-model-singular=horizontalpodautoscaler
+// +onecloud:swagger-gen-model-plural=horizontalpodautoscalers
+type SHorizontalPodAutoscalerManager struct {
+	SNamespaceResourceBaseManager
+}
+
+type SHorizontalPodAutoscaler struct {
+	SNamespaceResourceBase
+}
+
+func (m *SHorizontalPodAutoscalerManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.HorizontalPodAutoscalerCreateInput) (*api.HorizontalPodAutoscalerCreateInput, error) {
+	nInput, err := m.SNamespaceResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, &input.NamespaceResourceCreateInput)
+	if err != nil {
+		return nil, err
+	}
+	input.NamespaceResourceCreateInput = *nInput
+
+	ref := &input.ScaleTargetRef
+	var targetMan IClusterModelManager
+	switch ref.Kind {
+	case api.KindNameDeployment:
+		targetMan = GetDeploymentManager()
+	case api.KindNameStatefulSet:
+		targetMan = GetStatefulSetManager()
+	default:
+		return nil, httperrors.NewInputParameterError("unsupported scale target kind %q", ref.Kind)
+	}
+	if ref.Name == "" {
+		return nil, httperrors.NewNotEmptyError("scale target name is empty")
+	}
+	target, err := FetchClusterResourceByIdOrName(targetMan, userCred, input.ClusterId, input.NamespaceId, ref.Name)
+	if err != nil {
+		return nil, NewCheckIdOrNameError(ref.Kind, ref.Name, err)
+	}
+	ref.Name = target.GetName()
+	ref.APIVersion = apps.SchemeGroupVersion.String()
+
+	if len(input.Metrics) == 0 {
+		utilization := api.HorizontalPodAutoscalerDefaultCPUUtilization
+		input.Metrics = []autoscaling.MetricSpec{
+			{
+				Type: autoscaling.ResourceMetricSourceType,
+				Resource: &autoscaling.ResourceMetricSource{
+					Name: v1.ResourceCPU,
+					Target: autoscaling.MetricTarget{
+						Type:               autoscaling.UtilizationMetricType,
+						AverageUtilization: &utilization,
+					},
+				},
+			},
+		}
+	}
+	if err := ValidateHorizontalPodAutoscalerSpec(&input.HorizontalPodAutoscalerSpec); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (m *SHorizontalPodAutoscalerManager) NewRemoteObjectForCreate(model IClusterModel, cli *client.ClusterManager, data jsonutils.JSONObject) (interface{}, error) {
+	input := new(api.HorizontalPodAutoscalerCreateInput)
+	if err := data.Unmarshal(input); err != nil {
+		return nil, errors.Wrap(err, "horizontalpodautoscaler input unmarshal error")
+	}
+	objMeta, err := input.ToObjectMeta(model.(api.INamespaceGetter))
+	if err != nil {
+		return nil, errors.Wrap(err, "horizontalpodautoscaler input get meta error")
+	}
+	objMeta = *api.AddObjectMetaDefaultLabel(&objMeta)
+
+	res := new(unstructured.Unstructured)
+	res.SetName(objMeta.Name)
+	res.SetNamespace(objMeta.Namespace)
+	res.SetLabels(objMeta.Labels)
+	res.SetAnnotations(objMeta.Annotations)
+	if err := setHorizontalPodAutoscalerSpec(res, &input.HorizontalPodAutoscalerSpec); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (obj *SHorizontalPodAutoscaler) NewRemoteObjectForUpdate(cli *client.ClusterManager, remoteObj interface{}, data jsonutils.JSONObject) (interface{}, error) {
+	res := remoteObj.(*unstructured.Unstructured)
+	input := new(api.HorizontalPodAutoscalerUpdateInput)
+	if err := data.Unmarshal(input); err != nil {
+		return nil, err
+	}
+	hpa, err := toHorizontalPodAutoscaler(res)
+	if err != nil {
+		return nil, err
+	}
+	spec := &hpa.Spec
+	if input.MinReplicas != nil {
+		spec.MinReplicas = input.MinReplicas
+	}
+	if input.MaxReplicas != nil {
+		spec.MaxReplicas = *input.MaxReplicas
+	}
+	if input.Metrics != nil {
+		spec.Metrics = input.Metrics
+	}
+	if input.Behavior != nil {
+		spec.Behavior = input.Behavior
+	}
+	if err := ValidateHorizontalPodAutoscalerSpec(spec); err != nil {
+		return nil, err
+	}
+	if err := setHorizontalPodAutoscalerSpec(res, spec); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (obj *SHorizontalPodAutoscaler) GetDetails(ctx context.Context, cli *client.ClusterManager, base interface{}, k8sObj runtime.Object, isList bool) interface{} {
+	detail := api.HorizontalPodAutoscalerDetail{
+		NamespaceResourceDetail: obj.SNamespaceResourceBase.GetDetails(ctx, cli, base, k8sObj, isList).(api.NamespaceResourceDetail),
+	}
+	hpa, err := toHorizontalPodAutoscaler(k8sObj.(*unstructured.Unstructured))
+	if err != nil {
+		log.Errorf("Convert horizontalpodautoscaler %s error: %v", obj.GetName(), err)
+		return detail
+	}
+	detail.ScaleTargetRef = hpa.Spec.ScaleTargetRef
+	detail.MinReplicas = hpa.Spec.MinReplicas
+	detail.MaxReplicas = hpa.Spec.MaxReplicas
+	detail.Metrics = hpa.Spec.Metrics
+	detail.Behavior = hpa.Spec.Behavior
+	detail.CurrentReplicas = hpa.Status.CurrentReplicas
+	detail.DesiredReplicas = hpa.Status.DesiredReplicas
+	detail.LastScaleTime = hpa.Status.LastScaleTime
+	detail.CurrentMetrics = hpa.Status.CurrentMetrics
+	detail.Conditions = hpa.Status.Conditions
+	return detail
+}
+
+func toHorizontalPodAutoscaler(obj *unstructured.Unstructured) (*autoscaling.HorizontalPodAutoscaler, error) {
+	hpa := new(autoscaling.HorizontalPodAutoscaler)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, hpa); err != nil {
+		return nil, errors.Wrap(err, "convert from unstructured")
+	}
+	return hpa, nil
+}
+
+func setHorizontalPodAutoscalerSpec(obj *unstructured.Unstructured, spec *autoscaling.HorizontalPodAutoscalerSpec) error {
+	specObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(spec)
+	if err != nil {
+		return errors.Wrap(err, "convert spec to unstructured")
+	}
+	if err := unstructured.SetNestedMap(obj.Object, specObj, "spec"); err != nil {
+		return errors.Wrap(err, "set nested map of unstructured")
+	}
+	return nil
+}
+
+// GetHorizontalPodAutoscalersByTarget returns hpas scaling the workload of kind and name in namespace
+func GetHorizontalPodAutoscalersByTarget(cli *client.ClusterManager, namespace string, kind string, name string) ([]api.HorizontalPodAutoscaler, error) {
+	objs, err := cli.GetHandler().List(api.ResourceNameHorizontalPodAutoscaler, namespace, "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "list namespace %s horizontalpodautoscalers", namespace)
+	}
+	ret := make([]api.HorizontalPodAutoscaler, 0)
+	for _, obj := range objs {
+		hpa, err := toHorizontalPodAutoscaler(obj.(*unstructured.Unstructured))
+		if err != nil {
+			return nil, err
+		}
+		if !isHorizontalPodAutoscalerTarget(hpa, kind, name) {
+			continue
+		}
+		ret = append(ret, api.HorizontalPodAutoscaler{
+			Name:            hpa.GetName(),
+			MinReplicas:     hpa.Spec.MinReplicas,
+			MaxReplicas:     hpa.Spec.MaxReplicas,
+			CurrentReplicas: hpa.Status.CurrentReplicas,
+			DesiredReplicas: hpa.Status.DesiredReplicas,
+		})
+	}
+	return ret, nil
+}
+
+func isHorizontalPodAutoscalerTarget(hpa *autoscaling.HorizontalPodAutoscaler, kind string, name string) bool {
+	ref := hpa.Spec.ScaleTargetRef
+	return ref.Kind == kind && ref.Name == name
+}
+
+const (
+	// limits are same as validation of kube-apiserver
+	hpaMaxStabilizationWindowSeconds = 3600
+	hpaMaxScalingPolicyPeriodSeconds = 1800
+)
+
+// ValidateHorizontalPodAutoscalerSpec validates replicas, metrics and behavior of autoscaling/v2 spec
+func ValidateHorizontalPodAutoscalerSpec(spec *autoscaling.HorizontalPodAutoscalerSpec) error {
+	if spec.MinReplicas != nil && *spec.MinReplicas < 1 {
+		return httperrors.NewInputParameterError("minReplicas must be greater than 0")
+	}
+	minReplicas := int32(1)
+	if spec.MinReplicas != nil {
+		minReplicas = *spec.MinReplicas
+	}
+	if spec.MaxReplicas < minReplicas {
+		return httperrors.NewInputParameterError("maxReplicas %d must be greater than or equal to minReplicas %d", spec.MaxReplicas, minReplicas)
+	}
+	for i := range spec.Metrics {
+		if err := validateHPAMetricSpec(&spec.Metrics[i]); err != nil {
+			return errors.Wrapf(err, "metrics[%d]", i)
+		}
+	}
+	if spec.Behavior != nil {
+		if err := validateHPAScalingRules(spec.Behavior.ScaleUp); err != nil {
+			return errors.Wrap(err, "behavior scaleUp")
+		}
+		if err := validateHPAScalingRules(spec.Behavior.ScaleDown); err != nil {
+			return errors.Wrap(err, "behavior scaleDown")
+		}
+	}
+	return nil
+}
+
+func validateHPAMetricSpec(metric *autoscaling.MetricSpec) error {
+	switch metric.Type {
+	case autoscaling.ResourceMetricSourceType:
+		if metric.Resource == nil {
+			return httperrors.NewNotEmptyError("resource is empty")
+		}
+		if metric.Resource.Name != v1.ResourceCPU && metric.Resource.Name != v1.ResourceMemory {
+			return httperrors.NewInputParameterError("unsupported resource %q", metric.Resource.Name)
+		}
+		return validateHPAMetricTarget(&metric.Resource.Target,
+			autoscaling.UtilizationMetricType, autoscaling.AverageValueMetricType)
+	case autoscaling.PodsMetricSourceType:
+		if metric.Pods == nil {
+			return httperrors.NewNotEmptyError("pods is empty")
+		}
+		if metric.Pods.Metric.Name == "" {
+			return httperrors.NewNotEmptyError("pods metric name is empty")
+		}
+		return validateHPAMetricTarget(&metric.Pods.Target, autoscaling.AverageValueMetricType)
+	case autoscaling.ObjectMetricSourceType:
+		if metric.Object == nil {
+			return httperrors.NewNotEmptyError("object is empty")
+		}
+		if metric.Object.DescribedObject.Kind == "" || metric.Object.DescribedObject.Name == "" {
+			return httperrors.NewNotEmptyError("object describedObject kind and name are required")
+		}
+		if metric.Object.Metric.Name == "" {
+			return httperrors.NewNotEmptyError("object metric name is empty")
+		}
+		return validateHPAMetricTarget(&metric.Object.Target,
+			autoscaling.ValueMetricType, autoscaling.AverageValueMetricType)
+	case autoscaling.ExternalMetricSourceType:
+		if metric.External == nil {
+			return httperrors.NewNotEmptyError("external is empty")
+		}
+		if metric.External.Metric.Name == "" {
+			return httperrors.NewNotEmptyError("external metric name is empty")
+		}
+		return validateHPAMetricTarget(&metric.External.Target,
+			autoscaling.ValueMetricType, autoscaling.AverageValueMetricType)
+	default:
+		return httperrors.NewInputParameterError("unsupported metric type %q", metric.Type)
+	}
+}
+
+func validateHPAMetricTarget(target *autoscaling.MetricTarget, types ...autoscaling.MetricTargetType) error {
+	supported := false
+	for _, t := range types {
+		if target.Type == t {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return httperrors.NewInputParameterError("target type %q isn't one of %v", target.Type, types)
+	}
+	switch target.Type {
+	case autoscaling.UtilizationMetricType:
+		if target.AverageUtilization == nil || *target.AverageUtilization <= 0 {
+			return httperrors.NewInputParameterError("averageUtilization must be greater than 0")
+		}
+	case autoscaling.ValueMetricType:
+		if target.Value == nil || target.Value.Sign() <= 0 {
+			return httperrors.NewInputParameterError("value must be positive")
+		}
+	case autoscaling.AverageValueMetricType:
+		if target.AverageValue == nil || target.AverageValue.Sign() <= 0 {
+			return httperrors.NewInputParameterError("averageValue must be positive")
+		}
+	}
+	return nil
+}
+
+func validateHPAScalingRules(rules *autoscaling.HPAScalingRules) error {
+	if rules == nil {
+		return nil
+	}
+	if win := rules.StabilizationWindowSeconds; win != nil && (*win < 0 || *win > hpaMaxStabilizationWindowSeconds) {
+		return httperrors.NewInputParameterError("stabilizationWindowSeconds must be in [0, %d]", hpaMaxStabilizationWindowSeconds)
+	}
+	if sp := rules.SelectPolicy; sp != nil {
+		switch *sp {
+		case autoscaling.MaxPolicySelect, autoscaling.MinPolicySelect, autoscaling.DisabledPolicySelect:
+		default:
+			return httperrors.NewInputParameterError("unsupported selectPolicy %q", *sp)
+		}
+	}
+	if len(rules.Policies) == 0 && (rules.SelectPolicy == nil || *rules.SelectPolicy != autoscaling.DisabledPolicySelect) {
+		return httperrors.NewNotEmptyError("policies are empty")
+	}
+	for i, p := range rules.Policies {
+		if p.Type != autoscaling.PodsScalingPolicy && p.Type != autoscaling.PercentScalingPolicy {
+			return httperrors.NewInputParameterError("policies[%d] unsupported type %q", i, p.Type)
+		}
+		if p.Value <= 0 {
+			return httperrors.NewInputParameterError("policies[%d] value must be greater than 0", i)
+		}
+		if p.PeriodSeconds <= 0 || p.PeriodSeconds > hpaMaxScalingPolicyPeriodSeconds {
+			return httperrors.NewInputParameterError("policies[%d] periodSeconds must be in (0, %d]", i, hpaMaxScalingPolicyPeriodSeconds)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package models
+
+import (
+	"testing"
+
+	autoscaling "k8s.io/api/autoscaling/v2beta2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestValidateHorizontalPodAutoscalerSpec(t *testing.T) {
+	int32Ptr := func(i int32) *int32 { return &i }
+	cpuMetric := autoscaling.MetricSpec{
+		Type: autoscaling.ResourceMetricSourceType,
+		Resource: &autoscaling.ResourceMetricSource{
+			Name: v1.ResourceCPU,
+			Target: autoscaling.MetricTarget{
+				Type:               autoscaling.UtilizationMetricType,
+				AverageUtilization: int32Ptr(60),
+			},
+		},
+	}
+	qps := resource.MustParse("100")
+	disabled := autoscaling.DisabledPolicySelect
+
+	tests := []struct {
+		name    string
+		spec    autoscaling.HorizontalPodAutoscalerSpec
+		wantErr bool
+	}{
+		{
+			name: "cpu utilization",
+			spec: autoscaling.HorizontalPodAutoscalerSpec{
+				MinReplicas: int32Ptr(2),
+				MaxReplicas: 5,
+				Metrics:     []autoscaling.MetricSpec{cpuMetric},
+			},
+		},
+		{
+			name: "max less than min",
+			spec: autoscaling.HorizontalPodAutoscalerSpec{
+				MinReplicas: int32Ptr(3),
+				MaxReplicas: 2,
+			},
+			wantErr: true,
+		},
+		{
+			name: "max less than default min",
+			spec: autoscaling.HorizontalPodAutoscalerSpec{
+				MaxReplicas: 0,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported resource",
+			spec: autoscaling.HorizontalPodAutoscalerSpec{
+				MaxReplicas: 3,
+				Metrics: []autoscaling.MetricSpec{
+					{
+						Type: autoscaling.ResourceMetricSourceType,
+						Resource: &autoscaling.ResourceMetricSource{
+							Name: v1.ResourceStorage,
+							Target: autoscaling.MetricTarget{
+								Type:               autoscaling.UtilizationMetricType,
+								AverageUtilization: int32Ptr(60),
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "pods metric average value",
+			spec: autoscaling.HorizontalPodAutoscalerSpec{
+				MaxReplicas: 3,
+				Metrics: []autoscaling.MetricSpec{
+					{
+						Type: autoscaling.PodsMetricSourceType,
+						Pods: &autoscaling.PodsMetricSource{
+							Metric: autoscaling.MetricIdentifier{Name: "http_requests"},
+							Target: autoscaling.MetricTarget{
+								Type:         autoscaling.AverageValueMetricType,
+								AverageValue: &qps,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "pods metric doesn't support value",
+			spec: autoscaling.HorizontalPodAutoscalerSpec{
+				MaxReplicas: 3,
+				Metrics: []autoscaling.MetricSpec{
+					{
+						Type: autoscaling.PodsMetricSourceType,
+						Pods: &autoscaling.PodsMetricSource{
+							Metric: autoscaling.MetricIdentifier{Name: "http_requests"},
+							Target: autoscaling.MetricTarget{
+								Type:  autoscaling.ValueMetricType,
+								Value: &qps,
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "external metric without source",
+			spec: autoscaling.HorizontalPodAutoscalerSpec{
+				MaxReplicas: 3,
+				Metrics: []autoscaling.MetricSpec{
+					{Type: autoscaling.ExternalMetricSourceType},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "behavior policies",
+			spec: autoscaling.HorizontalPodAutoscalerSpec{
+				MaxReplicas: 10,
+				Metrics:     []autoscaling.MetricSpec{cpuMetric},
+				Behavior: &autoscaling.HorizontalPodAutoscalerBehavior{
+					ScaleUp: &autoscaling.HPAScalingRules{
+						StabilizationWindowSeconds: int32Ptr(0),
+						Policies: []autoscaling.HPAScalingPolicy{
+							{Type: autoscaling.PercentScalingPolicy, Value: 100, PeriodSeconds: 15},
+							{Type: autoscaling.PodsScalingPolicy, Value: 4, PeriodSeconds: 15},
+						},
+					},
+					ScaleDown: &autoscaling.HPAScalingRules{
+						SelectPolicy: &disabled,
+					},
+				},
+			},
+		},
+		{
+			name: "behavior period too long",
+			spec: autoscaling.HorizontalPodAutoscalerSpec{
+				MaxReplicas: 10,
+				Behavior: &autoscaling.HorizontalPodAutoscalerBehavior{
+					ScaleDown: &autoscaling.HPAScalingRules{
+						Policies: []autoscaling.HPAScalingPolicy{
+							{Type: autoscaling.PodsScalingPolicy, Value: 1, PeriodSeconds: 3600},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateHorizontalPodAutoscalerSpec(&tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateHorizontalPodAutoscalerSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHorizontalPodAutoscalerUnstructured(t *testing.T) {
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "autoscaling/v2",
+			"kind":       "HorizontalPodAutoscaler",
+			"metadata": map[string]interface{}{
+				"name":      "web",
+				"namespace": "default",
+			},
+			"spec": map[string]interface{}{
+				"scaleTargetRef": map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"name":       "web",
+				},
+				"minReplicas": int64(1),
+				"maxReplicas": int64(4),
+				"metrics": []interface{}{
+					map[string]interface{}{
+						"type": "Resource",
+						"resource": map[string]interface{}{
+							"name": "memory",
+							"target": map[string]interface{}{
+								"type":         "AverageValue",
+								"averageValue": "512Mi",
+							},
+						},
+					},
+				},
+			},
+			"status": map[string]interface{}{
+				"currentReplicas": int64(2),
+				"desiredReplicas": int64(3),
+			},
+		},
+	}
+	hpa, err := toHorizontalPodAutoscaler(obj)
+	if err != nil {
+		t.Fatalf("toHorizontalPodAutoscaler() error = %v", err)
+	}
+	if !isHorizontalPodAutoscalerTarget(hpa, "Deployment", "web") {
+		t.Errorf("hpa should target deployment web")
+	}
+	if isHorizontalPodAutoscalerTarget(hpa, "StatefulSet", "web") {
+		t.Errorf("hpa shouldn't target statefulset web")
+	}
+	if hpa.Status.DesiredReplicas != 3 || hpa.Spec.MaxReplicas != 4 {
+		t.Errorf("unexpected replicas: %#v", hpa)
+	}
+	if err := ValidateHorizontalPodAutoscalerSpec(&hpa.Spec); err != nil {
+		t.Errorf("ValidateHorizontalPodAutoscalerSpec() error = %v", err)
+	}
+
+	hpa.Spec.MaxReplicas = 6
+	if err := setHorizontalPodAutoscalerSpec(obj, &hpa.Spec); err != nil {
+		t.Fatalf("setHorizontalPodAutoscalerSpec() error = %v", err)
+	}
+	if max, _, _ := unstructured.NestedInt64(obj.Object, "spec", "maxReplicas"); max != 6 {
+		t.Errorf("spec maxReplicas = %d, want 6", max)
+	}
+	if metrics, _, _ := unstructured.NestedSlice(obj.Object, "spec", "metrics"); len(metrics) != 1 {
+		t.Errorf("spec metrics = %v, want 1 metric", metrics)
+	}
+}
@@ -27,7 +27,7 @@ type IMachineDriver interface {
 	PostCreate(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster, m *SMachine, data *jsonutils.JSONDict) error
 
 	RequestPrepareMachine(ctx context.Context, userCred mcclient.TokenCredential, m *SMachine, task taskman.ITask) error
-	PrepareResource(session *mcclient.ClientSession, m *SMachine, data *api.MachinePrepareInput) (jsonutils.JSONObject, error)
+	PrepareResource(ctx context.Context, session *mcclient.ClientSession, m *SMachine, data *api.MachinePrepareInput) (jsonutils.JSONObject, error)
 	PostPrepareResource(ctx context.Context, userCred mcclient.TokenCredential, m *SMachine, data jsonutils.JSONObject) (jsonutils.JSONObject, error)
 
 	ValidateDeleteCondition(ctx context.Context, userCred mcclient.TokenCredential, cluster *SCluster, m *SMachine) error
@@ -378,6 +378,13 @@ func (m *SMachine) StartMachineCreateTask(ctx context.Context, userCred mcclient
 	if err := m.SetStatus(ctx, userCred, api.MachineStatusCreating, ""); err != nil {
 		return errors.Wrapf(err, "set status to %s", api.MachineStatusCreating)
 	}
+	// ssh login of host machine is moved to system metadata by driver, task params must not keep it
+	if host, err := data.Get("config", "host"); err == nil {
+		if hostDict, ok := host.(*jsonutils.JSONDict); ok {
+			hostDict.Remove("password")
+			hostDict.Remove("private_key")
+		}
+	}
 	task, err := taskman.TaskManager.NewTask(ctx, "MachineCreateTask", m, userCred, data, parentTaskId, "", nil)
 	if err != nil {
 		return errors.Wrapf(err, "new MachineCreateTask")
@@ -658,6 +665,39 @@ func (m *SMachine) SetResourceId(id string) error {
 	return err
 }
 
+// IsHost returns true when machine is an existing host registered by address
+func (m *SMachine) IsHost() bool {
+	return m.ResourceType == string(api.MachineResourceTypeBaremetal)
+}
+
+func (m *SMachine) GetSSHLoginInfo(ctx context.Context) (*api.MachineCreateHostConfig, error) {
+	ret := m.GetMetadataJson(ctx, api.MachineMetadataSSHLogin, GetAdminCred())
+	if ret == nil {
+		return nil, errors.Wrapf(errors.ErrNotFound, "not found %s in metadata", api.MachineMetadataSSHLogin)
+	}
+	info := new(api.MachineCreateHostConfig)
+	if err := ret.Unmarshal(info); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal %s", api.MachineMetadataSSHLogin)
+	}
+	// login saved before port supported
+	if info.Port == 0 {
+		info.Port = api.DefaultMachineHostSSHPort
+	}
+	return info, nil
+}
+
+func (m *SMachine) GetDetailsPreflightChecks(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) ([]api.MachinePreflightCheck, error) {
+	ret := make([]api.MachinePreflightCheck, 0)
+	obj := m.GetMetadataJson(ctx, api.MachineMetadataPreflightChecks, userCred)
+	if obj == nil {
+		return ret, nil
+	}
+	if err := obj.Unmarshal(&ret); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal %s", api.MachineMetadataPreflightChecks)
+	}
+	return ret, nil
+}
+
 func (m *SMachine) GetDetailsNetworkaddress(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) ([]*computeapi.NetworkAddressDetails, error) {
 	s, err := GetUserSession(ctx, userCred)
 	if err != nil {
@@ -0,0 +1,339 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/client"
+)
+
+const (
+	metricsAPIPath = "/apis/metrics.k8s.io/v1beta1"
+
+	// MonitorPrometheusService is prometheus service deployed by monitor component
+	MonitorPrometheusService = "monitor-monitor-stack-prometheus"
+	MonitorPrometheusPort    = "9090"
+)
+
+// metrics.k8s.io/v1beta1 types, k8s.io/metrics isn't vendored
+type containerMetrics struct {
+	Name  string          `json:"name"`
+	Usage v1.ResourceList `json:"usage"`
+}
+
+type podMetrics struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Timestamp         metav1.Time        `json:"timestamp"`
+	Containers        []containerMetrics `json:"containers"`
+}
+
+type podMetricsList struct {
+	Items []podMetrics `json:"items"`
+}
+
+type nodeMetrics struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Timestamp         metav1.Time     `json:"timestamp"`
+	Usage             v1.ResourceList `json:"usage"`
+}
+
+func getMetricsAPI(ctx context.Context, cli *client.ClusterManager, path string, selector labels.Selector, out interface{}) error {
+	req := cli.GetHandler().GetClientset().Discovery().RESTClient().Get().AbsPath(metricsAPIPath, path)
+	if selector != nil && !selector.Empty() {
+		req = req.Param("labelSelector", selector.String())
+	}
+	data, err := req.Do(ctx).Raw()
+	if err != nil {
+		return errors.Wrapf(err, "get metrics %s", path)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return errors.Wrapf(err, "unmarshal metrics %s", path)
+	}
+	return nil
+}
+
+func newResourceUsage(usage v1.ResourceList, timestamp metav1.Time) *api.ResourceUsage {
+	return &api.ResourceUsage{
+		CPUUsage:    usage.Cpu().MilliValue(),
+		MemoryUsage: usage.Memory().Value(),
+		Timestamp:   timestamp.Time,
+	}
+}
+
+func sumContainersUsage(containers []containerMetrics) v1.ResourceList {
+	cpu, mem := resource.Quantity{}, resource.Quantity{}
+	for _, c := range containers {
+		cpu.Add(*c.Usage.Cpu())
+		mem.Add(*c.Usage.Memory())
+	}
+	return v1.ResourceList{v1.ResourceCPU: cpu, v1.ResourceMemory: mem}
+}
+
+// sumPodsUsage sums usage of pods, timestamp is the latest one of samples
+func sumPodsUsage(pods []podMetrics) *api.ResourceUsage {
+	ret := &api.ResourceUsage{Pods: len(pods)}
+	for _, pod := range pods {
+		usage := newResourceUsage(sumContainersUsage(pod.Containers), pod.Timestamp)
+		ret.CPUUsage += usage.CPUUsage
+		ret.MemoryUsage += usage.MemoryUsage
+		if usage.Timestamp.After(ret.Timestamp) {
+			ret.Timestamp = usage.Timestamp
+		}
+	}
+	return ret
+}
+
+// GetNodeUsage returns current usage of node with fractions of capacity
+func GetNodeUsage(ctx context.Context, cli *client.ClusterManager, node *v1.Node) (*api.ResourceUsage, error) {
+	metrics := new(nodeMetrics)
+	if err := getMetricsAPI(ctx, cli, "nodes/"+node.GetName(), nil, metrics); err != nil {
+		return nil, err
+	}
+	ret := newResourceUsage(metrics.Usage, metrics.Timestamp)
+	if capacity := node.Status.Capacity.Cpu().MilliValue(); capacity > 0 {
+		ret.CPUUsageFraction = float64(ret.CPUUsage) / float64(capacity) * 100
+	}
+	if capacity := node.Status.Capacity.Memory().Value(); capacity > 0 {
+		ret.MemoryUsageFraction = float64(ret.MemoryUsage) / float64(capacity) * 100
+	}
+	return ret, nil
+}
+
+// GetPodUsage returns current usage of pod and its containers
+func GetPodUsage(ctx context.Context, cli *client.ClusterManager, namespace, name string) (*api.ResourceUsage, []api.ContainerResourceUsage, error) {
+	metrics := new(podMetrics)
+	if err := getMetricsAPI(ctx, cli, fmt.Sprintf("namespaces/%s/pods/%s", namespace, name), nil, metrics); err != nil {
+		return nil, nil, err
+	}
+	containers := make([]api.ContainerResourceUsage, 0, len(metrics.Containers))
+	for _, c := range metrics.Containers {
+		containers = append(containers, api.ContainerResourceUsage{
+			Name:        c.Name,
+			CPUUsage:    c.Usage.Cpu().MilliValue(),
+			MemoryUsage: c.Usage.Memory().Value(),
+		})
+	}
+	return newResourceUsage(sumContainersUsage(metrics.Containers), metrics.Timestamp), containers, nil
+}
+
+// GetPodsUsage sums current usage of pods in namespace matched by selector
+func GetPodsUsage(ctx context.Context, cli *client.ClusterManager, namespace string, selector labels.Selector) (*api.ResourceUsage, error) {
+	list := new(podMetricsList)
+	if err := getMetricsAPI(ctx, cli, fmt.Sprintf("namespaces/%s/pods", namespace), selector, list); err != nil {
+		return nil, err
+	}
+	return sumPodsUsage(list.Items), nil
+}
+
+// GetWorkloadUsage sums current usage of pods selected by workload selector
+func GetWorkloadUsage(ctx context.Context, cli *client.ClusterManager, namespace string, labelSelector *metav1.LabelSelector) (*api.ResourceUsage, error) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert label selector")
+	}
+	// nothing selector matches no pods, but metrics api treats empty selector as everything
+	if selector.Empty() {
+		return &api.ResourceUsage{}, nil
+	}
+	return GetPodsUsage(ctx, cli, namespace, selector)
+}
+
+// logUsageError logs failures of metrics api, not found means metrics-server isn't installed or has no samples yet
+func logUsageError(kind, name string, err error) {
+	if kerrors.IsNotFound(errors.Cause(err)) || kerrors.IsServiceUnavailable(errors.Cause(err)) {
+		log.Debugf("Metrics of %s %s unavailable: %v", kind, name, err)
+		return
+	}
+	log.Warningf("Get metrics of %s %s error: %v", kind, name, err)
+}
+
+func (c *SCluster) isPrometheusEnabled() (bool, error) {
+	comp, err := c.GetComponentByTypeNoError(api.ClusterComponentMonitor)
+	if err != nil {
+		return false, errors.Wrap(err, "get monitor component")
+	}
+	if comp == nil || !comp.Enabled.Bool() {
+		return false, nil
+	}
+	settings, err := comp.GetSettings()
+	if err != nil {
+		return false, errors.Wrap(err, "get monitor component settings")
+	}
+	if settings.Monitor == nil || settings.Monitor.Prometheus == nil {
+		return true, nil
+	}
+	return !settings.Monitor.Prometheus.Disable, nil
+}
+
+func promLabelMatchers(matchers ...string) string {
+	return "{" + strings.Join(matchers, ",") + "}"
+}
+
+// getUsageTimeSeriesQueries returns promql of cpu millicores and memory bytes built on cadvisor metrics
+func getUsageTimeSeriesQueries(kind, namespace, name string) (string, string, error) {
+	container := []string{`container!=""`, `image!=""`}
+	var matchers []string
+	switch kind {
+	case api.KindNameNode:
+		// root cgroup of node
+		matchers = []string{fmt.Sprintf("node=%q", name), `id="/"`}
+	case api.KindNameNamespace:
+		matchers = append([]string{fmt.Sprintf("namespace=%q", name)}, container...)
+	case api.KindNamePod:
+		matchers = append([]string{fmt.Sprintf("namespace=%q", namespace), fmt.Sprintf("pod=%q", name)}, container...)
+	case api.KindNameDeployment:
+		// pod name is <deployment>-<replicaset hash>-<random suffix>
+		matchers = append([]string{fmt.Sprintf("namespace=%q", namespace), fmt.Sprintf("pod=~%q", name+"-[a-z0-9]+-[a-z0-9]+")}, container...)
+	case api.KindNameStatefulSet:
+		matchers = append([]string{fmt.Sprintf("namespace=%q", namespace), fmt.Sprintf("pod=~%q", name+"-[0-9]+")}, container...)
+	default:
+		return "", "", httperrors.NewInputParameterError("unsupported kind %q", kind)
+	}
+	sel := promLabelMatchers(matchers...)
+	cpu := fmt.Sprintf("sum(rate(container_cpu_usage_seconds_total%s[5m])) * 1000", sel)
+	mem := fmt.Sprintf("sum(container_memory_working_set_bytes%s)", sel)
+	return cpu, mem, nil
+}
+
+func parseUsageTimeRange(input *api.ClusterUsageTimeSeriesInput, now time.Time) (time.Time, time.Time, time.Duration, error) {
+	end, start, step := now, time.Time{}, api.UsageTimeSeriesDefaultStep
+	var err error
+	if input.End != "" {
+		if end, err = time.Parse(time.RFC3339, input.End); err != nil {
+			return start, end, step, httperrors.NewInputParameterError("invalid end %q: %v", input.End, err)
+		}
+	}
+	start = end.Add(-api.UsageTimeSeriesDefaultRange)
+	if input.Start != "" {
+		if start, err = time.Parse(time.RFC3339, input.Start); err != nil {
+			return start, end, step, httperrors.NewInputParameterError("invalid start %q: %v", input.Start, err)
+		}
+	}
+	if !start.Before(end) {
+		return start, end, step, httperrors.NewInputParameterError("start %s must be before end %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	if input.Step != "" {
+		if step, err = time.ParseDuration(input.Step); err != nil || step <= 0 {
+			return start, end, step, httperrors.NewInputParameterError("invalid step %q", input.Step)
+		}
+	}
+	if end.Sub(start)/step > api.UsageTimeSeriesMaxPoints {
+		return start, end, step, httperrors.NewInputParameterError("too many points, increase step or decrease range")
+	}
+	return start, end, step, nil
+}
+
+type promQueryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+func parsePromMatrix(data []byte) ([]api.UsagePoint, error) {
+	resp := new(promQueryResponse)
+	if err := json.Unmarshal(data, resp); err != nil {
+		return nil, errors.Wrap(err, "unmarshal prometheus response")
+	}
+	if resp.Status != "success" {
+		return nil, errors.Errorf("prometheus query %s: %s", resp.Status, resp.Error)
+	}
+	if resp.Data.ResultType != model.ValMatrix.String() {
+		return nil, errors.Errorf("unexpected prometheus result type %q", resp.Data.ResultType)
+	}
+	var matrix model.Matrix
+	if err := json.Unmarshal(resp.Data.Result, &matrix); err != nil {
+		return nil, errors.Wrap(err, "unmarshal prometheus matrix")
+	}
+	ret := make([]api.UsagePoint, 0)
+	// sum() returns at most one series
+	for _, stream := range matrix {
+		for _, val := range stream.Values {
+			ret = append(ret, api.UsagePoint{
+				Timestamp: val.Timestamp.Unix(),
+				Value:     float64(val.Value),
+			})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Timestamp < ret[j].Timestamp })
+	return ret, nil
+}
+
+func queryPrometheusRange(ctx context.Context, cli *client.ClusterManager, query string, start, end time.Time, step time.Duration) ([]api.UsagePoint, error) {
+	params := map[string]string{
+		"query": query,
+		"start": start.UTC().Format(time.RFC3339),
+		"end":   end.UTC().Format(time.RFC3339),
+		"step":  step.String(),
+	}
+	data, err := cli.GetHandler().GetClientset().CoreV1().Services(MonitorNamespace).
+		ProxyGet("http", MonitorPrometheusService, MonitorPrometheusPort, "api/v1/query_range", params).
+		DoRaw(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "query prometheus %q", query)
+	}
+	return parsePromMatrix(data)
+}
+
+// GetDetailsUsageTimeSeries queries cpu and memory usage of resource from prometheus of monitor component
+func (c *SCluster) GetDetailsUsageTimeSeries(ctx context.Context, userCred mcclient.TokenCredential, query *api.ClusterUsageTimeSeriesInput) (*api.UsageTimeSeries, error) {
+	if query.Name == "" {
+		return nil, httperrors.NewNotEmptyError("name is empty")
+	}
+	if query.Kind == api.KindNameNode || query.Kind == api.KindNameNamespace {
+		query.Namespace = ""
+	} else if query.Namespace == "" {
+		return nil, httperrors.NewNotEmptyError("namespace is empty")
+	}
+	cpuQuery, memQuery, err := getUsageTimeSeriesQueries(query.Kind, query.Namespace, query.Name)
+	if err != nil {
+		return nil, err
+	}
+	start, end, step, err := parseUsageTimeRange(query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := c.isPrometheusEnabled()
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, httperrors.NewNotSupportedError("prometheus of monitor component isn't enabled in cluster %s", c.GetName())
+	}
+	cli, err := c.GetRemoteClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get remote kubernetes client")
+	}
+	ret := &api.UsageTimeSeries{
+		Kind:      query.Kind,
+		Namespace: query.Namespace,
+		Name:      query.Name,
+		Step:      step.String(),
+	}
+	if ret.CPU, err = queryPrometheusRange(ctx, cli, cpuQuery, start, end, step); err != nil {
+		return nil, errors.Wrap(err, "cpu usage")
+	}
+	if ret.Memory, err = queryPrometheusRange(ctx, cli, memQuery, start, end, step); err != nil {
+		return nil, errors.Wrap(err, "memory usage")
+	}
+	return ret, nil
+}
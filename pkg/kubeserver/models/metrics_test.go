@@ -0,0 +1,123 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+func TestSumPodsUsage(t *testing.T) {
+	t1 := metav1.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	t2 := metav1.NewTime(t1.Add(time.Minute))
+	newUsage := func(cpu, mem string) v1.ResourceList {
+		return v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(cpu),
+			v1.ResourceMemory: resource.MustParse(mem),
+		}
+	}
+	pods := []podMetrics{
+		{
+			Timestamp: t1,
+			Containers: []containerMetrics{
+				{Name: "app", Usage: newUsage("250m", "128Mi")},
+				{Name: "sidecar", Usage: newUsage("1500u", "16Mi")},
+			},
+		},
+		{
+			Timestamp:  t2,
+			Containers: []containerMetrics{{Name: "app", Usage: newUsage("1", "1Gi")}},
+		},
+	}
+	got := sumPodsUsage(pods)
+	want := &api.ResourceUsage{
+		CPUUsage:    1252,
+		MemoryUsage: (128 + 16 + 1024) * 1024 * 1024,
+		Pods:        2,
+		Timestamp:   t2.Time,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sumPodsUsage() = %#v, want %#v", got, want)
+	}
+}
+
+func TestGetUsageTimeSeriesQueries(t *testing.T) {
+	tests := []struct {
+		kind    string
+		wantCPU string
+		wantMem string
+		wantErr bool
+	}{
+		{
+			kind:    api.KindNameNode,
+			wantCPU: `sum(rate(container_cpu_usage_seconds_total{node="web",id="/"}[5m])) * 1000`,
+			wantMem: `sum(container_memory_working_set_bytes{node="web",id="/"})`,
+		},
+		{
+			kind:    api.KindNameDeployment,
+			wantCPU: `sum(rate(container_cpu_usage_seconds_total{namespace="default",pod=~"web-[a-z0-9]+-[a-z0-9]+",container!="",image!=""}[5m])) * 1000`,
+			wantMem: `sum(container_memory_working_set_bytes{namespace="default",pod=~"web-[a-z0-9]+-[a-z0-9]+",container!="",image!=""})`,
+		},
+		{
+			kind:    api.KindNameStatefulSet,
+			wantCPU: `sum(rate(container_cpu_usage_seconds_total{namespace="default",pod=~"web-[0-9]+",container!="",image!=""}[5m])) * 1000`,
+			wantMem: `sum(container_memory_working_set_bytes{namespace="default",pod=~"web-[0-9]+",container!="",image!=""})`,
+		},
+		{
+			kind:    "Service",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			cpu, mem, err := getUsageTimeSeriesQueries(tt.kind, "default", "web")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getUsageTimeSeriesQueries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if cpu != tt.wantCPU || mem != tt.wantMem {
+				t.Errorf("getUsageTimeSeriesQueries() = %s, %s, want %s, %s", cpu, mem, tt.wantCPU, tt.wantMem)
+			}
+		})
+	}
+}
+
+func TestParseUsageTimeRange(t *testing.T) {
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	start, end, step, err := parseUsageTimeRange(&api.ClusterUsageTimeSeriesInput{}, now)
+	if err != nil {
+		t.Fatalf("default range: %v", err)
+	}
+	if !end.Equal(now) || !start.Equal(now.Add(-time.Hour)) || step != time.Minute {
+		t.Errorf("default range = %s, %s, %s", start, end, step)
+	}
+	for _, input := range []api.ClusterUsageTimeSeriesInput{
+		{Start: "2021-01-01T12:00:00Z", End: "2021-01-01T11:00:00Z"},
+		{Step: "-1m"},
+		{Start: "yesterday"},
+		{Start: "2020-01-01T00:00:00Z", Step: "1s"},
+	} {
+		if _, _, _, err := parseUsageTimeRange(&input, now); err == nil {
+			t.Errorf("parseUsageTimeRange(%#v) should fail", input)
+		}
+	}
+}
+
+func TestParsePromMatrix(t *testing.T) {
+	data := []byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[1609502460,"20.5"],[1609502400,"10"]]}]}}`)
+	got, err := parsePromMatrix(data)
+	if err != nil {
+		t.Fatalf("parsePromMatrix: %v", err)
+	}
+	want := []api.UsagePoint{{Timestamp: 1609502400, Value: 10}, {Timestamp: 1609502460, Value: 20.5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePromMatrix() = %v, want %v", got, want)
+	}
+	if _, err := parsePromMatrix([]byte(`{"status":"error","error":"bad query"}`)); err == nil {
+		t.Errorf("error response should fail")
+	}
+}
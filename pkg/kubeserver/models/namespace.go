@@ -170,6 +170,14 @@ func (m *SNamespace) GetDetails(ctx context.Context, cli *client.ClusterManager,
 	out := api.NamespaceDetailV2{
 		ClusterResourceDetail: detail,
 	}
+	if !isList {
+		usage, err := GetPodsUsage(ctx, cli, m.GetName(), nil)
+		if err != nil {
+			logUsageError(api.KindNameNamespace, m.GetName(), err)
+		} else {
+			out.Usage = usage
+		}
+	}
 	return out
 }
 
@@ -0,0 +1,216 @@
+package models
+
+import (
+	"context"
+	"net"
+
+	v1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/client"
+)
+
+var (
+	networkPolicyManager *SNetworkPolicyManager
+	_                    IClusterModel = new(SNetworkPolicy)
+)
+
+func init() {
+	GetNetworkPolicyManager()
+}
+
+func GetNetworkPolicyManager() *SNetworkPolicyManager {
+	if networkPolicyManager == nil {
+		networkPolicyManager = NewK8sNamespaceModelManager(func() ISyncableManager {
+			return &SNetworkPolicyManager{
+				SNamespaceResourceBaseManager: NewNamespaceResourceBaseManager(
+					SNetworkPolicy{},
+					"networkpolicies_tbl",
+					"networkpolicy",
+					"networkpolicies",
+					api.ResourceNameNetworkPolicy,
+					networking.GroupName,
+					networking.SchemeGroupVersion.Version,
+					api.KindNameNetworkPolicy,
+					new(networking.NetworkPolicy),
+				),
+			}
+		}).(*SNetworkPolicyManager)
+	}
+	return networkPolicyManager
+}
+
+// +onecloud:swagger-gen-model-singular=networkpolicy
+// +onecloud:swagger-gen-model-plural=networkpolicies
+type SNetworkPolicyManager struct {
+	SNamespaceResourceBaseManager
+}
+
+type SNetworkPolicy struct {
+	SNamespaceResourceBase
+}
+
+func (m *SNetworkPolicyManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.NetworkPolicyCreateInput) (*api.NetworkPolicyCreateInput, error) {
+	nInput, err := m.SNamespaceResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, &input.NamespaceResourceCreateInput)
+	if err != nil {
+		return nil, err
+	}
+	input.NamespaceResourceCreateInput = *nInput
+	if err := ValidateNetworkPolicySpec(&input.NetworkPolicySpec); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (m *SNetworkPolicyManager) NewRemoteObjectForCreate(model IClusterModel, cli *client.ClusterManager, data jsonutils.JSONObject) (interface{}, error) {
+	input := new(api.NetworkPolicyCreateInput)
+	if err := data.Unmarshal(input); err != nil {
+		return nil, errors.Wrap(err, "networkpolicy input unmarshal error")
+	}
+	objMeta, err := input.ToObjectMeta(model.(api.INamespaceGetter))
+	if err != nil {
+		return nil, errors.Wrap(err, "networkpolicy input get meta error")
+	}
+	return &networking.NetworkPolicy{
+		ObjectMeta: objMeta,
+		Spec:       input.NetworkPolicySpec,
+	}, nil
+}
+
+func (obj *SNetworkPolicy) NewRemoteObjectForUpdate(cli *client.ClusterManager, remoteObj interface{}, data jsonutils.JSONObject) (interface{}, error) {
+	np := remoteObj.(*networking.NetworkPolicy).DeepCopy()
+	input := new(api.NetworkPolicyUpdateInput)
+	if err := data.Unmarshal(input); err != nil {
+		return nil, err
+	}
+	if err := ValidateNetworkPolicySpec(&input.NetworkPolicySpec); err != nil {
+		return nil, err
+	}
+	np.Spec = input.NetworkPolicySpec
+	return np, nil
+}
+
+func (obj *SNetworkPolicy) GetDetails(ctx context.Context, cli *client.ClusterManager, base interface{}, k8sObj runtime.Object, isList bool) interface{} {
+	np := k8sObj.(*networking.NetworkPolicy)
+	return api.NetworkPolicyDetail{
+		NamespaceResourceDetail: obj.SNamespaceResourceBase.GetDetails(ctx, cli, base, k8sObj, isList).(api.NamespaceResourceDetail),
+		PodSelector:             np.Spec.PodSelector,
+		PolicyTypes:             getNetworkPolicyTypes(np),
+		Ingress:                 np.Spec.Ingress,
+		Egress:                  np.Spec.Egress,
+	}
+}
+
+// getNetworkPolicyTypes returns policy types defaulted like kube-apiserver
+func getNetworkPolicyTypes(np *networking.NetworkPolicy) []networking.PolicyType {
+	if len(np.Spec.PolicyTypes) != 0 {
+		return np.Spec.PolicyTypes
+	}
+	ret := []networking.PolicyType{networking.PolicyTypeIngress}
+	if len(np.Spec.Egress) != 0 {
+		ret = append(ret, networking.PolicyTypeEgress)
+	}
+	return ret
+}
+
+// ValidateNetworkPolicySpec validates selectors, peers and ports of networking/v1 spec
+func ValidateNetworkPolicySpec(spec *networking.NetworkPolicySpec) error {
+	if _, err := metav1.LabelSelectorAsSelector(&spec.PodSelector); err != nil {
+		return httperrors.NewInputParameterError("invalid podSelector: %v", err)
+	}
+	for _, pt := range spec.PolicyTypes {
+		if pt != networking.PolicyTypeIngress && pt != networking.PolicyTypeEgress {
+			return httperrors.NewInputParameterError("unsupported policy type %q", pt)
+		}
+	}
+	for i, rule := range spec.Ingress {
+		if err := validateNetworkPolicyPorts(rule.Ports); err != nil {
+			return errors.Wrapf(err, "ingress[%d]", i)
+		}
+		if err := validateNetworkPolicyPeers(rule.From); err != nil {
+			return errors.Wrapf(err, "ingress[%d] from", i)
+		}
+	}
+	for i, rule := range spec.Egress {
+		if err := validateNetworkPolicyPorts(rule.Ports); err != nil {
+			return errors.Wrapf(err, "egress[%d]", i)
+		}
+		if err := validateNetworkPolicyPeers(rule.To); err != nil {
+			return errors.Wrapf(err, "egress[%d] to", i)
+		}
+	}
+	return nil
+}
+
+func validateNetworkPolicyPorts(ports []networking.NetworkPolicyPort) error {
+	for i, port := range ports {
+		if port.Protocol != nil {
+			switch *port.Protocol {
+			case v1.ProtocolTCP, v1.ProtocolUDP, v1.ProtocolSCTP:
+			default:
+				return httperrors.NewInputParameterError("ports[%d] unsupported protocol %q", i, *port.Protocol)
+			}
+		}
+		if port.Port == nil {
+			continue
+		}
+		if port.Port.Type == intstr.Int {
+			if msgs := validation.IsValidPortNum(int(port.Port.IntVal)); len(msgs) != 0 {
+				return httperrors.NewInputParameterError("ports[%d] %v", i, msgs)
+			}
+		} else if msgs := validation.IsValidPortName(port.Port.StrVal); len(msgs) != 0 {
+			return httperrors.NewInputParameterError("ports[%d] %v", i, msgs)
+		}
+	}
+	return nil
+}
+
+func validateNetworkPolicyPeers(peers []networking.NetworkPolicyPeer) error {
+	for i, peer := range peers {
+		if peer.IPBlock != nil {
+			if peer.PodSelector != nil || peer.NamespaceSelector != nil {
+				return httperrors.NewInputParameterError("peers[%d] ipBlock can't be used with selectors", i)
+			}
+			_, cidr, err := net.ParseCIDR(peer.IPBlock.CIDR)
+			if err != nil {
+				return httperrors.NewInputParameterError("peers[%d] invalid cidr %q", i, peer.IPBlock.CIDR)
+			}
+			for _, except := range peer.IPBlock.Except {
+				exceptIP, exceptNet, err := net.ParseCIDR(except)
+				if err != nil {
+					return httperrors.NewInputParameterError("peers[%d] invalid except cidr %q", i, except)
+				}
+				cidrOnes, _ := cidr.Mask.Size()
+				exceptOnes, _ := exceptNet.Mask.Size()
+				if !cidr.Contains(exceptIP) || exceptOnes < cidrOnes {
+					return httperrors.NewInputParameterError("peers[%d] except %q isn't within cidr %q", i, except, peer.IPBlock.CIDR)
+				}
+			}
+			continue
+		}
+		if peer.PodSelector == nil && peer.NamespaceSelector == nil {
+			return httperrors.NewInputParameterError("peers[%d] must specify podSelector, namespaceSelector or ipBlock", i)
+		}
+		if peer.PodSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(peer.PodSelector); err != nil {
+				return httperrors.NewInputParameterError("peers[%d] invalid podSelector: %v", i, err)
+			}
+		}
+		if peer.NamespaceSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(peer.NamespaceSelector); err != nil {
+				return httperrors.NewInputParameterError("peers[%d] invalid namespaceSelector: %v", i, err)
+			}
+		}
+	}
+	return nil
+}
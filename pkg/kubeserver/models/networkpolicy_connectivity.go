@@ -0,0 +1,381 @@
+package models
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/client"
+)
+
+// networkPolicyEndpoint is one side of connection, pod is nil when only namespace is given,
+// which is evaluated as a pod without labels and ip
+type networkPolicyEndpoint struct {
+	namespace *v1.Namespace
+	pod       *v1.Pod
+}
+
+func (e networkPolicyEndpoint) podLabels() labels.Set {
+	if e.pod == nil {
+		return labels.Set{}
+	}
+	return labels.Set(e.pod.GetLabels())
+}
+
+func (e networkPolicyEndpoint) podIP() string {
+	if e.pod == nil {
+		return ""
+	}
+	return e.pod.Status.PodIP
+}
+
+func labelSelectorMatches(selector *metav1.LabelSelector, set labels.Set) bool {
+	sel, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return sel.Matches(set)
+}
+
+func networkPolicySelects(np *networking.NetworkPolicy, target networkPolicyEndpoint, policyType networking.PolicyType) bool {
+	if np.GetNamespace() != target.namespace.GetName() {
+		return false
+	}
+	// host network pods aren't affected by network policies
+	if target.pod != nil && target.pod.Spec.HostNetwork {
+		return false
+	}
+	hasType := false
+	for _, pt := range getNetworkPolicyTypes(np) {
+		if pt == policyType {
+			hasType = true
+			break
+		}
+	}
+	return hasType && labelSelectorMatches(&np.Spec.PodSelector, target.podLabels())
+}
+
+func ipBlockContains(block *networking.IPBlock, ipStr string) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	_, cidr, err := net.ParseCIDR(block.CIDR)
+	if err != nil || !cidr.Contains(ip) {
+		return false
+	}
+	for _, except := range block.Except {
+		if _, exceptNet, err := net.ParseCIDR(except); err == nil && exceptNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// networkPolicyPeerMatches checks peer of rule in policy namespace matches the other side of connection
+func networkPolicyPeerMatches(peer networking.NetworkPolicyPeer, policyNamespace string, target networkPolicyEndpoint) bool {
+	if peer.IPBlock != nil {
+		return ipBlockContains(peer.IPBlock, target.podIP())
+	}
+	if peer.NamespaceSelector == nil {
+		if target.namespace.GetName() != policyNamespace {
+			return false
+		}
+	} else if !labelSelectorMatches(peer.NamespaceSelector, labels.Set(target.namespace.GetLabels())) {
+		return false
+	}
+	if peer.PodSelector != nil {
+		return labelSelectorMatches(peer.PodSelector, target.podLabels())
+	}
+	return true
+}
+
+func networkPolicyPeersMatch(peers []networking.NetworkPolicyPeer, policyNamespace string, target networkPolicyEndpoint) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, peer := range peers {
+		if networkPolicyPeerMatches(peer, policyNamespace, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePodPort returns container port number of name in pod
+func resolvePodPort(pod *v1.Pod, name string, protocol v1.Protocol) (int32, bool) {
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			proto := p.Protocol
+			if proto == "" {
+				proto = v1.ProtocolTCP
+			}
+			if p.Name == name && proto == protocol {
+				return p.ContainerPort, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// networkPolicyPortsMatch checks destination port, named ports of both ingress and egress rules refer to destination pod
+func networkPolicyPortsMatch(ports []networking.NetworkPolicyPort, dst *v1.Pod, port int32, protocol v1.Protocol) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, p := range ports {
+		proto := v1.ProtocolTCP
+		if p.Protocol != nil {
+			proto = *p.Protocol
+		}
+		if proto != protocol {
+			continue
+		}
+		if p.Port == nil {
+			return true
+		}
+		if p.Port.Type == intstr.Int {
+			if p.Port.IntVal == port {
+				return true
+			}
+			continue
+		}
+		if num, ok := resolvePodPort(dst, p.Port.StrVal, proto); ok && num == port {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateNetworkPolicyIngress evaluates ingress policies selecting destination pod
+func evaluateNetworkPolicyIngress(policies []*networking.NetworkPolicy, src, dst networkPolicyEndpoint, port int32, protocol v1.Protocol) api.NetworkPolicyDirectionResult {
+	ret := api.NetworkPolicyDirectionResult{
+		Policies:     make([]string, 0),
+		MatchedRules: make([]api.NetworkPolicyRuleRef, 0),
+	}
+	for _, np := range policies {
+		if !networkPolicySelects(np, dst, networking.PolicyTypeIngress) {
+			continue
+		}
+		ret.Isolated = true
+		ret.Policies = append(ret.Policies, np.GetName())
+		for i, rule := range np.Spec.Ingress {
+			if networkPolicyPortsMatch(rule.Ports, dst.pod, port, protocol) && networkPolicyPeersMatch(rule.From, np.GetNamespace(), src) {
+				ret.MatchedRules = append(ret.MatchedRules, api.NetworkPolicyRuleRef{Policy: np.GetName(), Rule: i})
+			}
+		}
+	}
+	ret.Allowed = !ret.Isolated || len(ret.MatchedRules) != 0
+	return ret
+}
+
+// evaluateNetworkPolicyEgress evaluates egress policies selecting source pod
+func evaluateNetworkPolicyEgress(policies []*networking.NetworkPolicy, src, dst networkPolicyEndpoint, port int32, protocol v1.Protocol) api.NetworkPolicyDirectionResult {
+	ret := api.NetworkPolicyDirectionResult{
+		Policies:     make([]string, 0),
+		MatchedRules: make([]api.NetworkPolicyRuleRef, 0),
+	}
+	for _, np := range policies {
+		if !networkPolicySelects(np, src, networking.PolicyTypeEgress) {
+			continue
+		}
+		ret.Isolated = true
+		ret.Policies = append(ret.Policies, np.GetName())
+		for i, rule := range np.Spec.Egress {
+			if networkPolicyPortsMatch(rule.Ports, dst.pod, port, protocol) && networkPolicyPeersMatch(rule.To, np.GetNamespace(), dst) {
+				ret.MatchedRules = append(ret.MatchedRules, api.NetworkPolicyRuleRef{Policy: np.GetName(), Rule: i})
+			}
+		}
+	}
+	ret.Allowed = !ret.Isolated || len(ret.MatchedRules) != 0
+	return ret
+}
+
+// evaluateNetworkConnectivity evaluates connection is allowed by both egress policies of source and ingress policies of destination
+func evaluateNetworkConnectivity(policies []*networking.NetworkPolicy, src, dst networkPolicyEndpoint, port int32, protocol v1.Protocol) *api.ClusterNetworkConnectivity {
+	ret := &api.ClusterNetworkConnectivity{
+		Verdict:  api.NetworkPolicyVerdictDeny,
+		Port:     port,
+		Protocol: string(protocol),
+		Egress:   evaluateNetworkPolicyEgress(policies, src, dst, port, protocol),
+		Ingress:  evaluateNetworkPolicyIngress(policies, src, dst, port, protocol),
+	}
+	if ret.Egress.Allowed && ret.Ingress.Allowed {
+		ret.Verdict = api.NetworkPolicyVerdictAllow
+	}
+	return ret
+}
+
+// getNamespaceNetworkIsolation summarizes which pods are isolated by policies of namespace
+func getNamespaceNetworkIsolation(ns *v1.Namespace, pods []*v1.Pod, policies []*networking.NetworkPolicy) *api.NamespaceNetworkIsolation {
+	ret := &api.NamespaceNetworkIsolation{
+		Policies: make([]string, 0),
+		Pods:     make([]api.PodNetworkIsolation, 0),
+	}
+	for _, np := range policies {
+		if np.GetNamespace() == ns.GetName() {
+			ret.Policies = append(ret.Policies, np.GetName())
+		}
+	}
+	sort.Strings(ret.Policies)
+	for _, pod := range pods {
+		target := networkPolicyEndpoint{namespace: ns, pod: pod}
+		item := api.PodNetworkIsolation{
+			Name:            pod.GetName(),
+			IngressPolicies: make([]string, 0),
+			EgressPolicies:  make([]string, 0),
+		}
+		for _, np := range policies {
+			if networkPolicySelects(np, target, networking.PolicyTypeIngress) {
+				item.IngressPolicies = append(item.IngressPolicies, np.GetName())
+			}
+			if networkPolicySelects(np, target, networking.PolicyTypeEgress) {
+				item.EgressPolicies = append(item.EgressPolicies, np.GetName())
+			}
+		}
+		item.IngressIsolated = len(item.IngressPolicies) != 0
+		item.EgressIsolated = len(item.EgressPolicies) != 0
+		if item.IngressIsolated {
+			ret.IngressIsolated++
+		}
+		if item.EgressIsolated {
+			ret.EgressIsolated++
+		}
+		ret.Pods = append(ret.Pods, item)
+	}
+	sort.Slice(ret.Pods, func(i, j int) bool { return ret.Pods[i].Name < ret.Pods[j].Name })
+	return ret
+}
+
+func listRemoteNetworkPolicies(cli *client.ClusterManager, namespaces ...string) ([]*networking.NetworkPolicy, error) {
+	ret := make([]*networking.NetworkPolicy, 0)
+	for _, ns := range namespaces {
+		objs, err := cli.GetHandler().List(api.ResourceNameNetworkPolicy, ns, "")
+		if err != nil {
+			return nil, errors.Wrapf(err, "list namespace %s networkpolicies", ns)
+		}
+		for _, obj := range objs {
+			ret = append(ret, obj.(*networking.NetworkPolicy))
+		}
+	}
+	return ret, nil
+}
+
+func getRemoteNamespace(cli *client.ClusterManager, name string) (*v1.Namespace, error) {
+	obj, err := cli.GetHandler().Get(api.ResourceNameNamespace, "", name)
+	if err != nil {
+		return nil, httperrors.NewNotFoundError("namespace %s: %v", name, err)
+	}
+	return obj.(*v1.Namespace), nil
+}
+
+func getRemotePod(cli *client.ClusterManager, namespace, name string) (*v1.Pod, error) {
+	obj, err := cli.GetHandler().Get(api.ResourceNamePod, namespace, name)
+	if err != nil {
+		return nil, httperrors.NewNotFoundError("pod %s/%s: %v", namespace, name, err)
+	}
+	return obj.(*v1.Pod), nil
+}
+
+// GetDetailsNetworkConnectivity evaluates network policies applied to connection from source pod or namespace to destination pod port
+func (c *SCluster) GetDetailsNetworkConnectivity(ctx context.Context, userCred mcclient.TokenCredential, query *api.ClusterNetworkConnectivityInput) (*api.ClusterNetworkConnectivity, error) {
+	if query.SourceNamespace == "" {
+		return nil, httperrors.NewNotEmptyError("source_namespace is empty")
+	}
+	if query.DestinationPod == "" {
+		return nil, httperrors.NewNotEmptyError("destination_pod is empty")
+	}
+	if query.Port == "" {
+		return nil, httperrors.NewNotEmptyError("port is empty")
+	}
+	if query.DestinationNamespace == "" {
+		query.DestinationNamespace = query.SourceNamespace
+	}
+	protocol := v1.Protocol(strings.ToUpper(query.Protocol))
+	if protocol == "" {
+		protocol = v1.ProtocolTCP
+	}
+	cli, err := c.GetRemoteClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get remote kubernetes client")
+	}
+
+	src := networkPolicyEndpoint{}
+	if src.namespace, err = getRemoteNamespace(cli, query.SourceNamespace); err != nil {
+		return nil, err
+	}
+	if query.SourcePod != "" {
+		if src.pod, err = getRemotePod(cli, query.SourceNamespace, query.SourcePod); err != nil {
+			return nil, err
+		}
+	}
+	dst := networkPolicyEndpoint{}
+	if dst.namespace, err = getRemoteNamespace(cli, query.DestinationNamespace); err != nil {
+		return nil, err
+	}
+	if dst.pod, err = getRemotePod(cli, query.DestinationNamespace, query.DestinationPod); err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.Atoi(query.Port)
+	if err != nil {
+		num, ok := resolvePodPort(dst.pod, query.Port, protocol)
+		if !ok {
+			return nil, httperrors.NewInputParameterError("pod %s/%s has no %s port named %q", query.DestinationNamespace, query.DestinationPod, protocol, query.Port)
+		}
+		port = int(num)
+	}
+
+	namespaces := []string{query.SourceNamespace}
+	if query.DestinationNamespace != query.SourceNamespace {
+		namespaces = append(namespaces, query.DestinationNamespace)
+	}
+	policies, err := listRemoteNetworkPolicies(cli, namespaces...)
+	if err != nil {
+		return nil, err
+	}
+	return evaluateNetworkConnectivity(policies, src, dst, int32(port), protocol), nil
+}
+
+// GetDetailsNetworkIsolation lists pods isolated for ingress and egress by network policies
+func (ns *SNamespace) GetDetailsNetworkIsolation(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.NamespaceNetworkIsolation, error) {
+	cli, err := ns.GetClusterClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get cluster client")
+	}
+	remoteNs, err := getRemoteNamespace(cli, ns.GetName())
+	if err != nil {
+		return nil, err
+	}
+	objs, err := cli.GetHandler().List(api.ResourceNamePod, ns.GetName(), "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "list namespace %s pods", ns.GetName())
+	}
+	pods := make([]*v1.Pod, 0, len(objs))
+	for _, obj := range objs {
+		pod := obj.(*v1.Pod)
+		if isPodFinished(pod) {
+			continue
+		}
+		pods = append(pods, pod)
+	}
+	policies, err := listRemoteNetworkPolicies(cli, ns.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return getNamespaceNetworkIsolation(remoteNs, pods, policies), nil
+}
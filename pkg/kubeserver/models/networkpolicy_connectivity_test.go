@@ -0,0 +1,234 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+func newNetpolTestNamespace(name string, labels map[string]string) *v1.Namespace {
+	return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func newNetpolTestPod(ns, name, ip string, labels map[string]string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, Labels: labels},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name:  "app",
+					Ports: []v1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+				},
+			},
+		},
+		Status: v1.PodStatus{PodIP: ip},
+	}
+}
+
+func TestEvaluateNetworkConnectivity(t *testing.T) {
+	prodNs := newNetpolTestNamespace("prod", map[string]string{"env": "prod"})
+	monNs := newNetpolTestNamespace("monitor", map[string]string{"team": "ops"})
+	web := networkPolicyEndpoint{namespace: prodNs, pod: newNetpolTestPod("prod", "web", "10.0.0.2", map[string]string{"app": "web"})}
+	db := networkPolicyEndpoint{namespace: prodNs, pod: newNetpolTestPod("prod", "db", "10.0.0.3", map[string]string{"app": "db"})}
+	prom := networkPolicyEndpoint{namespace: monNs, pod: newNetpolTestPod("monitor", "prom", "10.0.1.2", map[string]string{"app": "prom"})}
+	monNsOnly := networkPolicyEndpoint{namespace: monNs}
+
+	httpPort := intstr.FromString("http")
+	tcp5432 := intstr.FromInt(5432)
+	policies := []*networking.NetworkPolicy{
+		{
+			// deny all ingress in prod
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "default-deny"},
+			Spec:       networking.NetworkPolicySpec{},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "db-from-web"},
+			Spec: networking.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+				Ingress: []networking.NetworkPolicyIngressRule{
+					{
+						Ports: []networking.NetworkPolicyPort{{Port: &tcp5432}},
+						From: []networking.NetworkPolicyPeer{
+							{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+						},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "metrics-from-ops"},
+			Spec: networking.NetworkPolicySpec{
+				Ingress: []networking.NetworkPolicyIngressRule{
+					{
+						Ports: []networking.NetworkPolicyPort{{Port: &httpPort}},
+						From: []networking.NetworkPolicyPeer{
+							{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ops"}}},
+						},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-egress"},
+			Spec: networking.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				PolicyTypes: []networking.PolicyType{networking.PolicyTypeEgress},
+				Egress: []networking.NetworkPolicyEgressRule{
+					{
+						To: []networking.NetworkPolicyPeer{
+							{IPBlock: &networking.IPBlock{CIDR: "10.0.0.0/24", Except: []string{"10.0.0.128/25"}}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		src         networkPolicyEndpoint
+		dst         networkPolicyEndpoint
+		port        int32
+		protocol    v1.Protocol
+		wantVerdict string
+		wantIngress []api.NetworkPolicyRuleRef
+		wantEgress  []api.NetworkPolicyRuleRef
+	}{
+		{
+			name:        "web to db postgres",
+			src:         web,
+			dst:         db,
+			port:        5432,
+			protocol:    v1.ProtocolTCP,
+			wantVerdict: api.NetworkPolicyVerdictAllow,
+			wantIngress: []api.NetworkPolicyRuleRef{{Policy: "db-from-web", Rule: 0}},
+			wantEgress:  []api.NetworkPolicyRuleRef{{Policy: "web-egress", Rule: 0}},
+		},
+		{
+			name:        "web to db udp",
+			src:         web,
+			dst:         db,
+			port:        5432,
+			protocol:    v1.ProtocolUDP,
+			wantVerdict: api.NetworkPolicyVerdictDeny,
+			wantIngress: []api.NetworkPolicyRuleRef{},
+			wantEgress:  []api.NetworkPolicyRuleRef{{Policy: "web-egress", Rule: 0}},
+		},
+		{
+			name:        "db to web is denied by default deny",
+			src:         db,
+			dst:         web,
+			port:        8080,
+			protocol:    v1.ProtocolTCP,
+			wantVerdict: api.NetworkPolicyVerdictDeny,
+			wantIngress: []api.NetworkPolicyRuleRef{},
+			wantEgress:  []api.NetworkPolicyRuleRef{},
+		},
+		{
+			name:        "prom scrapes named port",
+			src:         prom,
+			dst:         web,
+			port:        8080,
+			protocol:    v1.ProtocolTCP,
+			wantVerdict: api.NetworkPolicyVerdictAllow,
+			wantIngress: []api.NetworkPolicyRuleRef{{Policy: "metrics-from-ops", Rule: 0}},
+			wantEgress:  []api.NetworkPolicyRuleRef{},
+		},
+		{
+			name:        "monitor namespace as source",
+			src:         monNsOnly,
+			dst:         db,
+			port:        8080,
+			protocol:    v1.ProtocolTCP,
+			wantVerdict: api.NetworkPolicyVerdictAllow,
+			wantIngress: []api.NetworkPolicyRuleRef{{Policy: "metrics-from-ops", Rule: 0}},
+			wantEgress:  []api.NetworkPolicyRuleRef{},
+		},
+		{
+			name: "web egress to ip in except",
+			src:  web,
+			dst: networkPolicyEndpoint{
+				namespace: monNs,
+				pod:       newNetpolTestPod("monitor", "other", "10.0.0.200", nil),
+			},
+			port:        80,
+			protocol:    v1.ProtocolTCP,
+			wantVerdict: api.NetworkPolicyVerdictDeny,
+			wantIngress: []api.NetworkPolicyRuleRef{},
+			wantEgress:  []api.NetworkPolicyRuleRef{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := evaluateNetworkConnectivity(policies, tt.src, tt.dst, tt.port, tt.protocol)
+			if got.Verdict != tt.wantVerdict {
+				t.Errorf("verdict = %s, want %s", got.Verdict, tt.wantVerdict)
+			}
+			if !reflect.DeepEqual(got.Ingress.MatchedRules, tt.wantIngress) {
+				t.Errorf("ingress matched rules = %v, want %v", got.Ingress.MatchedRules, tt.wantIngress)
+			}
+			if !reflect.DeepEqual(got.Egress.MatchedRules, tt.wantEgress) {
+				t.Errorf("egress matched rules = %v, want %v", got.Egress.MatchedRules, tt.wantEgress)
+			}
+		})
+	}
+
+	isolation := getNamespaceNetworkIsolation(prodNs, []*v1.Pod{web.pod, db.pod}, policies)
+	if isolation.IngressIsolated != 2 || isolation.EgressIsolated != 1 {
+		t.Errorf("isolated ingress %d, egress %d, want 2 and 1", isolation.IngressIsolated, isolation.EgressIsolated)
+	}
+	if isolation.Pods[0].Name != "db" || !reflect.DeepEqual(isolation.Pods[0].IngressPolicies, []string{"default-deny", "db-from-web", "metrics-from-ops"}) {
+		t.Errorf("unexpected db isolation: %#v", isolation.Pods[0])
+	}
+}
+
+func TestValidateNetworkPolicySpec(t *testing.T) {
+	badPort := intstr.FromInt(70000)
+	sctp := v1.ProtocolSCTP
+	tests := []struct {
+		name    string
+		spec    networking.NetworkPolicySpec
+		wantErr bool
+	}{
+		{
+			name: "deny all",
+			spec: networking.NetworkPolicySpec{PolicyTypes: []networking.PolicyType{networking.PolicyTypeIngress, networking.PolicyTypeEgress}},
+		},
+		{
+			name: "invalid port",
+			spec: networking.NetworkPolicySpec{
+				Ingress: []networking.NetworkPolicyIngressRule{{Ports: []networking.NetworkPolicyPort{{Protocol: &sctp, Port: &badPort}}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "except outside cidr",
+			spec: networking.NetworkPolicySpec{
+				Egress: []networking.NetworkPolicyEgressRule{{
+					To: []networking.NetworkPolicyPeer{{IPBlock: &networking.IPBlock{CIDR: "10.0.0.0/24", Except: []string{"10.0.1.0/25"}}}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty peer",
+			spec: networking.NetworkPolicySpec{
+				Ingress: []networking.NetworkPolicyIngressRule{{From: []networking.NetworkPolicyPeer{{}}}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateNetworkPolicySpec(&tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateNetworkPolicySpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
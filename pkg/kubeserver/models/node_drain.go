@@ -0,0 +1,349 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models/manager"
+)
+
+const (
+	podEvictionRetryInterval = 5 * time.Second
+	podDeletePollInterval    = 2 * time.Second
+)
+
+type nodeDrainPods struct {
+	// toEvict pods are removed through eviction api which honours PodDisruptionBudget
+	toEvict []v1.Pod
+	// toDelete pods are already finished, delete them directly
+	toDelete []v1.Pod
+	skipped  []v1.Pod
+}
+
+func isMirrorPod(pod *v1.Pod) bool {
+	_, ok := pod.GetAnnotations()[v1.MirrorPodAnnotationKey]
+	return ok
+}
+
+func isDaemonSetPod(pod *v1.Pod) bool {
+	ref := metav1.GetControllerOf(pod)
+	return ref != nil && ref.Kind == string(api.KindNameDaemonSet)
+}
+
+func hasEmptyDirVolume(pod *v1.Pod) bool {
+	for _, vol := range pod.Spec.Volumes {
+		if vol.EmptyDir != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isPodFinished(pod *v1.Pod) bool {
+	return pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed
+}
+
+// filterNodeDrainPods classifies pods on node like kubectl drain does
+func filterNodeDrainPods(pods []v1.Pod, input *api.NodeDrainInput) (*nodeDrainPods, error) {
+	ret := new(nodeDrainPods)
+	var (
+		emptyDirPods  []string
+		unmanagedPods []string
+	)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if isMirrorPod(pod) || isDaemonSetPod(pod) {
+			ret.skipped = append(ret.skipped, *pod)
+			continue
+		}
+		if isPodFinished(pod) {
+			ret.toDelete = append(ret.toDelete, *pod)
+			continue
+		}
+		if metav1.GetControllerOf(pod) == nil && !input.Force {
+			unmanagedPods = append(unmanagedPods, pod.GetNamespace()+"/"+pod.GetName())
+		}
+		if hasEmptyDirVolume(pod) && !input.DeleteEmptyDirData {
+			emptyDirPods = append(emptyDirPods, pod.GetNamespace()+"/"+pod.GetName())
+		}
+		ret.toEvict = append(ret.toEvict, *pod)
+	}
+	errs := []string{}
+	if len(unmanagedPods) != 0 {
+		errs = append(errs, fmt.Sprintf("pods not managed by controller (use force to override): %s", strings.Join(unmanagedPods, ", ")))
+	}
+	if len(emptyDirPods) != 0 {
+		errs = append(errs, fmt.Sprintf("pods with local storage (use delete_empty_dir_data to override): %s", strings.Join(emptyDirPods, ", ")))
+	}
+	if len(errs) != 0 {
+		return nil, errors.Errorf("cannot drain node: %s", strings.Join(errs, "; "))
+	}
+	return ret, nil
+}
+
+// getEvictionGroupVersion returns group version of pods/eviction served by cluster like kubectl drain,
+// policy/v1 is preferred and policy/v1beta1 is used by clusters older than 1.22
+func getEvictionGroupVersion(cli kubernetes.Interface) string {
+	ret := policy.SchemeGroupVersion.String()
+	resources, err := cli.Discovery().ServerResourcesForGroupVersion("v1")
+	if err != nil {
+		log.Warningf("get v1 server resources error: %v, use eviction %s", err, ret)
+		return ret
+	}
+	for _, res := range resources.APIResources {
+		if res.Name == "pods/eviction" && res.Kind == "Eviction" && res.Group == policy.GroupName && res.Version != "" {
+			return policy.GroupName + "/" + res.Version
+		}
+	}
+	return ret
+}
+
+func newPodEviction(pod v1.Pod, gracePeriod *int64, groupVersion string) ([]byte, error) {
+	eviction := &policy.Eviction{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: groupVersion,
+			Kind:       "Eviction",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.GetName(),
+			Namespace: pod.GetNamespace(),
+		},
+		DeleteOptions: &metav1.DeleteOptions{
+			GracePeriodSeconds: gracePeriod,
+		},
+	}
+	// policy/v1 Eviction has the same schema as v1beta1
+	return json.Marshal(eviction)
+}
+
+func evictPod(ctx context.Context, cli kubernetes.Interface, pod v1.Pod, gracePeriod *int64, groupVersion string, deadline time.Time) error {
+	body, err := newPodEviction(pod, gracePeriod, groupVersion)
+	if err != nil {
+		return errors.Wrap(err, "marshal eviction")
+	}
+	for {
+		err := cli.CoreV1().RESTClient().Post().
+			Namespace(pod.GetNamespace()).Resource("pods").Name(pod.GetName()).SubResource("eviction").
+			Body(body).Do(ctx).Error()
+		if err == nil || kerrors.IsNotFound(err) {
+			return nil
+		}
+		if !kerrors.IsTooManyRequests(err) {
+			return errors.Wrapf(err, "evict pod %s/%s", pod.GetNamespace(), pod.GetName())
+		}
+		// eviction is refused because of PodDisruptionBudget, wait other pods become ready
+		if time.Now().After(deadline) {
+			return errors.Wrapf(err, "evict pod %s/%s timeout", pod.GetNamespace(), pod.GetName())
+		}
+		log.Infof("evict pod %s/%s blocked by disruption budget, retry after %s: %v", pod.GetNamespace(), pod.GetName(), podEvictionRetryInterval, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(podEvictionRetryInterval):
+		}
+	}
+}
+
+func waitPodDeleted(ctx context.Context, cli kubernetes.Interface, pod v1.Pod, deadline time.Time) error {
+	for {
+		obj, err := cli.CoreV1().Pods(pod.GetNamespace()).Get(ctx, pod.GetName(), metav1.GetOptions{})
+		if kerrors.IsNotFound(err) || (err == nil && obj.GetUID() != pod.GetUID()) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "get pod %s/%s", pod.GetNamespace(), pod.GetName())
+		}
+		if time.Now().After(deadline) {
+			return errors.Errorf("wait pod %s/%s deleted timeout", pod.GetNamespace(), pod.GetName())
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(podDeletePollInterval):
+		}
+	}
+}
+
+// DrainNode evicts all pods on node except daemonset and mirror pods,
+// node should be cordoned before calling it.
+func DrainNode(ctx context.Context, cli kubernetes.Interface, nodeName string, input *api.NodeDrainInput, onProgress func(done, total int)) (*api.NodeDrainResult, error) {
+	timeout := input.TimeoutSeconds
+	if timeout <= 0 {
+		timeout = api.DefaultNodeDrainTimeoutSeconds
+	}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+
+	podList, err := cli.CoreV1().Pods(v1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": nodeName}).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "list pods on node %s", nodeName)
+	}
+	pods, err := filterNodeDrainPods(podList.Items, input)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &api.NodeDrainResult{
+		Node:    nodeName,
+		Evicted: []string{},
+		Deleted: []string{},
+		Skipped: []string{},
+	}
+	for _, pod := range pods.skipped {
+		ret.Skipped = append(ret.Skipped, pod.GetNamespace()+"/"+pod.GetName())
+	}
+	total := len(pods.toDelete) + len(pods.toEvict)
+	done := 0
+	progress := func() {
+		done += 1
+		if onProgress != nil {
+			onProgress(done, total)
+		}
+	}
+	for _, pod := range pods.toDelete {
+		err := cli.CoreV1().Pods(pod.GetNamespace()).Delete(ctx, pod.GetName(), metav1.DeleteOptions{GracePeriodSeconds: input.GracePeriodSeconds})
+		if err != nil && !kerrors.IsNotFound(err) {
+			return ret, errors.Wrapf(err, "delete finished pod %s/%s", pod.GetNamespace(), pod.GetName())
+		}
+		ret.Deleted = append(ret.Deleted, pod.GetNamespace()+"/"+pod.GetName())
+		progress()
+	}
+
+	type evictResult struct {
+		pod v1.Pod
+		err error
+	}
+	resultCh := make(chan evictResult, len(pods.toEvict))
+	evictionVersion := ""
+	if len(pods.toEvict) != 0 {
+		evictionVersion = getEvictionGroupVersion(cli)
+	}
+	for _, pod := range pods.toEvict {
+		go func(pod v1.Pod) {
+			err := evictPod(ctx, cli, pod, input.GracePeriodSeconds, evictionVersion, deadline)
+			if err == nil {
+				err = waitPodDeleted(ctx, cli, pod, deadline)
+			}
+			resultCh <- evictResult{pod: pod, err: err}
+		}(pod)
+	}
+	errs := []error{}
+	for range pods.toEvict {
+		r := <-resultCh
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		ret.Evicted = append(ret.Evicted, r.pod.GetNamespace()+"/"+r.pod.GetName())
+		progress()
+	}
+	if len(errs) != 0 {
+		return ret, errors.NewAggregate(errs)
+	}
+	return ret, nil
+}
+
+// Drain cordons node then evicts its pods
+func (node *SNode) Drain(ctx context.Context, input *api.NodeDrainInput, onProgress func(done, total int)) (*api.NodeDrainResult, error) {
+	if err := node.SetNodeScheduleToggle(true); err != nil {
+		return nil, errors.Wrap(err, "cordon node")
+	}
+	cli, err := node.GetClusterClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get cluster client")
+	}
+	return DrainNode(ctx, cli.GetClientset(), node.GetName(), input, onProgress)
+}
+
+// matchMachineK8sNode finds node of machine by name or private ip
+func matchMachineK8sNode(m manager.IMachine, nodes []*v1.Node) *v1.Node {
+	ip, _ := m.GetPrivateIP()
+	for _, node := range nodes {
+		if node.GetName() == m.GetName() {
+			return node
+		}
+		if ip == "" {
+			continue
+		}
+		for _, addr := range node.Status.Addresses {
+			if addr.Address == ip {
+				return node
+			}
+		}
+	}
+	return nil
+}
+
+func (c *SCluster) listRemoteNodes() ([]*v1.Node, error) {
+	cli, err := c.GetRemoteClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get remote kubernetes client")
+	}
+	objs, err := cli.GetHandler().List(api.ResourceNameNode, "", "")
+	if err != nil {
+		return nil, errors.Wrap(err, "list nodes")
+	}
+	nodes := make([]*v1.Node, len(objs))
+	for i := range objs {
+		nodes[i] = objs[i].(*v1.Node)
+	}
+	return nodes, nil
+}
+
+// DrainMachinesNodes drains k8s nodes of machines one by one,
+// machine never joined the cluster is ignored
+func (c *SCluster) DrainMachinesNodes(ctx context.Context, userCred mcclient.TokenCredential, ms []manager.IMachine, input *api.NodeDrainInput, onProgress func(percent float32)) ([]*api.NodeDrainResult, error) {
+	if input == nil {
+		input = new(api.NodeDrainInput)
+	}
+	nodes, err := c.listRemoteNodes()
+	if err != nil {
+		return nil, err
+	}
+	rets := make([]*api.NodeDrainResult, 0)
+	for idx, m := range ms {
+		node := matchMachineK8sNode(m, nodes)
+		if node == nil {
+			log.Warningf("machine %s node not found, skip drain", m.GetName())
+			continue
+		}
+		obj, err := GetNodeManager().GetByName(userCred, c.GetId(), node.GetName())
+		if err != nil {
+			if errors.Cause(err) == sql.ErrNoRows {
+				log.Warningf("machine %s node %s not synced, skip drain", m.GetName(), node.GetName())
+				continue
+			}
+			return rets, errors.Wrapf(err, "get machine %s node %s", m.GetName(), node.GetName())
+		}
+		ret, err := obj.(*SNode).Drain(ctx, input, func(done, total int) {
+			if onProgress != nil {
+				onProgress((float32(idx) + float32(done)/float32(total)) * 100 / float32(len(ms)))
+			}
+		})
+		if err != nil {
+			return rets, errors.Wrapf(err, "drain node %s", m.GetName())
+		}
+		rets = append(rets, ret)
+	}
+	return rets, nil
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+func newDrainTestPod(name string, ownerKind string, mutate func(pod *v1.Pod)) v1.Pod {
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Status: v1.PodStatus{
+			Phase: v1.PodRunning,
+		},
+	}
+	if ownerKind != "" {
+		isController := true
+		pod.OwnerReferences = []metav1.OwnerReference{
+			{
+				Kind:       ownerKind,
+				Name:       name + "-owner",
+				Controller: &isController,
+			},
+		}
+	}
+	if mutate != nil {
+		mutate(&pod)
+	}
+	return pod
+}
+
+func podNames(pods []v1.Pod) []string {
+	ret := []string{}
+	for _, p := range pods {
+		ret = append(ret, p.GetName())
+	}
+	return ret
+}
+
+func TestFilterNodeDrainPods(t *testing.T) {
+	pods := []v1.Pod{
+		newDrainTestPod("rs-pod", "ReplicaSet", nil),
+		newDrainTestPod("ds-pod", "DaemonSet", nil),
+		newDrainTestPod("mirror-pod", "", func(pod *v1.Pod) {
+			pod.Annotations = map[string]string{v1.MirrorPodAnnotationKey: "hash"}
+		}),
+		newDrainTestPod("done-pod", "Job", func(pod *v1.Pod) {
+			pod.Status.Phase = v1.PodSucceeded
+		}),
+		newDrainTestPod("deleting-pod", "ReplicaSet", func(pod *v1.Pod) {
+			now := metav1.Now()
+			pod.DeletionTimestamp = &now
+		}),
+	}
+	bare := newDrainTestPod("bare-pod", "", nil)
+	emptyDir := newDrainTestPod("emptydir-pod", "StatefulSet", func(pod *v1.Pod) {
+		pod.Spec.Volumes = []v1.Volume{
+			{
+				Name:         "data",
+				VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+			},
+		}
+	})
+
+	ret, err := filterNodeDrainPods(pods, &api.NodeDrainInput{})
+	if err != nil {
+		t.Fatalf("filterNodeDrainPods() error = %v", err)
+	}
+	if got := podNames(ret.toEvict); len(got) != 1 || got[0] != "rs-pod" {
+		t.Errorf("toEvict = %v, want [rs-pod]", got)
+	}
+	if got := podNames(ret.toDelete); len(got) != 1 || got[0] != "done-pod" {
+		t.Errorf("toDelete = %v, want [done-pod]", got)
+	}
+	if got := podNames(ret.skipped); len(got) != 2 {
+		t.Errorf("skipped = %v, want [ds-pod mirror-pod]", got)
+	}
+
+	if _, err := filterNodeDrainPods(append(pods, bare), &api.NodeDrainInput{}); err == nil {
+		t.Errorf("expect error for unmanaged pod without force")
+	}
+	if ret, err := filterNodeDrainPods(append(pods, bare), &api.NodeDrainInput{Force: true}); err != nil || len(ret.toEvict) != 2 {
+		t.Errorf("force drain unmanaged pod: %v", err)
+	}
+	if _, err := filterNodeDrainPods(append(pods, emptyDir), &api.NodeDrainInput{}); err == nil {
+		t.Errorf("expect error for emptyDir pod without delete_empty_dir_data")
+	}
+	if ret, err := filterNodeDrainPods(append(pods, emptyDir), &api.NodeDrainInput{DeleteEmptyDirData: true}); err != nil || len(ret.toEvict) != 2 {
+		t.Errorf("drain emptyDir pod: %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
@@ -195,6 +196,12 @@ func (node *SNode) GetDetails(ctx context.Context, cli *client.ClusterManager, b
 	out.PodCIDR = rNode.Spec.PodCIDR
 	out.ProviderID = rNode.Spec.ProviderID
 	out.ContainerImages = node.getContainerImages(*rNode)
+	usage, err := GetNodeUsage(ctx, cli, rNode)
+	if err != nil {
+		logUsageError(api.KindNameNode, rNode.GetName(), err)
+	} else {
+		out.Usage = usage
+	}
 	// TODO: fill others details
 	return out
 }
@@ -254,6 +261,15 @@ func (node *SNode) SetStatusByRemoteObject(ctx context.Context, userCred mcclien
 	return nil
 }
 
+// SyncStatusFromRemote saves status of remote k8s node
+func (node *SNode) SyncStatusFromRemote(ctx context.Context, userCred mcclient.TokenCredential) error {
+	k8sNode, err := node.GetRawNode()
+	if err != nil {
+		return errors.Wrap(err, "get remote k8s node")
+	}
+	return node.SetStatus(ctx, userCred, node.getStatusFromRemote(k8sNode), "sync from remote")
+}
+
 func (node *SNode) updateCapacity(k8sNode *v1.Node) {
 	capacity := k8sNode.Status.Capacity
 	// cpu status
@@ -339,7 +355,24 @@ func (node *SNode) AllowPerformCordon(ctx context.Context, userCred mcclient.Tok
 }
 
 func (node *SNode) PerformCordon(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
-	return nil, node.SetNodeScheduleToggle(true)
+	if err := node.SetNodeScheduleToggle(true); err != nil {
+		return nil, err
+	}
+	// cordon doesn't evict pods, but warn budgets which would be broken when node is drained
+	cli, err := node.GetClusterClient()
+	if err != nil {
+		log.Errorf("Get node %s cluster client error: %v", node.GetName(), err)
+		return nil, nil
+	}
+	ret, err := CheckNodesDisruption(cli, []string{node.GetName()})
+	if err != nil {
+		log.Errorf("Check node %s pod disruption budgets error: %v", node.GetName(), err)
+		return nil, nil
+	}
+	for _, w := range ret.Warnings {
+		log.Warningf("Cordon node %s: poddisruptionbudget %s/%s %s", node.GetName(), w.Namespace, w.Name, w.Message)
+	}
+	return jsonutils.Marshal(ret), nil
 }
 
 func (node *SNode) AllowPerformUncordon(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
@@ -350,6 +383,31 @@ func (node *SNode) PerformUncordon(ctx context.Context, userCred mcclient.TokenC
 	return nil, node.SetNodeScheduleToggle(false)
 }
 
+func (node *SNode) AllowPerformDrain(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return db.IsDomainAllowPerform(ctx, userCred, node, "drain")
+}
+
+func (node *SNode) PerformDrain(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.NodeDrainInput) (jsonutils.JSONObject, error) {
+	if input.TimeoutSeconds < 0 {
+		return nil, httperrors.NewInputParameterError("timeout_seconds %d must not be negative", input.TimeoutSeconds)
+	}
+	if input.GracePeriodSeconds != nil && *input.GracePeriodSeconds < 0 {
+		return nil, httperrors.NewInputParameterError("grace_period_seconds %d must not be negative", *input.GracePeriodSeconds)
+	}
+	return nil, node.StartDrainTask(ctx, userCred, input, "")
+}
+
+func (node *SNode) StartDrainTask(ctx context.Context, userCred mcclient.TokenCredential, input *api.NodeDrainInput, parentTaskId string) error {
+	node.SetStatus(ctx, userCred, api.NodeStatusDraining, "start drain")
+	data := jsonutils.Marshal(input).(*jsonutils.JSONDict)
+	task, err := taskman.TaskManager.NewTask(ctx, "NodeDrainTask", node, userCred, data, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
 func (node *SNode) GetRawNode() (*v1.Node, error) {
 	obj, err := GetK8sObject(node)
 	if err != nil {
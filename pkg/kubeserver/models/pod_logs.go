@@ -0,0 +1,302 @@
+package models
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/utils/websocket"
+)
+
+const (
+	// DefaultPodLogTailLines is used when json logs are requested without any limitation
+	DefaultPodLogTailLines int64 = 500
+)
+
+func (p *SPod) GetRawPod() (*v1.Pod, error) {
+	obj, err := GetK8sObject(p)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*v1.Pod), nil
+}
+
+func getPodContainerNames(pod *v1.Pod) []string {
+	names := make([]string, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+	for _, c := range pod.Spec.Containers {
+		names = append(names, c.Name)
+	}
+	for _, c := range pod.Spec.InitContainers {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func getPodLogContainer(pod *v1.Pod, container string) (string, error) {
+	names := getPodContainerNames(pod)
+	if container == "" {
+		if len(names) == 0 {
+			return "", httperrors.NewNotFoundError("pod %s has no container", pod.GetName())
+		}
+		return names[0], nil
+	}
+	for _, name := range names {
+		if name == container {
+			return container, nil
+		}
+	}
+	return "", httperrors.NewNotFoundError("container %s not found in pod %s, choose one of %v", container, pod.GetName(), names)
+}
+
+func toPodLogOptions(input api.PodLogInput, container string) *v1.PodLogOptions {
+	opt := &v1.PodLogOptions{
+		Container:    container,
+		Follow:       input.Follow,
+		Previous:     input.Previous,
+		Timestamps:   input.Timestamps,
+		SinceSeconds: input.SinceSeconds,
+		TailLines:    input.TailLines,
+		LimitBytes:   input.LimitBytes,
+	}
+	return opt
+}
+
+func openPodLogStream(ctx context.Context, cli kubernetes.Interface, pod *v1.Pod, opt *v1.PodLogOptions) (io.ReadCloser, error) {
+	stream, err := cli.CoreV1().Pods(pod.GetNamespace()).GetLogs(pod.GetName(), opt).Stream(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get pod %s/%s container %s logs", pod.GetNamespace(), pod.GetName(), opt.Container)
+	}
+	return stream, nil
+}
+
+func (p *SPod) GetDetailsLogs(ctx context.Context, userCred mcclient.TokenCredential, input *api.PodLogInput) (*api.PodLogDetail, error) {
+	cli, err := p.GetClusterClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get cluster client")
+	}
+	pod, err := p.GetRawPod()
+	if err != nil {
+		return nil, errors.Wrap(err, "get remote pod")
+	}
+	container, err := getPodLogContainer(pod, input.Container)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Download {
+		input.Follow = false
+		input.Container = container
+		fileName := fmt.Sprintf("%s-%s.log", pod.GetName(), container)
+		return nil, streamPodsLogsAsFile(ctx, cli.GetClientset(), []*v1.Pod{pod}, *input, fileName)
+	}
+	if input.Follow {
+		return nil, p.followLogs(ctx, cli.GetClientset(), pod, toPodLogOptions(*input, container))
+	}
+
+	if input.TailLines == nil && input.SinceSeconds == nil && input.LimitBytes == nil {
+		tail := DefaultPodLogTailLines
+		input.TailLines = &tail
+	}
+	stream, err := openPodLogStream(ctx, cli.GetClientset(), pod, toPodLogOptions(*input, container))
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	content, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return nil, errors.Wrap(err, "read logs")
+	}
+	return &api.PodLogDetail{
+		Pod:        pod.GetName(),
+		Container:  container,
+		Containers: getPodContainerNames(pod),
+		Previous:   input.Previous,
+		Logs:       string(content),
+	}, nil
+}
+
+// followLogs streams logs through websocket when client asks for upgrade, otherwise chunked http response
+func (p *SPod) followLogs(ctx context.Context, cli kubernetes.Interface, pod *v1.Pod, opt *v1.PodLogOptions) error {
+	appParams := appsrv.AppContextGetParams(ctx)
+	if appParams.Request != nil && websocket.IsWebsocketRequest(appParams.Request) {
+		return followLogsByWebsocket(ctx, cli, pod, opt, appParams.Response, appParams.Request)
+	}
+	return followLogsByChunked(ctx, cli, pod, opt, appParams.Response)
+}
+
+func followLogsByChunked(ctx context.Context, cli kubernetes.Interface, pod *v1.Pod, opt *v1.PodLogOptions, w http.ResponseWriter) error {
+	stream, err := openPodLogStream(ctx, cli, pod, opt)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	header := w.Header()
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stream.Read(buf)
+		if n > 0 {
+			if _, wErr := w.Write(buf[:n]); wErr != nil {
+				log.Infof("client of pod %s/%s logs gone: %v", pod.GetNamespace(), pod.GetName(), wErr)
+				return nil
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Warningf("read pod %s/%s logs: %v", pod.GetNamespace(), pod.GetName(), err)
+			}
+			return nil
+		}
+	}
+}
+
+func followLogsByWebsocket(ctx context.Context, cli kubernetes.Interface, pod *v1.Pod, opt *v1.PodLogOptions, w http.ResponseWriter, r *http.Request) error {
+	conn, err := websocket.Upgrade(w, r, "")
+	if err != nil {
+		return httperrors.NewBadRequestError("upgrade to websocket: %v", err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// drain client messages, log stream is stopped once client closes
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	stream, err := openPodLogStream(ctx, cli, pod, opt)
+	if err != nil {
+		conn.CloseWithReason(websocket.CloseInternalError, err.Error())
+		return nil
+	}
+	defer stream.Close()
+
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if wErr := conn.WriteMessage(websocket.OpText, line); wErr != nil {
+				conn.Close()
+				return nil
+			}
+		}
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				conn.CloseWithReason(websocket.CloseInternalError, err.Error())
+				return nil
+			}
+			conn.CloseWithReason(websocket.CloseNormal, "")
+			return nil
+		}
+	}
+}
+
+// streamPodsLogsAsFile writes logs of all pods' containers to response as an attachment
+func streamPodsLogsAsFile(ctx context.Context, cli kubernetes.Interface, pods []*v1.Pod, input api.PodLogInput, fileName string) error {
+	appParams := appsrv.AppContextGetParams(ctx)
+	w := appParams.Response
+	header := w.Header()
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	header.Set("Logs-Filename", fileName)
+	w.WriteHeader(http.StatusOK)
+
+	merged := len(pods) > 1 || input.Container == ""
+	for _, pod := range pods {
+		containers := getPodContainerNames(pod)
+		if input.Container != "" {
+			containers = []string{input.Container}
+		}
+		for _, container := range containers {
+			if merged {
+				fmt.Fprintf(w, "==> %s/%s <==\n", pod.GetName(), container)
+			}
+			if err := copyPodLogs(ctx, cli, pod, toPodLogOptions(input, container), w); err != nil {
+				log.Warningf("download pod %s/%s logs: %v", pod.GetName(), container, err)
+				fmt.Fprintf(w, "%v\n", err)
+			}
+		}
+	}
+	return nil
+}
+
+func copyPodLogs(ctx context.Context, cli kubernetes.Interface, pod *v1.Pod, opt *v1.PodLogOptions, w io.Writer) error {
+	stream, err := openPodLogStream(ctx, cli, pod, opt)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	_, err = io.Copy(w, stream)
+	return err
+}
+
+// downloadPodOwnerLogs merges logs of all pods owned by workload into one file
+func downloadPodOwnerLogs(ctx context.Context, owner IPodOwnerModel, input *api.PodLogInput) (jsonutils.JSONObject, error) {
+	cli, err := owner.GetClusterClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get cluster client")
+	}
+	obj, err := GetK8sObject(owner)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get remote %s", owner.Keyword())
+	}
+	pods, err := owner.GetRawPods(cli, obj)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get %s pods", owner.Keyword())
+	}
+	if len(pods) == 0 {
+		return nil, httperrors.NewNotFoundError("%s %s has no pods", owner.Keyword(), owner.GetName())
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].GetName() < pods[j].GetName()
+	})
+	if input.Container != "" {
+		for _, pod := range pods {
+			if _, err := getPodLogContainer(pod, input.Container); err != nil {
+				return nil, err
+			}
+		}
+	}
+	input.Follow = false
+	fileName := fmt.Sprintf("%s-%s-%s.log", owner.Keyword(), owner.GetName(), time.Now().Format("20060102150405"))
+	return nil, streamPodsLogsAsFile(ctx, cli.GetClientset(), pods, *input, fileName)
+}
+
+func (obj *SDeployment) GetDetailsDownloadLogs(ctx context.Context, userCred mcclient.TokenCredential, input *api.PodLogInput) (jsonutils.JSONObject, error) {
+	return downloadPodOwnerLogs(ctx, obj, input)
+}
+
+func (obj *SStatefulSet) GetDetailsDownloadLogs(ctx context.Context, userCred mcclient.TokenCredential, input *api.PodLogInput) (jsonutils.JSONObject, error) {
+	return downloadPodOwnerLogs(ctx, obj, input)
+}
+
+func (obj *SDaemonSet) GetDetailsDownloadLogs(ctx context.Context, userCred mcclient.TokenCredential, input *api.PodLogInput) (jsonutils.JSONObject, error) {
+	return downloadPodOwnerLogs(ctx, obj, input)
+}
@@ -0,0 +1,385 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/client"
+)
+
+var (
+	pdbManager *SPodDisruptionBudgetManager
+	_          IClusterModel = new(SPodDisruptionBudget)
+)
+
+func init() {
+	GetPodDisruptionBudgetManager()
+}
+
+// GetPodDisruptionBudgetManager manages pdb through dynamic client, policy/v1 is preferred by cluster
+// and policy/v1beta1 is used by older clusters, the remote object is converted by v1beta1 types
+func GetPodDisruptionBudgetManager() *SPodDisruptionBudgetManager {
+	if pdbManager == nil {
+		pdbManager = NewK8sNamespaceModelManager(func() ISyncableManager {
+			return &SPodDisruptionBudgetManager{
+				SNamespaceResourceBaseManager: NewNamespaceResourceBaseManager(
+					SPodDisruptionBudget{},
+					"poddisruptionbudgets_tbl",
+					"poddisruptionbudget",
+					"poddisruptionbudgets",
+					api.ResourceNamePodDisruptionBudget,
+					"",
+					"",
+					api.KindNamePodDisruptionBudget,
+					new(unstructured.Unstructured),
+				),
+			}
+		}).(*SPodDisruptionBudgetManager)
+	}
+	return pdbManager
+}
+
+// +onecloud:swagger-gen-model-singular=poddisruptionbudget
+// +onecloud:swagger-gen-model-plural=poddisruptionbudgets
+type SPodDisruptionBudgetManager struct {
+	SNamespaceResourceBaseManager
+}
+
+type SPodDisruptionBudget struct {
+	SNamespaceResourceBase
+}
+
+func validatePodDisruptionBudgetSpec(spec *policy.PodDisruptionBudgetSpec) error {
+	if (spec.MinAvailable == nil) == (spec.MaxUnavailable == nil) {
+		return httperrors.NewInputParameterError("one of minAvailable and maxUnavailable must be specified")
+	}
+	for key, val := range map[string]*intstr.IntOrString{
+		"minAvailable":   spec.MinAvailable,
+		"maxUnavailable": spec.MaxUnavailable,
+	} {
+		if val == nil {
+			continue
+		}
+		// percentage is validated by scaling 100 pods
+		num, err := intstr.GetValueFromIntOrPercent(val, 100, true)
+		if err != nil {
+			return httperrors.NewInputParameterError("invalid %s %q: %v", key, val.String(), err)
+		}
+		if num < 0 || (val.Type == intstr.String && num > 100) {
+			return httperrors.NewInputParameterError("invalid %s %q", key, val.String())
+		}
+	}
+	return nil
+}
+
+func (m *SPodDisruptionBudgetManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.PodDisruptionBudgetCreateInput) (*api.PodDisruptionBudgetCreateInput, error) {
+	nInput, err := m.SNamespaceResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, &input.NamespaceResourceCreateInput)
+	if err != nil {
+		return nil, err
+	}
+	input.NamespaceResourceCreateInput = *nInput
+	// empty selector matches all pods in policy/v1 but none in v1beta1, so it's required
+	if input.Selector == nil || (len(input.Selector.MatchLabels) == 0 && len(input.Selector.MatchExpressions) == 0) {
+		return nil, httperrors.NewNotEmptyError("selector is empty")
+	}
+	if _, err := metav1.LabelSelectorAsSelector(input.Selector); err != nil {
+		return nil, httperrors.NewInputParameterError("invalid selector: %v", err)
+	}
+	if err := validatePodDisruptionBudgetSpec(&policy.PodDisruptionBudgetSpec{
+		MinAvailable:   input.MinAvailable,
+		MaxUnavailable: input.MaxUnavailable,
+	}); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (m *SPodDisruptionBudgetManager) NewRemoteObjectForCreate(model IClusterModel, cli *client.ClusterManager, data jsonutils.JSONObject) (interface{}, error) {
+	input := new(api.PodDisruptionBudgetCreateInput)
+	if err := data.Unmarshal(input); err != nil {
+		return nil, errors.Wrap(err, "poddisruptionbudget input unmarshal error")
+	}
+	objMeta, err := input.ToObjectMeta(model.(api.INamespaceGetter))
+	if err != nil {
+		return nil, errors.Wrap(err, "poddisruptionbudget input get meta error")
+	}
+	res := new(unstructured.Unstructured)
+	res.SetName(objMeta.Name)
+	res.SetNamespace(objMeta.Namespace)
+	res.SetLabels(objMeta.Labels)
+	res.SetAnnotations(objMeta.Annotations)
+	spec := &policy.PodDisruptionBudgetSpec{
+		Selector:       input.Selector,
+		MinAvailable:   input.MinAvailable,
+		MaxUnavailable: input.MaxUnavailable,
+	}
+	if err := setPodDisruptionBudgetSpec(res, spec); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (obj *SPodDisruptionBudget) NewRemoteObjectForUpdate(cli *client.ClusterManager, remoteObj interface{}, data jsonutils.JSONObject) (interface{}, error) {
+	res := remoteObj.(*unstructured.Unstructured).DeepCopy()
+	input := new(api.PodDisruptionBudgetUpdateInput)
+	if err := data.Unmarshal(input); err != nil {
+		return nil, err
+	}
+	pdb, err := toPodDisruptionBudget(res)
+	if err != nil {
+		return nil, err
+	}
+	spec := &pdb.Spec
+	spec.MinAvailable = input.MinAvailable
+	spec.MaxUnavailable = input.MaxUnavailable
+	if err := validatePodDisruptionBudgetSpec(spec); err != nil {
+		return nil, err
+	}
+	// keep selector of remote object as is
+	for key, val := range map[string]*intstr.IntOrString{
+		"minAvailable":   spec.MinAvailable,
+		"maxUnavailable": spec.MaxUnavailable,
+	} {
+		if val == nil {
+			unstructured.RemoveNestedField(res.Object, "spec", key)
+			continue
+		}
+		var field interface{} = val.StrVal
+		if val.Type == intstr.Int {
+			field = int64(val.IntVal)
+		}
+		if err := unstructured.SetNestedField(res.Object, field, "spec", key); err != nil {
+			return nil, errors.Wrapf(err, "set spec %s", key)
+		}
+	}
+	return res, nil
+}
+
+func (obj *SPodDisruptionBudget) GetDetails(ctx context.Context, cli *client.ClusterManager, base interface{}, k8sObj runtime.Object, isList bool) interface{} {
+	detail := api.PodDisruptionBudgetDetail{
+		NamespaceResourceDetail: obj.SNamespaceResourceBase.GetDetails(ctx, cli, base, k8sObj, isList).(api.NamespaceResourceDetail),
+	}
+	pdb, err := toPodDisruptionBudget(k8sObj.(*unstructured.Unstructured))
+	if err != nil {
+		log.Errorf("Convert poddisruptionbudget %s error: %v", obj.GetName(), err)
+		return detail
+	}
+	detail.Selector = pdb.Spec.Selector
+	detail.MinAvailable = pdb.Spec.MinAvailable
+	detail.MaxUnavailable = pdb.Spec.MaxUnavailable
+	detail.DisruptionsAllowed = pdb.Status.DisruptionsAllowed
+	detail.CurrentHealthy = pdb.Status.CurrentHealthy
+	detail.DesiredHealthy = pdb.Status.DesiredHealthy
+	detail.ExpectedPods = pdb.Status.ExpectedPods
+	if !isList {
+		workloads, err := getPodDisruptionBudgetWorkloads(cli, pdb)
+		if err != nil {
+			log.Errorf("Get poddisruptionbudget %s workloads error: %v", obj.GetName(), err)
+		} else {
+			detail.Workloads = workloads
+		}
+	}
+	return detail
+}
+
+// toPodDisruptionBudget converts policy/v1 or v1beta1 object, the selector is normalized
+// to nil if it selects nothing and empty if it selects all pods in namespace
+func toPodDisruptionBudget(obj *unstructured.Unstructured) (*policy.PodDisruptionBudget, error) {
+	pdb := new(policy.PodDisruptionBudget)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pdb); err != nil {
+		return nil, errors.Wrap(err, "convert from unstructured")
+	}
+	sel := pdb.Spec.Selector
+	isEmpty := sel == nil || (len(sel.MatchLabels) == 0 && len(sel.MatchExpressions) == 0)
+	if isEmpty {
+		if obj.GetAPIVersion() == policy.SchemeGroupVersion.String() {
+			pdb.Spec.Selector = nil
+		} else {
+			pdb.Spec.Selector = &metav1.LabelSelector{}
+		}
+	}
+	return pdb, nil
+}
+
+func setPodDisruptionBudgetSpec(obj *unstructured.Unstructured, spec *policy.PodDisruptionBudgetSpec) error {
+	specObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(spec)
+	if err != nil {
+		return errors.Wrap(err, "convert spec to unstructured")
+	}
+	if err := unstructured.SetNestedMap(obj.Object, specObj, "spec"); err != nil {
+		return errors.Wrap(err, "set nested map of unstructured")
+	}
+	return nil
+}
+
+func podDisruptionBudgetSelects(pdb *policy.PodDisruptionBudget, namespace string, podLabels map[string]string) bool {
+	if pdb.Spec.Selector == nil || pdb.GetNamespace() != namespace {
+		return false
+	}
+	return labelSelectorMatches(pdb.Spec.Selector, labels.Set(podLabels))
+}
+
+func listRemotePodDisruptionBudgets(cli *client.ClusterManager, namespace string) ([]*policy.PodDisruptionBudget, error) {
+	objs, err := cli.GetHandler().List(api.ResourceNamePodDisruptionBudget, namespace, "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "list namespace %q poddisruptionbudgets", namespace)
+	}
+	ret := make([]*policy.PodDisruptionBudget, 0, len(objs))
+	for _, obj := range objs {
+		pdb, err := toPodDisruptionBudget(obj.(*unstructured.Unstructured))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, pdb)
+	}
+	return ret, nil
+}
+
+func matchPodDisruptionBudgetWorkloads(pdb *policy.PodDisruptionBudget, deploys []*apps.Deployment, statefulsets []*apps.StatefulSet) []api.PodDisruptionBudgetWorkload {
+	ret := make([]api.PodDisruptionBudgetWorkload, 0)
+	for _, d := range deploys {
+		if podDisruptionBudgetSelects(pdb, d.GetNamespace(), d.Spec.Template.GetLabels()) {
+			ret = append(ret, api.PodDisruptionBudgetWorkload{Kind: api.KindNameDeployment, Name: d.GetName()})
+		}
+	}
+	for _, ss := range statefulsets {
+		if podDisruptionBudgetSelects(pdb, ss.GetNamespace(), ss.Spec.Template.GetLabels()) {
+			ret = append(ret, api.PodDisruptionBudgetWorkload{Kind: api.KindNameStatefulSet, Name: ss.GetName()})
+		}
+	}
+	return ret
+}
+
+func getPodDisruptionBudgetWorkloads(cli *client.ClusterManager, pdb *policy.PodDisruptionBudget) ([]api.PodDisruptionBudgetWorkload, error) {
+	indexer := cli.GetHandler().GetIndexer()
+	deploys, err := indexer.DeploymentLister().Deployments(pdb.GetNamespace()).List(labels.Everything())
+	if err != nil {
+		return nil, errors.Wrap(err, "list deployments")
+	}
+	statefulsets, err := indexer.StatefulSetLister().StatefulSets(pdb.GetNamespace()).List(labels.Everything())
+	if err != nil {
+		return nil, errors.Wrap(err, "list statefulsets")
+	}
+	return matchPodDisruptionBudgetWorkloads(pdb, deploys, statefulsets), nil
+}
+
+// GetPodDisruptionBudgetsByTemplate returns names of budgets selecting pods created from workload template
+func GetPodDisruptionBudgetsByTemplate(cli *client.ClusterManager, namespace string, template *v1.PodTemplateSpec) ([]string, error) {
+	pdbs, err := listRemotePodDisruptionBudgets(cli, namespace)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0)
+	for _, pdb := range pdbs {
+		if podDisruptionBudgetSelects(pdb, namespace, template.GetLabels()) {
+			ret = append(ret, pdb.GetName())
+		}
+	}
+	return ret, nil
+}
+
+func isPodHealthy(pod *v1.Pod) bool {
+	if pod.DeletionTimestamp != nil || pod.Status.Phase != v1.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// getPodDisruptionBudgetWarnings finds budgets whose healthy pods on nodes exceed allowed disruptions
+func getPodDisruptionBudgetWarnings(pdbs []*policy.PodDisruptionBudget, pods []*v1.Pod, nodes sets.String) []api.PodDisruptionBudgetWarning {
+	ret := make([]api.PodDisruptionBudgetWarning, 0)
+	for _, pdb := range pdbs {
+		affected := make([]string, 0)
+		for _, pod := range pods {
+			if !nodes.Has(pod.Spec.NodeName) || !isPodHealthy(pod) {
+				continue
+			}
+			if podDisruptionBudgetSelects(pdb, pod.GetNamespace(), pod.GetLabels()) {
+				affected = append(affected, pod.GetName())
+			}
+		}
+		if int32(len(affected)) <= pdb.Status.DisruptionsAllowed {
+			continue
+		}
+		sort.Strings(affected)
+		ret = append(ret, api.PodDisruptionBudgetWarning{
+			Namespace:          pdb.GetNamespace(),
+			Name:               pdb.GetName(),
+			DisruptionsAllowed: pdb.Status.DisruptionsAllowed,
+			Pods:               affected,
+			Message: fmt.Sprintf("%d healthy pods will be evicted but only %d disruptions are allowed, %d of %d desired healthy pods remain",
+				len(affected), pdb.Status.DisruptionsAllowed, pdb.Status.CurrentHealthy-int32(len(affected)), pdb.Status.DesiredHealthy),
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Namespace != ret[j].Namespace {
+			return ret[i].Namespace < ret[j].Namespace
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// CheckNodesDisruption checks pods evicted from nodes whether break pod disruption budgets
+func CheckNodesDisruption(cli *client.ClusterManager, nodes []string) (*api.NodeDisruptionCheckResult, error) {
+	ret := &api.NodeDisruptionCheckResult{
+		Nodes:    nodes,
+		Warnings: make([]api.PodDisruptionBudgetWarning, 0),
+	}
+	if len(nodes) == 0 {
+		return ret, nil
+	}
+	pdbs, err := listRemotePodDisruptionBudgets(cli, v1.NamespaceAll)
+	if err != nil {
+		return nil, err
+	}
+	if len(pdbs) == 0 {
+		return ret, nil
+	}
+	objs, err := cli.GetHandler().List(api.ResourceNamePod, v1.NamespaceAll, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "list pods")
+	}
+	pods := make([]*v1.Pod, len(objs))
+	for i := range objs {
+		pods[i] = objs[i].(*v1.Pod)
+	}
+	ret.Warnings = getPodDisruptionBudgetWarnings(pdbs, pods, sets.NewString(nodes...))
+	pdbByKey := make(map[string]*policy.PodDisruptionBudget)
+	for _, pdb := range pdbs {
+		pdbByKey[pdb.GetNamespace()+"/"+pdb.GetName()] = pdb
+	}
+	for i := range ret.Warnings {
+		w := &ret.Warnings[i]
+		workloads, err := getPodDisruptionBudgetWorkloads(cli, pdbByKey[w.Namespace+"/"+w.Name])
+		if err != nil {
+			log.Errorf("Get poddisruptionbudget %s/%s workloads error: %v", w.Namespace, w.Name, err)
+			continue
+		}
+		w.Workloads = workloads
+	}
+	return ret, nil
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func newPdbTestPod(name, node string, ready bool, labels map[string]string) *v1.Pod {
+	status := v1.ConditionFalse
+	if ready {
+		status = v1.ConditionTrue
+	}
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: labels},
+		Spec:       v1.PodSpec{NodeName: node},
+		Status: v1.PodStatus{
+			Phase:      v1.PodRunning,
+			Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestGetPodDisruptionBudgetWarnings(t *testing.T) {
+	web := map[string]string{"app": "web"}
+	db := map[string]string{"app": "db"}
+	pdbs := []*policy.PodDisruptionBudget{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+			Spec:       policy.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: web}},
+			Status:     policy.PodDisruptionBudgetStatus{DisruptionsAllowed: 1, CurrentHealthy: 3, DesiredHealthy: 2},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"},
+			Spec:       policy.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: db}},
+			Status:     policy.PodDisruptionBudgetStatus{DisruptionsAllowed: 0, CurrentHealthy: 1, DesiredHealthy: 1},
+		},
+	}
+	pods := []*v1.Pod{
+		newPdbTestPod("web-0", "node1", true, web),
+		newPdbTestPod("web-1", "node1", true, web),
+		newPdbTestPod("web-2", "node2", true, web),
+		newPdbTestPod("db-0", "node2", true, db),
+		newPdbTestPod("db-1", "node1", false, db),
+	}
+
+	tests := []struct {
+		name  string
+		nodes []string
+		want  map[string][]string
+	}{
+		{
+			name:  "node1 evicts two web pods",
+			nodes: []string{"node1"},
+			want:  map[string][]string{"web": {"web-0", "web-1"}},
+		},
+		{
+			name:  "node2 evicts healthy db pod",
+			nodes: []string{"node2"},
+			want:  map[string][]string{"db": {"db-0"}},
+		},
+		{
+			name:  "node3 has no pods",
+			nodes: []string{"node3"},
+			want:  map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string][]string)
+			for _, w := range getPodDisruptionBudgetWarnings(pdbs, pods, sets.NewString(tt.nodes...)) {
+				got[w.Name] = w.Pods
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("warnings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToPodDisruptionBudget(t *testing.T) {
+	newObj := func(apiVersion string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       "PodDisruptionBudget",
+			"metadata":   map[string]interface{}{"namespace": "default", "name": "pdb"},
+			"spec":       map[string]interface{}{"minAvailable": "50%"},
+			"status":     map[string]interface{}{"disruptionsAllowed": int64(2)},
+		}}
+	}
+	v1Pdb, err := toPodDisruptionBudget(newObj("policy/v1"))
+	if err != nil {
+		t.Fatalf("convert policy/v1: %v", err)
+	}
+	if !podDisruptionBudgetSelects(v1Pdb, "default", map[string]string{"app": "web"}) {
+		t.Errorf("policy/v1 empty selector should select all pods")
+	}
+	if v1Pdb.Status.DisruptionsAllowed != 2 || v1Pdb.Spec.MinAvailable.String() != "50%" {
+		t.Errorf("unexpected converted pdb %#v", v1Pdb)
+	}
+	betaPdb, err := toPodDisruptionBudget(newObj("policy/v1beta1"))
+	if err != nil {
+		t.Fatalf("convert policy/v1beta1: %v", err)
+	}
+	if podDisruptionBudgetSelects(betaPdb, "default", map[string]string{"app": "web"}) {
+		t.Errorf("policy/v1beta1 empty selector should select no pods")
+	}
+}
+
+func TestValidatePodDisruptionBudgetSpec(t *testing.T) {
+	one := intstr.FromInt(1)
+	half := intstr.FromString("50%")
+	over := intstr.FromString("150%")
+	bad := intstr.FromString("abc")
+	tests := []struct {
+		name    string
+		spec    policy.PodDisruptionBudgetSpec
+		wantErr bool
+	}{
+		{"min available", policy.PodDisruptionBudgetSpec{MinAvailable: &one}, false},
+		{"max unavailable percent", policy.PodDisruptionBudgetSpec{MaxUnavailable: &half}, false},
+		{"none", policy.PodDisruptionBudgetSpec{}, true},
+		{"both", policy.PodDisruptionBudgetSpec{MinAvailable: &one, MaxUnavailable: &half}, true},
+		{"over percent", policy.PodDisruptionBudgetSpec{MinAvailable: &over}, true},
+		{"invalid string", policy.PodDisruptionBudgetSpec{MaxUnavailable: &bad}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePodDisruptionBudgetSpec(&tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePodDisruptionBudgetSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
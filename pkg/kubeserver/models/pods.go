@@ -380,6 +380,13 @@ func (p *SPod) GetDetails(ctx context.Context, cli *client.ClusterManager, base
 		return out
 	}
 	out.Conditions = p.getConditions(pod)
+	usage, containersUsage, err := GetPodUsage(ctx, cli, pod.GetNamespace(), pod.GetName())
+	if err != nil {
+		logUsageError(api.KindNamePod, pod.GetName(), err)
+	} else {
+		out.Usage = usage
+		out.ContainersUsage = containersUsage
+	}
 	// TODO: fill secrets, pvcs...
 	return out
 }
@@ -72,6 +72,14 @@ type SRelease struct {
 	Chart        string               `width:"128" charset:"ascii" nullable:"false" create:"required" index:"true" list:"user"`
 	ChartVersion string               `width:"128" charset:"ascii" nullable:"false" list:"user"`
 	Config       jsonutils.JSONObject `nullable:"true" list:"user" update:"user"`
+
+	// Source is where chart and values come from, repo or git
+	Source    string               `width:"16" charset:"ascii" nullable:"false" default:"repo" list:"user"`
+	GitSource jsonutils.JSONObject `nullable:"true" list:"user"`
+	// GitCommit is last synced commit of git source
+	GitCommit      string    `width:"64" charset:"ascii" nullable:"true" list:"user"`
+	GitSyncedAt    time.Time `nullable:"true" list:"user"`
+	GitSyncSuspend bool      `nullable:"true" default:"false" list:"user" update:"user"`
 }
 
 type IReleaseDriver interface {
@@ -109,18 +117,32 @@ func (m *SReleaseManager) ShowChart(repo *SRepo, chartName string, version strin
 	return chartObj, nil
 }
 
-func (m *SReleaseManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerCred mcclient.IIdentityProvider, query jsonutils.JSONObject, data *api.ReleaseCreateInput) (*api.ReleaseCreateInput, error) {
+// fetchChartRepo resolves repo and chart name by chart_name, repo and chart input
+func (m *SReleaseManager) fetchChartRepo(ctx context.Context, userCred mcclient.TokenCredential, data *api.ReleaseCreateInput) (*SRepo, string, error) {
 	var (
 		repo  string
 		chart string
 	)
 
-	if len(data.ChartName) != 0 {
+	if helm.IsOCIURL(data.ChartName) {
+		repoUrl, chartName, version, err := helm.ParseOCIRef(data.ChartName)
+		if err != nil {
+			return nil, "", httperrors.NewInputParameterError("Illegal chart name: %v", err)
+		}
+		repoObj, err := RepoManager.FetchOCIRepoByUrl(ctx, userCred, repoUrl)
+		if err != nil {
+			return nil, "", err
+		}
+		repo, chart = repoObj.GetId(), chartName
+		if version != "" && data.Version == "" {
+			data.Version = version
+		}
+	} else if len(data.ChartName) != 0 {
 		segs := strings.Split(data.ChartName, "/")
 		if len(segs) == 2 {
 			repo, chart = segs[0], segs[1]
 		} else {
-			return nil, httperrors.NewInputParameterError("Illegal chart name: %q", data.ChartName)
+			return nil, "", httperrors.NewInputParameterError("Illegal chart name: %q", data.ChartName)
 		}
 	}
 	if data.Repo != "" {
@@ -130,16 +152,52 @@ func (m *SReleaseManager) ValidateCreateData(ctx context.Context, userCred mccli
 		chart = data.Chart
 	}
 	if repo == "" {
-		return nil, httperrors.NewNotEmptyError("repo must provided")
+		return nil, "", httperrors.NewNotEmptyError("repo must provided")
 	}
 	if chart == "" {
-		return nil, httperrors.NewNotEmptyError("chart must provided")
+		return nil, "", httperrors.NewNotEmptyError("chart must provided")
 	}
 	repoObj, err := db.FetchByIdOrName(ctx, RepoManager, userCred, repo)
 	if err != nil {
-		return nil, NewCheckIdOrNameError("repo", repo, err)
+		return nil, "", NewCheckIdOrNameError("repo", repo, err)
+	}
+	return repoObj.(*SRepo), chart, nil
+}
+
+func (m *SReleaseManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerCred mcclient.IIdentityProvider, query jsonutils.JSONObject, data *api.ReleaseCreateInput) (*api.ReleaseCreateInput, error) {
+	var gitChart *chart.Chart
+	switch data.Source {
+	case "", api.ReleaseSourceRepo:
+		data.Source = api.ReleaseSourceRepo
+		data.GitSource = nil
+		data.GitCredential = nil
+	case api.ReleaseSourceGit:
+		gs, err := m.validateGitSource(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		gitChart = gs.Chart
+	default:
+		return nil, httperrors.NewInputParameterError("unsupported release source %q", data.Source)
+	}
+
+	var (
+		repoObj *SRepo
+		chart   string
+		err     error
+	)
+	repoType := api.RepoTypeExternal
+	if gitChart == nil {
+		repoObj, chart, err = m.fetchChartRepo(ctx, userCred, data)
+		if err != nil {
+			return nil, err
+		}
+		repoType = repoObj.GetType()
+		data.Repo = repoObj.GetId()
+	} else {
+		// chart is stored along with values in git repository
+		data.Repo = ""
 	}
-	data.Repo = repoObj.GetId()
 
 	if data.ReleaseName != "" {
 		data.Name = data.ReleaseName
@@ -150,7 +208,7 @@ func (m *SReleaseManager) ValidateCreateData(ctx context.Context, userCred mccli
 		return nil, httperrors.NewInputParameterError("invalid name: %s", err)
 	}
 
-	drv, err := m.GetDriver(repoObj.(*SRepo).GetType())
+	drv, err := m.GetDriver(repoType)
 	if err != nil {
 		return nil, err
 	}
@@ -165,10 +223,15 @@ func (m *SReleaseManager) ValidateCreateData(ctx context.Context, userCred mccli
 	}
 	data.NamespaceResourceCreateInput = *nInput
 
+	if gitChart != nil {
+		data.Version = gitChart.Metadata.Version
+		data.Chart = gitChart.Metadata.Name
+		return data, nil
+	}
 	if data.Version == "" {
 		data.Version = ">0.0.0-0"
 	}
-	chartObj, err := m.ShowChart(repoObj.(*SRepo), chart, data.Version)
+	chartObj, err := m.ShowChart(repoObj, chart, data.Version)
 	if err != nil {
 		return nil, errors.Wrapf(err, "show chart %s:%s on repo %q", chart, data.Version, repoObj.GetName())
 	}
@@ -178,6 +241,16 @@ func (m *SReleaseManager) ValidateCreateData(ctx context.Context, userCred mccli
 }
 
 func (rls *SRelease) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	if data.Contains("git_credential") {
+		cred := new(api.ReleaseGitCredential)
+		if err := data.Unmarshal(cred, "git_credential"); err != nil {
+			log.Errorf("unmarshal release %s git credential: %v", rls.GetName(), err)
+		} else if err := rls.setGitCredential(ctx, userCred, cred); err != nil {
+			log.Errorf("set release %s git credential: %v", rls.GetName(), err)
+		}
+		// credential must not be saved in task params
+		data.(*jsonutils.JSONDict).Remove("git_credential")
+	}
 	rls.SNamespaceResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
 	input := new(api.ReleaseCreateInput)
 	if err := data.Unmarshal(input); err != nil {
@@ -283,6 +356,10 @@ func (r *SRelease) GetRepo() (*SRepo, error) {
 }
 
 func (r *SRelease) GetType() (api.RepoType, error) {
+	if r.RepoId == "" {
+		// chart of git source release is not from repo
+		return api.RepoTypeExternal, nil
+	}
 	repo, err := r.GetRepo()
 	if err != nil {
 		return "", errors.Wrap(err, "get repo")
@@ -318,6 +395,10 @@ func (r *SRelease) CustomizeCreate(ctx context.Context, userCred mcclient.TokenC
 	r.RepoId = input.Repo
 	r.Chart = input.Chart
 	r.ChartVersion = input.Version
+	r.Source = input.Source
+	if input.GitSource != nil {
+		r.GitSource = jsonutils.Marshal(input.GitSource)
+	}
 	drv, err := r.GetDriver()
 	if err != nil {
 		return errors.Wrap(err, "customize create get driver")
@@ -335,9 +416,25 @@ func (r *SRelease) doCreate() (*release.Release, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "get relase namespace")
 	}
-	chart, err := r.GetChart()
-	if err != nil {
-		return nil, errors.Wrap(err, "get chart")
+	var (
+		chart *chart.Chart
+		gs    *helm.GitSource
+	)
+	vals := r.GetHelmValues()
+	if r.IsGitSource() {
+		gs, err = r.loadGitSource(context.Background())
+		if err != nil {
+			return nil, errors.Wrap(err, "load git source")
+		}
+		chart = gs.Chart
+		// inline values override values files in git
+		vals = mergeMaps(gs.Values, vals)
+	}
+	if chart == nil {
+		chart, err = r.GetChart()
+		if err != nil {
+			return nil, errors.Wrap(err, "get chart")
+		}
 	}
 	cli, err := r.GetHelmClient()
 	if err != nil {
@@ -348,11 +445,18 @@ func (r *SRelease) doCreate() (*release.Release, error) {
 	install.ReleaseName = r.GetName()
 	// install.Atomic = true
 	install.Replace = true
-	vals := r.GetHelmValues()
+	if gs != nil {
+		install.Description = helm.GitSyncDescription(gs.Commit)
+	}
 	rls, err := install.Run(chart, vals)
 	if err != nil {
 		return nil, errors.Wrap(err, "install release")
 	}
+	if gs != nil {
+		if err := r.setGitCommit(gs.Commit, chart); err != nil {
+			return nil, errors.Wrap(err, "set git commit")
+		}
+	}
 	log.Infof("helm release %s installed", rls.Name)
 	return rls, nil
 }
@@ -431,6 +535,13 @@ func (r *SRelease) GetChartClient() (*helm.ChartClient, error) {
 }
 
 func (r *SRelease) GetChart() (*chart.Chart, error) {
+	if r.IsGitChart() {
+		gs, err := r.loadGitSource(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		return gs.Chart, nil
+	}
 	repo, err := r.GetRepo()
 	if err != nil {
 		return nil, err
@@ -607,6 +718,18 @@ func (m *SReleaseManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery
 }
 
 func (obj *SRelease) DeleteRemoteObject() error {
+	if err := obj.uninstall(); err != nil {
+		return err
+	}
+	if obj.IsGitSource() {
+		if err := obj.removeGitCheckout(); err != nil {
+			return errors.Wrap(err, "remove git checkout")
+		}
+	}
+	return nil
+}
+
+func (obj *SRelease) uninstall() error {
 	helmCli, err := obj.GetHelmClient()
 	if err != nil {
 		return errors.Wrap(err, "get helm client when delete")
@@ -701,20 +824,54 @@ func (obj *SRelease) PerformRollback(ctx context.Context, userCred mcclient.Toke
 	return nil, nil
 }
 
-func (obj *SRelease) PerformUpgrade(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ReleaseUpdateInput) (jsonutils.JSONObject, error) {
-	cli, err := obj.GetHelmClient()
-	if err != nil {
-		return nil, errors.Wrap(err, "get helm client")
+// fillUpgradeInput sets chart and release of upgrade input by current release
+func (obj *SRelease) fillUpgradeInput(input *api.ReleaseUpdateInput) error {
+	if obj.IsGitSource() {
+		return httperrors.NewNotAcceptableError("release %s is synced from git, commit changes to git or perform git-sync", obj.GetName())
 	}
 	repo, err := obj.GetRepo()
 	if err != nil {
-		return nil, errors.Wrapf(err, "get release %s repo", obj.GetName())
+		return errors.Wrapf(err, "get release %s repo", obj.GetName())
 	}
-	repoName := repo.GetName()
-	input.Repo = repoName
+	input.Repo = repo.GetName()
 	input.ChartName = obj.Chart
 	input.Namespace, _ = obj.GetNamespaceName()
 	input.ReleaseName = obj.GetName()
+	return nil
+}
+
+// GetDetailsUpgradePreview renders release by new chart version and values,
+// returns resources diff against deployed manifest and values schema errors
+func (obj *SRelease) GetDetailsUpgradePreview(ctx context.Context, userCred mcclient.TokenCredential, input *api.ReleaseUpdateInput) (*api.ReleaseUpgradePreview, error) {
+	cli, err := obj.GetHelmClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get helm client")
+	}
+	if err := obj.fillUpgradeInput(input); err != nil {
+		return nil, err
+	}
+	preview, err := cli.Release().UpgradePreview(input)
+	if err != nil {
+		return nil, errors.Wrap(err, "preview upgrade")
+	}
+	return preview, nil
+}
+
+func (obj *SRelease) PerformUpgrade(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ReleaseUpdateInput) (jsonutils.JSONObject, error) {
+	if input.DryRun {
+		preview, err := obj.GetDetailsUpgradePreview(ctx, userCred, input)
+		if err != nil {
+			return nil, err
+		}
+		return jsonutils.Marshal(preview), nil
+	}
+	cli, err := obj.GetHelmClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get helm client")
+	}
+	if err := obj.fillUpgradeInput(input); err != nil {
+		return nil, err
+	}
 	log.Infof("Upgrade repo=%q, chart=%q, release='%s/%s'", input.Repo, input.ChartName, input.Namespace, input.ReleaseName)
 	rls, err := cli.Release().Update(input)
 	if err != nil {
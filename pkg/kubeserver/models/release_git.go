@@ -0,0 +1,264 @@
+package models
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/helm"
+	"yunion.io/x/kubecomps/pkg/kubeserver/options"
+	"yunion.io/x/kubecomps/pkg/utils/gitrepo"
+	"yunion.io/x/kubecomps/pkg/utils/logclient"
+)
+
+// ReleaseGitSyncMinIntervalMinutes is lower bound of periodic git source sync interval
+const ReleaseGitSyncMinIntervalMinutes = 1
+
+// validateGitSource checks out git source to temporary directory to make sure values files and chart are loadable,
+// credentials embedded in https url are moved to input.GitCredential so that they never saved in git source
+func (m *SReleaseManager) validateGitSource(ctx context.Context, input *api.ReleaseCreateInput) (*helm.GitSource, error) {
+	src := input.GitSource
+	if src == nil || src.Url == "" {
+		return nil, httperrors.NewNotEmptyError("git_source.url must provided")
+	}
+	helm.SetGitSourceDefaults(src)
+	if err := gitrepo.ValidateRemote(src.Url, src.Branch, options.Options.ReleaseGitAllowLocalRepository); err != nil {
+		return nil, httperrors.NewInputParameterError("invalid git source: %v", err)
+	}
+	if err := stripGitSourceCredential(input); err != nil {
+		return nil, err
+	}
+	tmpDir, err := ioutil.TempDir("", "release-git-")
+	if err != nil {
+		return nil, errors.Wrap(err, "create temp dir")
+	}
+	defer os.RemoveAll(tmpDir)
+	co := gitrepo.NewCheckout(src.Url, src.Branch, filepath.Join(tmpDir, "source"))
+	setGitCheckoutCredential(co, input.GitCredential)
+	gs, err := helm.LoadGitSource(ctx, co, src)
+	if err != nil {
+		return nil, httperrors.NewInputParameterError("invalid git source: %v", err)
+	}
+	return gs, nil
+}
+
+func stripGitSourceCredential(input *api.ReleaseCreateInput) error {
+	u, err := url.Parse(input.GitSource.Url)
+	if err != nil || u.User == nil {
+		// scp like address, e.g. git@github.com:yunionio/charts.git
+		return nil
+	}
+	passwd, hasPasswd := u.User.Password()
+	if u.Scheme != "https" {
+		if hasPasswd {
+			return httperrors.NewInputParameterError("password in %s url is not supported", u.Scheme)
+		}
+		// ssh user is part of address
+		return nil
+	}
+	if input.GitCredential == nil {
+		input.GitCredential = &api.ReleaseGitCredential{
+			Username: u.User.Username(),
+			Password: passwd,
+		}
+	}
+	u.User = nil
+	input.GitSource.Url = u.String()
+	return nil
+}
+
+func setGitCheckoutCredential(co *gitrepo.Checkout, cred *api.ReleaseGitCredential) {
+	if cred == nil {
+		return
+	}
+	co.Username = cred.Username
+	co.Password = cred.Password
+}
+
+func (r *SRelease) IsGitSource() bool {
+	return r.Source == api.ReleaseSourceGit
+}
+
+// IsGitChart means chart of release is loaded from git repository instead of helm repo
+func (r *SRelease) IsGitChart() bool {
+	return r.IsGitSource() && r.RepoId == ""
+}
+
+func (r *SRelease) GetGitSource() (*api.ReleaseGitSource, error) {
+	if r.GitSource == nil {
+		return nil, errors.Errorf("release %s git source is empty", r.GetName())
+	}
+	src := new(api.ReleaseGitSource)
+	if err := r.GitSource.Unmarshal(src); err != nil {
+		return nil, errors.Wrap(err, "unmarshal git source")
+	}
+	helm.SetGitSourceDefaults(src)
+	return src, nil
+}
+
+func (r *SRelease) getGitCheckout(src *api.ReleaseGitSource) *gitrepo.Checkout {
+	co := gitrepo.NewCheckout(src.Url, src.Branch, filepath.Join(options.Options.HelmDataDir, "git", r.GetId()))
+	setGitCheckoutCredential(co, r.getGitCredential())
+	return co
+}
+
+func (r *SRelease) setGitCredential(ctx context.Context, userCred mcclient.TokenCredential, cred *api.ReleaseGitCredential) error {
+	return r.SetMetadata(ctx, api.ReleaseMetadataGitCredential, jsonutils.Marshal(cred), userCred)
+}
+
+func (r *SRelease) getGitCredential() *api.ReleaseGitCredential {
+	ret := r.GetMetadataJson(context.Background(), api.ReleaseMetadataGitCredential, GetAdminCred())
+	if ret == nil {
+		return nil
+	}
+	cred := new(api.ReleaseGitCredential)
+	if err := ret.Unmarshal(cred); err != nil {
+		log.Errorf("unmarshal release %s git credential: %v", r.GetName(), err)
+		return nil
+	}
+	return cred
+}
+
+// removeGitCheckout cleans local checkout of git source when release deleted
+func (r *SRelease) removeGitCheckout() error {
+	src, err := r.GetGitSource()
+	if err != nil {
+		return err
+	}
+	return r.getGitCheckout(src).Remove()
+}
+
+func (r *SRelease) loadGitSource(ctx context.Context) (*helm.GitSource, error) {
+	src, err := r.GetGitSource()
+	if err != nil {
+		return nil, err
+	}
+	// local repository may be disallowed after release created
+	if err := gitrepo.ValidateRemote(src.Url, src.Branch, options.Options.ReleaseGitAllowLocalRepository); err != nil {
+		return nil, err
+	}
+	return helm.LoadGitSource(ctx, r.getGitCheckout(src), src)
+}
+
+func (r *SRelease) setGitCommit(commit string, cp *chart.Chart) error {
+	_, err := db.Update(r, func() error {
+		r.GitCommit = commit
+		r.GitSyncedAt = time.Now()
+		if cp != nil && cp.Metadata != nil {
+			r.ChartVersion = cp.Metadata.Version
+		}
+		return nil
+	})
+	return err
+}
+
+// SyncGitSource upgrades release when git source has new commit, force upgrades even if commit not changed
+func (r *SRelease) SyncGitSource(ctx context.Context, userCred mcclient.TokenCredential, force bool) error {
+	gs, err := r.loadGitSource(ctx)
+	if err != nil {
+		return errors.Wrap(err, "load git source")
+	}
+	if gs.Commit == r.GitCommit && !force {
+		return nil
+	}
+	cp := gs.Chart
+	if cp == nil {
+		cp, err = r.GetChart()
+		if err != nil {
+			return errors.Wrap(err, "get chart")
+		}
+	}
+	ns, err := r.GetNamespaceName()
+	if err != nil {
+		return errors.Wrap(err, "get namespace")
+	}
+	cli, err := r.GetHelmClient()
+	if err != nil {
+		return errors.Wrap(err, "get helm client")
+	}
+	up := cli.Release().Upgrade()
+	up.Namespace = ns
+	up.Description = helm.GitSyncDescription(gs.Commit)
+	// inline values override values files in git
+	vals := mergeMaps(gs.Values, r.GetHelmValues())
+	log.Infof("Sync release %s/%s to git commit %s", ns, r.GetName(), gs.Commit)
+	if _, err := up.Run(r.GetName(), cp, vals); err != nil {
+		logclient.LogWithContext(ctx, r, logclient.ActionResourceSync, err, userCred, false)
+		return errors.Wrapf(err, "upgrade release to commit %s", gs.Commit)
+	}
+	logclient.LogWithContext(ctx, r, logclient.ActionResourceSync, jsonutils.Marshal(map[string]string{"commit": gs.Commit}), userCred, true)
+	return r.setGitCommit(gs.Commit, cp)
+}
+
+// PerformGitSync syncs release with latest commit of git source immediately
+func (r *SRelease) PerformGitSync(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ReleaseGitSyncInput) (jsonutils.JSONObject, error) {
+	if !r.IsGitSource() {
+		return nil, httperrors.NewNotAcceptableError("release %s is not synced from git", r.GetName())
+	}
+	if err := r.SyncGitSource(ctx, userCred, input.Force); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (r *SRelease) setGitSyncSuspend(suspend bool) error {
+	if !r.IsGitSource() {
+		return httperrors.NewNotAcceptableError("release %s is not synced from git", r.GetName())
+	}
+	_, err := db.Update(r, func() error {
+		r.GitSyncSuspend = suspend
+		return nil
+	})
+	return err
+}
+
+// PerformGitSuspend stops periodic git sync of release
+func (r *SRelease) PerformGitSuspend(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, r.setGitSyncSuspend(true)
+}
+
+// PerformGitResume resumes periodic git sync of release
+func (r *SRelease) PerformGitResume(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, r.setGitSyncSuspend(false)
+}
+
+func (m *SReleaseManager) fetchGitSyncReleases() ([]SRelease, error) {
+	q := m.Query().Equals("source", api.ReleaseSourceGit).IsFalse("git_sync_suspend")
+	rels := make([]SRelease, 0)
+	if err := db.FetchModelObjects(m, q, &rels); err != nil {
+		return nil, err
+	}
+	return rels, nil
+}
+
+// SyncGitReleases periodically upgrades git source releases having new commits
+func (m *SReleaseManager) SyncGitReleases(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	rels, err := m.fetchGitSyncReleases()
+	if err != nil {
+		log.Errorf("fetch git sync releases: %v", err)
+		return
+	}
+	for i := range rels {
+		r := &rels[i]
+		if r.GitCommit == "" {
+			// release is still creating
+			continue
+		}
+		if err := r.SyncGitSource(ctx, userCred, false); err != nil {
+			log.Errorf("sync release %s git source: %v", r.GetName(), err)
+		}
+	}
+}
@@ -83,6 +83,13 @@ type SRepo struct {
 	Password string `width:"256" charset:"ascii" nullable:"false" update:"user"`
 	Type     string `charset:"ascii" width:"128" create:"required" nullable:"true" list:"user"`
 	Backend  string `charset:"ascii" width:"128" create:"required" nullable:"true" list:"user"`
+
+	// ContainerRegistryId is the registry oci backend repo stored in,
+	// credential of registry is used when username is empty
+	ContainerRegistryId string `width:"36" charset:"ascii" nullable:"true" create:"optional" list:"user"`
+	// PlainHttp and InsecureSkipVerify are options of oci backend to access registry
+	PlainHttp          bool `nullable:"false" default:"false" create:"optional" list:"user"`
+	InsecureSkipVerify bool `nullable:"false" default:"false" create:"optional" list:"user"`
 }
 
 func (man *SRepoManager) AllowListItems(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
@@ -124,6 +131,13 @@ func (man *SRepoManager) FetchCustomizeColumns(
 			Type:                            rObj.Type,
 			ReleaseCount:                    rlsCnt,
 		}
+		if rObj.ContainerRegistryId != "" {
+			if reg, err := GetContainerRegistryManager().FetchById(rObj.ContainerRegistryId); err != nil {
+				log.Errorf("Get repo %s container registry error: %v", rObj.GetName(), err)
+			} else {
+				detail.ContainerRegistry = reg.GetName()
+			}
+		}
 		rows[i] = detail
 	}
 	return rows
@@ -153,12 +167,6 @@ func (man *SRepoManager) ValidateCreateData(ctx context.Context, userCred mcclie
 		return nil, err
 	}
 	data.StatusInfrasResourceBaseCreateInput = shareInput
-	if data.Url == "" {
-		return nil, httperrors.NewInputParameterError("Missing repo url")
-	}
-	if _, err := url.Parse(data.Url); err != nil {
-		return nil, httperrors.NewNotAcceptableError("Invalid repo url: %v", err)
-	}
 
 	if data.Type == "" {
 		data.Type = string(api.RepoTypeExternal)
@@ -180,7 +188,20 @@ func (man *SRepoManager) ValidateCreateData(ctx context.Context, userCred mcclie
 		return nil, errors.Wrapf(err, "driver %s ValidateCreateData", drv.GetBackend())
 	}
 
+	// oci backend may fill url by container registry
+	if data.Url == "" {
+		return nil, httperrors.NewInputParameterError("Missing repo url")
+	}
+	if _, err := url.Parse(data.Url); err != nil {
+		return nil, httperrors.NewNotAcceptableError("Invalid repo url: %v", err)
+	}
+
 	entry := man.ToEntry(data.Name, data.Url, data.Username, data.Password)
+	if data.Backend == api.RepoBackendOCI {
+		if err := man.setOCIEntry(entry, data.ContainerRegistryId, data.PlainHttp, data.InsecureSkipVerify); err != nil {
+			return nil, err
+		}
+	}
 	cli, err := man.GetClient(ownerId.GetProjectDomainId())
 	if err != nil {
 		return nil, errors.Wrapf(err, "GetClient by domainId: %s", ownerId.GetProjectDomainId())
@@ -233,8 +254,54 @@ func (man *SRepoManager) ToEntry(name, url, username, password string) *repo.Ent
 	return ret
 }
 
+// setOCIEntry applies access options and credential of container registry to oci repo entry
+func (man *SRepoManager) setOCIEntry(entry *repo.Entry, registryId string, plainHttp bool, insecure bool) error {
+	entry.InsecureSkipTLSverify = insecure
+	if plainHttp {
+		entry.URL = helm.OCIURLWithPlainHTTP(entry.URL)
+	}
+	if registryId == "" || entry.Username != "" {
+		return nil
+	}
+	// credential is read from registry every time, so it keeps up with registry updated
+	regObj, err := GetContainerRegistryManager().FetchById(registryId)
+	if err != nil {
+		return errors.Wrapf(err, "get container registry %s", registryId)
+	}
+	auth, err := regObj.(*SContainerRegistry).GetAuthConfig()
+	if err != nil {
+		return errors.Wrapf(err, "get container registry %s auth config", regObj.GetName())
+	}
+	entry.Username = auth.Username
+	entry.Password = auth.Password
+	return nil
+}
+
+// FetchOCIRepoByUrl finds oci backend repo visible to user by url like oci://registry/path
+func (man *SRepoManager) FetchOCIRepoByUrl(ctx context.Context, userCred mcclient.IIdentityProvider, repoUrl string) (*SRepo, error) {
+	q := man.Query().Equals("backend", api.RepoBackendOCI).In("url", []string{repoUrl, repoUrl + "/"})
+	q = man.FilterByOwner(ctx, q, man, nil, userCred, man.NamespaceScope())
+	repos := make([]SRepo, 0)
+	if err := db.FetchModelObjects(man, q, &repos); err != nil {
+		return nil, errors.Wrapf(err, "fetch oci repo by url %s", repoUrl)
+	}
+	if len(repos) == 0 {
+		return nil, httperrors.NewNotFoundError("oci repo of %s not found, please register it first", repoUrl)
+	}
+	if len(repos) > 1 {
+		return nil, httperrors.NewDuplicateResourceError("multiple oci repos of %s found, please specify repo", repoUrl)
+	}
+	return &repos[0], nil
+}
+
 func (r *SRepo) ToEntry() *repo.Entry {
-	return RepoManager.ToEntry(r.Name, r.Url, r.Username, r.Password)
+	entry := RepoManager.ToEntry(r.Name, r.Url, r.Username, r.Password)
+	if r.GetBackend() == api.RepoBackendOCI {
+		if err := RepoManager.setOCIEntry(entry, r.ContainerRegistryId, r.PlainHttp, r.InsecureSkipVerify); err != nil {
+			log.Errorf("Set oci repo %s entry error: %v", r.GetName(), err)
+		}
+	}
+	return entry
 }
 
 func (r *SRepo) GetReleaseCount() (int, error) {
@@ -277,8 +344,13 @@ func (r *SRepo) DoSync() error {
 		return err
 	}
 	entry := r.ToEntry()
-	if err := cli.Add(entry); err != nil && errors.Cause(err) != helm.ErrRepoAlreadyExists {
-		return err
+	if err := cli.Add(entry); err != nil {
+		if errors.Cause(err) != helm.ErrRepoAlreadyExists {
+			return err
+		}
+		if err := cli.UpdateEntry(entry); err != nil {
+			return errors.Wrapf(err, "update repo %s entry", r.GetName())
+		}
 	}
 	return cli.Update(r.Name)
 }
@@ -6,6 +6,9 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kubernetes/pkg/apis/core"
+	"k8s.io/kubernetes/pkg/apis/core/validation"
 
 	"yunion.io/x/jsonutils"
 	// "yunion.io/x/onecloud/pkg/cloudcommon/db"
@@ -82,6 +85,12 @@ func (m *SSecretManager) RegisterDriver(typ v1.SecretType, driver ISecretDriver)
 	}
 }
 
+func (m *SSecretManager) ValidateSecretObject(secret *v1.Secret) error {
+	return ValidateCreateK8sObject(secret, new(core.Secret), func(out interface{}) field.ErrorList {
+		return validation.ValidateSecret(out.(*core.Secret))
+	})
+}
+
 func (m *SSecretManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.SecretCreateInput) (*api.SecretCreateInput, error) {
 	if _, err := m.SNamespaceResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, &input.NamespaceResourceCreateInput); err != nil {
 		return input, err
@@ -161,6 +161,26 @@ func (obj *SStatefulSet) GetDetails(ctx context.Context, cli *client.ClusterMana
 		detail.Pods = *podInfo
 		detail.StatefulSetStatus = *getters.GetStatefulSetStatus(podInfo, *ss)
 	}
+	if !isList {
+		hpas, err := GetHorizontalPodAutoscalersByTarget(cli, ss.GetNamespace(), api.KindNameStatefulSet, ss.GetName())
+		if err != nil {
+			log.Errorf("Get horizontalpodautoscalers by statefulset %s error: %v", obj.GetName(), err)
+		} else {
+			detail.HorizontalPodAutoscalers = hpas
+		}
+		pdbs, err := GetPodDisruptionBudgetsByTemplate(cli, ss.GetNamespace(), &ss.Spec.Template)
+		if err != nil {
+			log.Errorf("Get poddisruptionbudgets by statefulset %s error: %v", obj.GetName(), err)
+		} else {
+			detail.PodDisruptionBudgets = pdbs
+		}
+		usage, err := GetWorkloadUsage(ctx, cli, ss.GetNamespace(), ss.Spec.Selector)
+		if err != nil {
+			logUsageError(api.KindNameStatefulSet, obj.GetName(), err)
+		} else {
+			detail.Usage = usage
+		}
+	}
 	return detail
 }
 
@@ -91,6 +91,14 @@ func (m *SStorageClassManager) GetDriver(provisioner string) (IStorageClassDrive
 	return drv.(IStorageClassDriver), nil
 }
 
+// GetDriverByInput returns generic csi driver when csi params are provided, otherwise driver of provisioner
+func (m *SStorageClassManager) GetDriverByInput(input *api.StorageClassCreateInput) (IStorageClassDriver, error) {
+	if input.CSI != nil {
+		return m.GetDriver(api.StorageClassDriverCSI)
+	}
+	return m.GetDriver(input.Provisioner)
+}
+
 func (m *SStorageClassManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.StorageClassCreateInput) (*api.StorageClassCreateInput, error) {
 	cinput, err := m.SClusterResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, &input.ClusterResourceCreateInput)
 	if err != nil {
@@ -100,7 +108,7 @@ func (m *SStorageClassManager) ValidateCreateData(ctx context.Context, userCred
 	if input.Provisioner == "" {
 		return nil, httperrors.NewNotEmptyError("provisioner is empty")
 	}
-	drv, err := m.GetDriver(input.Provisioner)
+	drv, err := m.GetDriverByInput(input)
 	if err != nil {
 		return nil, err
 	}
@@ -114,7 +122,7 @@ func (m *SStorageClassManager) ValidateCreateData(ctx context.Context, userCred
 func (m *SStorageClassManager) NewRemoteObjectForCreate(model IClusterModel, cli *client.ClusterManager, body jsonutils.JSONObject) (interface{}, error) {
 	input := new(api.StorageClassCreateInput)
 	body.Unmarshal(input)
-	drv, err := m.GetDriver(input.Provisioner)
+	drv, err := m.GetDriverByInput(input)
 	if err != nil {
 		return nil, err
 	}
@@ -124,13 +132,14 @@ func (m *SStorageClassManager) NewRemoteObjectForCreate(model IClusterModel, cli
 	}
 	objMeta := input.ToObjectMeta()
 	return &v1.StorageClass{
-		ObjectMeta:        objMeta,
-		Provisioner:       input.Provisioner,
-		Parameters:        params,
-		ReclaimPolicy:     input.ReclaimPolicy,
-		MountOptions:      input.MountOptions,
-		VolumeBindingMode: input.VolumeBindingMode,
-		AllowedTopologies: input.AllowedTopologies,
+		ObjectMeta:           objMeta,
+		Provisioner:          input.Provisioner,
+		Parameters:           params,
+		ReclaimPolicy:        input.ReclaimPolicy,
+		AllowVolumeExpansion: input.AllowVolumeExpansion,
+		MountOptions:         input.MountOptions,
+		VolumeBindingMode:    input.VolumeBindingMode,
+		AllowedTopologies:    input.AllowedTopologies,
 	}, nil
 }
 
@@ -156,7 +165,7 @@ func (m *SStorageClass) GetDetails(ctx context.Context, cli *client.ClusterManag
 }
 
 func (m *SStorageClassManager) PerformConnectionTest(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.StorageClassCreateInput) (*api.StorageClassTestResult, error) {
-	drv, err := m.GetDriver(input.Provisioner)
+	drv, err := m.GetDriverByInput(input)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,573 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/client"
+)
+
+// errWorkloadUnchanged is returned by mutate of updateRemoteWorkload to skip the update
+var errWorkloadUnchanged = errors.Error("workload unchanged")
+
+// updateRemoteWorkload applies mutate on a copy of the remote object, then
+// writes it back and syncs local model from the updated object
+func updateRemoteWorkload(ctx context.Context, userCred mcclient.TokenCredential, model IClusterModel, mutate func(obj runtime.Object) error) error {
+	obj, err := GetK8sObject(model)
+	if err != nil {
+		return errors.Wrap(err, "get remote object")
+	}
+	obj = obj.DeepCopyObject()
+	if err := mutate(obj); err != nil {
+		if errors.Cause(err) == errWorkloadUnchanged {
+			return nil
+		}
+		return err
+	}
+	newObj, err := model.UpdateRemoteObject(obj)
+	if err != nil {
+		return errors.Wrapf(err, "update remote %s %s", model.Keyword(), model.GetName())
+	}
+	return GetClusterResAPI().UpdateFromRemoteObject(model, ctx, userCred, newObj)
+}
+
+func validateScaleInput(input *api.WorkloadScaleInput) error {
+	if input.Replicas == nil {
+		return httperrors.NewNotEmptyError("replicas")
+	}
+	if *input.Replicas < 0 {
+		return httperrors.NewInputParameterError("replicas %d must not be negative", *input.Replicas)
+	}
+	return nil
+}
+
+func setPodTemplateRestartedAt(tpl *v1.PodTemplateSpec) {
+	if tpl.Annotations == nil {
+		tpl.Annotations = make(map[string]string)
+	}
+	tpl.Annotations[api.AnnotationRestartedAt] = time.Now().Format(time.RFC3339)
+}
+
+func podTemplateToYAML(tpl *v1.PodTemplateSpec) (string, error) {
+	if tpl == nil {
+		return "", nil
+	}
+	out, err := yaml.Marshal(tpl)
+	if err != nil {
+		return "", errors.Wrap(err, "marshal pod template")
+	}
+	return string(out), nil
+}
+
+// getPodTemplateDiff returns unified diff between pod templates of two revisions
+func getPodTemplateDiff(prev, cur *v1.PodTemplateSpec, prevName, curName string) (string, error) {
+	a, err := podTemplateToYAML(prev)
+	if err != nil {
+		return "", err
+	}
+	b, err := podTemplateToYAML(cur)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a),
+		B:        difflib.SplitLines(b),
+		FromFile: prevName,
+		ToFile:   curName,
+		Context:  3,
+	})
+}
+
+type workloadRevision struct {
+	api.WorkloadRevision
+	template *v1.PodTemplateSpec
+}
+
+// fillRevisionsDiff sorts revisions ascending and computes diff against the previous one
+func fillRevisionsDiff(revs []workloadRevision) ([]api.WorkloadRevision, error) {
+	sort.Slice(revs, func(i, j int) bool {
+		return revs[i].Revision < revs[j].Revision
+	})
+	ret := make([]api.WorkloadRevision, 0, len(revs))
+	for i := range revs {
+		rev := revs[i]
+		rev.ContainerImages = GetContainerImages(&rev.template.Spec)
+		var (
+			prev     *v1.PodTemplateSpec
+			prevName string
+		)
+		if i > 0 {
+			prev = revs[i-1].template
+			prevName = revs[i-1].Name
+		}
+		diff, err := getPodTemplateDiff(prev, rev.template, prevName, rev.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "diff revision %d", rev.Revision)
+		}
+		rev.Diff = diff
+		ret = append(ret, rev.WorkloadRevision)
+	}
+	return ret, nil
+}
+
+// findRollbackRevision returns the revision to rollback, toRevision 0 means the one before current
+func findRollbackRevision(revs []workloadRevision, current int64, toRevision int64) (*workloadRevision, error) {
+	if toRevision < 0 {
+		return nil, httperrors.NewInputParameterError("revision %d must not be negative", toRevision)
+	}
+	var target *workloadRevision
+	for i := range revs {
+		rev := &revs[i]
+		if toRevision == 0 {
+			if rev.Revision < current && (target == nil || rev.Revision > target.Revision) {
+				target = rev
+			}
+		} else if rev.Revision == toRevision {
+			target = rev
+		}
+	}
+	if target == nil {
+		if toRevision == 0 {
+			return nil, httperrors.NewNotFoundError("no rollout history found")
+		}
+		return nil, httperrors.NewNotFoundError("revision %d not found", toRevision)
+	}
+	return target, nil
+}
+
+func getReplicaSetRevision(rs metav1.Object) int64 {
+	v, err := strconv.ParseInt(rs.GetAnnotations()[api.AnnotationDeploymentRevision], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// ------ Deployment ------
+
+func (obj *SDeployment) getRevisions(cli *client.ClusterManager, deploy *apps.Deployment) ([]workloadRevision, error) {
+	rss, err := obj.GetRawReplicaSets(cli, deploy)
+	if err != nil {
+		return nil, errors.Wrap(err, "get replicasets")
+	}
+	revs := make([]workloadRevision, 0)
+	for _, rs := range rss {
+		if !metav1.IsControlledBy(rs, deploy) {
+			continue
+		}
+		tpl := rs.Spec.Template.DeepCopy()
+		// pod-template-hash is added by controller, drop it to get the user defined template
+		delete(tpl.Labels, apps.DefaultDeploymentUniqueLabelKey)
+		revs = append(revs, workloadRevision{
+			WorkloadRevision: api.WorkloadRevision{
+				Revision:          getReplicaSetRevision(rs),
+				Name:              rs.GetName(),
+				CreationTimestamp: rs.GetCreationTimestamp().Time,
+				ChangeCause:       rs.GetAnnotations()[api.AnnotationChangeCause],
+				Replicas:          rs.Spec.Replicas,
+			},
+			template: tpl,
+		})
+	}
+	return revs, nil
+}
+
+func (obj *SDeployment) GetDetailsRevisions(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.WorkloadRevisionsDetail, error) {
+	cli, err := obj.GetClusterClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get cluster client")
+	}
+	deploy, err := obj.GetRawDeployment()
+	if err != nil {
+		return nil, errors.Wrap(err, "get remote deployment")
+	}
+	revs, err := obj.getRevisions(cli, deploy)
+	if err != nil {
+		return nil, err
+	}
+	current := getReplicaSetRevision(deploy)
+	for i := range revs {
+		revs[i].Current = revs[i].Revision == current
+	}
+	ret, err := fillRevisionsDiff(revs)
+	if err != nil {
+		return nil, err
+	}
+	return &api.WorkloadRevisionsDetail{
+		Kind:            string(api.KindNameDeployment),
+		Name:            deploy.GetName(),
+		CurrentRevision: current,
+		Revisions:       ret,
+	}, nil
+}
+
+func (obj *SDeployment) AllowPerformScale(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return db.IsDomainAllowPerform(ctx, userCred, obj, "scale")
+}
+
+func (obj *SDeployment) PerformScale(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.WorkloadScaleInput) (jsonutils.JSONObject, error) {
+	if err := validateScaleInput(input); err != nil {
+		return nil, err
+	}
+	return nil, updateRemoteWorkload(ctx, userCred, obj, func(o runtime.Object) error {
+		o.(*apps.Deployment).Spec.Replicas = input.Replicas
+		return nil
+	})
+}
+
+func (obj *SDeployment) AllowPerformRestart(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return db.IsDomainAllowPerform(ctx, userCred, obj, "restart")
+}
+
+func (obj *SDeployment) PerformRestart(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, updateRemoteWorkload(ctx, userCred, obj, func(o runtime.Object) error {
+		deploy := o.(*apps.Deployment)
+		if deploy.Spec.Paused {
+			return httperrors.NewInvalidStatusError("can't restart paused deployment %s, resume it first", deploy.GetName())
+		}
+		setPodTemplateRestartedAt(&deploy.Spec.Template)
+		return nil
+	})
+}
+
+func (obj *SDeployment) setPaused(ctx context.Context, userCred mcclient.TokenCredential, paused bool) error {
+	return updateRemoteWorkload(ctx, userCred, obj, func(o runtime.Object) error {
+		deploy := o.(*apps.Deployment)
+		if deploy.Spec.Paused == paused {
+			if paused {
+				return httperrors.NewInvalidStatusError("deployment %s is already paused", deploy.GetName())
+			}
+			return httperrors.NewInvalidStatusError("deployment %s is not paused", deploy.GetName())
+		}
+		deploy.Spec.Paused = paused
+		return nil
+	})
+}
+
+func (obj *SDeployment) AllowPerformPause(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return db.IsDomainAllowPerform(ctx, userCred, obj, "pause")
+}
+
+func (obj *SDeployment) PerformPause(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, obj.setPaused(ctx, userCred, true)
+}
+
+func (obj *SDeployment) AllowPerformResume(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return db.IsDomainAllowPerform(ctx, userCred, obj, "resume")
+}
+
+func (obj *SDeployment) PerformResume(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, obj.setPaused(ctx, userCred, false)
+}
+
+func (obj *SDeployment) AllowPerformRollback(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return db.IsDomainAllowPerform(ctx, userCred, obj, "rollback")
+}
+
+// PerformRollback rolls deployment template back to the one of target ReplicaSet revision like `kubectl rollout undo`
+func (obj *SDeployment) PerformRollback(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.WorkloadRollbackInput) (jsonutils.JSONObject, error) {
+	cli, err := obj.GetClusterClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "get cluster client")
+	}
+	return nil, updateRemoteWorkload(ctx, userCred, obj, func(o runtime.Object) error {
+		deploy := o.(*apps.Deployment)
+		if deploy.Spec.Paused {
+			return httperrors.NewInvalidStatusError("can't rollback paused deployment %s, resume it first", deploy.GetName())
+		}
+		revs, err := obj.getRevisions(cli, deploy)
+		if err != nil {
+			return err
+		}
+		target, err := findRollbackRevision(revs, getReplicaSetRevision(deploy), input.Revision)
+		if err != nil {
+			return err
+		}
+		// pod-template-hash is dropped from target, compare with current one in the same way
+		current := deploy.Spec.Template.DeepCopy()
+		delete(current.Labels, apps.DefaultDeploymentUniqueLabelKey)
+		if equality.Semantic.DeepEqual(*current, *target.template) {
+			return errWorkloadUnchanged
+		}
+		deploy.Spec.Template = *target.template
+		if target.ChangeCause != "" {
+			if deploy.Annotations == nil {
+				deploy.Annotations = make(map[string]string)
+			}
+			deploy.Annotations[api.AnnotationChangeCause] = target.ChangeCause
+		}
+		return nil
+	})
+}
+
+// ------ StatefulSet and DaemonSet share ControllerRevision based history ------
+
+func getRawControllerRevisions(ctx context.Context, cli *client.ClusterManager, owner metav1.Object, selector *metav1.LabelSelector) ([]*apps.ControllerRevision, error) {
+	sel, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, errors.Wrap(err, "label selector")
+	}
+	list, err := cli.GetClientset().AppsV1().ControllerRevisions(owner.GetNamespace()).List(ctx, metav1.ListOptions{
+		LabelSelector: sel.String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list controllerrevisions")
+	}
+	ret := make([]*apps.ControllerRevision, 0)
+	for i := range list.Items {
+		cr := &list.Items[i]
+		if metav1.IsControlledBy(cr, owner) {
+			ret = append(ret, cr)
+		}
+	}
+	return ret, nil
+}
+
+// controllerRevisionTemplate decodes pod template from ControllerRevision data,
+// which is a strategic merge patch of `spec.template` for statefulset and daemonset
+func controllerRevisionTemplate(cr *apps.ControllerRevision) (*v1.PodTemplateSpec, error) {
+	patch := struct {
+		Spec struct {
+			Template v1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(cr.Data.Raw, &patch); err != nil {
+		return nil, errors.Wrapf(err, "decode controllerrevision %s", cr.GetName())
+	}
+	return &patch.Spec.Template, nil
+}
+
+type controllerRevisionHistory struct {
+	revs    []workloadRevision
+	crMap   map[int64]*apps.ControllerRevision
+	current int64
+}
+
+// getControllerRevisionHistory loads revisions of owner, isCurrent picks the current
+// revision, the latest one is used when isCurrent is nil or matches nothing
+func getControllerRevisionHistory(ctx context.Context, cli *client.ClusterManager, owner metav1.Object, selector *metav1.LabelSelector, isCurrent func(cr *apps.ControllerRevision) bool) (*controllerRevisionHistory, error) {
+	crs, err := getRawControllerRevisions(ctx, cli, owner, selector)
+	if err != nil {
+		return nil, err
+	}
+	h := &controllerRevisionHistory{
+		revs:  make([]workloadRevision, 0, len(crs)),
+		crMap: make(map[int64]*apps.ControllerRevision),
+	}
+	var latest int64
+	for _, cr := range crs {
+		tpl, err := controllerRevisionTemplate(cr)
+		if err != nil {
+			return nil, err
+		}
+		h.revs = append(h.revs, workloadRevision{
+			WorkloadRevision: api.WorkloadRevision{
+				Revision:          cr.Revision,
+				Name:              cr.GetName(),
+				CreationTimestamp: cr.GetCreationTimestamp().Time,
+				ChangeCause:       cr.GetAnnotations()[api.AnnotationChangeCause],
+			},
+			template: tpl,
+		})
+		h.crMap[cr.Revision] = cr
+		if cr.Revision > latest {
+			latest = cr.Revision
+		}
+		if isCurrent != nil && isCurrent(cr) {
+			h.current = cr.Revision
+		}
+	}
+	if h.current == 0 {
+		h.current = latest
+	}
+	return h, nil
+}
+
+func (h *controllerRevisionHistory) toDetail(kind string, owner metav1.Object) (*api.WorkloadRevisionsDetail, error) {
+	for i := range h.revs {
+		h.revs[i].Current = h.revs[i].Revision == h.current
+	}
+	ret, err := fillRevisionsDiff(h.revs)
+	if err != nil {
+		return nil, err
+	}
+	return &api.WorkloadRevisionsDetail{
+		Kind:            kind,
+		Name:            owner.GetName(),
+		CurrentRevision: h.current,
+		Revisions:       ret,
+	}, nil
+}
+
+// rollback patches the revision data back to workload like `kubectl rollout undo` does
+func (h *controllerRevisionHistory) rollback(
+	ctx context.Context, userCred mcclient.TokenCredential, model IClusterModel,
+	input *api.WorkloadRollbackInput, patch func(data []byte) (runtime.Object, error),
+) error {
+	target, err := findRollbackRevision(h.revs, h.current, input.Revision)
+	if err != nil {
+		return err
+	}
+	if target.Revision == h.current {
+		return nil
+	}
+	newObj, err := patch(h.crMap[target.Revision].Data.Raw)
+	if err != nil {
+		return errors.Wrapf(err, "rollback %s %s to revision %d", model.Keyword(), model.GetName(), target.Revision)
+	}
+	return GetClusterResAPI().UpdateFromRemoteObject(model, ctx, userCred, newObj)
+}
+
+// ------ StatefulSet ------
+
+func (obj *SStatefulSet) GetRawStatefulSet() (*apps.StatefulSet, error) {
+	kObj, err := GetK8sObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	return kObj.(*apps.StatefulSet), nil
+}
+
+func (obj *SStatefulSet) getRevisionHistory(ctx context.Context) (*client.ClusterManager, *apps.StatefulSet, *controllerRevisionHistory, error) {
+	cli, err := obj.GetClusterClient()
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "get cluster client")
+	}
+	ss, err := obj.GetRawStatefulSet()
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "get remote statefulset")
+	}
+	h, err := getControllerRevisionHistory(ctx, cli, ss, ss.Spec.Selector, func(cr *apps.ControllerRevision) bool {
+		return cr.GetName() == ss.Status.UpdateRevision
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return cli, ss, h, nil
+}
+
+func (obj *SStatefulSet) GetDetailsRevisions(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.WorkloadRevisionsDetail, error) {
+	_, ss, h, err := obj.getRevisionHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return h.toDetail(string(api.KindNameStatefulSet), ss)
+}
+
+func (obj *SStatefulSet) AllowPerformScale(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return db.IsDomainAllowPerform(ctx, userCred, obj, "scale")
+}
+
+func (obj *SStatefulSet) PerformScale(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.WorkloadScaleInput) (jsonutils.JSONObject, error) {
+	if err := validateScaleInput(input); err != nil {
+		return nil, err
+	}
+	return nil, updateRemoteWorkload(ctx, userCred, obj, func(o runtime.Object) error {
+		o.(*apps.StatefulSet).Spec.Replicas = input.Replicas
+		return nil
+	})
+}
+
+func (obj *SStatefulSet) AllowPerformRestart(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return db.IsDomainAllowPerform(ctx, userCred, obj, "restart")
+}
+
+func (obj *SStatefulSet) PerformRestart(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, updateRemoteWorkload(ctx, userCred, obj, func(o runtime.Object) error {
+		setPodTemplateRestartedAt(&o.(*apps.StatefulSet).Spec.Template)
+		return nil
+	})
+}
+
+func (obj *SStatefulSet) AllowPerformRollback(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return db.IsDomainAllowPerform(ctx, userCred, obj, "rollback")
+}
+
+func (obj *SStatefulSet) PerformRollback(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.WorkloadRollbackInput) (jsonutils.JSONObject, error) {
+	cli, ss, h, err := obj.getRevisionHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return nil, h.rollback(ctx, userCred, obj, input, func(data []byte) (runtime.Object, error) {
+		return cli.GetClientset().AppsV1().StatefulSets(ss.GetNamespace()).Patch(ctx, ss.GetName(), types.StrategicMergePatchType, data, metav1.PatchOptions{})
+	})
+}
+
+// ------ DaemonSet ------
+
+func (obj *SDaemonSet) GetRawDaemonSet() (*apps.DaemonSet, error) {
+	kObj, err := GetK8sObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	return kObj.(*apps.DaemonSet), nil
+}
+
+func (obj *SDaemonSet) getRevisionHistory(ctx context.Context) (*client.ClusterManager, *apps.DaemonSet, *controllerRevisionHistory, error) {
+	cli, err := obj.GetClusterClient()
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "get cluster client")
+	}
+	ds, err := obj.GetRawDaemonSet()
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "get remote daemonset")
+	}
+	// daemonset status doesn't record revision name, the latest one is always current
+	h, err := getControllerRevisionHistory(ctx, cli, ds, ds.Spec.Selector, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return cli, ds, h, nil
+}
+
+func (obj *SDaemonSet) GetDetailsRevisions(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.WorkloadRevisionsDetail, error) {
+	_, ds, h, err := obj.getRevisionHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return h.toDetail(string(api.KindNameDaemonSet), ds)
+}
+
+func (obj *SDaemonSet) AllowPerformRestart(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return db.IsDomainAllowPerform(ctx, userCred, obj, "restart")
+}
+
+func (obj *SDaemonSet) PerformRestart(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, updateRemoteWorkload(ctx, userCred, obj, func(o runtime.Object) error {
+		setPodTemplateRestartedAt(&o.(*apps.DaemonSet).Spec.Template)
+		return nil
+	})
+}
+
+func (obj *SDaemonSet) AllowPerformRollback(ctx context.Context, userCred mcclient.TokenCredential, query, data jsonutils.JSONObject) bool {
+	return db.IsDomainAllowPerform(ctx, userCred, obj, "rollback")
+}
+
+func (obj *SDaemonSet) PerformRollback(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.WorkloadRollbackInput) (jsonutils.JSONObject, error) {
+	cli, ds, h, err := obj.getRevisionHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return nil, h.rollback(ctx, userCred, obj, input, func(data []byte) (runtime.Object, error) {
+		return cli.GetClientset().AppsV1().DaemonSets(ds.GetNamespace()).Patch(ctx, ds.GetName(), types.StrategicMergePatchType, data, metav1.PatchOptions{})
+	})
+}
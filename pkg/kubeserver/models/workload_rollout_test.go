@@ -0,0 +1,95 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+)
+
+func newRolloutTestRevision(rev int64, image string) workloadRevision {
+	return workloadRevision{
+		WorkloadRevision: api.WorkloadRevision{Revision: rev},
+		template: &v1.PodTemplateSpec{
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{Name: "app", Image: image}},
+			},
+		},
+	}
+}
+
+func TestFindRollbackRevision(t *testing.T) {
+	revs := []workloadRevision{
+		newRolloutTestRevision(3, "app:v3"),
+		newRolloutTestRevision(1, "app:v1"),
+		newRolloutTestRevision(4, "app:v4"),
+	}
+	cases := []struct {
+		current int64
+		to      int64
+		want    int64
+		wantErr bool
+	}{
+		{current: 4, to: 0, want: 3},
+		{current: 3, to: 0, want: 1},
+		{current: 4, to: 1, want: 1},
+		{current: 1, to: 0, wantErr: true},
+		{current: 4, to: 2, wantErr: true},
+		{current: 4, to: -1, wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := findRollbackRevision(revs, c.current, c.to)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("current %d to %d: expect error, got revision %d", c.current, c.to, got.Revision)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("current %d to %d: %v", c.current, c.to, err)
+			continue
+		}
+		if got.Revision != c.want {
+			t.Errorf("current %d to %d: got %d, want %d", c.current, c.to, got.Revision, c.want)
+		}
+	}
+}
+
+func TestFillRevisionsDiff(t *testing.T) {
+	revs := []workloadRevision{
+		newRolloutTestRevision(2, "app:v2"),
+		newRolloutTestRevision(1, "app:v1"),
+	}
+	ret, err := fillRevisionsDiff(revs)
+	if err != nil {
+		t.Fatalf("fillRevisionsDiff: %v", err)
+	}
+	if ret[0].Revision != 1 || ret[1].Revision != 2 {
+		t.Fatalf("revisions not sorted: %d, %d", ret[0].Revision, ret[1].Revision)
+	}
+	if !strings.Contains(ret[1].Diff, "-  - image: app:v1") || !strings.Contains(ret[1].Diff, "+  - image: app:v2") {
+		t.Errorf("unexpected diff:\n%s", ret[1].Diff)
+	}
+	if ret[1].ContainerImages[0].Image != "app:v2" {
+		t.Errorf("unexpected images %v", ret[1].ContainerImages)
+	}
+}
+
+func TestControllerRevisionTemplate(t *testing.T) {
+	cr := &apps.ControllerRevision{
+		Data: runtime.RawExtension{
+			Raw: []byte(`{"spec":{"template":{"$patch":"replace","spec":{"containers":[{"name":"app","image":"app:v1"}]}}}}`),
+		},
+	}
+	tpl, err := controllerRevisionTemplate(cr)
+	if err != nil {
+		t.Fatalf("controllerRevisionTemplate: %v", err)
+	}
+	if len(tpl.Spec.Containers) != 1 || tpl.Spec.Containers[0].Image != "app:v1" {
+		t.Errorf("unexpected template %#v", tpl.Spec)
+	}
+}
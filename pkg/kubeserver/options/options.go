@@ -43,6 +43,17 @@ type KubeServerOptions struct {
 	OfflineRegistryServiceURL string `help:"offline registry service url"`
 
 	RunningMode string `help:"running mode" choices:"k8s|docker-compose" default:"k8s"`
+
+	// cluster certificates expiry check
+	CertificateExpiryWarningDays  int `help:"Raise cluster event when certificates expire in days" default:"30"`
+	CertificateExpiryCriticalDays int `help:"Certificates expire in days are treated as critical" default:"7"`
+
+	// federated resources drift reconcile
+	FederatedDriftCheckIntervalMinutes int `help:"Interval minutes of comparing federated resources with member cluster objects" default:"10"`
+
+	// git source releases sync
+	ReleaseGitSyncIntervalMinutes  int  `help:"Interval minutes of syncing releases with git source" default:"5"`
+	ReleaseGitAllowLocalRepository bool `help:"Allow git source of release to be local file:// repository on server" default:"false"`
 }
 
 const (
@@ -0,0 +1,110 @@
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+	"yunion.io/x/kubecomps/pkg/utils/logclient"
+)
+
+func init() {
+	taskman.RegisterTask(ClusterBackupCreateTask{})
+	taskman.RegisterTask(ClusterBackupDeleteTask{})
+	taskman.RegisterTask(ClusterBackupRestoreTask{})
+}
+
+type ClusterBackupCreateTask struct {
+	taskman.STask
+}
+
+func (t *ClusterBackupCreateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	backup := obj.(*models.SClusterBackup)
+	t.SetStage("OnBackupComplete", nil)
+	taskman.LocalTaskRun(t, func() (jsonutils.JSONObject, error) {
+		return nil, backup.DoBackup(ctx, t.UserCred)
+	})
+}
+
+func (t *ClusterBackupCreateTask) OnBackupComplete(ctx context.Context, backup *models.SClusterBackup, data jsonutils.JSONObject) {
+	backup.SetStatus(ctx, t.UserCred, api.ClusterBackupStatusReady, "")
+	if cluster, err := backup.GetCluster(); err == nil {
+		logclient.LogWithStartable(t, cluster, logclient.ActionClusterBackup, backup.GetName(), t.UserCred, true)
+		if err := models.ClusterBackupManager.PurgeExpiredBackups(ctx, t.UserCred, cluster); err != nil {
+			log.Errorf("purge cluster %s expired backups: %v", cluster.GetName(), err)
+		}
+	}
+	t.SetStageComplete(ctx, nil)
+}
+
+func (t *ClusterBackupCreateTask) OnBackupCompleteFailed(ctx context.Context, backup *models.SClusterBackup, reason jsonutils.JSONObject) {
+	if cluster, err := backup.GetCluster(); err == nil {
+		logclient.LogWithStartable(t, cluster, logclient.ActionClusterBackup, reason, t.UserCred, false)
+	}
+	SetObjectTaskFailed(ctx, t, backup, api.ClusterBackupStatusCreateFail, reason.String())
+}
+
+type ClusterBackupDeleteTask struct {
+	taskman.STask
+}
+
+func (t *ClusterBackupDeleteTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	backup := obj.(*models.SClusterBackup)
+	if err := backup.RemoveObject(ctx, t.UserCred); err != nil {
+		SetObjectTaskFailed(ctx, t, backup, api.ClusterBackupStatusDeleteFail, err.Error())
+		return
+	}
+	if err := backup.RealDelete(ctx, t.UserCred); err != nil {
+		SetObjectTaskFailed(ctx, t, backup, api.ClusterBackupStatusDeleteFail, err.Error())
+		return
+	}
+	t.SetStageComplete(ctx, nil)
+}
+
+// ClusterBackupRestoreTask restores cluster etcd from backup, then resyncs all cluster resources
+type ClusterBackupRestoreTask struct {
+	taskman.STask
+}
+
+func (t *ClusterBackupRestoreTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	backup := obj.(*models.SClusterBackup)
+	cluster, err := backup.GetCluster()
+	if err != nil {
+		SetObjectTaskFailed(ctx, t, backup, api.ClusterBackupStatusRestoreFail, errors.Wrap(err, "get cluster").Error())
+		return
+	}
+	t.SetStage("OnRestoreComplete", nil)
+	taskman.LocalTaskRun(t, func() (jsonutils.JSONObject, error) {
+		return nil, backup.DoRestore(ctx, t.UserCred, cluster)
+	})
+}
+
+func (t *ClusterBackupRestoreTask) OnRestoreComplete(ctx context.Context, backup *models.SClusterBackup, data jsonutils.JSONObject) {
+	backup.SetStatus(ctx, t.UserCred, api.ClusterBackupStatusReady, "")
+	cluster, err := backup.GetCluster()
+	if err != nil {
+		t.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+		return
+	}
+	cluster.SetStatus(ctx, t.UserCred, api.ClusterStatusRunning, "")
+	logclient.LogWithStartable(t, cluster, logclient.ActionClusterRestore, backup.GetName(), t.UserCred, true)
+	// records of resources not in restored etcd are removed by sync
+	if err := cluster.StartSyncTask(ctx, t.UserCred, nil, ""); err != nil {
+		log.Errorf("start cluster %s sync task after restore: %v", cluster.GetName(), err)
+	}
+	t.SetStageComplete(ctx, nil)
+}
+
+func (t *ClusterBackupRestoreTask) OnRestoreCompleteFailed(ctx context.Context, backup *models.SClusterBackup, reason jsonutils.JSONObject) {
+	if cluster, err := backup.GetCluster(); err == nil {
+		cluster.SetStatus(ctx, t.UserCred, api.ClusterStatusRestoreFail, reason.String())
+		logclient.LogWithStartable(t, cluster, logclient.ActionClusterRestore, reason, t.UserCred, false)
+	}
+	SetObjectTaskFailed(ctx, t, backup, api.ClusterBackupStatusRestoreFail, reason.String())
+}
@@ -6,6 +6,7 @@ import (
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/pkg/errors"
 
 	"yunion.io/x/kubecomps/pkg/kubeserver/api"
 	"yunion.io/x/kubecomps/pkg/kubeserver/models"
@@ -53,18 +54,45 @@ func (t *ClusterDeleteMachinesTask) OnInit(ctx context.Context, obj db.IStandalo
 		return
 	}
 
+	if t.IsFromClusterDeleteTask(cluster) {
+		t.OnClusterNodeRemoved(ctx, cluster, data)
+		return
+	}
+
+	input := new(api.ClusterDeleteMachinesInput)
+	t.GetParams().Unmarshal(input)
+	if !input.Drain {
+		t.OnDrainNodes(ctx, cluster, data)
+		return
+	}
+	t.SetStage("OnDrainNodes", nil)
+	taskman.LocalTaskRun(t, func() (jsonutils.JSONObject, error) {
+		rets, err := cluster.DrainMachinesNodes(ctx, t.GetUserCred(), ms, input.DrainOptions, func(percent float32) {
+			t.SetProgress(percent)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "drain machines nodes")
+		}
+		return jsonutils.Marshal(map[string]interface{}{"drain_results": rets}), nil
+	})
+}
+
+func (t *ClusterDeleteMachinesTask) OnDrainNodes(ctx context.Context, cluster *models.SCluster, data jsonutils.JSONObject) {
+	ms, err := t.getDeleteMachines(ctx)
+	if err != nil {
+		t.OnError(ctx, cluster, err.Error())
+		return
+	}
 	mIds := make([]string, 0)
 	for _, m := range ms {
 		mIds = append(mIds, m.GetId())
 	}
+	t.SetStage("OnClusterNodeRemoved", nil)
+	cluster.StartDeployMachinesTask(ctx, t.GetUserCred(), api.ClusterDeployActionRemoveNode, mIds, t.GetTaskId(), false)
+}
 
-	if t.IsFromClusterDeleteTask(cluster) {
-		t.OnClusterNodeRemoved(ctx, cluster, data)
-		return
-	} else {
-		t.SetStage("OnClusterNodeRemoved", nil)
-		cluster.StartDeployMachinesTask(ctx, t.GetUserCred(), api.ClusterDeployActionRemoveNode, mIds, t.GetTaskId(), false)
-	}
+func (t *ClusterDeleteMachinesTask) OnDrainNodesFailed(ctx context.Context, cluster *models.SCluster, data jsonutils.JSONObject) {
+	t.OnError(ctx, cluster, data.String())
 }
 
 func (t *ClusterDeleteMachinesTask) OnClusterNodeRemoved(ctx context.Context, cluster *models.SCluster, data jsonutils.JSONObject) {
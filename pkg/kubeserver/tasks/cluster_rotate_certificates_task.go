@@ -0,0 +1,61 @@
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+	"yunion.io/x/kubecomps/pkg/utils/logclient"
+)
+
+func init() {
+	taskman.RegisterTask(ClusterRotateCertificatesTask{})
+}
+
+// ClusterRotateCertificatesTask renews control plane certificates, then regenerates stored kubeconfig
+type ClusterRotateCertificatesTask struct {
+	taskman.STask
+}
+
+func (t *ClusterRotateCertificatesTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	cluster := obj.(*models.SCluster)
+	t.SetStage("OnRotateComplete", nil)
+	taskman.LocalTaskRun(t, func() (jsonutils.JSONObject, error) {
+		if err := cluster.GetDriver().RequestRotateCertificates(ctx, t.UserCred, cluster); err != nil {
+			return nil, errors.Wrap(err, "rotate certificates")
+		}
+		if err := cluster.RegenerateKubeconfig(); err != nil {
+			return nil, errors.Wrap(err, "regenerate kubeconfig")
+		}
+		return nil, nil
+	})
+}
+
+func (t *ClusterRotateCertificatesTask) OnRotateComplete(ctx context.Context, cluster *models.SCluster, data jsonutils.JSONObject) {
+	// expiry state is refreshed so the next check raises event again if still expiring
+	if err := cluster.RemoveMetadata(ctx, api.ClusterMetadataCertificateExpiry, t.UserCred); err != nil {
+		log.Errorf("remove cluster %s certificate expiry state: %v", cluster.GetName(), err)
+	}
+	logclient.LogWithStartable(t, cluster, logclient.ActionClusterRotateCerts, nil, t.UserCred, true)
+	t.SetStage("OnSyncStatusComplete", nil)
+	cluster.StartSyncStatus(ctx, t.UserCred, t.GetTaskId())
+}
+
+func (t *ClusterRotateCertificatesTask) OnRotateCompleteFailed(ctx context.Context, cluster *models.SCluster, reason jsonutils.JSONObject) {
+	logclient.LogWithStartable(t, cluster, logclient.ActionClusterRotateCerts, reason, t.UserCred, false)
+	SetObjectTaskFailed(ctx, t, cluster, api.ClusterStatusRotateCertsFail, reason.String())
+}
+
+func (t *ClusterRotateCertificatesTask) OnSyncStatusComplete(ctx context.Context, cluster *models.SCluster, data jsonutils.JSONObject) {
+	t.SetStageComplete(ctx, nil)
+}
+
+func (t *ClusterRotateCertificatesTask) OnSyncStatusCompleteFailed(ctx context.Context, cluster *models.SCluster, data jsonutils.JSONObject) {
+	t.SetStageFailed(ctx, data)
+}
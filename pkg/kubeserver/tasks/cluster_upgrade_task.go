@@ -0,0 +1,130 @@
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models/manager"
+	"yunion.io/x/kubecomps/pkg/utils/logclient"
+)
+
+func init() {
+	taskman.RegisterTask(ClusterUpgradeTask{})
+}
+
+// ClusterUpgradeTask upgrades control plane machines first, then worker machines one by one,
+// progress is saved to cluster upgrade state so a failed upgrade can be resumed
+type ClusterUpgradeTask struct {
+	taskman.STask
+}
+
+func (t *ClusterUpgradeTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	cluster := obj.(*models.SCluster)
+	input := new(api.ClusterUpgradeInput)
+	if err := t.GetParams().Unmarshal(input); err != nil {
+		t.OnUpgradeCompleteFailed(ctx, cluster, jsonutils.NewString(err.Error()))
+		return
+	}
+	state, err := cluster.GetUpgradeState(ctx, t.UserCred)
+	if err != nil || state == nil {
+		if err == nil {
+			err = errors.Error("upgrade state not found")
+		}
+		t.OnUpgradeCompleteFailed(ctx, cluster, jsonutils.NewString(err.Error()))
+		return
+	}
+	t.SetStage("OnUpgradeComplete", nil)
+	taskman.LocalTaskRun(t, func() (jsonutils.JSONObject, error) {
+		if err := t.upgrade(ctx, cluster, input, state); err != nil {
+			state.Reason = err.Error()
+			if sErr := cluster.SetUpgradeState(ctx, t.UserCred, state); sErr != nil {
+				log.Errorf("save cluster %s upgrade state: %v", cluster.GetName(), sErr)
+			}
+			return nil, err
+		}
+		return nil, nil
+	})
+}
+
+func (t *ClusterUpgradeTask) upgrade(ctx context.Context, cluster *models.SCluster, input *api.ClusterUpgradeInput, state *api.ClusterUpgradeState) error {
+	drv := cluster.GetDriver()
+	cps, workers, err := cluster.GetUpgradeMachines(state)
+	if err != nil {
+		return err
+	}
+	total := float32(len(workers) + 1)
+
+	if len(cps) != 0 {
+		// kubespray drains and upgrades control plane nodes serially
+		state.FailedMachine = api.RoleTypeControlplane
+		if err := drv.RequestUpgradeMachines(ctx, t.UserCred, cluster, state.ToVersion, cps, input.SkipDownloads); err != nil {
+			return errors.Wrap(err, "upgrade controlplane machines")
+		}
+		state.ControlplaneUpgraded = true
+		state.FailedMachine = ""
+		if err := cluster.SetUpgradeState(ctx, t.UserCred, state); err != nil {
+			return errors.Wrap(err, "save upgrade state")
+		}
+	}
+	t.SetProgress(100 / total)
+
+	for idx, m := range workers {
+		state.FailedMachine = m.GetName()
+		if err := t.upgradeWorker(ctx, cluster, input, state.ToVersion, m); err != nil {
+			return errors.Wrapf(err, "upgrade machine %s", m.GetName())
+		}
+		state.UpgradedMachines = append(state.UpgradedMachines, m.GetName())
+		state.FailedMachine = ""
+		if err := cluster.SetUpgradeState(ctx, t.UserCred, state); err != nil {
+			return errors.Wrap(err, "save upgrade state")
+		}
+		t.SetProgress(float32(idx+2) * 100 / total)
+	}
+
+	if err := cluster.SetK8sVersion(ctx, state.ToVersion); err != nil {
+		return errors.Wrap(err, "set cluster version")
+	}
+	return nil
+}
+
+func (t *ClusterUpgradeTask) upgradeWorker(ctx context.Context, cluster *models.SCluster, input *api.ClusterUpgradeInput, version string, m manager.IMachine) error {
+	if _, err := cluster.DrainMachinesNodes(ctx, t.UserCred, []manager.IMachine{m}, input.DrainOptions, nil); err != nil {
+		return errors.Wrap(err, "drain node")
+	}
+	if err := cluster.GetDriver().RequestUpgradeMachines(ctx, t.UserCred, cluster, version, []manager.IMachine{m}, input.SkipDownloads); err != nil {
+		return err
+	}
+	if err := cluster.SetMachineNodeSchedulable(t.UserCred, m); err != nil {
+		return errors.Wrap(err, "uncordon node")
+	}
+	return nil
+}
+
+func (t *ClusterUpgradeTask) OnUpgradeComplete(ctx context.Context, cluster *models.SCluster, data jsonutils.JSONObject) {
+	if err := cluster.ClearUpgradeState(ctx, t.UserCred); err != nil {
+		log.Errorf("clear cluster %s upgrade state: %v", cluster.GetName(), err)
+	}
+	logclient.LogWithStartable(t, cluster, logclient.ActionClusterUpgrade, nil, t.UserCred, true)
+	t.SetStage("OnSyncStatusComplete", nil)
+	cluster.StartSyncStatus(ctx, t.UserCred, t.GetTaskId())
+}
+
+func (t *ClusterUpgradeTask) OnUpgradeCompleteFailed(ctx context.Context, cluster *models.SCluster, reason jsonutils.JSONObject) {
+	logclient.LogWithStartable(t, cluster, logclient.ActionClusterUpgrade, reason, t.UserCred, false)
+	SetObjectTaskFailed(ctx, t, cluster, api.ClusterStatusUpgradeFail, reason.String())
+}
+
+func (t *ClusterUpgradeTask) OnSyncStatusComplete(ctx context.Context, cluster *models.SCluster, data jsonutils.JSONObject) {
+	t.SetStageComplete(ctx, nil)
+}
+
+func (t *ClusterUpgradeTask) OnSyncStatusCompleteFailed(ctx context.Context, cluster *models.SCluster, data jsonutils.JSONObject) {
+	t.SetStageFailed(ctx, data)
+}
@@ -0,0 +1,44 @@
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+	"yunion.io/x/kubecomps/pkg/utils/logclient"
+)
+
+func init() {
+	taskman.RegisterTask(ContainerImageMirrorSyncTask{})
+}
+
+type ContainerImageMirrorSyncTask struct {
+	taskman.STask
+}
+
+func (t *ContainerImageMirrorSyncTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	mirror := obj.(*models.SContainerImageMirror)
+	t.SetStage("OnSyncComplete", nil)
+	taskman.LocalTaskRun(t, func() (jsonutils.JSONObject, error) {
+		progress, err := mirror.DoSync(ctx, t.UserCred)
+		if err != nil {
+			return nil, err
+		}
+		return jsonutils.Marshal(progress), nil
+	})
+}
+
+func (t *ContainerImageMirrorSyncTask) OnSyncComplete(ctx context.Context, mirror *models.SContainerImageMirror, data jsonutils.JSONObject) {
+	mirror.SetStatus(ctx, t.UserCred, api.ContainerImageMirrorStatusReady, "")
+	logclient.LogWithStartable(t, mirror, logclient.ActionResourceSync, nil, t.UserCred, true)
+	t.SetStageComplete(ctx, nil)
+}
+
+func (t *ContainerImageMirrorSyncTask) OnSyncCompleteFailed(ctx context.Context, mirror *models.SContainerImageMirror, reason jsonutils.JSONObject) {
+	logclient.LogWithStartable(t, mirror, logclient.ActionResourceSync, reason, t.UserCred, false)
+	SetObjectTaskFailed(ctx, t, mirror, api.ContainerImageMirrorStatusSyncFail, reason.String())
+}
@@ -0,0 +1,69 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+)
+
+func init() {
+	taskman.RegisterTask(ContainerImageScanTask{})
+}
+
+type ContainerImageScanTask struct {
+	taskman.STask
+}
+
+func (t *ContainerImageScanTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	scan := obj.(*models.SContainerImageScan)
+	t.SetStage("OnScanRequested", nil)
+	taskman.LocalTaskRun(t, func() (jsonutils.JSONObject, error) {
+		return nil, scan.DoScan(ctx, t.UserCred)
+	})
+}
+
+func (t *ContainerImageScanTask) OnScanRequested(ctx context.Context, scan *models.SContainerImageScan, data jsonutils.JSONObject) {
+	t.fetchReport(ctx, scan)
+}
+
+func (t *ContainerImageScanTask) OnScanRequestedFailed(ctx context.Context, scan *models.SContainerImageScan, reason jsonutils.JSONObject) {
+	SetObjectTaskFailed(ctx, t, scan, api.ContainerImageScanStatusScanFail, reason.String())
+}
+
+func (t *ContainerImageScanTask) fetchReport(ctx context.Context, scan *models.SContainerImageScan) {
+	t.SetStage("OnReportFetched", nil)
+	taskman.LocalTaskRun(t, func() (jsonutils.JSONObject, error) {
+		ready, err := scan.FetchReport(ctx, t.UserCred)
+		if err != nil {
+			return nil, err
+		}
+		return jsonutils.Marshal(map[string]bool{"ready": ready}), nil
+	})
+}
+
+func (t *ContainerImageScanTask) OnReportFetched(ctx context.Context, scan *models.SContainerImageScan, data jsonutils.JSONObject) {
+	if ready, _ := data.Bool("ready"); ready {
+		scan.SetStatus(ctx, t.UserCred, api.ContainerImageScanStatusReady, "")
+		t.SetStageComplete(ctx, nil)
+		return
+	}
+	if time.Since(t.CreatedAt) > models.ContainerImageScanTimeout {
+		SetObjectTaskFailed(ctx, t, scan, api.ContainerImageScanStatusScanFail, fmt.Sprintf("wait scan report of %s timeout", scan.Image))
+		return
+	}
+	// don't hold task worker while scanner is working
+	time.AfterFunc(models.ContainerImageScanPollInterval, func() {
+		t.fetchReport(ctx, scan)
+	})
+}
+
+func (t *ContainerImageScanTask) OnReportFetchedFailed(ctx context.Context, scan *models.SContainerImageScan, reason jsonutils.JSONObject) {
+	SetObjectTaskFailed(ctx, t, scan, api.ContainerImageScanStatusScanFail, reason.String())
+}
@@ -65,6 +65,11 @@ func (t *FedResourceSyncTask) OnInit(ctx context.Context, obj db.IStandaloneMode
 	fedObj := obj.(models.IFedModel)
 	fedObj.SetStatus(ctx, t.GetUserCred(), api.FederatedResourceStatusSyncing, "start syncing")
 	taskman.LocalTaskRun(t, func() (jsonutils.JSONObject, error) {
+		if objModel, ok := fedObj.(models.IFedObjectModel); ok {
+			if err := fedApi.NamespaceScope().ReconcilePlacement(objModel, ctx, t.GetUserCred()); err != nil {
+				return nil, errors.Wrap(err, "reconcile placement")
+			}
+		}
 		clusters, err := fedApi.GetAttachedClusters(fedObj)
 		if err != nil {
 			return nil, errors.Wrap(err, "get attached clusters")
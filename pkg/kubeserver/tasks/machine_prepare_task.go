@@ -56,7 +56,7 @@ func (t *MachinePrepareTask) doPrepare(ctx context.Context, obj db.IStandaloneMo
 	}
 
 	log.Infof("Start prepare resource, data: %s", jsonutils.Marshal(prepareData))
-	if _, err := driver.PrepareResource(session, machine, prepareData); err != nil {
+	if _, err := driver.PrepareResource(ctx, session, machine, prepareData); err != nil {
 		return errors.Wrap(err, "prepare resource")
 	}
 
@@ -0,0 +1,59 @@
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/kubeserver/api"
+	"yunion.io/x/kubecomps/pkg/kubeserver/models"
+	"yunion.io/x/kubecomps/pkg/utils/logclient"
+)
+
+func init() {
+	taskman.RegisterTask(NodeDrainTask{})
+}
+
+type NodeDrainTask struct {
+	taskman.STask
+}
+
+func (t *NodeDrainTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	node := obj.(*models.SNode)
+	input := new(api.NodeDrainInput)
+	if err := t.GetParams().Unmarshal(input); err != nil {
+		t.OnDrainCompleteFailed(ctx, node, jsonutils.NewString(err.Error()))
+		return
+	}
+	t.SetStage("OnDrainComplete", nil)
+	taskman.LocalTaskRun(t, func() (jsonutils.JSONObject, error) {
+		ret, err := node.Drain(ctx, input, func(done, total int) {
+			t.SetProgress(float32(done) * 100 / float32(total))
+			log.Infof("drain node %s: %d/%d pods removed", node.GetName(), done, total)
+		})
+		if err != nil {
+			if ret != nil {
+				err = errors.Wrapf(err, "evicted %d pods", len(ret.Evicted))
+			}
+			return nil, errors.Wrapf(err, "drain node %s", node.GetName())
+		}
+		return jsonutils.Marshal(ret), nil
+	})
+}
+
+func (t *NodeDrainTask) OnDrainComplete(ctx context.Context, node *models.SNode, data jsonutils.JSONObject) {
+	if err := node.SyncStatusFromRemote(ctx, t.UserCred); err != nil {
+		log.Errorf("sync node %s status: %v", node.GetName(), err)
+	}
+	logclient.LogWithStartable(t, node, logclient.ActionNodeDrain, data, t.UserCred, true)
+	t.SetStageComplete(ctx, data.(*jsonutils.JSONDict))
+}
+
+func (t *NodeDrainTask) OnDrainCompleteFailed(ctx context.Context, node *models.SNode, reason jsonutils.JSONObject) {
+	logclient.LogWithStartable(t, node, logclient.ActionNodeDrain, reason, t.UserCred, false)
+	SetObjectTaskFailed(ctx, t, node, api.NodeStatusDrainFail, reason.String())
+}
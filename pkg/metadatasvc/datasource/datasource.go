@@ -3,25 +3,48 @@ package datasource
 import (
 	"context"
 	"net"
+	"sort"
 	"time"
 
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 
 	"yunion.io/x/kubecomps/pkg/metadatasvc/metadata"
-	// "yunion.io/x/kubecomps/pkg/metadatasvc/userdata"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/userdata"
 )
 
 const (
 	ErrDatasourceRetrievalTimeout = errors.Error("datasource: timeout during data-source retrieval")
+	ErrDatasourceNotFound         = errors.Error("datasource: no available data-source")
 )
 
 const (
-	DatasourceTypeEC2 = "ec2"
+	DatasourceTypeEC2         = "ec2"
+	DatasourceTypeOpenStack   = "openstack"
+	DatasourceTypeConfigDrive = "configdrive"
+	DatasourceTypeAzure       = "azure"
+	DatasourceTypeGCE         = "gce"
+	DatasourceTypeAliyun      = "aliyun"
+	DatasourceTypeTencent     = "tencent"
+	DatasourceTypeNoCloud     = "nocloud"
 )
 
 var (
 	providers map[DatasourceType]Provider = make(map[DatasourceType]Provider)
+
+	// providerPriority is the order available providers are preferred, disks attached to
+	// instance come first and openstack is before ec2 because nova serves ec2 compatible
+	// meta-data too, providers not listed are checked at last
+	providerPriority = []DatasourceType{
+		DatasourceTypeConfigDrive,
+		DatasourceTypeNoCloud,
+		DatasourceTypeAzure,
+		DatasourceTypeGCE,
+		DatasourceTypeAliyun,
+		DatasourceTypeTencent,
+		DatasourceTypeOpenStack,
+		DatasourceTypeEC2,
+	}
 )
 
 type DatasourceType string
@@ -32,7 +55,8 @@ type Provider interface {
 	FetchLocalIPv4(ctx context.Context) (net.IP, error)
 	FetchPublicIPv4(ctx context.Context) (net.IP, error)
 	FetchMetadata(ctx context.Context) (*metadata.Digest, error)
-	// FetchUserdata() (userdata.Map, error)
+	// FetchUserdata returns empty map when instance has no user-data
+	FetchUserdata(ctx context.Context) (userdata.Map, error)
 }
 
 func RegisterProvider(p Provider) {
@@ -47,28 +71,57 @@ func GetProviders() map[DatasourceType]Provider {
 	return providers
 }
 
-// FindProvider checks the given datasource providers, if it finds an available
-// data source before the specified duration, it returns the provider, else it
-// returns an ErrDatasourceRetrievalTimeout error
-func FindProvider(providers map[DatasourceType]Provider, timeout time.Duration) (Provider, error) {
-	providerCh := make(chan Provider)
-
-	for _, provider := range providers {
-		go func(p Provider) {
-			if isAvailable(context.Background(), p) {
-				providerCh <- p
+// sortProviders returns providers in order of providerPriority
+func sortProviders(providers map[DatasourceType]Provider) []Provider {
+	rank := func(typ DatasourceType) int {
+		for i, t := range providerPriority {
+			if t == typ {
+				return i
 			}
-		}(provider)
+		}
+		return len(providerPriority)
+	}
+	ret := make([]Provider, 0, len(providers))
+	for _, p := range providers {
+		ret = append(ret, p)
 	}
+	sort.Slice(ret, func(i, j int) bool {
+		ri, rj := rank(ret[i].GetType()), rank(ret[j].GetType())
+		if ri != rj {
+			return ri < rj
+		}
+		return ret[i].GetType() < ret[j].GetType()
+	})
+	return ret
+}
 
-	timeoutChan := time.NewTicker(timeout).C
+// FindProvider checks the given datasource providers concurrently and returns the
+// available one of highest priority once all providers before it are unavailable,
+// ErrDatasourceRetrievalTimeout is returned when it can't be decided within timeout
+func FindProvider(providers map[DatasourceType]Provider, timeout time.Duration) (Provider, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	select {
-	case p := <-providerCh:
-		return p, nil
-	case <-timeoutChan:
-		return nil, ErrDatasourceRetrievalTimeout
+	ordered := sortProviders(providers)
+	results := make([]chan bool, len(ordered))
+	for i, provider := range ordered {
+		results[i] = make(chan bool, 1)
+		go func(p Provider, ch chan<- bool) {
+			ch <- isAvailable(ctx, p)
+		}(provider, results[i])
+	}
+
+	for i, ch := range results {
+		select {
+		case ok := <-ch:
+			if ok {
+				return ordered[i], nil
+			}
+		case <-ctx.Done():
+			return nil, ErrDatasourceRetrievalTimeout
+		}
 	}
+	return nil, ErrDatasourceNotFound
 }
 
 func isAvailable(ctx context.Context, p Provider) bool {
@@ -0,0 +1,79 @@
+package datasource
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/metadatasvc/metadata"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/userdata"
+)
+
+type fakeProvider struct {
+	typ       DatasourceType
+	delay     time.Duration
+	available bool
+}
+
+func (p *fakeProvider) GetType() DatasourceType { return p.typ }
+
+func (p *fakeProvider) FetchHostname(ctx context.Context) (string, error) { return "", nil }
+
+func (p *fakeProvider) FetchLocalIPv4(ctx context.Context) (net.IP, error) { return nil, nil }
+
+func (p *fakeProvider) FetchPublicIPv4(ctx context.Context) (net.IP, error) { return nil, nil }
+
+func (p *fakeProvider) FetchMetadata(ctx context.Context) (*metadata.Digest, error) {
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if !p.available {
+		return nil, errors.Error("unavailable")
+	}
+	return new(metadata.Digest), nil
+}
+
+func (p *fakeProvider) FetchUserdata(ctx context.Context) (userdata.Map, error) { return nil, nil }
+
+func TestFindProvider(t *testing.T) {
+	newProviders := func(ps ...*fakeProvider) map[DatasourceType]Provider {
+		ret := make(map[DatasourceType]Provider)
+		for _, p := range ps {
+			ret[p.typ] = p
+		}
+		return ret
+	}
+
+	// openstack also serves ec2 meta-data, it's preferred even if ec2 responds first
+	p, err := FindProvider(newProviders(
+		&fakeProvider{typ: DatasourceTypeEC2, available: true},
+		&fakeProvider{typ: DatasourceTypeOpenStack, delay: 50 * time.Millisecond, available: true},
+		&fakeProvider{typ: DatasourceTypeConfigDrive},
+	), time.Second)
+	if err != nil || p.GetType() != DatasourceTypeOpenStack {
+		t.Errorf("FindProvider() = %v, %v, want openstack", p, err)
+	}
+
+	p, err = FindProvider(newProviders(
+		&fakeProvider{typ: DatasourceTypeEC2, available: true},
+		&fakeProvider{typ: DatasourceTypeOpenStack},
+	), time.Second)
+	if err != nil || p.GetType() != DatasourceTypeEC2 {
+		t.Errorf("FindProvider() = %v, %v, want ec2", p, err)
+	}
+
+	if _, err := FindProvider(newProviders(&fakeProvider{typ: DatasourceTypeEC2}), time.Second); err != ErrDatasourceNotFound {
+		t.Errorf("FindProvider() error = %v, want %v", err, ErrDatasourceNotFound)
+	}
+	if _, err := FindProvider(newProviders(
+		&fakeProvider{typ: DatasourceTypeOpenStack, delay: time.Second, available: true},
+		&fakeProvider{typ: DatasourceTypeEC2, available: true},
+	), 50*time.Millisecond); err != ErrDatasourceRetrievalTimeout {
+		t.Errorf("FindProvider() error = %v, want %v", err, ErrDatasourceRetrievalTimeout)
+	}
+}
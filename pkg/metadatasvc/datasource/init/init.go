@@ -1,5 +1,12 @@
 package init
 
 import (
+	_ "yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider/aliyun"
+	_ "yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider/azure"
+	_ "yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider/configdrive"
 	_ "yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider/ec2"
+	_ "yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider/gce"
+	_ "yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider/nocloud"
+	_ "yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider/openstack"
+	_ "yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider/tencent"
 )
@@ -0,0 +1,141 @@
+package aliyun
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/metadata"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/userdata"
+)
+
+const (
+	MetadataURL = "http://100.100.100.200/latest/"
+)
+
+func init() {
+	datasource.RegisterProvider(&metadataService{
+		URL: MetadataURL,
+	})
+}
+
+// metadataService is aliyun ecs instance meta-data service
+type metadataService struct {
+	URL string
+}
+
+func (s *metadataService) fetcher() provider.Fetcher {
+	return &provider.HTTPFetcher{BaseURL: s.URL}
+}
+
+func (s *metadataService) fetchMetadataTextAttr(ctx context.Context, attr string) (string, error) {
+	return provider.FetchText(ctx, s.fetcher(), "meta-data/"+attr)
+}
+
+func (s *metadataService) GetType() datasource.DatasourceType {
+	return datasource.DatasourceTypeAliyun
+}
+
+func (s *metadataService) FetchHostname(ctx context.Context) (string, error) {
+	return s.fetchMetadataTextAttr(ctx, "hostname")
+}
+
+func (s *metadataService) FetchLocalIPv4(ctx context.Context) (net.IP, error) {
+	ret, err := s.fetchMetadataTextAttr(ctx, "private-ipv4")
+	if err != nil {
+		return net.IP{}, err
+	}
+	return net.ParseIP(ret), nil
+}
+
+// FetchPublicIPv4 returns elastic ip if bound, or the public ip of instance
+func (s *metadataService) FetchPublicIPv4(ctx context.Context) (net.IP, error) {
+	for _, attr := range []string{"eipv4", "public-ipv4"} {
+		ret, err := s.fetchMetadataTextAttr(ctx, attr)
+		if err == nil {
+			return net.ParseIP(ret), nil
+		}
+		if errors.Cause(err) != provider.ErrNotFound {
+			return net.IP{}, err
+		}
+	}
+	return nil, nil
+}
+
+func (s *metadataService) FetchNetworkInterfaces(ctx context.Context) ([]metadata.NetworkInterface, error) {
+	out, err := s.fetchMetadataTextAttr(ctx, "network/interfaces/macs/")
+	if err != nil {
+		return nil, errors.Wrap(err, "list macs")
+	}
+	ret := make([]metadata.NetworkInterface, 0)
+	for _, macStr := range provider.SplitLines(out) {
+		mac, err := provider.ParseMAC(macStr)
+		if err != nil {
+			log.Warningf("invalid mac %q: %v", macStr, err)
+			continue
+		}
+		prefix := "network/interfaces/macs/" + macStr + "/"
+		primary, err := s.fetchMetadataTextAttr(ctx, prefix+"primary-ip-address")
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetch primary ip of %s", macStr)
+		}
+		nic := metadata.NetworkInterface{
+			Mac:       mac,
+			PrimaryIP: net.ParseIP(primary),
+		}
+		// private-ipv4s is json array, e.g. ["172.16.0.1","172.16.0.2"]
+		privates, err := s.fetchMetadataTextAttr(ctx, prefix+"private-ipv4s")
+		if err != nil && errors.Cause(err) != provider.ErrNotFound {
+			return nil, errors.Wrapf(err, "fetch private ips of %s", macStr)
+		}
+		if privates != "" {
+			ips := make([]string, 0)
+			if err := json.Unmarshal([]byte(privates), &ips); err != nil {
+				return nil, errors.Wrapf(err, "unmarshal private ips %q", privates)
+			}
+			nic.PrivateIPs = provider.ParseIPs(ips...)
+		} else if nic.PrimaryIP != nil {
+			nic.PrivateIPs = []net.IP{nic.PrimaryIP}
+		}
+		ret = append(ret, nic)
+	}
+	return ret, nil
+}
+
+func (s *metadataService) FetchMetadata(ctx context.Context) (*metadata.Digest, error) {
+	hostname, err := s.FetchHostname(ctx)
+	if err != nil {
+		return nil, err
+	}
+	localIPv4, err := s.FetchLocalIPv4(ctx)
+	if err != nil {
+		return nil, err
+	}
+	publicIPv4, err := s.FetchPublicIPv4(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nics, err := s.FetchNetworkInterfaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &metadata.Digest{
+		Hostname:          hostname,
+		LocalIPv4:         localIPv4,
+		PublicIPv4:        publicIPv4,
+		NetworkInterfaces: nics,
+	}, nil
+}
+
+func (s *metadataService) FetchUserdata(ctx context.Context) (userdata.Map, error) {
+	data, err := provider.FetchOptional(ctx, s.fetcher(), "user-data")
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch user-data")
+	}
+	return userdata.Parse(data)
+}
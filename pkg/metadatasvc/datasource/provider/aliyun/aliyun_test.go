@@ -0,0 +1,50 @@
+package aliyun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetadataService(t *testing.T) {
+	attrs := map[string]string{
+		"/latest/meta-data/hostname":                 "iZbp1abc",
+		"/latest/meta-data/private-ipv4":             "172.16.0.10",
+		"/latest/meta-data/eipv4":                    "47.1.2.3",
+		"/latest/meta-data/network/interfaces/macs/": "00:16:3e:00:00:01/",
+		"/latest/meta-data/network/interfaces/macs/00:16:3e:00:00:01/primary-ip-address": "172.16.0.10",
+		"/latest/meta-data/network/interfaces/macs/00:16:3e:00:00:01/private-ipv4s":      `["172.16.0.10","172.16.0.11"]`,
+		"/latest/user-data": "#cloud-config\nhostname: foo\n",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		val, ok := attrs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(val))
+	}))
+	defer srv.Close()
+
+	s := &metadataService{URL: srv.URL + "/latest/"}
+	ctx := context.Background()
+	digest, err := s.FetchMetadata(ctx)
+	if err != nil {
+		t.Fatalf("FetchMetadata: %v", err)
+	}
+	if digest.Hostname != "iZbp1abc" || digest.LocalIPv4.String() != "172.16.0.10" || digest.PublicIPv4.String() != "47.1.2.3" {
+		t.Errorf("unexpected digest %s", digest)
+	}
+	if len(digest.NetworkInterfaces) != 1 || len(digest.NetworkInterfaces[0].PrivateIPs) != 2 {
+		t.Fatalf("unexpected interfaces %s", digest)
+	}
+
+	data, err := s.FetchUserdata(ctx)
+	if err != nil {
+		t.Fatalf("FetchUserdata: %v", err)
+	}
+	if len(data.CloudConfigs()) != 1 {
+		t.Errorf("want 1 cloud-config, got %v", data)
+	}
+}
@@ -0,0 +1,180 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/metadata"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/userdata"
+)
+
+const (
+	MetadataURL = "http://169.254.169.254/metadata/"
+
+	instancePath = "instance?api-version=2021-02-01"
+	userdataPath = "instance/compute/userData?api-version=2021-01-01&format=text"
+)
+
+func init() {
+	datasource.RegisterProvider(&metadataService{
+		URL: MetadataURL,
+	})
+}
+
+type ipAddress struct {
+	PrivateIpAddress string `json:"privateIpAddress"`
+	PublicIpAddress  string `json:"publicIpAddress"`
+}
+
+type networkInterface struct {
+	// MacAddress is hex digits without separator, e.g. 000D3AF806EC
+	MacAddress string `json:"macAddress"`
+	IPv4       struct {
+		IPAddress []ipAddress `json:"ipAddress"`
+	} `json:"ipv4"`
+}
+
+// instance is part of azure instance metadata service response
+type instance struct {
+	Compute struct {
+		Name      string `json:"name"`
+		OsProfile struct {
+			ComputerName string `json:"computerName"`
+		} `json:"osProfile"`
+	} `json:"compute"`
+	Network struct {
+		Interface []networkInterface `json:"interface"`
+	} `json:"network"`
+}
+
+// metadataService is azure instance metadata service (IMDS)
+type metadataService struct {
+	URL string
+}
+
+func (s *metadataService) fetcher() provider.Fetcher {
+	return &provider.HTTPFetcher{
+		BaseURL: s.URL,
+		Header:  http.Header{"Metadata": []string{"true"}},
+	}
+}
+
+func (s *metadataService) fetchInstance(ctx context.Context) (*instance, error) {
+	data, err := s.fetcher().Fetch(ctx, instancePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch instance metadata")
+	}
+	ins := new(instance)
+	if err := json.Unmarshal(data, ins); err != nil {
+		return nil, errors.Wrap(err, "unmarshal instance metadata")
+	}
+	return ins, nil
+}
+
+func (ins *instance) hostname() string {
+	if ins.Compute.OsProfile.ComputerName != "" {
+		return ins.Compute.OsProfile.ComputerName
+	}
+	return ins.Compute.Name
+}
+
+func (ins *instance) networkInterfaces() []metadata.NetworkInterface {
+	ret := make([]metadata.NetworkInterface, 0)
+	for _, iface := range ins.Network.Interface {
+		mac, err := provider.ParseMAC(iface.MacAddress)
+		if err != nil {
+			log.Warningf("invalid mac %q: %v", iface.MacAddress, err)
+			continue
+		}
+		nic := metadata.NetworkInterface{
+			Mac: mac,
+		}
+		for _, addr := range iface.IPv4.IPAddress {
+			nic.PrivateIPs = append(nic.PrivateIPs, provider.ParseIPs(addr.PrivateIpAddress)...)
+			nic.PublicIPs = append(nic.PublicIPs, provider.ParseIPs(addr.PublicIpAddress)...)
+		}
+		if len(nic.PrivateIPs) > 0 {
+			nic.PrimaryIP = nic.PrivateIPs[0]
+		}
+		ret = append(ret, nic)
+	}
+	return ret
+}
+
+func firstIP(nics []metadata.NetworkInterface, public bool) net.IP {
+	for _, nic := range nics {
+		ips := nic.PrivateIPs
+		if public {
+			ips = nic.PublicIPs
+		}
+		if len(ips) > 0 {
+			return ips[0]
+		}
+	}
+	return nil
+}
+
+func (s *metadataService) GetType() datasource.DatasourceType {
+	return datasource.DatasourceTypeAzure
+}
+
+func (s *metadataService) FetchHostname(ctx context.Context) (string, error) {
+	ins, err := s.fetchInstance(ctx)
+	if err != nil {
+		return "", err
+	}
+	return ins.hostname(), nil
+}
+
+func (s *metadataService) FetchLocalIPv4(ctx context.Context) (net.IP, error) {
+	ins, err := s.fetchInstance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return firstIP(ins.networkInterfaces(), false), nil
+}
+
+// FetchPublicIPv4 returns public ip of basic sku, standard sku public ip isn't given by IMDS
+func (s *metadataService) FetchPublicIPv4(ctx context.Context) (net.IP, error) {
+	ins, err := s.fetchInstance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return firstIP(ins.networkInterfaces(), true), nil
+}
+
+func (s *metadataService) FetchMetadata(ctx context.Context) (*metadata.Digest, error) {
+	ins, err := s.fetchInstance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nics := ins.networkInterfaces()
+	return &metadata.Digest{
+		Hostname:          ins.hostname(),
+		LocalIPv4:         firstIP(nics, false),
+		PublicIPv4:        firstIP(nics, true),
+		NetworkInterfaces: nics,
+	}, nil
+}
+
+// FetchUserdata returns decoded user data, which is base64 encoded by IMDS
+func (s *metadataService) FetchUserdata(ctx context.Context) (userdata.Map, error) {
+	data, err := provider.FetchOptional(ctx, s.fetcher(), userdataPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch user data")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, errors.Wrap(err, "decode user data")
+	}
+	return userdata.Parse(decoded)
+}
@@ -0,0 +1,58 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const fakeInstance = `{
+  "compute": {"name": "vm-1", "osProfile": {"computerName": "vm-host-1"}},
+  "network": {
+    "interface": [{
+      "macAddress": "000D3AF806EC",
+      "ipv4": {"ipAddress": [{"privateIpAddress": "10.1.0.4", "publicIpAddress": "20.1.2.3"}]}
+    }]
+  }
+}`
+
+func TestMetadataService(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/metadata/instance":
+			w.Write([]byte(fakeInstance))
+		case "/metadata/instance/compute/userData":
+			w.Write([]byte(base64.StdEncoding.EncodeToString([]byte("#cloud-config\npackages: [git]\n"))))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	s := &metadataService{URL: srv.URL + "/metadata/"}
+	ctx := context.Background()
+	digest, err := s.FetchMetadata(ctx)
+	if err != nil {
+		t.Fatalf("FetchMetadata: %v", err)
+	}
+	if digest.Hostname != "vm-host-1" || digest.LocalIPv4.String() != "10.1.0.4" || digest.PublicIPv4.String() != "20.1.2.3" {
+		t.Errorf("unexpected digest %s", digest)
+	}
+	if len(digest.NetworkInterfaces) != 1 || digest.NetworkInterfaces[0].Mac.String() != "00:0d:3a:f8:06:ec" {
+		t.Errorf("unexpected interfaces %s", digest)
+	}
+
+	data, err := s.FetchUserdata(ctx)
+	if err != nil {
+		t.Fatalf("FetchUserdata: %v", err)
+	}
+	if len(data.CloudConfigs()) != 1 {
+		t.Errorf("want 1 cloud-config, got %v", data)
+	}
+}
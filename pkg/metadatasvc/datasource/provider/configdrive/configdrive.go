@@ -0,0 +1,99 @@
+package configdrive
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider/openstack"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/metadata"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/userdata"
+)
+
+func init() {
+	datasource.RegisterProvider(&configDrive{
+		disk: provider.NewDiskFetcher(provider.NewLabelMounter("config-2", "CONFIG-2")),
+	})
+}
+
+// configDrive is openstack config drive, files are in the same layout of
+// openstack meta-data service under openstack/latest directory
+type configDrive struct {
+	disk provider.Fetcher
+}
+
+type latestFetcher struct {
+	disk provider.Fetcher
+}
+
+func (f latestFetcher) Fetch(ctx context.Context, path string) ([]byte, error) {
+	data, err := f.disk.Fetch(ctx, filepath.Join("openstack", "latest", path))
+	if err != nil && errors.Cause(err) != provider.ErrNotFound {
+		return nil, errors.Wrap(err, "read config drive")
+	}
+	return data, err
+}
+
+// withFetcher calls fn with fetcher of openstack/latest directory
+func (d *configDrive) withFetcher(fn func(f provider.Fetcher) error) error {
+	return fn(latestFetcher{disk: d.disk})
+}
+
+func (d *configDrive) GetType() datasource.DatasourceType {
+	return datasource.DatasourceTypeConfigDrive
+}
+
+func (d *configDrive) FetchHostname(ctx context.Context) (string, error) {
+	var hostname string
+	err := d.withFetcher(func(f provider.Fetcher) error {
+		md, err := openstack.FetchMetaData(ctx, f)
+		if err != nil {
+			return err
+		}
+		hostname = md.GetHostname()
+		return nil
+	})
+	return hostname, err
+}
+
+func (d *configDrive) FetchLocalIPv4(ctx context.Context) (net.IP, error) {
+	var ip net.IP
+	err := d.withFetcher(func(f provider.Fetcher) error {
+		nics, err := openstack.FetchNetworkInterfaces(ctx, f)
+		if err != nil {
+			return err
+		}
+		ip = openstack.FirstIPv4(nics)
+		return nil
+	})
+	return ip, err
+}
+
+// FetchPublicIPv4 always returns nil, floating ip isn't written to config drive
+func (d *configDrive) FetchPublicIPv4(ctx context.Context) (net.IP, error) {
+	return nil, nil
+}
+
+func (d *configDrive) FetchMetadata(ctx context.Context) (*metadata.Digest, error) {
+	var digest *metadata.Digest
+	err := d.withFetcher(func(f provider.Fetcher) error {
+		var err error
+		digest, err = openstack.FetchMetadata(ctx, f)
+		return err
+	})
+	return digest, err
+}
+
+func (d *configDrive) FetchUserdata(ctx context.Context) (userdata.Map, error) {
+	var ret userdata.Map
+	err := d.withFetcher(func(f provider.Fetcher) error {
+		var err error
+		ret, err = openstack.FetchUserdata(ctx, f)
+		return err
+	})
+	return ret, err
+}
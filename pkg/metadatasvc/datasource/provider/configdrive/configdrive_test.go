@@ -0,0 +1,61 @@
+package configdrive
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider"
+)
+
+func TestConfigDrive(t *testing.T) {
+	root, err := ioutil.TempDir("", "configdrive-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "openstack", "latest")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"meta_data.json":    `{"name":"vm-1"}`,
+		"network_data.json": `{"links":[{"id":"tap1","ethernet_mac_address":"fa:16:3e:00:00:01"}],"networks":[{"link":"tap1","type":"ipv4","ip_address":"192.168.0.5"}]}`,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mounted, mountCount := 0, 0
+	d := &configDrive{
+		disk: provider.NewDiskFetcher(func() (string, func(), error) {
+			mounted++
+			mountCount++
+			return root, func() { mounted-- }, nil
+		}),
+	}
+	ctx := context.Background()
+	digest, err := d.FetchMetadata(ctx)
+	if err != nil {
+		t.Fatalf("FetchMetadata: %v", err)
+	}
+	if digest.Hostname != "vm-1" || digest.LocalIPv4.String() != "192.168.0.5" || len(digest.NetworkInterfaces) != 1 {
+		t.Errorf("unexpected digest %s", digest)
+	}
+	data, err := d.FetchUserdata(ctx)
+	if err != nil {
+		t.Fatalf("FetchUserdata: %v", err)
+	}
+	if len(data) != 0 {
+		t.Errorf("want empty user-data, got %v", data)
+	}
+	if mounted != 0 {
+		t.Errorf("config drive isn't unmounted")
+	}
+	if mountCount != 1 {
+		t.Errorf("config drive is mounted %d times, want once", mountCount)
+	}
+}
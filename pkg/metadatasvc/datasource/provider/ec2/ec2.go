@@ -2,21 +2,20 @@ package ec2
 
 import (
 	"context"
-	"io/ioutil"
 	"net"
 
-	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
-	"yunion.io/x/pkg/util/httputils"
 
 	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource"
 	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider"
 	"yunion.io/x/kubecomps/pkg/metadatasvc/metadata"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/userdata"
 )
 
 const (
 	LatestSupportedVersion                    = "2016-09-02"
-	MetadataURL            provider.FormatURL = "http://169.254.169.254/%s/%s/%s"
+	MetadataURL            provider.FormatURL = "http://169.254.169.254/%s/"
 )
 
 func init() {
@@ -29,32 +28,14 @@ type metadataService struct {
 	URL provider.FormatURL
 }
 
-func (s *metadataService) fetchMetadataTextAttr(ctx context.Context, attr string) (string, error) {
-	version := LatestSupportedVersion
-
-	url := s.URL.Fill(version, "meta-data", attr)
-	resp, err := httputils.Request(httputils.GetDefaultClient(), ctx, httputils.GET, url, nil, nil, true)
-	if err != nil {
-		return "", errors.Wrapf(err, "request url %s", url)
+func (s *metadataService) fetcher() provider.Fetcher {
+	return &provider.HTTPFetcher{
+		BaseURL: s.URL.Fill(LatestSupportedVersion),
 	}
-	buf, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	return string(buf), nil
 }
 
-func (s *metadataService) fetchMetadataJSONAttr(ctx context.Context, attr string) (jsonutils.JSONObject, error) {
-	version := LatestSupportedVersion
-
-	url := s.URL.Fill(version, "meta-data", attr)
-	_, resp, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, httputils.GET, url, nil, nil, true)
-	if err != nil {
-		return nil, errors.Wrapf(err, "request url %s", url)
-	}
-	return resp, nil
+func (s *metadataService) fetchMetadataTextAttr(ctx context.Context, attr string) (string, error) {
+	return provider.FetchText(ctx, s.fetcher(), "meta-data/"+attr)
 }
 
 func (s *metadataService) GetType() datasource.DatasourceType {
@@ -77,14 +58,54 @@ func (s *metadataService) FetchLocalIPv4(ctx context.Context) (net.IP, error) {
 	return net.ParseIP(ret), nil
 }
 
+// FetchPublicIPv4 returns nil when instance has no public ip
 func (s *metadataService) FetchPublicIPv4(ctx context.Context) (net.IP, error) {
 	ret, err := s.fetchMetadataTextAttr(ctx, "public-ipv4")
 	if err != nil {
+		if errors.Cause(err) == provider.ErrNotFound {
+			return nil, nil
+		}
 		return net.IP{}, err
 	}
 	return net.ParseIP(ret), nil
 }
 
+func (s *metadataService) FetchNetworkInterfaces(ctx context.Context) ([]metadata.NetworkInterface, error) {
+	f := s.fetcher()
+	// network attributes are absent in some ec2 compatible services
+	out, err := provider.FetchOptional(ctx, f, "meta-data/network/interfaces/macs/")
+	if err != nil {
+		return nil, errors.Wrap(err, "list macs")
+	}
+	ret := make([]metadata.NetworkInterface, 0)
+	for _, macStr := range provider.SplitLines(string(out)) {
+		mac, err := provider.ParseMAC(macStr)
+		if err != nil {
+			log.Warningf("invalid mac %q: %v", macStr, err)
+			continue
+		}
+		prefix := "meta-data/network/interfaces/macs/" + macStr + "/"
+		locals, err := provider.FetchOptional(ctx, f, prefix+"local-ipv4s")
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetch local ipv4s of %s", macStr)
+		}
+		publics, err := provider.FetchOptional(ctx, f, prefix+"public-ipv4s")
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetch public ipv4s of %s", macStr)
+		}
+		nic := metadata.NetworkInterface{
+			Mac:        mac,
+			PrivateIPs: provider.ParseIPs(provider.SplitLines(string(locals))...),
+			PublicIPs:  provider.ParseIPs(provider.SplitLines(string(publics))...),
+		}
+		if len(nic.PrivateIPs) > 0 {
+			nic.PrimaryIP = nic.PrivateIPs[0]
+		}
+		ret = append(ret, nic)
+	}
+	return ret, nil
+}
+
 func (s *metadataService) FetchMetadata(ctx context.Context) (*metadata.Digest, error) {
 	hostname, err := s.FetchHostname(ctx)
 	if err != nil {
@@ -101,10 +122,24 @@ func (s *metadataService) FetchMetadata(ctx context.Context) (*metadata.Digest,
 		return nil, err
 	}
 
+	nics, err := s.FetchNetworkInterfaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	digest := &metadata.Digest{
-		Hostname:   hostname,
-		LocalIPv4:  localIPv4,
-		PublicIPv4: publicIPv4,
+		Hostname:          hostname,
+		LocalIPv4:         localIPv4,
+		PublicIPv4:        publicIPv4,
+		NetworkInterfaces: nics,
 	}
 	return digest, nil
 }
+
+func (s *metadataService) FetchUserdata(ctx context.Context) (userdata.Map, error) {
+	data, err := provider.FetchOptional(ctx, s.fetcher(), "user-data")
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch user-data")
+	}
+	return userdata.Parse(data)
+}
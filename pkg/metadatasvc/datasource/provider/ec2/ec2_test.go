@@ -0,0 +1,90 @@
+package ec2
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider"
+)
+
+func newFakeServer(attrs map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		val, ok := attrs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(val))
+	}))
+}
+
+func TestMetadataService(t *testing.T) {
+	prefix := "/" + LatestSupportedVersion + "/"
+	srv := newFakeServer(map[string]string{
+		prefix + "meta-data/hostname":                                               "ip-10-0-0-5.ec2.internal",
+		prefix + "meta-data/local-ipv4":                                             "10.0.0.5",
+		prefix + "meta-data/public-ipv4":                                            "54.1.2.3",
+		prefix + "meta-data/network/interfaces/macs/":                               "0a:00:00:00:00:01/\n0a:00:00:00:00:02/",
+		prefix + "meta-data/network/interfaces/macs/0a:00:00:00:00:01/local-ipv4s":  "10.0.0.5\n10.0.0.6",
+		prefix + "meta-data/network/interfaces/macs/0a:00:00:00:00:01/public-ipv4s": "54.1.2.3",
+		prefix + "meta-data/network/interfaces/macs/0a:00:00:00:00:02/local-ipv4s":  "10.0.1.5",
+		prefix + "user-data":                                                        "#!/bin/bash\necho hello\n",
+	})
+	defer srv.Close()
+
+	s := &metadataService{URL: provider.FormatURL(srv.URL + "/%s/")}
+	ctx := context.Background()
+	digest, err := s.FetchMetadata(ctx)
+	if err != nil {
+		t.Fatalf("FetchMetadata: %v", err)
+	}
+	if digest.Hostname != "ip-10-0-0-5.ec2.internal" || digest.LocalIPv4.String() != "10.0.0.5" || digest.PublicIPv4.String() != "54.1.2.3" {
+		t.Errorf("unexpected digest %s", digest)
+	}
+	if len(digest.NetworkInterfaces) != 2 {
+		t.Fatalf("want 2 interfaces, got %d", len(digest.NetworkInterfaces))
+	}
+	nic := digest.NetworkInterfaces[0]
+	if nic.Mac.String() != "0a:00:00:00:00:01" || nic.PrimaryIP.String() != "10.0.0.5" || len(nic.PrivateIPs) != 2 || len(nic.PublicIPs) != 1 {
+		t.Errorf("unexpected interface %#v", nic)
+	}
+	if nic := digest.NetworkInterfaces[1]; len(nic.PublicIPs) != 0 || nic.PrimaryIP.String() != "10.0.1.5" {
+		t.Errorf("unexpected interface %#v", nic)
+	}
+
+	data, err := s.FetchUserdata(ctx)
+	if err != nil {
+		t.Fatalf("FetchUserdata: %v", err)
+	}
+	if len(data.Scripts()) != 1 {
+		t.Errorf("want 1 script, got %v", data)
+	}
+}
+
+func TestMetadataServiceWithoutOptionalAttrs(t *testing.T) {
+	prefix := "/" + LatestSupportedVersion + "/"
+	srv := newFakeServer(map[string]string{
+		prefix + "meta-data/hostname":   "host",
+		prefix + "meta-data/local-ipv4": "10.0.0.5",
+	})
+	defer srv.Close()
+
+	s := &metadataService{URL: provider.FormatURL(srv.URL + "/%s/")}
+	ctx := context.Background()
+	digest, err := s.FetchMetadata(ctx)
+	if err != nil {
+		t.Fatalf("FetchMetadata: %v", err)
+	}
+	if digest.PublicIPv4 != nil || len(digest.NetworkInterfaces) != 0 {
+		t.Errorf("unexpected digest %s", digest)
+	}
+	data, err := s.FetchUserdata(ctx)
+	if err != nil {
+		t.Fatalf("FetchUserdata: %v", err)
+	}
+	if len(data) != 0 {
+		t.Errorf("want empty user-data, got %v", data)
+	}
+}
@@ -0,0 +1,142 @@
+package gce
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/metadata"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/userdata"
+)
+
+const (
+	MetadataURL = "http://169.254.169.254/computeMetadata/v1/"
+)
+
+func init() {
+	datasource.RegisterProvider(&metadataService{
+		URL: MetadataURL,
+	})
+}
+
+type accessConfig struct {
+	ExternalIp string `json:"externalIp"`
+}
+
+type networkInterface struct {
+	Mac           string         `json:"mac"`
+	Ip            string         `json:"ip"`
+	AccessConfigs []accessConfig `json:"accessConfigs"`
+}
+
+// metadataService is google compute engine metadata server
+type metadataService struct {
+	URL string
+}
+
+func (s *metadataService) fetcher() provider.Fetcher {
+	return &provider.HTTPFetcher{
+		BaseURL: s.URL,
+		Header:  http.Header{"Metadata-Flavor": []string{"Google"}},
+	}
+}
+
+func (s *metadataService) fetchIP(ctx context.Context, attr string) (net.IP, error) {
+	ret, err := provider.FetchText(ctx, s.fetcher(), attr)
+	if err != nil {
+		return nil, err
+	}
+	return net.ParseIP(ret), nil
+}
+
+func (s *metadataService) GetType() datasource.DatasourceType {
+	return datasource.DatasourceTypeGCE
+}
+
+func (s *metadataService) FetchHostname(ctx context.Context) (string, error) {
+	return provider.FetchText(ctx, s.fetcher(), "instance/hostname")
+}
+
+func (s *metadataService) FetchLocalIPv4(ctx context.Context) (net.IP, error) {
+	return s.fetchIP(ctx, "instance/network-interfaces/0/ip")
+}
+
+// FetchPublicIPv4 returns nil when instance has no external ip
+func (s *metadataService) FetchPublicIPv4(ctx context.Context) (net.IP, error) {
+	ip, err := s.fetchIP(ctx, "instance/network-interfaces/0/access-configs/0/external-ip")
+	if err != nil {
+		if errors.Cause(err) == provider.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ip, nil
+}
+
+func (s *metadataService) FetchNetworkInterfaces(ctx context.Context) ([]metadata.NetworkInterface, error) {
+	data, err := s.fetcher().Fetch(ctx, "instance/network-interfaces/?recursive=true")
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch network interfaces")
+	}
+	ifaces := make([]networkInterface, 0)
+	if err := json.Unmarshal(data, &ifaces); err != nil {
+		return nil, errors.Wrap(err, "unmarshal network interfaces")
+	}
+	ret := make([]metadata.NetworkInterface, 0)
+	for _, iface := range ifaces {
+		mac, err := provider.ParseMAC(iface.Mac)
+		if err != nil {
+			log.Warningf("invalid mac %q: %v", iface.Mac, err)
+			continue
+		}
+		nic := metadata.NetworkInterface{
+			Mac:        mac,
+			PrivateIPs: provider.ParseIPs(iface.Ip),
+			PublicIPs:  make([]net.IP, 0),
+		}
+		for _, ac := range iface.AccessConfigs {
+			nic.PublicIPs = append(nic.PublicIPs, provider.ParseIPs(ac.ExternalIp)...)
+		}
+		if len(nic.PrivateIPs) > 0 {
+			nic.PrimaryIP = nic.PrivateIPs[0]
+		}
+		ret = append(ret, nic)
+	}
+	return ret, nil
+}
+
+func (s *metadataService) FetchMetadata(ctx context.Context) (*metadata.Digest, error) {
+	hostname, err := s.FetchHostname(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nics, err := s.FetchNetworkInterfaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	digest := &metadata.Digest{
+		Hostname:          hostname,
+		NetworkInterfaces: nics,
+	}
+	if len(nics) > 0 {
+		digest.LocalIPv4 = nics[0].PrimaryIP
+		if len(nics[0].PublicIPs) > 0 {
+			digest.PublicIPv4 = nics[0].PublicIPs[0]
+		}
+	}
+	return digest, nil
+}
+
+func (s *metadataService) FetchUserdata(ctx context.Context) (userdata.Map, error) {
+	data, err := provider.FetchOptional(ctx, s.fetcher(), "instance/attributes/user-data")
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch user-data")
+	}
+	return userdata.Parse(data)
+}
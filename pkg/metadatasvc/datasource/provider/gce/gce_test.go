@@ -0,0 +1,50 @@
+package gce
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetadataService(t *testing.T) {
+	attrs := map[string]string{
+		"/computeMetadata/v1/instance/hostname":             "vm-1.c.project.internal",
+		"/computeMetadata/v1/instance/network-interfaces/":  `[{"mac":"42:01:0a:80:00:02","ip":"10.128.0.2","accessConfigs":[{"externalIp":"35.1.2.3"}]}]`,
+		"/computeMetadata/v1/instance/attributes/user-data": "#!/bin/bash\necho hi\n",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		val, ok := attrs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(val))
+	}))
+	defer srv.Close()
+
+	s := &metadataService{URL: srv.URL + "/computeMetadata/v1/"}
+	ctx := context.Background()
+	digest, err := s.FetchMetadata(ctx)
+	if err != nil {
+		t.Fatalf("FetchMetadata: %v", err)
+	}
+	if digest.Hostname != "vm-1.c.project.internal" || digest.LocalIPv4.String() != "10.128.0.2" || digest.PublicIPv4.String() != "35.1.2.3" {
+		t.Errorf("unexpected digest %s", digest)
+	}
+	if ip, err := s.FetchPublicIPv4(ctx); err != nil || ip != nil {
+		t.Errorf("FetchPublicIPv4 of absent attribute: %v %v", ip, err)
+	}
+
+	data, err := s.FetchUserdata(ctx)
+	if err != nil {
+		t.Fatalf("FetchUserdata: %v", err)
+	}
+	if len(data.Scripts()) != 1 {
+		t.Errorf("want 1 script, got %v", data)
+	}
+}
@@ -0,0 +1,216 @@
+package nocloud
+
+import (
+	"context"
+	"net"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/metadata"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/userdata"
+)
+
+const (
+	MetaDataPath      = "meta-data"
+	NetworkConfigPath = "network-config"
+	UserDataPath      = "user-data"
+)
+
+func init() {
+	datasource.RegisterProvider(&noCloud{
+		disk: provider.NewDiskFetcher(provider.NewLabelMounter("cidata", "CIDATA")),
+	})
+}
+
+type metaData struct {
+	InstanceId    string `json:"instance-id"`
+	LocalHostname string `json:"local-hostname"`
+}
+
+type subnetV1 struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+}
+
+type configV1 struct {
+	Type       string     `json:"type"`
+	MacAddress string     `json:"mac_address"`
+	Subnets    []subnetV1 `json:"subnets"`
+}
+
+type ethernetV2 struct {
+	Match struct {
+		MacAddress string `json:"macaddress"`
+	} `json:"match"`
+	Addresses []string `json:"addresses"`
+}
+
+// networkConfig is cloud-init network config of version 1 or 2,
+// it may be wrapped by a top level network key
+type networkConfig struct {
+	Version   int                   `json:"version"`
+	Config    []configV1            `json:"config"`
+	Ethernets map[string]ethernetV2 `json:"ethernets"`
+	Network   *networkConfig        `json:"network"`
+}
+
+func (nc *networkConfig) networkInterfaces() []metadata.NetworkInterface {
+	if nc.Network != nil {
+		return nc.Network.networkInterfaces()
+	}
+	ret := make([]metadata.NetworkInterface, 0)
+	addNic := func(macStr string, addrs []string) {
+		mac, err := provider.ParseMAC(macStr)
+		if err != nil {
+			log.Warningf("invalid mac %q: %v", macStr, err)
+			return
+		}
+		nic := metadata.NetworkInterface{
+			Mac:        mac,
+			PrivateIPs: provider.ParseIPs(addrs...),
+		}
+		if len(nic.PrivateIPs) > 0 {
+			nic.PrimaryIP = nic.PrivateIPs[0]
+		}
+		ret = append(ret, nic)
+	}
+	switch nc.Version {
+	case 1:
+		for _, conf := range nc.Config {
+			if conf.Type != "physical" || conf.MacAddress == "" {
+				continue
+			}
+			addrs := make([]string, 0)
+			for _, subnet := range conf.Subnets {
+				addrs = append(addrs, subnet.Address)
+			}
+			addNic(conf.MacAddress, addrs)
+		}
+	case 2:
+		names := make([]string, 0, len(nc.Ethernets))
+		for name := range nc.Ethernets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			eth := nc.Ethernets[name]
+			if eth.Match.MacAddress == "" {
+				continue
+			}
+			addNic(eth.Match.MacAddress, eth.Addresses)
+		}
+	default:
+		log.Warningf("unsupported network config version %d", nc.Version)
+	}
+	return ret
+}
+
+// noCloud is cloud-init nocloud data source on disk labeled cidata
+type noCloud struct {
+	disk provider.Fetcher
+}
+
+func (n *noCloud) withFetcher(fn func(f provider.Fetcher) error) error {
+	return fn(n.disk)
+}
+
+func fetchMetaData(ctx context.Context, f provider.Fetcher) (*metaData, error) {
+	data, err := f.Fetch(ctx, MetaDataPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch %s", MetaDataPath)
+	}
+	md := new(metaData)
+	if err := yaml.Unmarshal(data, md); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal %s", MetaDataPath)
+	}
+	return md, nil
+}
+
+func fetchNetworkInterfaces(ctx context.Context, f provider.Fetcher) ([]metadata.NetworkInterface, error) {
+	data, err := provider.FetchOptional(ctx, f, NetworkConfigPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch %s", NetworkConfigPath)
+	}
+	if len(data) == 0 {
+		return make([]metadata.NetworkInterface, 0), nil
+	}
+	nc := new(networkConfig)
+	if err := yaml.Unmarshal(data, nc); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal %s", NetworkConfigPath)
+	}
+	return nc.networkInterfaces(), nil
+}
+
+func (n *noCloud) GetType() datasource.DatasourceType {
+	return datasource.DatasourceTypeNoCloud
+}
+
+func (n *noCloud) FetchHostname(ctx context.Context) (string, error) {
+	digest, err := n.FetchMetadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	return digest.Hostname, nil
+}
+
+func (n *noCloud) FetchLocalIPv4(ctx context.Context) (net.IP, error) {
+	digest, err := n.FetchMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return digest.LocalIPv4, nil
+}
+
+// FetchPublicIPv4 always returns nil, nocloud has no public ip concept
+func (n *noCloud) FetchPublicIPv4(ctx context.Context) (net.IP, error) {
+	return nil, nil
+}
+
+func (n *noCloud) FetchMetadata(ctx context.Context) (*metadata.Digest, error) {
+	digest := new(metadata.Digest)
+	err := n.withFetcher(func(f provider.Fetcher) error {
+		md, err := fetchMetaData(ctx, f)
+		if err != nil {
+			return err
+		}
+		nics, err := fetchNetworkInterfaces(ctx, f)
+		if err != nil {
+			return err
+		}
+		digest.Hostname = md.LocalHostname
+		if digest.Hostname == "" {
+			digest.Hostname = md.InstanceId
+		}
+		digest.NetworkInterfaces = nics
+		for _, nic := range nics {
+			if nic.PrimaryIP != nil {
+				digest.LocalIPv4 = nic.PrimaryIP
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
+
+func (n *noCloud) FetchUserdata(ctx context.Context) (userdata.Map, error) {
+	var ret userdata.Map
+	err := n.withFetcher(func(f provider.Fetcher) error {
+		data, err := provider.FetchOptional(ctx, f, UserDataPath)
+		if err != nil {
+			return errors.Wrapf(err, "fetch %s", UserDataPath)
+		}
+		ret, err = userdata.Parse(data)
+		return err
+	})
+	return ret, err
+}
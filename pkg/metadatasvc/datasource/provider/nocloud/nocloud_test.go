@@ -0,0 +1,72 @@
+package nocloud
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider"
+)
+
+func newFakeCidata(t *testing.T, files map[string]string) *noCloud {
+	root, err := ioutil.TempDir("", "cidata-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	return &noCloud{
+		disk: provider.NewDiskFetcher(func() (string, func(), error) {
+			return root, func() {}, nil
+		}),
+	}
+}
+
+func TestNoCloud(t *testing.T) {
+	for name, networkConfig := range map[string]string{
+		"v1": `version: 1
+config:
+- type: physical
+  name: eth0
+  mac_address: "52:54:00:12:34:00"
+  subnets:
+  - type: static
+    address: 192.168.1.10/24
+`,
+		"v2": `network:
+  version: 2
+  ethernets:
+    id0:
+      match:
+        macaddress: "52:54:00:12:34:00"
+      addresses: [192.168.1.10/24]
+`,
+	} {
+		n := newFakeCidata(t, map[string]string{
+			MetaDataPath:      "instance-id: iid-local01\nlocal-hostname: cloudimg\n",
+			NetworkConfigPath: networkConfig,
+			UserDataPath:      "#cloud-config\npassword: passw0rd\n",
+		})
+		ctx := context.Background()
+		digest, err := n.FetchMetadata(ctx)
+		if err != nil {
+			t.Fatalf("%s FetchMetadata: %v", name, err)
+		}
+		if digest.Hostname != "cloudimg" || digest.LocalIPv4.String() != "192.168.1.10" || len(digest.NetworkInterfaces) != 1 {
+			t.Errorf("%s unexpected digest %s", name, digest)
+		}
+		data, err := n.FetchUserdata(ctx)
+		if err != nil {
+			t.Fatalf("%s FetchUserdata: %v", name, err)
+		}
+		if len(data.CloudConfigs()) != 1 {
+			t.Errorf("%s want 1 cloud-config, got %v", name, data)
+		}
+	}
+}
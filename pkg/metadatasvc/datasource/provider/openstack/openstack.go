@@ -0,0 +1,249 @@
+package openstack
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/metadata"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/userdata"
+)
+
+const (
+	MetadataURL = "http://169.254.169.254/openstack/latest/"
+	// EC2MetadataURL is ec2 compatible meta-data of nova, only used to fetch ipv4 addresses
+	EC2MetadataURL = "http://169.254.169.254/latest/meta-data/"
+
+	MetaDataPath    = "meta_data.json"
+	NetworkDataPath = "network_data.json"
+	UserDataPath    = "user_data"
+)
+
+func init() {
+	datasource.RegisterProvider(&metadataService{
+		URL:    MetadataURL,
+		EC2URL: EC2MetadataURL,
+	})
+}
+
+// MetaData is part of meta_data.json
+type MetaData struct {
+	UUID     string `json:"uuid"`
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+}
+
+type NetworkLink struct {
+	Id         string `json:"id"`
+	Type       string `json:"type"`
+	MacAddress string `json:"ethernet_mac_address"`
+}
+
+type Network struct {
+	Id        string `json:"id"`
+	Type      string `json:"type"`
+	Link      string `json:"link"`
+	IPAddress string `json:"ip_address"`
+}
+
+// NetworkData is network_data.json, ip_address of network is absent when type is dhcp
+type NetworkData struct {
+	Links    []NetworkLink `json:"links"`
+	Networks []Network     `json:"networks"`
+}
+
+// FetchMetaData reads meta_data.json of openstack metadata service or config drive
+func FetchMetaData(ctx context.Context, f provider.Fetcher) (*MetaData, error) {
+	data, err := f.Fetch(ctx, MetaDataPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch %s", MetaDataPath)
+	}
+	md := new(MetaData)
+	if err := json.Unmarshal(data, md); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal %s", MetaDataPath)
+	}
+	return md, nil
+}
+
+// GetHostname returns hostname of instance, instance name is used when hostname is absent
+func (md MetaData) GetHostname() string {
+	if md.Hostname != "" {
+		return md.Hostname
+	}
+	return md.Name
+}
+
+// FetchNetworkInterfaces reads network_data.json, empty is returned when it's absent
+func FetchNetworkInterfaces(ctx context.Context, f provider.Fetcher) ([]metadata.NetworkInterface, error) {
+	data, err := provider.FetchOptional(ctx, f, NetworkDataPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch %s", NetworkDataPath)
+	}
+	ret := make([]metadata.NetworkInterface, 0)
+	if len(data) == 0 {
+		return ret, nil
+	}
+	nd := new(NetworkData)
+	if err := json.Unmarshal(data, nd); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal %s", NetworkDataPath)
+	}
+	return nd.NetworkInterfaces(), nil
+}
+
+// NetworkInterfaces returns interfaces of physical links with addresses of their networks
+func (nd NetworkData) NetworkInterfaces() []metadata.NetworkInterface {
+	ret := make([]metadata.NetworkInterface, 0)
+	for _, link := range nd.Links {
+		if link.MacAddress == "" {
+			continue
+		}
+		mac, err := provider.ParseMAC(link.MacAddress)
+		if err != nil {
+			log.Warningf("invalid mac %q of link %s: %v", link.MacAddress, link.Id, err)
+			continue
+		}
+		nic := metadata.NetworkInterface{
+			Mac: mac,
+		}
+		for _, network := range nd.Networks {
+			if network.Link != link.Id {
+				continue
+			}
+			nic.PrivateIPs = append(nic.PrivateIPs, provider.ParseIPs(network.IPAddress)...)
+		}
+		if len(nic.PrivateIPs) > 0 {
+			nic.PrimaryIP = nic.PrivateIPs[0]
+		}
+		ret = append(ret, nic)
+	}
+	return ret
+}
+
+// FetchUserdata reads user_data of openstack metadata service or config drive
+func FetchUserdata(ctx context.Context, f provider.Fetcher) (userdata.Map, error) {
+	data, err := provider.FetchOptional(ctx, f, UserDataPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch %s", UserDataPath)
+	}
+	return userdata.Parse(data)
+}
+
+// FetchMetadata returns digest of meta data and network data, local ipv4 is the
+// first ipv4 address of interfaces
+func FetchMetadata(ctx context.Context, f provider.Fetcher) (*metadata.Digest, error) {
+	md, err := FetchMetaData(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	nics, err := FetchNetworkInterfaces(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return &metadata.Digest{
+		Hostname:          md.GetHostname(),
+		LocalIPv4:         FirstIPv4(nics),
+		NetworkInterfaces: nics,
+	}, nil
+}
+
+// FirstIPv4 returns first private ipv4 address of interfaces
+func FirstIPv4(nics []metadata.NetworkInterface) net.IP {
+	for _, nic := range nics {
+		for _, ip := range nic.PrivateIPs {
+			if ip.To4() != nil {
+				return ip
+			}
+		}
+	}
+	return nil
+}
+
+// metadataService is openstack nova meta-data service
+type metadataService struct {
+	URL    string
+	EC2URL string
+}
+
+func (s *metadataService) fetcher() provider.Fetcher {
+	return &provider.HTTPFetcher{BaseURL: s.URL}
+}
+
+func (s *metadataService) fetchEC2IPv4(ctx context.Context, attr string) (net.IP, error) {
+	data, err := provider.FetchOptional(ctx, &provider.HTTPFetcher{BaseURL: s.EC2URL}, attr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch %s", attr)
+	}
+	ips := provider.ParseIPs(string(data))
+	if len(ips) == 0 {
+		return nil, nil
+	}
+	return ips[0], nil
+}
+
+func (s *metadataService) GetType() datasource.DatasourceType {
+	return datasource.DatasourceTypeOpenStack
+}
+
+func (s *metadataService) FetchHostname(ctx context.Context) (string, error) {
+	md, err := FetchMetaData(ctx, s.fetcher())
+	if err != nil {
+		return "", err
+	}
+	return md.GetHostname(), nil
+}
+
+// FetchLocalIPv4 prefers ec2 compatible local-ipv4, since address of dhcp network
+// is absent in network data
+func (s *metadataService) FetchLocalIPv4(ctx context.Context) (net.IP, error) {
+	ip, err := s.fetchEC2IPv4(ctx, "local-ipv4")
+	if err != nil || ip != nil {
+		return ip, err
+	}
+	nics, err := FetchNetworkInterfaces(ctx, s.fetcher())
+	if err != nil {
+		return nil, err
+	}
+	return FirstIPv4(nics), nil
+}
+
+// FetchPublicIPv4 returns floating ip, which is only given by ec2 compatible meta-data
+func (s *metadataService) FetchPublicIPv4(ctx context.Context) (net.IP, error) {
+	return s.fetchEC2IPv4(ctx, "public-ipv4")
+}
+
+func (s *metadataService) FetchMetadata(ctx context.Context) (*metadata.Digest, error) {
+	digest, err := FetchMetadata(ctx, s.fetcher())
+	if err != nil {
+		return nil, err
+	}
+	localIPv4, err := s.fetchEC2IPv4(ctx, "local-ipv4")
+	if err != nil {
+		return nil, err
+	}
+	if localIPv4 != nil {
+		digest.LocalIPv4 = localIPv4
+	}
+	publicIPv4, err := s.FetchPublicIPv4(ctx)
+	if err != nil {
+		return nil, err
+	}
+	digest.PublicIPv4 = publicIPv4
+	if publicIPv4 != nil {
+		for i := range digest.NetworkInterfaces {
+			nic := &digest.NetworkInterfaces[i]
+			if nic.PrimaryIP.Equal(digest.LocalIPv4) {
+				nic.PublicIPs = append(nic.PublicIPs, publicIPv4)
+			}
+		}
+	}
+	return digest, nil
+}
+
+func (s *metadataService) FetchUserdata(ctx context.Context) (userdata.Map, error) {
+	return FetchUserdata(ctx, s.fetcher())
+}
@@ -0,0 +1,70 @@
+package openstack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	fakeMetaData    = `{"uuid":"83679162-1378-4288-a2d4-70e13ec132aa","name":"vm-1","hostname":"vm-1.novalocal"}`
+	fakeNetworkData = `{
+  "links": [
+    {"id": "tap1", "type": "ovs", "ethernet_mac_address": "fa:16:3e:00:00:01"},
+    {"id": "tap2", "type": "ovs", "ethernet_mac_address": "fa:16:3e:00:00:02"}
+  ],
+  "networks": [
+    {"id": "network0", "type": "ipv4", "link": "tap1", "ip_address": "192.168.0.5"},
+    {"id": "network1", "type": "ipv4_dhcp", "link": "tap2"}
+  ]
+}`
+)
+
+func TestMetadataService(t *testing.T) {
+	attrs := map[string]string{
+		"/openstack/latest/meta_data.json":    fakeMetaData,
+		"/openstack/latest/network_data.json": fakeNetworkData,
+		"/openstack/latest/user_data":         "#!/bin/sh\ntrue\n",
+		"/latest/meta-data/public-ipv4":       "203.0.113.5",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		val, ok := attrs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(val))
+	}))
+	defer srv.Close()
+
+	s := &metadataService{
+		URL:    srv.URL + "/openstack/latest/",
+		EC2URL: srv.URL + "/latest/meta-data/",
+	}
+	ctx := context.Background()
+	digest, err := s.FetchMetadata(ctx)
+	if err != nil {
+		t.Fatalf("FetchMetadata: %v", err)
+	}
+	if digest.Hostname != "vm-1.novalocal" || digest.LocalIPv4.String() != "192.168.0.5" || digest.PublicIPv4.String() != "203.0.113.5" {
+		t.Errorf("unexpected digest %s", digest)
+	}
+	if len(digest.NetworkInterfaces) != 2 {
+		t.Fatalf("unexpected interfaces %s", digest)
+	}
+	if nic := digest.NetworkInterfaces[0]; nic.PrimaryIP.String() != "192.168.0.5" || len(nic.PublicIPs) != 1 {
+		t.Errorf("unexpected interface %#v", nic)
+	}
+	if nic := digest.NetworkInterfaces[1]; nic.PrimaryIP != nil || len(nic.PrivateIPs) != 0 {
+		t.Errorf("unexpected interface %#v", nic)
+	}
+
+	data, err := s.FetchUserdata(ctx)
+	if err != nil {
+		t.Fatalf("FetchUserdata: %v", err)
+	}
+	if len(data.Scripts()) != 1 {
+		t.Errorf("want 1 script, got %v", data)
+	}
+}
@@ -1,6 +1,28 @@
 package provider
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/httputils"
+)
+
+const (
+	ErrNotFound = errors.Error("provider: attribute not found")
+
+	requestTimeout = 5 * time.Second
+)
 
 // FormatURL is the meta-data service url as a format string
 // to be replaced by version.
@@ -13,3 +35,221 @@ type FormatURL string
 func (u FormatURL) Fill(vals ...interface{}) string {
 	return fmt.Sprintf(string(u), vals...)
 }
+
+// Fetcher reads attribute of meta-data source by path
+type Fetcher interface {
+	Fetch(ctx context.Context, path string) ([]byte, error)
+}
+
+// HTTPFetcher fetches attribute from meta-data service, path is appended to BaseURL
+type HTTPFetcher struct {
+	BaseURL string
+	Header  http.Header
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, path string) ([]byte, error) {
+	return FetchURL(ctx, f.BaseURL+path, f.Header)
+}
+
+// FetchURL gets content of url, ErrNotFound is returned when status code is 404
+func FetchURL(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "new request %s", url)
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	resp, err := httputils.GetTimeoutClient(requestTimeout).Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "request url %s", url)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read response of %s", url)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.Wrap(ErrNotFound, url)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, errors.Errorf("request url %s status %d: %s", url, resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// FileFetcher reads attribute from mounted meta-data filesystem, path is relative to Root
+type FileFetcher struct {
+	Root string
+}
+
+func (f *FileFetcher) Fetch(ctx context.Context, path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(f.Root, path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Wrap(ErrNotFound, path)
+		}
+		return nil, errors.Wrapf(err, "read %s", path)
+	}
+	return data, nil
+}
+
+// FetchText returns trimmed text attribute
+func FetchText(ctx context.Context, f Fetcher, path string) (string, error) {
+	data, err := f.Fetch(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// FetchOptional returns empty content instead of ErrNotFound
+func FetchOptional(ctx context.Context, f Fetcher, path string) ([]byte, error) {
+	data, err := f.Fetch(ctx, path)
+	if errors.Cause(err) == ErrNotFound {
+		return nil, nil
+	}
+	return data, err
+}
+
+// MountFunc mounts meta-data filesystem and returns its root and cleanup function
+type MountFunc func() (string, func(), error)
+
+// NewLabelMounter returns MountFunc mounting filesystem of first found label read only,
+// e.g. config-2 of openstack config drive and cidata of nocloud
+func NewLabelMounter(labels ...string) MountFunc {
+	return func() (string, func(), error) {
+		dev := ""
+		for _, label := range labels {
+			p := filepath.Join("/dev/disk/by-label", label)
+			if _, err := os.Stat(p); err == nil {
+				dev = p
+				break
+			}
+		}
+		if dev == "" {
+			return "", nil, errors.Wrapf(ErrNotFound, "device of labels %v", labels)
+		}
+		dir, err := ioutil.TempDir("", "metadatasvc-")
+		if err != nil {
+			return "", nil, errors.Wrap(err, "create mount dir")
+		}
+		if out, err := exec.Command("mount", "-o", "ro", dev, dir).CombinedOutput(); err != nil {
+			os.Remove(dir)
+			return "", nil, errors.Wrapf(err, "mount %s: %s", dev, out)
+		}
+		cleanup := func() {
+			if out, err := exec.Command("umount", dir).CombinedOutput(); err != nil {
+				log.Warningf("umount %s: %v %s", dir, err, out)
+				return
+			}
+			os.Remove(dir)
+		}
+		return dir, cleanup, nil
+	}
+}
+
+// DiskFetcher mounts meta-data filesystem on first fetch and keeps all its files in
+// memory, content of config drive and cidata doesn't change during instance lifetime
+type DiskFetcher struct {
+	mount MountFunc
+
+	lock  sync.Mutex
+	files map[string][]byte
+}
+
+func NewDiskFetcher(mount MountFunc) *DiskFetcher {
+	return &DiskFetcher{mount: mount}
+}
+
+func (f *DiskFetcher) load() (map[string][]byte, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.files != nil {
+		return f.files, nil
+	}
+	root, cleanup, err := f.mount()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	files := make(map[string][]byte)
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "read %s", rel)
+		}
+		files[rel] = data
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "read files of %s", root)
+	}
+	f.files = files
+	return files, nil
+}
+
+func (f *DiskFetcher) Fetch(ctx context.Context, path string) ([]byte, error) {
+	files, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	data, ok := files[filepath.Clean(path)]
+	if !ok {
+		return nil, errors.Wrap(ErrNotFound, path)
+	}
+	return data, nil
+}
+
+// ParseMAC parses mac address, hex digits without separator are also accepted like azure gives
+func ParseMAC(s string) (net.HardwareAddr, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 12 && !strings.ContainsAny(s, ":-.") {
+		parts := make([]string, 0, 6)
+		for i := 0; i < 12; i += 2 {
+			parts = append(parts, s[i:i+2])
+		}
+		s = strings.Join(parts, ":")
+	}
+	return net.ParseMAC(s)
+}
+
+// ParseIPs parses valid ips of strings, items may be in CIDR form
+func ParseIPs(ss ...string) []net.IP {
+	ret := make([]net.IP, 0)
+	for _, s := range ss {
+		s = strings.TrimSpace(s)
+		if idx := strings.Index(s, "/"); idx >= 0 {
+			s = s[:idx]
+		}
+		if ip := net.ParseIP(s); ip != nil {
+			ret = append(ret, ip)
+		}
+	}
+	return ret
+}
+
+// SplitLines returns non empty lines of listing attribute, trailing slash of directory is trimmed
+func SplitLines(s string) []string {
+	ret := make([]string, 0)
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSuffix(strings.TrimSpace(line), "/")
+		if line != "" {
+			ret = append(ret, line)
+		}
+	}
+	return ret
+}
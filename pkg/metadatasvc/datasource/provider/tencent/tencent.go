@@ -0,0 +1,148 @@
+package tencent
+
+import (
+	"context"
+	"net"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/datasource/provider"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/metadata"
+	"yunion.io/x/kubecomps/pkg/metadatasvc/userdata"
+)
+
+const (
+	// MetadataURL is address of metadata.tencentyun.com, ip is used to not depend on dns
+	MetadataURL = "http://169.254.0.23/latest/"
+)
+
+func init() {
+	datasource.RegisterProvider(&metadataService{
+		URL: MetadataURL,
+	})
+}
+
+// metadataService is tencent cvm instance meta-data service
+type metadataService struct {
+	URL string
+}
+
+func (s *metadataService) fetcher() provider.Fetcher {
+	return &provider.HTTPFetcher{BaseURL: s.URL}
+}
+
+func (s *metadataService) fetchMetadataTextAttr(ctx context.Context, attr string) (string, error) {
+	return provider.FetchText(ctx, s.fetcher(), "meta-data/"+attr)
+}
+
+func (s *metadataService) GetType() datasource.DatasourceType {
+	return datasource.DatasourceTypeTencent
+}
+
+// FetchHostname returns instance name, tencent meta-data has no hostname attribute
+func (s *metadataService) FetchHostname(ctx context.Context) (string, error) {
+	return s.fetchMetadataTextAttr(ctx, "instance-name")
+}
+
+func (s *metadataService) FetchLocalIPv4(ctx context.Context) (net.IP, error) {
+	ret, err := s.fetchMetadataTextAttr(ctx, "local-ipv4")
+	if err != nil {
+		return net.IP{}, err
+	}
+	return net.ParseIP(ret), nil
+}
+
+func (s *metadataService) FetchPublicIPv4(ctx context.Context) (net.IP, error) {
+	ret, err := s.fetchMetadataTextAttr(ctx, "public-ipv4")
+	if err != nil {
+		if errors.Cause(err) == provider.ErrNotFound {
+			return nil, nil
+		}
+		return net.IP{}, err
+	}
+	return net.ParseIP(ret), nil
+}
+
+func (s *metadataService) FetchNetworkInterfaces(ctx context.Context) ([]metadata.NetworkInterface, error) {
+	out, err := s.fetchMetadataTextAttr(ctx, "network/interfaces/macs/")
+	if err != nil {
+		return nil, errors.Wrap(err, "list macs")
+	}
+	ret := make([]metadata.NetworkInterface, 0)
+	for _, macStr := range provider.SplitLines(out) {
+		mac, err := provider.ParseMAC(macStr)
+		if err != nil {
+			log.Warningf("invalid mac %q: %v", macStr, err)
+			continue
+		}
+		prefix := "network/interfaces/macs/" + macStr + "/"
+		nic := metadata.NetworkInterface{
+			Mac: mac,
+		}
+		primary, err := s.fetchMetadataTextAttr(ctx, prefix+"primary-local-ipv4")
+		if err != nil && errors.Cause(err) != provider.ErrNotFound {
+			return nil, errors.Wrapf(err, "fetch primary ip of %s", macStr)
+		}
+		nic.PrimaryIP = net.ParseIP(primary)
+		// local-ipv4s is directory of ips, public ip is under each of them
+		locals, err := s.fetchMetadataTextAttr(ctx, prefix+"local-ipv4s/")
+		if err != nil {
+			return nil, errors.Wrapf(err, "list local ips of %s", macStr)
+		}
+		for _, ipStr := range provider.SplitLines(locals) {
+			ips := provider.ParseIPs(ipStr)
+			if len(ips) == 0 {
+				continue
+			}
+			nic.PrivateIPs = append(nic.PrivateIPs, ips[0])
+			public, err := s.fetchMetadataTextAttr(ctx, prefix+"local-ipv4s/"+ipStr+"/public-ipv4")
+			if err != nil {
+				if errors.Cause(err) == provider.ErrNotFound {
+					continue
+				}
+				return nil, errors.Wrapf(err, "fetch public ip of %s", ipStr)
+			}
+			nic.PublicIPs = append(nic.PublicIPs, provider.ParseIPs(public)...)
+		}
+		if nic.PrimaryIP == nil && len(nic.PrivateIPs) > 0 {
+			nic.PrimaryIP = nic.PrivateIPs[0]
+		}
+		ret = append(ret, nic)
+	}
+	return ret, nil
+}
+
+func (s *metadataService) FetchMetadata(ctx context.Context) (*metadata.Digest, error) {
+	hostname, err := s.FetchHostname(ctx)
+	if err != nil {
+		return nil, err
+	}
+	localIPv4, err := s.FetchLocalIPv4(ctx)
+	if err != nil {
+		return nil, err
+	}
+	publicIPv4, err := s.FetchPublicIPv4(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nics, err := s.FetchNetworkInterfaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &metadata.Digest{
+		Hostname:          hostname,
+		LocalIPv4:         localIPv4,
+		PublicIPv4:        publicIPv4,
+		NetworkInterfaces: nics,
+	}, nil
+}
+
+func (s *metadataService) FetchUserdata(ctx context.Context) (userdata.Map, error) {
+	data, err := provider.FetchOptional(ctx, s.fetcher(), "user-data")
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch user-data")
+	}
+	return userdata.Parse(data)
+}
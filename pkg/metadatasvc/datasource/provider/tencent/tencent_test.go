@@ -0,0 +1,54 @@
+package tencent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetadataService(t *testing.T) {
+	mac := "/latest/meta-data/network/interfaces/macs/52:54:00:00:00:01/"
+	attrs := map[string]string{
+		"/latest/meta-data/instance-name":            "cvm-1",
+		"/latest/meta-data/local-ipv4":               "10.0.0.8",
+		"/latest/meta-data/network/interfaces/macs/": "52:54:00:00:00:01/",
+		mac + "primary-local-ipv4":                   "10.0.0.8",
+		mac + "local-ipv4s/":                         "10.0.0.8/\n10.0.0.9/",
+		mac + "local-ipv4s/10.0.0.8/public-ipv4":     "119.1.2.3",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		val, ok := attrs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(val))
+	}))
+	defer srv.Close()
+
+	s := &metadataService{URL: srv.URL + "/latest/"}
+	ctx := context.Background()
+	digest, err := s.FetchMetadata(ctx)
+	if err != nil {
+		t.Fatalf("FetchMetadata: %v", err)
+	}
+	if digest.Hostname != "cvm-1" || digest.LocalIPv4.String() != "10.0.0.8" || digest.PublicIPv4 != nil {
+		t.Errorf("unexpected digest %s", digest)
+	}
+	if len(digest.NetworkInterfaces) != 1 {
+		t.Fatalf("unexpected interfaces %s", digest)
+	}
+	nic := digest.NetworkInterfaces[0]
+	if len(nic.PrivateIPs) != 2 || len(nic.PublicIPs) != 1 || nic.PublicIPs[0].String() != "119.1.2.3" {
+		t.Errorf("unexpected interface %#v", nic)
+	}
+
+	data, err := s.FetchUserdata(ctx)
+	if err != nil {
+		t.Fatalf("FetchUserdata: %v", err)
+	}
+	if len(data) != 0 {
+		t.Errorf("want empty user-data, got %v", data)
+	}
+}
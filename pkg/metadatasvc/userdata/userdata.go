@@ -1,6 +1,15 @@
 package userdata
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
 	"strings"
 
 	"yunion.io/x/pkg/errors"
@@ -11,12 +20,15 @@ const (
 	ErrNotAScript          = errors.Error("userdata: not a user-data script file")
 )
 
+// DefaultKey is the key of user-data not in multipart format
+const DefaultKey = "user-data"
+
 // Map contains the user-data attributes given by the provider
 type Map map[string]string
 
 // IsScript checks whether the given content belongs to a script file
 func IsScript(content string) error {
-	if !strings.HasPrefix(content, "#! ") {
+	if !strings.HasPrefix(content, "#!") {
 		return ErrNotAScript
 	}
 
@@ -39,7 +51,7 @@ func (m Map) Scripts() map[string]string {
 
 // IsCloudConfig checks whether the given content belongs to a cloud-config file
 func IsCloudConfig(content string) error {
-	if !strings.HasPrefix(content, "#cloud-config\n") {
+	if !strings.HasPrefix(content, "#cloud-config\n") && !strings.HasPrefix(content, "#cloud-config\r\n") {
 		return ErrNotACloudConfigFile
 	}
 
@@ -57,3 +69,84 @@ func (m Map) CloudConfigs() map[string]string {
 
 	return confs
 }
+
+// Parse parses raw user-data given by provider, gzip compressed content is
+// decompressed and parts of cloud-init MIME multipart archive are keyed by filename
+func Parse(data []byte) (Map, error) {
+	m := make(Map)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return m, nil
+	}
+	data, err := gunzip(data)
+	if err != nil {
+		return nil, err
+	}
+	if !isMultipart(data) {
+		m[DefaultKey] = string(data)
+		return m, nil
+	}
+	if err := parseMultipart(m, data); err != nil {
+		return nil, errors.Wrap(err, "parse multipart user-data")
+	}
+	return m, nil
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "new gzip reader")
+	}
+	defer r.Close()
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "decompress user-data")
+	}
+	return out, nil
+}
+
+func isMultipart(data []byte) bool {
+	for _, line := range strings.SplitN(string(data), "\n", 5) {
+		if strings.HasPrefix(strings.ToLower(line), "content-type: multipart/") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseMultipart(m Map, data []byte) error {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "read message")
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return errors.Wrap(err, "parse content type")
+	}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for idx := 0; ; idx++ {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "next part")
+		}
+		// quoted-printable is decoded by multipart reader
+		var body io.Reader = part
+		if strings.EqualFold(strings.TrimSpace(part.Header.Get("Content-Transfer-Encoding")), "base64") {
+			body = base64.NewDecoder(base64.StdEncoding, part)
+		}
+		content, err := ioutil.ReadAll(body)
+		if err != nil {
+			return errors.Wrap(err, "read part")
+		}
+		name := part.FileName()
+		if name == "" {
+			name = fmt.Sprintf("part-%03d", idx)
+		}
+		m[name] = string(content)
+	}
+}
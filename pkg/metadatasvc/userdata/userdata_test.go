@@ -0,0 +1,69 @@
+package userdata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+const multipartUserdata = `Content-Type: multipart/mixed; boundary="BOUNDARY"
+MIME-Version: 1.0
+
+--BOUNDARY
+Content-Type: text/cloud-config; charset="us-ascii"
+Content-Disposition: attachment; filename="cloud-config.txt"
+
+#cloud-config
+hostname: foo
+
+--BOUNDARY
+Content-Type: text/x-shellscript; charset="us-ascii"
+
+#!/bin/bash
+echo hello
+
+--BOUNDARY
+Content-Type: text/x-shellscript
+Content-Transfer-Encoding: base64
+Content-Disposition: attachment; filename="encoded.sh"
+
+IyEvYmluL2Jhc2gKZWNobyBl
+bmNvZGVkCg==
+--BOUNDARY--
+`
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte("#!/bin/sh\ntrue\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Scripts()) != 1 || m[DefaultKey] == "" {
+		t.Errorf("unexpected map of script %v", m)
+	}
+
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	w.Write([]byte(multipartUserdata))
+	w.Close()
+	m, err = Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.CloudConfigs()) != 1 || len(m.Scripts()) != 2 {
+		t.Errorf("unexpected map of multipart %v", m)
+	}
+	if _, ok := m["cloud-config.txt"]; !ok {
+		t.Errorf("part should be keyed by filename: %v", m)
+	}
+	if _, ok := m["part-001"]; !ok {
+		t.Errorf("part without filename should be keyed by index: %v", m)
+	}
+	if got := m["encoded.sh"]; got != "#!/bin/bash\necho encoded\n" {
+		t.Errorf("base64 part should be decoded, got %q", got)
+	}
+
+	m, err = Parse(nil)
+	if err != nil || len(m) != 0 {
+		t.Errorf("empty user-data: %v %v", m, err)
+	}
+}
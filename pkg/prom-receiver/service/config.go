@@ -0,0 +1,254 @@
+package service
+
+import (
+	"io/ioutil"
+	"text/template"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	DefaultRepeatInterval = 4 * time.Hour
+
+	ReceiverTypeFeishu   = "feishu"
+	ReceiverTypeDingTalk = "dingtalk"
+	ReceiverTypeWeCom    = "wecom"
+	ReceiverTypeSlack    = "slack"
+	ReceiverTypeWebhook  = "webhook"
+	ReceiverTypeSMTP     = "smtp"
+)
+
+// Config is loaded from notify config file, e.g.
+//
+//	route:
+//	  receiver: ops
+//	  group_by: [alertname, namespace]
+//	  repeat_interval: 4h
+//	  routes:
+//	  - matchers: ['severity="critical"']
+//	    receiver: oncall
+//	    continue: true
+//	receivers:
+//	- name: ops
+//	  type: feishu
+//	  url: https://open.feishu.cn/open-apis/bot/v2/hook/xxx
+//	  send_resolved: true
+//	- name: oncall
+//	  type: dingtalk
+//	  url: https://oapi.dingtalk.com/robot/send?access_token=xxx
+//	  secret: SECxxx
+type Config struct {
+	Route     *Route      `json:"route"`
+	Receivers []*Receiver `json:"receivers"`
+}
+
+// Route routes alerts matching all matchers to receiver, alerts are passed to the first
+// matching child route, or all matching ones when child route sets continue
+type Route struct {
+	Receiver string   `json:"receiver"`
+	Matchers []string `json:"matchers"`
+	// GroupBy labels of alerts sent in one notification, all alerts are in one group when empty
+	GroupBy []string `json:"group_by"`
+	// RepeatInterval is the interval to resend firing alert already notified
+	RepeatInterval string   `json:"repeat_interval"`
+	Continue       bool     `json:"continue"`
+	Routes         []*Route `json:"routes"`
+
+	matchers       []*Matcher
+	repeatInterval time.Duration
+}
+
+type Receiver struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// SendResolved notifies resolved alerts which were notified when firing
+	SendResolved bool `json:"send_resolved"`
+	// Title and Template are go templates rendered with TemplateData
+	Title    string `json:"title"`
+	Template string `json:"template"`
+
+	// URL is webhook address of feishu, dingtalk, wecom, slack and webhook receivers
+	URL string `json:"url"`
+	// Secret signs requests of feishu and dingtalk robots
+	Secret string `json:"secret"`
+	// Channel overrides default channel of slack incoming webhook
+	Channel string      `json:"channel"`
+	SMTP    *SMTPConfig `json:"smtp"`
+
+	titleTmpl   *template.Template
+	contentTmpl *template.Template
+}
+
+type SMTPConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	// TLS uses implicit tls connection, STARTTLS is used when server supports it otherwise
+	TLS bool `json:"tls"`
+}
+
+func LoadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read config file %s", file)
+	}
+	return ParseConfig(data)
+}
+
+func ParseConfig(data []byte) (*Config, error) {
+	conf := new(Config)
+	if err := yaml.Unmarshal(data, conf); err != nil {
+		return nil, errors.Wrap(err, "unmarshal config")
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// NewHookConfig returns config of the legacy options, loki firing alerts are sent to feishu robot hook
+func NewHookConfig(hook string) *Config {
+	conf := &Config{
+		Route: &Route{
+			Routes: []*Route{
+				{
+					Receiver: "feishu",
+					Matchers: []string{`from="loki"`},
+				},
+			},
+		},
+		Receivers: []*Receiver{
+			{
+				Name: "feishu",
+				Type: ReceiverTypeFeishu,
+				URL:  "https://open.feishu.cn/open-apis/bot/v2/hook/" + hook,
+			},
+		},
+	}
+	if err := conf.Validate(); err != nil {
+		// built in config is always valid
+		panic(err)
+	}
+	return conf
+}
+
+func (c *Config) Validate() error {
+	if c.Route == nil {
+		return errors.Error("route is empty")
+	}
+	names := make(map[string]bool)
+	for _, r := range c.Receivers {
+		if r.Name == "" {
+			return errors.Error("receiver name is empty")
+		}
+		if names[r.Name] {
+			return errors.Errorf("duplicate receiver %q", r.Name)
+		}
+		names[r.Name] = true
+		if err := r.validate(); err != nil {
+			return errors.Wrapf(err, "receiver %q", r.Name)
+		}
+	}
+	return c.Route.init(nil, names)
+}
+
+func (r *Receiver) validate() error {
+	if _, ok := notifierFactories[r.Type]; !ok {
+		return errors.Errorf("unsupported type %q", r.Type)
+	}
+	title := r.Title
+	if title == "" {
+		title = defaultTitleTemplate
+	}
+	content := r.Template
+	if content == "" {
+		content = defaultContentTemplate
+	}
+	var err error
+	if r.titleTmpl, err = newTemplate("title", title); err != nil {
+		return errors.Wrap(err, "parse title template")
+	}
+	if r.contentTmpl, err = newTemplate("content", content); err != nil {
+		return errors.Wrap(err, "parse template")
+	}
+	return nil
+}
+
+// init inherits settings from parent and compiles matchers
+func (r *Route) init(parent *Route, receivers map[string]bool) error {
+	if parent != nil {
+		if r.Receiver == "" {
+			r.Receiver = parent.Receiver
+		}
+		if r.GroupBy == nil {
+			r.GroupBy = parent.GroupBy
+		}
+		if r.RepeatInterval == "" {
+			r.repeatInterval = parent.repeatInterval
+		}
+	}
+	if r.RepeatInterval != "" {
+		d, err := time.ParseDuration(r.RepeatInterval)
+		if err != nil {
+			return errors.Wrapf(err, "parse repeat_interval %q", r.RepeatInterval)
+		}
+		r.repeatInterval = d
+	}
+	if r.repeatInterval == 0 {
+		r.repeatInterval = DefaultRepeatInterval
+	}
+	// route without receiver drops matched alerts
+	if r.Receiver != "" && !receivers[r.Receiver] {
+		return errors.Errorf("receiver %q not found", r.Receiver)
+	}
+	r.matchers = make([]*Matcher, 0, len(r.Matchers))
+	for _, s := range r.Matchers {
+		m, err := ParseMatcher(s)
+		if err != nil {
+			return err
+		}
+		r.matchers = append(r.matchers, m)
+	}
+	for _, child := range r.Routes {
+		if err := child.init(r, receivers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match returns routes alert labels are routed to
+func (r *Route) Match(labels map[string]string) []*Route {
+	for _, m := range r.matchers {
+		if !m.Matches(labels) {
+			return nil
+		}
+	}
+	ret := make([]*Route, 0)
+	for _, child := range r.Routes {
+		matched := child.Match(labels)
+		ret = append(ret, matched...)
+		if len(matched) != 0 && !child.Continue {
+			break
+		}
+	}
+	if len(ret) == 0 {
+		ret = append(ret, r)
+	}
+	return ret
+}
+
+// GroupLabels returns labels alerts are grouped by
+func (r *Route) GroupLabels(labels map[string]string) map[string]string {
+	ret := make(map[string]string)
+	for _, name := range r.GroupBy {
+		ret[name] = labels[name]
+	}
+	return ret
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+)
+
+// notifyStateTTL is how long notified state of alert is kept when no update received
+const notifyStateTTL = 7 * 24 * time.Hour
+
+type notifyState struct {
+	Status string    `json:"status"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// PartialNotifyError is returned by notifier sending alerts of message separately
+// when only some of them are sent, the sent ones are not notified again
+type PartialNotifyError struct {
+	Sent []PrometheusAlert
+	Err  error
+}
+
+func (e *PartialNotifyError) Error() string {
+	return e.Err.Error()
+}
+
+// alertGroup is alerts of one receiver sent in one notification
+type alertGroup struct {
+	route       *Route
+	groupLabels map[string]string
+	alerts      []PrometheusAlert
+}
+
+// Dispatcher routes alerts to receivers, groups them by route group_by labels and
+// deduplicates by fingerprint, a firing alert is resent after repeat interval and
+// resolved alert is only sent when its firing has been notified
+type Dispatcher struct {
+	route     *Route
+	receivers map[string]*Receiver
+	notifiers map[string]Notifier
+
+	lock     sync.Mutex
+	notified map[string]*notifyState
+	// sending records alerts being notified, concurrent requests skip them
+	sending map[string]bool
+	// stateFile persists notified state across restarts when it's set
+	stateFile string
+	now       func() time.Time
+}
+
+func NewDispatcher(conf *Config) (*Dispatcher, error) {
+	d := &Dispatcher{
+		route:     conf.Route,
+		receivers: make(map[string]*Receiver),
+		notifiers: make(map[string]Notifier),
+		notified:  make(map[string]*notifyState),
+		sending:   make(map[string]bool),
+		now:       time.Now,
+	}
+	for _, r := range conf.Receivers {
+		n, err := NewNotifier(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "new notifier of receiver %q", r.Name)
+		}
+		d.receivers[r.Name] = r
+		d.notifiers[r.Name] = n
+	}
+	return d, nil
+}
+
+// LoadState loads notified state from file and saves state to it after each notification,
+// file not exist is treated as empty state
+func (d *Dispatcher) LoadState(file string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.stateFile = file
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "read state file %s", file)
+	}
+	notified := make(map[string]*notifyState)
+	if err := json.Unmarshal(data, &notified); err != nil {
+		return errors.Wrapf(err, "unmarshal state file %s", file)
+	}
+	d.notified = notified
+	return nil
+}
+
+// saveState writes notified state to a temporary file and renames it, caller must hold the lock
+func (d *Dispatcher) saveState() error {
+	if d.stateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(d.notified)
+	if err != nil {
+		return errors.Wrap(err, "marshal state")
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(d.stateFile), filepath.Base(d.stateFile)+".*")
+	if err != nil {
+		return errors.Wrap(err, "create temp state file")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "write temp state file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "close temp state file")
+	}
+	return os.Rename(tmp.Name(), d.stateFile)
+}
+
+// Fingerprint returns alertmanager fingerprint of alert, or hash of its labels when absent
+func Fingerprint(alert PrometheusAlert) string {
+	if alert.Fingerprint != "" {
+		return alert.Fingerprint
+	}
+	names := make([]string, 0, len(alert.Labels))
+	for name := range alert.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := fnv.New64a()
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{0xff})
+		h.Write([]byte(alert.Labels[name]))
+		h.Write([]byte{0xff})
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func groupKey(receiver string, groupLabels map[string]string) string {
+	names := make([]string, 0, len(groupLabels))
+	for name := range groupLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, groupLabels[name]))
+	}
+	return receiver + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func stateKey(receiver, fingerprint string) string {
+	return receiver + "/" + fingerprint
+}
+
+// groupAlerts routes alerts and returns groups keyed by receiver and group labels
+func (d *Dispatcher) groupAlerts(alerts []PrometheusAlert) map[string]*alertGroup {
+	groups := make(map[string]*alertGroup)
+	for _, alert := range alerts {
+		routed := make(map[string]bool)
+		for _, route := range d.route.Match(alert.Labels) {
+			if route.Receiver == "" {
+				continue
+			}
+			groupLabels := route.GroupLabels(alert.Labels)
+			key := groupKey(route.Receiver, groupLabels)
+			// alert matched several routes of same group is sent once
+			if routed[key] {
+				continue
+			}
+			routed[key] = true
+			group, ok := groups[key]
+			if !ok {
+				group = &alertGroup{
+					route:       route,
+					groupLabels: groupLabels,
+				}
+				groups[key] = group
+			}
+			group.alerts = append(group.alerts, alert)
+		}
+	}
+	return groups
+}
+
+// filterAlerts returns alerts of group need to be notified and marks them sending,
+// caller must call markNotified with the returned alerts after notifying
+func (d *Dispatcher) filterAlerts(group *alertGroup) []PrometheusAlert {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	receiver := d.receivers[group.route.Receiver]
+	now := d.now()
+	ret := make([]PrometheusAlert, 0)
+	for _, alert := range group.alerts {
+		key := stateKey(receiver.Name, Fingerprint(alert))
+		if d.sending[key] {
+			continue
+		}
+		prev := d.notified[key]
+		switch alert.Status {
+		case AlertStatusFiring:
+			if prev != nil && prev.Status == AlertStatusFiring && now.Sub(prev.SentAt) < group.route.repeatInterval {
+				continue
+			}
+		case AlertStatusResolved:
+			if !receiver.SendResolved || prev == nil || prev.Status != AlertStatusFiring {
+				continue
+			}
+		default:
+			log.Warningf("ignore alert %s with status %q", alert.Labels["alertname"], alert.Status)
+			continue
+		}
+		d.sending[key] = true
+		ret = append(ret, alert)
+	}
+	return ret
+}
+
+// markNotified clears sending mark of alerts and records the sent ones, resolved ones are forgotten
+func (d *Dispatcher) markNotified(receiver string, alerts []PrometheusAlert, sent []PrometheusAlert) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, alert := range alerts {
+		delete(d.sending, stateKey(receiver, Fingerprint(alert)))
+	}
+	now := d.now()
+	for _, alert := range sent {
+		key := stateKey(receiver, Fingerprint(alert))
+		if alert.Status == AlertStatusResolved {
+			delete(d.notified, key)
+			continue
+		}
+		d.notified[key] = &notifyState{
+			Status: alert.Status,
+			SentAt: now,
+		}
+	}
+	for key, state := range d.notified {
+		if now.Sub(state.SentAt) > notifyStateTTL {
+			delete(d.notified, key)
+		}
+	}
+	if err := d.saveState(); err != nil {
+		log.Errorf("save notify state: %v", err)
+	}
+}
+
+func (d *Dispatcher) newMessage(receiver *Receiver, externalURL string, group *alertGroup, alerts []PrometheusAlert) (*Message, error) {
+	data := newTemplateData(receiver.Name, externalURL, group.groupLabels, alerts)
+	title, err := renderTemplate(receiver.titleTmpl, data)
+	if err != nil {
+		return nil, err
+	}
+	content, err := renderTemplate(receiver.contentTmpl, data)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{
+		TemplateData: data,
+		Title:        title,
+		Content:      content,
+	}, nil
+}
+
+// Dispatch sends alerts of request to receivers, errors of all receivers are aggregated
+func (d *Dispatcher) Dispatch(ctx context.Context, req *PrometheusRequest) error {
+	groups := d.groupAlerts(req.Alerts)
+	var (
+		errGrp errgroup.Group
+		lock   sync.Mutex
+		errs   = make([]error, 0)
+	)
+	for key := range groups {
+		key := key
+		group := groups[key]
+		alerts := d.filterAlerts(group)
+		if len(alerts) == 0 {
+			continue
+		}
+		receiver := d.receivers[group.route.Receiver]
+		errGrp.Go(func() error {
+			err := func() error {
+				var sent []PrometheusAlert
+				defer func() {
+					d.markNotified(receiver.Name, alerts, sent)
+				}()
+				msg, err := d.newMessage(receiver, req.ExternalURL, group, alerts)
+				if err != nil {
+					return err
+				}
+				log.Infof("send %d alerts of group %s", len(alerts), key)
+				if err := d.notifiers[receiver.Name].Notify(ctx, msg); err != nil {
+					if pErr, ok := errors.Cause(err).(*PartialNotifyError); ok {
+						sent = pErr.Sent
+					}
+					return err
+				}
+				sent = alerts
+				return nil
+			}()
+			if err != nil {
+				lock.Lock()
+				errs = append(errs, errors.Wrapf(err, "notify group %s", key))
+				lock.Unlock()
+			}
+			return nil
+		})
+	}
+	errGrp.Wait()
+	return errors.NewAggregate(errs)
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+func TestParseMatcher(t *testing.T) {
+	labels := map[string]string{"severity": "critical", "from": "loki"}
+	for s, want := range map[string]bool{
+		`from="loki"`:                  true,
+		`from=loki`:                    true,
+		`from!="loki"`:                 false,
+		`severity=~"critical|warning"`: true,
+		`severity=~"crit"`:             false,
+		`severity!~"info"`:             true,
+		`namespace=""`:                 true,
+	} {
+		m, err := ParseMatcher(s)
+		if err != nil {
+			t.Fatalf("ParseMatcher(%q): %v", s, err)
+		}
+		if got := m.Matches(labels); got != want {
+			t.Errorf("%s matches %v, want %v", s, got, want)
+		}
+	}
+	for _, s := range []string{"", "from", `=loki`, `severity=~"("`} {
+		if _, err := ParseMatcher(s); err == nil {
+			t.Errorf("ParseMatcher(%q) should fail", s)
+		}
+	}
+}
+
+type fakeNotifier struct {
+	msgs []*Message
+	// fail returns alerts failed to send
+	fail func(alert PrometheusAlert) bool
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, msg *Message) error {
+	f.msgs = append(f.msgs, msg)
+	if f.fail == nil {
+		return nil
+	}
+	sent := make([]PrometheusAlert, 0)
+	for _, alert := range msg.Alerts {
+		if !f.fail(alert) {
+			sent = append(sent, alert)
+		}
+	}
+	if len(sent) == len(msg.Alerts) {
+		return nil
+	}
+	return &PartialNotifyError{Sent: sent, Err: errors.Error("send failed")}
+}
+
+const testConfig = `
+route:
+  receiver: ops
+  group_by: [alertname]
+  repeat_interval: 1h
+  routes:
+  - matchers: ['severity="critical"']
+    receiver: oncall
+    continue: true
+  - matchers: ['from="loki"']
+    receiver: logs
+receivers:
+- name: ops
+  type: webhook
+  url: http://ops
+- name: oncall
+  type: webhook
+  url: http://oncall
+  send_resolved: true
+  title: '{{ .Status }} {{ .GroupLabels.alertname }}'
+- name: logs
+  type: webhook
+  url: http://logs
+`
+
+func TestDispatcher(t *testing.T) {
+	conf, err := ParseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	d, err := NewDispatcher(conf)
+	if err != nil {
+		t.Fatalf("NewDispatcher: %v", err)
+	}
+	fakes := make(map[string]*fakeNotifier)
+	for name := range d.notifiers {
+		fakes[name] = new(fakeNotifier)
+		d.notifiers[name] = fakes[name]
+	}
+	now := time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+
+	alert := func(name, status string, labels ...string) PrometheusAlert {
+		a := PrometheusAlert{Status: status, Labels: map[string]string{"alertname": name}}
+		for i := 0; i+1 < len(labels); i += 2 {
+			a.Labels[labels[i]] = labels[i+1]
+		}
+		return a
+	}
+	dispatch := func(alerts ...PrometheusAlert) {
+		if err := d.Dispatch(context.Background(), &PrometheusRequest{Alerts: alerts}); err != nil {
+			t.Fatalf("Dispatch: %v", err)
+		}
+	}
+	count := func(name string) int {
+		n := len(fakes[name].msgs)
+		fakes[name].msgs = nil
+		return n
+	}
+
+	dispatch(
+		alert("disk", AlertStatusFiring, "severity", "critical", "from", "loki", "pod", "a"),
+		alert("disk", AlertStatusFiring, "severity", "critical", "from", "loki", "pod", "b"),
+		alert("cpu", AlertStatusFiring, "severity", "warning"),
+	)
+	// critical alerts continue to logs route, grouped by alertname
+	if got := fakes["oncall"].msgs; len(got) != 1 || len(got[0].Alerts) != 2 || got[0].Title != "firing disk" {
+		t.Fatalf("oncall messages: %#v", got)
+	}
+	if n := count("oncall"); n != 1 {
+		t.Errorf("oncall got %d messages", n)
+	}
+	if n := count("logs"); n != 1 {
+		t.Errorf("logs got %d messages", n)
+	}
+	if n := count("ops"); n != 1 {
+		t.Errorf("ops got %d messages", n)
+	}
+
+	// duplicated firing alert is not sent again before repeat interval
+	dispatch(alert("cpu", AlertStatusFiring, "severity", "warning"))
+	if n := count("ops"); n != 0 {
+		t.Errorf("ops got %d duplicated messages", n)
+	}
+	now = now.Add(2 * time.Hour)
+	dispatch(alert("cpu", AlertStatusFiring, "severity", "warning"))
+	if n := count("ops"); n != 1 {
+		t.Errorf("ops got %d messages after repeat interval", n)
+	}
+
+	// resolved is only sent by receivers with send_resolved
+	dispatch(
+		alert("disk", AlertStatusResolved, "severity", "critical", "from", "loki", "pod", "a"),
+		alert("cpu", AlertStatusResolved, "severity", "warning"),
+	)
+	if n := count("oncall"); n != 1 {
+		t.Errorf("oncall got %d resolved messages", n)
+	}
+	if n := count("logs") + count("ops"); n != 0 {
+		t.Errorf("got %d resolved messages without send_resolved", n)
+	}
+	// resolved alert never notified firing is ignored
+	dispatch(alert("disk", AlertStatusResolved, "severity", "critical", "pod", "c"))
+	if n := count("oncall"); n != 0 {
+		t.Errorf("oncall got %d messages of resolved alert not notified", n)
+	}
+}
+
+func TestDispatcherPartialFailureAndState(t *testing.T) {
+	conf, err := ParseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	newDispatcher := func() (*Dispatcher, *fakeNotifier) {
+		d, err := NewDispatcher(conf)
+		if err != nil {
+			t.Fatalf("NewDispatcher: %v", err)
+		}
+		for name := range d.notifiers {
+			d.notifiers[name] = new(fakeNotifier)
+		}
+		return d, d.notifiers["ops"].(*fakeNotifier)
+	}
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	d, ops := newDispatcher()
+	if err := d.LoadState(stateFile); err != nil {
+		t.Fatalf("LoadState of missing file: %v", err)
+	}
+	alerts := []PrometheusAlert{
+		{Status: AlertStatusFiring, Labels: map[string]string{"alertname": "cpu", "pod": "a"}},
+		{Status: AlertStatusFiring, Labels: map[string]string{"alertname": "cpu", "pod": "b"}},
+	}
+	ops.fail = func(alert PrometheusAlert) bool {
+		return alert.Labels["pod"] == "b"
+	}
+	if err := d.Dispatch(context.Background(), &PrometheusRequest{Alerts: alerts}); err == nil {
+		t.Fatalf("Dispatch should fail")
+	}
+	// only the failed alert is sent again
+	ops.fail = nil
+	ops.msgs = nil
+	if err := d.Dispatch(context.Background(), &PrometheusRequest{Alerts: alerts}); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if len(ops.msgs) != 1 || len(ops.msgs[0].Alerts) != 1 || ops.msgs[0].Alerts[0].Labels["pod"] != "b" {
+		t.Fatalf("resent messages: %#v", ops.msgs)
+	}
+	if len(d.sending) != 0 {
+		t.Errorf("sending marks aren't cleared: %v", d.sending)
+	}
+
+	// notified state is restored from file
+	d, ops = newDispatcher()
+	if err := d.LoadState(stateFile); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if err := d.Dispatch(context.Background(), &PrometheusRequest{Alerts: alerts}); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if len(ops.msgs) != 0 {
+		t.Errorf("got %d duplicated messages after state restored", len(ops.msgs))
+	}
+}
+
+func TestHookConfig(t *testing.T) {
+	conf := NewHookConfig("token")
+	routes := conf.Route.Match(map[string]string{"from": "loki"})
+	if len(routes) != 1 || routes[0].Receiver != "feishu" {
+		t.Errorf("loki alert routes: %v", routes)
+	}
+	routes = conf.Route.Match(map[string]string{"from": "prometheus"})
+	if len(routes) != 1 || routes[0].Receiver != "" {
+		t.Errorf("alert not from loki should be dropped, routes: %v", routes)
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+)
+
+type MatchType string
+
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// Matcher matches alert label like alertmanager, e.g. severity=~"critical|warning"
+type Matcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+
+	re *regexp.Regexp
+}
+
+func NewMatcher(name string, typ MatchType, value string) (*Matcher, error) {
+	m := &Matcher{
+		Name:  name,
+		Type:  typ,
+		Value: value,
+	}
+	switch typ {
+	case MatchEqual, MatchNotEqual:
+	case MatchRegexp, MatchNotRegexp:
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, errors.Wrapf(err, "compile regexp %q", value)
+		}
+		m.re = re
+	default:
+		return nil, errors.Errorf("invalid match type %q", typ)
+	}
+	return m, nil
+}
+
+// ParseMatcher parses matcher string in form of name<op>value, value can be double quoted
+func ParseMatcher(s string) (*Matcher, error) {
+	s = strings.TrimSpace(s)
+	idx := strings.IndexAny(s, "=!")
+	if idx <= 0 {
+		return nil, errors.Errorf("invalid matcher %q", s)
+	}
+	name := strings.TrimSpace(s[:idx])
+	rest := s[idx:]
+	var typ MatchType
+	for _, t := range []MatchType{MatchRegexp, MatchNotRegexp, MatchNotEqual, MatchEqual} {
+		if strings.HasPrefix(rest, string(t)) {
+			typ = t
+			break
+		}
+	}
+	if typ == "" {
+		return nil, errors.Errorf("invalid operator of matcher %q", s)
+	}
+	value := strings.TrimSpace(strings.TrimPrefix(rest, string(typ)))
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		value = value[1 : len(value)-1]
+	}
+	return NewMatcher(name, typ, value)
+}
+
+func (m *Matcher) Matches(labels map[string]string) bool {
+	// absent label is matched as empty value
+	val := labels[m.Name]
+	switch m.Type {
+	case MatchEqual:
+		return val == m.Value
+	case MatchNotEqual:
+		return val != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(val)
+	case MatchNotRegexp:
+		return !m.re.MatchString(val)
+	}
+	return false
+}
+
+func (m *Matcher) String() string {
+	return fmt.Sprintf("%s%s%q", m.Name, m.Type, m.Value)
+}
@@ -2,23 +2,25 @@ package service
 
 import (
 	"bytes"
+	"strings"
 	"text/template"
 
 	"yunion.io/x/pkg/errors"
 )
 
 const (
-	tmpl = `
+	defaultTitleTemplate = `[{{ .Status | toUpper }}{{ if eq .Status "firing" }}:{{ len .Alerts }}{{ end }}] {{ .CommonLabels.alertname }}`
+
+	defaultContentTemplate = `
 {{ $var := .ExternalURL}}{{ range $k,$v:=.Alerts }}
 {{if eq $v.Status "resolved"}}
 ## [Prometheus恢复信息]({{$v.GeneratorURL}})
-#### [{{$v.labels.alertname}}]({{$var}})
-###### 告警级别：{{$v.labels.level}}
-###### 开始时间：{{$v.startsAt}}
-###### 结束时间：{{$v.endsAt}}
-###### 故障主机IP：{{$v.labels.instance}}
+#### [{{$v.Labels.alertname}}]({{$var}})
+###### 告警级别：{{$v.Labels.severity}}
+###### 开始时间：{{$v.StartsAt}}
+###### 结束时间：{{$v.EndsAt}}
+###### 故障主机IP：{{$v.Labels.instance}}
 ##### {{$v.Annotations.description}}
-![Prometheus](https://raw.githubusercontent.com/feiyu563/PrometheusAlert/master/doc/alert-center.png)
 {{else}}
 # [Prometheus告警信息]({{$v.GeneratorURL}})
 ## [{{$v.Labels.alertname}}]({{$var}})
@@ -27,23 +29,73 @@ const (
 ### pod：{{$v.Labels.namespace}}/{{$v.Labels.pod}}
 ### node：{{$v.Labels.hostname}}
 ### 描述: {{$v.Annotations.description}}
-![Prometheus](https://raw.githubusercontent.com/feiyu563/PrometheusAlert/master/doc/alert-center.png)
 {{end}}
 ---
 {{ end }}
 	`
 )
 
-func genMarkdownContent(input *PrometheusRequest) (string, error) {
-	tmp, err := template.New("").Parse(tmpl)
-	if err != nil {
-		return "", errors.Wrap(err, "parse template")
-	}
+// TemplateData is rendered by receiver title and content templates
+type TemplateData struct {
+	Receiver     string
+	Status       string
+	ExternalURL  string
+	GroupLabels  map[string]string
+	CommonLabels map[string]string
+	Alerts       []PrometheusAlert
+}
+
+var templateFuncs = template.FuncMap{
+	"toUpper": strings.ToUpper,
+	"toLower": strings.ToLower,
+	"join":    strings.Join,
+}
+
+func newTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Parse(text)
+}
 
+func renderTemplate(tmpl *template.Template, data *TemplateData) (string, error) {
 	var out bytes.Buffer
-	if err := tmp.Execute(&out, input); err != nil {
-		return "", errors.Wrap(err, "execute template")
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", errors.Wrapf(err, "execute template %s", tmpl.Name())
 	}
+	return strings.TrimSpace(out.String()), nil
+}
 
-	return out.String(), nil
+func newTemplateData(receiver, externalURL string, groupLabels map[string]string, alerts []PrometheusAlert) *TemplateData {
+	data := &TemplateData{
+		Receiver:     receiver,
+		Status:       AlertStatusResolved,
+		ExternalURL:  externalURL,
+		GroupLabels:  groupLabels,
+		CommonLabels: commonLabels(alerts),
+		Alerts:       alerts,
+	}
+	for _, alert := range alerts {
+		if alert.Status == AlertStatusFiring {
+			data.Status = AlertStatusFiring
+			break
+		}
+	}
+	return data
+}
+
+// commonLabels returns labels with same value in all alerts
+func commonLabels(alerts []PrometheusAlert) map[string]string {
+	ret := make(map[string]string)
+	if len(alerts) == 0 {
+		return ret
+	}
+	for k, v := range alerts[0].Labels {
+		ret[k] = v
+	}
+	for _, alert := range alerts[1:] {
+		for k, v := range ret {
+			if alert.Labels[k] != v {
+				delete(ret, k)
+			}
+		}
+	}
+	return ret
 }
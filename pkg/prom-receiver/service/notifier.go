@@ -0,0 +1,84 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/httputils"
+)
+
+const notifyTimeout = 15 * time.Second
+
+// Message is one notification of grouped alerts
+type Message struct {
+	*TemplateData
+
+	Title   string
+	Content string
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, msg *Message) error
+}
+
+type NotifierFactory func(r *Receiver) (Notifier, error)
+
+var notifierFactories = make(map[string]NotifierFactory)
+
+func RegisterNotifier(typ string, factory NotifierFactory) {
+	notifierFactories[typ] = factory
+}
+
+func NewNotifier(r *Receiver) (Notifier, error) {
+	factory, ok := notifierFactories[r.Type]
+	if !ok {
+		return nil, errors.Errorf("unsupported receiver type %q", r.Type)
+	}
+	return factory(r)
+}
+
+// postJSON posts body to url and returns response body, non 2xx status code is treated as error
+func postJSON(ctx context.Context, url string, body interface{}) ([]byte, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal body")
+	}
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "new request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httputils.GetDefaultClient().Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "post %s", req.URL.Host)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read response")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, errors.Errorf("post %s status %d: %s", req.URL.Host, resp.StatusCode, respBody)
+	}
+	return respBody, nil
+}
+
+// robotResponse is response of dingtalk and wecom robots
+type robotResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func (r robotResponse) err() error {
+	if r.ErrCode != 0 {
+		return errors.Errorf("response error, code: %d, msg: %s", r.ErrCode, r.ErrMsg)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+func init() {
+	RegisterNotifier(ReceiverTypeDingTalk, NewDingTalkNotifier)
+}
+
+// DingTalkNotifier sends markdown message by dingtalk custom robot webhook
+type DingTalkNotifier struct {
+	url    string
+	secret string
+}
+
+func NewDingTalkNotifier(r *Receiver) (Notifier, error) {
+	if r.URL == "" {
+		return nil, errors.Error("dingtalk webhook url is empty")
+	}
+	return &DingTalkNotifier{
+		url:    r.URL,
+		secret: r.Secret,
+	}, nil
+}
+
+func (d *DingTalkNotifier) Notify(ctx context.Context, msg *Message) error {
+	hook := d.url
+	if d.secret != "" {
+		ts := time.Now().UnixNano() / int64(time.Millisecond)
+		u, err := url.Parse(hook)
+		if err != nil {
+			return errors.Wrap(err, "parse webhook url")
+		}
+		query := u.Query()
+		query.Set("timestamp", strconv.FormatInt(ts, 10))
+		query.Set("sign", dingTalkSign(d.secret, ts))
+		u.RawQuery = query.Encode()
+		hook = u.String()
+	}
+	body := map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": msg.Title,
+			"text":  fmt.Sprintf("### %s\n\n%s", msg.Title, msg.Content),
+		},
+	}
+	out, err := postJSON(ctx, hook, body)
+	if err != nil {
+		return err
+	}
+	resp := robotResponse{}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return errors.Wrapf(err, "unmarshal response %s", out)
+	}
+	return resp.err()
+}
+
+// dingTalkSign signs request of robot with signature security setting
+func dingTalkSign(secret string, timestamp int64) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(fmt.Sprintf("%d\n%s", timestamp, secret)))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/onecloud/pkg/monitor/notifydrivers/feishu"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/httputils"
+)
+
+func init() {
+	RegisterNotifier(ReceiverTypeFeishu, NewFeishuNotifier)
+}
+
+// FeishuNotifier sends interactive cards by feishu custom robot webhook
+type FeishuNotifier struct {
+	url    string
+	secret string
+	// cardPerAlert sends builtin card of each alert when receiver has no custom template
+	cardPerAlert bool
+}
+
+func NewFeishuNotifier(r *Receiver) (Notifier, error) {
+	if r.URL == "" {
+		return nil, errors.Error("feishu webhook url is empty")
+	}
+	return &FeishuNotifier{
+		url:          r.URL,
+		secret:       r.Secret,
+		cardPerAlert: r.Template == "",
+	}, nil
+}
+
+type feishuWebhookResponse struct {
+	Code          int    `json:"code"`
+	Msg           string `json:"msg"`
+	StatusCode    int    `json:"StatusCode"`
+	StatusMessage string `json:"StatusMessage"`
+}
+
+// Notify sends one card of each alert when cardPerAlert, alerts of cards sent
+// are reported by PartialNotifyError when some cards fail
+func (fs *FeishuNotifier) Notify(ctx context.Context, msg *Message) error {
+	if !fs.cardPerAlert {
+		card, err := genCard(msg.Title, msg.Content)
+		if err != nil {
+			return errors.Wrap(err, "genCard")
+		}
+		return fs.send(ctx, card)
+	}
+	sent := make([]PrometheusAlert, 0)
+	errs := make([]error, 0)
+	for _, alert := range msg.Alerts {
+		card, err := genCardByAlert(alert)
+		if err != nil {
+			return errors.Wrapf(err, "genCardByAlert")
+		}
+		if err := fs.send(ctx, card); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sent = append(sent, alert)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &PartialNotifyError{Sent: sent, Err: errors.NewAggregate(errs)}
+}
+
+func (fs *FeishuNotifier) send(ctx context.Context, card *feishu.MsgReq) error {
+	body := jsonutils.Marshal(card).(*jsonutils.JSONDict)
+	if fs.secret != "" {
+		ts := time.Now().Unix()
+		body.Set("timestamp", jsonutils.NewString(strconv.FormatInt(ts, 10)))
+		body.Set("sign", jsonutils.NewString(feishuSign(fs.secret, ts)))
+	}
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	_, obj, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, httputils.POST, fs.url, http.Header{}, body, false)
+	if err != nil {
+		return err
+	}
+	resp := new(feishuWebhookResponse)
+	if err := obj.Unmarshal(resp); err != nil {
+		return errors.Wrap(err, "unmarshal response")
+	}
+	if resp.Code != 0 || resp.StatusCode != 0 {
+		return errors.Errorf("response error, code: %d, msg: %s%s", resp.Code+resp.StatusCode, resp.Msg, resp.StatusMessage)
+	}
+	return nil
+}
+
+// feishuSign signs request of robot with signature verification enabled
+func feishuSign(secret string, timestamp int64) string {
+	h := hmac.New(sha256.New, []byte(fmt.Sprintf("%d\n%s", timestamp, secret)))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func getCommonInfoMod(alert PrometheusAlert, labels map[string]string) feishu.CardElement {
+	starsAt := alert.StartsAt
+	utcTime, err := time.Parse(time.RFC3339Nano, starsAt)
+	if err == nil {
+		starsAt = utcTime.Local().String()
+	} else {
+		log.Errorf("parse input startsAt time %q", starsAt)
+	}
+	elem := feishu.CardElement{
+		Tag: feishu.TagDiv,
+		// Text: feishu.NewCardElementText(config.Title),
+		Fields: []*feishu.CardElementField{
+			feishu.NewCardElementTextField(false, fmt.Sprintf("**触发时间:** %s", alert.StartsAt)),
+		},
+	}
+	if alert.Status == AlertStatusResolved {
+		elem.Fields = append(elem.Fields, feishu.NewCardElementTextField(false, fmt.Sprintf("**恢复时间:** %s", alert.EndsAt)))
+	}
+	severity := labels["severity"]
+	if severity != "" {
+		field := feishu.NewCardElementTextField(false, fmt.Sprintf("**级别:** %s", severity))
+		elem.Fields = append(elem.Fields, field)
+	}
+	return elem
+}
+
+func getAlertMod(alert PrometheusAlert, labels map[string]string) *feishu.CardElement {
+	elem := feishu.CardElement{
+		Tag: feishu.TagDiv,
+		Fields: []*feishu.CardElementField{
+			feishu.NewCardElementTextField(false, fmt.Sprintf("**容器:** %s/%s/%s", labels["namespace"], labels["pod"], labels["container"])),
+		},
+	}
+
+	if hostname, ok := labels["hostname"]; ok {
+		elem.Fields = append(elem.Fields, feishu.NewCardElementTextField(false, fmt.Sprintf("**节点: ** %s", hostname)))
+	}
+
+	annotations := alert.Annotations
+
+	elem.Fields = append(elem.Fields, feishu.NewCardElementTextField(false, fmt.Sprintf("**描述: ** %s", annotations["description"])))
+	return &elem
+}
+
+func genCardByAlert(alert PrometheusAlert) (*feishu.MsgReq, error) {
+	labels := alert.Labels
+
+	commonElem := getCommonInfoMod(alert, labels)
+
+	title := labels["alertname"]
+	if alert.Status == AlertStatusResolved {
+		title = "[恢复] " + title
+	}
+	msg := &feishu.MsgReq{
+		MsgType: feishu.MsgTypeInteractive,
+		Card: &feishu.Card{
+			Config: &feishu.CardConfig{
+				WideScreenMode: false,
+			},
+			Header: &feishu.CardHeader{
+				Title: &feishu.CardHeaderTitle{
+					Tag:     feishu.TagPlainText,
+					Content: title,
+				},
+			},
+			Elements: []interface{}{
+				commonElem,
+			},
+		},
+	}
+
+	msElem := getAlertMod(alert, labels)
+	msg.Card.Elements = append(msg.Card.Elements, msElem)
+	return msg, nil
+}
+
+func genCard(title string, mdContent string) (*feishu.MsgReq, error) {
+	// 消息卡片: https://open.feishu.cn/document/ukTMukTMukTM/uYTNwUjL2UDM14iN1ATN
+	msg := &feishu.MsgReq{
+		MsgType: feishu.MsgTypeInteractive,
+		Card: &feishu.Card{
+			Config: &feishu.CardConfig{
+				WideScreenMode: false,
+			},
+			Header: &feishu.CardHeader{
+				Title: &feishu.CardHeaderTitle{
+					Tag:     feishu.TagPlainText,
+					Content: title,
+				},
+			},
+			Elements: []interface{}{
+				feishu.CardElement{
+					Tag: feishu.TagDiv,
+					Text: &feishu.CardElement{
+						Content: mdContent,
+						Tag:     feishu.TagLarkMd,
+					},
+				},
+			},
+		},
+	}
+	return msg, nil
+}
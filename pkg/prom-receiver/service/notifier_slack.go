@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/pkg/errors"
+)
+
+func init() {
+	RegisterNotifier(ReceiverTypeSlack, NewSlackNotifier)
+}
+
+// SlackNotifier sends message by slack incoming webhook
+type SlackNotifier struct {
+	url     string
+	channel string
+}
+
+func NewSlackNotifier(r *Receiver) (Notifier, error) {
+	if r.URL == "" {
+		return nil, errors.Error("slack webhook url is empty")
+	}
+	return &SlackNotifier{
+		url:     r.URL,
+		channel: r.Channel,
+	}, nil
+}
+
+func (s *SlackNotifier) Notify(ctx context.Context, msg *Message) error {
+	body := map[string]interface{}{
+		"text":   fmt.Sprintf("*%s*\n%s", msg.Title, msg.Content),
+		"mrkdwn": true,
+	}
+	if s.channel != "" {
+		body["channel"] = s.channel
+	}
+	// slack responds plain text "ok", failures are told by status code
+	_, err := postJSON(ctx, s.url, body)
+	return err
+}
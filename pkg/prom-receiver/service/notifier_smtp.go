@@ -0,0 +1,132 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+func init() {
+	RegisterNotifier(ReceiverTypeSMTP, NewSMTPNotifier)
+}
+
+// SMTPNotifier sends message as plain text email
+type SMTPNotifier struct {
+	conf *SMTPConfig
+}
+
+func NewSMTPNotifier(r *Receiver) (Notifier, error) {
+	conf := r.SMTP
+	if conf == nil || conf.Host == "" {
+		return nil, errors.Error("smtp host is empty")
+	}
+	if conf.From == "" || len(conf.To) == 0 {
+		return nil, errors.Error("smtp from and to are required")
+	}
+	if conf.Port == 0 {
+		conf.Port = 25
+		if conf.TLS {
+			conf.Port = 465
+		}
+	}
+	return &SMTPNotifier{conf: conf}, nil
+}
+
+func (s *SMTPNotifier) dial(ctx context.Context, addr string) (net.Conn, error) {
+	if s.conf.TLS {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: s.conf.Host}}
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+	dialer := &net.Dialer{}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+func (s *SMTPNotifier) Notify(ctx context.Context, msg *Message) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(s.conf.Host, strconv.Itoa(s.conf.Port))
+	var auth smtp.Auth
+	if s.conf.Username != "" {
+		auth = smtp.PlainAuth("", s.conf.Username, s.conf.Password, s.conf.Host)
+	}
+	body := s.buildMail(msg)
+
+	conn, err := s.dial(ctx, addr)
+	if err != nil {
+		return errors.Wrapf(err, "dial %s", addr)
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	// smtp conversation is aborted by closing connection when ctx is canceled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	cli, err := smtp.NewClient(conn, s.conf.Host)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "new smtp client")
+	}
+	defer cli.Close()
+	if !s.conf.TLS {
+		// STARTTLS is used when server supports it
+		if ok, _ := cli.Extension("STARTTLS"); ok {
+			if err := cli.StartTLS(&tls.Config{ServerName: s.conf.Host}); err != nil {
+				return errors.Wrap(err, "smtp starttls")
+			}
+		}
+	}
+	if auth != nil {
+		if err := cli.Auth(auth); err != nil {
+			return errors.Wrap(err, "smtp auth")
+		}
+	}
+	if err := cli.Mail(s.conf.From); err != nil {
+		return errors.Wrap(err, "smtp mail")
+	}
+	for _, to := range s.conf.To {
+		if err := cli.Rcpt(to); err != nil {
+			return errors.Wrapf(err, "smtp rcpt %s", to)
+		}
+	}
+	w, err := cli.Data()
+	if err != nil {
+		return errors.Wrap(err, "smtp data")
+	}
+	if _, err := w.Write(body); err != nil {
+		return errors.Wrap(err, "write mail")
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "close mail")
+	}
+	return cli.Quit()
+}
+
+func (s *SMTPNotifier) buildMail(msg *Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.conf.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(s.conf.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Content, "\n", "\r\n"))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"yunion.io/x/pkg/errors"
+)
+
+func init() {
+	RegisterNotifier(ReceiverTypeWebhook, NewWebhookNotifier)
+}
+
+// WebhookNotifier posts rendered message and grouped alerts as json
+type WebhookNotifier struct {
+	url string
+}
+
+type webhookBody struct {
+	Receiver     string            `json:"receiver"`
+	Status       string            `json:"status"`
+	Title        string            `json:"title"`
+	Content      string            `json:"content"`
+	ExternalURL  string            `json:"externalURL"`
+	GroupLabels  map[string]string `json:"groupLabels"`
+	CommonLabels map[string]string `json:"commonLabels"`
+	Alerts       []PrometheusAlert `json:"alerts"`
+}
+
+func NewWebhookNotifier(r *Receiver) (Notifier, error) {
+	if r.URL == "" {
+		return nil, errors.Error("webhook url is empty")
+	}
+	return &WebhookNotifier{url: r.URL}, nil
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, msg *Message) error {
+	_, err := postJSON(ctx, w.url, webhookBody{
+		Receiver:     msg.Receiver,
+		Status:       msg.Status,
+		Title:        msg.Title,
+		Content:      msg.Content,
+		ExternalURL:  msg.ExternalURL,
+		GroupLabels:  msg.GroupLabels,
+		CommonLabels: msg.CommonLabels,
+		Alerts:       msg.Alerts,
+	})
+	return err
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"yunion.io/x/pkg/errors"
+)
+
+func init() {
+	RegisterNotifier(ReceiverTypeWeCom, NewWeComNotifier)
+}
+
+// WeComNotifier sends markdown message by wecom group robot webhook
+type WeComNotifier struct {
+	url string
+}
+
+func NewWeComNotifier(r *Receiver) (Notifier, error) {
+	if r.URL == "" {
+		return nil, errors.Error("wecom webhook url is empty")
+	}
+	return &WeComNotifier{url: r.URL}, nil
+}
+
+func (w *WeComNotifier) Notify(ctx context.Context, msg *Message) error {
+	body := map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"content": fmt.Sprintf("**%s**\n%s", msg.Title, msg.Content),
+		},
+	}
+	out, err := postJSON(ctx, w.url, body)
+	if err != nil {
+		return err
+	}
+	resp := robotResponse{}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return errors.Wrapf(err, "unmarshal response %s", out)
+	}
+	return resp.err()
+}
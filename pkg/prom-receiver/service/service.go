@@ -2,239 +2,84 @@ package service
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"os"
-	"time"
-
-	"golang.org/x/sync/errgroup"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/onecloud/pkg/appsrv"
 	appcommon "yunion.io/x/onecloud/pkg/cloudcommon/app"
 	"yunion.io/x/onecloud/pkg/cloudcommon/options"
-	"yunion.io/x/onecloud/pkg/monitor/notifydrivers/feishu"
+	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/pkg/errors"
-	"yunion.io/x/pkg/util/httputils"
 )
 
 var (
-	Options SOptions
-	fsCli   *FeishuNotifier
+	Options    SOptions
+	dispatcher *Dispatcher
 )
 
 type SOptions struct {
 	options.BaseOptions
 
-	AppId     string
-	AppSecret string
-	Hook      string
+	// Hook is token of feishu robot used when NotifyConfig is not set
+	Hook         string
+	NotifyConfig string `help:"Path of receivers and routing rules config file"`
+	// NotifyStateFile keeps notified alerts across restarts to avoid duplicated notifications
+	NotifyStateFile string `help:"Path of file persisting notified alerts state, state is kept in memory when it's empty"`
 }
 
 func StartService() {
 	opts := &Options
 	options.ParseOptions(opts, os.Args, "prom-receiver.conf", "prom-receiver")
 
-	app := appcommon.InitApp(&opts.BaseOptions, true)
-	initHandler(app)
-	/*
-	 * fsCli, err = NewFeishuNotifier(opts.AppId, opts.AppSecret)
-	 * if err != nil {
-	 *     log.Fatalf("new feishu client error: %v", err)
-	 * }
-	 */
-	appcommon.ServeForeverWithCleanup(app, &opts.BaseOptions, func() {})
-}
-
-func initHandler(app *appsrv.Application) {
-	app.AddHandler("POST", "/prometheus/alert", processPromAlert)
-}
-
-func processPromAlert(ctx context.Context, rw http.ResponseWriter, r *http.Request) {
-	_, _, body := appsrv.FetchEnv(ctx, rw, r)
-
-	if err := FsSend(ctx, Options.Hook, body); err != nil {
-		log.Errorf("FsSend error: %v", err)
-	}
-
-	appsrv.SendJSON(rw, jsonutils.JSONTrue)
-}
-
-type FeishuNotifier struct {
-	Client  *feishu.Tenant
-	ChatIds []string
-}
-
-func NewFeishuNotifier(appId, appSecret string) (*FeishuNotifier, error) {
-	cli, err := feishu.NewTenant(appId, appSecret)
+	conf, err := loadNotifyConfig(opts)
 	if err != nil {
-		return nil, errors.Wrap(err, "new feishu client")
+		log.Fatalf("load notify config: %v", err)
 	}
-	ret, err := cli.ChatList(0, "")
+	dispatcher, err = NewDispatcher(conf)
 	if err != nil {
-		return nil, err
-	}
-	chatIds := make([]string, 0)
-	for _, obj := range ret.Data.Groups {
-		chatIds = append(chatIds, obj.ChatId)
-	}
-	return &FeishuNotifier{
-		Client:  cli,
-		ChatIds: chatIds,
-	}, nil
-}
-
-func FsSend(ctx context.Context, hook string, body jsonutils.JSONObject) error {
-	// url := feishu.ApiWebhookRobotSendMessage + hook
-	url := "https://open.feishu.cn/open-apis/bot/v2/hook/" + hook
-
-	req := new(PrometheusRequest)
-	if err := body.Unmarshal(req); err != nil {
-		return errors.Wrapf(err, "unmarshal to prometheus request: %v", err)
+		log.Fatalf("new dispatcher: %v", err)
 	}
-
-	errGrp := errgroup.Group{}
-	for idx := range req.Alerts {
-		alert := req.Alerts[idx]
-		if from := alert.Labels["from"]; from != "loki" {
-			log.Infof("--ignore alert %s not from loki", alert.Labels["alertname"])
-			continue
+	if opts.NotifyStateFile != "" {
+		if err := dispatcher.LoadState(opts.NotifyStateFile); err != nil {
+			log.Fatalf("load notify state: %v", err)
 		}
-		if alert.Status != "firing" {
-			log.Infof("--ignore alert %s status %q", alert.Labels["alertname"], alert.Status)
-			log.Warningf("ignored alert %s", jsonutils.Marshal(alert))
-			continue
-		}
-		errGrp.Go(func() error {
-			log.Debugf("==starting send alert %s", jsonutils.Marshal(alert))
-			card, err := genCardByAlert(alert)
-			if err != nil {
-				return errors.Wrapf(err, "genCardByAlert")
-			}
-			obj, err := feishu.Request(httputils.POST, url, http.Header{}, jsonutils.Marshal(card))
-			if err != nil {
-				return err
-			}
-			resp := new(feishu.WebhookRobotMsgResp)
-			err = obj.Unmarshal(resp)
-			if !resp.Ok {
-				return errors.Errorf("response error, msg: %s", resp.Error)
-			}
-			return err
-		})
 	}
-	return errGrp.Wait()
-}
 
-func (fs *FeishuNotifier) Send(ctx context.Context, body jsonutils.JSONObject) error {
-	log.Infof("Sending alert notification to feishu")
-	errGrp := errgroup.Group{}
-	for _, cId := range fs.ChatIds {
-		errGrp.Go(func() error {
-			msg, err := genCard(cId, body.PrettyString())
-			if err != nil {
-				return err
-			}
-			if _, err := fs.Client.SendMessage(*msg); err != nil {
-				log.Errorf("--feishu send msg error: %s, error: %v", jsonutils.Marshal(msg), err)
-				return err
-			}
-			log.Errorf("--feishu send msg: %s", jsonutils.Marshal(msg))
-			return nil
-		})
-	}
-	return errGrp.Wait()
+	app := appcommon.InitApp(&opts.BaseOptions, true)
+	initHandler(app)
+	appcommon.ServeForeverWithCleanup(app, &opts.BaseOptions, func() {})
 }
 
-func getCommonInfoMod(alert PrometheusAlert, labels map[string]string) feishu.CardElement {
-	starsAt := alert.StartsAt
-	utcTime, err := time.Parse(time.RFC3339Nano, starsAt)
-	if err == nil {
-		starsAt = utcTime.Local().String()
-	} else {
-		log.Errorf("parse input startsAt time %q", starsAt)
-	}
-	elem := feishu.CardElement{
-		Tag: feishu.TagDiv,
-		// Text: feishu.NewCardElementText(config.Title),
-		Fields: []*feishu.CardElementField{
-			feishu.NewCardElementTextField(false, fmt.Sprintf("**触发时间:** %s", alert.StartsAt)),
-		},
+func loadNotifyConfig(opts *SOptions) (*Config, error) {
+	if opts.NotifyConfig != "" {
+		return LoadConfig(opts.NotifyConfig)
 	}
-	severity := labels["severity"]
-	if severity != "" {
-		field := feishu.NewCardElementTextField(false, fmt.Sprintf("**级别:** %s", severity))
-		elem.Fields = append(elem.Fields, field)
+	if opts.Hook == "" {
+		return nil, errors.Error("neither notify_config nor hook is set")
 	}
-	return elem
+	return NewHookConfig(opts.Hook), nil
 }
 
-func getAlertMod(alert PrometheusAlert, labels map[string]string) *feishu.CardElement {
-	elem := feishu.CardElement{
-		Tag: feishu.TagDiv,
-		Fields: []*feishu.CardElementField{
-			feishu.NewCardElementTextField(false, fmt.Sprintf("**容器:** %s/%s/%s", labels["namespace"], labels["pod"], labels["container"])),
-		},
-	}
-
-	if hostname, ok := labels["hostname"]; ok {
-		elem.Fields = append(elem.Fields, feishu.NewCardElementTextField(false, fmt.Sprintf("**节点: ** %s", hostname)))
-	}
-
-	annotations := alert.Annotations
-
-	elem.Fields = append(elem.Fields, feishu.NewCardElementTextField(false, fmt.Sprintf("**描述: ** %s", annotations["description"])))
-	return &elem
+func initHandler(app *appsrv.Application) {
+	app.AddHandler("POST", "/prometheus/alert", processPromAlert)
 }
 
-func genCardByAlert(alert PrometheusAlert) (*feishu.MsgReq, error) {
-	labels := alert.Labels
-
-	commonElem := getCommonInfoMod(alert, labels)
+func processPromAlert(ctx context.Context, rw http.ResponseWriter, r *http.Request) {
+	_, _, body := appsrv.FetchEnv(ctx, rw, r)
 
-	msg := &feishu.MsgReq{
-		MsgType: feishu.MsgTypeInteractive,
-		Card: &feishu.Card{
-			Config: &feishu.CardConfig{
-				WideScreenMode: false,
-			},
-			Header: &feishu.CardHeader{
-				Title: &feishu.CardHeaderTitle{
-					Tag:     feishu.TagPlainText,
-					Content: labels["alertname"],
-				},
-			},
-			Elements: []interface{}{
-				commonElem,
-			},
-		},
+	req := new(PrometheusRequest)
+	if err := body.Unmarshal(req); err != nil {
+		httperrors.InputParameterError(ctx, rw, "unmarshal to prometheus request: %v", err)
+		return
 	}
-
-	msElem := getAlertMod(alert, labels)
-	msg.Card.Elements = append(msg.Card.Elements, msElem)
-	return msg, nil
-}
-
-func genCard(chatId string, mdContent string) (*feishu.MsgReq, error) {
-	// 消息卡片: https://open.feishu.cn/document/ukTMukTMukTM/uYTNwUjL2UDM14iN1ATN
-	msg := &feishu.MsgReq{
-		ChatId:  chatId,
-		MsgType: feishu.MsgTypeInteractive,
-		Card: &feishu.Card{
-			Config: &feishu.CardConfig{
-				WideScreenMode: false,
-			},
-			Elements: []interface{}{
-				feishu.CardElement{
-					Tag: feishu.TagDiv,
-					Text: &feishu.CardElement{
-						Content: mdContent,
-						Tag:     feishu.TagLarkMd,
-					},
-				},
-			},
-		},
+	// alertmanager retries notification on 5xx response
+	if err := dispatcher.Dispatch(ctx, req); err != nil {
+		log.Errorf("Dispatch error: %v", err)
+		httperrors.GeneralServerError(ctx, rw, err)
+		return
 	}
-	return msg, nil
+
+	appsrv.SendJSON(rw, jsonutils.JSONTrue)
 }
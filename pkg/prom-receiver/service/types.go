@@ -1,9 +1,19 @@
 package service
 
+const (
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+)
+
+// PrometheusRequest is the alertmanager webhook payload
 type PrometheusRequest struct {
-	Status      string            `json:"status"`
-	ExternalURL string            `json:"externalURL"`
-	Alerts      []PrometheusAlert `json:"alert"`
+	Receiver          string            `json:"receiver"`
+	Status            string            `json:"status"`
+	ExternalURL       string            `json:"externalURL"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	Alerts            []PrometheusAlert `json:"alerts"`
 }
 
 type PrometheusAlert struct {
@@ -13,4 +23,5 @@ type PrometheusAlert struct {
 	StartsAt     string            `json:"startsAt"`
 	EndsAt       string            `json:"endsAt"`
 	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
 }
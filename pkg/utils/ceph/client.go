@@ -2,6 +2,7 @@ package ceph
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -106,3 +107,32 @@ func (c *Client) ListPoolsNoDefault() ([]string, error) {
 	}
 	return ret, nil
 }
+
+// CephFS is a filesystem listed by 'ceph fs ls'
+type CephFS struct {
+	Name         string   `json:"name"`
+	MetadataPool string   `json:"metadata_pool"`
+	DataPools    []string `json:"data_pools"`
+}
+
+func (c *Client) ListFilesystems() ([]CephFS, error) {
+	ret, err := c.withCluster(func(conn *rados.Conn) (i interface{}, err error) {
+		cmd, err := json.Marshal(map[string]string{"prefix": "fs ls", "format": "json"})
+		if err != nil {
+			return nil, err
+		}
+		buf, info, err := conn.MonCommand(cmd)
+		if err != nil {
+			return nil, errors.Wrapf(err, "mon command 'fs ls': %s", info)
+		}
+		fss := make([]CephFS, 0)
+		if err := json.Unmarshal(buf, &fss); err != nil {
+			return nil, errors.Wrap(err, "unmarshal 'fs ls' output")
+		}
+		return fss, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret.([]CephFS), nil
+}
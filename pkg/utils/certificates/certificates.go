@@ -31,6 +31,7 @@ import (
 	"strings"
 	"time"
 
+	"k8s.io/client-go/tools/clientcmd"
 	kapi "k8s.io/client-go/tools/clientcmd/api"
 
 	"yunion.io/x/log"
@@ -252,6 +253,49 @@ func DecodeCertPEM(encoded []byte) (*x509.Certificate, error) {
 	return x509.ParseCertificate(block.Bytes)
 }
 
+// ParseCertificates returns certificates of PEM encoded bundle, client certificates
+// are returned when data is a kubeconfig
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0)
+	hasBlock := false
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		hasBlock = true
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse certificate")
+		}
+		certs = append(certs, cert)
+	}
+	if hasBlock {
+		return certs, nil
+	}
+
+	cfg, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "neither PEM nor kubeconfig")
+	}
+	for _, auth := range cfg.AuthInfos {
+		if len(auth.ClientCertificateData) == 0 {
+			continue
+		}
+		authCerts, err := ParseCertificates(auth.ClientCertificateData)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse client certificate data")
+		}
+		certs = append(certs, authCerts...)
+	}
+	return certs, nil
+}
+
 // DecodePrivateKeyPEM attempts to return a decoded key or nil
 // if the encoded input does not contain a private key.
 func DecodePrivateKeyPEM(encoded []byte) (*rsa.PrivateKey, error) {
@@ -0,0 +1,291 @@
+package etcdsnapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/seclib"
+
+	"yunion.io/x/kubecomps/pkg/utils/ssh"
+)
+
+const (
+	// KubesprayClusterToken is the initial cluster token of etcd deployed by kubespray
+	KubesprayClusterToken = "k8s_etcd"
+)
+
+// Executor runs shell command on an etcd member
+type Executor interface {
+	Run(cmd string, stdin io.Reader, stdout io.Writer) error
+}
+
+type sshExecutor struct {
+	host       string
+	port       int
+	username   string
+	password   string
+	privateKey string
+}
+
+func NewSSHExecutor(host string, port int, username, password, privateKey string) Executor {
+	return &sshExecutor{
+		host:       host,
+		port:       port,
+		username:   username,
+		password:   password,
+		privateKey: privateKey,
+	}
+}
+
+func (e *sshExecutor) Run(cmd string, stdin io.Reader, stdout io.Writer) error {
+	return ssh.RemoteSSHCommandStream(e.host, e.port, e.username, e.password, e.privateKey, cmd, stdin, stdout)
+}
+
+type localExecutor struct{}
+
+// NewLocalExecutor runs command on local machine, it's used to work with a local etcd
+func NewLocalExecutor() Executor {
+	return localExecutor{}
+}
+
+func (e localExecutor) Run(cmd string, stdin io.Reader, stdout io.Writer) error {
+	stderr := new(bytes.Buffer)
+	c := exec.Command("bash", "-c", cmd)
+	c.Stdin = stdin
+	c.Stdout = stdout
+	c.Stderr = stderr
+	if err := c.Run(); err != nil {
+		return errors.Wrapf(err, "run %q: %s", cmd, stderr.String())
+	}
+	return nil
+}
+
+type Config struct {
+	Etcdctl   string
+	Endpoints string
+	CACert    string
+	Cert      string
+	Key       string
+	DataDir   string
+	// EnvFile is the environment file etcd service started with
+	EnvFile string
+	// Service is the systemd unit of etcd, stop and start are skipped when it's empty
+	Service string
+	// Sudo prefixes privileged commands with sudo
+	Sudo bool
+}
+
+// NewKubesprayConfig returns config of etcd member deployed by kubespray
+func NewKubesprayConfig(hostname string, sudo bool) *Config {
+	return &Config{
+		Etcdctl:   "/usr/local/bin/etcdctl",
+		Endpoints: "https://127.0.0.1:2379",
+		CACert:    "/etc/ssl/etcd/ssl/ca.pem",
+		Cert:      fmt.Sprintf("/etc/ssl/etcd/ssl/admin-%s.pem", hostname),
+		Key:       fmt.Sprintf("/etc/ssl/etcd/ssl/admin-%s-key.pem", hostname),
+		DataDir:   "/var/lib/etcd",
+		EnvFile:   "/etc/etcd.env",
+		Service:   "etcd",
+		Sudo:      sudo,
+	}
+}
+
+func (c Config) sudo(cmd string) string {
+	if c.Sudo {
+		return "sudo " + cmd
+	}
+	return cmd
+}
+
+func (c Config) etcdctl(args ...string) string {
+	cmd := []string{"ETCDCTL_API=3", shellQuote(c.Etcdctl), "--endpoints", shellQuote(c.Endpoints)}
+	for _, flag := range [][2]string{
+		{"--cacert", c.CACert},
+		{"--cert", c.Cert},
+		{"--key", c.Key},
+	} {
+		if flag[1] != "" {
+			cmd = append(cmd, flag[0], shellQuote(flag[1]))
+		}
+	}
+	for _, arg := range args {
+		cmd = append(cmd, shellQuote(arg))
+	}
+	return c.sudo(strings.Join(cmd, " "))
+}
+
+func (c Config) restoreDataDir() string {
+	return c.DataDir + ".restore"
+}
+
+// Member is an etcd cluster member
+type Member struct {
+	Name     string
+	PeerURL  string
+	Config   *Config
+	Executor Executor
+}
+
+// InitialCluster returns value of etcd `--initial-cluster` flag
+func InitialCluster(members []*Member) string {
+	peers := make([]string, len(members))
+	for i, m := range members {
+		peers[i] = fmt.Sprintf("%s=%s", m.Name, m.PeerURL)
+	}
+	return strings.Join(peers, ",")
+}
+
+// LoadEnvironment reads etcd environment file of member, member name, peer url
+// and data dir are overridden by the values etcd is really running with
+func LoadEnvironment(m *Member) (map[string]string, error) {
+	if m.Config.EnvFile == "" {
+		return map[string]string{}, nil
+	}
+	out := new(bytes.Buffer)
+	if err := m.Executor.Run(m.Config.sudo("cat "+shellQuote(m.Config.EnvFile)), nil, out); err != nil {
+		return nil, errors.Wrapf(err, "read environment of member %s", m.Name)
+	}
+	env := parseEnvironment(out.String())
+	if val := env["ETCD_NAME"]; val != "" {
+		m.Name = val
+	}
+	if val := env["ETCD_INITIAL_ADVERTISE_PEER_URLS"]; val != "" {
+		m.PeerURL = strings.Split(val, ",")[0]
+	}
+	if val := env["ETCD_DATA_DIR"]; val != "" {
+		m.Config.DataDir = val
+	}
+	return env, nil
+}
+
+func parseEnvironment(content string) map[string]string {
+	env := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		env[strings.TrimSpace(parts[0])] = strings.Trim(strings.TrimSpace(parts[1]), `"'`)
+	}
+	return env
+}
+
+func tempSnapshotFile() string {
+	return fmt.Sprintf("/tmp/etcd-snapshot-%s.db", strings.ToLower(seclib.RandomPassword(8)))
+}
+
+// Save takes a snapshot on member and writes its content to w
+func Save(m *Member, w io.Writer) error {
+	conf := m.Config
+	tmp := tempSnapshotFile()
+	cmd := fmt.Sprintf("%s >&2 && %s; ret=$?; %s; exit $ret",
+		conf.etcdctl("snapshot", "save", tmp),
+		conf.sudo("cat "+tmp),
+		conf.sudo("rm -f "+tmp))
+	if err := m.Executor.Run(cmd, nil, w); err != nil {
+		return errors.Wrapf(err, "save snapshot on member %s", m.Name)
+	}
+	return nil
+}
+
+// PrepareRestore restores snapshot read from r to a new data directory on member,
+// the running etcd is not touched until SwapDataDir is called
+func PrepareRestore(m *Member, initialCluster, clusterToken string, r io.Reader) error {
+	conf := m.Config
+	tmp := tempSnapshotFile()
+	restoreDir := conf.restoreDataDir()
+	cmd := strings.Join([]string{
+		"set -e",
+		fmt.Sprintf("trap 'rm -f %s' EXIT", tmp),
+		fmt.Sprintf("(umask 077; cat > %s)", tmp),
+		conf.sudo("rm -rf " + shellQuote(restoreDir)),
+		conf.etcdctl("snapshot", "restore", tmp,
+			"--name", m.Name,
+			"--initial-cluster", initialCluster,
+			"--initial-cluster-token", clusterToken,
+			"--initial-advertise-peer-urls", m.PeerURL,
+			"--data-dir", restoreDir) + " >&2",
+	}, "\n")
+	if err := m.Executor.Run(cmd, r, nil); err != nil {
+		return errors.Wrapf(err, "prepare restore on member %s", m.Name)
+	}
+	return nil
+}
+
+// Stop stops etcd service of member
+func Stop(m *Member) error {
+	if m.Config.Service == "" {
+		return nil
+	}
+	cmd := m.Config.sudo("systemctl stop " + shellQuote(m.Config.Service))
+	if err := m.Executor.Run(cmd, nil, nil); err != nil {
+		return errors.Wrapf(err, "stop etcd on member %s", m.Name)
+	}
+	return nil
+}
+
+// Start starts etcd service of member without waiting, a restored member
+// can't become ready until a quorum of members is started
+func Start(m *Member) error {
+	if m.Config.Service == "" {
+		return nil
+	}
+	cmd := m.Config.sudo("systemctl start --no-block " + shellQuote(m.Config.Service))
+	if err := m.Executor.Run(cmd, nil, nil); err != nil {
+		return errors.Wrapf(err, "start etcd on member %s", m.Name)
+	}
+	return nil
+}
+
+// SwapDataDir moves the current data directory to a backup one suffixed by
+// backupSuffix and replaces it with the prepared restore directory
+func SwapDataDir(m *Member, backupSuffix string) error {
+	conf := m.Config
+	dataDir := shellQuote(conf.DataDir)
+	cmd := strings.Join([]string{
+		"set -e",
+		conf.sudo(fmt.Sprintf("test -d %s", shellQuote(conf.restoreDataDir()))),
+		fmt.Sprintf("if %s; then %s; fi",
+			conf.sudo("test -e "+dataDir),
+			conf.sudo(fmt.Sprintf("mv %s %s", dataDir, shellQuote(conf.DataDir+"."+backupSuffix)))),
+		conf.sudo(fmt.Sprintf("mv %s %s", shellQuote(conf.restoreDataDir()), dataDir)),
+	}, "\n")
+	if err := m.Executor.Run(cmd, nil, nil); err != nil {
+		return errors.Wrapf(err, "swap data dir on member %s", m.Name)
+	}
+	return nil
+}
+
+// RevertDataDir moves the data directory backed up by SwapDataDir back,
+// it's a no-op when the backup directory doesn't exist
+func RevertDataDir(m *Member, backupSuffix string) error {
+	conf := m.Config
+	dataDir := shellQuote(conf.DataDir)
+	backupDir := shellQuote(conf.DataDir + "." + backupSuffix)
+	cmd := strings.Join([]string{
+		"set -e",
+		fmt.Sprintf("if %s; then %s; %s; fi",
+			conf.sudo("test -e "+backupDir),
+			conf.sudo("rm -rf "+dataDir),
+			conf.sudo(fmt.Sprintf("mv %s %s", backupDir, dataDir))),
+	}, "\n")
+	if err := m.Executor.Run(cmd, nil, nil); err != nil {
+		return errors.Wrapf(err, "revert data dir on member %s", m.Name)
+	}
+	return nil
+}
+
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=,") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
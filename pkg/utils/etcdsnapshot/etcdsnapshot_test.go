@@ -0,0 +1,185 @@
+package etcdsnapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"yunion.io/x/kubecomps/pkg/utils/objstore/s3"
+)
+
+func TestParseEnvironment(t *testing.T) {
+	env := parseEnvironment(`# etcd
+ETCD_DATA_DIR=/var/lib/etcd
+ETCD_NAME=etcd1
+ETCD_INITIAL_ADVERTISE_PEER_URLS=https://10.0.0.1:2380
+ETCD_INITIAL_CLUSTER_TOKEN="k8s_etcd"
+INVALID
+`)
+	for key, val := range map[string]string{
+		"ETCD_DATA_DIR":                    "/var/lib/etcd",
+		"ETCD_NAME":                        "etcd1",
+		"ETCD_INITIAL_ADVERTISE_PEER_URLS": "https://10.0.0.1:2380",
+		"ETCD_INITIAL_CLUSTER_TOKEN":       "k8s_etcd",
+	} {
+		if env[key] != val {
+			t.Errorf("%s: got %q, want %q", key, env[key], val)
+		}
+	}
+	if len(env) != 4 {
+		t.Errorf("unexpected env %v", env)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"/var/lib/etcd":                 "/var/lib/etcd",
+		"etcd1=https://10.0.0.1:2380":   "etcd1=https://10.0.0.1:2380",
+		"a b":                           "'a b'",
+		"it's":                          `'it'\''s'`,
+		"":                              "''",
+		"$(rm -rf /)":                   "'$(rm -rf /)'",
+		"https://127.0.0.1:2379,http:/": "https://127.0.0.1:2379,http:/",
+	} {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestLocalSaveRestore runs against a local etcd, e.g.
+//
+//	ETCD_TEST_ENDPOINTS=http://127.0.0.1:2379 go test ./pkg/utils/etcdsnapshot/
+//
+// the snapshot is uploaded to and downloaded from a local MinIO when MINIO_TEST_ENDPOINT,
+// MINIO_TEST_ACCESS_KEY, MINIO_TEST_SECRET_KEY and MINIO_TEST_BUCKET are set
+func TestLocalSaveRestore(t *testing.T) {
+	endpoints := os.Getenv("ETCD_TEST_ENDPOINTS")
+	if endpoints == "" {
+		t.Skip("ETCD_TEST_ENDPOINTS not set")
+	}
+	etcdctl := os.Getenv("ETCD_TEST_ETCDCTL")
+	if etcdctl == "" {
+		etcdctl = "etcdctl"
+	}
+	dataDir := filepath.Join(t.TempDir(), "etcd")
+	m := &Member{
+		Name:    "default",
+		PeerURL: "http://localhost:2380",
+		Config: &Config{
+			Etcdctl:   etcdctl,
+			Endpoints: endpoints,
+			DataDir:   dataDir,
+		},
+		Executor: NewLocalExecutor(),
+	}
+
+	snapshot := new(bytes.Buffer)
+	if err := Save(m, snapshot); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if snapshot.Len() == 0 {
+		t.Fatalf("empty snapshot")
+	}
+
+	var content io.Reader = snapshot
+	if minioEndpoint := os.Getenv("MINIO_TEST_ENDPOINT"); minioEndpoint != "" {
+		content = roundTripMinio(t, minioEndpoint, snapshot.Bytes())
+	}
+
+	if err := PrepareRestore(m, InitialCluster([]*Member{m}), KubesprayClusterToken, content); err != nil {
+		t.Fatalf("PrepareRestore: %v", err)
+	}
+	if err := SwapDataDir(m, "bak"); err != nil {
+		t.Fatalf("SwapDataDir: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "member", "snap", "db")); err != nil {
+		t.Errorf("restored data dir: %v", err)
+	}
+}
+
+func TestSwapAndRevertDataDir(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "etcd")
+	m := &Member{
+		Name:     "default",
+		Config:   &Config{DataDir: dataDir},
+		Executor: NewLocalExecutor(),
+	}
+	for dir, content := range map[string]string{dataDir: "old", m.Config.restoreDataDir(): "new"} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "db"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readDB := func() string {
+		content, err := os.ReadFile(filepath.Join(dataDir, "db"))
+		if err != nil {
+			t.Fatalf("read db: %v", err)
+		}
+		return string(content)
+	}
+
+	if err := SwapDataDir(m, "bak"); err != nil {
+		t.Fatalf("SwapDataDir: %v", err)
+	}
+	if got := readDB(); got != "new" {
+		t.Errorf("swapped db = %q, want new", got)
+	}
+	if err := RevertDataDir(m, "bak"); err != nil {
+		t.Fatalf("RevertDataDir: %v", err)
+	}
+	if got := readDB(); got != "old" {
+		t.Errorf("reverted db = %q, want old", got)
+	}
+	// nothing to revert
+	if err := RevertDataDir(m, "bak"); err != nil {
+		t.Fatalf("RevertDataDir again: %v", err)
+	}
+	if got := readDB(); got != "old" {
+		t.Errorf("db = %q after second revert, want old", got)
+	}
+}
+
+func roundTripMinio(t *testing.T, endpoint string, content []byte) io.Reader {
+	ctx := context.Background()
+	cli, err := s3.NewClient(&s3.Config{
+		Endpoint:  endpoint,
+		AccessKey: os.Getenv("MINIO_TEST_ACCESS_KEY"),
+		SecretKey: os.Getenv("MINIO_TEST_SECRET_KEY"),
+	})
+	if err != nil {
+		t.Fatalf("new minio client: %v", err)
+	}
+	bucket := os.Getenv("MINIO_TEST_BUCKET")
+	key := fmt.Sprintf("etcdsnapshot-test/%d.db", time.Now().UnixNano())
+	size, _, err := cli.PutStream(ctx, bucket, key, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("PutStream: %v", err)
+	}
+	defer cli.RemoveObject(ctx, bucket, key, minio.RemoveObjectOptions{})
+	if size != int64(len(content)) {
+		t.Fatalf("uploaded size %d, want %d", size, len(content))
+	}
+	obj, err := cli.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	defer obj.Close()
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		t.Fatalf("read object: %v", err)
+	}
+	return bytes.NewReader(data)
+}
@@ -0,0 +1,222 @@
+package gitrepo
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+)
+
+// GitCommandTimeout limits every git command, e.g. clone of unreachable remote
+const GitCommandTimeout = 5 * time.Minute
+
+var (
+	// AllowedSchemes are url schemes of remote repository accepted by ValidateRemote
+	AllowedSchemes = []string{"https", "ssh", "git"}
+	// scpLikeUrl is ssh url like git@github.com:org/repo.git
+	scpLikeUrl = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:`)
+)
+
+// validateArgs rejects url and branch which would be parsed as git options
+func validateArgs(remoteUrl, branch string) error {
+	if strings.HasPrefix(remoteUrl, "-") {
+		return errors.Errorf("url %q should not start with -", remoteUrl)
+	}
+	if strings.HasPrefix(branch, "-") {
+		return errors.Errorf("branch %q should not start with -", branch)
+	}
+	return nil
+}
+
+// LocalScheme is url scheme of repository on local filesystem
+const LocalScheme = "file"
+
+// ValidateRemote checks url and branch of remote repository given by user,
+// only https, ssh and git schemes are allowed so local repositories can't be read,
+// file scheme is accepted as well when allowLocal is true
+func ValidateRemote(remoteUrl, branch string, allowLocal bool) error {
+	if err := validateArgs(remoteUrl, branch); err != nil {
+		return err
+	}
+	if !strings.Contains(remoteUrl, "://") {
+		if scpLikeUrl.MatchString(remoteUrl) {
+			return nil
+		}
+		return errors.Errorf("url %q is not a remote repository", remoteUrl)
+	}
+	u, err := url.Parse(remoteUrl)
+	if err != nil {
+		return errors.Wrapf(err, "parse url %q", remoteUrl)
+	}
+	if u.Scheme == LocalScheme {
+		if !allowLocal {
+			return errors.Errorf("local repository %q is not allowed", remoteUrl)
+		}
+		if u.Path == "" {
+			return errors.Errorf("path of url %q is empty", remoteUrl)
+		}
+		return nil
+	}
+	allowed := false
+	for _, scheme := range AllowedSchemes {
+		if u.Scheme == scheme {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return errors.Errorf("url scheme %q not in %v", u.Scheme, AllowedSchemes)
+	}
+	if u.Host == "" {
+		return errors.Errorf("host of url %q is empty", remoteUrl)
+	}
+	return nil
+}
+
+// Checkout is a local working copy tracking one branch of remote repository
+type Checkout struct {
+	Url    string
+	Branch string
+	Dir    string
+
+	// Username and Password are sent as http basic auth header, so they never appear in url or arguments
+	Username string
+	Password string
+}
+
+func NewCheckout(url, branch, dir string) *Checkout {
+	return &Checkout{
+		Url:    url,
+		Branch: branch,
+		Dir:    filepath.Clean(dir),
+	}
+}
+
+func (c *Checkout) git(ctx context.Context, dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, GitCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// never wait for credentials input
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if c.Username != "" || c.Password != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Password))
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			fmt.Sprintf("GIT_CONFIG_VALUE_0=Authorization: Basic %s", auth),
+		)
+	}
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func (c *Checkout) isCloned() bool {
+	_, err := os.Stat(filepath.Join(c.Dir, ".git"))
+	return err == nil
+}
+
+// Sync fetches latest commit of branch and resets working copy to it, returns commit sha
+func (c *Checkout) Sync(ctx context.Context) (string, error) {
+	if err := validateArgs(c.Url, c.Branch); err != nil {
+		return "", err
+	}
+	if !c.isCloned() {
+		if err := os.MkdirAll(filepath.Dir(c.Dir), 0755); err != nil {
+			return "", errors.Wrapf(err, "mkdir %s", filepath.Dir(c.Dir))
+		}
+		if err := os.RemoveAll(c.Dir); err != nil {
+			return "", errors.Wrapf(err, "remove %s", c.Dir)
+		}
+		log.Infof("clone %s branch %s to %s", c.Url, c.Branch, c.Dir)
+		if _, err := c.git(ctx, filepath.Dir(c.Dir), "clone", "--single-branch", "--branch", c.Branch, "--", c.Url, c.Dir); err != nil {
+			return "", err
+		}
+	} else {
+		if _, err := c.git(ctx, c.Dir, "fetch", "--prune", "--", c.Url, c.Branch); err != nil {
+			return "", err
+		}
+		if _, err := c.git(ctx, c.Dir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+		if _, err := c.git(ctx, c.Dir, "clean", "-fdx"); err != nil {
+			return "", err
+		}
+	}
+	return c.Head(ctx)
+}
+
+// Head returns commit sha of working copy
+func (c *Checkout) Head(ctx context.Context) (string, error) {
+	return c.git(ctx, c.Dir, "rev-parse", "HEAD")
+}
+
+// Path returns local path of file inside working copy, path escaping working copy is rejected,
+// so is working copy containing symlink which points out of it
+func (c *Checkout) Path(relPath string) (string, error) {
+	p := filepath.Join(c.Dir, filepath.Clean("/"+relPath))
+	if !isSubPath(c.Dir, p) {
+		return "", errors.Errorf("path %q is out of repository", relPath)
+	}
+	if err := c.checkSymlinks(); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+func isSubPath(dir, p string) bool {
+	return p == dir || strings.HasPrefix(p, dir+string(filepath.Separator))
+}
+
+// checkSymlinks walks working copy and resolves every symlink, chart loader and values reader follow symlinks,
+// so any of them pointing out of working copy could be used to read files of server
+func (c *Checkout) checkSymlinks() error {
+	root, err := filepath.EvalSymlinks(c.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "resolve %s", c.Dir)
+	}
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+		target, err := filepath.EvalSymlinks(p)
+		if err != nil {
+			return errors.Wrapf(err, "resolve symlink %s", p)
+		}
+		if !isSubPath(root, target) {
+			rel, _ := filepath.Rel(root, p)
+			return errors.Errorf("symlink %q points out of repository", rel)
+		}
+		return nil
+	})
+}
+
+// Remove deletes working copy
+func (c *Checkout) Remove() error {
+	return os.RemoveAll(c.Dir)
+}
@@ -0,0 +1,183 @@
+package gitrepo
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return string(out)
+}
+
+func commitFile(t *testing.T, workDir, name, content string) {
+	if err := ioutil.WriteFile(filepath.Join(workDir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, workDir, "add", name)
+	runGit(t, workDir, "commit", "-m", "update "+name)
+	runGit(t, workDir, "push", "origin", "HEAD:main")
+}
+
+func TestCheckoutSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	tmpDir, err := ioutil.TempDir("", "gitrepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	bareDir := filepath.Join(tmpDir, "values.git")
+	runGit(t, tmpDir, "init", "--bare", bareDir)
+	workDir := filepath.Join(tmpDir, "work")
+	runGit(t, tmpDir, "clone", bareDir, workDir)
+	commitFile(t, workDir, "values.yaml", "replicas: 1\n")
+
+	ctx := context.Background()
+	co := NewCheckout("file://"+bareDir, "main", filepath.Join(tmpDir, "checkout"))
+	sha1, err := co.Sync(ctx)
+	if err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if sha1 == "" {
+		t.Fatal("empty commit sha")
+	}
+	// sync without new commits keeps same sha
+	if sha, err := co.Sync(ctx); err != nil || sha != sha1 {
+		t.Fatalf("sync again: %s, %v", sha, err)
+	}
+
+	commitFile(t, workDir, "values.yaml", "replicas: 2\n")
+	sha2, err := co.Sync(ctx)
+	if err != nil {
+		t.Fatalf("sync new commit: %v", err)
+	}
+	if sha2 == sha1 {
+		t.Errorf("new commit not fetched")
+	}
+	valuesPath, err := co.Path("values.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(valuesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "replicas: 2\n" {
+		t.Errorf("values content = %q", content)
+	}
+}
+
+func TestCheckoutPath(t *testing.T) {
+	co := NewCheckout("file:///tmp/repo.git", "main", "/var/lib/checkout")
+	p, err := co.Path("../../etc/passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != "/var/lib/checkout/etc/passwd" {
+		t.Errorf("path = %s", p)
+	}
+}
+
+func TestCheckoutPathRejectsSymlink(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	tmpDir := t.TempDir()
+	bareDir := filepath.Join(tmpDir, "values.git")
+	runGit(t, tmpDir, "init", "--bare", bareDir)
+	workDir := filepath.Join(tmpDir, "work")
+	runGit(t, tmpDir, "clone", bareDir, workDir)
+	commitFile(t, workDir, "values.yaml", "replicas: 1\n")
+
+	ctx := context.Background()
+	co := NewCheckout("file://"+bareDir, "main", filepath.Join(tmpDir, "checkout"))
+	if _, err := co.Sync(ctx); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if _, err := co.Path("values.yaml"); err != nil {
+		t.Fatalf("path of regular file: %v", err)
+	}
+
+	// symlink inside repository is fine
+	if err := os.Symlink("values.yaml", filepath.Join(workDir, "prod.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, workDir, "add", "prod.yaml")
+	runGit(t, workDir, "commit", "-m", "add prod.yaml")
+	runGit(t, workDir, "push", "origin", "HEAD:main")
+	if _, err := co.Sync(ctx); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if _, err := co.Path("prod.yaml"); err != nil {
+		t.Fatalf("path of symlink inside repository: %v", err)
+	}
+
+	if err := os.Symlink("/etc", filepath.Join(workDir, "chart")); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, workDir, "add", "chart")
+	runGit(t, workDir, "commit", "-m", "add chart")
+	runGit(t, workDir, "push", "origin", "HEAD:main")
+	if _, err := co.Sync(ctx); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	for _, p := range []string{"chart/passwd", "chart", "values.yaml"} {
+		if _, err := co.Path(p); err == nil {
+			t.Errorf("path %s: symlink escaping repository should be rejected", p)
+		}
+	}
+}
+
+func TestValidateRemote(t *testing.T) {
+	cases := []struct {
+		url        string
+		branch     string
+		allowLocal bool
+		wantErr    bool
+	}{
+		{url: "https://github.com/yunionio/charts.git", branch: "main"},
+		{url: "ssh://git@github.com/yunionio/charts.git", branch: "main"},
+		{url: "git://github.com/yunionio/charts.git", branch: "main"},
+		{url: "git@github.com:yunionio/charts.git", branch: "main"},
+		{url: "file:///srv/git/deploy.git", branch: "main", wantErr: true},
+		{url: "file:///srv/git/deploy.git", branch: "main", allowLocal: true},
+		{url: "file://", branch: "main", allowLocal: true, wantErr: true},
+		{url: "/srv/git/deploy.git", branch: "main", allowLocal: true, wantErr: true},
+		{url: "/srv/git/deploy.git", branch: "main", wantErr: true},
+		{url: "ext::sh -c touch% /tmp/pwned", branch: "main", wantErr: true},
+		{url: "--upload-pack=touch /tmp/pwned", branch: "main", wantErr: true},
+		{url: "https://github.com/yunionio/charts.git", branch: "--upload-pack=touch /tmp/pwned", wantErr: true},
+		{url: "https:///charts.git", branch: "main", wantErr: true},
+	}
+	for _, c := range cases {
+		err := ValidateRemote(c.url, c.branch, c.allowLocal)
+		if c.wantErr && err == nil {
+			t.Errorf("%s %s: want error", c.url, c.branch)
+		}
+		if !c.wantErr && err != nil {
+			t.Errorf("%s %s: %v", c.url, c.branch, err)
+		}
+	}
+}
+
+func TestCheckoutSyncRejectsOption(t *testing.T) {
+	co := NewCheckout("--upload-pack=touch /tmp/pwned", "main", filepath.Join(t.TempDir(), "checkout"))
+	if _, err := co.Sync(context.Background()); err == nil {
+		t.Errorf("url starting with - should be rejected")
+	}
+}
@@ -19,17 +19,25 @@ const (
 	ActionClusterSyncStatus     TEventAction = "cluster_sync_status"
 	ActionClusterSync           TEventAction = "cluster_sync"
 	ActionClusterDeploy         TEventAction = "cluster_deploy"
+	ActionClusterUpgrade        TEventAction = "cluster_upgrade"
+	ActionClusterBackup         TEventAction = "cluster_backup"
+	ActionClusterRestore        TEventAction = "cluster_restore"
+	ActionClusterCertsExpiring  TEventAction = "cluster_certs_expiring"
+	ActionClusterRotateCerts    TEventAction = "cluster_rotate_certs"
 
 	ActionMachineCreate  TEventAction = "machine_create"
 	ActionMachinePrepare TEventAction = "machine_prepare"
 	ActionMachineDelete  TEventAction = "machine_delete"
 
+	ActionNodeDrain TEventAction = "node_drain"
+
 	ActionResourceCreate TEventAction = "resource_create"
 	ActionResourceUpdate TEventAction = "resource_update"
 	ActionResourceDelete TEventAction = "resource_delete"
 	ActionResourceSync   TEventAction = "resource_sync"
 	ActionResourceAttach TEventAction = "resource_attach"
 	ActionResourceDetach TEventAction = "resource_detach"
+	ActionResourceDrift  TEventAction = "resource_drift"
 )
 
 var (
@@ -48,15 +56,22 @@ func init() {
 		ActionClusterApplyAddons:    "部署插件",
 		ActionClusterSyncStatus:     "同步状态",
 		ActionClusterSync:           "同步",
+		ActionClusterUpgrade:        "升级集群",
+		ActionClusterBackup:         "备份集群",
+		ActionClusterRestore:        "恢复集群",
+		ActionClusterCertsExpiring:  "证书即将过期",
+		ActionClusterRotateCerts:    "轮换证书",
 		ActionMachineCreate:         "创建机器",
 		ActionMachinePrepare:        "准备机器",
 		ActionMachineDelete:         "删除机器",
+		ActionNodeDrain:             "驱逐节点",
 		ActionResourceCreate:        "创建资源",
 		ActionResourceUpdate:        "更新资源",
 		ActionResourceDelete:        "删除资源",
 		ActionResourceAttach:        "绑定资源",
 		ActionResourceDetach:        "解绑资源",
 		ActionResourceSync:          "同步资源",
+		ActionResourceDrift:         "资源漂移",
 	}
 }
 
@@ -0,0 +1,268 @@
+package nfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// minimal ONC RPC client querying exports like 'showmount -e',
+// ref: RFC 5531 (RPC), RFC 1833 (portmapper), RFC 1813 appendix I (MOUNT v3)
+
+const (
+	portmapPort    = 111
+	portmapProgram = 100000
+	portmapVersion = 2
+	portmapGetPort = 3
+
+	mountProgram = 100005
+	mountVersion = 3
+	mountExport  = 5
+
+	ipProtoTCP = 6
+
+	rpcCall          = 0
+	rpcReply         = 1
+	rpcVersion       = 2
+	rpcMsgAccepted   = 0
+	rpcAcceptSuccess = 0
+
+	lastFragment = 0x80000000
+
+	dialTimeout = 5 * time.Second
+	ioTimeout   = 10 * time.Second
+)
+
+// Export is a directory exported by nfs server
+type Export struct {
+	Dir    string   `json:"dir"`
+	Groups []string `json:"groups"`
+}
+
+// ListExports lists exports of nfs server through portmapper and mountd
+func ListExports(ctx context.Context, server string) ([]Export, error) {
+	return listExports(ctx, server, portmapPort)
+}
+
+func listExports(ctx context.Context, server string, pmPort int) ([]Export, error) {
+	args := new(xdrWriter)
+	args.uint32(mountProgram)
+	args.uint32(mountVersion)
+	args.uint32(ipProtoTCP)
+	args.uint32(0)
+	reply, err := rpcCallTCP(ctx, net.JoinHostPort(server, fmt.Sprintf("%d", pmPort)), portmapProgram, portmapVersion, portmapGetPort, args.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "portmapper getport of mountd")
+	}
+	port, err := reply.uint32()
+	if err != nil {
+		return nil, errors.Wrap(err, "decode mountd port")
+	}
+	if port == 0 {
+		return nil, errors.Errorf("mountd v3 over tcp isn't registered on %s", server)
+	}
+	reply, err = rpcCallTCP(ctx, net.JoinHostPort(server, fmt.Sprintf("%d", port)), mountProgram, mountVersion, mountExport, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "mountd export")
+	}
+	return decodeExports(reply)
+}
+
+func decodeExports(r *xdrReader) ([]Export, error) {
+	exports := make([]Export, 0)
+	for {
+		follows, err := r.bool()
+		if err != nil {
+			return nil, err
+		}
+		if !follows {
+			return exports, nil
+		}
+		exp := Export{Groups: make([]string, 0)}
+		if exp.Dir, err = r.string(); err != nil {
+			return nil, err
+		}
+		for {
+			follows, err := r.bool()
+			if err != nil {
+				return nil, err
+			}
+			if !follows {
+				break
+			}
+			group, err := r.string()
+			if err != nil {
+				return nil, err
+			}
+			exp.Groups = append(exp.Groups, group)
+		}
+		exports = append(exports, exp)
+	}
+}
+
+func rpcCallTCP(ctx context.Context, addr string, prog, vers, proc uint32, args []byte) (*xdrReader, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dial %s", addr)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(ioTimeout))
+
+	xid := rand.Uint32()
+	msg := new(xdrWriter)
+	msg.uint32(xid)
+	msg.uint32(rpcCall)
+	msg.uint32(rpcVersion)
+	msg.uint32(prog)
+	msg.uint32(vers)
+	msg.uint32(proc)
+	// AUTH_NONE credential and verifier
+	msg.uint32(0)
+	msg.uint32(0)
+	msg.uint32(0)
+	msg.uint32(0)
+	msg.buf.Write(args)
+	if err := writeRecord(conn, msg.Bytes()); err != nil {
+		return nil, errors.Wrap(err, "write rpc call")
+	}
+	data, err := readRecord(conn)
+	if err != nil {
+		return nil, errors.Wrap(err, "read rpc reply")
+	}
+	r := &xdrReader{r: bytes.NewReader(data)}
+	if err := r.replyHeader(xid); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func writeRecord(w io.Writer, data []byte) error {
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, lastFragment|uint32(len(data)))
+	_, err := w.Write(append(header, data...))
+	return err
+}
+
+func readRecord(r io.Reader) ([]byte, error) {
+	ret := new(bytes.Buffer)
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		h := binary.BigEndian.Uint32(header)
+		if _, err := io.CopyN(ret, r, int64(h&^lastFragment)); err != nil {
+			return nil, err
+		}
+		if h&lastFragment != 0 {
+			return ret.Bytes(), nil
+		}
+	}
+}
+
+type xdrWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *xdrWriter) uint32(v uint32) {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	w.buf.Write(b)
+}
+
+func (w *xdrWriter) bool(v bool) {
+	if v {
+		w.uint32(1)
+	} else {
+		w.uint32(0)
+	}
+}
+
+func (w *xdrWriter) string(s string) {
+	w.uint32(uint32(len(s)))
+	w.buf.WriteString(s)
+	if pad := (4 - len(s)%4) % 4; pad > 0 {
+		w.buf.Write(make([]byte, pad))
+	}
+}
+
+func (w *xdrWriter) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+type xdrReader struct {
+	r *bytes.Reader
+}
+
+func (r *xdrReader) uint32() (uint32, error) {
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return 0, errors.Wrap(err, "read uint32")
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+func (r *xdrReader) bool() (bool, error) {
+	v, err := r.uint32()
+	return v != 0, err
+}
+
+func (r *xdrReader) opaque() ([]byte, error) {
+	l, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if int64(l) > int64(r.r.Len()) {
+		return nil, errors.Errorf("opaque length %d exceeds remaining %d", l, r.r.Len())
+	}
+	b := make([]byte, l+(4-l%4)%4)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return nil, errors.Wrap(err, "read opaque")
+	}
+	return b[:l], nil
+}
+
+func (r *xdrReader) string() (string, error) {
+	b, err := r.opaque()
+	return string(b), err
+}
+
+func (r *xdrReader) replyHeader(xid uint32) error {
+	vals := make([]uint32, 3)
+	for i := range vals {
+		v, err := r.uint32()
+		if err != nil {
+			return err
+		}
+		vals[i] = v
+	}
+	if vals[0] != xid || vals[1] != rpcReply {
+		return errors.Errorf("unexpected rpc reply xid %d type %d", vals[0], vals[1])
+	}
+	if vals[2] != rpcMsgAccepted {
+		return errors.Errorf("rpc call denied, reply stat %d", vals[2])
+	}
+	// verifier flavor and body
+	if _, err := r.uint32(); err != nil {
+		return err
+	}
+	if _, err := r.opaque(); err != nil {
+		return err
+	}
+	stat, err := r.uint32()
+	if err != nil {
+		return err
+	}
+	if stat != rpcAcceptSuccess {
+		return errors.Errorf("rpc call not succeeded, accept stat %d", stat)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package nfs
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// serveRPC replies to portmapper getport with its own port and to mountd export with exports
+func serveRPC(t *testing.T, l net.Listener, exports []Export) {
+	port := uint32(l.Addr().(*net.TCPAddr).Port)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		data, err := readRecord(conn)
+		if err != nil {
+			t.Errorf("read call: %v", err)
+			conn.Close()
+			continue
+		}
+		r := &xdrReader{r: bytes.NewReader(data)}
+		xid, _ := r.uint32()
+		for i := 0; i < 2; i++ {
+			r.uint32()
+		}
+		prog, _ := r.uint32()
+		r.uint32()
+		proc, _ := r.uint32()
+
+		w := new(xdrWriter)
+		w.uint32(xid)
+		w.uint32(rpcReply)
+		w.uint32(rpcMsgAccepted)
+		w.uint32(0)
+		w.string("")
+		w.uint32(rpcAcceptSuccess)
+		switch {
+		case prog == portmapProgram && proc == portmapGetPort:
+			w.uint32(port)
+		case prog == mountProgram && proc == mountExport:
+			for _, exp := range exports {
+				w.bool(true)
+				w.string(exp.Dir)
+				for _, g := range exp.Groups {
+					w.bool(true)
+					w.string(g)
+				}
+				w.bool(false)
+			}
+			w.bool(false)
+		default:
+			t.Errorf("unexpected call prog %d proc %d", prog, proc)
+		}
+		writeRecord(conn, w.Bytes())
+		conn.Close()
+	}
+}
+
+func TestListExports(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	want := []Export{
+		{Dir: "/data/k8s", Groups: []string{"10.0.0.0/8", "192.168.1.1"}},
+		{Dir: "/export", Groups: []string{"*"}},
+	}
+	go serveRPC(t, l, want)
+
+	got, err := listExports(context.Background(), "127.0.0.1", l.Addr().(*net.TCPAddr).Port)
+	if err != nil {
+		t.Fatalf("listExports: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestReadRecordFragments(t *testing.T) {
+	buf := new(bytes.Buffer)
+	// first fragment without last fragment bit
+	buf.Write([]byte{0, 0, 0, 2, 'a', 'b'})
+	writeRecord(buf, []byte("cd"))
+	got, err := readRecord(buf)
+	if err != nil {
+		t.Fatalf("readRecord: %v", err)
+	}
+	if string(got) != "abcd" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestXdrStringPadding(t *testing.T) {
+	for _, s := range []string{"", "a", "abcd", "abcde"} {
+		w := new(xdrWriter)
+		w.string(s)
+		if w.buf.Len()%4 != 0 {
+			t.Errorf("%q encoded length %d not aligned", s, w.buf.Len())
+		}
+		r := &xdrReader{r: bytes.NewReader(w.Bytes())}
+		got, err := r.string()
+		if err != nil || got != s {
+			t.Errorf("decode %q: got %q, %v", s, got, err)
+		}
+		if r.r.Len() != 0 {
+			t.Errorf("%q: %d bytes remaining", s, r.r.Len())
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package s3
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/minio/minio-go/v7/pkg/s3utils"
@@ -35,3 +40,21 @@ func NewClient(conf *Config) (*Client, error) {
 func CheckValidBucketNameStrict(name string) error {
 	return s3utils.CheckValidBucketNameStrict(name)
 }
+
+// PutStream uploads content written by write to bucket without knowing its size,
+// the size and sha256 checksum of uploaded content are returned
+func (c *Client) PutStream(ctx context.Context, bucket, key string, write func(w io.Writer) error) (int64, string, error) {
+	pr, pw := io.Pipe()
+	hasher := sha256.New()
+	go func() {
+		pw.CloseWithError(write(io.MultiWriter(hasher, pw)))
+	}()
+	info, err := c.PutObject(ctx, bucket, key, pr, -1, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	if err != nil {
+		pr.CloseWithError(err)
+		return 0, "", errors.Wrapf(err, "put object %s/%s", bucket, key)
+	}
+	return info.Size, hex.EncodeToString(hasher.Sum(nil)), nil
+}
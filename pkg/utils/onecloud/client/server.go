@@ -88,6 +88,8 @@ type ServerSSHLoginInfo struct {
 	ExternalId            string
 	Cloudregion           string
 	CloudregionExternalId string
+	// Port is ssh port, default port 22 is used when it's 0
+	Port int
 }
 
 func (info ServerSSHLoginInfo) GetAccessIP() string {
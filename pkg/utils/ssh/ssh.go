@@ -1,14 +1,17 @@
 package ssh
 
 import (
+	"bytes"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
 	"yunion.io/x/log"
 	"yunion.io/x/onecloud/pkg/util/procutils"
 	"yunion.io/x/onecloud/pkg/util/ssh"
+	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/util/seclib"
 	"yunion.io/x/pkg/util/wait"
 )
@@ -43,6 +46,37 @@ func RemoteSSHCommand(host string, port int, username string, passwd string, pri
 	return strings.Join(ret, "\n"), nil
 }
 
+// RemoteSSHCommandStream executes command on remote machine, stdin and stdout
+// are streamed so large content like snapshot files are not buffered in memory
+func RemoteSSHCommandStream(host string, port int, username string, passwd string, privateKey, cmd string, stdin io.Reader, stdout io.Writer) error {
+	cli, err := ssh.ClientConfig{
+		Host:       host,
+		Port:       port,
+		Username:   username,
+		Password:   passwd,
+		PrivateKey: privateKey,
+	}.Connect()
+	if err != nil {
+		return errors.Wrapf(err, "connect %s:%d", host, port)
+	}
+	defer cli.Close()
+
+	session, err := cli.NewSession()
+	if err != nil {
+		return errors.Wrap(err, "new session")
+	}
+	defer session.Close()
+
+	stderr := new(bytes.Buffer)
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = stderr
+	if err := session.Run(cmd); err != nil {
+		return errors.Wrapf(err, "run %q: %s", cmd, stderr.String())
+	}
+	return nil
+}
+
 func CheckRemotePortOpen(host string, port int) error {
 	log.Infof("CheckRemotePortOpen for remote: %s:%d", host, port)
 	err := procutils.NewCommand("nc", "-z", host, fmt.Sprintf("%d", port)).Run()
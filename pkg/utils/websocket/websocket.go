@@ -0,0 +1,300 @@
+// Package websocket implements the server side of RFC 6455, just enough to
+// proxy interactive streams like pod exec terminals over a hijacked http connection.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xA
+
+	CloseNormal        = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	CloseInternalError = 1011
+
+	// MaxMessageSize limits the size of a single reassembled message
+	MaxMessageSize = 1 << 20
+
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+var (
+	ErrNotWebsocket   = errors.Error("request is not a websocket upgrade")
+	ErrMessageTooBig  = errors.Error("websocket message too big")
+	ErrProtocol       = errors.Error("websocket protocol error")
+	ErrConnectionDone = errors.Error("websocket connection closed")
+)
+
+// CloseError is returned by ReadMessage when the peer sends a close frame
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Reason)
+}
+
+// Conn is a server side websocket connection
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	writeLock sync.Mutex
+	closeOnce sync.Once
+	closed    bool
+}
+
+// IsWebsocketRequest reports whether r asks for a websocket upgrade
+func IsWebsocketRequest(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") &&
+		headerContains(r.Header, "Upgrade", "websocket")
+}
+
+func headerContains(h http.Header, key, value string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(key)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func computeAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Upgrade hijacks the http connection and completes the websocket handshake,
+// subprotocol is echoed back when the client offers it.
+func Upgrade(w http.ResponseWriter, r *http.Request, subprotocol string) (*Conn, error) {
+	if r.Method != http.MethodGet || !IsWebsocketRequest(r) {
+		return nil, ErrNotWebsocket
+	}
+	if r.Header.Get("Sec-Websocket-Version") != "13" {
+		return nil, errors.Wrapf(ErrNotWebsocket, "unsupported version %q", r.Header.Get("Sec-Websocket-Version"))
+	}
+	key := r.Header.Get("Sec-Websocket-Key")
+	if key == "" {
+		return nil, errors.Wrap(ErrNotWebsocket, "missing Sec-Websocket-Key")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.Error("response writer does not support hijack")
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, errors.Wrap(err, "hijack connection")
+	}
+	if brw.Reader.Buffered() > 0 {
+		conn.Close()
+		return nil, errors.Wrap(ErrProtocol, "client sent data before handshake")
+	}
+
+	resp := []string{
+		"HTTP/1.1 101 Switching Protocols",
+		"Upgrade: websocket",
+		"Connection: Upgrade",
+		"Sec-WebSocket-Accept: " + computeAcceptKey(key),
+	}
+	if subprotocol != "" && headerContains(r.Header, "Sec-Websocket-Protocol", subprotocol) {
+		resp = append(resp, "Sec-WebSocket-Protocol: "+subprotocol)
+	}
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := conn.Write([]byte(strings.Join(resp, "\r\n") + "\r\n\r\n")); err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "write handshake response")
+	}
+	conn.SetWriteDeadline(time.Time{})
+	return &Conn{
+		conn: conn,
+		br:   brw.Reader,
+	}, nil
+}
+
+// ReadMessage reads a complete data message, control frames are handled internally
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var (
+		msgOp int = -1
+		msg   []byte
+	)
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case OpPing:
+			if err := c.writeFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			continue
+		case OpClose:
+			cerr := &CloseError{Code: CloseNormal}
+			if len(payload) >= 2 {
+				cerr.Code = int(binary.BigEndian.Uint16(payload))
+				cerr.Reason = string(payload[2:])
+			}
+			c.writeClose(cerr.Code, "")
+			c.Close()
+			return 0, nil, cerr
+		case OpContinuation:
+			if msgOp < 0 {
+				return 0, nil, errors.Wrap(ErrProtocol, "unexpected continuation frame")
+			}
+		case OpText, OpBinary:
+			if msgOp >= 0 {
+				return 0, nil, errors.Wrap(ErrProtocol, "expect continuation frame")
+			}
+			msgOp = op
+		default:
+			return 0, nil, errors.Wrapf(ErrProtocol, "unknown opcode %d", op)
+		}
+		if len(msg)+len(payload) > MaxMessageSize {
+			return 0, nil, ErrMessageTooBig
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msgOp, msg, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, errors.Wrap(ErrProtocol, "reserved bits set")
+	}
+	op := int(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+	if !masked {
+		// clients must mask every frame they send
+		return false, 0, nil, errors.Wrap(ErrProtocol, "unmasked client frame")
+	}
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= OpClose && (length > 125 || !fin) {
+		return false, 0, nil, errors.Wrap(ErrProtocol, "invalid control frame")
+	}
+	if length > MaxMessageSize {
+		return false, 0, nil, ErrMessageTooBig
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// WriteMessage sends data as a single unfragmented frame
+func (c *Conn) WriteMessage(op int, data []byte) error {
+	return c.writeFrame(op, data)
+}
+
+func (c *Conn) writeFrame(op int, data []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if c.closed {
+		return ErrConnectionDone
+	}
+
+	buf := make([]byte, 0, len(data)+10)
+	buf = append(buf, 0x80|byte(op))
+	length := len(data)
+	switch {
+	case length <= 125:
+		buf = append(buf, byte(length))
+	case length <= 0xffff:
+		buf = append(buf, 126, byte(length>>8), byte(length))
+	default:
+		buf = append(buf, 127)
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(length))
+		buf = append(buf, ext[:]...)
+	}
+	buf = append(buf, data...)
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+func (c *Conn) writeClose(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	return c.writeFrame(OpClose, payload)
+}
+
+// CloseWithReason sends a close frame to peer and closes the connection
+func (c *Conn) CloseWithReason(code int, reason string) error {
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	c.writeClose(code, reason)
+	return c.Close()
+}
+
+// Close closes the underlying connection without handshake
+func (c *Conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.writeLock.Lock()
+		c.closed = true
+		c.writeLock.Unlock()
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// SetReadDeadline sets deadline of the underlying connection reading
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
@@ -0,0 +1,151 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestComputeAcceptKey(t *testing.T) {
+	// example from RFC 6455 section 1.3
+	got := computeAcceptKey("dGhlIHNhbXBsZSBub25jZQ==")
+	want := "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+	if got != want {
+		t.Errorf("computeAcceptKey() = %s, want %s", got, want)
+	}
+}
+
+func writeClientFrame(w io.Writer, fin bool, op int, payload []byte) error {
+	b0 := byte(op)
+	if fin {
+		b0 |= 0x80
+	}
+	buf := []byte{b0}
+	switch {
+	case len(payload) <= 125:
+		buf = append(buf, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		buf = append(buf, 0x80|126, byte(len(payload)>>8), byte(len(payload)))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(len(payload)))
+		buf = append(buf, 0x80|127)
+		buf = append(buf, ext[:]...)
+	}
+	mask := []byte{0x11, 0x22, 0x33, 0x44}
+	buf = append(buf, mask...)
+	for i, b := range payload {
+		buf = append(buf, b^mask[i%4])
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func readServerFrame(r *bufio.Reader) (int, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := int(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(r, ext[:])
+		length = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload := make([]byte, length)
+	_, err := io.ReadFull(r, payload)
+	return int(header[0] & 0x0f), payload, err
+}
+
+func TestConnEcho(t *testing.T) {
+	done := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, "channel.k8s.io")
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+		for {
+			op, msg, err := conn.ReadMessage()
+			if err != nil {
+				if _, ok := err.(*CloseError); ok {
+					err = nil
+				}
+				done <- err
+				return
+			}
+			if err := conn.WriteMessage(op, msg); err != nil {
+				done <- err
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	req := "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Protocol: base64.channel.k8s.io, channel.k8s.io\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Protocol"); got != "channel.k8s.io" {
+		t.Errorf("subprotocol = %q", got)
+	}
+
+	// fragmented text message interleaved with a ping
+	writeClientFrame(conn, false, OpText, []byte("hello "))
+	writeClientFrame(conn, true, OpPing, []byte("p"))
+	writeClientFrame(conn, true, OpContinuation, []byte("world"))
+	op, payload, err := readServerFrame(br)
+	if err != nil || op != OpPong || string(payload) != "p" {
+		t.Fatalf("expect pong, got op %d payload %q err %v", op, payload, err)
+	}
+	op, payload, err = readServerFrame(br)
+	if err != nil || op != OpText || string(payload) != "hello world" {
+		t.Fatalf("expect echo, got op %d payload %q err %v", op, payload, err)
+	}
+
+	big := []byte(strings.Repeat("x", 70000))
+	writeClientFrame(conn, true, OpBinary, big)
+	op, payload, err = readServerFrame(br)
+	if err != nil || op != OpBinary || len(payload) != len(big) {
+		t.Fatalf("expect big echo, got op %d len %d err %v", op, len(payload), err)
+	}
+
+	writeClientFrame(conn, true, OpClose, []byte{0x03, 0xe8})
+	if err := <-done; err != nil {
+		t.Errorf("server error: %v", err)
+	}
+}
+
+func TestUpgradeRejectPlainRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	if _, err := Upgrade(w, r, ""); err != ErrNotWebsocket {
+		t.Errorf("Upgrade() error = %v, want %v", err, ErrNotWebsocket)
+	}
+}